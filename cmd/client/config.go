@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/voilet/quic-flow/pkg/agentconfig"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/transport/client"
+)
+
+// loadAgentConfig 加载 Agent 配置文件，命令行显式指定的参数优先
+func loadAgentConfig(cmd *cobra.Command) (*agentconfig.Config, error) {
+	cfg, err := agentconfig.Load(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("server") || configFile == "" {
+		cfg.ServerAddr = serverAddr
+	}
+	if flags.Changed("id") || configFile == "" {
+		cfg.ClientID = clientID
+	}
+	if flags.Changed("insecure") || configFile == "" {
+		cfg.Insecure = insecure
+	}
+	return cfg, cfg.Validate()
+}
+
+// newConfigReloader 创建热加载回调：心跳间隔、日志级别、硬件缓存时间
+// 状态打印间隔和标签在使用时直接读取，无需处理
+func newConfigReloader(logger *monitoring.Logger, c *client.Client) agentconfig.Reloader {
+	return func(old, new *agentconfig.Config) error {
+		if new.HeartbeatIntervalSec != old.HeartbeatIntervalSec {
+			if err := c.SetHeartbeatInterval(time.Duration(new.HeartbeatIntervalSec) * time.Second); err != nil {
+				return err
+			}
+		}
+		if new.LogLevel != old.LogLevel {
+			logger.SetLevel(monitoring.LogLevel(new.LogLevel))
+		}
+		if new.HardwareCacheTTLSec != old.HardwareCacheTTLSec {
+			hwCacheMu.Lock()
+			hwCacheTTL = time.Duration(new.HardwareCacheTTLSec) * time.Second
+			hwCacheMu.Unlock()
+		}
+		return nil
+	}
+}
+
+// restartAgent 以相同参数和环境重新执行当前程序，使恢复的配置生效
+func restartAgent() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	return syscall.Exec(exe, os.Args, os.Environ())
+}
@@ -12,11 +12,19 @@ import (
 
 	"github.com/quic-go/quic-go"
 	"github.com/spf13/cobra"
+	"github.com/voilet/quic-flow/pkg/agentconfig"
+	"github.com/voilet/quic-flow/pkg/agentupdate"
 	"github.com/voilet/quic-flow/pkg/command"
 	"github.com/voilet/quic-flow/pkg/dispatcher"
+	"github.com/voilet/quic-flow/pkg/eventbus"
+	"github.com/voilet/quic-flow/pkg/fsutil"
+	"github.com/voilet/quic-flow/pkg/localapi"
 	"github.com/voilet/quic-flow/pkg/monitoring"
 	"github.com/voilet/quic-flow/pkg/protocol"
 	"github.com/voilet/quic-flow/pkg/router"
+	"github.com/voilet/quic-flow/pkg/spool"
+	"github.com/voilet/quic-flow/pkg/task/executor"
+	"github.com/voilet/quic-flow/pkg/task/local"
 	"github.com/voilet/quic-flow/pkg/router/handlers"
 	"github.com/voilet/quic-flow/pkg/transport/client"
 	"github.com/voilet/quic-flow/pkg/version"
@@ -27,6 +35,7 @@ var (
 	serverAddr string
 	clientID   string
 	insecure   bool
+	configFile string
 
 	// hwinfo 参数
 	hwinfoFormat      string
@@ -45,6 +54,26 @@ var (
 	sshPassword     string
 	sshShell        string
 	sshPortForward  bool
+
+	// 自升级参数
+	updatePublicKey string
+	updateDir       string
+
+	// 插件参数
+	pluginsDir string
+
+	// 本地 API 参数
+	localAPIAddr  string
+	localAPIToken string
+
+	// 离线缓存参数
+	spoolDir        string
+	spoolMaxMB      int
+	spoolMaxAge     time.Duration
+	spoolDropPolicy string
+
+	// 本地任务调度参数
+	taskDir string
 )
 
 // 硬件信息缓存
@@ -95,6 +124,7 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&serverAddr, "server", "s", "localhost:8474", "服务器地址")
 	rootCmd.PersistentFlags().StringVarP(&clientID, "id", "i", "client-001", "客户端 ID")
 	rootCmd.PersistentFlags().BoolVarP(&insecure, "insecure", "k", true, "跳过 TLS 证书验证（仅开发环境）")
+	rootCmd.Flags().StringVarP(&configFile, "config", "c", "config/client.json", "Agent 配置文件路径（为空则不持久化远程配置）")
 
 	// SSH 参数
 	rootCmd.Flags().BoolVar(&sshEnabled, "ssh", true, "启用 SSH 服务（允许服务器通过 QUIC 连接 SSH 到本机）")
@@ -103,6 +133,26 @@ func init() {
 	rootCmd.Flags().StringVar(&sshShell, "ssh-shell", "/bin/sh", "SSH 默认 Shell")
 	rootCmd.Flags().BoolVar(&sshPortForward, "ssh-port-forward", true, "允许 SSH 端口转发")
 
+	// 自升级参数
+	rootCmd.Flags().StringVar(&updatePublicKey, "update-public-key", "", "升级包签名公钥（base64 或文件路径），为空则禁用自升级")
+	rootCmd.Flags().StringVar(&updateDir, "update-dir", "", "升级包暂存目录（默认 <当前二进制>.staging，须属于当前用户或 root 且组与其他用户不可写）")
+
+	// 插件参数
+	rootCmd.Flags().StringVar(&pluginsDir, "plugins-dir", "", "插件目录（绝对路径，如 /var/lib/quic-client/plugins；每个插件一个子目录，包含 plugin.json），为空则禁用插件")
+
+	// 本地 API 参数
+	rootCmd.Flags().StringVar(&localAPIAddr, "local-api", "", "本地 API 监听地址（Unix socket 路径，或 tcp://127.0.0.1:port），为空则禁用")
+	rootCmd.Flags().StringVar(&localAPIToken, "local-api-token", "", "本地 API 访问令牌（字符串或文件路径），TCP 监听时必填")
+
+	// 离线缓存参数
+	rootCmd.Flags().StringVar(&spoolDir, "spool-dir", "", "离线缓存目录（绝对路径，如 /var/lib/quic-client/spool；断线期间缓存事件与上报，重连后重放），为空则禁用")
+	rootCmd.Flags().IntVar(&spoolMaxMB, "spool-max-mb", 64, "离线缓存最大占用（MB）")
+	rootCmd.Flags().DurationVar(&spoolMaxAge, "spool-max-age", 24*time.Hour, "离线缓存消息最长保留时间")
+	rootCmd.Flags().StringVar(&spoolDropPolicy, "spool-drop", "oldest", "离线缓存满时的丢弃策略：oldest（丢弃最早的）或 newest（拒绝新消息）")
+
+	// 本地任务调度参数
+	rootCmd.Flags().StringVar(&taskDir, "task-dir", "tasks", "本地任务调度目录（保存服务器下发的任务配置、待同步的执行结果与每日统计），为空则禁用")
+
 	// hwinfo 子命令参数
 	hwinfoCmd.Flags().StringVarP(&hwinfoFormat, "format", "f", "json", "输出格式 (json|text)")
 	hwinfoCmd.Flags().BoolVarP(&hwinfoForceRefresh, "force-refresh", "F", false, "强制刷新硬件信息（忽略缓存）")
@@ -129,19 +179,25 @@ func main() {
 
 // runClient 运行客户端（连接服务器模式）
 func runClient(cmd *cobra.Command, args []string) {
+	// 加载 Agent 配置
+	agentCfg, err := loadAgentConfig(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		os.Exit(1)
+	}
+
 	// 创建日志器
-	logger := monitoring.NewLogger(monitoring.LogLevelInfo, "text")
+	logger := monitoring.NewLogger(monitoring.LogLevel(agentCfg.LogLevel), agentCfg.LogFormat)
 
 	logger.Info("=== QUIC Backbone Client ===")
 	logger.Info("Version", "version", version.String())
-	logger.Info("Connecting to server", "server", serverAddr, "client_id", clientID)
+	logger.Info("Connecting to server", "server", agentCfg.ServerAddr, "client_id", agentCfg.ClientID)
 
 	// SSH 集成
 	var sshIntegration *SSHIntegration
 
 	if sshEnabled {
 		logger.Info("SSH service enabled", "user", sshUser, "shell", sshShell)
-		var err error
 		sshIntegration, err = NewSSHIntegration(&SSHConfig{
 			Enabled:          true,
 			User:             sshUser,
@@ -161,9 +217,34 @@ func runClient(cmd *cobra.Command, args []string) {
 	}
 
 	// 创建客户端配置
-	config := client.NewDefaultClientConfig(clientID)
-	config.InsecureSkipVerify = insecure
+	config := client.NewDefaultClientConfig(agentCfg.ClientID)
+	config.InsecureSkipVerify = agentCfg.Insecure
+	config.HeartbeatInterval = time.Duration(agentCfg.HeartbeatIntervalSec) * time.Second
 	config.Logger = logger
+	hwCacheTTL = time.Duration(agentCfg.HardwareCacheTTLSec) * time.Second
+
+	// 每次连接/重连后：向服务器重新声明主题订阅，重放离线缓存，拉取本地任务配置并同步执行结果
+	var events *eventbus.AgentLink
+	var outbox *spool.Outbox
+	var localTasks *local.Scheduler
+	onConnected := func() {
+		if outbox != nil {
+			outbox.Trigger()
+		}
+		if err := events.Sync(context.Background()); err != nil {
+			logger.Warn("Failed to sync event subscriptions", "error", err)
+		}
+		if localTasks != nil {
+			if err := localTasks.Pull(context.Background()); err != nil {
+				logger.Warn("Failed to pull local task config", "error", err)
+			}
+			localTasks.Trigger()
+		}
+	}
+	config.Hooks = &monitoring.EventHooks{
+		OnConnect:   func(string) { go onConnected() },
+		OnReconnect: func(string, int) { go onConnected() },
+	}
 
 	// 创建客户端
 	c, err := client.NewClient(config)
@@ -172,11 +253,58 @@ func runClient(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	// 离线缓存：可缓存的出站消息经 agent 发送，断线期间写入磁盘队列
+	outbox, err = newOutbox(logger, c)
+	if err != nil {
+		logger.Error("Failed to open spool", "error", err)
+		os.Exit(1)
+	}
+	if outbox != nil {
+		outbox.Start()
+	}
+	agent := &spooledClient{Client: c, outbox: outbox}
+	events = eventbus.NewAgentLink(agent, 0, logger)
+
+	// 配置管理器：远程更新后热加载，宽限期内未重连则自动回滚
+	cfgManager := agentconfig.NewManager(configFile, agentCfg, logger)
+	cfgManager.SetReloader(newConfigReloader(logger, c))
+	cfgManager.SetConnectedFunc(c.IsConnected)
+	cfgManager.SetRestartFunc(restartAgent)
+
+	// 自升级：接收服务器推送的签名二进制，替换后重启，未能重连则回滚
+	updater, err := newUpdater(logger)
+	if err != nil {
+		logger.Error("Failed to create updater", "error", err)
+		os.Exit(1)
+	}
+	if updater != nil {
+		updater.SetConnectedFunc(c.IsConnected)
+		c.SetFileTransferHandler(func(stream *quic.Stream) error {
+			return updater.HandleStream(stream)
+		})
+	}
+
+	// 定时任务执行器：执行服务器下发的任务，结果经离线缓存上报
+	tasks := executor.NewRunner(agent, agentCfg.ClientID, logger)
+
+	// 本地任务调度：按服务器下发的配置在本地执行（断网期间照常运行），结果在线时同步
+	if taskDir != "" {
+		localTasks, err = local.NewScheduler(taskDir, agent, tasks, logger)
+		if err != nil {
+			logger.Error("Failed to create local task scheduler", "error", err)
+			os.Exit(1)
+		}
+	}
+
 	// 设置命令路由器
-	cmdRouter := SetupClientRouter(logger)
+	cmdRouter, plugins := SetupClientRouter(logger, cfgManager, updater, tasks, localTasks, pluginsDir)
+	if localTasks != nil {
+		// 插件执行器在路由器中注册，之后再开始调度
+		localTasks.Start()
+	}
 
 	// 创建 Dispatcher 并注册消息处理器
-	disp := setupDispatcher(logger, c, cmdRouter)
+	disp := setupDispatcher(logger, agent, cmdRouter, events)
 
 	// 设置 Dispatcher 到客户端
 	c.SetDispatcher(disp)
@@ -190,11 +318,49 @@ func runClient(cmd *cobra.Command, args []string) {
 		logger.Info("SSH handler attached to client")
 	}
 
+	// 本地 API：供同主机应用上报事件、查询状态、注册短期命令处理器
+	var localAPI *localapi.Server
+	if localAPIAddr != "" {
+		var token string
+		token, err = fsutil.ReadSecret(localAPIToken)
+		if err == nil {
+			localAPI, err = localapi.NewServer(localapi.Config{
+				Address: localAPIAddr,
+				Token:   token,
+				Version: version.String(),
+				Router:  cmdRouter,
+				Events:  events,
+				Logger:  logger,
+			}, agent)
+		}
+		if err == nil && outbox != nil {
+			localAPI.AddStatus("spool", func() interface{} { return outbox.Stats() })
+		}
+		if err == nil && localTasks != nil {
+			localAPI.AddStatus("local_tasks", func() interface{} { return localTasks.Status() })
+		}
+		if err == nil {
+			err = localAPI.Start()
+		}
+		if err != nil {
+			logger.Error("Failed to start local API", "error", err)
+			os.Exit(1)
+		}
+	}
+
 	// 连接到服务器
-	if err := c.Connect(serverAddr); err != nil {
+	if err := c.Connect(agentCfg.ServerAddr); err != nil {
 		logger.Error("Failed to connect", "error", err)
 	}
 
+	// 上次需要重启的配置变更尚未确认时，等待连接后确认，超时则恢复变更前的配置并重启
+	cfgManager.CheckPending(context.Background())
+
+	// 上次升级尚未确认时，等待重连后确认，超时则回滚
+	if updater != nil {
+		updater.CheckPending(context.Background())
+	}
+
 	logger.Info("Client started (auto-reconnect enabled)")
 	logger.Info("Ready to receive and execute commands")
 	if sshEnabled {
@@ -206,14 +372,14 @@ func runClient(cmd *cobra.Command, args []string) {
 	go func() {
 		time.Sleep(1 * time.Second) // 等待连接完全建立
 		if c.IsConnected() {
-			reportHardwareInfo(c, logger)
+			reportHardwareInfo(agent, logger)
 		}
 	}()
 
 	// 注意：SSH 流现在由 receiveLoop 中的 SSH handler 处理，不再需要单独的 AcceptSSHStreams
 
 	// 定期打印状态
-	go printStatus(c, cmdRouter, sshEnabled)
+	go printStatus(c, cmdRouter, cfgManager, outbox, localTasks, sshEnabled)
 
 	// 等待中断信号
 	sigChan := make(chan os.Signal, 1)
@@ -221,6 +387,23 @@ func runClient(cmd *cobra.Command, args []string) {
 	<-sigChan
 
 	// 优雅关闭
+	if localAPI != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		localAPI.Stop(ctx)
+		cancel()
+	}
+	cfgManager.Stop()
+	// 先停止本地调度，再取消执行中的任务，取消结果写入离线缓存
+	if localTasks != nil {
+		localTasks.Stop()
+	}
+	tasks.Stop()
+	if outbox != nil {
+		outbox.Stop()
+	}
+	if plugins != nil {
+		plugins.Stop()
+	}
 	shutdown(logger, disp, c, sshIntegration)
 }
 
@@ -380,7 +563,7 @@ func formatUptime(seconds uint64) string {
 }
 
 // setupDispatcher 设置消息分发器
-func setupDispatcher(logger *monitoring.Logger, c *client.Client, cmdRouter *router.Router) *dispatcher.Dispatcher {
+func setupDispatcher(logger *monitoring.Logger, sender command.ClientAPI, cmdRouter *router.Router, events *eventbus.AgentLink) *dispatcher.Dispatcher {
 	dispatcherConfig := &dispatcher.DispatcherConfig{
 		WorkerCount:    10,
 		TaskQueueSize:  1000,
@@ -390,15 +573,21 @@ func setupDispatcher(logger *monitoring.Logger, c *client.Client, cmdRouter *rou
 	disp := dispatcher.NewDispatcher(dispatcherConfig)
 
 	// 创建命令处理器
-	commandHandler := command.NewCommandHandler(c, cmdRouter, logger)
+	commandHandler := command.NewCommandHandler(sender, cmdRouter, logger)
 
 	// 注册 MESSAGE_TYPE_COMMAND 处理器
 	disp.RegisterHandler(protocol.MessageType_MESSAGE_TYPE_COMMAND, dispatcher.MessageHandlerFunc(func(ctx context.Context, msg *protocol.DataMessage) (*protocol.DataMessage, error) {
 		return commandHandler.HandleCommand(ctx, msg)
 	}))
 
-	// 处理 Server 推送的事件
+	// 处理 Server 推送的事件（主题事件投递到本地总线）
 	disp.RegisterHandler(protocol.MessageType_MESSAGE_TYPE_EVENT, dispatcher.MessageHandlerFunc(func(ctx context.Context, msg *protocol.DataMessage) (*protocol.DataMessage, error) {
+		if handled, err := events.HandleMessage(msg); handled {
+			if err != nil {
+				logger.Warn("Invalid event delivery", "msg_id", msg.MsgId, "error", err)
+			}
+			return nil, nil
+		}
 		logger.Info("Received event from server", "msg_id", msg.MsgId)
 		return nil, nil
 	}))
@@ -411,11 +600,18 @@ func setupDispatcher(logger *monitoring.Logger, c *client.Client, cmdRouter *rou
 }
 
 // printStatus 定期打印状态
-func printStatus(c *client.Client, cmdRouter interface{ ListCommands() []string }, sshEnabled bool) {
-	ticker := time.NewTicker(5 * time.Second)
+func printStatus(c *client.Client, cmdRouter interface{ ListCommands() []string }, cfgManager *agentconfig.Manager, outbox *spool.Outbox, localTasks *local.Scheduler, sshEnabled bool) {
+	interval := time.Duration(cfgManager.Get().StatusIntervalSec) * time.Second
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for range ticker.C {
+		// 打印间隔支持热更新
+		if d := time.Duration(cfgManager.Get().StatusIntervalSec) * time.Second; d != interval {
+			interval = d
+			ticker.Reset(interval)
+		}
+
 		state := c.GetState()
 		metrics := c.GetMetrics()
 		lastPong := c.GetTimeSinceLastPong()
@@ -426,6 +622,14 @@ func printStatus(c *client.Client, cmdRouter interface{ ListCommands() []string
 		fmt.Printf("Last Pong: %v ago\n", lastPong.Round(time.Second))
 		fmt.Printf("Heartbeats Sent: %d\n", metrics.ConnectedClients)
 		fmt.Printf("Registered Commands: %v\n", cmdRouter.ListCommands())
+		if outbox != nil {
+			st := outbox.Stats()
+			fmt.Printf("Spool: depth=%d bytes=%d dropped=%d expired=%d\n", st.Depth, st.Bytes, st.Dropped, st.Expired)
+		}
+		if localTasks != nil {
+			st := localTasks.Status()
+			fmt.Printf("Local Tasks: version=%d tasks=%d running=%d pending_results=%d\n", st.ConfigVersion, st.Tasks, st.Running, st.PendingResults)
+		}
 		if sshEnabled {
 			fmt.Printf("SSH Service: enabled\n")
 		}
@@ -494,7 +698,7 @@ func getCachedHardwareInfo(ctx context.Context, force bool) (*command.HardwareIn
 }
 
 // reportHardwareInfo 上报硬件信息到服务器
-func reportHardwareInfo(c *client.Client, logger *monitoring.Logger) {
+func reportHardwareInfo(c *spooledClient, logger *monitoring.Logger) {
 	// 等待一小段时间确保连接完全建立
 	time.Sleep(500 * time.Millisecond)
 
@@ -532,7 +736,7 @@ func reportHardwareInfo(c *client.Client, logger *monitoring.Logger) {
 	}
 
 	// 发送消息
-	if err := c.SendMessageAsync(msg); err != nil {
+	if _, err := c.SendMessage(context.Background(), msg, false, 0); err != nil {
 		logger.Warn("Failed to report hardware info", "error", err)
 	} else {
 		logger.Info("Hardware info reported to server")
@@ -543,3 +747,21 @@ func reportHardwareInfo(c *client.Client, logger *monitoring.Logger) {
 func generateMsgID() string {
 	return fmt.Sprintf("%d", time.Now().UnixNano())
 }
+
+// newUpdater 根据 --update-public-key 创建升级器，未配置公钥时返回 nil
+// 公钥只能通过命令行指定，远程配置无法替换信任锚
+func newUpdater(logger *monitoring.Logger) (*agentupdate.Updater, error) {
+	if updatePublicKey == "" {
+		return nil, nil
+	}
+	raw, err := fsutil.ReadSecret(updatePublicKey)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := agentupdate.ParsePublicKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Agent self-update enabled")
+	return agentupdate.NewUpdater("", updateDir, pub, logger)
+}
@@ -8,20 +8,33 @@ import (
 	"runtime"
 	"time"
 
+	"github.com/voilet/quic-flow/pkg/agentconfig"
+	"github.com/voilet/quic-flow/pkg/agentupdate"
 	"github.com/voilet/quic-flow/pkg/command"
 	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/plugin"
+	"github.com/voilet/quic-flow/pkg/protocol"
 	"github.com/voilet/quic-flow/pkg/router"
 	"github.com/voilet/quic-flow/pkg/router/handlers"
+	"github.com/voilet/quic-flow/pkg/task/executor"
+	"github.com/voilet/quic-flow/pkg/task/local"
 )
 
 // 客户端版本号
 const ClientVersion = "1.0.0"
 
 // SetupClientRouter 设置客户端路由器
-// 用于处理来自 Server 的命令
-func SetupClientRouter(logger *monitoring.Logger) *router.Router {
+// 用于处理来自 Server 的命令；pluginsDir 不为空时加载其中的插件命令（返回插件管理器），
+// 插件同时作为定时任务的插件执行器；localTasks 不为空时接收服务器推送的本地调度配置
+func SetupClientRouter(logger *monitoring.Logger, cfgManager *agentconfig.Manager, updater *agentupdate.Updater, tasks *executor.Runner, localTasks *local.Scheduler, pluginsDir string) (*router.Router, *plugin.Manager) {
 	r := router.NewRouter(logger)
 
+	var plugins *plugin.Manager
+	if pluginsDir != "" {
+		plugins = plugin.NewManager(pluginsDir, r, logger)
+		tasks.SetExecutor(protocol.ExecutorType_EXECUTOR_TYPE_PLUGIN, executor.PluginExecutor{Plugins: plugins})
+	}
+
 	// ========================================
 	// 添加全局中间件
 	// ========================================
@@ -32,7 +45,12 @@ func SetupClientRouter(logger *monitoring.Logger) *router.Router {
 	// 注册内置处理器
 	// ========================================
 	handlers.RegisterBuiltinHandlers(r, &handlers.Config{
-		Version: ClientVersion,
+		Version:     ClientVersion,
+		AgentConfig: cfgManager, // config.get / config.update
+		Updater:     updater,    // agent.update / agent.update_status
+		Plugins:     plugins,    // plugin.list / plugin.rescan
+		Tasks:       tasks,      // task.execute / task.cancel
+		LocalTasks:  localTasks, // task.config.push
 	})
 
 	// ========================================
@@ -55,9 +73,6 @@ func SetupClientRouter(logger *monitoring.Logger) *router.Router {
 	// file.read - 读取文件
 	r.Register(command.CmdFileRead, handleFileRead)
 
-	// config.update - 更新配置
-	r.Register(command.CmdConfigUpdate, handleConfigUpdate)
-
 	// ========================================
 	// 注册发布系统命令处理器
 	// ========================================
@@ -74,9 +89,18 @@ func SetupClientRouter(logger *monitoring.Logger) *router.Router {
 	// git.versions - 获取 Git 版本信息
 	r.Register(command.CmdGitVersions, handlers.GitVersions)
 
+	// ========================================
+	// 加载插件命令（在内置命令之后，冲突的插件命令不会覆盖内置命令）
+	// ========================================
+	if plugins != nil {
+		if _, err := plugins.Scan(); err != nil {
+			logger.Warn("Failed to scan plugins", "dir", pluginsDir, "error", err)
+		}
+	}
+
 	logger.Info("✅ Client router initialized", "commands", r.ListCommands())
 
-	return r
+	return r, plugins
 }
 
 // ========================================
@@ -159,22 +183,3 @@ func handleFileRead(ctx context.Context, payload json.RawMessage) (json.RawMessa
 		"size":    len(content),
 	})
 }
-
-// handleConfigUpdate 更新配置（示例）
-func handleConfigUpdate(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
-	var params struct {
-		Key   string      `json:"key"`
-		Value interface{} `json:"value"`
-	}
-	if err := json.Unmarshal(payload, &params); err != nil {
-		return nil, fmt.Errorf("invalid params: %w", err)
-	}
-
-	// TODO: 实际的配置更新逻辑
-
-	return json.Marshal(map[string]interface{}{
-		"success": true,
-		"key":     params.Key,
-		"message": "config updated",
-	})
-}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/protocol"
+	"github.com/voilet/quic-flow/pkg/spool"
+	"github.com/voilet/quic-flow/pkg/transport/client"
+)
+
+// spooledClient 出站消息经离线缓存发送的客户端，其余能力与 *client.Client 相同
+// outbox 为 nil（离线缓存未启用）时直接发送
+type spooledClient struct {
+	*client.Client
+	outbox *spool.Outbox
+}
+
+// SendMessage 发送消息，可缓存的消息在链路断开时写入离线缓存
+func (c *spooledClient) SendMessage(ctx context.Context, msg *protocol.DataMessage, waitAck bool, timeout time.Duration) (*protocol.AckMessage, error) {
+	if c.outbox == nil {
+		return c.Client.SendMessage(ctx, msg, waitAck, timeout)
+	}
+	return c.outbox.SendMessage(ctx, msg, waitAck, timeout)
+}
+
+// newOutbox 根据 --spool-* 参数创建离线缓存，目录为空时返回 nil
+func newOutbox(logger *monitoring.Logger, c *client.Client) (*spool.Outbox, error) {
+	if spoolDir == "" {
+		return nil, nil
+	}
+	s, err := spool.Open(spool.Config{
+		Dir:        spoolDir,
+		MaxBytes:   int64(spoolMaxMB) * 1024 * 1024,
+		MaxAge:     spoolMaxAge,
+		DropPolicy: spool.DropPolicy(spoolDropPolicy),
+	}, logger)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Offline spool enabled", "dir", spoolDir, "depth", s.Depth())
+	return spool.NewOutbox(c, s, nil, logger), nil
+}
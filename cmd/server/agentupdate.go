@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/voilet/quic-flow/pkg/agentupdate"
+	"github.com/voilet/quic-flow/pkg/api"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/config"
+	"github.com/voilet/quic-flow/pkg/filetransfer"
+	"github.com/voilet/quic-flow/pkg/fsutil"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/transport/server"
+)
+
+// SetupAgentUpdate 初始化 Agent 自升级：升级包从文件传输存储读取，经 QUIC 流推送给客户端
+func SetupAgentUpdate(httpServer *api.HTTPServer, srv *server.Server, commandManager *command.CommandManager,
+	storage filetransfer.StorageBackend, settings config.AgentUpdateSettings, logger *monitoring.Logger) (*agentupdate.Manager, error) {
+	store, err := agentupdate.NewStore("data/agent-updates")
+	if err != nil {
+		return nil, err
+	}
+
+	push := func(ctx context.Context, clientID, updateID, name string, size int64, checksum string, r io.Reader) error {
+		conn := srv.GetClientConnection(clientID)
+		if conn == nil {
+			return fmt.Errorf("client %s is not connected", clientID)
+		}
+		return agentupdate.PushToConn(ctx, conn, updateID, name, size, checksum, r)
+	}
+	manager := agentupdate.NewManager(store, storage, commandManager, push, logger)
+
+	if settings.SigningKey != "" {
+		raw, err := fsutil.ReadSecret(settings.SigningKey)
+		if err != nil {
+			return nil, fmt.Errorf("load signing key: %w", err)
+		}
+		key, err := agentupdate.ParsePrivateKey(raw)
+		if err != nil {
+			return nil, err
+		}
+		manager.SetSigner(key)
+	}
+
+	manager.Recover()
+	httpServer.AddAgentUpdateRoutes(manager)
+	return manager, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/voilet/quic-flow/pkg/config"
+	"github.com/voilet/quic-flow/pkg/eventbus"
+	"github.com/voilet/quic-flow/pkg/localapi"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/router"
+	"github.com/voilet/quic-flow/pkg/transport/server"
+)
+
+// appEventTopicPrefix 本机应用事件在事件总线上的主题前缀（app.<event>）
+const appEventTopicPrefix = "app."
+
+// SetupEventBus 初始化主题事件：Agent 通过 event.publish / event.subscribe 发布与订阅，
+// 服务器侧代码与 HTTP/WebSocket 订阅者直接使用事件总线；
+// 本机应用经 Agent 本地 API 上报的 report.event 以 app.<event> 主题发布到总线
+func SetupEventBus(msgRouter *router.Router, srv *server.Server, settings config.EventSettings, logger *monitoring.Logger) (*eventbus.Bus, *eventbus.Relay) {
+	bus := eventbus.NewBus(settings.Retention, logger)
+	relay := eventbus.NewRelay(bus, srv, logger)
+	relay.Register(msgRouter)
+	registerAppEvents(msgRouter, bus, logger)
+	logger.Info("Event bus initialized", "retention", settings.Retention)
+	return bus, relay
+}
+
+// appEventData 本机应用事件发布到总线的数据
+type appEventData struct {
+	ClientID  string          `json:"client_id"`
+	Source    string          `json:"source,omitempty"`
+	Event     string          `json:"event"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Timestamp int64           `json:"timestamp,omitempty"`
+}
+
+// registerAppEvents 注册 report.event：记录本机应用上报的事件并发布到事件总线
+// 客户端 ID 取自连接会话，不信任载荷中的字段
+func registerAppEvents(msgRouter *router.Router, bus *eventbus.Bus, logger *monitoring.Logger) {
+	msgRouter.Register(localapi.CmdReportEvent, func(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+		var event localapi.AppEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("invalid app event: %w", err)
+		}
+		if event.Event == "" {
+			return nil, fmt.Errorf("invalid app event: event is required")
+		}
+		clientID := router.GetClientID(ctx)
+		if clientID == "" {
+			return nil, fmt.Errorf("invalid app event: sender is unknown")
+		}
+
+		logger.Info("App event received",
+			"client_id", clientID,
+			"source", event.Source,
+			"event", event.Event,
+		)
+		data, err := json.Marshal(appEventData{
+			ClientID:  clientID,
+			Source:    event.Source,
+			Event:     event.Event,
+			Data:      event.Data,
+			Timestamp: event.Timestamp,
+		})
+		if err != nil {
+			return nil, err
+		}
+		published, err := bus.Publish(appEventTopicPrefix+event.Event, clientID, data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid app event: %w", err)
+		}
+		return json.Marshal(map[string]interface{}{
+			"received": true,
+			"id":       published.ID,
+		})
+	})
+}
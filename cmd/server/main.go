@@ -11,12 +11,15 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/voilet/quic-flow/pkg/agentupdate"
 	"github.com/voilet/quic-flow/pkg/api"
 	"github.com/voilet/quic-flow/pkg/auth"
 	"github.com/voilet/quic-flow/pkg/auth/captcha"
 	"github.com/voilet/quic-flow/pkg/auth/middleware"
 	"github.com/voilet/quic-flow/pkg/audit"
 	"github.com/voilet/quic-flow/pkg/batch"
+	"github.com/voilet/quic-flow/pkg/calendar"
+	"github.com/voilet/quic-flow/pkg/cmdschedule"
 	"github.com/voilet/quic-flow/pkg/command"
 	"github.com/voilet/quic-flow/pkg/config"
 	"github.com/voilet/quic-flow/pkg/dispatcher"
@@ -24,10 +27,12 @@ import (
 	"github.com/voilet/quic-flow/pkg/monitoring"
 	"github.com/voilet/quic-flow/pkg/profiling"
 	"github.com/voilet/quic-flow/pkg/protocol"
+	"github.com/voilet/quic-flow/pkg/query"
 	"github.com/voilet/quic-flow/pkg/recording"
 	releaseapi "github.com/voilet/quic-flow/pkg/release/api"
 	releasemodels "github.com/voilet/quic-flow/pkg/release/models"
 	"github.com/voilet/quic-flow/pkg/router"
+	"github.com/voilet/quic-flow/pkg/runbook"
 	"github.com/voilet/quic-flow/pkg/task/scheduler"
 	"github.com/voilet/quic-flow/pkg/task/store"
 	"github.com/voilet/quic-flow/pkg/transport/server"
@@ -160,8 +165,14 @@ func runServer(cmd *cobra.Command, args []string) {
 	// 设置消息路由器
 	msgRouter := SetupServerRouter(logger)
 
+	// 主题事件总线（Agent 发布/订阅）
+	eventBus, eventRelay := SetupEventBus(msgRouter, srv, cfg.Events, logger)
+
+	// Agent 查询路由器（QUERY 消息，结果通过 Ack 返回）
+	queryRouter := SetupQueryRouter(eventBus, logger)
+
 	// 创建 Dispatcher 并注册消息处理器
-	disp := setupServerDispatcherWithConfig(logger, msgRouter,
+	disp := setupServerDispatcherWithConfig(logger, msgRouter, queryRouter,
 		cfg.Message.WorkerCount,
 		cfg.Message.TaskQueueSize,
 		cfg.GetHandlerTimeout())
@@ -208,6 +219,10 @@ func runServer(cmd *cobra.Command, args []string) {
 	// 启动 HTTP API 服务器
 	httpServer := api.NewHTTPServer(cfg.Server.APIAddr, srv, commandManager, logger)
 
+	// Prometheus 指标（任务系统初始化后注册任务健康指标）
+	metricsHandler := monitoring.NewSnapshotPrometheusHandler(srv.GetMetrics, "")
+	httpServer.AddMetricsRoute(metricsHandler)
+
 	// 添加数据库初始化引导 API
 	setupAPI := api.NewSetupAPI(configFile, logger)
 	httpServer.AddSetupRoutes(setupAPI)
@@ -242,12 +257,49 @@ func runServer(cmd *cobra.Command, args []string) {
 
 	// 添加批量执行 API
 	if batchExecutor != nil {
+		if releaseDB != nil {
+			setupBatchStore(batchExecutor, releaseDB, cfg.Batch.ResumeOnStartup, logger)
+		}
 		httpServer.AddBatchRoutes(batchExecutor)
 	}
 
 	// 添加流式 API（SSE）
 	httpServer.AddStreamRoutes()
 
+	// 添加主题事件 API（HTTP 拉取、WebSocket 订阅、服务器发布）
+	httpServer.AddEventRoutes(eventBus, eventRelay)
+
+	// 添加网络诊断 API（多 Agent 探测延迟矩阵）
+	httpServer.AddNetworkRoutes()
+
+	// 添加 Agent 配置管理 API（按客户端/标签批量下发）
+	httpServer.AddConfigRoutes()
+
+	// 添加命令模板与 Runbook API
+	runbookStore, err := runbook.NewStore("data/runbooks")
+	if err != nil {
+		logger.Error("Failed to create runbook store", "error", err)
+		os.Exit(1)
+	}
+	httpServer.AddRunbookRoutes(runbookStore)
+
+	// 添加定时/周期命令 API
+	scheduleStore, err := cmdschedule.NewStore("data/schedules")
+	if err != nil {
+		logger.Error("Failed to create schedule store", "error", err)
+		os.Exit(1)
+	}
+	commandScheduler := httpServer.AddScheduleRoutes(scheduleStore, batchExecutor)
+	commandScheduler.Start()
+
+	// 添加禁止窗口 API（定时任务与发布在窗口内被阻止）
+	blackouts, err := calendar.NewStore("data/calendar")
+	if err != nil {
+		logger.Error("Failed to create calendar store", "error", err)
+		os.Exit(1)
+	}
+	httpServer.AddCalendarRoutes(blackouts)
+
 	// 创建 SSH 客户端管理器
 	sshManager := NewSSHClientManager(srv, nil, logger)
 	sshAPIAdapter := NewSSHClientManagerAPIAdapter(sshManager)
@@ -318,6 +370,7 @@ func runServer(cmd *cobra.Command, args []string) {
 
 	// 添加发布系统 API 路由
 	releaseAPI := releaseapi.NewReleaseAPIWithRemote(releaseDB, commandManager)
+	releaseAPI.SetCalendar(blackouts)
 	httpServer.AddReleaseRoutes(releaseAPI)
 
 	// ========== 硬件信息功能 ==========
@@ -376,14 +429,34 @@ func runServer(cmd *cobra.Command, args []string) {
 	logger.Info("Standard pprof enabled at /debug/pprof/ (use 'go tool pprof http://host:port/debug/pprof/profile?seconds=30')")
 
 	// ========== 文件传输功能 ==========
-	_, _, _, fileAPI := SetupFileTransfer(releaseDB, logger)
+	fileManager, _, _, fileAPI := SetupFileTransfer(releaseDB, logger)
 	if fileAPI != nil {
 		AddFileTransferRoutes(httpServer, fileAPI)
 		logger.Info("File transfer system enabled")
 	}
+	// 任务执行日志转存到文件传输存储
+	var taskLogStorage scheduler.LogStorage
+	if fileManager != nil {
+		taskLogStorage = fileManager.Storage()
+	}
+
+	// ========== Agent 自升级（升级包存放在文件传输存储中）==========
+	var agentUpdateManager *agentupdate.Manager
+	if fileManager != nil {
+		agentUpdateManager, err = SetupAgentUpdate(httpServer, srv, commandManager, fileManager.Storage(), cfg.AgentUpdate, logger)
+		if err != nil {
+			logger.Error("Failed to setup agent update", "error", err)
+			os.Exit(1)
+		}
+		registerAgentUpdateQueries(queryRouter, agentUpdateManager)
+		logger.Info("Agent self-update enabled")
+	} else {
+		logger.Warn("Agent self-update disabled (file transfer not available)")
+	}
 
 	// ========== 任务管理系统变量（需要在回调中使用）==========
 	var taskManager *scheduler.TaskManager
+	var workflowManager *scheduler.WorkflowManager
 	var taskWSAPI *api.TaskWSAPI
 
 	// 设置数据库初始化回调，当通过 setup 页面初始化数据库后更新 release API 和 audit_store
@@ -394,6 +467,11 @@ func runServer(cmd *cobra.Command, args []string) {
 		releaseAPI.SetRemoteExecutor(commandManager)
 		logger.Info("Release API database updated via setup")
 
+		// 批量任务持久化
+		if batchExecutor != nil {
+			setupBatchStore(batchExecutor, db, cfg.Batch.ResumeOnStartup, logger)
+		}
+
 		// 创建 PostgreSQL audit store 并更新相关组件
 		newAuditStore, err := audit.NewPostgresStore(db)
 		if err != nil {
@@ -452,7 +530,7 @@ func runServer(cmd *cobra.Command, args []string) {
 		// 初始化任务管理系统（如果尚未初始化）
 		if taskManager == nil {
 			var err error
-			taskManager, taskWSAPI, err = SetupTaskSystem(db, srv, disp, srv.GetSessions(), logger)
+			taskManager, workflowManager, taskWSAPI, err = SetupTaskSystem(db, srv, msgRouter, queryRouter, srv.GetSessions(), taskLogStorage, httpServer.TaskTargetResolver(), blackouts, logger)
 			if err != nil {
 				logger.Error("Failed to setup task system via setup", "error", err)
 			} else if taskManager != nil {
@@ -460,9 +538,13 @@ func runServer(cmd *cobra.Command, args []string) {
 				taskStore := store.NewTaskStore(db)
 				executionStore := store.NewExecutionStore(db)
 				groupStore := store.NewGroupStore(db)
+				dailyStatsStore := store.NewDailyStatsStore(db)
+				workflowStore := store.NewWorkflowStore(db)
 
 				// 添加任务管理 API 路由
-				AddTaskRoutes(httpServer, taskManager, taskStore, executionStore, groupStore, taskWSAPI, logger)
+				AddTaskRoutes(httpServer, taskManager, workflowManager, taskStore, executionStore, groupStore, dailyStatsStore, workflowStore, taskWSAPI, logger)
+				metricsHandler.AddCollector(taskManager.MetricsCollector())
+				SetupTaskSync(taskManager, cfg.TaskSync, logger)
 
 				logger.Info("Task management system enabled via setup")
 			}
@@ -480,13 +562,11 @@ func runServer(cmd *cobra.Command, args []string) {
 	if releaseDB != nil {
 		logger.Info("Initializing task management system...", "database_available", true, "releaseDB", releaseDB != nil)
 		var err error
-		// 获取 dispatcher 和 session manager
-		if disp == nil {
-			logger.Error("Dispatcher is nil, cannot setup task system")
-		} else if srv.GetSessions() == nil {
+		// 获取 session manager
+		if srv.GetSessions() == nil {
 			logger.Error("Session manager is nil, cannot setup task system")
 		} else {
-			taskManager, taskWSAPI, err = SetupTaskSystem(releaseDB, srv, disp, srv.GetSessions(), logger)
+			taskManager, workflowManager, taskWSAPI, err = SetupTaskSystem(releaseDB, srv, msgRouter, queryRouter, srv.GetSessions(), taskLogStorage, httpServer.TaskTargetResolver(), blackouts, logger)
 			if err != nil {
 				logger.Error("Failed to setup task system", "error", err)
 			} else if taskManager != nil {
@@ -494,10 +574,14 @@ func runServer(cmd *cobra.Command, args []string) {
 				taskStore := store.NewTaskStore(releaseDB)
 				executionStore := store.NewExecutionStore(releaseDB)
 				groupStore := store.NewGroupStore(releaseDB)
+				dailyStatsStore := store.NewDailyStatsStore(releaseDB)
+				workflowStore := store.NewWorkflowStore(releaseDB)
 
 				// 添加任务管理 API 路由（在 Start 之前）
 				logger.Info("Registering task management routes...")
-				AddTaskRoutes(httpServer, taskManager, taskStore, executionStore, groupStore, taskWSAPI, logger)
+				AddTaskRoutes(httpServer, taskManager, workflowManager, taskStore, executionStore, groupStore, dailyStatsStore, workflowStore, taskWSAPI, logger)
+				metricsHandler.AddCollector(taskManager.MetricsCollector())
+				SetupTaskSync(taskManager, cfg.TaskSync, logger)
 
 				logger.Info("Task management system enabled and routes registered")
 			} else {
@@ -542,6 +626,11 @@ func runServer(cmd *cobra.Command, args []string) {
 	<-sigChan
 
 	// 优雅关闭
+	commandScheduler.Stop()
+	eventRelay.Stop()
+	if agentUpdateManager != nil {
+		agentUpdateManager.Stop()
+	}
 	if batchExecutor != nil {
 		batchExecutor.Stop()
 	}
@@ -607,7 +696,7 @@ func buildServerConfig(cfg *config.ServerConfig, logger *monitoring.Logger) *ser
 }
 
 // setupServerDispatcherWithConfig 使用自定义配置设置服务器消息分发器
-func setupServerDispatcherWithConfig(logger *monitoring.Logger, msgRouter *router.Router, workerCount int, queueSize int, timeout time.Duration) *dispatcher.Dispatcher {
+func setupServerDispatcherWithConfig(logger *monitoring.Logger, msgRouter *router.Router, queryRouter *query.Router, workerCount int, queueSize int, timeout time.Duration) *dispatcher.Dispatcher {
 	dispatcherConfig := &dispatcher.DispatcherConfig{
 		WorkerCount:    workerCount,
 		TaskQueueSize:  queueSize,
@@ -629,7 +718,8 @@ func setupServerDispatcherWithConfig(logger *monitoring.Logger, msgRouter *route
 			cmdPayload.Payload = msg.Payload
 		}
 
-		// 使用路由器执行
+		// 使用路由器执行（发送方取自连接会话，传输层已用会话的客户端 ID 覆盖 SenderId）
+		ctx = router.WithCommandContext(ctx, cmdPayload.CommandType, msg.MsgId, msg.SenderId)
 		result, err := msgRouter.ExecuteWithContext(ctx, cmdPayload.CommandType, cmdPayload.Payload)
 		if err != nil {
 			return nil, err
@@ -646,8 +736,10 @@ func setupServerDispatcherWithConfig(logger *monitoring.Logger, msgRouter *route
 	}
 
 	// 注册消息类型处理器
-	disp.RegisterHandler(protocol.MessageType_MESSAGE_TYPE_EVENT, dispatcher.MessageHandlerFunc(routeHandler))
-	disp.RegisterHandler(protocol.MessageType_MESSAGE_TYPE_QUERY, dispatcher.MessageHandlerFunc(routeHandler))
+	// Agent 离线缓存的事件与上报在重连后重放，按 msg_id 去重
+	dedup := dispatcher.NewDeduplicator(dispatcher.DefaultDedupWindow, dispatcher.DefaultDedupMaxEntries)
+	disp.RegisterHandler(protocol.MessageType_MESSAGE_TYPE_EVENT, dedup.Wrap(dispatcher.MessageHandlerFunc(routeHandler)))
+	disp.RegisterHandler(protocol.MessageType_MESSAGE_TYPE_QUERY, dispatcher.MessageHandlerFunc(queryRouter.HandleMessage))
 	disp.RegisterHandler(protocol.MessageType_MESSAGE_TYPE_RESPONSE, dispatcher.MessageHandlerFunc(func(ctx context.Context, msg *protocol.DataMessage) (*protocol.DataMessage, error) {
 		logger.Info("Received response from client", "msg_id", msg.MsgId, "sender", msg.SenderId)
 		return nil, nil
@@ -777,6 +869,23 @@ func initDatabase(cfg *config.ServerConfig, logger *monitoring.Logger) (*gorm.DB
 	return db, nil
 }
 
+// setupBatchStore 为批量执行器启用数据库持久化，并恢复重启前未结束的任务
+func setupBatchStore(executor *batch.BatchExecutor, db *gorm.DB, resume bool, logger *monitoring.Logger) {
+	store, err := batch.NewDBStore(db)
+	if err != nil {
+		logger.Error("Failed to create batch job store", "error", err)
+		return
+	}
+	executor.SetStore(store)
+
+	recovered, err := executor.Recover(resume)
+	if err != nil {
+		logger.Error("Failed to recover batch jobs", "error", err)
+		return
+	}
+	logger.Info("Batch job persistence enabled", "recovered_jobs", recovered, "resume_on_startup", resume)
+}
+
 // initAuthSystem 初始化权限系统
 func initAuthSystem(db *gorm.DB) error {
 	fmt.Println("=== 初始化权限系统 ===")
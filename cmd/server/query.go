@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/agentupdate"
+	"github.com/voilet/quic-flow/pkg/eventbus"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/query"
+	"github.com/voilet/quic-flow/pkg/version"
+)
+
+// ServerInfo server.info 查询结果
+type ServerInfo struct {
+	ClientID   string `json:"client_id"` // 服务器识别到的客户端 ID
+	ServerTime int64  `json:"server_time"`
+	Version    string `json:"version"`
+}
+
+// RecentEventsQuery event.recent 查询参数
+type RecentEventsQuery struct {
+	Pattern string `json:"pattern"`
+	After   uint64 `json:"after,omitempty"`
+	Limit   int    `json:"limit,omitempty"`
+}
+
+// AgentArtifactsQuery agent.update.artifacts 查询参数（为空时不过滤）
+type AgentArtifactsQuery struct {
+	OS   string `json:"os,omitempty"`
+	Arch string `json:"arch,omitempty"`
+}
+
+// SetupQueryRouter 创建 Agent 查询路由器并注册内置查询
+// 依赖后续初始化的组件（如自升级）的查询在组件就绪后注册
+func SetupQueryRouter(bus *eventbus.Bus, logger *monitoring.Logger) *query.Router {
+	r := query.NewRouter(logger)
+	r.Use(query.RecoveryMiddleware(logger))
+	r.Use(query.LoggingMiddleware(logger))
+
+	// server.info - 服务器时间与版本，Agent 可用于校时与兼容性判断
+	r.Register("server.info", query.Typed(func(ctx context.Context, clientID string, _ struct{}) (*ServerInfo, error) {
+		return &ServerInfo{ClientID: clientID, ServerTime: time.Now().UnixMilli(), Version: version.String()}, nil
+	}))
+
+	// event.recent - 拉取服务器总线上保留的主题事件（如最近一次配置变更）
+	r.Register("event.recent", query.Typed(func(ctx context.Context, clientID string, req RecentEventsQuery) ([]*eventbus.Event, error) {
+		if req.Pattern == "" {
+			return nil, fmt.Errorf("pattern is required")
+		}
+		if req.Limit <= 0 || req.Limit > 1000 {
+			req.Limit = 100
+		}
+		return bus.Recent(req.Pattern, req.After, req.Limit)
+	}))
+
+	return r
+}
+
+// registerAgentUpdateQueries 注册自升级相关查询
+func registerAgentUpdateQueries(r *query.Router, manager *agentupdate.Manager) {
+	// agent.update.artifacts - 已登记的 Agent 升级包清单（版本、平台、摘要与签名）
+	r.Register("agent.update.artifacts", query.Typed(func(ctx context.Context, clientID string, req AgentArtifactsQuery) ([]*agentupdate.Artifact, error) {
+		var out []*agentupdate.Artifact
+		for _, a := range manager.ListArtifacts() {
+			if (req.OS == "" || a.OS == req.OS) && (req.Arch == "" || a.Arch == req.Arch) {
+				out = append(out, a)
+			}
+		}
+		return out, nil
+	}))
+}
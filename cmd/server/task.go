@@ -2,10 +2,15 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/voilet/quic-flow/pkg/api"
-	"github.com/voilet/quic-flow/pkg/dispatcher"
+	"github.com/voilet/quic-flow/pkg/calendar"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/protocol"
+	"github.com/voilet/quic-flow/pkg/query"
+	"github.com/voilet/quic-flow/pkg/router"
 	"github.com/voilet/quic-flow/pkg/session"
 	"github.com/voilet/quic-flow/pkg/task/models"
 	"github.com/voilet/quic-flow/pkg/task/scheduler"
@@ -17,21 +22,29 @@ import (
 )
 
 // SetupTaskSystem 初始化任务管理系统
+// 任务经 srv 下发到客户端，客户端上报的进度与结果由 msgRouter 上的 task.progress/task.result 处理；
+// Agent 本地调度的配置拉取与结果同步由 queryRouter 上的查询处理；
+// 工作流管理器在节点执行结束时推进工作流运行；logStorage 非空时较大的执行输出转存到文件存储；
+// resolver 提供动态分组筛选客户端所需的硬件信息、Agent 标签与容器；定时触发落在 blackouts 的禁止窗口内时跳过
 func SetupTaskSystem(
 	db *gorm.DB,
 	srv *server.Server,
-	disp *dispatcher.Dispatcher,
+	msgRouter *router.Router,
+	queryRouter *query.Router,
 	sessionMgr *session.SessionManager,
+	logStorage scheduler.LogStorage,
+	resolver scheduler.TargetResolver,
+	blackouts *calendar.Store,
 	logger *monitoring.Logger,
-) (*scheduler.TaskManager, *api.TaskWSAPI, error) {
+) (*scheduler.TaskManager, *scheduler.WorkflowManager, *api.TaskWSAPI, error) {
 	if db == nil {
 		logger.Warn("Database not available, task system will be disabled")
-		return nil, nil, nil
+		return nil, nil, nil, nil
 	}
 
 	// 执行数据库迁移
 	if err := models.Migrate(db); err != nil {
-		return nil, nil, fmt.Errorf("failed to migrate task models: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to migrate task models: %w", err)
 	}
 	logger.Info("Task models migrated")
 
@@ -40,18 +53,30 @@ func SetupTaskSystem(
 	executionStore := store.NewExecutionStore(db)
 
 	// 创建任务分发器
-	if disp == nil {
-		return nil, nil, fmt.Errorf("dispatcher not available")
+	if srv == nil || msgRouter == nil || queryRouter == nil {
+		return nil, nil, nil, fmt.Errorf("server not available")
 	}
 	if sessionMgr == nil {
-		return nil, nil, fmt.Errorf("session manager not available")
+		return nil, nil, nil, fmt.Errorf("session manager not available")
 	}
 
-	taskDispatcher := scheduler.NewTaskDispatcher(disp, sessionMgr, taskStore, logger)
+	// 创建 WebSocket API（推送执行记录更新）
+	wsAPI := api.NewTaskWSAPI(logger)
+
+	taskDispatcher := scheduler.NewTaskDispatcher(srv, sessionMgr, taskStore, executionStore, logger)
+	taskDispatcher.SetExecutionHook(wsAPI.BroadcastExecutionUpdate)
+	taskDispatcher.SetDailyStatsStore(store.NewDailyStatsStore(db))
+	taskDispatcher.SetLogHook(wsAPI.BroadcastExecutionLog)
+	taskDispatcher.SetTargetResolver(resolver)
+	if logStorage != nil {
+		taskDispatcher.SetLogStorage(logStorage, scheduler.DefaultLogOffloadSize)
+	}
+	RegisterTaskResultHandler(msgRouter, taskDispatcher, logger)
 	logger.Info("Task dispatcher created")
 
 	// 创建调度器（需要 dispatcher）
 	cronScheduler := scheduler.NewCronScheduler(logger, taskDispatcher)
+	cronScheduler.SetCalendar(blackouts)
 	cronScheduler.Start()
 	logger.Info("Cron scheduler started")
 
@@ -63,6 +88,8 @@ func SetupTaskSystem(
 		executionStore,
 		logger,
 	)
+	wsAPI.SetOutputLoader(taskManager.ExecutionOutput)
+	taskManager.SetGroupStore(store.NewGroupStore(db))
 
 	// 初始化任务管理器（加载所有启用的任务）
 	ctx := context.Background()
@@ -72,20 +99,35 @@ func SetupTaskSystem(
 	} else {
 		logger.Info("Task manager initialized")
 	}
+	registerLocalTaskQueries(queryRouter, taskManager, taskDispatcher)
 
-	// 创建 WebSocket API
-	wsAPI := api.NewTaskWSAPI(logger)
+	// 创建工作流管理器（注册定时运行并继续推进未结束的运行）
+	workflowManager := scheduler.NewWorkflowManager(
+		cronScheduler,
+		taskDispatcher,
+		taskStore,
+		store.NewWorkflowStore(db),
+		executionStore,
+		logger,
+	)
+	workflowManager.SetRunHook(wsAPI.BroadcastWorkflowRunUpdate)
+	if err := workflowManager.Initialize(ctx); err != nil {
+		logger.Warn("Failed to initialize workflow manager", "error", err)
+	}
 
-	return taskManager, wsAPI, nil
+	return taskManager, workflowManager, wsAPI, nil
 }
 
 // AddTaskRoutes 添加任务管理 API 路由
 func AddTaskRoutes(
 	httpServer *api.HTTPServer,
 	taskManager *scheduler.TaskManager,
+	workflowManager *scheduler.WorkflowManager,
 	taskStore store.TaskStore,
 	executionStore store.ExecutionStore,
 	groupStore store.GroupStore,
+	dailyStatsStore store.DailyStatsStore,
+	workflowStore store.WorkflowStore,
 	wsAPI *api.TaskWSAPI,
 	logger *monitoring.Logger,
 ) {
@@ -116,14 +158,24 @@ func AddTaskRoutes(
 
 	// 执行监控 API
 	executionAPI := api.NewExecutionAPI(executionStore, logger)
+	executionAPI.SetDailyStatsStore(dailyStatsStore)
+	executionAPI.SetTaskManager(taskManager)
 	executionAPI.RegisterRoutes(apiGroup)
 	logger.Info("Execution API routes registered", "path", "/api/executions")
 
 	// 分组管理 API
 	groupAPI := api.NewGroupAPI(groupStore, logger)
+	groupAPI.SetTaskManager(taskManager)
 	groupAPI.RegisterRoutes(apiGroup)
 	logger.Info("Group API routes registered", "path", "/api/groups")
 
+	// 任务工作流 API
+	if workflowManager != nil {
+		workflowAPI := api.NewWorkflowAPI(workflowManager, workflowStore, logger)
+		workflowAPI.RegisterRoutes(apiGroup)
+		logger.Info("Workflow API routes registered", "path", "/api/workflows")
+	}
+
 	// WebSocket API
 	if wsAPI != nil {
 		wsAPI.RegisterRoutes(apiGroup)
@@ -133,13 +185,59 @@ func AddTaskRoutes(
 	logger.Info("Task management routes registered successfully")
 }
 
-// RegisterTaskResultHandler 注册任务执行结果处理器到服务器路由
+// RegisterTaskResultHandler 注册客户端任务执行上报的处理器
+// task.progress 更新执行状态并追加输出，task.result 写入最终结果
 func RegisterTaskResultHandler(
-	msgRouter interface{}, // *router.Router
-	executionStore store.ExecutionStore,
+	msgRouter *router.Router,
+	taskDispatcher *scheduler.TaskDispatcher,
 	logger *monitoring.Logger,
 ) {
-	// 这个函数将在 router.go 中实现，用于处理客户端上报的任务执行结果
-	// 由于 router 的类型是 *router.Router，我们需要在 router.go 中实现
-	logger.Info("Task result handler registration (to be implemented in router.go)")
+	msgRouter.Register(command.EventTaskProgress, func(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+		var report command.TaskProgressReport
+		if err := json.Unmarshal(payload, &report); err != nil {
+			return nil, fmt.Errorf("invalid task progress: %w", err)
+		}
+		if err := taskDispatcher.HandleProgress(ctx, &report); err != nil {
+			return nil, err
+		}
+		return json.Marshal(map[string]interface{}{"received": true})
+	})
+
+	msgRouter.Register(command.EventTaskResult, func(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+		var report command.TaskResultReport
+		if err := json.Unmarshal(payload, &report); err != nil {
+			return nil, fmt.Errorf("invalid task result: %w", err)
+		}
+		if err := taskDispatcher.HandleResult(ctx, &report); err != nil {
+			return nil, err
+		}
+		return json.Marshal(map[string]interface{}{"received": true})
+	})
+
+	msgRouter.Register(command.EventTaskStats, func(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+		var report protocol.DailyStatsReport
+		if err := json.Unmarshal(payload, &report); err != nil {
+			return nil, fmt.Errorf("invalid task stats: %w", err)
+		}
+		if err := taskDispatcher.HandleDailyStats(ctx, &report); err != nil {
+			return nil, err
+		}
+		return json.Marshal(map[string]interface{}{"received": true})
+	})
+
+	logger.Info("Task result handlers registered",
+		"commands", []string{command.EventTaskProgress, command.EventTaskResult, command.EventTaskStats})
+}
+
+// registerLocalTaskQueries 注册 Agent 本地调度使用的查询（客户端 ID 取自连接会话）
+func registerLocalTaskQueries(r *query.Router, taskManager *scheduler.TaskManager, taskDispatcher *scheduler.TaskDispatcher) {
+	// task.config.pull - 拉取本地调度任务配置（版本一致时不返回任务列表）
+	r.Register(command.QueryTaskConfigPull, query.Typed(func(ctx context.Context, clientID string, req *protocol.TaskConfigPull) (*protocol.TaskConfigPullResponse, error) {
+		return taskManager.PullConfig(ctx, clientID, req)
+	}))
+
+	// task.offline.sync - 同步本地执行结果到执行记录
+	r.Register(command.QueryTaskOfflineSync, query.Typed(func(ctx context.Context, clientID string, req command.TaskOfflineSync) (*protocol.OfflineSyncResponse, error) {
+		return taskDispatcher.HandleOfflineSync(ctx, clientID, &req)
+	}))
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/config"
+	"github.com/voilet/quic-flow/pkg/git"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/task/scheduler"
+)
+
+// gitBundleSource 从 Git 仓库目录读取任务定义（按文件路径顺序合并）
+type gitBundleSource struct {
+	client   *git.Client
+	settings config.TaskSyncSettings
+}
+
+// Load 实现 scheduler.BundleSource
+func (s *gitBundleSource) Load(ctx context.Context) (*scheduler.Bundle, string, error) {
+	files, err := s.client.FetchFiles(ctx, &git.FetchFilesRequest{
+		RepoURL:    s.settings.RepoURL,
+		Branch:     s.settings.Branch,
+		Path:       s.settings.Path,
+		Extensions: []string{".yaml", ".yml"},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	paths := make([]string, 0, len(files.Files))
+	for path := range files.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	bundle := &scheduler.Bundle{}
+	for _, path := range paths {
+		doc, err := scheduler.ParseBundle(files.Files[path])
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", path, err)
+		}
+		bundle.Merge(doc)
+	}
+	return bundle, files.Commit, nil
+}
+
+// SetupTaskSync 配置了仓库地址时定期从 Git 仓库同步任务定义
+func SetupTaskSync(taskManager *scheduler.TaskManager, settings config.TaskSyncSettings, logger *monitoring.Logger) {
+	if settings.RepoURL == "" {
+		return
+	}
+	source := &gitBundleSource{
+		client:   git.NewClientWithAuth(settings.AuthType, settings.SSHKey, settings.Token, settings.Username, settings.Password),
+		settings: settings,
+	}
+	interval := time.Duration(settings.Interval) * time.Second
+	if err := taskManager.EnableBundleSync(source, interval, settings.Prune); err != nil {
+		logger.Error("Failed to enable task bundle sync", "error", err)
+		return
+	}
+	logger.Info("Task bundle sync enabled",
+		"repo", settings.RepoURL,
+		"branch", settings.Branch,
+		"path", settings.Path,
+		"interval", interval,
+		"prune", settings.Prune)
+}
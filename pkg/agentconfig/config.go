@@ -0,0 +1,196 @@
+package agentconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 日志级别
+var validLogLevels = map[string]bool{
+	"DEBUG": true,
+	"INFO":  true,
+	"WARN":  true,
+	"ERROR": true,
+}
+
+// labelKeyRe 标签键格式（与 k8s label key 的常用子集一致）
+var labelKeyRe = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]{0,62}[A-Za-z0-9])?$`)
+
+// Config Agent 配置
+// 标记为 hot 的字段修改后立即生效，其余字段需要重启 Agent
+type Config struct {
+	ClientID             string            `json:"client_id"`              // 客户端 ID（重启生效）
+	ServerAddr           string            `json:"server_addr"`            // 服务器地址（重启生效）
+	Insecure             bool              `json:"insecure"`               // 跳过 TLS 证书验证（重启生效）
+	LogFormat            string            `json:"log_format"`             // 日志格式 text/json（重启生效）
+	LogLevel             string            `json:"log_level"`              // 日志级别（hot）
+	HeartbeatIntervalSec int               `json:"heartbeat_interval_sec"` // 心跳间隔（hot）
+	HardwareCacheTTLSec  int               `json:"hardware_cache_ttl_sec"` // 硬件信息缓存时间（hot）
+	StatusIntervalSec    int               `json:"status_interval_sec"`    // 状态打印间隔（hot）
+	RollbackGraceSec     int               `json:"rollback_grace_sec"`     // 变更后等待重连的宽限期（hot）
+	Labels               map[string]string `json:"labels,omitempty"`       // 客户端标签（hot）
+}
+
+// hotFields 支持热加载的字段（JSON 名）
+var hotFields = map[string]bool{
+	"log_level":              true,
+	"heartbeat_interval_sec": true,
+	"hardware_cache_ttl_sec": true,
+	"status_interval_sec":    true,
+	"rollback_grace_sec":     true,
+	"labels":                 true,
+}
+
+// Default 返回默认配置
+func Default() *Config {
+	return &Config{
+		ClientID:             "client-001",
+		ServerAddr:           "localhost:8474",
+		Insecure:             true,
+		LogFormat:            "text",
+		LogLevel:             "INFO",
+		HeartbeatIntervalSec: 15,
+		HardwareCacheTTLSec:  180,
+		StatusIntervalSec:    5,
+		RollbackGraceSec:     60,
+	}
+}
+
+// Load 从 JSON 文件加载配置，未设置的字段使用默认值
+// 文件不存在时返回默认配置
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	if err := decodeStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate 校验配置
+func (c *Config) Validate() error {
+	if c.ClientID == "" {
+		return fmt.Errorf("client_id is required")
+	}
+	if c.ServerAddr == "" {
+		return fmt.Errorf("server_addr is required")
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		return fmt.Errorf("log_format must be text or json, got %q", c.LogFormat)
+	}
+	c.LogLevel = strings.ToUpper(c.LogLevel)
+	if !validLogLevels[c.LogLevel] {
+		return fmt.Errorf("log_level must be one of DEBUG/INFO/WARN/ERROR, got %q", c.LogLevel)
+	}
+	if c.HeartbeatIntervalSec < 1 || c.HeartbeatIntervalSec > 300 {
+		return fmt.Errorf("heartbeat_interval_sec must be between 1 and 300, got %d", c.HeartbeatIntervalSec)
+	}
+	if c.HardwareCacheTTLSec < 0 || c.HardwareCacheTTLSec > 86400 {
+		return fmt.Errorf("hardware_cache_ttl_sec must be between 0 and 86400, got %d", c.HardwareCacheTTLSec)
+	}
+	if c.StatusIntervalSec < 1 || c.StatusIntervalSec > 3600 {
+		return fmt.Errorf("status_interval_sec must be between 1 and 3600, got %d", c.StatusIntervalSec)
+	}
+	if c.RollbackGraceSec < 10 || c.RollbackGraceSec > 3600 {
+		return fmt.Errorf("rollback_grace_sec must be between 10 and 3600, got %d", c.RollbackGraceSec)
+	}
+	for k, v := range c.Labels {
+		if !labelKeyRe.MatchString(k) {
+			return fmt.Errorf("invalid label key: %q", k)
+		}
+		if len(v) > 63 {
+			return fmt.Errorf("label %s value too long (max 63)", k)
+		}
+	}
+	return nil
+}
+
+// Clone 深拷贝配置
+func (c *Config) Clone() *Config {
+	clone := *c
+	if c.Labels != nil {
+		clone.Labels = make(map[string]string, len(c.Labels))
+		for k, v := range c.Labels {
+			clone.Labels[k] = v
+		}
+	}
+	return &clone
+}
+
+// MatchLabels 判断配置中的标签是否包含 selector 的所有键值
+func (c *Config) MatchLabels(selector map[string]string) bool {
+	for k, v := range selector {
+		if c.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Diff 返回 old 与 new 之间发生变化的字段（JSON 名，已排序）
+func Diff(old, new *Config) []string {
+	a, b := toMap(old), toMap(new)
+	var changed []string
+	for k, v := range b {
+		if !jsonEqual(a[k], v) {
+			changed = append(changed, k)
+		}
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// RestartRequired 返回变更字段中需要重启才能生效的字段
+func RestartRequired(changed []string) []string {
+	var fields []string
+	for _, f := range changed {
+		if !hotFields[f] {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// toMap 将配置转换为 JSON 对象
+func toMap(c *Config) map[string]interface{} {
+	data, _ := json.Marshal(c)
+	m := make(map[string]interface{})
+	_ = json.Unmarshal(data, &m)
+	return m
+}
+
+// jsonEqual 比较两个 JSON 值
+func jsonEqual(a, b interface{}) bool {
+	da, _ := json.Marshal(a)
+	db, _ := json.Marshal(b)
+	return string(da) == string(db)
+}
+
+// decodeStrict 解码 JSON，拒绝未知字段
+func decodeStrict(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
@@ -0,0 +1,282 @@
+package agentconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/monitoring"
+)
+
+// Reloader 热加载回调，返回错误时本次变更会被撤销
+type Reloader func(old, new *Config) error
+
+// UpdateOptions 配置更新选项
+type UpdateOptions struct {
+	DryRun      bool          // 仅校验，不应用
+	GracePeriod time.Duration // 自动回滚宽限期，0 表示使用配置中的 rollback_grace_sec
+	NoRollback  bool          // 不启用自动回滚
+}
+
+// UpdateResult 配置更新结果
+type UpdateResult struct {
+	Config          *Config   // 更新后的配置
+	Changed         []string  // 变更的字段
+	RestartRequired []string  // 需要重启才能生效的字段
+	Revision        int64     // 配置版本（每次变更递增）
+	Persisted       bool      // 是否已写入配置文件
+	RollbackAt      time.Time // 未重连时的自动回滚时间（零值表示未启用）
+}
+
+// Manager Agent 配置管理器
+// 负责校验、持久化、热加载，以及变更后连接失败时的自动回滚；
+// 需要重启的变更另外记录在配置文件旁，重启后由 CheckPending 确认或恢复
+type Manager struct {
+	path      string
+	logger    *monitoring.Logger
+	reloader  Reloader
+	connected func() bool
+	restart   func() error
+
+	mu        sync.Mutex
+	current   *Config
+	confirmed *Config // 最后一次确认可用的配置（回滚目标）
+	revision  int64
+	timer     *time.Timer
+}
+
+// NewManager 创建配置管理器
+// path 为空时不持久化
+func NewManager(path string, cfg *Config, logger *monitoring.Logger) *Manager {
+	if cfg == nil {
+		cfg = Default()
+	}
+	if logger == nil {
+		logger = monitoring.NewDefaultLogger()
+	}
+	return &Manager{
+		path:      path,
+		logger:    logger,
+		current:   cfg.Clone(),
+		confirmed: cfg.Clone(),
+	}
+}
+
+// SetReloader 设置热加载回调
+func (m *Manager) SetReloader(r Reloader) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reloader = r
+}
+
+// SetConnectedFunc 设置连接状态检查函数（用于自动回滚判断）
+func (m *Manager) SetConnectedFunc(fn func() bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.connected = fn
+}
+
+// Path 返回配置文件路径
+func (m *Manager) Path() string {
+	return m.path
+}
+
+// Get 返回当前生效配置的副本
+func (m *Manager) Get() *Config {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.current.Clone()
+}
+
+// Revision 返回当前配置版本
+func (m *Manager) Revision() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.revision
+}
+
+// PendingRollback 是否有等待确认的变更
+func (m *Manager) PendingRollback() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.timer != nil
+}
+
+// Update 应用 merge patch
+func (m *Manager) Update(patch json.RawMessage, opts UpdateOptions) (*UpdateResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	next, err := ApplyPatch(m.current, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := Diff(m.current, next)
+	result := &UpdateResult{
+		Config:          next,
+		Changed:         changed,
+		RestartRequired: RestartRequired(changed),
+		Revision:        m.revision,
+	}
+	if opts.DryRun || len(changed) == 0 {
+		return result, nil
+	}
+
+	rollback := !opts.NoRollback && m.connected != nil
+	grace := opts.GracePeriod
+	if grace <= 0 {
+		grace = time.Duration(next.RollbackGraceSec) * time.Second
+	}
+
+	// 需要重启的字段在当前连接上无法验证：记录变更前的配置，重启后由 CheckPending 确认或恢复
+	var pending *pendingState
+	if rollback && len(result.RestartRequired) > 0 {
+		if pending, err = m.loadPending(); err != nil {
+			return nil, fmt.Errorf("read pending config marker: %w", err)
+		}
+		if err := m.savePending(m.revision+1, result.RestartRequired, grace); err != nil {
+			return nil, fmt.Errorf("save pending config marker: %w", err)
+		}
+	}
+
+	old := m.current
+	if err := m.apply(old, next); err != nil {
+		if rollback && len(result.RestartRequired) > 0 && pending == nil {
+			m.removePending()
+		}
+		return nil, err
+	}
+	m.revision++
+	result.Revision = m.revision
+	result.Persisted = m.path != ""
+
+	// 启动自动回滚计时：宽限期结束时仍未连接则回滚到最后确认的配置
+	if rollback {
+		if m.timer != nil {
+			m.timer.Stop()
+		}
+		revision := m.revision
+		m.timer = time.AfterFunc(grace, func() { m.checkRollback(revision) })
+		result.RollbackAt = time.Now().Add(grace)
+	} else {
+		m.confirmed = next.Clone()
+	}
+
+	m.logger.Info("Agent config updated",
+		"revision", m.revision,
+		"changed", changed,
+		"restart_required", result.RestartRequired,
+	)
+	return result, nil
+}
+
+// Confirm 确认当前配置可用，取消待执行的回滚
+func (m *Manager) Confirm() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+	m.confirmed = m.current.Clone()
+}
+
+// Stop 停止回滚计时
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+}
+
+// checkRollback 宽限期结束后检查连接状态
+func (m *Manager) checkRollback(revision int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 已被后续变更取代或已确认
+	if revision != m.revision || m.timer == nil {
+		return
+	}
+	m.timer = nil
+
+	// 需要重启的字段仍由待确认记录保护，当前连接只能确认可热加载的字段
+	pending, err := m.loadPending()
+	if err != nil {
+		m.logger.Warn("Failed to read pending config marker", "error", err)
+	}
+	if m.connected() {
+		m.confirmed = m.current.Clone()
+		if pending != nil {
+			m.logger.Info("Agent config confirmed, restart required to verify",
+				"revision", m.revision,
+				"restart_required", pending.RestartRequired,
+			)
+			return
+		}
+		m.logger.Info("Agent config confirmed", "revision", m.revision)
+		return
+	}
+
+	m.logger.Warn("Agent not connected after config change, rolling back", "revision", m.revision)
+	target := m.confirmed.Clone()
+	if pending != nil {
+		target = pending.Previous.Clone()
+	}
+	if err := m.apply(m.current, target); err != nil {
+		m.logger.Error("Failed to roll back agent config", "error", err)
+		return
+	}
+	m.removePending()
+	m.confirmed = target
+	m.revision++
+}
+
+// apply 持久化并热加载配置，失败时恢复原配置
+// 调用方需持有锁
+func (m *Manager) apply(old, next *Config) error {
+	if err := m.persist(next); err != nil {
+		return err
+	}
+	if m.reloader != nil {
+		if err := m.reloader(old.Clone(), next.Clone()); err != nil {
+			// 恢复文件和已生效的部分
+			if perr := m.persist(old); perr != nil {
+				m.logger.Error("Failed to restore agent config file", "error", perr)
+			}
+			_ = m.reloader(next.Clone(), old.Clone())
+			return fmt.Errorf("reload config: %w", err)
+		}
+	}
+	m.current = next.Clone()
+	return nil
+}
+
+// persist 原子写入配置文件
+func (m *Manager) persist(cfg *Config) error {
+	if m.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package agentconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPatch(t *testing.T) {
+	base := Default()
+	base.Labels = map[string]string{"env": "prod", "zone": "a"}
+
+	cfg, err := ApplyPatch(base, json.RawMessage(`{"log_level":"debug","labels":{"zone":null,"role":"web"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "DEBUG", cfg.LogLevel)
+	assert.Equal(t, map[string]string{"env": "prod", "role": "web"}, cfg.Labels)
+	assert.Equal(t, []string{"labels", "log_level"}, Diff(base, cfg))
+
+	// null 恢复默认值
+	base.HeartbeatIntervalSec = 30
+	cfg, err = ApplyPatch(base, json.RawMessage(`{"heartbeat_interval_sec":null}`))
+	require.NoError(t, err)
+	assert.Equal(t, Default().HeartbeatIntervalSec, cfg.HeartbeatIntervalSec)
+
+	_, err = ApplyPatch(base, json.RawMessage(`{"unknown_field":1}`))
+	assert.Error(t, err)
+	_, err = ApplyPatch(base, json.RawMessage(`{"heartbeat_interval_sec":0}`))
+	assert.Error(t, err)
+	_, err = ApplyPatch(base, json.RawMessage(`{"heartbeat_interval_sec":"15"}`))
+	assert.Error(t, err)
+	_, err = ApplyPatch(base, json.RawMessage(`[1]`))
+	assert.Error(t, err)
+}
+
+func TestKeyPatchAndLookup(t *testing.T) {
+	patch, err := KeyPatch("labels.env", "dev")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"labels":{"env":"dev"}}`, string(patch))
+
+	_, err = KeyPatch("labels..env", "dev")
+	assert.Error(t, err)
+
+	cfg := Default()
+	cfg.Labels = map[string]string{"env": "dev"}
+	v, ok := Lookup(cfg, "labels.env")
+	assert.True(t, ok)
+	assert.Equal(t, "dev", v)
+	_, ok = Lookup(cfg, "missing")
+	assert.False(t, ok)
+}
+
+func TestRestartRequired(t *testing.T) {
+	assert.Equal(t, []string{"server_addr"}, RestartRequired([]string{"log_level", "server_addr"}))
+}
+
+func TestManagerUpdatePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client.json")
+	m := NewManager(path, Default(), nil)
+
+	var reloaded *Config
+	m.SetReloader(func(old, new *Config) error {
+		reloaded = new
+		return nil
+	})
+
+	res, err := m.Update(json.RawMessage(`{"log_level":"WARN","server_addr":"10.0.0.1:8474"}`), UpdateOptions{})
+	require.NoError(t, err)
+	assert.True(t, res.Persisted)
+	assert.Equal(t, int64(1), res.Revision)
+	assert.Equal(t, []string{"server_addr"}, res.RestartRequired)
+	assert.Equal(t, "WARN", reloaded.LogLevel)
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1:8474", loaded.ServerAddr)
+
+	// dry run 不修改
+	res, err = m.Update(json.RawMessage(`{"log_level":"ERROR"}`), UpdateOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"log_level"}, res.Changed)
+	assert.Equal(t, "WARN", m.Get().LogLevel)
+}
+
+func TestManagerReloadFailureReverts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client.json")
+	m := NewManager(path, Default(), nil)
+	m.SetReloader(func(old, new *Config) error {
+		if new.LogLevel == "DEBUG" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	_, err := m.Update(json.RawMessage(`{"log_level":"DEBUG"}`), UpdateOptions{})
+	assert.Error(t, err)
+	assert.Equal(t, "INFO", m.Get().LogLevel)
+	assert.Equal(t, int64(0), m.Revision())
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "INFO", loaded.LogLevel)
+}
+
+func TestManagerRollbackWhenDisconnected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client.json")
+	m := NewManager(path, Default(), nil)
+
+	var connected atomic.Bool
+	m.SetConnectedFunc(connected.Load)
+
+	res, err := m.Update(json.RawMessage(`{"heartbeat_interval_sec":200}`), UpdateOptions{GracePeriod: 20 * time.Millisecond})
+	require.NoError(t, err)
+	assert.False(t, res.RollbackAt.IsZero())
+	assert.True(t, m.PendingRollback())
+
+	assert.Eventually(t, func() bool {
+		return m.Get().HeartbeatIntervalSec == Default().HeartbeatIntervalSec
+	}, time.Second, 5*time.Millisecond)
+	assert.False(t, m.PendingRollback())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"heartbeat_interval_sec": 15`)
+}
+
+func TestManagerConfirmWhenConnected(t *testing.T) {
+	m := NewManager("", Default(), nil)
+
+	var connected atomic.Bool
+	connected.Store(true)
+	m.SetConnectedFunc(connected.Load)
+
+	_, err := m.Update(json.RawMessage(`{"log_level":"DEBUG"}`), UpdateOptions{GracePeriod: 10 * time.Millisecond})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return !m.PendingRollback() }, time.Second, 5*time.Millisecond)
+
+	// 后续变更失败时回滚到已确认的配置
+	connected.Store(false)
+	_, err = m.Update(json.RawMessage(`{"log_level":"ERROR"}`), UpdateOptions{GracePeriod: 10 * time.Millisecond})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return m.Get().LogLevel == "DEBUG" }, time.Second, 5*time.Millisecond)
+}
+
+func TestManagerRestartRequiredRollbackAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client.json")
+	m := NewManager(path, Default(), nil)
+
+	// 旧连接仍然在线：可热加载的字段被确认，需要重启的字段仍待确认
+	m.SetConnectedFunc(func() bool { return true })
+	_, err := m.Update(json.RawMessage(`{"server_addr":"10.0.0.9:8474"}`), UpdateOptions{GracePeriod: 10 * time.Millisecond})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return !m.PendingRollback() }, time.Second, 5*time.Millisecond)
+	_, err = os.Stat(path + ".pending.json")
+	require.NoError(t, err, "marker survives confirmation on the old connection")
+
+	// 重启后未能连接：恢复变更前的配置文件并重启
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.9:8474", loaded.ServerAddr)
+	restarted := make(chan struct{}, 1)
+	after := NewManager(path, loaded, nil)
+	after.SetConnectedFunc(func() bool { return false })
+	after.SetRestartFunc(func() error { restarted <- struct{}{}; return nil })
+	rewritePendingGrace(t, path, 1)
+	after.CheckPending(context.Background())
+
+	select {
+	case <-restarted:
+	case <-time.After(3 * time.Second):
+		t.Fatal("config was not rolled back")
+	}
+	loaded, err = Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, Default().ServerAddr, loaded.ServerAddr)
+	_, err = os.Stat(path + ".pending.json")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestManagerRestartRequiredConfirmAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client.json")
+	m := NewManager(path, Default(), nil)
+	m.SetConnectedFunc(func() bool { return false })
+	_, err := m.Update(json.RawMessage(`{"client_id":"agent-7"}`), UpdateOptions{GracePeriod: time.Hour})
+	require.NoError(t, err)
+	m.Stop()
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	after := NewManager(path, loaded, nil)
+	after.SetConnectedFunc(func() bool { return true })
+	after.CheckPending(context.Background())
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(path + ".pending.json")
+		return os.IsNotExist(err)
+	}, 3*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "agent-7", after.Get().ClientID)
+}
+
+// rewritePendingGrace 修改待确认记录的宽限期，避免测试等待
+func rewritePendingGrace(t *testing.T, path string, sec int) {
+	data, err := os.ReadFile(path + ".pending.json")
+	require.NoError(t, err)
+	var st pendingState
+	require.NoError(t, json.Unmarshal(data, &st))
+	st.GraceSec = sec
+	data, err = json.Marshal(st)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path+".pending.json", data, 0600))
+}
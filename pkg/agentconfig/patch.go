@@ -0,0 +1,85 @@
+package agentconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ApplyPatch 对配置应用 JSON Merge Patch（RFC 7386）并校验
+// 值为 null 的字段恢复为默认值，未知字段会被拒绝
+func ApplyPatch(base *Config, patch json.RawMessage) (*Config, error) {
+	var p interface{}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+	if _, ok := p.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("invalid patch: must be a JSON object")
+	}
+
+	merged := mergePatch(toMap(base), p)
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	if err := decodeStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// KeyPatch 将 "a.b" 形式的键值转换为 merge patch
+func KeyPatch(key string, value interface{}) (json.RawMessage, error) {
+	if key == "" {
+		return nil, fmt.Errorf("key is required")
+	}
+	parts := strings.Split(key, ".")
+	var patch interface{} = value
+	for i := len(parts) - 1; i >= 0; i-- {
+		if parts[i] == "" {
+			return nil, fmt.Errorf("invalid key: %s", key)
+		}
+		patch = map[string]interface{}{parts[i]: patch}
+	}
+	return json.Marshal(patch)
+}
+
+// Lookup 按 "a.b" 形式的键获取配置值
+func Lookup(c *Config, key string) (interface{}, bool) {
+	var cur interface{} = toMap(c)
+	for _, part := range strings.Split(key, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// mergePatch RFC 7386 合并算法
+func mergePatch(target, patch interface{}) interface{} {
+	pm, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	tm, ok := target.(map[string]interface{})
+	if !ok {
+		tm = make(map[string]interface{})
+	}
+	for k, v := range pm {
+		if v == nil {
+			delete(tm, k)
+			continue
+		}
+		tm[k] = mergePatch(tm[k], v)
+	}
+	return tm
+}
@@ -0,0 +1,177 @@
+package agentconfig
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// pendingState 等待重启后确认的变更，保存在 <config>.pending.json
+// 需要重启的字段在旧连接上无法验证，重启后在宽限期内未能连接则恢复 Previous
+type pendingState struct {
+	Revision        int64     `json:"revision"`
+	RestartRequired []string  `json:"restart_required"`
+	Previous        *Config   `json:"previous"` // 最后一次确认可用的配置
+	GraceSec        int       `json:"grace_sec"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// SetRestartFunc 设置重启函数：启动时回滚需要重启的变更后调用，使恢复的配置生效
+func (m *Manager) SetRestartFunc(fn func() error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.restart = fn
+}
+
+// CheckPending 启动时检查上次运行留下的待确认变更：宽限期内连接成功则确认，
+// 否则恢复变更前的配置文件并重启
+func (m *Manager) CheckPending(ctx context.Context) {
+	st, err := m.loadPending()
+	if err != nil || st == nil {
+		return
+	}
+	grace := time.Duration(st.GraceSec) * time.Second
+	if grace <= 0 {
+		grace = time.Duration(st.Previous.RollbackGraceSec) * time.Second
+	}
+	deadline := time.Now().Add(grace)
+	m.logger.Info("Pending agent config change, waiting for connection",
+		"restart_required", st.RestartRequired,
+		"deadline", deadline,
+	)
+
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			if m.connected != nil && m.connected() {
+				m.confirmPending()
+				return
+			}
+			if time.Now().After(deadline) {
+				m.rollbackPending(st)
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// confirmPending 重启后已连接，确认变更
+func (m *Manager) confirmPending() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removePending()
+	m.confirmed = m.current.Clone()
+	m.logger.Info("Agent config confirmed after restart")
+}
+
+// rollbackPending 重启后未能连接，恢复变更前的配置文件并重启
+func (m *Manager) rollbackPending(st *pendingState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.logger.Warn("Agent not connected after restart with new config, rolling back",
+		"restart_required", st.RestartRequired,
+	)
+	if err := m.persist(st.Previous); err != nil {
+		m.logger.Error("Failed to restore agent config file", "error", err)
+		return
+	}
+	m.removePending()
+	m.current = st.Previous.Clone()
+	m.confirmed = st.Previous.Clone()
+	m.revision++
+
+	if m.restart == nil {
+		m.logger.Warn("Agent config restored, restart the agent to apply it")
+		return
+	}
+	if err := m.restart(); err != nil {
+		m.logger.Error("Failed to restart after config rollback", "error", err)
+	}
+}
+
+// savePending 记录需要重启才能验证的变更；已有记录时保留其中的 Previous
+// 调用方需持有锁
+func (m *Manager) savePending(revision int64, restartRequired []string, grace time.Duration) error {
+	if m.path == "" {
+		return nil
+	}
+	st, err := m.loadPending()
+	if err != nil {
+		return err
+	}
+	if st == nil {
+		st = &pendingState{Previous: m.confirmed.Clone()}
+	}
+	st.Revision = revision
+	st.RestartRequired = mergeFields(st.RestartRequired, restartRequired)
+	st.GraceSec = int(grace / time.Second)
+	st.UpdatedAt = time.Now()
+
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.pendingFile() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.pendingFile())
+}
+
+// loadPending 读取待确认记录，不存在时返回 nil
+func (m *Manager) loadPending() (*pendingState, error) {
+	if m.path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(m.pendingFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var st pendingState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, err
+	}
+	if st.Previous == nil {
+		return nil, nil
+	}
+	return &st, nil
+}
+
+// removePending 删除待确认记录
+func (m *Manager) removePending() {
+	if m.path == "" {
+		return
+	}
+	if err := os.Remove(m.pendingFile()); err != nil && !os.IsNotExist(err) {
+		m.logger.Warn("Failed to remove pending config marker", "error", err)
+	}
+}
+
+// pendingFile 待确认记录路径
+func (m *Manager) pendingFile() string {
+	return m.path + ".pending.json"
+}
+
+// mergeFields 合并字段列表（去重，保持顺序）
+func mergeFields(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var out []string
+	for _, f := range append(append([]string{}, a...), b...) {
+		if !seen[f] {
+			seen[f] = true
+			out = append(out, f)
+		}
+	}
+	return out
+}
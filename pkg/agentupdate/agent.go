@@ -0,0 +1,399 @@
+package agentupdate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/version"
+)
+
+// 升级状态（Agent 本地状态文件）
+const (
+	StatePending    = "pending"     // 新版本已安装，等待重连确认
+	StateCommitted  = "committed"   // 新版本已确认可用
+	StateRolledBack = "rolled_back" // 新版本未能重连，已回滚
+)
+
+// Agent 端默认值
+const (
+	defaultReconnectTimeout = 60 * time.Second
+	preflightTimeout        = 10 * time.Second
+	restartDelay            = time.Second // 留出时间回复 agent.update 的 Ack
+)
+
+// updateState Agent 本地升级状态，保存在 <binary>.update.json
+type updateState struct {
+	UpdateID            string    `json:"update_id"`
+	FromVersion         string    `json:"from_version"`
+	ToVersion           string    `json:"to_version"`
+	Previous            string    `json:"previous"` // 旧二进制备份路径
+	State               string    `json:"state"`
+	ReconnectTimeoutSec int       `json:"reconnect_timeout_sec"`
+	AppliedAt           time.Time `json:"applied_at"`
+	Message             string    `json:"message,omitempty"`
+}
+
+// Updater Agent 自升级
+// 服务器先通过文件传输流推送二进制（HandleStream），再下发 agent.update（Apply）。
+// Apply 校验 SHA256 与签名清单（版本、平台、摘要），拒绝未明确允许的降级，试运行新二进制后原子替换并重新执行；
+// 新进程启动后调用 CheckPending，超时未重连则恢复旧二进制并重新执行
+type Updater struct {
+	binary    string
+	dir       string
+	publicKey ed25519.PublicKey
+	logger    *monitoring.Logger
+	connected func() bool
+	restart   func(binary string) error
+
+	mu     sync.Mutex
+	staged map[string]string // updateID -> 暂存文件
+}
+
+// NewUpdater 创建升级器
+// binary 为当前可执行文件路径（为空时使用 os.Executable），dir 为暂存目录（为空时使用 <binary>.staging，
+// 与状态文件、旧版本备份放在一起），publicKey 为空时拒绝所有升级
+func NewUpdater(binary, dir string, publicKey ed25519.PublicKey, logger *monitoring.Logger) (*Updater, error) {
+	if binary == "" {
+		exe, err := os.Executable()
+		if err != nil {
+			return nil, fmt.Errorf("locate executable: %w", err)
+		}
+		if binary, err = filepath.EvalSymlinks(exe); err != nil {
+			return nil, fmt.Errorf("locate executable: %w", err)
+		}
+	}
+	if dir == "" {
+		dir = binary + ".staging"
+	}
+	if logger == nil {
+		logger = monitoring.NewDefaultLogger()
+	}
+	return &Updater{
+		binary:    binary,
+		dir:       dir,
+		publicKey: publicKey,
+		logger:    logger,
+		connected: func() bool { return true },
+		restart:   reexec,
+		staged:    make(map[string]string),
+	}, nil
+}
+
+// SetConnectedFunc 设置连接状态检查函数（用于重连确认）
+func (u *Updater) SetConnectedFunc(fn func() bool) {
+	u.connected = fn
+}
+
+// HandleStream 接收服务器推送的二进制（文件传输流）
+func (u *Updater) HandleStream(rw io.ReadWriter) error {
+	updateID, path, err := receive(rw, u.dir)
+	if err != nil {
+		u.logger.Warn("Failed to receive update binary", "error", err)
+		return err
+	}
+	u.mu.Lock()
+	if old, ok := u.staged[updateID]; ok && old != path {
+		os.Remove(old)
+	}
+	u.staged[updateID] = path
+	u.mu.Unlock()
+	u.logger.Info("Update binary received", "update_id", updateID, "path", path)
+	return nil
+}
+
+// Apply 安装已推送的二进制并安排重启
+func (u *Updater) Apply(params *command.AgentUpdateParams) (*command.AgentUpdateResult, error) {
+	if u.publicKey == nil {
+		return nil, fmt.Errorf("agent updates are disabled: no update public key configured")
+	}
+	if params.OS != runtime.GOOS || params.Arch != runtime.GOARCH {
+		return nil, fmt.Errorf("platform mismatch: binary is %s/%s, agent is %s/%s", params.OS, params.Arch, runtime.GOOS, runtime.GOARCH)
+	}
+	if cmp, ok := CompareVersions(params.Version, version.Version); ok && cmp < 0 && !params.AllowDowngrade {
+		return nil, fmt.Errorf("refusing to downgrade from %s to %s: allow_downgrade is not set", version.Version, params.Version)
+	}
+	if st, err := u.loadState(); err == nil && st.State == StatePending {
+		return nil, fmt.Errorf("update %s is still pending confirmation", st.UpdateID)
+	}
+
+	u.mu.Lock()
+	staged, ok := u.staged[params.UpdateID]
+	u.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("binary for update %s has not been received", params.UpdateID)
+	}
+
+	// 校验、试运行与安装都针对同一份私有副本，暂存文件之后被替换不影响安装内容
+	candidate, sum, err := u.copyStaged(staged)
+	if err != nil {
+		return nil, fmt.Errorf("copy staged binary: %w", err)
+	}
+	installed := false
+	defer func() {
+		if !installed {
+			os.Remove(candidate)
+		}
+	}()
+	if sum != params.SHA256 {
+		return nil, fmt.Errorf("checksum mismatch: got %s, expected %s", sum, params.SHA256)
+	}
+	manifest := Manifest{Version: params.Version, OS: params.OS, Arch: params.Arch, SHA256: sum}
+	if err := Verify(u.publicKey, manifest, params.Signature); err != nil {
+		return nil, err
+	}
+	if err := preflight(candidate); err != nil {
+		return nil, fmt.Errorf("new binary failed preflight: %w", err)
+	}
+
+	previous := u.binary + ".previous"
+	if err := copyFile(u.binary, previous); err != nil {
+		return nil, fmt.Errorf("backup current binary: %w", err)
+	}
+	if err := os.Rename(candidate, u.binary); err != nil {
+		return nil, fmt.Errorf("install new binary: %w", err)
+	}
+	installed = true
+
+	timeout := params.ReconnectTimeoutSec
+	if timeout <= 0 {
+		timeout = int(defaultReconnectTimeout / time.Second)
+	}
+	st := &updateState{
+		UpdateID:            params.UpdateID,
+		FromVersion:         version.Version,
+		ToVersion:           params.Version,
+		Previous:            previous,
+		State:               StatePending,
+		ReconnectTimeoutSec: timeout,
+		AppliedAt:           time.Now(),
+	}
+	if err := u.saveState(st); err != nil {
+		// 无状态文件时新进程无法回滚，恢复旧二进制
+		replaceFile(previous, u.binary)
+		return nil, fmt.Errorf("save update state: %w", err)
+	}
+
+	u.mu.Lock()
+	delete(u.staged, params.UpdateID)
+	u.mu.Unlock()
+	os.Remove(staged)
+
+	u.logger.Info("Update installed, restarting", "from", st.FromVersion, "to", st.ToVersion, "update_id", st.UpdateID)
+	time.AfterFunc(restartDelay, func() {
+		if err := u.restart(u.binary); err != nil {
+			u.logger.Error("Failed to restart after update, rolling back", "error", err)
+			u.rollback(st, fmt.Sprintf("restart failed: %v", err), false)
+		}
+	})
+
+	result := u.Status()
+	result.Message = "update installed, restarting"
+	return result, nil
+}
+
+// Status 返回当前版本与最近一次升级状态
+func (u *Updater) Status() *command.AgentUpdateResult {
+	result := &command.AgentUpdateResult{
+		Success:  true,
+		Version:  version.Version,
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+		Message:  "ok",
+	}
+	if st, err := u.loadState(); err == nil {
+		result.State = st.State
+		result.FromVersion = st.FromVersion
+		result.ToVersion = st.ToVersion
+		result.UpdateID = st.UpdateID
+		if st.Message != "" {
+			result.Message = st.Message
+		}
+	}
+	return result
+}
+
+// CheckPending 启动时检查未确认的升级：在超时前重连则确认，否则回滚并重新执行旧版本
+// 若进程在超时后才启动（如新版本反复崩溃后被守护进程拉起），立即回滚
+func (u *Updater) CheckPending(ctx context.Context) {
+	st, err := u.loadState()
+	if err != nil || st.State != StatePending {
+		return
+	}
+	deadline := st.AppliedAt.Add(time.Duration(st.ReconnectTimeoutSec) * time.Second)
+	u.logger.Info("Pending update, waiting for reconnect", "update_id", st.UpdateID, "deadline", deadline)
+
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			if u.connected() {
+				st.State = StateCommitted
+				st.Message = "update committed"
+				if err := u.saveState(st); err != nil {
+					u.logger.Warn("Failed to save update state", "error", err)
+				}
+				u.logger.Info("Update committed", "version", st.ToVersion, "update_id", st.UpdateID)
+				return
+			}
+			if time.Now().After(deadline) {
+				u.rollback(st, "new version did not reconnect in time", true)
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// rollback 恢复旧二进制，restart 为 true 时重新执行
+func (u *Updater) rollback(st *updateState, reason string, restart bool) {
+	u.logger.Warn("Rolling back update", "update_id", st.UpdateID, "reason", reason)
+	if err := replaceFile(st.Previous, u.binary); err != nil {
+		u.logger.Error("Failed to restore previous binary", "error", err)
+		return
+	}
+	st.State = StateRolledBack
+	st.Message = reason
+	if err := u.saveState(st); err != nil {
+		u.logger.Warn("Failed to save update state", "error", err)
+	}
+	if restart {
+		if err := u.restart(u.binary); err != nil {
+			u.logger.Error("Failed to restart previous binary", "error", err)
+		}
+	}
+}
+
+// stateFile 状态文件路径
+func (u *Updater) stateFile() string {
+	return u.binary + ".update.json"
+}
+
+func (u *Updater) loadState() (*updateState, error) {
+	data, err := os.ReadFile(u.stateFile())
+	if err != nil {
+		return nil, err
+	}
+	var st updateState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func (u *Updater) saveState(st *updateState) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := u.stateFile() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, u.stateFile())
+}
+
+// stagedPath 暂存文件路径
+func stagedPath(dir, updateID string) string {
+	return filepath.Join(dir, updateID+".bin")
+}
+
+// copyStaged 只打开一次暂存文件，边复制边计算 SHA256，写入当前二进制同目录下以 O_EXCL 新建的私有临时文件，
+// 返回临时文件路径与摘要
+func (u *Updater) copyStaged(staged string) (string, string, error) {
+	in, err := os.OpenFile(staged, os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		return "", "", err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return "", "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", "", fmt.Errorf("%s is not a regular file", staged)
+	}
+
+	out, err := os.CreateTemp(filepath.Dir(u.binary), filepath.Base(u.binary)+".new-*")
+	if err != nil {
+		return "", "", err
+	}
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, h), in)
+	if err == nil {
+		err = out.Chmod(0755)
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return "", "", err
+	}
+	return out.Name(), hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// preflight 试运行新二进制的 version 子命令，确认可以在本机执行
+func preflight(binary string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), preflightTimeout)
+	defer cancel()
+	if out, err := exec.CommandContext(ctx, binary, "version").CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, out)
+	}
+	return nil
+}
+
+// replaceFile 将 src 复制到 dst 同目录的临时文件后原子替换 dst
+func replaceFile(src, dst string) error {
+	tmp := dst + ".new"
+	if err := copyFile(src, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// copyFile 复制可执行文件并落盘
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// reexec 以相同参数和环境重新执行（替换当前进程）
+func reexec(binary string) error {
+	return syscall.Exec(binary, os.Args, os.Environ())
+}
@@ -0,0 +1,297 @@
+package agentupdate
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/version"
+)
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestSignVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	m := Manifest{Version: "1.4.0", OS: "linux", Arch: "amd64", SHA256: sha256Hex([]byte("binary"))}
+
+	sig, err := Sign(priv, m)
+	require.NoError(t, err)
+	assert.NoError(t, Verify(pub, m, sig))
+
+	// 摘要、版本或平台任一不同都不能通过校验
+	for _, other := range []Manifest{
+		{Version: m.Version, OS: m.OS, Arch: m.Arch, SHA256: sha256Hex([]byte("other"))},
+		{Version: "1.3.0", OS: m.OS, Arch: m.Arch, SHA256: m.SHA256},
+		{Version: m.Version, OS: "darwin", Arch: m.Arch, SHA256: m.SHA256},
+		{Version: m.Version, OS: m.OS, Arch: "arm64", SHA256: m.SHA256},
+	} {
+		assert.ErrorIs(t, Verify(pub, other, sig), ErrInvalidSignature)
+	}
+
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+	assert.ErrorIs(t, Verify(otherPub, m, sig), ErrInvalidSignature)
+	_, err = Sign(priv, Manifest{Version: "1.4.0\nos=linux", OS: "linux", Arch: "amd64", SHA256: m.SHA256})
+	assert.Error(t, err)
+}
+
+func TestCompareVersions(t *testing.T) {
+	cmp, ok := CompareVersions("v1.10.0", "1.9.3")
+	assert.True(t, ok)
+	assert.Equal(t, 1, cmp)
+	cmp, ok = CompareVersions("1.4", "v1.4.0-rc1")
+	assert.True(t, ok)
+	assert.Zero(t, cmp)
+	_, ok = CompareVersions("1.4.0", "dev")
+	assert.False(t, ok)
+}
+
+// sendOverPipe 通过内存管道发送数据并由 receive 接收
+func sendOverPipe(t *testing.T, dir, updateID string, data []byte, checksum string) (string, error, error) {
+	server, agent := net.Pipe()
+	defer server.Close()
+	defer agent.Close()
+
+	var path string
+	var recvErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, path, recvErr = receive(agent, dir)
+	}()
+	sendErr := Send(server, updateID, "quic-client", int64(len(data)), checksum, bytes.NewReader(data))
+	wg.Wait()
+	return path, sendErr, recvErr
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	data := bytes.Repeat([]byte("0123456789"), chunkSize/5) // 跨多个数据帧
+
+	path, sendErr, recvErr := sendOverPipe(t, dir, "u-1", data, sha256Hex(data))
+	require.NoError(t, sendErr)
+	require.NoError(t, recvErr)
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	_, sendErr, recvErr = sendOverPipe(t, dir, "u-2", data, sha256Hex([]byte("wrong")))
+	assert.Error(t, sendErr)
+	assert.Error(t, recvErr)
+	_, err = os.Stat(stagedPath(dir, "u-2"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReceiveReplacesExistingFile(t *testing.T) {
+	data := []byte("binary")
+
+	// 已存在的暂存文件（含符号链接）被替换而不是复用
+	dir := t.TempDir()
+	target := filepath.Join(t.TempDir(), "target")
+	require.NoError(t, os.WriteFile(target, []byte("keep"), 0600))
+	require.NoError(t, os.Symlink(target, stagedPath(dir, "u-1")))
+	path, sendErr, recvErr := sendOverPipe(t, dir, "u-1", data, sha256Hex(data))
+	require.NoError(t, sendErr)
+	require.NoError(t, recvErr)
+	got, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+	got, err = os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "keep", string(got))
+}
+
+// writeScript 写入可执行脚本，version 子命令输出 ver
+func writeScript(t *testing.T, path, ver string) []byte {
+	data := []byte("#!/bin/sh\necho " + ver + "\n")
+	require.NoError(t, os.WriteFile(path, data, 0755))
+	return data
+}
+
+// newTestUpdater 创建使用脚本作为当前二进制的升级器，重启仅记录调用
+func newTestUpdater(t *testing.T) (*Updater, ed25519.PrivateKey, chan string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "quic-client")
+	writeScript(t, binary, "v1")
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	u, err := NewUpdater(binary, filepath.Join(dir, "staging"), pub, monitoring.NewDefaultLogger())
+	require.NoError(t, err)
+	restarts := make(chan string, 4)
+	u.restart = func(binary string) error {
+		restarts <- binary
+		return nil
+	}
+	return u, priv, restarts
+}
+
+// stage 推送新二进制并返回升级参数
+func stage(t *testing.T, u *Updater, priv ed25519.PrivateKey, updateID string) (*command.AgentUpdateParams, []byte) {
+	src := filepath.Join(t.TempDir(), "new")
+	data := writeScript(t, src, "v2")
+	sum := sha256Hex(data)
+
+	server, agent := net.Pipe()
+	defer server.Close()
+	errCh := make(chan error, 1)
+	go func() { errCh <- u.HandleStream(agent) }()
+	require.NoError(t, Send(server, updateID, "quic-client", int64(len(data)), sum, bytes.NewReader(data)))
+	require.NoError(t, <-errCh)
+
+	sig, err := Sign(priv, Manifest{Version: "v2", OS: runtime.GOOS, Arch: runtime.GOARCH, SHA256: sum})
+	require.NoError(t, err)
+	return &command.AgentUpdateParams{
+		UpdateID:            updateID,
+		Version:             "v2",
+		OS:                  runtime.GOOS,
+		Arch:                runtime.GOARCH,
+		SHA256:              sum,
+		Signature:           sig,
+		ReconnectTimeoutSec: 1,
+	}, data
+}
+
+func TestUpdaterApply(t *testing.T) {
+	u, priv, restarts := newTestUpdater(t)
+	params, data := stage(t, u, priv, "u-1")
+
+	result, err := u.Apply(params)
+	require.NoError(t, err)
+	assert.Equal(t, StatePending, result.State)
+	assert.Equal(t, "u-1", result.UpdateID)
+
+	got, err := os.ReadFile(u.binary)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	select {
+	case b := <-restarts:
+		assert.Equal(t, u.binary, b)
+	case <-time.After(3 * time.Second):
+		t.Fatal("updater did not restart")
+	}
+
+	// 未确认前拒绝新的升级
+	next, _ := stage(t, u, priv, "u-2")
+	_, err = u.Apply(next)
+	assert.ErrorContains(t, err, "pending")
+}
+
+func TestUpdaterRejectsBadSignature(t *testing.T) {
+	u, priv, _ := newTestUpdater(t)
+	params, _ := stage(t, u, priv, "u-1")
+
+	_, otherPriv, _ := ed25519.GenerateKey(nil)
+	params.Signature, _ = Sign(otherPriv, Manifest{Version: params.Version, OS: params.OS, Arch: params.Arch, SHA256: params.SHA256})
+	_, err := u.Apply(params)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	got, err := os.ReadFile(u.binary)
+	require.NoError(t, err)
+	assert.Contains(t, string(got), "v1")
+
+	// 校验失败时不残留临时副本
+	leftovers, err := filepath.Glob(u.binary + ".new-*")
+	require.NoError(t, err)
+	assert.Empty(t, leftovers)
+}
+
+func TestUpdaterRejectsSwappedStagedFile(t *testing.T) {
+	u, priv, _ := newTestUpdater(t)
+	params, _ := stage(t, u, priv, "u-1")
+
+	// 暂存文件被替换为指向其他文件的符号链接时拒绝安装
+	staged := u.staged["u-1"]
+	other := filepath.Join(t.TempDir(), "other")
+	writeScript(t, other, "evil")
+	require.NoError(t, os.Remove(staged))
+	require.NoError(t, os.Symlink(other, staged))
+	_, err := u.Apply(params)
+	assert.Error(t, err)
+
+	// 暂存文件被替换为其他内容时摘要不匹配
+	require.NoError(t, os.Remove(staged))
+	writeScript(t, staged, "evil")
+	_, err = u.Apply(params)
+	assert.ErrorContains(t, err, "checksum mismatch")
+
+	got, err := os.ReadFile(u.binary)
+	require.NoError(t, err)
+	assert.Contains(t, string(got), "v1")
+}
+
+func TestUpdaterRejectsRelabeledAndDowngrade(t *testing.T) {
+	u, priv, _ := newTestUpdater(t)
+
+	// 签名绑定版本：已签名的二进制不能冒充其他版本
+	params, _ := stage(t, u, priv, "u-1")
+	params.Version = "v3"
+	_, err := u.Apply(params)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	// 低于当前运行版本时需要明确允许降级
+	old := version.Version
+	version.Version = "v3.0.0"
+	defer func() { version.Version = old }()
+	params, _ = stage(t, u, priv, "u-2")
+	_, err = u.Apply(params)
+	assert.ErrorContains(t, err, "downgrade")
+	params.AllowDowngrade = true
+	_, err = u.Apply(params)
+	assert.NoError(t, err)
+}
+
+func TestCheckPendingCommit(t *testing.T) {
+	u, priv, restarts := newTestUpdater(t)
+	params, _ := stage(t, u, priv, "u-1")
+	_, err := u.Apply(params)
+	require.NoError(t, err)
+	<-restarts
+
+	u.SetConnectedFunc(func() bool { return true })
+	u.CheckPending(context.Background())
+	require.Eventually(t, func() bool { return u.Status().State == StateCommitted }, 3*time.Second, 50*time.Millisecond)
+}
+
+func TestCheckPendingRollback(t *testing.T) {
+	u, priv, restarts := newTestUpdater(t)
+	params, _ := stage(t, u, priv, "u-1")
+	_, err := u.Apply(params)
+	require.NoError(t, err)
+	<-restarts
+
+	u.SetConnectedFunc(func() bool { return false })
+	u.CheckPending(context.Background())
+	select {
+	case <-restarts:
+	case <-time.After(5 * time.Second):
+		t.Fatal("updater did not restart previous binary")
+	}
+
+	st := u.Status()
+	assert.Equal(t, StateRolledBack, st.State)
+	got, err := os.ReadFile(u.binary)
+	require.NoError(t, err)
+	assert.Contains(t, string(got), "v1")
+}
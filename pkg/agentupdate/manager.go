@@ -0,0 +1,542 @@
+package agentupdate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/voilet/quic-flow/pkg/batch"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/filetransfer"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+)
+
+// 服务端默认值
+const (
+	defaultConcurrency  = 10
+	defaultPollInterval = 2 * time.Second
+	statusTimeout       = 15 * time.Second
+	installTimeout      = 60 * time.Second // 包含 Agent 试运行新二进制的时间
+	pushTimeout         = 10 * time.Minute
+	verifyGrace         = 30 * time.Second // 重连超时之外的额外等待
+)
+
+// CommandSender 命令下发接口（由 command.CommandManager 实现）
+type CommandSender interface {
+	SendCommandToMultiple(clientIDs []string, commandType string, payload json.RawMessage, timeout time.Duration) *command.MultiCommandResponse
+}
+
+// ArtifactSource 升级包来源（由 filetransfer.StorageBackend 实现）
+type ArtifactSource interface {
+	Retrieve(ctx context.Context, path string) (io.ReadCloser, filetransfer.FileMeta, error)
+}
+
+// PushFunc 向客户端推送二进制（通常为查找连接后调用 PushToConn）
+type PushFunc func(ctx context.Context, clientID, updateID, name string, size int64, checksum string, r io.Reader) error
+
+// Manager 服务端升级管理：登记升级包并按批次驱动客户端升级
+type Manager struct {
+	store        *Store
+	source       ArtifactSource
+	sender       CommandSender
+	push         PushFunc
+	signer       ed25519.PrivateKey
+	logger       *monitoring.Logger
+	concurrency  int
+	pollInterval time.Duration
+
+	mu     sync.Mutex
+	active map[string]*Rollout           // 运行中或暂停的升级任务
+	cancel map[string]context.CancelFunc // 运行中升级任务的取消函数
+}
+
+// NewManager 创建升级管理器
+func NewManager(store *Store, source ArtifactSource, sender CommandSender, push PushFunc, logger *monitoring.Logger) *Manager {
+	if logger == nil {
+		logger = monitoring.NewDefaultLogger()
+	}
+	return &Manager{
+		store:        store,
+		source:       source,
+		sender:       sender,
+		push:         push,
+		logger:       logger,
+		concurrency:  defaultConcurrency,
+		pollInterval: defaultPollInterval,
+		active:       make(map[string]*Rollout),
+		cancel:       make(map[string]context.CancelFunc),
+	}
+}
+
+// SetSigner 设置签名私钥，登记未签名的升级包时自动签名
+func (m *Manager) SetSigner(key ed25519.PrivateKey) {
+	m.signer = key
+}
+
+// RegisterArtifact 登记升级包：读取存储中的二进制计算 SHA256 与大小，
+// 未提供签名时使用签名私钥签名
+func (m *Manager) RegisterArtifact(ctx context.Context, a *Artifact) (*Artifact, error) {
+	if a.Version == "" || a.OS == "" || a.Arch == "" || a.Path == "" {
+		return nil, fmt.Errorf("version, os, arch and path are required")
+	}
+	r, _, err := m.source.Retrieve(ctx, a.Path)
+	if err != nil {
+		return nil, fmt.Errorf("retrieve %s: %w", a.Path, err)
+	}
+	defer r.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, io.LimitReader(r, MaxBinarySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", a.Path, err)
+	}
+	if size == 0 || size > MaxBinarySize {
+		return nil, fmt.Errorf("invalid binary size: %d", size)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	if a.SHA256 != "" && a.SHA256 != sum {
+		return nil, fmt.Errorf("checksum mismatch: got %s, expected %s", sum, a.SHA256)
+	}
+
+	manifest := Manifest{Version: a.Version, OS: a.OS, Arch: a.Arch, SHA256: sum}
+	switch {
+	case a.Signature == "" && m.signer == nil:
+		return nil, fmt.Errorf("signature is required: no signing key configured")
+	case a.Signature == "":
+		if a.Signature, err = Sign(m.signer, manifest); err != nil {
+			return nil, err
+		}
+	case m.signer != nil:
+		if err := Verify(m.signer.Public().(ed25519.PublicKey), manifest, a.Signature); err != nil {
+			return nil, err
+		}
+	}
+
+	a.ID = uuid.New().String()
+	a.SHA256 = sum
+	a.Size = size
+	a.CreatedAt = time.Now()
+	if err := m.store.SaveArtifact(a); err != nil {
+		return nil, err
+	}
+	m.logger.Info("Agent update artifact registered", "version", a.Version, "platform", a.Platform(), "sha256", sum)
+	return a, nil
+}
+
+// ListArtifacts 列出升级包
+func (m *Manager) ListArtifacts() []*Artifact {
+	return m.store.ListArtifacts()
+}
+
+// GetArtifact 获取升级包
+func (m *Manager) GetArtifact(id string) (*Artifact, error) {
+	return m.store.GetArtifact(id)
+}
+
+// DeleteArtifact 删除升级包登记（不删除存储中的文件）
+func (m *Manager) DeleteArtifact(id string) error {
+	return m.store.DeleteArtifact(id)
+}
+
+// StartRollout 创建并启动分批升级任务
+func (m *Manager) StartRollout(req *RolloutRequest) (*Rollout, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if len(m.store.FindArtifacts(req.Version)) == 0 {
+		return nil, fmt.Errorf("no artifact registered for version %s", req.Version)
+	}
+	timeout := req.ReconnectTimeoutSec
+	if timeout == 0 {
+		timeout = int(defaultReconnectTimeout / time.Second)
+	}
+
+	r := &Rollout{
+		ID:                  uuid.New().String(),
+		Version:             req.Version,
+		Policy:              req.Policy,
+		ReconnectTimeoutSec: timeout,
+		Force:               req.Force,
+		AllowDowngrade:      req.AllowDowngrade,
+		Status:              RolloutRunning,
+		CreatedAt:           time.Now(),
+	}
+	waves := batch.SplitWaves(req.ClientIDs, req.Policy)
+	r.WaveCount = len(waves)
+	for i, ids := range waves {
+		for _, id := range ids {
+			r.Clients = append(r.Clients, &ClientUpdate{ClientID: id, Wave: i, Status: ClientPending})
+		}
+	}
+	r.recount()
+
+	m.mu.Lock()
+	m.active[r.ID] = r
+	if err := m.store.SaveRollout(r); err != nil {
+		delete(m.active, r.ID)
+		m.mu.Unlock()
+		return nil, err
+	}
+	snapshot := r.clone()
+	m.mu.Unlock()
+
+	m.logger.Info("Agent update rollout started", "rollout_id", r.ID, "version", r.Version, "clients", r.Total, "waves", r.WaveCount)
+	go m.execute(r)
+	return snapshot, nil
+}
+
+// Resume 恢复暂停、熔断或中断的升级任务
+func (m *Manager) Resume(id string) (*Rollout, error) {
+	m.mu.Lock()
+	r, ok := m.active[id]
+	if !ok {
+		stored, err := m.store.GetRollout(id)
+		if err != nil {
+			m.mu.Unlock()
+			return nil, err
+		}
+		r = stored
+	}
+	switch r.Status {
+	case RolloutPaused, RolloutHalted, RolloutInterrupted:
+	default:
+		m.mu.Unlock()
+		return nil, fmt.Errorf("rollout %s is %s, only paused, halted or interrupted rollouts can be resumed", id, r.Status)
+	}
+	r.Status = RolloutRunning
+	r.HaltReason = ""
+	r.FinishedAt = nil
+	m.active[id] = r
+	m.save(r)
+	snapshot := r.clone()
+	m.mu.Unlock()
+
+	m.logger.Info("Agent update rollout resumed", "rollout_id", id, "wave", r.CurrentWave)
+	go m.execute(r)
+	return snapshot, nil
+}
+
+// Cancel 取消升级任务，正在升级的客户端会完成当前步骤
+func (m *Manager) Cancel(id string) (*Rollout, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.active[id]
+	if !ok {
+		stored, err := m.store.GetRollout(id)
+		if err != nil {
+			return nil, err
+		}
+		r = stored
+	}
+	switch r.Status {
+	case RolloutRunning, RolloutPaused, RolloutHalted, RolloutInterrupted:
+	default:
+		return nil, fmt.Errorf("rollout %s is already %s", id, r.Status)
+	}
+	if cancel, ok := m.cancel[id]; ok {
+		cancel()
+	}
+	m.finish(r, RolloutCancelled)
+	m.logger.Info("Agent update rollout cancelled", "rollout_id", id)
+	return r.clone(), nil
+}
+
+// List 列出升级任务
+func (m *Manager) List() []*Rollout {
+	list := m.store.ListRollouts()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, r := range list {
+		if live, ok := m.active[r.ID]; ok {
+			list[i] = live.clone()
+		}
+	}
+	return list
+}
+
+// Get 获取升级任务
+func (m *Manager) Get(id string) (*Rollout, error) {
+	m.mu.Lock()
+	if r, ok := m.active[id]; ok {
+		defer m.mu.Unlock()
+		return r.clone(), nil
+	}
+	m.mu.Unlock()
+	return m.store.GetRollout(id)
+}
+
+// Recover 服务启动时将未完成的升级任务标记为中断，可通过 Resume 继续
+func (m *Manager) Recover() {
+	for _, r := range m.store.ListRollouts() {
+		if r.Status != RolloutRunning && r.Status != RolloutPaused {
+			continue
+		}
+		r.Status = RolloutInterrupted
+		r.HaltReason = "server restarted"
+		if err := m.store.SaveRollout(r); err != nil {
+			m.logger.Warn("Failed to save agent update rollout", "rollout_id", r.ID, "error", err)
+		}
+		m.logger.Info("Agent update rollout interrupted", "rollout_id", r.ID, "wave", r.CurrentWave)
+	}
+}
+
+// Stop 取消所有运行中的升级任务（不改变状态，重启后由 Recover 标记为中断）
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, cancel := range m.cancel {
+		cancel()
+	}
+}
+
+// execute 从当前批次开始逐批升级，熔断、暂停或完成时返回
+func (m *Manager) execute(r *Rollout) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.mu.Lock()
+	m.cancel[r.ID] = cancel
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.cancel, r.ID)
+		m.mu.Unlock()
+		cancel()
+	}()
+
+	artifacts := m.store.FindArtifacts(r.Version)
+	for {
+		m.mu.Lock()
+		if r.Status != RolloutRunning {
+			m.mu.Unlock()
+			return
+		}
+		if r.CurrentWave >= r.WaveCount {
+			m.finish(r, RolloutCompleted)
+			m.mu.Unlock()
+			return
+		}
+		wave := r.CurrentWave
+		var todo []*ClientUpdate
+		for _, cu := range r.Clients {
+			if cu.Wave == wave && !cu.Status.finished() {
+				todo = append(todo, cu)
+			}
+		}
+		m.mu.Unlock()
+
+		sem := make(chan struct{}, m.concurrency)
+		var wg sync.WaitGroup
+		for _, cu := range todo {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(cu *ClientUpdate) {
+				defer func() { <-sem; wg.Done() }()
+				m.updateClient(ctx, r, cu, artifacts)
+			}(cu)
+		}
+		wg.Wait()
+		if ctx.Err() != nil {
+			return
+		}
+
+		m.mu.Lock()
+		var succeeded, failed int
+		for _, cu := range r.Clients {
+			if cu.Wave != wave {
+				continue
+			}
+			switch cu.Status {
+			case ClientSucceeded:
+				succeeded++
+			case ClientFailed, ClientRolledBack:
+				failed++
+			}
+		}
+		rate := 0.0
+		if succeeded+failed > 0 {
+			rate = float64(failed) / float64(succeeded+failed) * 100
+		}
+		r.CurrentWave++
+		more := r.CurrentWave < r.WaveCount
+		switch {
+		case more && r.Policy != nil && r.Policy.MaxFailureRate > 0 && rate > r.Policy.MaxFailureRate:
+			r.Status = RolloutHalted
+			r.HaltReason = fmt.Sprintf("wave %d failure rate %.1f%% exceeds %.1f%%", wave, rate, r.Policy.MaxFailureRate)
+			m.logger.Warn("Agent update rollout halted", "rollout_id", r.ID, "reason", r.HaltReason)
+		case more && r.Policy != nil && r.Policy.PauseBetweenWaves:
+			r.Status = RolloutPaused
+			m.logger.Info("Agent update rollout paused", "rollout_id", r.ID, "next_wave", r.CurrentWave)
+		}
+		m.save(r)
+		m.mu.Unlock()
+	}
+}
+
+// updateClient 升级单个客户端：查询状态 → 推送二进制 → 安装 → 等待新版本重连
+func (m *Manager) updateClient(ctx context.Context, r *Rollout, cu *ClientUpdate, artifacts map[string]*Artifact) {
+	status, err := m.queryStatus(cu.ClientID)
+	if err != nil {
+		m.setClient(r, cu, ClientFailed, fmt.Sprintf("query status: %v", err))
+		return
+	}
+	m.mu.Lock()
+	resumed := cu.StartedAt != nil
+	cu.Platform = status.Platform
+	if !resumed {
+		now := time.Now()
+		cu.StartedAt = &now
+		cu.FromVersion = status.Version
+	}
+	m.mu.Unlock()
+
+	if status.Version == r.Version && (resumed || !r.Force) {
+		if resumed {
+			// 中断前已完成升级
+			m.setClient(r, cu, ClientSucceeded, "")
+		} else {
+			m.setClient(r, cu, ClientSkipped, "")
+		}
+		return
+	}
+	a, ok := artifacts[status.Platform]
+	if !ok {
+		m.setClient(r, cu, ClientFailed, fmt.Sprintf("no artifact for platform %s", status.Platform))
+		return
+	}
+	m.mu.Lock()
+	cu.ArtifactID = a.ID
+	m.mu.Unlock()
+
+	updateID := uuid.New().String()
+	m.setClient(r, cu, ClientPushing, "")
+	if err := m.pushArtifact(ctx, cu.ClientID, updateID, a); err != nil {
+		m.setClient(r, cu, ClientFailed, fmt.Sprintf("push binary: %v", err))
+		return
+	}
+
+	m.setClient(r, cu, ClientInstalling, "")
+	payload, _ := json.Marshal(command.AgentUpdateParams{
+		UpdateID:            updateID,
+		Version:             a.Version,
+		OS:                  a.OS,
+		Arch:                a.Arch,
+		SHA256:              a.SHA256,
+		Signature:           a.Signature,
+		ReconnectTimeoutSec: r.ReconnectTimeoutSec,
+		AllowDowngrade:      r.AllowDowngrade,
+	})
+	if _, err := m.call(cu.ClientID, command.CmdAgentUpdate, payload, installTimeout); err != nil {
+		m.setClient(r, cu, ClientFailed, fmt.Sprintf("install: %v", err))
+		return
+	}
+
+	m.setClient(r, cu, ClientVerifying, "")
+	deadline := time.Now().Add(time.Duration(r.ReconnectTimeoutSec)*time.Second + verifyGrace)
+	ticker := time.NewTicker(m.pollInterval)
+	defer ticker.Stop()
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// Agent 重启期间查询失败属于正常情况
+		status, err := m.queryStatus(cu.ClientID)
+		if err != nil || status.UpdateID != updateID {
+			continue
+		}
+		switch status.State {
+		case StateCommitted:
+			if status.Version != r.Version {
+				m.setClient(r, cu, ClientFailed, fmt.Sprintf("agent reports version %s after update", status.Version))
+			} else {
+				m.setClient(r, cu, ClientSucceeded, "")
+			}
+			return
+		case StateRolledBack:
+			m.setClient(r, cu, ClientRolledBack, status.Message)
+			return
+		}
+	}
+	m.setClient(r, cu, ClientFailed, "agent did not confirm the new version in time")
+}
+
+// pushArtifact 从存储读取升级包并推送给客户端
+func (m *Manager) pushArtifact(ctx context.Context, clientID, updateID string, a *Artifact) error {
+	ctx, cancel := context.WithTimeout(ctx, pushTimeout)
+	defer cancel()
+	rc, _, err := m.source.Retrieve(ctx, a.Path)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return m.push(ctx, clientID, updateID, path.Base(a.Path), a.Size, a.SHA256, rc)
+}
+
+// queryStatus 查询客户端版本与升级状态
+func (m *Manager) queryStatus(clientID string) (*command.AgentUpdateResult, error) {
+	return m.call(clientID, command.CmdAgentUpdateStatus, nil, statusTimeout)
+}
+
+// call 向单个客户端下发命令并解析 AgentUpdateResult
+func (m *Manager) call(clientID, commandType string, payload json.RawMessage, timeout time.Duration) (*command.AgentUpdateResult, error) {
+	resp := m.sender.SendCommandToMultiple([]string{clientID}, commandType, payload, timeout)
+	if resp == nil || len(resp.Results) == 0 || resp.Results[0] == nil {
+		return nil, fmt.Errorf("no response from client")
+	}
+	res := resp.Results[0]
+	if res.Status != command.CommandStatusCompleted {
+		if res.Error != "" {
+			return nil, fmt.Errorf("%s: %s", res.Status, res.Error)
+		}
+		return nil, fmt.Errorf("command %s", res.Status)
+	}
+	var result command.AgentUpdateResult
+	if err := json.Unmarshal(res.Result, &result); err != nil {
+		return nil, fmt.Errorf("invalid result: %w", err)
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("%s", result.Error)
+	}
+	return &result, nil
+}
+
+// setClient 更新客户端状态并保存任务
+func (m *Manager) setClient(r *Rollout, cu *ClientUpdate, status ClientStatus, errMsg string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cu.Status = status
+	cu.Error = errMsg
+	if status.finished() {
+		now := time.Now()
+		cu.FinishedAt = &now
+		m.logger.Info("Agent update client finished", "rollout_id", r.ID, "client_id", cu.ClientID, "status", status, "error", errMsg)
+	}
+	m.save(r)
+}
+
+// finish 结束升级任务（调用方持有锁）
+func (m *Manager) finish(r *Rollout, status RolloutStatus) {
+	now := time.Now()
+	r.Status = status
+	r.FinishedAt = &now
+	m.save(r)
+	delete(m.active, r.ID)
+	if status == RolloutCompleted {
+		m.logger.Info("Agent update rollout completed", "rollout_id", r.ID, "succeeded", r.Succeeded, "failed", r.Failed, "skipped", r.Skipped)
+	}
+}
+
+// save 统计并保存任务（调用方持有锁）
+func (m *Manager) save(r *Rollout) {
+	r.recount()
+	if err := m.store.SaveRollout(r); err != nil {
+		m.logger.Warn("Failed to save agent update rollout", "rollout_id", r.ID, "error", err)
+	}
+}
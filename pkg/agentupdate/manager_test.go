@@ -0,0 +1,237 @@
+package agentupdate
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/batch"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/filetransfer"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+)
+
+// fakeSource 内存中的升级包存储
+type fakeSource map[string][]byte
+
+func (s fakeSource) Retrieve(ctx context.Context, path string) (io.ReadCloser, filetransfer.FileMeta, error) {
+	data, ok := s[path]
+	if !ok {
+		return nil, filetransfer.FileMeta{}, filetransfer.ErrFileNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), filetransfer.FileMeta{Path: path, Size: int64(len(data))}, nil
+}
+
+// fakeAgent 模拟的客户端升级状态
+type fakeAgent struct {
+	version  string
+	state    string
+	updateID string
+	pushed   string
+	rollback bool // 安装后回滚
+}
+
+// fakeFleet 模拟 agent.update_status / agent.update 与二进制推送
+type fakeFleet struct {
+	mu     sync.Mutex
+	agents map[string]*fakeAgent
+}
+
+func newFakeFleet(version string, ids ...string) *fakeFleet {
+	f := &fakeFleet{agents: make(map[string]*fakeAgent)}
+	for _, id := range ids {
+		f.agents[id] = &fakeAgent{version: version}
+	}
+	return f
+}
+
+func (f *fakeFleet) SendCommandToMultiple(clientIDs []string, commandType string, payload json.RawMessage, timeout time.Duration) *command.MultiCommandResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resp := &command.MultiCommandResponse{Total: len(clientIDs)}
+	for _, cid := range clientIDs {
+		r := &command.ClientCommandResult{ClientID: cid, Status: command.CommandStatusCompleted}
+		a, ok := f.agents[cid]
+		if !ok {
+			r.Status = command.CommandStatusFailed
+			r.Error = "client not connected"
+			resp.Results = append(resp.Results, r)
+			continue
+		}
+		if commandType == command.CmdAgentUpdate {
+			var params command.AgentUpdateParams
+			json.Unmarshal(payload, &params)
+			a.updateID = params.UpdateID
+			if a.rollback || params.UpdateID != a.pushed {
+				a.state = StateRolledBack
+			} else {
+				a.version, a.state = params.Version, StateCommitted
+			}
+		}
+		r.Result, _ = json.Marshal(command.AgentUpdateResult{
+			Success:  true,
+			Version:  a.version,
+			Platform: "linux/amd64",
+			State:    a.state,
+			UpdateID: a.updateID,
+		})
+		resp.Results = append(resp.Results, r)
+	}
+	return resp
+}
+
+func (f *fakeFleet) push(ctx context.Context, clientID, updateID, name string, size int64, checksum string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if sha256Hex(data) != checksum || int64(len(data)) != size {
+		return assert.AnError
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.agents[clientID].pushed = updateID
+	return nil
+}
+
+func (f *fakeFleet) version(id string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.agents[id].version
+}
+
+func newTestManager(t *testing.T, fleet *fakeFleet) *Manager {
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	source := fakeSource{"/agents/quic-client-v2": []byte("new binary")}
+	m := NewManager(store, source, fleet, fleet.push, monitoring.NewDefaultLogger())
+	m.pollInterval = 10 * time.Millisecond
+	_, priv, _ := ed25519.GenerateKey(nil)
+	m.SetSigner(priv)
+
+	_, err = m.RegisterArtifact(context.Background(), &Artifact{Version: "v2", OS: "linux", Arch: "amd64", Path: "/agents/quic-client-v2"})
+	require.NoError(t, err)
+	return m
+}
+
+// waitRollout 等待升级任务离开 running 状态
+func waitRollout(t *testing.T, m *Manager, id string) *Rollout {
+	var r *Rollout
+	require.Eventually(t, func() bool {
+		var err error
+		r, err = m.Get(id)
+		require.NoError(t, err)
+		return r.Status != RolloutRunning
+	}, 5*time.Second, 10*time.Millisecond)
+	return r
+}
+
+func TestRegisterArtifact(t *testing.T) {
+	m := newTestManager(t, newFakeFleet("v1"))
+	list := m.ListArtifacts()
+	require.Len(t, list, 1)
+	assert.Equal(t, sha256Hex([]byte("new binary")), list[0].SHA256)
+	assert.NotEmpty(t, list[0].Signature)
+	assert.Equal(t, int64(len("new binary")), list[0].Size)
+
+	_, err := m.RegisterArtifact(context.Background(), &Artifact{Version: "v3", OS: "linux", Arch: "amd64", Path: "/missing"})
+	assert.ErrorIs(t, err, filetransfer.ErrFileNotFound)
+}
+
+func TestRolloutSucceeds(t *testing.T) {
+	fleet := newFakeFleet("v1", "c1", "c2", "c3")
+	fleet.agents["c3"].version = "v2"
+	m := newTestManager(t, fleet)
+
+	r, err := m.StartRollout(&RolloutRequest{Version: "v2", ClientIDs: []string{"c1", "c2", "c3", "c4"}})
+	require.NoError(t, err)
+	r = waitRollout(t, m, r.ID)
+
+	assert.Equal(t, RolloutCompleted, r.Status)
+	assert.Equal(t, 2, r.Succeeded)
+	assert.Equal(t, 1, r.Skipped)
+	assert.Equal(t, 1, r.Failed) // c4 未连接
+	assert.Equal(t, "v2", fleet.version("c1"))
+	assert.Equal(t, "v2", fleet.version("c2"))
+}
+
+func TestRolloutHaltsAndResumes(t *testing.T) {
+	fleet := newFakeFleet("v1", "c1", "c2", "c3")
+	fleet.agents["c1"].rollback = true
+	m := newTestManager(t, fleet)
+
+	r, err := m.StartRollout(&RolloutRequest{
+		Version:   "v2",
+		ClientIDs: []string{"c1", "c2", "c3"},
+		Policy:    &batch.RolloutPolicy{FirstWave: 1, MaxFailureRate: 50},
+	})
+	require.NoError(t, err)
+	r = waitRollout(t, m, r.ID)
+
+	assert.Equal(t, RolloutHalted, r.Status)
+	assert.Equal(t, ClientRolledBack, r.Clients[0].Status)
+	assert.Equal(t, ClientPending, r.Clients[1].Status)
+	assert.Equal(t, "v1", fleet.version("c2"))
+
+	_, err = m.Resume(r.ID)
+	require.NoError(t, err)
+	r = waitRollout(t, m, r.ID)
+	assert.Equal(t, RolloutCompleted, r.Status)
+	assert.Equal(t, 2, r.Succeeded)
+	assert.Equal(t, 1, r.Failed)
+}
+
+func TestRolloutRecover(t *testing.T) {
+	fleet := newFakeFleet("v1", "c1", "c2")
+	m := newTestManager(t, fleet)
+
+	r, err := m.StartRollout(&RolloutRequest{
+		Version:   "v2",
+		ClientIDs: []string{"c1", "c2"},
+		Policy:    &batch.RolloutPolicy{FirstWave: 1, PauseBetweenWaves: true},
+	})
+	require.NoError(t, err)
+	r = waitRollout(t, m, r.ID)
+	require.Equal(t, RolloutPaused, r.Status)
+
+	// 模拟服务重启
+	restarted := NewManager(m.store, m.source, fleet, fleet.push, monitoring.NewDefaultLogger())
+	restarted.pollInterval = 10 * time.Millisecond
+	restarted.Recover()
+	r, err = restarted.Get(r.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RolloutInterrupted, r.Status)
+
+	_, err = restarted.Resume(r.ID)
+	require.NoError(t, err)
+	r = waitRollout(t, restarted, r.ID)
+	assert.Equal(t, RolloutCompleted, r.Status)
+	assert.Equal(t, 2, r.Succeeded)
+}
+
+func TestCancelRollout(t *testing.T) {
+	m := newTestManager(t, newFakeFleet("v1", "c1", "c2"))
+	r, err := m.StartRollout(&RolloutRequest{
+		Version:   "v2",
+		ClientIDs: []string{"c1", "c2"},
+		Policy:    &batch.RolloutPolicy{FirstWave: 1, PauseBetweenWaves: true},
+	})
+	require.NoError(t, err)
+	waitRollout(t, m, r.ID)
+
+	r, err = m.Cancel(r.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RolloutCancelled, r.Status)
+	_, err = m.Resume(r.ID)
+	assert.Error(t, err)
+
+	_, err = m.Get("missing")
+	assert.ErrorIs(t, err, ErrRolloutNotFound)
+}
@@ -0,0 +1,153 @@
+package agentupdate
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidSignature 签名校验失败
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// manifestHeader 签名清单的首行（区分用途与格式版本）
+const manifestHeader = "quic-client-update/v1"
+
+// Manifest 签名清单：版本、平台与二进制摘要一起签名，
+// 避免旧版本或其他平台的已签名二进制被当作目标版本安装
+// 签名与密钥均使用标准 base64 编码
+type Manifest struct {
+	Version string
+	OS      string
+	Arch    string
+	SHA256  string // 十六进制
+}
+
+// Bytes 返回规范化的签名内容（逐行 key=value，顺序固定）
+func (m Manifest) Bytes() ([]byte, error) {
+	digest, err := hex.DecodeString(m.SHA256)
+	if err != nil || len(digest) != sha256.Size {
+		return nil, fmt.Errorf("invalid sha256: %s", m.SHA256)
+	}
+	for _, v := range []string{m.Version, m.OS, m.Arch} {
+		if v == "" || strings.ContainsAny(v, "\n\r") {
+			return nil, fmt.Errorf("invalid manifest: version, os and arch are required")
+		}
+	}
+	return []byte(fmt.Sprintf("%s\nversion=%s\nos=%s\narch=%s\nsha256=%s\n",
+		manifestHeader, m.Version, m.OS, m.Arch, strings.ToLower(m.SHA256))), nil
+}
+
+// Sign 使用私钥对清单签名
+func Sign(key ed25519.PrivateKey, m Manifest) (string, error) {
+	data, err := m.Bytes()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)), nil
+}
+
+// Verify 使用公钥校验清单的签名
+func Verify(key ed25519.PublicKey, m Manifest, signature string) error {
+	data, err := m.Bytes()
+	if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if !ed25519.Verify(key, data, sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// CompareVersions 比较语义化版本号的主、次、修订号（可带 v 前缀）
+// 任一版本无法解析（如 dev 构建）时 ok 为 false
+func CompareVersions(a, b string) (cmp int, ok bool) {
+	pa, okA := parseVersion(a)
+	pb, okB := parseVersion(b)
+	if !okA || !okB {
+		return 0, false
+	}
+	for i := range pa {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, true
+}
+
+// parseVersion 解析 [v]major[.minor[.patch]]，忽略预发布与构建元数据
+func parseVersion(s string) ([3]int, bool) {
+	var v [3]int
+	s = strings.TrimPrefix(s, "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return v, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		v[i] = n
+	}
+	return v, true
+}
+
+// ParsePublicKey 解析 base64 编码的 ed25519 公钥
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	if len(data) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key size: %d", len(data))
+	}
+	return ed25519.PublicKey(data), nil
+}
+
+// ParsePrivateKey 解析 base64 编码的 ed25519 私钥（64 字节私钥或 32 字节种子）
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	switch len(data) {
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(data), nil
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(data), nil
+	default:
+		return nil, fmt.Errorf("invalid private key size: %d", len(data))
+	}
+}
+
+// FileSHA256 计算文件的 SHA256（十六进制）
+func FileSHA256(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
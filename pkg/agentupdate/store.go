@@ -0,0 +1,178 @@
+package agentupdate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/voilet/quic-flow/pkg/jsonfile"
+)
+
+// 存储错误
+var (
+	ErrArtifactNotFound = errors.New("artifact not found")
+	ErrRolloutNotFound  = errors.New("rollout not found")
+)
+
+// maxRollouts 保留的升级任务数量
+const maxRollouts = 100
+
+// Store 升级包与升级任务存储（JSON 文件）
+type Store struct {
+	file      *jsonfile.File
+	artifacts map[string]*Artifact
+	rollouts  map[string]*Rollout
+}
+
+// storeFile 持久化文件格式
+type storeFile struct {
+	Artifacts []*Artifact `json:"artifacts"`
+	Rollouts  []*Rollout  `json:"rollouts"`
+}
+
+// NewStore 创建存储，dir 为空时仅保存在内存中
+func NewStore(dir string) (*Store, error) {
+	var f storeFile
+	file, err := jsonfile.Open(dir, "agent_updates.json", &f)
+	if err != nil {
+		return nil, fmt.Errorf("open agent update store: %w", err)
+	}
+	s := &Store{
+		file:      file,
+		artifacts: make(map[string]*Artifact),
+		rollouts:  make(map[string]*Rollout),
+	}
+	for _, a := range f.Artifacts {
+		s.artifacts[a.ID] = a
+	}
+	for _, r := range f.Rollouts {
+		s.rollouts[r.ID] = r
+	}
+	return s, nil
+}
+
+// ListArtifacts 列出升级包（按版本、平台排序）
+func (s *Store) ListArtifacts() []*Artifact {
+	s.file.RLock()
+	defer s.file.RUnlock()
+	list := make([]*Artifact, 0, len(s.artifacts))
+	for _, a := range s.artifacts {
+		cp := *a
+		list = append(list, &cp)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Version != list[j].Version {
+			return list[i].Version < list[j].Version
+		}
+		return list[i].Platform() < list[j].Platform()
+	})
+	return list
+}
+
+// GetArtifact 获取升级包
+func (s *Store) GetArtifact(id string) (*Artifact, error) {
+	s.file.RLock()
+	defer s.file.RUnlock()
+	a, ok := s.artifacts[id]
+	if !ok {
+		return nil, ErrArtifactNotFound
+	}
+	cp := *a
+	return &cp, nil
+}
+
+// FindArtifacts 按版本查找升级包，返回 platform -> 升级包
+func (s *Store) FindArtifacts(version string) map[string]*Artifact {
+	s.file.RLock()
+	defer s.file.RUnlock()
+	found := make(map[string]*Artifact)
+	for _, a := range s.artifacts {
+		if a.Version == version {
+			cp := *a
+			found[a.Platform()] = &cp
+		}
+	}
+	return found
+}
+
+// SaveArtifact 保存升级包（同版本同平台的旧记录被替换）
+func (s *Store) SaveArtifact(a *Artifact) error {
+	s.file.Lock()
+	defer s.file.Unlock()
+	for id, old := range s.artifacts {
+		if old.Version == a.Version && old.Platform() == a.Platform() && id != a.ID {
+			delete(s.artifacts, id)
+		}
+	}
+	cp := *a
+	s.artifacts[a.ID] = &cp
+	return s.persist()
+}
+
+// DeleteArtifact 删除升级包
+func (s *Store) DeleteArtifact(id string) error {
+	s.file.Lock()
+	defer s.file.Unlock()
+	if _, ok := s.artifacts[id]; !ok {
+		return ErrArtifactNotFound
+	}
+	delete(s.artifacts, id)
+	return s.persist()
+}
+
+// ListRollouts 列出升级任务（最新在前）
+func (s *Store) ListRollouts() []*Rollout {
+	s.file.RLock()
+	defer s.file.RUnlock()
+	list := make([]*Rollout, 0, len(s.rollouts))
+	for _, r := range s.rollouts {
+		list = append(list, r.clone())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list
+}
+
+// GetRollout 获取升级任务
+func (s *Store) GetRollout(id string) (*Rollout, error) {
+	s.file.RLock()
+	defer s.file.RUnlock()
+	r, ok := s.rollouts[id]
+	if !ok {
+		return nil, ErrRolloutNotFound
+	}
+	return r.clone(), nil
+}
+
+// SaveRollout 保存升级任务，超出 maxRollouts 时删除最早的已结束任务
+func (s *Store) SaveRollout(r *Rollout) error {
+	s.file.Lock()
+	defer s.file.Unlock()
+	s.rollouts[r.ID] = r.clone()
+	if len(s.rollouts) > maxRollouts {
+		var oldest *Rollout
+		for _, old := range s.rollouts {
+			if old.FinishedAt != nil && (oldest == nil || old.CreatedAt.Before(oldest.CreatedAt)) {
+				oldest = old
+			}
+		}
+		if oldest != nil {
+			delete(s.rollouts, oldest.ID)
+		}
+	}
+	return s.persist()
+}
+
+// persist 写入存储文件（调用方持有锁）
+func (s *Store) persist() error {
+	f := storeFile{
+		Artifacts: make([]*Artifact, 0, len(s.artifacts)),
+		Rollouts:  make([]*Rollout, 0, len(s.rollouts)),
+	}
+	for _, a := range s.artifacts {
+		f.Artifacts = append(f.Artifacts, a)
+	}
+	for _, r := range s.rollouts {
+		f.Rollouts = append(f.Rollouts, r)
+	}
+	return s.file.Save(f)
+}
@@ -0,0 +1,186 @@
+package agentupdate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/quic-go/quic-go"
+	"github.com/voilet/quic-flow/pkg/filetransfer"
+	"github.com/voilet/quic-flow/pkg/fsutil"
+	quicssh "github.com/voilet/quic-flow/pkg/ssh"
+)
+
+// 传输参数
+const (
+	chunkSize     = 256 * 1024
+	MaxBinarySize = 512 * 1024 * 1024 // 单个二进制上限
+
+	statusReceived = "received"
+)
+
+// 传输错误码（ErrorFrame.Code）
+const (
+	errCodeInvalid  = 1
+	errCodeChecksum = 2
+	errCodeStorage  = 3
+)
+
+// updateIDRe 升级 ID 格式（用作暂存文件名）
+var updateIDRe = regexp.MustCompile(`^[A-Za-z0-9-]{1,64}$`)
+
+// Send 通过流发送二进制：Init → Data... → Complete，然后等待 Agent 的确认帧
+func Send(rw io.ReadWriter, updateID, name string, size int64, checksum string, r io.Reader) error {
+	w := filetransfer.NewFrameWriter(rw)
+	if err := w.WriteFrame(filetransfer.NewInitFrame(updateID, name, size, checksum, chunkSize)); err != nil {
+		return fmt.Errorf("send init: %w", err)
+	}
+
+	buf := make([]byte, chunkSize)
+	var offset int64
+	var seq uint64
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if werr := w.WriteFrame(filetransfer.NewDataFrame(updateID, seq, offset, buf[:n])); werr != nil {
+				return fmt.Errorf("send data: %w", werr)
+			}
+			offset += int64(n)
+			seq++
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read binary: %w", err)
+		}
+	}
+	if offset != size {
+		return fmt.Errorf("binary size mismatch: sent %d, expected %d", offset, size)
+	}
+	if err := w.WriteFrame(filetransfer.NewCompleteFrame(updateID, checksum, "sent")); err != nil {
+		return fmt.Errorf("send complete: %w", err)
+	}
+
+	frame, err := filetransfer.NewFrameReader(rw).ReadFrame()
+	if err != nil {
+		return fmt.Errorf("read agent ack: %w", err)
+	}
+	switch f := frame.(type) {
+	case *filetransfer.CompleteFrame:
+		if f.Status != statusReceived || f.Checksum != checksum {
+			return fmt.Errorf("unexpected agent ack: %s", f.Status)
+		}
+		return nil
+	case *filetransfer.ErrorFrame:
+		return fmt.Errorf("agent rejected binary: %s", f.Message)
+	default:
+		return fmt.Errorf("unexpected frame type: %d", frame.Type())
+	}
+}
+
+// PushToConn 在客户端连接上打开文件传输流并发送二进制
+func PushToConn(ctx context.Context, conn *quic.Conn, updateID, name string, size int64, checksum string, r io.Reader) error {
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return fmt.Errorf("open stream: %w", err)
+	}
+	defer stream.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetDeadline(deadline)
+	}
+	if err := quicssh.WriteHeader(stream, quicssh.StreamTypeFileTransfer); err != nil {
+		return fmt.Errorf("write stream header: %w", err)
+	}
+	return Send(stream, updateID, name, size, checksum, r)
+}
+
+// receive 从流中接收二进制并写入 dir/<update_id>.bin，校验大小与 SHA256 后回复确认帧
+// 返回升级 ID 与暂存文件路径
+func receive(rw io.ReadWriter, dir string) (string, string, error) {
+	reader := filetransfer.NewFrameReader(rw)
+	writer := filetransfer.NewFrameWriter(rw)
+
+	frame, err := reader.ReadFrame()
+	if err != nil {
+		return "", "", fmt.Errorf("read init frame: %w", err)
+	}
+	init, ok := frame.(*filetransfer.InitFrame)
+	if !ok {
+		return "", "", fmt.Errorf("expected init frame, got %d", frame.Type())
+	}
+	fail := func(code int, err error) (string, string, error) {
+		writer.WriteFrame(filetransfer.NewErrorFrame(init.TaskID, code, err.Error()))
+		return "", "", err
+	}
+	if !updateIDRe.MatchString(init.TaskID) {
+		return fail(errCodeInvalid, fmt.Errorf("invalid update id: %q", init.TaskID))
+	}
+	if init.FileSize <= 0 || init.FileSize > MaxBinarySize {
+		return fail(errCodeInvalid, fmt.Errorf("invalid binary size: %d", init.FileSize))
+	}
+
+	if err := fsutil.EnsurePrivateDir(dir); err != nil {
+		return fail(errCodeStorage, fmt.Errorf("staging dir: %w", err))
+	}
+	// 重新推送同一升级时先删除旧文件，再以 O_EXCL 新建，不复用已存在的文件或符号链接
+	path := stagedPath(dir, init.TaskID)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fail(errCodeStorage, err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fail(errCodeStorage, err)
+	}
+	done := false
+	defer func() {
+		f.Close()
+		if !done {
+			os.Remove(path)
+		}
+	}()
+
+	h := sha256.New()
+	var written int64
+	for {
+		frame, err := reader.ReadFrame()
+		if err != nil {
+			return fail(errCodeInvalid, fmt.Errorf("read frame: %w", err))
+		}
+		switch fr := frame.(type) {
+		case *filetransfer.DataFrame:
+			if fr.Offset != written || written+int64(len(fr.Data)) > init.FileSize {
+				return fail(errCodeInvalid, fmt.Errorf("unexpected data at offset %d", fr.Offset))
+			}
+			if _, err := f.Write(fr.Data); err != nil {
+				return fail(errCodeStorage, err)
+			}
+			h.Write(fr.Data)
+			written += int64(len(fr.Data))
+		case *filetransfer.CompleteFrame:
+			if written != init.FileSize {
+				return fail(errCodeInvalid, fmt.Errorf("size mismatch: got %d, expected %d", written, init.FileSize))
+			}
+			if sum := hex.EncodeToString(h.Sum(nil)); sum != init.Checksum {
+				return fail(errCodeChecksum, fmt.Errorf("checksum mismatch: got %s, expected %s", sum, init.Checksum))
+			}
+			if err := f.Sync(); err != nil {
+				return fail(errCodeStorage, err)
+			}
+			done = true
+			if err := writer.WriteFrame(filetransfer.NewCompleteFrame(init.TaskID, init.Checksum, statusReceived)); err != nil {
+				return "", "", err
+			}
+			return init.TaskID, path, nil
+		case *filetransfer.ErrorFrame:
+			return "", "", errors.New(fr.Message)
+		default:
+			return fail(errCodeInvalid, fmt.Errorf("unexpected frame type: %d", frame.Type()))
+		}
+	}
+}
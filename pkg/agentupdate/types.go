@@ -0,0 +1,151 @@
+package agentupdate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/batch"
+)
+
+// Artifact Agent 升级包（二进制位于文件传输存储中）
+type Artifact struct {
+	ID        string    `json:"id"`
+	Version   string    `json:"version"`
+	OS        string    `json:"os"`
+	Arch      string    `json:"arch"`
+	Path      string    `json:"path"` // 文件传输存储中的路径
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	Signature string    `json:"signature"` // 对版本、平台与 SHA256 清单的 ed25519 签名（base64）
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Platform 返回 os/arch
+func (a *Artifact) Platform() string {
+	return a.OS + "/" + a.Arch
+}
+
+// RolloutStatus 升级任务状态
+type RolloutStatus string
+
+const (
+	RolloutRunning     RolloutStatus = "running"
+	RolloutPaused      RolloutStatus = "paused" // 批次间等待批准
+	RolloutHalted      RolloutStatus = "halted" // 失败率超过阈值
+	RolloutCompleted   RolloutStatus = "completed"
+	RolloutCancelled   RolloutStatus = "cancelled"
+	RolloutInterrupted RolloutStatus = "interrupted" // 服务重启时未完成
+)
+
+// ClientStatus 单个客户端的升级状态
+type ClientStatus string
+
+const (
+	ClientPending    ClientStatus = "pending"
+	ClientSkipped    ClientStatus = "skipped" // 已是目标版本
+	ClientPushing    ClientStatus = "pushing"
+	ClientInstalling ClientStatus = "installing"
+	ClientVerifying  ClientStatus = "verifying" // 等待新版本重连
+	ClientSucceeded  ClientStatus = "succeeded"
+	ClientFailed     ClientStatus = "failed"
+	ClientRolledBack ClientStatus = "rolled_back"
+)
+
+// finished 是否为终态
+func (s ClientStatus) finished() bool {
+	switch s {
+	case ClientSkipped, ClientSucceeded, ClientFailed, ClientRolledBack:
+		return true
+	}
+	return false
+}
+
+// RolloutRequest 升级任务请求
+type RolloutRequest struct {
+	Version             string               `json:"version"`
+	ClientIDs           []string             `json:"client_ids"`                      // 目标客户端（已按标签解析）
+	Policy              *batch.RolloutPolicy `json:"rollout,omitempty"`               // 分批策略（与批量任务一致）
+	ReconnectTimeoutSec int                  `json:"reconnect_timeout_sec,omitempty"` // Agent 重连超时，默认 60
+	Force               bool                 `json:"force,omitempty"`                 // 已是目标版本时也重新安装
+	AllowDowngrade      bool                 `json:"allow_downgrade,omitempty"`       // 允许 Agent 安装低于当前运行版本的目标版本
+}
+
+// Validate 校验请求
+func (r *RolloutRequest) Validate() error {
+	if r.Version == "" {
+		return fmt.Errorf("version is required")
+	}
+	if len(r.ClientIDs) == 0 {
+		return fmt.Errorf("no target clients")
+	}
+	if r.Policy != nil {
+		if err := r.Policy.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.ReconnectTimeoutSec < 0 || r.ReconnectTimeoutSec > 3600 {
+		return fmt.Errorf("reconnect_timeout_sec must be between 0 and 3600")
+	}
+	return nil
+}
+
+// ClientUpdate 单个客户端的升级记录
+type ClientUpdate struct {
+	ClientID    string       `json:"client_id"`
+	Wave        int          `json:"wave"`
+	Platform    string       `json:"platform,omitempty"`
+	FromVersion string       `json:"from_version,omitempty"`
+	ArtifactID  string       `json:"artifact_id,omitempty"`
+	Status      ClientStatus `json:"status"`
+	Error       string       `json:"error,omitempty"`
+	StartedAt   *time.Time   `json:"started_at,omitempty"`
+	FinishedAt  *time.Time   `json:"finished_at,omitempty"`
+}
+
+// Rollout 分批升级任务
+type Rollout struct {
+	ID                  string               `json:"id"`
+	Version             string               `json:"version"`
+	Policy              *batch.RolloutPolicy `json:"rollout,omitempty"`
+	ReconnectTimeoutSec int                  `json:"reconnect_timeout_sec"`
+	Force               bool                 `json:"force,omitempty"`
+	AllowDowngrade      bool                 `json:"allow_downgrade,omitempty"`
+	Status              RolloutStatus        `json:"status"`
+	WaveCount           int                  `json:"wave_count"`
+	CurrentWave         int                  `json:"current_wave"`
+	HaltReason          string               `json:"halt_reason,omitempty"`
+	Total               int                  `json:"total"`
+	Succeeded           int                  `json:"succeeded"`
+	Failed              int                  `json:"failed"`
+	Skipped             int                  `json:"skipped"`
+	Clients             []*ClientUpdate      `json:"clients"`
+	CreatedAt           time.Time            `json:"created_at"`
+	FinishedAt          *time.Time           `json:"finished_at,omitempty"`
+}
+
+// clone 深拷贝
+func (r *Rollout) clone() *Rollout {
+	cp := *r
+	cp.Clients = make([]*ClientUpdate, len(r.Clients))
+	for i, c := range r.Clients {
+		cc := *c
+		cp.Clients[i] = &cc
+	}
+	return &cp
+}
+
+// recount 重新统计各状态数量
+func (r *Rollout) recount() {
+	r.Total = len(r.Clients)
+	r.Succeeded, r.Failed, r.Skipped = 0, 0, 0
+	for _, c := range r.Clients {
+		switch c.Status {
+		case ClientSucceeded:
+			r.Succeeded++
+		case ClientFailed, ClientRolledBack:
+			r.Failed++
+		case ClientSkipped:
+			r.Skipped++
+		}
+	}
+}
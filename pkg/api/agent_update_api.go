@@ -0,0 +1,220 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/voilet/quic-flow/pkg/agentupdate"
+	"github.com/voilet/quic-flow/pkg/batch"
+	"github.com/voilet/quic-flow/pkg/filetransfer"
+)
+
+// AgentUpdateAPI Agent 自升级 API
+type AgentUpdateAPI struct {
+	manager *agentupdate.Manager
+	resolve func(candidates []string, selector map[string]string) []string
+}
+
+// AgentRolloutRequest 创建升级任务请求
+// client_ids 与 labels 至少指定一个；同时指定时取交集
+type AgentRolloutRequest struct {
+	Version             string               `json:"version" binding:"required"`
+	ClientIDs           []string             `json:"client_ids,omitempty"`
+	Labels              map[string]string    `json:"labels,omitempty"`
+	Rollout             *batch.RolloutPolicy `json:"rollout,omitempty"`
+	ReconnectTimeoutSec int                  `json:"reconnect_timeout_sec,omitempty"`
+	Force               bool                 `json:"force,omitempty"`
+	AllowDowngrade      bool                 `json:"allow_downgrade,omitempty"`
+}
+
+// NewAgentUpdateAPI 创建 Agent 自升级 API
+func NewAgentUpdateAPI(manager *agentupdate.Manager) *AgentUpdateAPI {
+	return &AgentUpdateAPI{manager: manager}
+}
+
+// RegisterRoutes 注册路由
+func (a *AgentUpdateAPI) RegisterRoutes(r *gin.RouterGroup) {
+	updates := r.Group("/agent-updates")
+	{
+		updates.GET("/artifacts", a.ListArtifacts)
+		updates.POST("/artifacts", a.RegisterArtifact)
+		updates.GET("/artifacts/:id", a.GetArtifact)
+		updates.DELETE("/artifacts/:id", a.DeleteArtifact)
+
+		updates.GET("/rollouts", a.ListRollouts)
+		updates.POST("/rollouts", a.StartRollout)
+		updates.GET("/rollouts/:id", a.GetRollout)
+		updates.POST("/rollouts/:id/resume", a.ResumeRollout)
+		updates.POST("/rollouts/:id/cancel", a.CancelRollout)
+	}
+}
+
+// ListArtifacts 列出升级包
+func (a *AgentUpdateAPI) ListArtifacts(c *gin.Context) {
+	list := a.manager.ListArtifacts()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"total":   len(list),
+		"data":    list,
+	})
+}
+
+// RegisterArtifact 登记升级包（二进制需先通过文件传输上传）
+func (a *AgentUpdateAPI) RegisterArtifact(c *gin.Context) {
+	var art agentupdate.Artifact
+	if err := c.ShouldBindJSON(&art); err != nil {
+		agentUpdateError(c, err)
+		return
+	}
+	created, err := a.manager.RegisterArtifact(c.Request.Context(), &art)
+	if err != nil {
+		agentUpdateError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    created,
+	})
+}
+
+// GetArtifact 获取升级包
+func (a *AgentUpdateAPI) GetArtifact(c *gin.Context) {
+	art, err := a.manager.GetArtifact(c.Param("id"))
+	if err != nil {
+		agentUpdateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    art,
+	})
+}
+
+// DeleteArtifact 删除升级包登记
+func (a *AgentUpdateAPI) DeleteArtifact(c *gin.Context) {
+	if err := a.manager.DeleteArtifact(c.Param("id")); err != nil {
+		agentUpdateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Artifact deleted",
+	})
+}
+
+// ListRollouts 列出升级任务
+func (a *AgentUpdateAPI) ListRollouts(c *gin.Context) {
+	list := a.manager.List()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"total":   len(list),
+		"data":    list,
+	})
+}
+
+// StartRollout 按客户端或标签创建分批升级任务
+func (a *AgentUpdateAPI) StartRollout(c *gin.Context) {
+	var req AgentRolloutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		agentUpdateError(c, err)
+		return
+	}
+	if len(req.ClientIDs) == 0 && len(req.Labels) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "client_ids or labels is required",
+		})
+		return
+	}
+	clientIDs := req.ClientIDs
+	if len(req.Labels) > 0 && a.resolve != nil {
+		clientIDs = a.resolve(clientIDs, req.Labels)
+	}
+	if len(clientIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "no clients match the selector",
+		})
+		return
+	}
+
+	rollout, err := a.manager.StartRollout(&agentupdate.RolloutRequest{
+		Version:             req.Version,
+		ClientIDs:           clientIDs,
+		Policy:              req.Rollout,
+		ReconnectTimeoutSec: req.ReconnectTimeoutSec,
+		Force:               req.Force,
+		AllowDowngrade:      req.AllowDowngrade,
+	})
+	if err != nil {
+		agentUpdateError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data":    rollout,
+	})
+}
+
+// GetRollout 获取升级任务及各客户端状态
+func (a *AgentUpdateAPI) GetRollout(c *gin.Context) {
+	rollout, err := a.manager.Get(c.Param("id"))
+	if err != nil {
+		agentUpdateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    rollout,
+	})
+}
+
+// ResumeRollout 恢复暂停、熔断或中断的升级任务
+func (a *AgentUpdateAPI) ResumeRollout(c *gin.Context) {
+	rollout, err := a.manager.Resume(c.Param("id"))
+	if err != nil {
+		agentUpdateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    rollout,
+	})
+}
+
+// CancelRollout 取消升级任务
+func (a *AgentUpdateAPI) CancelRollout(c *gin.Context) {
+	rollout, err := a.manager.Cancel(c.Param("id"))
+	if err != nil {
+		agentUpdateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    rollout,
+	})
+}
+
+// agentUpdateError 将升级错误转换为 HTTP 响应
+func agentUpdateError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, agentupdate.ErrArtifactNotFound) || errors.Is(err, agentupdate.ErrRolloutNotFound) ||
+		errors.Is(err, filetransfer.ErrFileNotFound) {
+		status = http.StatusNotFound
+	}
+	c.JSON(status, gin.H{
+		"success": false,
+		"error":   err.Error(),
+	})
+}
+
+// AddAgentUpdateRoutes 添加 Agent 自升级路由
+func (h *HTTPServer) AddAgentUpdateRoutes(manager *agentupdate.Manager) {
+	a := NewAgentUpdateAPI(manager)
+	a.resolve = func(candidates []string, selector map[string]string) []string {
+		return h.resolveLabelSelector(candidates, selector, selectorTimeout)
+	}
+	a.RegisterRoutes(h.router.Group("/api"))
+	h.logger.Info("Agent update API routes registered", "artifacts", len(manager.ListArtifacts()))
+}
@@ -1,17 +1,23 @@
 package api
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/voilet/quic-flow/pkg/batch"
+	"github.com/voilet/quic-flow/pkg/cmdschedule"
+	"github.com/voilet/quic-flow/pkg/command"
 )
 
 // BatchAPI 批量执行 API 扩展
 type BatchAPI struct {
-	executor *batch.BatchExecutor
+	executor  *batch.BatchExecutor
+	scheduler func() *cmdschedule.Scheduler // 定时调度器（可能晚于批量路由创建）
 }
 
 // NewBatchAPI 创建批量执行 API
@@ -29,40 +35,53 @@ func (b *BatchAPI) RegisterRoutes(api *gin.RouterGroup) {
 		batchGroup.GET("/jobs", b.handleListJobs)
 		batchGroup.GET("/jobs/:id", b.handleGetJob)
 		batchGroup.POST("/jobs/:id/cancel", b.handleCancelJob)
+		batchGroup.POST("/jobs/:id/resume", b.handleResumeJob)
+		batchGroup.GET("/jobs/:id/waves/:index", b.handleGetWave)
+		batchGroup.GET("/jobs/:id/aggregate", b.handleAggregateJob)
+		batchGroup.GET("/jobs/:id/export", b.handleExportJob)
 		batchGroup.GET("/stats", b.handleStats)
 	}
 }
 
 // BatchExecuteRequest 批量执行请求
 type BatchExecuteRequest struct {
-	Command       string          `json:"command" binding:"required"`       // 命令类型
-	Payload       json.RawMessage `json:"payload"`                          // 命令参数
-	TargetClients []string        `json:"target_clients"`                   // 目标客户端（空表示全部）
-	WaitForResult bool            `json:"wait_for_result"`                  // 是否等待执行结果
-	Timeout       int             `json:"timeout"`                          // 超时时间（秒）
+	Command       string               `json:"command" binding:"required"` // 命令类型
+	Payload       json.RawMessage      `json:"payload"`                    // 命令参数
+	TargetClients []string             `json:"target_clients"`             // 目标客户端（空表示全部）
+	WaitForResult bool                 `json:"wait_for_result"`            // 是否等待执行结果
+	Timeout       int                  `json:"timeout"`                    // 超时时间（秒）
+	Rollout       *batch.RolloutPolicy `json:"rollout,omitempty"`          // 分批发布策略（空表示一次性下发）
+
+	// 定时执行（指定任一项时创建计划，通过 /api/schedules 管理）
+	RunAt         *time.Time `json:"run_at,omitempty"`         // 一次性执行时间
+	Cron          string     `json:"cron,omitempty"`           // 周期执行（6 段 cron 表达式，含秒）
+	MisfirePolicy string     `json:"misfire_policy,omitempty"` // 错过执行时的处理：skip（默认）/catch_up
 }
 
 // BatchExecuteResponse 批量执行响应
 type BatchExecuteResponse struct {
-	Success bool            `json:"success"`
-	JobID   string          `json:"job_id,omitempty"`
-	Message string          `json:"message,omitempty"`
-	Error   string          `json:"error,omitempty"`
-	Job     *BatchJobInfo   `json:"job,omitempty"`
+	Success bool          `json:"success"`
+	JobID   string        `json:"job_id,omitempty"`
+	Message string        `json:"message,omitempty"`
+	Error   string        `json:"error,omitempty"`
+	Job     *BatchJobInfo `json:"job,omitempty"`
 }
 
 // BatchJobInfo 任务信息（用于 API 响应）
 type BatchJobInfo struct {
-	ID           string                 `json:"id"`
-	Status       batch.BatchJobStatus   `json:"status"`
-	Command      string                 `json:"command"`
-	CreatedAt    time.Time              `json:"created_at"`
-	TotalCount   int64                  `json:"total_count"`
-	SuccessCount int64                  `json:"success_count"`
-	FailedCount  int64                  `json:"failed_count"`
-	PendingCount int64                  `json:"pending_count"`
-	Progress     float64                `json:"progress"`
-	Duration     string                 `json:"duration,omitempty"`
+	ID           string               `json:"id"`
+	Status       batch.BatchJobStatus `json:"status"`
+	Command      string               `json:"command"`
+	CreatedAt    time.Time            `json:"created_at"`
+	TotalCount   int64                `json:"total_count"`
+	SuccessCount int64                `json:"success_count"`
+	FailedCount  int64                `json:"failed_count"`
+	PendingCount int64                `json:"pending_count"`
+	Progress     float64              `json:"progress"`
+	Duration     string               `json:"duration,omitempty"`
+	WaveCount    int                  `json:"wave_count"`
+	CurrentWave  int                  `json:"current_wave"`
+	HaltReason   string               `json:"halt_reason,omitempty"`
 }
 
 // toJobInfo 转换为 API 响应格式
@@ -86,6 +105,9 @@ func toJobInfo(job *batch.BatchJob) *BatchJobInfo {
 		FailedCount:  job.FailedCount,
 		PendingCount: job.PendingCount,
 		Progress:     progress,
+		WaveCount:    len(job.Waves),
+		CurrentWave:  job.CurrentWave,
+		HaltReason:   job.HaltReason,
 	}
 }
 
@@ -108,8 +130,23 @@ func (b *BatchAPI) handleExecute(c *gin.Context) {
 		return
 	}
 
+	if req.Rollout != nil {
+		if err := req.Rollout.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, BatchExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	if req.RunAt != nil || req.Cron != "" {
+		b.scheduleJob(c, &req)
+		return
+	}
+
 	// 创建任务
-	job, err := b.executor.Execute(req.Command, req.Payload, req.TargetClients, req.WaitForResult)
+	job, err := b.executor.ExecuteWithRollout(req.Command, req.Payload, req.TargetClients, req.WaitForResult, req.Rollout)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, BatchExecuteResponse{
 			Success: false,
@@ -126,6 +163,45 @@ func (b *BatchAPI) handleExecute(c *gin.Context) {
 	})
 }
 
+// scheduleJob 将批量执行请求保存为定时计划，每次执行创建新的批量任务
+func (b *BatchAPI) scheduleJob(c *gin.Context, req *BatchExecuteRequest) {
+	var scheduler *cmdschedule.Scheduler
+	if b.scheduler != nil {
+		scheduler = b.scheduler()
+	}
+	if scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, BatchExecuteResponse{
+			Success: false,
+			Error:   "Command scheduler not initialized",
+		})
+		return
+	}
+
+	sc, err := scheduler.Create(&cmdschedule.Schedule{
+		Kind:          cmdschedule.KindBatch,
+		ClientIDs:     req.TargetClients,
+		CommandType:   req.Command,
+		Payload:       req.Payload,
+		WaitForResult: req.WaitForResult,
+		Rollout:       req.Rollout,
+		RunAt:         req.RunAt,
+		Cron:          req.Cron,
+		MisfirePolicy: cmdschedule.MisfirePolicy(req.MisfirePolicy),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, BatchExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"success":  true,
+		"message":  "Batch job scheduled",
+		"schedule": sc,
+	})
+}
+
 // handleListJobs 处理获取任务列表请求
 func (b *BatchAPI) handleListJobs(c *gin.Context) {
 	if b.executor == nil {
@@ -182,6 +258,7 @@ func (b *BatchAPI) handleGetJob(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"job":     toJobInfo(job),
+		"waves":   job.GetWaves(),
 		"results": results,
 		"errors":  errors,
 	})
@@ -210,6 +287,248 @@ func (b *BatchAPI) handleCancelJob(c *gin.Context) {
 	}
 }
 
+// handleResumeJob 处理恢复任务请求（批准下一批次或解除熔断）
+func (b *BatchAPI) handleResumeJob(c *gin.Context) {
+	if b.executor == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Batch executor not initialized",
+		})
+		return
+	}
+
+	jobID := c.Param("id")
+	if _, found := b.executor.GetJob(jobID); !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Job not found",
+		})
+		return
+	}
+	if err := b.executor.ResumeJob(jobID); err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Job resumed",
+	})
+}
+
+// handleGetWave 处理获取单个批次结果请求
+func (b *BatchAPI) handleGetWave(c *gin.Context) {
+	if b.executor == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Batch executor not initialized",
+		})
+		return
+	}
+
+	job, found := b.executor.GetJob(c.Param("id"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Job not found",
+		})
+		return
+	}
+	waves := job.GetWaves()
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index < 0 || index >= len(waves) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Wave not found",
+		})
+		return
+	}
+
+	wave := waves[index]
+	results := make([]*batch.TaskResult, 0, len(wave.ClientIDs))
+	for _, cid := range wave.ClientIDs {
+		if val, ok := job.Results.Load(cid); ok {
+			results = append(results, val.(*batch.TaskResult))
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"wave":    wave,
+		"results": results,
+	})
+}
+
+// handleAggregateJob 处理任务结果聚合请求
+// 查询参数：field（JSON 路径）、pattern（归一化正则）、outlier_threshold（百分比）、wave（仅聚合指定批次）
+func (b *BatchAPI) handleAggregateJob(c *gin.Context) {
+	if b.executor == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Batch executor not initialized",
+		})
+		return
+	}
+
+	job, found := b.executor.GetJob(c.Param("id"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Job not found",
+		})
+		return
+	}
+
+	opts := &command.AggregateOptions{
+		Field:   c.Query("field"),
+		Pattern: c.Query("pattern"),
+	}
+	if v := c.Query("outlier_threshold"); v != "" {
+		threshold, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid outlier_threshold",
+			})
+			return
+		}
+		opts.OutlierThreshold = threshold
+	}
+	if err := opts.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	clientIDs := job.TargetClients
+	if v := c.Query("wave"); v != "" {
+		waves := job.GetWaves()
+		index, err := strconv.Atoi(v)
+		if err != nil || index < 0 || index >= len(waves) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Wave not found",
+			})
+			return
+		}
+		clientIDs = waves[index].ClientIDs
+	}
+
+	results := make([]*command.ClientCommandResult, 0, len(clientIDs))
+	for _, cid := range clientIDs {
+		if val, ok := job.Results.Load(cid); ok {
+			results = append(results, batchToCommandResult(val.(*batch.TaskResult)))
+		}
+	}
+	agg, err := command.AggregateResults(results, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"job":       toJobInfo(job),
+		"aggregate": agg,
+	})
+}
+
+// batchToCommandResult 将批量任务结果转换为命令结果，便于统一聚合
+func batchToCommandResult(r *batch.TaskResult) *command.ClientCommandResult {
+	status := command.CommandStatusCompleted
+	if !r.Success {
+		status = command.CommandStatusFailed
+	}
+	return &command.ClientCommandResult{
+		ClientID: r.ClientID,
+		Status:   status,
+		Result:   r.Result,
+		Error:    r.Error,
+	}
+}
+
+// BatchExportRow 导出的单客户端结果
+type BatchExportRow struct {
+	ClientID   string           `json:"client_id"`
+	Wave       int              `json:"wave"`
+	Status     batch.TaskStatus `json:"status"`
+	RetryCount int              `json:"retry_count"`
+	DurationMs int64            `json:"duration_ms"`
+	Error      string           `json:"error,omitempty"`
+	Result     json.RawMessage  `json:"result,omitempty"`
+}
+
+// handleExportJob 导出任务的全部客户端结果
+// 查询参数 format=json（默认）或 csv；未执行的客户端状态为 pending
+func (b *BatchAPI) handleExportJob(c *gin.Context) {
+	if b.executor == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Batch executor not initialized",
+		})
+		return
+	}
+
+	job, found := b.executor.GetJob(c.Param("id"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Job not found",
+		})
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "format must be json or csv",
+		})
+		return
+	}
+
+	rows := buildExportRows(job)
+	filename := fmt.Sprintf("batch-%s.%s", job.ID, format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "json" {
+		c.JSON(http.StatusOK, gin.H{
+			"job":     toJobInfo(job),
+			"results": rows,
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"client_id", "wave", "status", "retry_count", "duration_ms", "error", "result"})
+	for _, row := range rows {
+		_ = w.Write([]string{
+			row.ClientID,
+			strconv.Itoa(row.Wave),
+			string(row.Status),
+			strconv.Itoa(row.RetryCount),
+			strconv.FormatInt(row.DurationMs, 10),
+			row.Error,
+			string(row.Result),
+		})
+	}
+	w.Flush()
+}
+
+// buildExportRows 按目标客户端顺序整理结果
+func buildExportRows(job *batch.BatchJob) []BatchExportRow {
+	rows := make([]BatchExportRow, 0, len(job.TargetClients))
+	for _, wave := range job.GetWaves() {
+		for _, cid := range wave.ClientIDs {
+			row := BatchExportRow{ClientID: cid, Wave: wave.Index, Status: batch.TaskPending}
+			if val, ok := job.Results.Load(cid); ok {
+				r := val.(*batch.TaskResult)
+				row.Status = r.Status
+				row.RetryCount = r.RetryCount
+				row.DurationMs = r.Duration.Milliseconds()
+				row.Error = r.Error
+				row.Result = r.Result
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
 // handleStats 处理获取统计信息请求
 func (b *BatchAPI) handleStats(c *gin.Context) {
 	if b.executor == nil {
@@ -229,6 +548,7 @@ func (b *BatchAPI) handleStats(c *gin.Context) {
 // AddBatchRoutes 向 HTTPServer 添加批量执行路由
 func (h *HTTPServer) AddBatchRoutes(executor *batch.BatchExecutor) {
 	batchAPI := NewBatchAPI(executor)
+	batchAPI.scheduler = func() *cmdschedule.Scheduler { return h.scheduler }
 	api := h.router.Group("/api")
 	batchAPI.RegisterRoutes(api)
 	h.logger.Info("Batch API routes registered")
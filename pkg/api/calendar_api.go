@@ -0,0 +1,214 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/voilet/quic-flow/pkg/calendar"
+)
+
+// maxOccurrenceRange 查询生效区间的最大时间范围
+const maxOccurrenceRange = 366 * 24 * time.Hour
+
+// CalendarAPI 禁止窗口 API
+type CalendarAPI struct {
+	store *calendar.Store
+}
+
+// NewCalendarAPI 创建禁止窗口 API
+func NewCalendarAPI(store *calendar.Store) *CalendarAPI {
+	return &CalendarAPI{store: store}
+}
+
+// RegisterRoutes 注册路由
+func (a *CalendarAPI) RegisterRoutes(r *gin.RouterGroup) {
+	windows := r.Group("/calendar")
+	{
+		windows.GET("/windows", a.List)
+		windows.POST("/windows", a.Create)
+		windows.GET("/windows/:id", a.Get)
+		windows.PUT("/windows/:id", a.Update)
+		windows.DELETE("/windows/:id", a.Delete)
+		windows.POST("/windows/:id/enable", a.Enable)
+		windows.POST("/windows/:id/disable", a.Disable)
+		windows.GET("/occurrences", a.Occurrences)
+		windows.GET("/active", a.Active)
+	}
+}
+
+// List 列出禁止窗口
+func (a *CalendarAPI) List(c *gin.Context) {
+	list := a.store.List()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"total":   len(list),
+		"data":    list,
+	})
+}
+
+// Get 获取禁止窗口
+func (a *CalendarAPI) Get(c *gin.Context) {
+	w, err := a.store.Get(c.Param("id"))
+	if err != nil {
+		calendarError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    w,
+	})
+}
+
+// Create 创建禁止窗口
+func (a *CalendarAPI) Create(c *gin.Context) {
+	var w calendar.Window
+	if err := c.ShouldBindJSON(&w); err != nil {
+		calendarError(c, err)
+		return
+	}
+	created, err := a.store.Create(&w)
+	if err != nil {
+		calendarError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    created,
+	})
+}
+
+// Update 修改禁止窗口
+func (a *CalendarAPI) Update(c *gin.Context) {
+	var w calendar.Window
+	if err := c.ShouldBindJSON(&w); err != nil {
+		calendarError(c, err)
+		return
+	}
+	updated, err := a.store.Update(c.Param("id"), &w)
+	if err != nil {
+		calendarError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updated,
+	})
+}
+
+// Delete 删除禁止窗口
+func (a *CalendarAPI) Delete(c *gin.Context) {
+	if err := a.store.Delete(c.Param("id")); err != nil {
+		calendarError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Window deleted",
+	})
+}
+
+// Enable 启用禁止窗口
+func (a *CalendarAPI) Enable(c *gin.Context) {
+	a.setEnabled(c, true)
+}
+
+// Disable 停用禁止窗口
+func (a *CalendarAPI) Disable(c *gin.Context) {
+	a.setEnabled(c, false)
+}
+
+func (a *CalendarAPI) setEnabled(c *gin.Context, enabled bool) {
+	w, err := a.store.SetEnabled(c.Param("id"), enabled)
+	if err != nil {
+		calendarError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    w,
+	})
+}
+
+// Occurrences 列出 [from, to]（RFC3339，默认从现在起 30 天）内已启用窗口的生效区间，kind 为 tasks 或 releases 时只列出限制该操作的窗口
+func (a *CalendarAPI) Occurrences(c *gin.Context) {
+	from, err := queryTime(c, "from", time.Now())
+	if err != nil {
+		calendarError(c, err)
+		return
+	}
+	to, err := queryTime(c, "to", from.Add(30*24*time.Hour))
+	if err != nil {
+		calendarError(c, err)
+		return
+	}
+	if to.Before(from) || to.Sub(from) > maxOccurrenceRange {
+		calendarError(c, errors.New("to must be after from and within 366 days"))
+		return
+	}
+
+	list := a.store.Occurrences(calendar.Kind(c.Query("kind")), from, to)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"total":   len(list),
+		"data":    list,
+	})
+}
+
+// Active 列出 at（RFC3339，默认现在）生效的窗口
+// kind 默认为 tasks；group_id（可重复）与 environment_id 指定操作对象，未指定时只检查全局窗口
+func (a *CalendarAPI) Active(c *gin.Context) {
+	at, err := queryTime(c, "at", time.Now())
+	if err != nil {
+		calendarError(c, err)
+		return
+	}
+	kind := calendar.Kind(c.DefaultQuery("kind", string(calendar.KindTasks)))
+	if kind != calendar.KindTasks && kind != calendar.KindReleases {
+		calendarError(c, errors.New("kind must be tasks or releases"))
+		return
+	}
+
+	target := calendar.Target{
+		GroupIDs:      c.QueryArray("group_id"),
+		EnvironmentID: c.Query("environment_id"),
+	}
+	windows := a.store.Active(kind, target, at)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"blocked": len(windows) > 0,
+		"data":    windows,
+	})
+}
+
+// queryTime 解析 RFC3339 时间参数，未指定时返回 def
+func queryTime(c *gin.Context, name string, def time.Time) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return def, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New("invalid " + name + ": must be RFC3339")
+	}
+	return t, nil
+}
+
+// calendarError 将禁止窗口错误转换为 HTTP 响应
+func calendarError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, calendar.ErrWindowNotFound) {
+		status = http.StatusNotFound
+	}
+	c.JSON(status, gin.H{
+		"success": false,
+		"error":   err.Error(),
+	})
+}
+
+// AddCalendarRoutes 添加禁止窗口路由
+func (h *HTTPServer) AddCalendarRoutes(store *calendar.Store) {
+	NewCalendarAPI(store).RegisterRoutes(h.router.Group("/api"))
+	h.logger.Info("Calendar API routes registered", "windows", len(store.List()))
+}
@@ -0,0 +1,276 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/voilet/quic-flow/pkg/command"
+)
+
+// 配置下发限制
+const (
+	configDefaultTimeout = 30  // 秒
+	configMaxTimeout     = 120 // 秒
+)
+
+// ConfigPushRequest 批量下发 Agent 配置请求
+// client_ids 与 labels 至少指定一个；同时指定时取交集
+type ConfigPushRequest struct {
+	ClientIDs      []string          `json:"client_ids,omitempty"`       // 目标客户端
+	Labels         map[string]string `json:"labels,omitempty"`           // 标签选择器（全部匹配）
+	Patch          json.RawMessage   `json:"patch" binding:"required"`   // JSON Merge Patch
+	DryRun         bool              `json:"dry_run,omitempty"`          // 仅校验不应用
+	GracePeriodSec int               `json:"grace_period_sec,omitempty"` // 自动回滚宽限期（秒）
+	NoRollback     bool              `json:"no_rollback,omitempty"`      // 禁用自动回滚
+	Timeout        int               `json:"timeout,omitempty"`          // 命令超时（秒），默认 30
+}
+
+// ConfigPushClientResult 单个客户端的下发结果
+type ConfigPushClientResult struct {
+	ClientID        string   `json:"client_id"`
+	Success         bool     `json:"success"`
+	Revision        int64    `json:"revision,omitempty"`
+	Changed         []string `json:"changed,omitempty"`
+	RestartRequired []string `json:"restart_required,omitempty"`
+	RollbackAt      string   `json:"rollback_at,omitempty"`
+	Message         string   `json:"message,omitempty"`
+	Error           string   `json:"error,omitempty"`
+}
+
+// ConfigPushResponse 批量下发 Agent 配置响应
+type ConfigPushResponse struct {
+	Success      bool                     `json:"success"`
+	DryRun       bool                     `json:"dry_run,omitempty"`
+	Matched      []string                 `json:"matched"`       // 命中的客户端
+	Total        int                      `json:"total"`         // 命中数量
+	SuccessCount int                      `json:"success_count"` // 成功数量
+	FailedCount  int                      `json:"failed_count"`  // 失败数量
+	Results      []ConfigPushClientResult `json:"results"`
+	Duration     string                   `json:"duration"`
+}
+
+// AddConfigRoutes 添加 Agent 配置管理路由
+func (h *HTTPServer) AddConfigRoutes() {
+	api := h.router.Group("/api/agent-config")
+	{
+		api.GET("/:client_id", h.handleGetAgentConfig)
+		api.POST("/push", h.handlePushAgentConfig)
+	}
+	h.logger.Info("Agent config API routes registered")
+}
+
+// handleGetAgentConfig 获取单个客户端的生效配置
+func (h *HTTPServer) handleGetAgentConfig(c *gin.Context) {
+	if h.commandManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Command manager not initialized",
+		})
+		return
+	}
+
+	clientID := c.Param("client_id")
+	payload, _ := json.Marshal(command.ConfigGetParams{Key: c.Query("key")})
+	resp := h.commandManager.SendCommandToMultiple([]string{clientID}, command.CmdConfigGet, payload, configDefaultTimeout*time.Second)
+	if resp == nil || len(resp.Results) == 0 || resp.Results[0] == nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": "no response from client",
+		})
+		return
+	}
+
+	r := resp.Results[0]
+	if r.Status != command.CommandStatusCompleted {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"client_id": clientID,
+			"status":    r.Status,
+			"error":     r.Error,
+		})
+		return
+	}
+
+	var result command.ConfigResult
+	if err := json.Unmarshal(r.Result, &result); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": fmt.Sprintf("invalid result: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"client_id": clientID,
+		"result":    result,
+	})
+}
+
+// handlePushAgentConfig 按客户端 ID 或标签批量下发配置补丁
+func (h *HTTPServer) handlePushAgentConfig(c *gin.Context) {
+	if h.commandManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Command manager not initialized",
+		})
+		return
+	}
+
+	var req ConfigPushRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if len(req.ClientIDs) == 0 && len(req.Labels) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "client_ids or labels is required",
+		})
+		return
+	}
+
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = configDefaultTimeout
+	}
+	if timeout > configMaxTimeout {
+		timeout = configMaxTimeout
+	}
+
+	start := time.Now()
+
+	// 按标签筛选
+	targets := req.ClientIDs
+	if len(req.Labels) > 0 {
+		targets = h.resolveLabelSelector(targets, req.Labels, time.Duration(timeout)*time.Second)
+	}
+
+	h.logger.Info("Agent config push request received",
+		"target_count", len(targets),
+		"labels", req.Labels,
+		"dry_run", req.DryRun,
+	)
+
+	out := &ConfigPushResponse{
+		DryRun:  req.DryRun,
+		Matched: targets,
+		Total:   len(targets),
+		Results: []ConfigPushClientResult{},
+	}
+	if len(targets) == 0 {
+		out.Success = true
+		out.Duration = time.Since(start).String()
+		c.JSON(http.StatusOK, out)
+		return
+	}
+
+	payload, err := json.Marshal(command.ConfigUpdateParams{
+		Patch:          req.Patch,
+		DryRun:         req.DryRun,
+		GracePeriodSec: req.GracePeriodSec,
+		NoRollback:     req.NoRollback,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("invalid patch: %v", err),
+		})
+		return
+	}
+
+	resp := h.commandManager.SendCommandToMultiple(targets, command.CmdConfigUpdate, payload, time.Duration(timeout)*time.Second)
+	out.Results = buildConfigPushResults(targets, resp)
+	for _, r := range out.Results {
+		if r.Success {
+			out.SuccessCount++
+		} else {
+			out.FailedCount++
+		}
+	}
+	out.Success = out.FailedCount == 0
+	out.Duration = time.Since(start).String()
+
+	c.JSON(http.StatusOK, out)
+}
+
+// resolveLabelSelector 向候选客户端查询 Agent 配置中的标签，返回全部匹配的客户端
+// candidates 为空时查询所有在线客户端
+func (h *HTTPServer) resolveLabelSelector(candidates []string, selector map[string]string, timeout time.Duration) []string {
+	if len(candidates) == 0 && h.serverAPI != nil {
+		candidates = h.serverAPI.ListClients()
+	}
+	if len(candidates) == 0 {
+		return []string{}
+	}
+	payload, _ := json.Marshal(command.ConfigGetParams{Key: "labels"})
+	resp := h.commandManager.SendCommandToMultiple(candidates, command.CmdConfigGet, payload, timeout)
+	return matchConfigLabels(resp, selector)
+}
+
+// matchConfigLabels 从 config.get(labels) 的结果中筛选标签全部匹配的客户端
+func matchConfigLabels(resp *command.MultiCommandResponse, selector map[string]string) []string {
+	matched := []string{}
+	if resp == nil {
+		return matched
+	}
+	for _, r := range resp.Results {
+		if r == nil || r.Status != command.CommandStatusCompleted {
+			continue
+		}
+		var result command.ConfigResult
+		if err := json.Unmarshal(r.Result, &result); err != nil || !result.Success {
+			continue
+		}
+		labels, _ := result.Value.(map[string]interface{})
+		ok := true
+		for k, v := range selector {
+			if s, _ := labels[k].(string); s != v {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matched = append(matched, r.ClientID)
+		}
+	}
+	return matched
+}
+
+// buildConfigPushResults 整理各客户端的 config.update 结果，保持目标顺序
+func buildConfigPushResults(targets []string, resp *command.MultiCommandResponse) []ConfigPushClientResult {
+	byClient := make(map[string]*command.ClientCommandResult)
+	if resp != nil {
+		for _, r := range resp.Results {
+			if r != nil {
+				byClient[r.ClientID] = r
+			}
+		}
+	}
+
+	results := make([]ConfigPushClientResult, 0, len(targets))
+	for _, cid := range targets {
+		item := ConfigPushClientResult{ClientID: cid}
+		r, ok := byClient[cid]
+		switch {
+		case !ok:
+			item.Error = "no result"
+		case r.Status != command.CommandStatusCompleted:
+			item.Error = r.Error
+			if item.Error == "" {
+				item.Error = string(r.Status)
+			}
+		default:
+			var result command.ConfigResult
+			if err := json.Unmarshal(r.Result, &result); err != nil {
+				item.Error = fmt.Sprintf("invalid result: %v", err)
+				break
+			}
+			item.Success = result.Success
+			item.Revision = result.Revision
+			item.Changed = result.Changed
+			item.RestartRequired = result.RestartRequired
+			item.RollbackAt = result.RollbackAt
+			item.Message = result.Message
+			item.Error = result.Error
+		}
+		results = append(results, item)
+	}
+	return results
+}
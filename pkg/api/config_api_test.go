@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/voilet/quic-flow/pkg/command"
+)
+
+func TestMatchConfigLabels(t *testing.T) {
+	prod, _ := json.Marshal(command.ConfigResult{Success: true, Value: map[string]string{"env": "prod", "zone": "a"}})
+	dev, _ := json.Marshal(command.ConfigResult{Success: true, Value: map[string]string{"env": "dev"}})
+
+	resp := &command.MultiCommandResponse{Results: []*command.ClientCommandResult{
+		{ClientID: "a", Status: command.CommandStatusCompleted, Result: prod},
+		{ClientID: "b", Status: command.CommandStatusCompleted, Result: dev},
+		{ClientID: "c", Status: command.CommandStatusTimeout},
+	}}
+
+	assert.Equal(t, []string{"a"}, matchConfigLabels(resp, map[string]string{"env": "prod"}))
+	assert.Equal(t, []string{"a", "b"}, matchConfigLabels(resp, map[string]string{}))
+	assert.Empty(t, matchConfigLabels(resp, map[string]string{"env": "staging"}))
+	assert.Empty(t, matchConfigLabels(nil, map[string]string{"env": "prod"}))
+}
+
+func TestBuildConfigPushResults(t *testing.T) {
+	ok, _ := json.Marshal(command.ConfigResult{Success: true, Revision: 3, Changed: []string{"log_level"}, Message: "config updated"})
+	rejected, _ := json.Marshal(command.ConfigResult{Success: false, Error: "log_level must be one of DEBUG/INFO/WARN/ERROR"})
+
+	resp := &command.MultiCommandResponse{Results: []*command.ClientCommandResult{
+		{ClientID: "b", Status: command.CommandStatusCompleted, Result: rejected},
+		{ClientID: "a", Status: command.CommandStatusCompleted, Result: ok},
+	}}
+
+	results := buildConfigPushResults([]string{"a", "b", "c"}, resp)
+	assert.Len(t, results, 3)
+	assert.True(t, results[0].Success)
+	assert.Equal(t, int64(3), results[0].Revision)
+	assert.Equal(t, []string{"log_level"}, results[0].Changed)
+	assert.False(t, results[1].Success)
+	assert.Contains(t, results[1].Error, "log_level")
+	assert.Equal(t, "no result", results[2].Error)
+}
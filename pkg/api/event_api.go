@@ -0,0 +1,211 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/voilet/quic-flow/pkg/eventbus"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+)
+
+// 事件 WebSocket 参数
+const (
+	eventWSWriteTimeout = 10 * time.Second
+	eventWSPingInterval = 30 * time.Second
+)
+
+// EventAPI 主题事件 API
+type EventAPI struct {
+	bus    *eventbus.Bus
+	relay  *eventbus.Relay
+	logger *monitoring.Logger
+}
+
+// PublishEventRequest 服务器发布事件请求
+type PublishEventRequest struct {
+	Topic string          `json:"topic" binding:"required"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// NewEventAPI 创建主题事件 API
+func NewEventAPI(bus *eventbus.Bus, relay *eventbus.Relay, logger *monitoring.Logger) *EventAPI {
+	return &EventAPI{bus: bus, relay: relay, logger: logger}
+}
+
+// RegisterRoutes 注册路由
+func (a *EventAPI) RegisterRoutes(r *gin.RouterGroup) {
+	events := r.Group("/events")
+	{
+		events.GET("", a.ListEvents)
+		events.POST("", a.PublishEvent)
+		events.GET("/topics", a.ListTopics)
+		events.GET("/subscriptions", a.ListAgentSubscriptions)
+		events.GET("/ws", a.Subscribe)
+	}
+}
+
+// patternsFromQuery 读取 pattern 参数（可重复或逗号分隔），默认订阅全部主题
+func patternsFromQuery(c *gin.Context) []string {
+	var patterns []string
+	for _, v := range c.QueryArray("pattern") {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				patterns = append(patterns, p)
+			}
+		}
+	}
+	if len(patterns) == 0 {
+		patterns = []string{"#"}
+	}
+	return patterns
+}
+
+// ListEvents 查询保留的最近事件
+// 参数：pattern（默认 #）、after（只返回序号更大的事件，用于增量拉取）、limit（默认 100）
+func (a *EventAPI) ListEvents(c *gin.Context) {
+	after, _ := strconv.ParseUint(c.Query("after"), 10, 64)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	var events []*eventbus.Event
+	seen := make(map[uint64]bool)
+	for _, pattern := range patternsFromQuery(c) {
+		list, err := a.bus.Recent(pattern, after, 0)
+		if err != nil {
+			eventError(c, err)
+			return
+		}
+		for _, ev := range list {
+			if !seen[ev.Seq] {
+				seen[ev.Seq] = true
+				events = append(events, ev)
+			}
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Seq < events[j].Seq })
+	if limit > 0 && len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"total":   len(events),
+		"data":    events,
+	})
+}
+
+// PublishEvent 以 server 身份发布事件，订阅了匹配模式的 Agent 会收到
+func (a *EventAPI) PublishEvent(c *gin.Context) {
+	var req PublishEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		eventError(c, err)
+		return
+	}
+	ev, err := a.bus.Publish(req.Topic, eventbus.SourceServer, req.Data)
+	if err != nil {
+		eventError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": ev})
+}
+
+// ListTopics 列出主题
+func (a *EventAPI) ListTopics(c *gin.Context) {
+	topics := a.bus.Topics()
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"total":       len(topics),
+		"subscribers": a.bus.Subscribers(),
+		"data":        topics,
+	})
+}
+
+// ListAgentSubscriptions 列出各 Agent 声明的订阅模式
+func (a *EventAPI) ListAgentSubscriptions(c *gin.Context) {
+	subs := a.relay.Subscriptions()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"total":   len(subs),
+		"data":    subs,
+	})
+}
+
+// Subscribe 通过 WebSocket 订阅事件
+// 参数：pattern（可重复），replay=true 时先推送保留的匹配事件
+func (a *EventAPI) Subscribe(c *gin.Context) {
+	patterns := patternsFromQuery(c)
+	for _, p := range patterns {
+		if err := eventbus.ValidatePattern(p); err != nil {
+			eventError(c, err)
+			return
+		}
+	}
+
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		a.logger.Error("Failed to upgrade WebSocket", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	sub, err := a.bus.Subscribe(patterns, 0, c.Query("replay") == "true")
+	if err != nil {
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, err.Error()))
+		return
+	}
+	defer sub.Close()
+	a.logger.Info("Event subscriber connected", "remote_addr", c.Request.RemoteAddr, "patterns", patterns)
+
+	// 读取循环仅用于感知连接关闭
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(eventWSPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case ev, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(eventWSWriteTimeout))
+			if err := conn.WriteJSON(ev); err != nil {
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(eventWSWriteTimeout))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-closed:
+			a.logger.Info("Event subscriber disconnected", "remote_addr", c.Request.RemoteAddr, "dropped", sub.Dropped())
+			return
+		}
+	}
+}
+
+// eventError 返回错误响应
+func eventError(c *gin.Context, err error) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"success": false,
+		"error":   err.Error(),
+	})
+}
+
+// AddEventRoutes 添加主题事件路由
+func (h *HTTPServer) AddEventRoutes(bus *eventbus.Bus, relay *eventbus.Relay) {
+	a := NewEventAPI(bus, relay, h.logger)
+	a.RegisterRoutes(h.router.Group("/api"))
+	h.logger.Info("Event API routes registered")
+}
@@ -1,18 +1,25 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/voilet/quic-flow/pkg/task/scheduler"
 	"github.com/voilet/quic-flow/pkg/task/store"
 	"github.com/voilet/quic-flow/pkg/monitoring"
+	"gorm.io/gorm"
 )
 
 // ExecutionAPI 执行监控 API
 type ExecutionAPI struct {
-	executionStore store.ExecutionStore
-	logger         *monitoring.Logger
+	executionStore  store.ExecutionStore
+	dailyStatsStore store.DailyStatsStore // Agent 本地调度任务的每日统计（可选）
+	taskManager     *scheduler.TaskManager // 读取转存的日志与清理执行记录（可选）
+	logger          *monitoring.Logger
 }
 
 // NewExecutionAPI 创建执行监控 API
@@ -23,6 +30,16 @@ func NewExecutionAPI(executionStore store.ExecutionStore, logger *monitoring.Log
 	}
 }
 
+// SetDailyStatsStore 设置每日统计存储，设置后执行统计包含按日汇总（daily）
+func (api *ExecutionAPI) SetDailyStatsStore(s store.DailyStatsStore) {
+	api.dailyStatsStore = s
+}
+
+// SetTaskManager 设置任务管理器，设置后执行日志包含转存到文件存储的完整输出，并提供执行记录清理与执行分析接口
+func (api *ExecutionAPI) SetTaskManager(m *scheduler.TaskManager) {
+	api.taskManager = m
+}
+
 // RegisterRoutes 注册路由
 func (api *ExecutionAPI) RegisterRoutes(r *gin.RouterGroup) {
 	executions := r.Group("/executions")
@@ -31,6 +48,11 @@ func (api *ExecutionAPI) RegisterRoutes(r *gin.RouterGroup) {
 		executions.GET("/:id", api.GetExecutionDetail)
 		executions.GET("/:id/logs", api.GetExecutionLogs)
 		executions.GET("/stats", api.GetExecutionStats)
+		executions.GET("/purge", api.GetPurgeReport)
+		executions.POST("/purge", api.PurgeExecutions)
+		executions.GET("/analytics/health", api.GetHealthReport)
+		executions.GET("/analytics/tasks/:id", api.GetTaskAnalytics)
+		executions.GET("/analytics/clients/:client_id", api.GetClientAnalytics)
 	}
 }
 
@@ -123,8 +145,28 @@ func (api *ExecutionAPI) GetExecutionLogs(c *gin.Context) {
 		return
 	}
 
-	execution, err := api.executionStore.GetByID(c.Request.Context(), executionID)
-	if err != nil {
+	if api.taskManager == nil {
+		execution, err := api.executionStore.GetByID(c.Request.Context(), executionID)
+		if err != nil {
+			api.logger.Error("Failed to get execution", "execution_id", executionID, "error", err)
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   "execution not found",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"output":    execution.Output,
+				"error_msg": execution.ErrorMsg,
+			},
+		})
+		return
+	}
+
+	execution, output, err := api.taskManager.ExecutionOutput(c.Request.Context(), executionID)
+	if execution == nil {
 		api.logger.Error("Failed to get execution", "execution_id", executionID, "error", err)
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
@@ -133,12 +175,165 @@ func (api *ExecutionAPI) GetExecutionLogs(c *gin.Context) {
 		return
 	}
 
+	data := gin.H{
+		"output":      output,
+		"error_msg":   execution.ErrorMsg,
+		"output_size": execution.OutputSize,
+		"output_path": execution.OutputPath,
+	}
+	// 转存的日志读取失败时返回数据库中的尾部预览
+	if err != nil {
+		api.logger.Warn("Failed to load execution output", "execution_id", executionID, "error", err)
+		data["truncated"] = true
+		data["output_error"] = err.Error()
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data": gin.H{
-			"output":   execution.Output,
-			"error_msg": execution.ErrorMsg,
-		},
+		"data":    data,
+	})
+}
+
+// GetPurgeReport 获取最近一次执行记录清理报告
+func (api *ExecutionAPI) GetPurgeReport(c *gin.Context) {
+	if api.taskManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "task manager not available",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    api.taskManager.LastPurgeReport(),
+	})
+}
+
+// PurgeExecutions 按任务保留策略立即清理执行记录，dry_run=true 时只返回将被清理的统计
+func (api *ExecutionAPI) PurgeExecutions(c *gin.Context) {
+	if api.taskManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "task manager not available",
+		})
+		return
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+	report, err := api.taskManager.PurgeExecutions(c.Request.Context(), dryRun)
+	if err != nil {
+		api.logger.Error("Failed to purge executions", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// GetHealthReport 获取所有任务最近 7 天的健康报告，refresh=true 时重新生成
+func (api *ExecutionAPI) GetHealthReport(c *gin.Context) {
+	if api.taskManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "task manager not available",
+		})
+		return
+	}
+
+	report := api.taskManager.HealthReport()
+	if report == nil || c.Query("refresh") == "true" {
+		var err error
+		report, err = api.taskManager.RefreshHealth(c.Request.Context())
+		if err != nil {
+			api.logger.Error("Failed to refresh task health", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// GetTaskAnalytics 获取任务的执行分析（成功率、耗时趋势、客户端异常与耗时突增），days 为趋势天数
+func (api *ExecutionAPI) GetTaskAnalytics(c *gin.Context) {
+	if api.taskManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "task manager not available",
+		})
+		return
+	}
+
+	taskID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "invalid task id",
+		})
+		return
+	}
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+	analytics, err := api.taskManager.TaskAnalytics(c.Request.Context(), taskID, days)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   "task not found",
+			})
+			return
+		}
+		api.logger.Error("Failed to get task analytics", "task_id", taskID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    analytics,
+	})
+}
+
+// GetClientAnalytics 获取客户端的执行分析（成功率、耗时趋势与各任务的异常标记），days 为趋势天数
+func (api *ExecutionAPI) GetClientAnalytics(c *gin.Context) {
+	if api.taskManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "task manager not available",
+		})
+		return
+	}
+
+	clientID := c.Param("client_id")
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+	analytics, err := api.taskManager.ClientAnalytics(c.Request.Context(), clientID, days)
+	if err != nil {
+		api.logger.Error("Failed to get client analytics", "client_id", clientID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    analytics,
 	})
 }
 
@@ -220,8 +415,70 @@ func (api *ExecutionAPI) GetExecutionStats(c *gin.Context) {
 		stats["avg_duration"] = totalDuration / int64(count)
 	}
 
+	// Agent 上报的每日统计（含离线期间本地执行的任务）
+	if api.dailyStatsStore != nil {
+		days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+		daily, err := api.dailyStats(c.Request.Context(), taskID, clientID, days)
+		if err != nil {
+			api.logger.Error("Failed to get daily stats", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+		stats["daily"] = daily
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    stats,
 	})
 }
+
+// dailyStats 按日期汇总最近 days 天的每日统计（多个客户端、任务合并）
+func (api *ExecutionAPI) dailyStats(ctx context.Context, taskID *int64, clientID string, days int) ([]gin.H, error) {
+	if days <= 0 {
+		days = 7
+	}
+	list, err := api.dailyStatsStore.List(ctx, &store.DailyStatsListParams{
+		TaskID:   taskID,
+		ClientID: clientID,
+		FromDate: time.Now().AddDate(0, 0, -(days - 1)).Format("2006-01-02"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	daily := make([]gin.H, 0)
+	index := make(map[string]gin.H)
+	for _, s := range list {
+		day, ok := index[s.Date]
+		if !ok {
+			day = gin.H{
+				"date":           s.Date,
+				"success":        0,
+				"failed":         0,
+				"timeout":        0,
+				"cancelled":      0,
+				"total_duration": int64(0),
+				"max_duration":   0,
+				"min_duration":   0,
+			}
+			index[s.Date] = day
+			daily = append(daily, day) // List 已按日期排序
+		}
+		day["success"] = day["success"].(int) + s.SuccessCount
+		day["failed"] = day["failed"].(int) + s.FailureCount
+		day["timeout"] = day["timeout"].(int) + s.TimeoutCount
+		day["cancelled"] = day["cancelled"].(int) + s.CancelledCount
+		day["total_duration"] = day["total_duration"].(int64) + s.TotalDuration
+		if s.MaxDuration > day["max_duration"].(int) {
+			day["max_duration"] = s.MaxDuration
+		}
+		if min := day["min_duration"].(int); s.TotalCount() > 0 && (min == 0 || s.MinDuration < min) {
+			day["min_duration"] = s.MinDuration
+		}
+	}
+	return daily, nil
+}
@@ -1,19 +1,22 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/voilet/quic-flow/pkg/task/models"
+	"github.com/voilet/quic-flow/pkg/task/scheduler"
 	"github.com/voilet/quic-flow/pkg/task/store"
 	"github.com/voilet/quic-flow/pkg/monitoring"
 )
 
 // GroupAPI 分组管理 API
 type GroupAPI struct {
-	groupStore store.GroupStore
-	logger     *monitoring.Logger
+	groupStore  store.GroupStore
+	taskManager *scheduler.TaskManager // 预览分组成员（可选）
+	logger      *monitoring.Logger
 }
 
 // NewGroupAPI 创建分组管理 API
@@ -24,16 +27,23 @@ func NewGroupAPI(groupStore store.GroupStore, logger *monitoring.Logger) *GroupA
 	}
 }
 
+// SetTaskManager 设置任务管理器，设置后可预览分组当前的在线成员
+func (api *GroupAPI) SetTaskManager(m *scheduler.TaskManager) {
+	api.taskManager = m
+}
+
 // RegisterRoutes 注册路由
 func (api *GroupAPI) RegisterRoutes(r *gin.RouterGroup) {
 	groups := r.Group("/groups")
 	{
 		groups.GET("", api.GetGroups)
 		groups.POST("", api.CreateGroup)
+		groups.POST("/preview", api.PreviewSelector)
 		groups.GET("/:id", api.GetGroup)
 		groups.PUT("/:id", api.UpdateGroup)
 		groups.DELETE("/:id", api.DeleteGroup)
 		groups.GET("/:id/clients", api.GetGroupClients)
+		groups.GET("/:id/preview", api.PreviewGroup)
 		groups.POST("/:id/clients", api.AddGroupClients)
 		groups.DELETE("/:id/clients/:client_id", api.RemoveGroupClient)
 	}
@@ -60,9 +70,10 @@ func (api *GroupAPI) GetGroups(c *gin.Context) {
 // CreateGroup 创建分组
 func (api *GroupAPI) CreateGroup(c *gin.Context) {
 	var req struct {
-		Name        string `json:"name" binding:"required"`
-		Description string `json:"description"`
-		Tags        string `json:"tags"`
+		Name        string                `json:"name" binding:"required"`
+		Description string                `json:"description"`
+		Tags        string                `json:"tags"`
+		Selector    *models.GroupSelector `json:"selector"` // 设置时为动态分组
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -73,10 +84,21 @@ func (api *GroupAPI) CreateGroup(c *gin.Context) {
 		return
 	}
 
+	if req.Selector != nil {
+		if err := req.Selector.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
 	group := &models.TaskGroup{
 		Name:        req.Name,
 		Description: req.Description,
 		Tags:        req.Tags,
+		Selector:    req.Selector,
 	}
 
 	if err := api.groupStore.Create(c.Request.Context(), group); err != nil {
@@ -133,9 +155,10 @@ func (api *GroupAPI) UpdateGroup(c *gin.Context) {
 	}
 
 	var req struct {
-		Name        *string `json:"name"`
-		Description *string `json:"description"`
-		Tags        *string `json:"tags"`
+		Name        *string         `json:"name"`
+		Description *string         `json:"description"`
+		Tags        *string         `json:"tags"`
+		Selector    json.RawMessage `json:"selector"` // 对象：动态分组；null：改为静态分组
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -164,6 +187,26 @@ func (api *GroupAPI) UpdateGroup(c *gin.Context) {
 	if req.Tags != nil {
 		group.Tags = *req.Tags
 	}
+	if req.Selector != nil {
+		var selector *models.GroupSelector
+		if err := json.Unmarshal(req.Selector, &selector); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "invalid selector: " + err.Error(),
+			})
+			return
+		}
+		if selector != nil {
+			if err := selector.Validate(); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"error":   err.Error(),
+				})
+				return
+			}
+		}
+		group.Selector = selector
+	}
 
 	if err := api.groupStore.Update(c.Request.Context(), group); err != nil {
 		api.logger.Error("Failed to update group", "group_id", groupID, "error", err)
@@ -233,6 +276,76 @@ func (api *GroupAPI) GetGroupClients(c *gin.Context) {
 	})
 }
 
+// PreviewGroup 预览分组当前的在线成员（动态分组按选择器实时筛选）
+func (api *GroupAPI) PreviewGroup(c *gin.Context) {
+	groupID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "invalid group id",
+		})
+		return
+	}
+
+	group, err := api.groupStore.GetByID(c.Request.Context(), groupID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "group not found",
+		})
+		return
+	}
+
+	api.preview(c, group)
+}
+
+// PreviewSelector 预览未保存的选择器匹配的在线客户端
+func (api *GroupAPI) PreviewSelector(c *gin.Context) {
+	var selector models.GroupSelector
+	if err := c.ShouldBindJSON(&selector); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	api.preview(c, &models.TaskGroup{Selector: &selector})
+}
+
+// preview 返回分组当前的在线成员
+func (api *GroupAPI) preview(c *gin.Context, group *models.TaskGroup) {
+	if api.taskManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"error":   "task manager not available",
+		})
+		return
+	}
+
+	clients, err := api.taskManager.ResolveGroup(c.Request.Context(), group)
+	if err != nil {
+		api.logger.Warn("Failed to resolve group", "group_id", group.ID, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	if clients == nil {
+		clients = []string{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"dynamic": group.IsDynamic(),
+			"clients": clients,
+			"total":   len(clients),
+		},
+	})
+}
+
 // AddGroupClients 添加客户端到分组
 func (api *GroupAPI) AddGroupClients(c *gin.Context) {
 	groupID, err := strconv.ParseInt(c.Param("id"), 10, 64)
@@ -10,6 +10,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/voilet/quic-flow/pkg/cmdschedule"
 	"github.com/voilet/quic-flow/pkg/command"
 	"github.com/voilet/quic-flow/pkg/hardware"
 	"github.com/voilet/quic-flow/pkg/monitoring"
@@ -34,7 +35,8 @@ type HTTPServer struct {
 	router         *gin.Engine
 	serverAPI      ServerAPI
 	commandManager *command.CommandManager
-	hardwareStore  *hardware.Store // 硬件信息存储
+	hardwareStore  *hardware.Store        // 硬件信息存储
+	scheduler      *cmdschedule.Scheduler // 定时命令调度器（AddScheduleRoutes 后可用）
 	logger         *monitoring.Logger
 	listenAddr     string
 }
@@ -683,6 +685,21 @@ func (h *HTTPServer) handleSendMultiCommand(c *gin.Context) {
 		return
 	}
 
+	if req.Aggregate != nil {
+		if err := req.Aggregate.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	// 指定 run_at 或 cron 时创建定时计划，不立即执行
+	if req.RunAt != nil || req.Cron != "" {
+		h.scheduleMultiCommand(c, &req)
+		return
+	}
+
 	// 设置默认超时
 	timeout := time.Duration(req.Timeout) * time.Second
 	if timeout == 0 {
@@ -697,6 +714,9 @@ func (h *HTTPServer) handleSendMultiCommand(c *gin.Context) {
 
 	// 下发多播命令
 	response := h.commandManager.SendCommandToMultiple(req.ClientIDs, req.CommandType, req.Payload, timeout)
+	if req.Aggregate != nil {
+		response.Aggregate, _ = command.AggregateResults(response.Results, req.Aggregate)
+	}
 
 	h.logger.Info("Multi-command completed",
 		"total", response.Total,
@@ -707,6 +727,37 @@ func (h *HTTPServer) handleSendMultiCommand(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// scheduleMultiCommand 将多播命令请求保存为定时计划
+func (h *HTTPServer) scheduleMultiCommand(c *gin.Context, req *command.MultiCommandRequest) {
+	if h.scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Command scheduler not initialized",
+		})
+		return
+	}
+	sc, err := h.scheduler.Create(&cmdschedule.Schedule{
+		Kind:          cmdschedule.KindMulti,
+		ClientIDs:     req.ClientIDs,
+		CommandType:   req.CommandType,
+		Payload:       req.Payload,
+		Timeout:       req.Timeout,
+		RunAt:         req.RunAt,
+		Cron:          req.Cron,
+		MisfirePolicy: cmdschedule.MisfirePolicy(req.MisfirePolicy),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"success":  true,
+		"message":  "Command scheduled",
+		"schedule": sc,
+	})
+}
+
 // handleCancelMultiCommand 处理停止多播任务请求
 func (h *HTTPServer) handleCancelMultiCommand(c *gin.Context) {
 	if h.commandManager == nil {
@@ -790,6 +841,12 @@ func (h *HTTPServer) AddReleaseRoutes(releaseAPI ReleaseAPIRegistrar) {
 	h.logger.Info("Release API routes added")
 }
 
+// AddMetricsRoute 添加 Prometheus 指标路由（/metrics，与 /health 一样不在 /api 下）
+func (h *HTTPServer) AddMetricsRoute(handler *monitoring.PrometheusHandler) {
+	h.router.GET("/metrics", gin.WrapH(handler))
+	h.logger.Info("Prometheus metrics route added")
+}
+
 // AddSetupRoutes 添加数据库初始化引导路由
 func (h *HTTPServer) AddSetupRoutes(setupAPI *SetupAPI) {
 	api := h.router.Group("/api")
@@ -0,0 +1,350 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/voilet/quic-flow/pkg/command"
+)
+
+// 网络探测类型
+const (
+	ProbeTypePing  = "ping"
+	ProbeTypeTrace = "trace"
+	ProbeTypeTCP   = "tcp"
+	ProbeTypeHTTP  = "http"
+	ProbeTypeDNS   = "dns"
+)
+
+// 网络探测限制
+const (
+	probeMaxTargets     = 20
+	probeDefaultTimeout = 60  // 秒
+	probeMaxTimeout     = 300 // 秒
+)
+
+// NetworkProbeRequest 多 Agent 网络探测请求
+// targets 格式：ping/trace/dns 为主机名或 IP，tcp 为 host:port，http 为完整 URL
+type NetworkProbeRequest struct {
+	ClientIDs []string        `json:"client_ids" binding:"required,min=1"` // 发起探测的客户端
+	Type      string          `json:"type" binding:"required"`             // 探测类型（ping/trace/tcp/http/dns）
+	Targets   []string        `json:"targets" binding:"required,min=1"`    // 探测目标
+	Count     int             `json:"count,omitempty"`                     // 探测次数（ping/tcp）
+	TimeoutMs int             `json:"timeout_ms,omitempty"`                // 单次探测超时（毫秒）
+	Options   json.RawMessage `json:"options,omitempty"`                   // 额外参数，按类型合并进命令参数（不能包含目标字段）
+	Timeout   int             `json:"timeout,omitempty"`                   // 命令整体超时（秒），默认 60
+}
+
+// NetworkProbeCell 延迟矩阵单元（某客户端到某目标）
+type NetworkProbeCell struct {
+	ClientID    string          `json:"client_id"`
+	Target      string          `json:"target"`
+	Success     bool            `json:"success"`
+	LatencyMs   float64         `json:"latency_ms"`       // 代表性延迟（ping/tcp 平均值，http 总耗时，dns 解析耗时，trace 末跳 RTT）
+	LossPercent float64         `json:"loss_percent"`     // 丢包/失败率（%）
+	Error       string          `json:"error,omitempty"`  // 错误信息
+	Result      json.RawMessage `json:"result,omitempty"` // 原始结果
+}
+
+// NetworkProbeTargetSummary 单个目标在所有客户端上的汇总
+type NetworkProbeTargetSummary struct {
+	Target       string  `json:"target"`
+	Reachable    int     `json:"reachable"`   // 探测成功的客户端数
+	Unreachable  int     `json:"unreachable"` // 探测失败的客户端数
+	MinLatencyMs float64 `json:"min_latency_ms"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	MaxLatencyMs float64 `json:"max_latency_ms"`
+}
+
+// NetworkProbeResponse 多 Agent 网络探测响应
+type NetworkProbeResponse struct {
+	Success  bool                        `json:"success"`
+	Type     string                      `json:"type"`
+	Clients  []string                    `json:"clients"`  // 矩阵行
+	Targets  []string                    `json:"targets"`  // 矩阵列
+	Matrix   [][]NetworkProbeCell        `json:"matrix"`   // [客户端][目标]
+	Summary  []NetworkProbeTargetSummary `json:"summary"`  // 按目标汇总
+	Duration string                      `json:"duration"` // 总耗时
+}
+
+// AddNetworkRoutes 添加网络诊断路由
+func (h *HTTPServer) AddNetworkRoutes() {
+	api := h.router.Group("/api/network")
+	{
+		api.POST("/probe", h.handleNetworkProbe)
+	}
+	h.logger.Info("Network diagnostics API routes registered")
+}
+
+// handleNetworkProbe 从多个客户端向一个或多个目标发起探测，汇总延迟矩阵
+func (h *HTTPServer) handleNetworkProbe(c *gin.Context) {
+	if h.commandManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Command manager not initialized",
+		})
+		return
+	}
+
+	var req NetworkProbeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if len(req.Targets) > probeMaxTargets {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("too many targets: %d (max %d)", len(req.Targets), probeMaxTargets),
+		})
+		return
+	}
+
+	// 预先构造每个目标的命令参数，参数错误直接返回
+	commandType := ""
+	payloads := make([]json.RawMessage, len(req.Targets))
+	for i, target := range req.Targets {
+		cmdType, payload, err := buildProbePayload(&req, target)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		commandType = cmdType
+		payloads[i] = payload
+	}
+
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = probeDefaultTimeout
+	}
+	if timeout > probeMaxTimeout {
+		timeout = probeMaxTimeout
+	}
+
+	h.logger.Info("Network probe request received",
+		"type", req.Type,
+		"client_count", len(req.ClientIDs),
+		"target_count", len(req.Targets),
+	)
+
+	start := time.Now()
+
+	// 各目标并行下发，单个目标内由 SendCommandToMultiple 并行到所有客户端
+	responses := make([]*command.MultiCommandResponse, len(req.Targets))
+	var wg sync.WaitGroup
+	for i := range req.Targets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = h.commandManager.SendCommandToMultiple(req.ClientIDs, commandType, payloads[i], time.Duration(timeout)*time.Second)
+		}(i)
+	}
+	wg.Wait()
+
+	resp := buildProbeMatrix(req.Type, req.ClientIDs, req.Targets, responses)
+	resp.Duration = time.Since(start).String()
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// probeTargetOptions 由 targets 决定、options 中不允许出现的参数
+var probeTargetOptions = map[string]bool{"target": true, "host": true, "port": true, "url": true, "name": true}
+
+// buildProbePayload 根据探测类型构造命令类型与参数
+func buildProbePayload(req *NetworkProbeRequest, target string) (string, json.RawMessage, error) {
+	var cmdType string
+	var params interface{}
+
+	switch req.Type {
+	case ProbeTypePing:
+		cmdType = command.CmdNetworkPing
+		params = &command.NetworkPingParams{Target: target, Count: req.Count, TimeoutMs: req.TimeoutMs}
+	case ProbeTypeTrace:
+		cmdType = command.CmdNetworkTrace
+		params = &command.NetworkTraceParams{Target: target, TimeoutMs: req.TimeoutMs}
+	case ProbeTypeTCP:
+		host, portStr, err := net.SplitHostPort(target)
+		if err != nil {
+			return "", nil, fmt.Errorf("tcp target must be host:port: %s", target)
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid port in target: %s", target)
+		}
+		cmdType = command.CmdNetworkTCP
+		params = &command.NetworkTCPParams{Host: host, Port: port, Count: req.Count, TimeoutMs: req.TimeoutMs}
+	case ProbeTypeHTTP:
+		cmdType = command.CmdNetworkHTTP
+		params = &command.NetworkHTTPParams{URL: target, TimeoutMs: req.TimeoutMs}
+	case ProbeTypeDNS:
+		cmdType = command.CmdNetworkDNS
+		params = &command.NetworkDNSParams{Name: target, TimeoutMs: req.TimeoutMs}
+	default:
+		return "", nil, fmt.Errorf("unsupported probe type: %s", req.Type)
+	}
+
+	// 合并额外参数（options 中的字段优先，但不能改写探测目标）
+	if len(req.Options) > 0 {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(req.Options, &fields); err != nil {
+			return "", nil, fmt.Errorf("invalid options: %w", err)
+		}
+		for key := range fields {
+			// encoding/json 匹配字段名不区分大小写
+			if probeTargetOptions[strings.ToLower(key)] {
+				return "", nil, fmt.Errorf("invalid options: %q is set by targets", key)
+			}
+		}
+		if err := json.Unmarshal(req.Options, params); err != nil {
+			return "", nil, fmt.Errorf("invalid options: %w", err)
+		}
+	}
+
+	payload, err := json.Marshal(params)
+	if err != nil {
+		return "", nil, err
+	}
+	return cmdType, payload, nil
+}
+
+// buildProbeMatrix 将各目标的多播结果整理为 [客户端][目标] 矩阵
+func buildProbeMatrix(probeType string, clientIDs, targets []string, responses []*command.MultiCommandResponse) *NetworkProbeResponse {
+	resp := &NetworkProbeResponse{
+		Success: true,
+		Type:    probeType,
+		Clients: clientIDs,
+		Targets: targets,
+		Matrix:  make([][]NetworkProbeCell, len(clientIDs)),
+		Summary: make([]NetworkProbeTargetSummary, len(targets)),
+	}
+
+	rowIndex := make(map[string]int, len(clientIDs))
+	for i, cid := range clientIDs {
+		rowIndex[cid] = i
+		resp.Matrix[i] = make([]NetworkProbeCell, len(targets))
+		for j, target := range targets {
+			resp.Matrix[i][j] = NetworkProbeCell{
+				ClientID:    cid,
+				Target:      target,
+				LossPercent: 100,
+				Error:       "no result",
+			}
+		}
+	}
+
+	for j, mr := range responses {
+		if mr == nil {
+			continue
+		}
+		for _, r := range mr.Results {
+			if r == nil {
+				continue
+			}
+			i, ok := rowIndex[r.ClientID]
+			if !ok {
+				continue
+			}
+			cell := &resp.Matrix[i][j]
+			cell.Result = r.Result
+			if r.Status != command.CommandStatusCompleted {
+				cell.Error = r.Error
+				if cell.Error == "" {
+					cell.Error = string(r.Status)
+				}
+				continue
+			}
+			fillProbeCell(probeType, cell)
+		}
+	}
+
+	for j, target := range targets {
+		s := NetworkProbeTargetSummary{Target: target}
+		var sum float64
+		for i := range clientIDs {
+			cell := resp.Matrix[i][j]
+			if !cell.Success {
+				s.Unreachable++
+				continue
+			}
+			if s.Reachable == 0 || cell.LatencyMs < s.MinLatencyMs {
+				s.MinLatencyMs = cell.LatencyMs
+			}
+			if cell.LatencyMs > s.MaxLatencyMs {
+				s.MaxLatencyMs = cell.LatencyMs
+			}
+			sum += cell.LatencyMs
+			s.Reachable++
+		}
+		if s.Reachable > 0 {
+			s.AvgLatencyMs = math.Round(sum/float64(s.Reachable)*100) / 100
+		}
+		if s.Unreachable > 0 {
+			resp.Success = false
+		}
+		resp.Summary[j] = s
+	}
+
+	return resp
+}
+
+// fillProbeCell 从命令结果中提取代表性延迟与丢包率
+func fillProbeCell(probeType string, cell *NetworkProbeCell) {
+	cell.Error = ""
+	cell.LossPercent = 0
+	var err error
+
+	switch probeType {
+	case ProbeTypePing:
+		var r command.NetworkPingResult
+		if err = json.Unmarshal(cell.Result, &r); err == nil {
+			cell.Success, cell.LatencyMs, cell.LossPercent, cell.Error = r.Success, r.AvgMs, r.LossPercent, r.Error
+		}
+	case ProbeTypeTrace:
+		var r command.NetworkTraceResult
+		if err = json.Unmarshal(cell.Result, &r); err == nil {
+			cell.Success, cell.Error = r.Success, r.Error
+			if n := len(r.Hops); n > 0 {
+				last := r.Hops[n-1]
+				cell.LatencyMs = last.AvgMs
+				if len(last.RTTs) > 0 {
+					cell.LossPercent = float64(last.Loss) / float64(len(last.RTTs)) * 100
+				}
+			}
+		}
+	case ProbeTypeTCP:
+		var r command.NetworkTCPResult
+		if err = json.Unmarshal(cell.Result, &r); err == nil {
+			cell.Success, cell.LatencyMs, cell.Error = r.Success, r.AvgMs, r.Error
+			if r.Attempts > 0 {
+				cell.LossPercent = float64(r.Attempts-r.Successes) / float64(r.Attempts) * 100
+			}
+		}
+	case ProbeTypeHTTP:
+		var r command.NetworkHTTPResult
+		if err = json.Unmarshal(cell.Result, &r); err == nil {
+			cell.Success, cell.LatencyMs, cell.Error = r.Success, r.TotalMs, r.Error
+		}
+	case ProbeTypeDNS:
+		var r command.NetworkDNSResult
+		if err = json.Unmarshal(cell.Result, &r); err == nil {
+			cell.Success, cell.LatencyMs, cell.Error = r.Success, r.DurationMs, r.Error
+		}
+	}
+
+	if err != nil {
+		cell.Success = false
+		cell.Error = fmt.Sprintf("invalid result: %v", err)
+	}
+	if !cell.Success && cell.LossPercent == 0 {
+		cell.LossPercent = 100
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/voilet/quic-flow/pkg/command"
+)
+
+func TestBuildProbePayload(t *testing.T) {
+	req := &NetworkProbeRequest{Type: ProbeTypeTCP, Count: 2}
+
+	cmdType, payload, err := buildProbePayload(req, "10.0.0.1:443")
+	assert.NoError(t, err)
+	assert.Equal(t, command.CmdNetworkTCP, cmdType)
+
+	var params command.NetworkTCPParams
+	assert.NoError(t, json.Unmarshal(payload, &params))
+	assert.Equal(t, "10.0.0.1", params.Host)
+	assert.Equal(t, 443, params.Port)
+	assert.Equal(t, 2, params.Count)
+
+	_, _, err = buildProbePayload(req, "10.0.0.1")
+	assert.Error(t, err)
+
+	_, _, err = buildProbePayload(&NetworkProbeRequest{Type: "unknown"}, "10.0.0.1")
+	assert.Error(t, err)
+
+	// options 可以调整参数，但不能改写探测目标
+	req = &NetworkProbeRequest{Type: ProbeTypeDNS, Options: json.RawMessage(`{"type":"MX","server":"10.0.0.53"}`)}
+	_, payload, err = buildProbePayload(req, "example.com")
+	assert.NoError(t, err)
+	var dns command.NetworkDNSParams
+	assert.NoError(t, json.Unmarshal(payload, &dns))
+	assert.Equal(t, "example.com", dns.Name)
+	assert.Equal(t, "MX", dns.Type)
+
+	for typ, options := range map[string]string{
+		ProbeTypeHTTP: `{"url":"http://169.254.169.254/"}`,
+		ProbeTypeTCP:  `{"Port":22}`,
+		ProbeTypePing: `{"TARGET":"10.0.0.2"}`,
+	} {
+		req = &NetworkProbeRequest{Type: typ, Options: json.RawMessage(options)}
+		_, _, err = buildProbePayload(req, "10.0.0.1:443")
+		assert.ErrorContains(t, err, "set by targets", typ)
+	}
+}
+
+func TestBuildProbeMatrix(t *testing.T) {
+	ok, _ := json.Marshal(command.NetworkPingResult{Success: true, AvgMs: 10, LossPercent: 25})
+	fast, _ := json.Marshal(command.NetworkPingResult{Success: true, AvgMs: 2})
+
+	responses := []*command.MultiCommandResponse{
+		{Results: []*command.ClientCommandResult{
+			{ClientID: "a", Status: command.CommandStatusCompleted, Result: ok},
+			{ClientID: "b", Status: command.CommandStatusTimeout, Error: "timeout"},
+		}},
+		{Results: []*command.ClientCommandResult{
+			{ClientID: "a", Status: command.CommandStatusCompleted, Result: fast},
+			{ClientID: "b", Status: command.CommandStatusCompleted, Result: ok},
+		}},
+	}
+
+	resp := buildProbeMatrix(ProbeTypePing, []string{"a", "b"}, []string{"t1", "t2"}, responses)
+
+	assert.False(t, resp.Success)
+	assert.Equal(t, 10.0, resp.Matrix[0][0].LatencyMs)
+	assert.Equal(t, 25.0, resp.Matrix[0][0].LossPercent)
+	assert.False(t, resp.Matrix[1][0].Success)
+	assert.Equal(t, 100.0, resp.Matrix[1][0].LossPercent)
+	assert.Equal(t, "timeout", resp.Matrix[1][0].Error)
+
+	assert.Equal(t, 1, resp.Summary[0].Reachable)
+	assert.Equal(t, 1, resp.Summary[0].Unreachable)
+	assert.Equal(t, 2, resp.Summary[1].Reachable)
+	assert.Equal(t, 2.0, resp.Summary[1].MinLatencyMs)
+	assert.Equal(t, 10.0, resp.Summary[1].MaxLatencyMs)
+	assert.Equal(t, 6.0, resp.Summary[1].AvgLatencyMs)
+}
@@ -0,0 +1,358 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/runbook"
+)
+
+// selectorTimeout 按标签解析目标客户端的超时
+const selectorTimeout = 10 * time.Second
+
+// RunbookAPI 命令模板与 Runbook API
+type RunbookAPI struct {
+	store    *runbook.Store
+	executor *runbook.Executor
+	resolve  func(candidates []string, selector map[string]string) []string
+	logger   *monitoring.Logger
+}
+
+// RunbookExecuteRequest 执行模板/Runbook 请求
+// client_ids 与 labels 至少指定一个；同时指定时取交集
+type RunbookExecuteRequest struct {
+	ClientIDs []string          `json:"client_ids,omitempty"` // 目标客户端
+	Labels    map[string]string `json:"labels,omitempty"`     // 标签选择器
+	Params    map[string]string `json:"params,omitempty"`     // 参数值
+}
+
+// NewRunbookAPI 创建 Runbook API
+func NewRunbookAPI(store *runbook.Store, executor *runbook.Executor, logger *monitoring.Logger) *RunbookAPI {
+	return &RunbookAPI{
+		store:    store,
+		executor: executor,
+		logger:   logger,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (a *RunbookAPI) RegisterRoutes(r *gin.RouterGroup) {
+	templates := r.Group("/command-templates")
+	{
+		templates.GET("", a.ListTemplates)
+		templates.POST("", a.CreateTemplate)
+		templates.GET("/:id", a.GetTemplate)
+		templates.PUT("/:id", a.UpdateTemplate)
+		templates.DELETE("/:id", a.DeleteTemplate)
+		templates.POST("/:id/execute", a.ExecuteTemplate)
+	}
+
+	runbooks := r.Group("/runbooks")
+	{
+		runbooks.GET("", a.ListRunbooks)
+		runbooks.POST("", a.CreateRunbook)
+		runbooks.GET("/runs", a.ListRuns)
+		runbooks.GET("/runs/:run_id", a.GetRun)
+		runbooks.POST("/runs/:run_id/cancel", a.CancelRun)
+		runbooks.GET("/:id", a.GetRunbook)
+		runbooks.PUT("/:id", a.UpdateRunbook)
+		runbooks.DELETE("/:id", a.DeleteRunbook)
+		runbooks.POST("/:id/execute", a.ExecuteRunbook)
+	}
+}
+
+// ListTemplates 列出命令模板
+func (a *RunbookAPI) ListTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    a.store.ListTemplates(),
+	})
+}
+
+// GetTemplate 获取命令模板
+func (a *RunbookAPI) GetTemplate(c *gin.Context) {
+	t, err := a.store.GetTemplate(c.Param("id"))
+	if err != nil {
+		runbookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    t,
+	})
+}
+
+// CreateTemplate 创建命令模板
+func (a *RunbookAPI) CreateTemplate(c *gin.Context) {
+	var t runbook.Template
+	if err := c.ShouldBindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	t.ID = ""
+	if err := a.store.SaveTemplate(&t); err != nil {
+		runbookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    t,
+	})
+}
+
+// UpdateTemplate 更新命令模板
+func (a *RunbookAPI) UpdateTemplate(c *gin.Context) {
+	var t runbook.Template
+	if err := c.ShouldBindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	t.ID = c.Param("id")
+	if err := a.store.SaveTemplate(&t); err != nil {
+		runbookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    t,
+	})
+}
+
+// DeleteTemplate 删除命令模板
+func (a *RunbookAPI) DeleteTemplate(c *gin.Context) {
+	if err := a.store.DeleteTemplate(c.Param("id")); err != nil {
+		runbookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// ExecuteTemplate 同步执行单个模板
+func (a *RunbookAPI) ExecuteTemplate(c *gin.Context) {
+	var req RunbookExecuteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	clientIDs, ok := a.targets(c, &req)
+	if !ok {
+		return
+	}
+
+	run, err := a.executor.RunTemplate(c.Param("id"), req.Params, clientIDs)
+	if err != nil {
+		runbookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": run.Status == runbook.RunStatusSuccess,
+		"data":    run,
+	})
+}
+
+// ListRunbooks 列出 Runbook
+func (a *RunbookAPI) ListRunbooks(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    a.store.ListRunbooks(),
+	})
+}
+
+// GetRunbook 获取 Runbook
+func (a *RunbookAPI) GetRunbook(c *gin.Context) {
+	rb, err := a.store.GetRunbook(c.Param("id"))
+	if err != nil {
+		runbookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    rb,
+	})
+}
+
+// CreateRunbook 创建 Runbook
+func (a *RunbookAPI) CreateRunbook(c *gin.Context) {
+	var rb runbook.Runbook
+	if err := c.ShouldBindJSON(&rb); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	rb.ID = ""
+	if err := a.store.SaveRunbook(&rb); err != nil {
+		runbookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    rb,
+	})
+}
+
+// UpdateRunbook 更新 Runbook
+func (a *RunbookAPI) UpdateRunbook(c *gin.Context) {
+	var rb runbook.Runbook
+	if err := c.ShouldBindJSON(&rb); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	rb.ID = c.Param("id")
+	if err := a.store.SaveRunbook(&rb); err != nil {
+		runbookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    rb,
+	})
+}
+
+// DeleteRunbook 删除 Runbook
+func (a *RunbookAPI) DeleteRunbook(c *gin.Context) {
+	if err := a.store.DeleteRunbook(c.Param("id")); err != nil {
+		runbookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// ExecuteRunbook 异步执行 Runbook，返回执行记录
+func (a *RunbookAPI) ExecuteRunbook(c *gin.Context) {
+	var req RunbookExecuteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	clientIDs, ok := a.targets(c, &req)
+	if !ok {
+		return
+	}
+
+	run, err := a.executor.Start(c.Param("id"), req.Params, clientIDs)
+	if err != nil {
+		runbookError(c, err)
+		return
+	}
+	a.logger.Info("Runbook execution started", "run_id", run.ID, "runbook", run.RunbookName, "client_count", len(clientIDs))
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data":    run,
+	})
+}
+
+// ListRuns 列出执行记录
+func (a *RunbookAPI) ListRuns(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    a.executor.ListRuns(c.Query("runbook_id")),
+	})
+}
+
+// GetRun 获取执行记录
+func (a *RunbookAPI) GetRun(c *gin.Context) {
+	run, ok := a.executor.GetRun(c.Param("run_id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "run not found",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    run,
+	})
+}
+
+// CancelRun 取消执行
+func (a *RunbookAPI) CancelRun(c *gin.Context) {
+	if err := a.executor.Cancel(c.Param("run_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "cancellation requested",
+	})
+}
+
+// targets 解析目标客户端，失败时已写入响应
+func (a *RunbookAPI) targets(c *gin.Context, req *RunbookExecuteRequest) ([]string, bool) {
+	if len(req.ClientIDs) == 0 && len(req.Labels) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "client_ids or labels is required",
+		})
+		return nil, false
+	}
+	clientIDs := req.ClientIDs
+	if len(req.Labels) > 0 && a.resolve != nil {
+		clientIDs = a.resolve(clientIDs, req.Labels)
+	}
+	if len(clientIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "no clients match the selector",
+		})
+		return nil, false
+	}
+	return clientIDs, true
+}
+
+// runbookError 将存储/执行错误转换为 HTTP 响应
+func runbookError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, runbook.ErrTemplateNotFound), errors.Is(err, runbook.ErrRunbookNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, runbook.ErrTemplateInUse):
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{
+		"success": false,
+		"error":   err.Error(),
+	})
+}
+
+// AddRunbookRoutes 添加命令模板与 Runbook 路由
+func (h *HTTPServer) AddRunbookRoutes(store *runbook.Store) *runbook.Executor {
+	executor := runbook.NewExecutor(store, h.commandManager, h.logger)
+	a := NewRunbookAPI(store, executor, h.logger)
+	a.resolve = func(candidates []string, selector map[string]string) []string {
+		return h.resolveLabelSelector(candidates, selector, selectorTimeout)
+	}
+	a.RegisterRoutes(h.router.Group("/api"))
+	h.logger.Info("Runbook API routes registered",
+		"templates", len(store.ListTemplates()),
+		"runbooks", len(store.ListRunbooks()),
+	)
+	return executor
+}
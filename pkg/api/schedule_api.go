@@ -0,0 +1,181 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/voilet/quic-flow/pkg/batch"
+	"github.com/voilet/quic-flow/pkg/cmdschedule"
+)
+
+// ScheduleAPI 定时/周期命令 API
+type ScheduleAPI struct {
+	scheduler *cmdschedule.Scheduler
+}
+
+// NewScheduleAPI 创建定时命令 API
+func NewScheduleAPI(scheduler *cmdschedule.Scheduler) *ScheduleAPI {
+	return &ScheduleAPI{scheduler: scheduler}
+}
+
+// RegisterRoutes 注册路由
+func (a *ScheduleAPI) RegisterRoutes(r *gin.RouterGroup) {
+	schedules := r.Group("/schedules")
+	{
+		schedules.GET("", a.List)
+		schedules.POST("", a.Create)
+		schedules.GET("/occurrences/:occ_id", a.GetOccurrence)
+		schedules.GET("/:id", a.Get)
+		schedules.PUT("/:id", a.Update)
+		schedules.DELETE("/:id", a.Delete)
+		schedules.POST("/:id/enable", a.Enable)
+		schedules.POST("/:id/disable", a.Disable)
+		schedules.GET("/:id/occurrences", a.ListOccurrences)
+	}
+}
+
+// List 列出计划
+func (a *ScheduleAPI) List(c *gin.Context) {
+	list := a.scheduler.List()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"total":   len(list),
+		"data":    list,
+	})
+}
+
+// Get 获取计划
+func (a *ScheduleAPI) Get(c *gin.Context) {
+	sc, err := a.scheduler.Get(c.Param("id"))
+	if err != nil {
+		scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    sc,
+	})
+}
+
+// Create 创建计划
+func (a *ScheduleAPI) Create(c *gin.Context) {
+	var sc cmdschedule.Schedule
+	if err := c.ShouldBindJSON(&sc); err != nil {
+		scheduleError(c, err)
+		return
+	}
+	created, err := a.scheduler.Create(&sc)
+	if err != nil {
+		scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    created,
+	})
+}
+
+// Update 修改计划
+func (a *ScheduleAPI) Update(c *gin.Context) {
+	var sc cmdschedule.Schedule
+	if err := c.ShouldBindJSON(&sc); err != nil {
+		scheduleError(c, err)
+		return
+	}
+	updated, err := a.scheduler.Update(c.Param("id"), &sc)
+	if err != nil {
+		scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updated,
+	})
+}
+
+// Delete 删除计划
+func (a *ScheduleAPI) Delete(c *gin.Context) {
+	if err := a.scheduler.Delete(c.Param("id")); err != nil {
+		scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Schedule deleted",
+	})
+}
+
+// Enable 启用计划
+func (a *ScheduleAPI) Enable(c *gin.Context) {
+	a.setEnabled(c, true)
+}
+
+// Disable 停用计划
+func (a *ScheduleAPI) Disable(c *gin.Context) {
+	a.setEnabled(c, false)
+}
+
+func (a *ScheduleAPI) setEnabled(c *gin.Context, enabled bool) {
+	sc, err := a.scheduler.SetEnabled(c.Param("id"), enabled)
+	if err != nil {
+		scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    sc,
+	})
+}
+
+// ListOccurrences 列出计划的执行记录
+func (a *ScheduleAPI) ListOccurrences(c *gin.Context) {
+	occs, err := a.scheduler.ListOccurrences(c.Param("id"))
+	if err != nil {
+		scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"total":   len(occs),
+		"data":    occs,
+	})
+}
+
+// GetOccurrence 获取单次执行及其结果集
+// batch 计划的结果通过 batch_job_id 在 /api/batch/jobs/:id 查询
+func (a *ScheduleAPI) GetOccurrence(c *gin.Context) {
+	occ, results, err := a.scheduler.GetOccurrence(c.Param("occ_id"))
+	if err != nil {
+		scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    occ,
+		"results": results,
+	})
+}
+
+// scheduleError 将调度错误转换为 HTTP 响应
+func scheduleError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, cmdschedule.ErrScheduleNotFound) || errors.Is(err, cmdschedule.ErrOccurrenceNotFound) {
+		status = http.StatusNotFound
+	}
+	c.JSON(status, gin.H{
+		"success": false,
+		"error":   err.Error(),
+	})
+}
+
+// AddScheduleRoutes 添加定时命令路由，返回调度器（由调用方启动与停止）
+// batchExecutor 为空时不支持 kind 为 batch 的计划
+func (h *HTTPServer) AddScheduleRoutes(store *cmdschedule.Store, batchExecutor *batch.BatchExecutor) *cmdschedule.Scheduler {
+	scheduler := cmdschedule.NewScheduler(store, h.commandManager, h.logger)
+	scheduler.SetBatchExecutor(batchExecutor)
+	h.scheduler = scheduler
+	NewScheduleAPI(scheduler).RegisterRoutes(h.router.Group("/api"))
+	h.logger.Info("Schedule API routes registered", "schedules", len(store.List()))
+	return scheduler
+}
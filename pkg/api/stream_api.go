@@ -12,10 +12,11 @@ import (
 
 // StreamCommandRequest 流式命令请求
 type StreamCommandRequest struct {
-	ClientIDs   []string        `json:"client_ids" binding:"required"`
-	CommandType string          `json:"command_type" binding:"required"`
-	Payload     json.RawMessage `json:"payload"`
-	Timeout     int             `json:"timeout"` // 秒
+	ClientIDs   []string                  `json:"client_ids" binding:"required"`
+	CommandType string                    `json:"command_type" binding:"required"`
+	Payload     json.RawMessage           `json:"payload"`
+	Timeout     int                       `json:"timeout"`             // 秒
+	Aggregate   *command.AggregateOptions `json:"aggregate,omitempty"` // 完成事件中的结果聚合选项
 }
 
 // StreamCommandEvent SSE 事件
@@ -29,10 +30,11 @@ type StreamCommandEvent struct {
 
 // StreamSummary 流式命令汇总
 type StreamSummary struct {
-	Total        int `json:"total"`
-	SuccessCount int `json:"success_count"`
-	FailedCount  int `json:"failed_count"`
-	Duration     int `json:"duration_ms"`
+	Total        int                      `json:"total"`
+	SuccessCount int                      `json:"success_count"`
+	FailedCount  int                      `json:"failed_count"`
+	Duration     int                      `json:"duration_ms"`
+	Aggregate    *command.ResultAggregate `json:"aggregate,omitempty"`
 }
 
 // handleStreamMultiCommand 处理流式多播命令请求 (SSE)
@@ -58,6 +60,14 @@ func (h *HTTPServer) handleStreamMultiCommand(c *gin.Context) {
 		})
 		return
 	}
+	if req.Aggregate != nil {
+		if err := req.Aggregate.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
 
 	timeout := time.Duration(req.Timeout) * time.Second
 	if timeout == 0 {
@@ -119,7 +129,11 @@ func (h *HTTPServer) handleStreamMultiCommand(c *gin.Context) {
 
 	// 流式输出结果
 	var successCount, failedCount int
+	var collected []*command.ClientCommandResult
 	for result := range resultChan {
+		if req.Aggregate != nil {
+			collected = append(collected, result)
+		}
 		// 更新统计
 		if result.Status == command.CommandStatusCompleted {
 			successCount++
@@ -150,6 +164,9 @@ func (h *HTTPServer) handleStreamMultiCommand(c *gin.Context) {
 			Duration:     int(duration),
 		},
 	}
+	if req.Aggregate != nil {
+		completeEvent.Summary.Aggregate, _ = command.AggregateResults(collected, req.Aggregate)
+	}
 
 	data, _ = json.Marshal(completeEvent)
 	fmt.Fprintf(c.Writer, "data: %s\n\n", data)
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/task/scheduler"
+)
+
+// taskTargetResolver 任务动态分组的信息来源：硬件信息来自硬件信息存储，
+// 标签与容器通过 config.get / container.list 命令实时查询 Agent
+type taskTargetResolver struct {
+	h *HTTPServer
+}
+
+// TaskTargetResolver 返回任务动态分组使用的 TargetResolver
+func (h *HTTPServer) TaskTargetResolver() scheduler.TargetResolver {
+	return &taskTargetResolver{h: h}
+}
+
+// HostFacts 从硬件信息存储获取客户端的硬件信息
+func (r *taskTargetResolver) HostFacts(ctx context.Context, clientIDs []string) (map[string]*scheduler.HostFacts, error) {
+	if r.h.hardwareStore == nil {
+		return nil, fmt.Errorf("hardware store not configured")
+	}
+	devices, err := r.h.hardwareStore.ListDevicesByClientIDs(clientIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	facts := make(map[string]*scheduler.HostFacts, len(devices))
+	for _, device := range devices {
+		info := device.FullHardwareInfo
+		cpus := info.CPUThreadCount
+		if cpus == 0 {
+			cpus = info.NumCPUKernel
+		}
+		facts[device.ClientID] = &scheduler.HostFacts{
+			Hostname: device.Hostname,
+			OS:       device.OS,
+			Arch:     device.KernelArch,
+			CPUs:     cpus,
+			MemoryGB: device.MemoryTotalGB,
+		}
+	}
+	return facts, nil
+}
+
+// MatchLabels 查询 Agent 配置中的标签
+func (r *taskTargetResolver) MatchLabels(ctx context.Context, clientIDs []string, labels map[string]string) []string {
+	return r.h.resolveLabelSelector(clientIDs, labels, selectorTimeout)
+}
+
+// MatchContainers 查询 Agent 上运行中的容器
+func (r *taskTargetResolver) MatchContainers(ctx context.Context, clientIDs []string, prefix string) []string {
+	payload, _ := json.Marshal(command.ContainerListParams{Prefixes: []string{prefix}})
+	resp := r.h.commandManager.SendCommandToMultiple(clientIDs, command.CmdContainerList, payload, selectorTimeout)
+	return matchContainerPrefix(resp, prefix)
+}
+
+// matchContainerPrefix 从 container.list 的结果中筛选存在名称以 prefix 开头的容器的客户端
+func matchContainerPrefix(resp *command.MultiCommandResponse, prefix string) []string {
+	matched := []string{}
+	if resp == nil {
+		return matched
+	}
+	for _, r := range resp.Results {
+		if r == nil || r.Status != command.CommandStatusCompleted {
+			continue
+		}
+		var result command.ContainerListResult
+		if err := json.Unmarshal(r.Result, &result); err != nil || !result.Success {
+			continue
+		}
+		for _, container := range result.Containers {
+			if strings.HasPrefix(strings.TrimPrefix(container.ContainerName, "/"), prefix) {
+				matched = append(matched, r.ClientID)
+				break
+			}
+		}
+	}
+	return matched
+}
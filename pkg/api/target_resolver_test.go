@@ -0,0 +1,32 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/voilet/quic-flow/pkg/command"
+)
+
+func TestMatchContainerPrefix(t *testing.T) {
+	web, _ := json.Marshal(command.ContainerListResult{Success: true, Containers: []command.ContainerInfoCmd{
+		{ContainerName: "/nginx-1"}, {ContainerName: "web-api-7"},
+	}})
+	db, _ := json.Marshal(command.ContainerListResult{Success: true, Containers: []command.ContainerInfoCmd{
+		{ContainerName: "mysql"},
+	}})
+	failed, _ := json.Marshal(command.ContainerListResult{Success: false, Error: "docker not running"})
+
+	resp := &command.MultiCommandResponse{Results: []*command.ClientCommandResult{
+		{ClientID: "a", Status: command.CommandStatusCompleted, Result: web},
+		{ClientID: "b", Status: command.CommandStatusCompleted, Result: db},
+		{ClientID: "c", Status: command.CommandStatusCompleted, Result: failed},
+		{ClientID: "d", Status: command.CommandStatusTimeout},
+	}}
+
+	assert.Equal(t, []string{"a"}, matchContainerPrefix(resp, "web-"))
+	assert.Equal(t, []string{"a"}, matchContainerPrefix(resp, "nginx"))
+	assert.Equal(t, []string{"b"}, matchContainerPrefix(resp, "my"))
+	assert.Empty(t, matchContainerPrefix(resp, "redis"))
+	assert.Empty(t, matchContainerPrefix(nil, "web-"))
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -39,6 +40,10 @@ func (api *TaskAPI) RegisterRoutes(r *gin.RouterGroup) {
 		tasks.POST("/:id/disable", api.DisableTask)
 		tasks.POST("/:id/trigger", api.TriggerTask)
 		tasks.GET("/:id/next-run", api.GetNextRunTime)
+		tasks.GET("/export", api.ExportTasks)
+		tasks.POST("/apply", api.ApplyTasks)
+		tasks.GET("/sync", api.GetSyncStatus)
+		tasks.POST("/sync", api.SyncTasks)
 	}
 	// 添加测试路由以验证注册是否成功
 	r.GET("/tasks-test", func(c *gin.Context) {
@@ -311,3 +316,92 @@ func (api *TaskAPI) GetNextRunTime(c *gin.Context) {
 		},
 	})
 }
+
+// ExportTasks 导出所有任务与分组（YAML）
+func (api *TaskAPI) ExportTasks(c *gin.Context) {
+	bundle, err := api.taskManager.ExportBundle(c.Request.Context())
+	if err == nil {
+		var data []byte
+		if data, err = bundle.Marshal(); err == nil {
+			c.Data(http.StatusOK, "application/x-yaml; charset=utf-8", data)
+			return
+		}
+	}
+	api.logger.Error("Failed to export tasks", "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"success": false,
+		"error":   err.Error(),
+	})
+}
+
+// ApplyTasks 应用 YAML 任务定义，dry_run=true 时只返回变更，prune=true 时删除定义中不存在的任务与分组，
+// 空定义配合 prune 须同时指定 allow_empty=true
+func (api *TaskAPI) ApplyTasks(c *gin.Context) {
+	data, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+	bundle, err := scheduler.ParseBundle(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	opts := scheduler.ApplyOptions{
+		DryRun:     c.Query("dry_run") == "true",
+		Prune:      c.Query("prune") == "true",
+		AllowEmpty: c.Query("allow_empty") == "true",
+	}
+	result, err := api.taskManager.ApplyBundle(c.Request.Context(), bundle, opts)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, scheduler.ErrInvalidBundle) {
+			status = http.StatusBadRequest
+		} else {
+			api.logger.Error("Failed to apply tasks", "error", err)
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// GetSyncStatus 获取 Git 任务定义同步状态
+func (api *TaskAPI) GetSyncStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    api.taskManager.BundleSyncStatus(),
+	})
+}
+
+// SyncTasks 立即从 Git 仓库同步任务定义，dry_run=true 时只返回变更
+func (api *TaskAPI) SyncTasks(c *gin.Context) {
+	result, err := api.taskManager.SyncBundle(c.Request.Context(), c.Query("dry_run") == "true")
+	if err != nil {
+		api.logger.Error("Failed to sync tasks", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
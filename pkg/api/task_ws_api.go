@@ -1,8 +1,10 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"sync"
 
 	"github.com/gin-gonic/gin"
@@ -28,8 +30,9 @@ type TaskWSMessage struct {
 
 // TaskWSAPI WebSocket 推送服务
 type TaskWSAPI struct {
-	hub    *WSHub
-	logger *monitoring.Logger
+	hub          *WSHub
+	logger       *monitoring.Logger
+	outputLoader func(ctx context.Context, executionID int64) (*models.Execution, string, error)
 }
 
 // WSHub WebSocket 连接中心
@@ -39,14 +42,23 @@ type WSHub struct {
 	unregister chan *WSClient
 	broadcast  chan []byte
 	mu         sync.RWMutex
+
+	// 执行日志订阅：执行 ID -> 客户端，日志订阅客户端不接收广播消息
+	logClients map[int64]map[*WSClient]bool
+	logMu      sync.Mutex
 }
 
 // WSClient WebSocket 客户端
 type WSClient struct {
-	hub    *WSHub
-	conn   *websocket.Conn
-	send   chan []byte
-	logger *monitoring.Logger
+	hub         *WSHub
+	conn        *websocket.Conn
+	send        chan []byte
+	logger      *monitoring.Logger
+	executionID int64 // 订阅日志的执行 ID，0 表示接收广播消息
+
+	// 加载已有输出期间到达的日志增量（由 logMu 保护），快照发送后按偏移去重补发
+	buffering bool
+	pending   []*models.ExecutionLogChunk
 }
 
 // NewTaskWSAPI 创建 WebSocket API
@@ -56,6 +68,7 @@ func NewTaskWSAPI(logger *monitoring.Logger) *TaskWSAPI {
 		register:   make(chan *WSClient),
 		unregister: make(chan *WSClient),
 		broadcast:  make(chan []byte, 256),
+		logClients: make(map[int64]map[*WSClient]bool),
 	}
 
 	go hub.run()
@@ -76,6 +89,10 @@ func (h *WSHub) run() {
 			h.mu.Unlock()
 
 		case client := <-h.unregister:
+			if client.executionID != 0 {
+				h.unsubscribeLog(client)
+				continue
+			}
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
@@ -98,6 +115,26 @@ func (h *WSHub) run() {
 	}
 }
 
+// unsubscribeLog 取消日志订阅，调用方不能持有 logMu
+func (h *WSHub) unsubscribeLog(client *WSClient) {
+	h.logMu.Lock()
+	defer h.logMu.Unlock()
+	h.removeLogClient(client)
+}
+
+// removeLogClient 移除日志订阅客户端并关闭发送通道，调用方需持有 logMu
+func (h *WSHub) removeLogClient(client *WSClient) {
+	clients := h.logClients[client.executionID]
+	if !clients[client] {
+		return
+	}
+	delete(clients, client)
+	close(client.send)
+	if len(clients) == 0 {
+		delete(h.logClients, client.executionID)
+	}
+}
+
 // readPump 读取客户端消息
 func (c *WSClient) readPump() {
 	defer func() {
@@ -139,6 +176,12 @@ func (c *WSClient) writePump() {
 // RegisterRoutes 注册路由
 func (api *TaskWSAPI) RegisterRoutes(r *gin.RouterGroup) {
 	r.GET("/ws/tasks", api.HandleWebSocket)
+	r.GET("/ws/executions/:id/logs", api.HandleExecutionLogs)
+}
+
+// SetOutputLoader 设置执行输出的加载函数（实时日志订阅时发送已有输出）
+func (api *TaskWSAPI) SetOutputLoader(loader func(ctx context.Context, executionID int64) (*models.Execution, string, error)) {
+	api.outputLoader = loader
 }
 
 // HandleWebSocket 处理 WebSocket 连接
@@ -165,6 +208,177 @@ func (api *TaskWSAPI) HandleWebSocket(c *gin.Context) {
 	api.logger.Info("WebSocket client connected", "remote_addr", c.Request.RemoteAddr)
 }
 
+// HandleExecutionLogs 实时查看执行日志：连接后先发送已有输出（offset 为 0），之后推送增量，
+// 执行结束时推送 finished 消息并关闭连接
+func (api *TaskWSAPI) HandleExecutionLogs(c *gin.Context) {
+	executionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || executionID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "invalid execution ID"})
+		return
+	}
+	if api.outputLoader == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "error": "execution logs not available"})
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		api.logger.Error("Failed to upgrade WebSocket", "error", err)
+		return
+	}
+
+	client := &WSClient{
+		hub:         api.hub,
+		conn:        conn,
+		send:        make(chan []byte, 256),
+		logger:      api.logger,
+		executionID: executionID,
+	}
+
+	// 先订阅并缓存增量，再在锁外加载已有输出（可能访问数据库或对象存储），
+	// 之后按偏移丢弃快照已包含的部分，保证增量不丢失也不重复
+	hub := api.hub
+	client.buffering = true
+	hub.logMu.Lock()
+	if hub.logClients[executionID] == nil {
+		hub.logClients[executionID] = make(map[*WSClient]bool)
+	}
+	hub.logClients[executionID][client] = true
+	hub.logMu.Unlock()
+
+	execution, output, err := api.outputLoader(c.Request.Context(), executionID)
+	if err != nil && execution == nil {
+		hub.unsubscribeLog(client)
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "execution not found"))
+		conn.Close()
+		return
+	}
+	if err != nil {
+		api.logger.Warn("Failed to load execution output", "execution_id", executionID, "error", err)
+	}
+	snapshot := &models.ExecutionLogChunk{
+		ExecutionID: executionID,
+		Output:      output,
+		Finished:    execution.IsFinished(),
+		Status:      execution.Status,
+	}
+
+	// 加载期间增量过多的客户端已被断开（发送通道已关闭），不再发送
+	hub.logMu.Lock()
+	finished := true
+	if hub.logClients[executionID][client] {
+		if data, err := marshalLogChunk(snapshot); err == nil {
+			client.send <- data
+		}
+		finished = snapshot.Finished
+		for _, chunk := range client.pending {
+			if finished {
+				break
+			}
+			if chunk = trimLogChunk(chunk, len(output)); chunk == nil {
+				continue
+			}
+			if !api.sendLogChunk(client, chunk) {
+				break
+			}
+			finished = chunk.Finished
+		}
+		client.buffering = false
+		client.pending = nil
+		if finished {
+			// 已结束的执行只发送完整输出
+			hub.removeLogClient(client)
+		}
+	}
+	hub.logMu.Unlock()
+
+	go client.writePump()
+	if !finished {
+		go client.readPump()
+	}
+
+	api.logger.Info("WebSocket log client connected", "execution_id", executionID, "remote_addr", c.Request.RemoteAddr)
+}
+
+// BroadcastExecutionLog 向订阅了执行日志的客户端推送日志增量，执行结束时关闭订阅
+func (api *TaskWSAPI) BroadcastExecutionLog(chunk *models.ExecutionLogChunk) {
+	hub := api.hub
+	hub.logMu.Lock()
+	defer hub.logMu.Unlock()
+
+	clients := hub.logClients[chunk.ExecutionID]
+	if len(clients) == 0 {
+		return
+	}
+	data, err := marshalLogChunk(chunk)
+	if err != nil {
+		api.logger.Error("Failed to marshal execution log message", "error", err)
+		return
+	}
+	for client := range clients {
+		if client.buffering {
+			if len(client.pending) >= cap(client.send) {
+				hub.removeLogClient(client)
+				continue
+			}
+			client.pending = append(client.pending, chunk)
+			continue
+		}
+		select {
+		case client.send <- data:
+		default:
+			// 跟不上的客户端断开，重新连接时会重新发送完整输出
+			hub.removeLogClient(client)
+			continue
+		}
+		if chunk.Finished {
+			hub.removeLogClient(client)
+		}
+	}
+}
+
+// sendLogChunk 发送日志增量，跟不上的客户端断开；执行结束时关闭订阅。调用方需持有 logMu
+func (api *TaskWSAPI) sendLogChunk(client *WSClient, chunk *models.ExecutionLogChunk) bool {
+	data, err := marshalLogChunk(chunk)
+	if err != nil {
+		api.logger.Error("Failed to marshal execution log message", "error", err)
+		return true
+	}
+	select {
+	case client.send <- data:
+		return true
+	default:
+		client.hub.removeLogClient(client)
+		return false
+	}
+}
+
+// trimLogChunk 丢弃增量中已包含在长度为 size 的快照内的部分，整段已包含且不是结束消息时返回 nil
+func trimLogChunk(chunk *models.ExecutionLogChunk, size int) *models.ExecutionLogChunk {
+	end := chunk.Offset + len(chunk.Output)
+	if end <= size {
+		if !chunk.Finished {
+			return nil
+		}
+		trimmed := *chunk
+		trimmed.Offset = end
+		trimmed.Output = ""
+		return &trimmed
+	}
+	if chunk.Offset >= size {
+		return chunk
+	}
+	trimmed := *chunk
+	trimmed.Output = chunk.Output[size-chunk.Offset:]
+	trimmed.Offset = size
+	return &trimmed
+}
+
+// marshalLogChunk 序列化执行日志消息
+func marshalLogChunk(chunk *models.ExecutionLogChunk) ([]byte, error) {
+	return json.Marshal(TaskWSMessage{Type: "execution_log", Data: chunk})
+}
+
 // BroadcastTaskStatus 广播任务状态更新
 func (api *TaskWSAPI) BroadcastTaskStatus(taskID string, status int) {
 	message := TaskWSMessage{
@@ -200,6 +414,22 @@ func (api *TaskWSAPI) BroadcastExecutionUpdate(execution *models.Execution) {
 	api.hub.broadcast <- data
 }
 
+// BroadcastWorkflowRunUpdate 广播工作流运行状态更新（含各节点状态）
+func (api *TaskWSAPI) BroadcastWorkflowRunUpdate(run *models.WorkflowRun) {
+	message := TaskWSMessage{
+		Type: "workflow_run_update",
+		Data: run,
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		api.logger.Error("Failed to marshal workflow run update message", "error", err)
+		return
+	}
+
+	api.hub.broadcast <- data
+}
+
 // BroadcastTaskCreated 广播任务创建
 func (api *TaskWSAPI) BroadcastTaskCreated(task *models.Task) {
 	message := TaskWSMessage{
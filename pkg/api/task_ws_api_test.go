@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/task/models"
+)
+
+func TestTaskWSAPI_ExecutionLogsDuringLoad(t *testing.T) {
+	api := NewTaskWSAPI(monitoring.NewLogger(monitoring.LogLevelError, "text"))
+	// 加载已有输出期间到达的增量：第一段已包含在快照中，第二段部分重叠
+	api.SetOutputLoader(func(ctx context.Context, executionID int64) (*models.Execution, string, error) {
+		api.BroadcastExecutionLog(&models.ExecutionLogChunk{ExecutionID: executionID, Offset: 0, Output: "hel"})
+		api.BroadcastExecutionLog(&models.ExecutionLogChunk{ExecutionID: executionID, Offset: 3, Output: "lo world"})
+		return &models.Execution{ID: executionID, Status: models.ExecutionStatusRunning}, "hello ", nil
+	})
+
+	router := setupTestRouter()
+	router.GET("/executions/:id/logs", api.HandleExecutionLogs)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/executions/7/logs", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	read := func() *models.ExecutionLogChunk {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var msg struct {
+			Data models.ExecutionLogChunk `json:"data"`
+		}
+		_, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &msg))
+		return &msg.Data
+	}
+
+	snapshot := read()
+	assert.Equal(t, 0, snapshot.Offset)
+	assert.Equal(t, "hello ", snapshot.Output)
+	chunk := read()
+	assert.Equal(t, 6, chunk.Offset)
+	assert.Equal(t, "world", chunk.Output)
+
+	api.BroadcastExecutionLog(&models.ExecutionLogChunk{ExecutionID: 7, Offset: 11, Finished: true, Status: models.ExecutionStatusSuccess})
+	assert.True(t, read().Finished)
+}
@@ -0,0 +1,364 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/task/scheduler"
+	"github.com/voilet/quic-flow/pkg/task/store"
+)
+
+// WorkflowAPI 任务工作流 API
+type WorkflowAPI struct {
+	workflowManager *scheduler.WorkflowManager
+	workflowStore   store.WorkflowStore
+	logger          *monitoring.Logger
+}
+
+// NewWorkflowAPI 创建任务工作流 API
+func NewWorkflowAPI(workflowManager *scheduler.WorkflowManager, workflowStore store.WorkflowStore, logger *monitoring.Logger) *WorkflowAPI {
+	return &WorkflowAPI{
+		workflowManager: workflowManager,
+		workflowStore:   workflowStore,
+		logger:          logger,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (api *WorkflowAPI) RegisterRoutes(r *gin.RouterGroup) {
+	workflows := r.Group("/workflows")
+	{
+		workflows.GET("", api.ListWorkflows)
+		workflows.POST("", api.CreateWorkflow)
+		workflows.GET("/:id", api.GetWorkflow)
+		workflows.PUT("/:id", api.UpdateWorkflow)
+		workflows.DELETE("/:id", api.DeleteWorkflow)
+		workflows.POST("/:id/enable", api.EnableWorkflow)
+		workflows.POST("/:id/disable", api.DisableWorkflow)
+		workflows.POST("/:id/trigger", api.TriggerWorkflow)
+		workflows.GET("/:id/next-run", api.GetNextRunTime)
+		workflows.GET("/:id/runs", api.ListRuns)
+	}
+
+	runs := r.Group("/workflow-runs")
+	{
+		runs.GET("", api.ListRuns)
+		runs.GET("/:id", api.GetRun)
+	}
+}
+
+// idParam 解析路径中的 ID，失败时返回 400
+func (api *WorkflowAPI) idParam(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "invalid " + name + " id",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// ListWorkflows 获取工作流列表
+func (api *WorkflowAPI) ListWorkflows(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	params := &store.ListParams{
+		Page:     page,
+		PageSize: pageSize,
+		Keyword:  c.Query("keyword"),
+	}
+	if s, err := strconv.Atoi(c.Query("status")); err == nil {
+		params.Status = &s
+	}
+
+	workflows, total, err := api.workflowStore.List(c.Request.Context(), params)
+	if err != nil {
+		api.logger.Error("Failed to list workflows", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"workflows": workflows,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// CreateWorkflow 创建工作流
+func (api *WorkflowAPI) CreateWorkflow(c *gin.Context) {
+	var req scheduler.CreateWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	workflow, err := api.workflowManager.CreateWorkflow(c.Request.Context(), &req)
+	if err != nil {
+		api.logger.Error("Failed to create workflow", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    workflow,
+	})
+}
+
+// GetWorkflow 获取工作流详情
+func (api *WorkflowAPI) GetWorkflow(c *gin.Context) {
+	workflowID, ok := api.idParam(c, "workflow")
+	if !ok {
+		return
+	}
+
+	workflow, err := api.workflowStore.GetByID(c.Request.Context(), workflowID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "workflow not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    workflow,
+	})
+}
+
+// UpdateWorkflow 更新工作流
+func (api *WorkflowAPI) UpdateWorkflow(c *gin.Context) {
+	workflowID, ok := api.idParam(c, "workflow")
+	if !ok {
+		return
+	}
+
+	var req scheduler.UpdateWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	req.WorkflowID = workflowID
+	workflow, err := api.workflowManager.UpdateWorkflow(c.Request.Context(), &req)
+	if err != nil {
+		api.logger.Error("Failed to update workflow", "workflow_id", workflowID, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    workflow,
+	})
+}
+
+// DeleteWorkflow 删除工作流
+func (api *WorkflowAPI) DeleteWorkflow(c *gin.Context) {
+	workflowID, ok := api.idParam(c, "workflow")
+	if !ok {
+		return
+	}
+
+	if err := api.workflowManager.DeleteWorkflow(c.Request.Context(), workflowID); err != nil {
+		api.logger.Error("Failed to delete workflow", "workflow_id", workflowID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "workflow deleted",
+	})
+}
+
+// EnableWorkflow 启用工作流
+func (api *WorkflowAPI) EnableWorkflow(c *gin.Context) {
+	workflowID, ok := api.idParam(c, "workflow")
+	if !ok {
+		return
+	}
+
+	if err := api.workflowManager.EnableWorkflow(c.Request.Context(), workflowID); err != nil {
+		api.logger.Error("Failed to enable workflow", "workflow_id", workflowID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "workflow enabled",
+	})
+}
+
+// DisableWorkflow 禁用工作流
+func (api *WorkflowAPI) DisableWorkflow(c *gin.Context) {
+	workflowID, ok := api.idParam(c, "workflow")
+	if !ok {
+		return
+	}
+
+	if err := api.workflowManager.DisableWorkflow(c.Request.Context(), workflowID); err != nil {
+		api.logger.Error("Failed to disable workflow", "workflow_id", workflowID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "workflow disabled",
+	})
+}
+
+// TriggerWorkflow 手动触发工作流运行，返回运行记录
+func (api *WorkflowAPI) TriggerWorkflow(c *gin.Context) {
+	workflowID, ok := api.idParam(c, "workflow")
+	if !ok {
+		return
+	}
+
+	run, err := api.workflowManager.TriggerWorkflow(c.Request.Context(), workflowID)
+	if err != nil {
+		api.logger.Error("Failed to trigger workflow", "workflow_id", workflowID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    run,
+	})
+}
+
+// GetNextRunTime 获取工作流下次定时运行时间
+func (api *WorkflowAPI) GetNextRunTime(c *gin.Context) {
+	workflowID, ok := api.idParam(c, "workflow")
+	if !ok {
+		return
+	}
+
+	nextRun, err := api.workflowManager.GetNextRunTime(workflowID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"next_run_time": nextRun,
+		},
+	})
+}
+
+// ListRuns 获取运行记录列表（/workflows/:id/runs 限定工作流，/workflow-runs 支持 workflow_id 筛选）
+func (api *WorkflowAPI) ListRuns(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	params := &store.WorkflowRunListParams{
+		Page:     page,
+		PageSize: pageSize,
+	}
+	workflowIDStr := c.Param("id")
+	if workflowIDStr == "" {
+		workflowIDStr = c.Query("workflow_id")
+	}
+	if workflowIDStr != "" {
+		workflowID, err := strconv.ParseInt(workflowIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "invalid workflow id",
+			})
+			return
+		}
+		params.WorkflowID = &workflowID
+	}
+	if s, err := strconv.Atoi(c.Query("status")); err == nil {
+		params.Status = &s
+	}
+
+	runs, total, err := api.workflowStore.ListRuns(c.Request.Context(), params)
+	if err != nil {
+		api.logger.Error("Failed to list workflow runs", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"runs":      runs,
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+	})
+}
+
+// GetRun 获取运行记录的可视化状态：各节点状态与执行、边的走向及 Mermaid 流程图
+func (api *WorkflowAPI) GetRun(c *gin.Context) {
+	runID, ok := api.idParam(c, "run")
+	if !ok {
+		return
+	}
+
+	graph, err := api.workflowManager.RunGraph(c.Request.Context(), runID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    graph,
+	})
+}
@@ -0,0 +1,332 @@
+package batch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrJobNotFound 任务不存在
+var ErrJobNotFound = errors.New("batch job not found")
+
+// JobStore 批量任务持久化接口
+type JobStore interface {
+	// SaveJob 保存任务定义与进度
+	SaveJob(job *BatchJob) error
+	// SaveResult 保存单个客户端结果
+	SaveResult(jobID string, result *TaskResult) error
+	// LoadJob 加载任务（含客户端结果）
+	LoadJob(jobID string) (*BatchJob, error)
+	// ListJobs 列出最近的任务（不含客户端结果，最新在前）
+	ListJobs(limit int) ([]*BatchJob, error)
+	// ListUnfinished 列出未结束的任务（含客户端结果）
+	ListUnfinished() ([]*BatchJob, error)
+}
+
+// BatchJobModel 批量任务表
+type BatchJobModel struct {
+	ID            string `gorm:"primaryKey;size:64"`
+	Command       string `gorm:"size:100;not null"`
+	Payload       string `gorm:"type:text"`
+	TargetClients string `gorm:"type:text"` // JSON 数组
+	WaitForResult bool   `gorm:"default:false"`
+	Rollout       string `gorm:"type:text"` // JSON，空表示一次性下发
+	Waves         string `gorm:"type:text"` // JSON []waveState
+	CurrentWave   int    `gorm:"default:0"`
+	HaltReason    string `gorm:"size:500"`
+	Status        string `gorm:"size:20;index;not null"`
+	TotalCount    int64  `gorm:"default:0"`
+	SuccessCount  int64  `gorm:"default:0"`
+	FailedCount   int64  `gorm:"default:0"`
+	StartedAt     *time.Time
+	FinishedAt    *time.Time
+	CreatedAt     time.Time `gorm:"index;not null"`
+	UpdatedAt     time.Time
+}
+
+func (BatchJobModel) TableName() string {
+	return "batch_jobs"
+}
+
+// BatchResultModel 批量任务客户端结果表
+type BatchResultModel struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	JobID      string    `gorm:"size:64;not null;uniqueIndex:idx_batch_result_job_client"`
+	ClientID   string    `gorm:"size:100;not null;uniqueIndex:idx_batch_result_job_client"`
+	Status     string    `gorm:"size:20;index"`
+	Result     string    `gorm:"type:text"`
+	Error      string    `gorm:"type:text"`
+	DurationMs int64     `gorm:"default:0"`
+	RetryCount int       `gorm:"default:0"`
+	UpdatedAt  time.Time `gorm:"not null"`
+}
+
+func (BatchResultModel) TableName() string {
+	return "batch_job_results"
+}
+
+// waveState 批次持久化状态（客户端按顺序从 TargetClients 中切分）
+type waveState struct {
+	Size       int        `json:"size"`
+	Status     WaveStatus `json:"status"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// DBStore 基于数据库的批量任务存储
+type DBStore struct {
+	db *gorm.DB
+}
+
+// NewDBStore 创建数据库存储并迁移表结构
+func NewDBStore(db *gorm.DB) (*DBStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database connection is required")
+	}
+	if err := db.AutoMigrate(&BatchJobModel{}, &BatchResultModel{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate batch job tables: %w", err)
+	}
+	return &DBStore{db: db}, nil
+}
+
+// SaveJob 保存任务定义与进度
+func (s *DBStore) SaveJob(job *BatchJob) error {
+	model, err := newJobModel(job)
+	if err != nil {
+		return err
+	}
+	return s.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(model).Error
+}
+
+// SaveResult 保存单个客户端结果（同一客户端覆盖）
+func (s *DBStore) SaveResult(jobID string, result *TaskResult) error {
+	model := &BatchResultModel{
+		JobID:      jobID,
+		ClientID:   result.ClientID,
+		Status:     string(result.Status),
+		Result:     string(result.Result),
+		Error:      result.Error,
+		DurationMs: result.Duration.Milliseconds(),
+		RetryCount: result.RetryCount,
+		UpdatedAt:  time.Now(),
+	}
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "job_id"}, {Name: "client_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "result", "error", "duration_ms", "retry_count", "updated_at"}),
+	}).Create(model).Error
+}
+
+// LoadJob 加载任务（含客户端结果）
+func (s *DBStore) LoadJob(jobID string) (*BatchJob, error) {
+	var model BatchJobModel
+	if err := s.db.Where("id = ?", jobID).First(&model).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	return s.restore(&model, true)
+}
+
+// ListJobs 列出最近的任务（不含客户端结果，最新在前）
+func (s *DBStore) ListJobs(limit int) ([]*BatchJob, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	var models []BatchJobModel
+	if err := s.db.Order("created_at DESC").Limit(limit).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	jobs := make([]*BatchJob, 0, len(models))
+	for i := range models {
+		job, err := s.restore(&models[i], false)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// ListUnfinished 列出未结束的任务（含客户端结果）
+func (s *DBStore) ListUnfinished() ([]*BatchJob, error) {
+	var models []BatchJobModel
+	statuses := []string{string(BatchJobPending), string(BatchJobRunning), string(BatchJobPaused), string(BatchJobHalted), string(BatchJobInterrupted)}
+	if err := s.db.Where("status IN ?", statuses).Order("created_at ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	jobs := make([]*BatchJob, 0, len(models))
+	for i := range models {
+		job, err := s.restore(&models[i], true)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// restore 由数据库记录重建任务，withResults 为 true 时同时加载结果并重新统计进度
+func (s *DBStore) restore(model *BatchJobModel, withResults bool) (*BatchJob, error) {
+	job := &BatchJob{
+		ID:            model.ID,
+		CreatedAt:     model.CreatedAt,
+		Command:       model.Command,
+		WaitForResult: model.WaitForResult,
+		CurrentWave:   model.CurrentWave,
+		HaltReason:    model.HaltReason,
+		Status:        BatchJobStatus(model.Status),
+		TotalCount:    model.TotalCount,
+		SuccessCount:  model.SuccessCount,
+		FailedCount:   model.FailedCount,
+		PendingCount:  model.TotalCount - model.SuccessCount - model.FailedCount,
+	}
+	if model.Payload != "" {
+		job.Payload = json.RawMessage(model.Payload)
+	}
+	if model.StartedAt != nil {
+		job.startTime = *model.StartedAt
+	}
+	if model.FinishedAt != nil {
+		job.endTime = *model.FinishedAt
+	}
+	if err := json.Unmarshal([]byte(model.TargetClients), &job.TargetClients); err != nil {
+		return nil, fmt.Errorf("job %s: invalid target clients: %w", model.ID, err)
+	}
+	if model.Rollout != "" {
+		job.Rollout = &RolloutPolicy{}
+		if err := json.Unmarshal([]byte(model.Rollout), job.Rollout); err != nil {
+			return nil, fmt.Errorf("job %s: invalid rollout: %w", model.ID, err)
+		}
+	}
+
+	var states []waveState
+	if model.Waves != "" {
+		if err := json.Unmarshal([]byte(model.Waves), &states); err != nil {
+			return nil, fmt.Errorf("job %s: invalid waves: %w", model.ID, err)
+		}
+	}
+	start := 0
+	for i, st := range states {
+		end := min(start+st.Size, len(job.TargetClients))
+		job.Waves = append(job.Waves, &Wave{
+			Index:      i,
+			ClientIDs:  job.TargetClients[start:end],
+			Status:     st.Status,
+			StartedAt:  st.StartedAt,
+			FinishedAt: st.FinishedAt,
+		})
+		start = end
+	}
+	if len(job.Waves) == 0 {
+		job.Waves = planWaves(job.TargetClients, nil)
+	}
+
+	if !withResults {
+		return job, nil
+	}
+
+	var results []BatchResultModel
+	if err := s.db.Where("job_id = ?", model.ID).Find(&results).Error; err != nil {
+		return nil, err
+	}
+	waveOf := make(map[string]*Wave, len(job.TargetClients))
+	for _, w := range job.Waves {
+		for _, cid := range w.ClientIDs {
+			waveOf[cid] = w
+		}
+	}
+	job.SuccessCount, job.FailedCount = 0, 0
+	for _, r := range results {
+		result := &TaskResult{
+			ClientID:   r.ClientID,
+			Status:     TaskStatus(r.Status),
+			Success:    TaskStatus(r.Status) == TaskSucceeded,
+			Error:      r.Error,
+			Duration:   time.Duration(r.DurationMs) * time.Millisecond,
+			RetryCount: r.RetryCount,
+		}
+		if r.Result != "" {
+			result.Result = json.RawMessage(r.Result)
+		}
+		job.Results.Store(r.ClientID, result)
+
+		w := waveOf[r.ClientID]
+		switch result.Status {
+		case TaskSucceeded:
+			job.SuccessCount++
+			if w != nil {
+				w.SuccessCount++
+			}
+		case TaskFailed:
+			job.FailedCount++
+			job.Errors.Store(r.ClientID, errors.New(r.Error))
+			if w != nil {
+				w.FailedCount++
+			}
+		}
+	}
+	job.PendingCount = job.TotalCount - job.SuccessCount - job.FailedCount
+	return job, nil
+}
+
+// newJobModel 在任务锁内生成数据库记录
+func newJobModel(job *BatchJob) (*BatchJobModel, error) {
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+
+	targets, err := json.Marshal(job.TargetClients)
+	if err != nil {
+		return nil, err
+	}
+	states := make([]waveState, len(job.Waves))
+	for i, w := range job.Waves {
+		states[i] = waveState{
+			Size:       len(w.ClientIDs),
+			Status:     w.Status,
+			StartedAt:  w.StartedAt,
+			FinishedAt: w.FinishedAt,
+		}
+	}
+	waves, err := json.Marshal(states)
+	if err != nil {
+		return nil, err
+	}
+
+	model := &BatchJobModel{
+		ID:            job.ID,
+		Command:       job.Command,
+		Payload:       string(job.Payload),
+		TargetClients: string(targets),
+		WaitForResult: job.WaitForResult,
+		Waves:         string(waves),
+		CurrentWave:   job.CurrentWave,
+		HaltReason:    job.HaltReason,
+		Status:        string(job.Status),
+		TotalCount:    job.TotalCount,
+		SuccessCount:  atomic.LoadInt64(&job.SuccessCount),
+		FailedCount:   atomic.LoadInt64(&job.FailedCount),
+		CreatedAt:     job.CreatedAt,
+	}
+	if job.Rollout != nil {
+		rollout, err := json.Marshal(job.Rollout)
+		if err != nil {
+			return nil, err
+		}
+		model.Rollout = string(rollout)
+	}
+	if !job.startTime.IsZero() {
+		started := job.startTime
+		model.StartedAt = &started
+	}
+	if !job.endTime.IsZero() {
+		finished := job.endTime
+		model.FinishedAt = &finished
+	}
+	return model, nil
+}
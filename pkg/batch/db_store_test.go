@@ -0,0 +1,132 @@
+package batch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestStore(t *testing.T) *DBStore {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	store, err := NewDBStore(db)
+	require.NoError(t, err)
+	return store
+}
+
+// seedRunningJob 构造重启前运行到第一批次中途的任务：c00 成功，c01 未返回
+func seedRunningJob(t *testing.T, store *DBStore) *BatchJob {
+	targets := clients(4)
+	job := &BatchJob{
+		ID:            "job-1",
+		CreatedAt:     time.Now(),
+		Command:       "exec_shell",
+		TargetClients: targets,
+		Rollout:       &RolloutPolicy{FirstWave: 2},
+		Waves:         planWaves(targets, &RolloutPolicy{FirstWave: 2}),
+		Status:        BatchJobRunning,
+		TotalCount:    4,
+	}
+	job.Waves[0].Status = WaveRunning
+	require.NoError(t, store.SaveJob(job))
+	require.NoError(t, store.SaveResult(job.ID, &TaskResult{ClientID: "c00", Status: TaskSucceeded, Success: true}))
+	return job
+}
+
+func TestDBStoreRoundTrip(t *testing.T) {
+	store := newTestStore(t)
+	e := newTestExecutor(&fakeSender{failing: map[string]bool{"c01": true}})
+	defer e.Stop()
+	e.SetStore(store)
+
+	job, err := e.ExecuteWithRollout("exec_shell", []byte(`{"command":"uptime"}`), clients(3), false, &RolloutPolicy{FirstWave: 1})
+	require.NoError(t, err)
+	waitStatus(t, job, BatchJobCompleted)
+
+	loaded, err := store.LoadJob(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, BatchJobCompleted, loaded.Status)
+	assert.Equal(t, int64(2), loaded.SuccessCount)
+	assert.Equal(t, int64(1), loaded.FailedCount)
+	assert.Equal(t, &RolloutPolicy{FirstWave: 1}, loaded.Rollout)
+	assert.JSONEq(t, `{"command":"uptime"}`, string(loaded.Payload))
+	require.Len(t, loaded.Waves, 2)
+	assert.Equal(t, []string{"c01", "c02"}, loaded.Waves[1].ClientIDs)
+
+	val, ok := loaded.Results.Load("c01")
+	require.True(t, ok)
+	assert.Equal(t, TaskFailed, val.(*TaskResult).Status)
+
+	_, err = store.LoadJob("missing")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+
+	// 内存中清理后仍可从存储查询
+	e.activeJobs.Delete(job.ID)
+	_, found := e.GetJob(job.ID)
+	assert.True(t, found)
+	assert.Len(t, e.ListJobs(), 1)
+}
+
+func TestRecoverMarksInterrupted(t *testing.T) {
+	store := newTestStore(t)
+	seedRunningJob(t, store)
+
+	sender := &fakeSender{}
+	e := newTestExecutor(sender)
+	defer e.Stop()
+	e.SetStore(store)
+
+	n, err := e.Recover(false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	job, ok := e.GetJob("job-1")
+	require.True(t, ok)
+	assert.Equal(t, BatchJobInterrupted, job.Status)
+	val, _ := job.Results.Load("c01")
+	assert.Equal(t, TaskInterrupted, val.(*TaskResult).Status)
+	assert.Equal(t, 0, sender.sentCount())
+
+	persisted, err := store.LoadJob("job-1")
+	require.NoError(t, err)
+	assert.Equal(t, BatchJobInterrupted, persisted.Status)
+	assert.Equal(t, WaveInterrupted, persisted.Waves[0].Status)
+
+	// 手动恢复：仅重新执行未完成的客户端
+	require.NoError(t, e.ResumeJob("job-1"))
+	waitStatus(t, job, BatchJobCompleted)
+	assert.ElementsMatch(t, []string{"c01", "c02", "c03"}, sender.sent)
+	assert.Equal(t, int64(4), job.SuccessCount)
+}
+
+func TestRecoverResumesRunningJobs(t *testing.T) {
+	store := newTestStore(t)
+	seedRunningJob(t, store)
+
+	sender := &fakeSender{}
+	e := newTestExecutor(sender)
+	e.resumeDelay = time.Hour
+	defer e.Stop()
+	e.SetStore(store)
+
+	_, err := e.Recover(true)
+	require.NoError(t, err)
+	job, _ := e.GetJob("job-1")
+
+	// 等待尚未返回结果的客户端重连后再继续
+	time.Sleep(1500 * time.Millisecond)
+	assert.Zero(t, sender.sentCount())
+	sender.setOnline("c01", "c02", "c03")
+	waitStatus(t, job, BatchJobCompleted)
+	assert.Equal(t, 3, sender.sentCount())
+
+	persisted, err := store.LoadJob("job-1")
+	require.NoError(t, err)
+	assert.Equal(t, BatchJobCompleted, persisted.Status)
+	assert.Equal(t, int64(4), persisted.SuccessCount)
+}
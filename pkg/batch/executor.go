@@ -3,7 +3,9 @@ package batch
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,6 +16,12 @@ import (
 	"github.com/voilet/quic-flow/pkg/protocol"
 )
 
+// maxHistoryJobs ListJobs 从存储加载的历史任务数量
+const maxHistoryJobs = 100
+
+// defaultResumeDelay 启动后恢复任务前等待 Agent 重连的最长时间
+const defaultResumeDelay = 30 * time.Second
+
 // BatchExecutor 批量任务执行器
 // 支持向大量客户端并发发送命令，带进度跟踪和流控
 type BatchExecutor struct {
@@ -27,6 +35,10 @@ type BatchExecutor struct {
 	activeJobs sync.Map // jobID -> *BatchJob
 	jobCount   atomic.Int64
 
+	// 持久化（可选）
+	store       JobStore
+	resumeDelay time.Duration // 启动后恢复任务前等待目标客户端重连的最长时间
+
 	// 日志
 	logger *monitoring.Logger
 
@@ -59,8 +71,8 @@ type BatchConfig struct {
 	RetryInterval time.Duration // 重试间隔（默认 1s）
 
 	// 进度回调
-	OnProgress func(job *BatchJob)     // 进度更新回调
-	OnComplete func(job *BatchJob)     // 任务完成回调
+	OnProgress func(job *BatchJob)              // 进度更新回调
+	OnComplete func(job *BatchJob)              // 任务完成回调
 	OnError    func(clientID string, err error) // 单客户端错误回调
 
 	// 日志
@@ -73,16 +85,23 @@ type BatchJob struct {
 	CreatedAt time.Time `json:"created_at"`
 
 	// 任务内容
-	Command     string          `json:"command"`
-	Payload     json.RawMessage `json:"payload"`
-	TargetClients []string      `json:"target_clients"` // 空表示所有客户端
+	Command       string          `json:"command"`
+	Payload       json.RawMessage `json:"payload"`
+	TargetClients []string        `json:"target_clients"` // 空表示所有客户端
+
+	// 分批发布
+	WaitForResult bool           `json:"wait_for_result"`
+	Rollout       *RolloutPolicy `json:"rollout,omitempty"`
+	Waves         []*Wave        `json:"waves"`
+	CurrentWave   int            `json:"current_wave"`          // 下一个待执行批次的索引
+	HaltReason    string         `json:"halt_reason,omitempty"` // 熔断原因
 
 	// 执行状态
-	Status      BatchJobStatus `json:"status"`
-	TotalCount  int64          `json:"total_count"`
-	SuccessCount int64         `json:"success_count"`
-	FailedCount int64          `json:"failed_count"`
-	PendingCount int64         `json:"pending_count"`
+	Status       BatchJobStatus `json:"status"`
+	TotalCount   int64          `json:"total_count"`
+	SuccessCount int64          `json:"success_count"`
+	FailedCount  int64          `json:"failed_count"`
+	PendingCount int64          `json:"pending_count"`
 
 	// 结果
 	Results sync.Map // clientID -> *TaskResult
@@ -91,23 +110,39 @@ type BatchJob struct {
 	// 内部控制
 	startTime time.Time
 	endTime   time.Time
+	cancel    context.CancelFunc
 	mu        sync.RWMutex
+	saveMu    sync.Mutex // 保证任务记录按顺序写入存储
 }
 
 // BatchJobStatus 任务状态
 type BatchJobStatus string
 
 const (
-	BatchJobPending   BatchJobStatus = "pending"
-	BatchJobRunning   BatchJobStatus = "running"
-	BatchJobCompleted BatchJobStatus = "completed"
-	BatchJobFailed    BatchJobStatus = "failed"
-	BatchJobCancelled BatchJobStatus = "cancelled"
+	BatchJobPending     BatchJobStatus = "pending"
+	BatchJobRunning     BatchJobStatus = "running"
+	BatchJobCompleted   BatchJobStatus = "completed"
+	BatchJobFailed      BatchJobStatus = "failed"
+	BatchJobCancelled   BatchJobStatus = "cancelled"
+	BatchJobPaused      BatchJobStatus = "paused"      // 批次间等待批准
+	BatchJobHalted      BatchJobStatus = "halted"      // 失败率超过阈值，已熔断
+	BatchJobInterrupted BatchJobStatus = "interrupted" // 服务重启时未完成
+)
+
+// TaskStatus 单客户端任务状态
+type TaskStatus string
+
+const (
+	TaskSucceeded   TaskStatus = "success"
+	TaskFailed      TaskStatus = "failed"
+	TaskInterrupted TaskStatus = "interrupted" // 服务重启时未返回结果
+	TaskPending     TaskStatus = "pending"     // 尚未执行（仅用于导出）
 )
 
 // TaskResult 单任务结果
 type TaskResult struct {
 	ClientID   string          `json:"client_id"`
+	Status     TaskStatus      `json:"status"`
 	Success    bool            `json:"success"`
 	Result     json.RawMessage `json:"result,omitempty"`
 	Error      string          `json:"error,omitempty"`
@@ -144,20 +179,38 @@ func NewBatchExecutor(sender MessageSender, config *BatchConfig) *BatchExecutor
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &BatchExecutor{
-		sender: sender,
-		config: config,
-		logger: config.Logger,
-		ctx:    ctx,
-		cancel: cancel,
+		sender:      sender,
+		config:      config,
+		logger:      config.Logger,
+		resumeDelay: defaultResumeDelay,
+		ctx:         ctx,
+		cancel:      cancel,
 	}
 }
 
+// SetStore 设置任务持久化存储
+func (e *BatchExecutor) SetStore(store JobStore) {
+	e.store = store
+}
+
 // Execute 执行批量任务
 // command: 命令类型
 // payload: 命令参数
 // targetClients: 目标客户端列表（空表示所有在线客户端）
 // waitForResult: 是否等待执行结果
 func (e *BatchExecutor) Execute(command string, payload json.RawMessage, targetClients []string, waitForResult bool) (*BatchJob, error) {
+	return e.ExecuteWithRollout(command, payload, targetClients, waitForResult, nil)
+}
+
+// ExecuteWithRollout 按分批策略执行批量任务
+// rollout 为空时一次性下发到全部目标客户端
+func (e *BatchExecutor) ExecuteWithRollout(command string, payload json.RawMessage, targetClients []string, waitForResult bool, rollout *RolloutPolicy) (*BatchJob, error) {
+	if rollout != nil {
+		if err := rollout.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid rollout policy: %w", err)
+		}
+	}
+
 	// 确定目标客户端
 	if len(targetClients) == 0 {
 		targetClients = e.sender.ListClients()
@@ -174,6 +227,9 @@ func (e *BatchExecutor) Execute(command string, payload json.RawMessage, targetC
 		Command:       command,
 		Payload:       payload,
 		TargetClients: targetClients,
+		WaitForResult: waitForResult,
+		Rollout:       rollout,
+		Waves:         planWaves(targetClients, rollout),
 		Status:        BatchJobPending,
 		TotalCount:    int64(len(targetClients)),
 		PendingCount:  int64(len(targetClients)),
@@ -182,27 +238,35 @@ func (e *BatchExecutor) Execute(command string, payload json.RawMessage, targetC
 	// 存储任务
 	e.activeJobs.Store(job.ID, job)
 	e.jobCount.Add(1)
+	e.saveJob(job)
 
 	e.logger.Info("Batch job created",
 		"job_id", job.ID,
 		"command", command,
 		"target_count", len(targetClients),
+		"waves", len(job.Waves),
 		"wait_for_result", waitForResult)
 
 	// 异步执行
-	go e.executeJob(job, waitForResult)
+	go e.executeJob(job)
 
 	return job, nil
 }
 
 // ExecuteSync 同步执行批量任务（等待完成）
 func (e *BatchExecutor) ExecuteSync(ctx context.Context, command string, payload json.RawMessage, targetClients []string) (*BatchJob, error) {
-	job, err := e.Execute(command, payload, targetClients, true)
+	return e.ExecuteSyncWithRollout(ctx, command, payload, targetClients, nil)
+}
+
+// ExecuteSyncWithRollout 按分批策略同步执行批量任务
+// 任务结束，或暂停、熔断、中断（需人工恢复，原因见 HaltReason）时返回
+func (e *BatchExecutor) ExecuteSyncWithRollout(ctx context.Context, command string, payload json.RawMessage, targetClients []string, rollout *RolloutPolicy) (*BatchJob, error) {
+	job, err := e.ExecuteWithRollout(command, payload, targetClients, true, rollout)
 	if err != nil {
 		return nil, err
 	}
 
-	// 等待完成
+	// 等待完成或停止
 	for {
 		select {
 		case <-ctx.Done():
@@ -213,59 +277,170 @@ func (e *BatchExecutor) ExecuteSync(ctx context.Context, command string, payload
 			status := job.Status
 			job.mu.RUnlock()
 
-			if status == BatchJobCompleted || status == BatchJobFailed || status == BatchJobCancelled {
+			switch status {
+			case BatchJobCompleted, BatchJobFailed, BatchJobCancelled,
+				BatchJobPaused, BatchJobHalted, BatchJobInterrupted:
 				return job, nil
 			}
 		}
 	}
 }
 
-// executeJob 执行批量任务
-func (e *BatchExecutor) executeJob(job *BatchJob, waitForResult bool) {
+// ResumeJob 恢复暂停、已熔断或因重启中断的任务，从下一个未完成的批次继续执行
+func (e *BatchExecutor) ResumeJob(jobID string) error {
+	val, ok := e.activeJobs.Load(jobID)
+	if !ok {
+		return fmt.Errorf("job not found: %s", jobID)
+	}
+	job := val.(*BatchJob)
+
 	job.mu.Lock()
+	if job.Status != BatchJobPaused && job.Status != BatchJobHalted && job.Status != BatchJobInterrupted {
+		status := job.Status
+		job.mu.Unlock()
+		return fmt.Errorf("job %s is %s, only paused, halted or interrupted jobs can be resumed", jobID, status)
+	}
 	job.Status = BatchJobRunning
-	job.startTime = time.Now()
+	job.HaltReason = ""
 	job.mu.Unlock()
 
-	// 创建任务超时 context
+	e.logger.Info("Batch job resumed", "job_id", jobID, "wave", job.CurrentWave)
+	go e.executeJob(job)
+	return nil
+}
+
+// executeJob 从当前批次开始逐批执行，熔断或等待批准时返回
+func (e *BatchExecutor) executeJob(job *BatchJob) {
+	// 创建任务超时 context（每次恢复重新计时）
 	ctx, cancel := context.WithTimeout(e.ctx, e.config.JobTimeout)
 	defer cancel()
 
-	// 使用信号量控制并发
-	sem := make(chan struct{}, e.config.MaxConcurrency)
-	var wg sync.WaitGroup
+	job.mu.Lock()
+	if job.Status == BatchJobCancelled {
+		job.mu.Unlock()
+		e.finishJob(job)
+		return
+	}
+	job.Status = BatchJobRunning
+	if job.startTime.IsZero() {
+		job.startTime = time.Now()
+	}
+	job.cancel = cancel
+	job.mu.Unlock()
+	e.saveJob(job)
 
 	e.logger.Info("Batch job started",
 		"job_id", job.ID,
 		"total_clients", len(job.TargetClients),
+		"wave", job.CurrentWave,
 		"max_concurrency", e.config.MaxConcurrency)
 
-	// 向所有目标客户端发送任务
-	for _, clientID := range job.TargetClients {
+	for {
+		job.mu.Lock()
+		if job.Status != BatchJobRunning || job.CurrentWave >= len(job.Waves) {
+			job.mu.Unlock()
+			break
+		}
+		wave := job.Waves[job.CurrentWave]
+		now := time.Now()
+		wave.Status = WaveRunning
+		wave.StartedAt = &now
+		job.mu.Unlock()
+
+		e.runWave(ctx, job, wave)
+
+		// 执行器停止（服务关闭）时保留运行状态，由下次启动时恢复
+		if e.ctx.Err() != nil {
+			return
+		}
+
+		// 批次结束：检查失败率，决定继续、暂停或熔断
+		rate := wave.failureRate()
+		job.mu.Lock()
+		end := time.Now()
+		wave.FinishedAt = &end
+		wave.FailureRate = rate
+		if job.Status != BatchJobRunning {
+			wave.Status = WaveCancelled
+			job.mu.Unlock()
+			break
+		}
+		wave.Status = WaveCompleted
+		job.CurrentWave++
+		more := job.CurrentWave < len(job.Waves)
+		switch {
+		case more && job.Rollout != nil && job.Rollout.MaxFailureRate > 0 && rate > job.Rollout.MaxFailureRate:
+			wave.Status = WaveHalted
+			job.Status = BatchJobHalted
+			job.HaltReason = fmt.Sprintf("wave %d failure rate %.1f%% exceeds %.1f%%", wave.Index, rate, job.Rollout.MaxFailureRate)
+		case more && job.Rollout != nil && job.Rollout.PauseBetweenWaves:
+			job.Status = BatchJobPaused
+		}
+		status, reason := job.Status, job.HaltReason
+		if status != BatchJobRunning {
+			job.cancel = nil
+		}
+		job.mu.Unlock()
+		e.saveJob(job)
+
+		switch status {
+		case BatchJobHalted:
+			e.logger.Warn("Batch job halted", "job_id", job.ID, "wave", wave.Index, "reason", reason)
+			return
+		case BatchJobPaused:
+			e.logger.Info("Batch job paused for approval", "job_id", job.ID, "next_wave", wave.Index+1)
+			return
+		}
+	}
+
+	e.finishJob(job)
+}
+
+// runWave 并发向批次内的客户端发送任务
+func (e *BatchExecutor) runWave(ctx context.Context, job *BatchJob, wave *Wave) {
+	// 使用信号量控制并发
+	sem := make(chan struct{}, e.config.MaxConcurrency)
+	var wg sync.WaitGroup
+
+	for _, clientID := range wave.ClientIDs {
+		// 恢复执行时跳过已有最终结果的客户端
+		if val, ok := job.Results.Load(clientID); ok && val.(*TaskResult).Status != TaskInterrupted {
+			continue
+		}
+
 		select {
 		case <-ctx.Done():
+			wg.Wait()
+			if e.ctx.Err() != nil {
+				return
+			}
 			e.logger.Warn("Batch job timeout or cancelled", "job_id", job.ID)
 			job.mu.Lock()
 			job.Status = BatchJobCancelled
 			job.mu.Unlock()
-			goto done
+			return
 		case sem <- struct{}{}:
 			wg.Add(1)
 			go func(cid string) {
 				defer wg.Done()
 				defer func() { <-sem }()
 
-				e.executeTask(ctx, job, cid, waitForResult)
+				e.executeTask(ctx, job, wave, cid, job.WaitForResult)
 			}(clientID)
 		}
 	}
 
 	wg.Wait()
+}
 
-done:
-	// 更新最终状态
+// finishJob 更新最终状态并安排清理
+func (e *BatchExecutor) finishJob(job *BatchJob) {
 	job.mu.Lock()
+	job.cancel = nil
 	job.endTime = time.Now()
+	if job.startTime.IsZero() {
+		job.startTime = job.endTime
+	}
 	if job.Status == BatchJobRunning {
 		if job.FailedCount == 0 {
 			job.Status = BatchJobCompleted
@@ -275,7 +450,13 @@ done:
 			job.Status = BatchJobCompleted // 部分成功也算完成
 		}
 	}
+	for _, w := range job.Waves {
+		if w.Status == WavePending {
+			w.Status = WaveCancelled
+		}
+	}
 	job.mu.Unlock()
+	e.saveJob(job)
 
 	duration := job.endTime.Sub(job.startTime)
 	e.logger.Info("Batch job completed",
@@ -301,7 +482,7 @@ done:
 }
 
 // executeTask 执行单个任务
-func (e *BatchExecutor) executeTask(ctx context.Context, job *BatchJob, clientID string, waitForResult bool) {
+func (e *BatchExecutor) executeTask(ctx context.Context, job *BatchJob, wave *Wave, clientID string, waitForResult bool) {
 	startTime := time.Now()
 	result := &TaskResult{
 		ClientID: clientID,
@@ -353,7 +534,7 @@ func (e *BatchExecutor) executeTask(ctx context.Context, job *BatchJob, clientID
 					result.Success = resp.AckMessage.Status == protocol.AckStatus_ACK_STATUS_SUCCESS
 					result.Result = resp.AckMessage.Result
 					if !result.Success && resp.AckMessage.Error != "" {
-						lastErr = fmt.Errorf(resp.AckMessage.Error)
+						lastErr = errors.New(resp.AckMessage.Error)
 						continue
 					}
 				}
@@ -374,6 +555,11 @@ func (e *BatchExecutor) executeTask(ctx context.Context, job *BatchJob, clientID
 	}
 
 failed:
+	// 执行器停止（服务关闭）时不记录结果，由下次启动时恢复或标记为中断
+	if e.ctx.Err() != nil {
+		return
+	}
+	result.Status = TaskFailed
 	result.Success = false
 	result.Error = lastErr.Error()
 	result.Duration = time.Since(startTime)
@@ -381,6 +567,8 @@ failed:
 	job.Errors.Store(clientID, lastErr)
 	atomic.AddInt64(&job.FailedCount, 1)
 	atomic.AddInt64(&job.PendingCount, -1)
+	atomic.AddInt64(&wave.FailedCount, 1)
+	e.saveResult(job, result)
 
 	if e.config.OnError != nil {
 		e.config.OnError(clientID, lastErr)
@@ -390,10 +578,13 @@ failed:
 	return
 
 success:
+	result.Status = TaskSucceeded
 	result.Duration = time.Since(startTime)
 	job.Results.Store(clientID, result)
 	atomic.AddInt64(&job.SuccessCount, 1)
 	atomic.AddInt64(&job.PendingCount, -1)
+	atomic.AddInt64(&wave.SuccessCount, 1)
+	e.saveResult(job, result)
 
 	e.reportProgress(job)
 }
@@ -421,40 +612,231 @@ func (e *BatchExecutor) reportProgress(job *BatchJob) {
 	}
 }
 
-// GetJob 获取任务状态
+// GetJob 获取任务状态（内存中不存在时从存储加载）
 func (e *BatchExecutor) GetJob(jobID string) (*BatchJob, bool) {
 	if val, ok := e.activeJobs.Load(jobID); ok {
 		return val.(*BatchJob), true
 	}
+	if e.store != nil {
+		if job, err := e.store.LoadJob(jobID); err == nil {
+			return job, true
+		}
+	}
 	return nil, false
 }
 
 // CancelJob 取消任务
+// 运行中的任务会中断当前批次，暂停、熔断或中断的任务直接结束
 func (e *BatchExecutor) CancelJob(jobID string) bool {
-	if val, ok := e.activeJobs.Load(jobID); ok {
-		job := val.(*BatchJob)
-		job.mu.Lock()
-		if job.Status == BatchJobPending || job.Status == BatchJobRunning {
-			job.Status = BatchJobCancelled
-			job.mu.Unlock()
-			e.logger.Info("Batch job cancelled", "job_id", jobID)
-			return true
-		}
+	val, ok := e.activeJobs.Load(jobID)
+	if !ok {
+		return false
+	}
+	job := val.(*BatchJob)
+
+	job.mu.Lock()
+	prev := job.Status
+	switch prev {
+	case BatchJobPending, BatchJobRunning, BatchJobPaused, BatchJobHalted, BatchJobInterrupted:
+	default:
 		job.mu.Unlock()
+		return false
+	}
+	job.Status = BatchJobCancelled
+	cancel := job.cancel
+	job.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
 	}
-	return false
+	e.logger.Info("Batch job cancelled", "job_id", jobID)
+
+	// 暂停、熔断或中断的任务没有执行协程，在此完成收尾
+	if prev == BatchJobPaused || prev == BatchJobHalted || prev == BatchJobInterrupted {
+		e.finishJob(job)
+	} else {
+		e.saveJob(job)
+	}
+	return true
 }
 
-// ListJobs 获取所有活跃任务
+// GetWaves 返回批次信息的副本
+func (j *BatchJob) GetWaves() []Wave {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	waves := make([]Wave, len(j.Waves))
+	for i, w := range j.Waves {
+		waves[i] = Wave{
+			Index:        w.Index,
+			ClientIDs:    w.ClientIDs,
+			Status:       w.Status,
+			SuccessCount: atomic.LoadInt64(&w.SuccessCount),
+			FailedCount:  atomic.LoadInt64(&w.FailedCount),
+			FailureRate:  w.failureRate(),
+			StartedAt:    w.StartedAt,
+			FinishedAt:   w.FinishedAt,
+		}
+	}
+	return waves
+}
+
+// ListJobs 获取所有活跃任务，配置存储时合并最近的历史任务（最新在前）
 func (e *BatchExecutor) ListJobs() []*BatchJob {
 	var jobs []*BatchJob
+	seen := make(map[string]bool)
 	e.activeJobs.Range(func(key, value interface{}) bool {
 		jobs = append(jobs, value.(*BatchJob))
+		seen[key.(string)] = true
 		return true
 	})
+	if e.store == nil {
+		return jobs
+	}
+
+	history, err := e.store.ListJobs(maxHistoryJobs)
+	if err != nil {
+		e.logger.Warn("Failed to list persisted batch jobs", "error", err)
+	}
+	for _, job := range history {
+		if !seen[job.ID] {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
 	return jobs
 }
 
+// Recover 加载服务重启前未结束的任务
+// resume 为 true 时继续执行运行中的任务（跳过已有结果的客户端），
+// 否则将其标记为中断，未返回结果的客户端记为 interrupted，可通过 ResumeJob 继续
+func (e *BatchExecutor) Recover(resume bool) (int, error) {
+	if e.store == nil {
+		return 0, nil
+	}
+	jobs, err := e.store.ListUnfinished()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, job := range jobs {
+		if _, exists := e.activeJobs.Load(job.ID); exists {
+			continue
+		}
+		e.activeJobs.Store(job.ID, job)
+		e.jobCount.Add(1)
+
+		switch job.Status {
+		case BatchJobPending, BatchJobRunning:
+			if resume {
+				job.Status = BatchJobPending
+				e.logger.Info("Resuming batch job after restart", "job_id", job.ID, "wave", job.CurrentWave)
+				go e.resumeAfterReconnect(job)
+				continue
+			}
+			e.interruptJob(job)
+			e.logger.Warn("Batch job interrupted by restart", "job_id", job.ID, "wave", job.CurrentWave)
+		default:
+			e.logger.Info("Batch job restored", "job_id", job.ID, "status", job.Status)
+		}
+	}
+	return len(jobs), nil
+}
+
+// resumeAfterReconnect 等待尚未返回结果的目标客户端重连（最长 resumeDelay）后继续执行任务
+// 服务器刚启动时 Agent 尚未重连，立即执行会使所有目标都因未连接而失败
+func (e *BatchExecutor) resumeAfterReconnect(job *BatchJob) {
+	pending := e.pendingClients(job)
+	deadline := time.Now().Add(e.resumeDelay)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for time.Now().Before(deadline) {
+		online := make(map[string]bool)
+		for _, cid := range e.sender.ListClients() {
+			online[cid] = true
+		}
+		waiting := 0
+		for _, cid := range pending {
+			if !online[cid] {
+				waiting++
+			}
+		}
+		if waiting == 0 {
+			break
+		}
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+	e.executeJob(job)
+}
+
+// pendingClients 返回当前及后续批次中尚未返回结果的客户端
+func (e *BatchExecutor) pendingClients(job *BatchJob) []string {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	var pending []string
+	for i := job.CurrentWave; i < len(job.Waves); i++ {
+		for _, cid := range job.Waves[i].ClientIDs {
+			if _, ok := job.Results.Load(cid); !ok {
+				pending = append(pending, cid)
+			}
+		}
+	}
+	return pending
+}
+
+// interruptJob 将重启前运行中的任务标记为中断，当前批次中未返回结果的客户端记为 interrupted
+func (e *BatchExecutor) interruptJob(job *BatchJob) {
+	var interrupted []*TaskResult
+	job.mu.Lock()
+	job.Status = BatchJobInterrupted
+	job.HaltReason = "server restarted"
+	if job.CurrentWave < len(job.Waves) {
+		wave := job.Waves[job.CurrentWave]
+		if wave.Status == WaveRunning {
+			wave.Status = WaveInterrupted
+			for _, cid := range wave.ClientIDs {
+				if _, ok := job.Results.Load(cid); ok {
+					continue
+				}
+				result := &TaskResult{ClientID: cid, Status: TaskInterrupted, Error: "server restarted before result was received"}
+				job.Results.Store(cid, result)
+				interrupted = append(interrupted, result)
+			}
+		}
+	}
+	job.mu.Unlock()
+
+	for _, result := range interrupted {
+		e.saveResult(job, result)
+	}
+	e.saveJob(job)
+}
+
+// saveJob 持久化任务定义与进度，失败仅记录日志
+func (e *BatchExecutor) saveJob(job *BatchJob) {
+	if e.store == nil {
+		return
+	}
+	job.saveMu.Lock()
+	defer job.saveMu.Unlock()
+	if err := e.store.SaveJob(job); err != nil {
+		e.logger.Warn("Failed to persist batch job", "job_id", job.ID, "error", err)
+	}
+}
+
+// saveResult 持久化单个客户端结果，失败仅记录日志
+func (e *BatchExecutor) saveResult(job *BatchJob, result *TaskResult) {
+	if e.store == nil {
+		return
+	}
+	if err := e.store.SaveResult(job.ID, result); err != nil {
+		e.logger.Warn("Failed to persist batch result", "job_id", job.ID, "client_id", result.ClientID, "error", err)
+	}
+}
+
 // GetStats 获取统计信息
 func (e *BatchExecutor) GetStats() *BatchStats {
 	stats := &BatchStats{
@@ -472,6 +854,8 @@ func (e *BatchExecutor) GetStats() *BatchStats {
 			stats.CompletedJobs++
 		case BatchJobFailed:
 			stats.FailedJobs++
+		case BatchJobPaused, BatchJobHalted, BatchJobInterrupted:
+			stats.PausedJobs++
 		}
 		stats.TotalTasks += job.TotalCount
 		stats.CompletedTasks += job.SuccessCount + job.FailedCount
@@ -488,6 +872,7 @@ type BatchStats struct {
 	RunningJobs    int64 `json:"running_jobs"`
 	CompletedJobs  int64 `json:"completed_jobs"`
 	FailedJobs     int64 `json:"failed_jobs"`
+	PausedJobs     int64 `json:"paused_jobs"` // 等待批准、已熔断或已中断
 	TotalTasks     int64 `json:"total_tasks"`
 	CompletedTasks int64 `json:"completed_tasks"`
 }
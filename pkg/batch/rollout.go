@@ -0,0 +1,114 @@
+package batch
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// RolloutPolicy 分批发布策略
+// 先向 FirstWave 个客户端下发，再按 Waves 中的累计百分比逐批扩大范围，
+// 最后一批总是覆盖剩余全部客户端
+type RolloutPolicy struct {
+	FirstWave         int       `json:"first_wave,omitempty"`          // 首批客户端数量（0 表示不单独设首批）
+	Waves             []float64 `json:"waves,omitempty"`               // 后续批次累计百分比（递增，0-100]，如 [10, 50, 100]
+	MaxFailureRate    float64   `json:"max_failure_rate,omitempty"`    // 单批失败率阈值（百分比），超过后熔断，0 表示不启用
+	PauseBetweenWaves bool      `json:"pause_between_waves,omitempty"` // 每批完成后暂停，等待人工批准
+}
+
+// Validate 校验分批策略
+func (p *RolloutPolicy) Validate() error {
+	if p.FirstWave < 0 {
+		return fmt.Errorf("first_wave must be >= 0")
+	}
+	prev := 0.0
+	for _, pct := range p.Waves {
+		if pct <= prev || pct > 100 {
+			return fmt.Errorf("waves must be increasing percentages in (0, 100]")
+		}
+		prev = pct
+	}
+	if p.MaxFailureRate < 0 || p.MaxFailureRate > 100 {
+		return fmt.Errorf("max_failure_rate must be between 0 and 100")
+	}
+	return nil
+}
+
+// WaveStatus 批次状态
+type WaveStatus string
+
+const (
+	WavePending     WaveStatus = "pending"
+	WaveRunning     WaveStatus = "running"
+	WaveCompleted   WaveStatus = "completed"
+	WaveHalted      WaveStatus = "halted" // 失败率超过阈值
+	WaveCancelled   WaveStatus = "cancelled"
+	WaveInterrupted WaveStatus = "interrupted" // 服务重启时未完成
+)
+
+// Wave 发布批次
+type Wave struct {
+	Index        int        `json:"index"`
+	ClientIDs    []string   `json:"client_ids"`
+	Status       WaveStatus `json:"status"`
+	SuccessCount int64      `json:"success_count"`
+	FailedCount  int64      `json:"failed_count"`
+	FailureRate  float64    `json:"failure_rate"` // 百分比
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
+
+// failureRate 计算批次失败率（百分比）
+func (w *Wave) failureRate() float64 {
+	done := atomic.LoadInt64(&w.SuccessCount) + atomic.LoadInt64(&w.FailedCount)
+	if done == 0 {
+		return 0
+	}
+	return float64(atomic.LoadInt64(&w.FailedCount)) / float64(done) * 100
+}
+
+// planWaves 按策略将目标客户端划分为批次，policy 为空时只有一个批次
+func planWaves(targets []string, policy *RolloutPolicy) []*Wave {
+	n := len(targets)
+	var bounds []int
+	if policy != nil {
+		if policy.FirstWave > 0 {
+			bounds = append(bounds, min(policy.FirstWave, n))
+		}
+		for _, pct := range policy.Waves {
+			b := min(int(math.Ceil(float64(n)*pct/100)), n)
+			if len(bounds) == 0 || b > bounds[len(bounds)-1] {
+				bounds = append(bounds, b)
+			}
+		}
+	}
+	if len(bounds) == 0 || bounds[len(bounds)-1] < n {
+		bounds = append(bounds, n)
+	}
+
+	waves := make([]*Wave, 0, len(bounds))
+	start := 0
+	for _, end := range bounds {
+		if end <= start {
+			continue
+		}
+		waves = append(waves, &Wave{
+			Index:     len(waves),
+			ClientIDs: targets[start:end],
+			Status:    WavePending,
+		})
+		start = end
+	}
+	return waves
+}
+
+// SplitWaves 按策略划分目标客户端，供其他分批执行场景（如 Agent 升级）复用
+func SplitWaves(targets []string, policy *RolloutPolicy) [][]string {
+	waves := planWaves(targets, policy)
+	groups := make([][]string, len(waves))
+	for i, w := range waves {
+		groups[i] = w.ClientIDs
+	}
+	return groups
+}
@@ -0,0 +1,161 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/callback"
+	"github.com/voilet/quic-flow/pkg/protocol"
+)
+
+// fakeSender 对 failing 中的客户端返回发送失败
+type fakeSender struct {
+	mu      sync.Mutex
+	sent    []string
+	failing map[string]bool
+	online  []string
+}
+
+func (f *fakeSender) SendTo(clientID string, msg *protocol.DataMessage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, clientID)
+	if f.failing[clientID] {
+		return errors.New("send failed")
+	}
+	return nil
+}
+
+func (f *fakeSender) SendToWithPromise(clientID string, msg *protocol.DataMessage, timeout time.Duration) (*callback.Promise, error) {
+	return nil, errors.New("not supported")
+}
+
+func (f *fakeSender) ListClients() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.online
+}
+
+func (f *fakeSender) setOnline(ids ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.online = ids
+}
+
+func (f *fakeSender) sentCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.sent)
+}
+
+func clients(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("c%02d", i)
+	}
+	return ids
+}
+
+func waitStatus(t *testing.T, job *BatchJob, status BatchJobStatus) {
+	require.Eventually(t, func() bool {
+		job.mu.RLock()
+		defer job.mu.RUnlock()
+		return job.Status == status
+	}, 2*time.Second, 5*time.Millisecond)
+}
+
+func newTestExecutor(sender MessageSender) *BatchExecutor {
+	return NewBatchExecutor(sender, &BatchConfig{MaxRetries: 0, RetryInterval: time.Millisecond})
+}
+
+func TestPlanWaves(t *testing.T) {
+	sizes := func(waves []*Wave) []int {
+		var out []int
+		for _, w := range waves {
+			out = append(out, len(w.ClientIDs))
+		}
+		return out
+	}
+
+	assert.Equal(t, []int{10}, sizes(planWaves(clients(10), nil)))
+	assert.Equal(t, []int{1, 4, 5}, sizes(planWaves(clients(10), &RolloutPolicy{FirstWave: 1, Waves: []float64{50}})))
+	assert.Equal(t, []int{2, 1, 7}, sizes(planWaves(clients(10), &RolloutPolicy{FirstWave: 2, Waves: []float64{10, 30, 100}})))
+	assert.Equal(t, []int{3}, sizes(planWaves(clients(3), &RolloutPolicy{FirstWave: 5, Waves: []float64{50}})))
+
+	assert.Error(t, (&RolloutPolicy{Waves: []float64{50, 20}}).Validate())
+	assert.Error(t, (&RolloutPolicy{Waves: []float64{120}}).Validate())
+	assert.Error(t, (&RolloutPolicy{MaxFailureRate: -1}).Validate())
+}
+
+func TestRolloutHaltsOnFailureRate(t *testing.T) {
+	sender := &fakeSender{failing: map[string]bool{"c00": true}}
+	e := newTestExecutor(sender)
+	defer e.Stop()
+
+	job, err := e.ExecuteWithRollout("exec_shell", nil, clients(10), false, &RolloutPolicy{
+		FirstWave:      1,
+		Waves:          []float64{50},
+		MaxFailureRate: 20,
+	})
+	require.NoError(t, err)
+	waitStatus(t, job, BatchJobHalted)
+
+	waves := job.GetWaves()
+	require.Len(t, waves, 3)
+	assert.Equal(t, WaveHalted, waves[0].Status)
+	assert.Equal(t, float64(100), waves[0].FailureRate)
+	assert.Equal(t, WavePending, waves[1].Status)
+	assert.Equal(t, 1, sender.sentCount())
+	assert.Contains(t, job.HaltReason, "wave 0")
+
+	// 恢复后继续执行剩余批次
+	require.NoError(t, e.ResumeJob(job.ID))
+	waitStatus(t, job, BatchJobCompleted)
+	assert.Equal(t, 10, sender.sentCount())
+	assert.Equal(t, int64(9), job.SuccessCount)
+	assert.Equal(t, int64(1), job.FailedCount)
+	assert.Error(t, e.ResumeJob(job.ID))
+}
+
+func TestExecuteSyncReturnsWhenHalted(t *testing.T) {
+	e := newTestExecutor(&fakeSender{})
+	defer e.Stop()
+
+	// fakeSender 不支持等待结果，首批失败后熔断，同步等待立即返回而不是等到超时
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	job, err := e.ExecuteSyncWithRollout(ctx, "exec_shell", nil, clients(4), &RolloutPolicy{FirstWave: 1, MaxFailureRate: 20})
+	require.NoError(t, err)
+	assert.Equal(t, BatchJobHalted, job.Status)
+	assert.Contains(t, job.HaltReason, "wave 0")
+	assert.NoError(t, ctx.Err())
+}
+
+func TestRolloutPauseBetweenWaves(t *testing.T) {
+	sender := &fakeSender{}
+	e := newTestExecutor(sender)
+	defer e.Stop()
+
+	job, err := e.ExecuteWithRollout("exec_shell", nil, clients(4), false, &RolloutPolicy{
+		FirstWave:         1,
+		PauseBetweenWaves: true,
+	})
+	require.NoError(t, err)
+	waitStatus(t, job, BatchJobPaused)
+	assert.Equal(t, 1, job.CurrentWave)
+	assert.Equal(t, 1, sender.sentCount())
+
+	// 暂停中的任务可以直接取消
+	require.True(t, e.CancelJob(job.ID))
+	waitStatus(t, job, BatchJobCancelled)
+	waves := job.GetWaves()
+	assert.Equal(t, WaveCompleted, waves[0].Status)
+	assert.Equal(t, WaveCancelled, waves[1].Status)
+	assert.Equal(t, 1, sender.sentCount())
+}
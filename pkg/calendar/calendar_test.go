@@ -0,0 +1,136 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	require.NoError(t, err)
+	return loc
+}
+
+func TestWindow_Validate(t *testing.T) {
+	start := time.Now()
+	end := start.Add(time.Hour)
+	for name, w := range map[string]*Window{
+		"missing name":     {Type: WindowRecurring},
+		"missing scope_id": {Name: "a", Scope: ScopeGroup, Type: WindowRecurring},
+		"invalid scope":    {Name: "a", Scope: "project", Type: WindowRecurring},
+		"invalid kind":     {Name: "a", AppliesTo: []Kind{"deploys"}, Type: WindowRecurring},
+		"invalid type":     {Name: "a"},
+		"once without end": {Name: "a", Type: WindowOnce, StartAt: &start},
+		"once reversed":    {Name: "a", Type: WindowOnce, StartAt: &end, EndAt: &start},
+		"invalid day":      {Name: "a", Type: WindowRecurring, Days: []int{0}},
+		"invalid date":     {Name: "a", Type: WindowRecurring, Dates: []string{"13-01"}},
+		"invalid time":     {Name: "a", Type: WindowRecurring, StartTime: "9:00"},
+		"start at 24:00":   {Name: "a", Type: WindowRecurring, StartTime: "24:00"},
+		"invalid timezone": {Name: "a", Type: WindowRecurring, Timezone: "Mars/Olympus"},
+	} {
+		assert.Error(t, w.Validate(), name)
+	}
+
+	w := &Window{Name: "a", Type: WindowRecurring, ScopeID: "ignored"}
+	require.NoError(t, w.Validate())
+	assert.Equal(t, ScopeGlobal, w.Scope)
+	assert.Empty(t, w.ScopeID)
+}
+
+func TestWindow_Contains(t *testing.T) {
+	loc := mustLoad(t, "Asia/Shanghai")
+	at := func(day, hour, minute int) time.Time {
+		// 2026-11-02 为周一
+		return time.Date(2026, 11, day, hour, minute, 0, 0, loc)
+	}
+
+	// 周五 18:00 到周一 08:00 的周末封网（每天的区间跨越午夜）
+	weekend := &Window{Name: "weekend", Type: WindowRecurring, Timezone: "Asia/Shanghai",
+		Days: []int{5, 6, 7}, StartTime: "18:00", EndTime: "08:00"}
+	require.NoError(t, weekend.Validate())
+	assert.False(t, weekend.Contains(at(6, 17, 59)))
+	assert.True(t, weekend.Contains(at(6, 18, 0)))
+	assert.True(t, weekend.Contains(at(7, 3, 0)))
+	assert.True(t, weekend.Contains(at(9, 7, 59)), "Sunday night runs into Monday morning")
+	assert.False(t, weekend.Contains(at(9, 8, 0)))
+	assert.False(t, weekend.Contains(at(7, 12, 0)), "Saturday 08:00-18:00 is open")
+	assert.True(t, weekend.Contains(at(6, 10, 0).UTC().Add(8*time.Hour)), "time zone of the argument does not matter")
+
+	// 每年双十一全天，以及指定日期
+	sale := &Window{Name: "sale", Type: WindowRecurring, Timezone: "Asia/Shanghai", Dates: []string{"11-11", "2026-11-20"}}
+	require.NoError(t, sale.Validate())
+	assert.True(t, sale.Contains(at(11, 0, 0)))
+	assert.True(t, sale.Contains(at(11, 23, 59)))
+	assert.False(t, sale.Contains(at(12, 0, 0)))
+	assert.True(t, sale.Contains(at(20, 12, 0)))
+	assert.True(t, sale.Contains(time.Date(2030, 11, 11, 12, 0, 0, 0, loc)))
+	assert.False(t, sale.Contains(time.Date(2027, 11, 20, 12, 0, 0, 0, loc)))
+
+	start, end := at(3, 22, 0), at(4, 2, 0)
+	once := &Window{Name: "migration", Type: WindowOnce, StartAt: &start, EndAt: &end}
+	require.NoError(t, once.Validate())
+	assert.True(t, once.Contains(start))
+	assert.True(t, once.Contains(at(4, 1, 59)))
+	assert.False(t, once.Contains(end))
+
+	occs := weekend.Occurrences(at(2, 0, 0), at(9, 0, 0))
+	require.Len(t, occs, 4)
+	assert.Equal(t, at(1, 18, 0), occs[0].Start, "Sunday's window reaches into Monday")
+	assert.Equal(t, at(6, 18, 0), occs[1].Start)
+	assert.Equal(t, at(7, 8, 0), occs[1].End)
+	assert.Equal(t, at(8, 18, 0), occs[3].Start)
+}
+
+func TestStore_Active(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewStore(dir)
+	require.NoError(t, err)
+
+	now := time.Now()
+	start, end := now.Add(-time.Hour), now.Add(time.Hour)
+	create := func(w *Window) *Window {
+		w.Type = WindowOnce
+		w.StartAt, w.EndAt = &start, &end
+		created, err := s.Create(w)
+		require.NoError(t, err)
+		return created
+	}
+	global := create(&Window{Name: "freeze", AppliesTo: []Kind{KindReleases}})
+	group := create(&Window{Name: "db maintenance", Scope: ScopeGroup, ScopeID: "3"})
+	create(&Window{Name: "prod freeze", Scope: ScopeEnvironment, ScopeID: "env-prod"})
+	assert.True(t, global.Enabled)
+
+	assert.Equal(t, []string{"freeze", "prod freeze"}, Names(s.Active(KindReleases, Target{EnvironmentID: "env-prod"}, now)))
+	assert.Equal(t, []string{"freeze"}, Names(s.Active(KindReleases, Target{EnvironmentID: "env-test"}, now)))
+	assert.Empty(t, s.Active(KindTasks, Target{GroupIDs: []string{"1", "2"}}, now))
+	assert.Equal(t, []string{"db maintenance"}, Names(s.Active(KindTasks, Target{GroupIDs: []string{"1", "3"}}, now)))
+	assert.Empty(t, s.Active(KindTasks, Target{GroupIDs: []string{"3"}}, end))
+
+	_, err = s.SetEnabled(group.ID, false)
+	require.NoError(t, err)
+	assert.Empty(t, s.Active(KindTasks, Target{GroupIDs: []string{"3"}}, now))
+	assert.Len(t, s.Occurrences("", start, end), 2)
+	assert.Len(t, s.Occurrences(KindTasks, start, end), 1)
+
+	// 修改保留启用状态，重新加载后保持
+	updated, err := s.Update(group.ID, &Window{Name: "db upgrade", Scope: ScopeGroup, ScopeID: "3", Type: WindowRecurring})
+	require.NoError(t, err)
+	assert.False(t, updated.Enabled)
+	assert.Equal(t, group.CreatedAt, updated.CreatedAt)
+	_, err = s.Update("missing", &Window{Name: "x", Type: WindowRecurring})
+	assert.ErrorIs(t, err, ErrWindowNotFound)
+
+	reloaded, err := NewStore(dir)
+	require.NoError(t, err)
+	assert.Len(t, reloaded.List(), 3)
+	w, err := reloaded.Get(group.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "db upgrade", w.Name)
+	assert.False(t, w.Enabled)
+
+	require.NoError(t, reloaded.Delete(group.ID))
+	assert.ErrorIs(t, reloaded.Delete(group.ID), ErrWindowNotFound)
+}
@@ -0,0 +1,185 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/voilet/quic-flow/pkg/jsonfile"
+)
+
+// ErrWindowNotFound 禁止窗口不存在
+var ErrWindowNotFound = errors.New("window not found")
+
+// Store 禁止窗口存储（JSON 文件）
+type Store struct {
+	file    *jsonfile.File
+	windows map[string]*Window
+}
+
+// storeFile 持久化文件格式
+type storeFile struct {
+	Windows []*Window `json:"windows"`
+}
+
+// NewStore 创建存储，dir 为空时仅保存在内存中
+func NewStore(dir string) (*Store, error) {
+	var f storeFile
+	file, err := jsonfile.Open(dir, "calendar.json", &f)
+	if err != nil {
+		return nil, fmt.Errorf("open calendar store: %w", err)
+	}
+	s := &Store{
+		file:    file,
+		windows: make(map[string]*Window),
+	}
+	for _, w := range f.Windows {
+		s.windows[w.ID] = w
+	}
+	return s, nil
+}
+
+// List 列出所有窗口（按名称排序）
+func (s *Store) List() []*Window {
+	s.file.RLock()
+	defer s.file.RUnlock()
+	list := make([]*Window, 0, len(s.windows))
+	for _, w := range s.windows {
+		cp := *w
+		list = append(list, &cp)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get 获取窗口
+func (s *Store) Get(id string) (*Window, error) {
+	s.file.RLock()
+	defer s.file.RUnlock()
+	w, ok := s.windows[id]
+	if !ok {
+		return nil, ErrWindowNotFound
+	}
+	cp := *w
+	return &cp, nil
+}
+
+// Create 创建窗口（创建后即启用）
+func (s *Store) Create(w *Window) (*Window, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.file.Lock()
+	defer s.file.Unlock()
+	now := time.Now()
+	w.ID = uuid.New().String()
+	w.Enabled = true
+	w.CreatedAt = now
+	w.UpdatedAt = now
+	s.windows[w.ID] = w
+	if err := s.persist(); err != nil {
+		delete(s.windows, w.ID)
+		return nil, err
+	}
+	cp := *w
+	return &cp, nil
+}
+
+// Update 修改窗口定义，保留启用状态与创建信息
+func (s *Store) Update(id string, w *Window) (*Window, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.file.Lock()
+	defer s.file.Unlock()
+	old, ok := s.windows[id]
+	if !ok {
+		return nil, ErrWindowNotFound
+	}
+	w.ID = id
+	w.Enabled = old.Enabled
+	w.CreatedBy = old.CreatedBy
+	w.CreatedAt = old.CreatedAt
+	w.UpdatedAt = time.Now()
+	s.windows[id] = w
+	if err := s.persist(); err != nil {
+		s.windows[id] = old
+		return nil, err
+	}
+	cp := *w
+	return &cp, nil
+}
+
+// SetEnabled 启用或停用窗口
+func (s *Store) SetEnabled(id string, enabled bool) (*Window, error) {
+	s.file.Lock()
+	defer s.file.Unlock()
+	w, ok := s.windows[id]
+	if !ok {
+		return nil, ErrWindowNotFound
+	}
+	w.Enabled = enabled
+	w.UpdatedAt = time.Now()
+	if err := s.persist(); err != nil {
+		return nil, err
+	}
+	cp := *w
+	return &cp, nil
+}
+
+// Delete 删除窗口
+func (s *Store) Delete(id string) error {
+	s.file.Lock()
+	defer s.file.Unlock()
+	if _, ok := s.windows[id]; !ok {
+		return ErrWindowNotFound
+	}
+	delete(s.windows, id)
+	return s.persist()
+}
+
+// Active 返回时间 at 生效、限制该操作且作用于 target 的已启用窗口
+func (s *Store) Active(kind Kind, target Target, at time.Time) []*Window {
+	var active []*Window
+	for _, w := range s.List() {
+		if w.Enabled && w.Applies(kind) && w.Matches(target) && w.Contains(at) {
+			active = append(active, w)
+		}
+	}
+	return active
+}
+
+// Occurrences 返回 [from, to] 内已启用窗口的生效区间（按开始时间排序），kind 为空时不按操作筛选
+func (s *Store) Occurrences(kind Kind, from, to time.Time) []*Occurrence {
+	var list []*Occurrence
+	for _, w := range s.List() {
+		if !w.Enabled || (kind != "" && !w.Applies(kind)) {
+			continue
+		}
+		list = append(list, w.Occurrences(from, to)...)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Start.Before(list[j].Start) })
+	return list
+}
+
+// persist 写入存储文件（调用方持有锁）
+func (s *Store) persist() error {
+	f := storeFile{Windows: make([]*Window, 0, len(s.windows))}
+	for _, w := range s.windows {
+		f.Windows = append(f.Windows, w)
+	}
+	return s.file.Save(f)
+}
+
+// Names 窗口名称列表（用于日志与错误信息）
+func Names(windows []*Window) []string {
+	names := make([]string, 0, len(windows))
+	for _, w := range windows {
+		names = append(names, w.Name)
+	}
+	return names
+}
@@ -0,0 +1,280 @@
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Scope 禁止窗口的作用范围
+type Scope string
+
+const (
+	ScopeGlobal      Scope = "global"      // 所有任务与发布
+	ScopeGroup       Scope = "group"       // 关联了该任务分组的任务（scope_id 为分组 ID）
+	ScopeEnvironment Scope = "environment" // 该发布环境（scope_id 为环境 ID）
+)
+
+// Kind 受禁止窗口限制的操作
+type Kind string
+
+const (
+	KindTasks    Kind = "tasks"    // 定时任务的触发
+	KindReleases Kind = "releases" // 开始发布
+)
+
+// WindowType 禁止窗口类型
+type WindowType string
+
+const (
+	WindowOnce      WindowType = "once"      // 一次性：start_at 到 end_at
+	WindowRecurring WindowType = "recurring" // 周期性：匹配的日期内 start_time 到 end_time
+)
+
+// Window 禁止窗口（如节假日、大促期间的封网）
+// 周期性窗口在 days 或 dates 匹配的日期（两者都为空时为每天）生效，end_time 不晚于 start_time 时结束于次日
+type Window struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Scope       Scope      `json:"scope"`
+	ScopeID     string     `json:"scope_id,omitempty"`
+	AppliesTo   []Kind     `json:"applies_to,omitempty"` // 为空时同时限制任务与发布
+	Type        WindowType `json:"type"`
+	Timezone    string     `json:"timezone,omitempty"` // 周期性窗口的时区，默认服务器本地时区
+
+	// 一次性窗口
+	StartAt *time.Time `json:"start_at,omitempty"`
+	EndAt   *time.Time `json:"end_at,omitempty"`
+
+	// 周期性窗口
+	Days      []int    `json:"days,omitempty"`       // 1-7 (Monday-Sunday)
+	Dates     []string `json:"dates,omitempty"`      // MM-DD（每年）或 YYYY-MM-DD
+	StartTime string   `json:"start_time,omitempty"` // HH:MM，默认 00:00
+	EndTime   string   `json:"end_time,omitempty"`   // HH:MM，默认 24:00
+
+	Enabled   bool      `json:"enabled"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Occurrence 禁止窗口的一次生效区间
+type Occurrence struct {
+	WindowID string    `json:"window_id"`
+	Name     string    `json:"name"`
+	Scope    Scope     `json:"scope"`
+	ScopeID  string    `json:"scope_id,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+}
+
+// Target 待检查的操作对象：全局窗口之外，只检查作用于这些分组或环境的窗口
+type Target struct {
+	GroupIDs      []string
+	EnvironmentID string
+}
+
+// Validate 校验窗口并填充默认值
+func (w *Window) Validate() error {
+	if w.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch w.Scope {
+	case "":
+		w.Scope = ScopeGlobal
+		fallthrough
+	case ScopeGlobal:
+		w.ScopeID = ""
+	case ScopeGroup, ScopeEnvironment:
+		if w.ScopeID == "" {
+			return fmt.Errorf("scope_id is required for %s scope", w.Scope)
+		}
+	default:
+		return fmt.Errorf("invalid scope: %s", w.Scope)
+	}
+	for _, kind := range w.AppliesTo {
+		if kind != KindTasks && kind != KindReleases {
+			return fmt.Errorf("invalid applies_to: %s", kind)
+		}
+	}
+	if _, err := w.location(); err != nil {
+		return fmt.Errorf("invalid timezone: %w", err)
+	}
+
+	switch w.Type {
+	case WindowOnce:
+		if w.StartAt == nil || w.EndAt == nil {
+			return fmt.Errorf("start_at and end_at are required for once window")
+		}
+		if !w.EndAt.After(*w.StartAt) {
+			return fmt.Errorf("end_at must be after start_at")
+		}
+	case WindowRecurring:
+		for _, day := range w.Days {
+			if day < 1 || day > 7 {
+				return fmt.Errorf("invalid day: %d (1-7, Monday-Sunday)", day)
+			}
+		}
+		for _, date := range w.Dates {
+			if _, _, err := parseDate(date); err != nil {
+				return err
+			}
+		}
+		start, err := parseClock(w.StartTime, 0)
+		if err != nil || start == 24*time.Hour {
+			return fmt.Errorf("invalid start_time: %q", w.StartTime)
+		}
+		if _, err := parseClock(w.EndTime, 24*time.Hour); err != nil {
+			return fmt.Errorf("invalid end_time: %w", err)
+		}
+	default:
+		return fmt.Errorf("invalid type: %s", w.Type)
+	}
+	return nil
+}
+
+// Applies 窗口是否限制该操作
+func (w *Window) Applies(kind Kind) bool {
+	if len(w.AppliesTo) == 0 {
+		return true
+	}
+	for _, k := range w.AppliesTo {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches 窗口是否作用于该对象
+func (w *Window) Matches(target Target) bool {
+	switch w.Scope {
+	case ScopeGlobal:
+		return true
+	case ScopeGroup:
+		for _, id := range target.GroupIDs {
+			if id == w.ScopeID {
+				return true
+			}
+		}
+	case ScopeEnvironment:
+		return target.EnvironmentID != "" && target.EnvironmentID == w.ScopeID
+	}
+	return false
+}
+
+// Contains 时间 t 是否在窗口内（不考虑启用状态）
+func (w *Window) Contains(t time.Time) bool {
+	for _, occ := range w.Occurrences(t, t) {
+		if !t.Before(occ.Start) && t.Before(occ.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// Occurrences 返回与 [from, to] 有交集的生效区间，按开始时间排序
+func (w *Window) Occurrences(from, to time.Time) []*Occurrence {
+	var list []*Occurrence
+	add := func(start, end time.Time) {
+		if end.After(from) && !start.After(to) {
+			list = append(list, &Occurrence{WindowID: w.ID, Name: w.Name, Scope: w.Scope, ScopeID: w.ScopeID, Start: start, End: end})
+		}
+	}
+
+	if w.Type == WindowOnce {
+		if w.StartAt != nil && w.EndAt != nil {
+			add(*w.StartAt, *w.EndAt)
+		}
+		return list
+	}
+
+	loc, err := w.location()
+	if err != nil {
+		return nil
+	}
+	start, err := parseClock(w.StartTime, 0)
+	if err != nil {
+		return nil
+	}
+	end, err := parseClock(w.EndTime, 24*time.Hour)
+	if err != nil {
+		return nil
+	}
+	if end <= start {
+		end += 24 * time.Hour
+	}
+
+	// 前一天开始的区间可能跨越午夜
+	first := from.In(loc).AddDate(0, 0, -1)
+	day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc)
+	for ; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if w.matchesDay(day) {
+			add(day.Add(start), day.Add(end))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Start.Before(list[j].Start) })
+	return list
+}
+
+// matchesDay 周期性窗口是否在该日期生效
+func (w *Window) matchesDay(day time.Time) bool {
+	if len(w.Days) == 0 && len(w.Dates) == 0 {
+		return true
+	}
+	weekday := int(day.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	for _, d := range w.Days {
+		if d == weekday {
+			return true
+		}
+	}
+	for _, date := range w.Dates {
+		year, yearDay, err := parseDate(date)
+		if err != nil {
+			continue
+		}
+		if yearDay == day.Format("01-02") && (year == 0 || year == day.Year()) {
+			return true
+		}
+	}
+	return false
+}
+
+// location 窗口时区
+func (w *Window) location() (*time.Location, error) {
+	if w.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(w.Timezone)
+}
+
+// parseClock 解析 HH:MM（允许 24:00），为空时返回 def
+func parseClock(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	var hour, minute int
+	if n, err := fmt.Sscanf(s, "%d:%d", &hour, &minute); err != nil || n != 2 || len(s) != 5 {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("%q is out of range", s)
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
+
+// parseDate 解析 MM-DD 或 YYYY-MM-DD，返回年份（每年时为 0）与 MM-DD
+func parseDate(s string) (int, string, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t.Year(), t.Format("01-02"), nil
+	}
+	// 使用闰年解析，允许 02-29
+	if t, err := time.Parse("2006-01-02", "2000-"+s); err == nil && len(s) == 5 {
+		return 0, t.Format("01-02"), nil
+	}
+	return 0, "", fmt.Errorf("invalid date: %q (MM-DD or YYYY-MM-DD)", s)
+}
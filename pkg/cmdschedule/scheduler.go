@@ -0,0 +1,356 @@
+package cmdschedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/voilet/quic-flow/pkg/batch"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+)
+
+// tickInterval 调度检查间隔
+const tickInterval = time.Second
+
+// CommandSender 命令下发接口（由 command.CommandManager 实现）
+type CommandSender interface {
+	SendCommandToMultiple(clientIDs []string, commandType string, payload json.RawMessage, timeout time.Duration) *command.MultiCommandResponse
+}
+
+// BatchRunner 批量任务接口（由 batch.BatchExecutor 实现）
+type BatchRunner interface {
+	ExecuteWithRollout(command string, payload json.RawMessage, targetClients []string, waitForResult bool, rollout *batch.RolloutPolicy) (*batch.BatchJob, error)
+}
+
+// Scheduler 定时/周期命令调度器
+type Scheduler struct {
+	store  *Store
+	sender CommandSender
+	batch  BatchRunner
+	logger *monitoring.Logger
+
+	mu      sync.Mutex
+	running map[string]bool // scheduleID -> 是否有执行中的记录
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScheduler 创建调度器
+func NewScheduler(store *Store, sender CommandSender, logger *monitoring.Logger) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		store:   store,
+		sender:  sender,
+		logger:  logger,
+		running: make(map[string]bool),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// SetBatchExecutor 设置批量执行器（kind 为 batch 的计划需要）
+func (s *Scheduler) SetBatchExecutor(executor *batch.BatchExecutor) {
+	if executor != nil {
+		s.batch = executor
+	}
+}
+
+// Start 启动调度循环
+// 服务重启前仍在执行的记录标记为 interrupted，错过的执行按各计划的 misfire_policy 处理
+func (s *Scheduler) Start() {
+	for _, sc := range s.store.List() {
+		for _, occ := range s.store.ListOccurrences(sc.ID) {
+			if occ.Status != OccurrenceRunning {
+				continue
+			}
+			now := time.Now()
+			occ.Status = OccurrenceInterrupted
+			occ.FinishedAt = &now
+			occ.Message = "server restarted while running"
+			if err := s.store.SaveOccurrence(occ); err != nil {
+				s.logger.Warn("Failed to save occurrence", "occurrence_id", occ.ID, "error", err)
+			}
+		}
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(tickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.tick(now)
+			}
+		}
+	}()
+	s.logger.Info("Command scheduler started", "schedules", len(s.store.List()))
+}
+
+// Stop 停止调度并等待执行中的命令结束
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Create 创建计划（创建后立即启用）
+func (s *Scheduler) Create(sc *Schedule) (*Schedule, error) {
+	if err := sc.normalize(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if sc.RunAt != nil && sc.RunAt.Before(now) {
+		return nil, fmt.Errorf("run_at must be in the future")
+	}
+	if sc.Kind == KindBatch && s.batch == nil {
+		return nil, fmt.Errorf("batch executor not available")
+	}
+
+	sc.ID = uuid.New().String()
+	sc.Enabled = true
+	sc.LastRunAt = nil
+	sc.NextRunAt = sc.next(now)
+	sc.CreatedAt = now
+	sc.UpdatedAt = now
+	if err := s.store.Save(sc); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Schedule created", "schedule_id", sc.ID, "kind", sc.Kind, "command_type", sc.CommandType, "next_run_at", sc.NextRunAt)
+	return s.store.Get(sc.ID)
+}
+
+// Update 修改计划定义，保留启用状态与执行历史
+func (s *Scheduler) Update(id string, sc *Schedule) (*Schedule, error) {
+	old, err := s.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := sc.normalize(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if old.Enabled && sc.RunAt != nil && sc.RunAt.Before(now) {
+		return nil, fmt.Errorf("run_at must be in the future")
+	}
+	if sc.Kind == KindBatch && s.batch == nil {
+		return nil, fmt.Errorf("batch executor not available")
+	}
+
+	sc.ID = old.ID
+	sc.Enabled = old.Enabled
+	sc.LastRunAt = old.LastRunAt
+	sc.CreatedAt = old.CreatedAt
+	sc.UpdatedAt = now
+	sc.NextRunAt = nil
+	if sc.Enabled {
+		sc.NextRunAt = sc.next(now)
+	}
+	if err := s.store.Save(sc); err != nil {
+		return nil, err
+	}
+	return s.store.Get(id)
+}
+
+// SetEnabled 启用或停用计划，启用时从当前时间重新计算下次执行时间（不补执行停用期间的计划）
+func (s *Scheduler) SetEnabled(id string, enabled bool) (*Schedule, error) {
+	sc, err := s.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	sc.NextRunAt = nil
+	if enabled {
+		if sc.NextRunAt = sc.next(now); sc.NextRunAt == nil {
+			return nil, fmt.Errorf("run_at is in the past")
+		}
+	}
+	sc.Enabled = enabled
+	sc.UpdatedAt = now
+	if err := s.store.Save(sc); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// Delete 删除计划
+func (s *Scheduler) Delete(id string) error {
+	return s.store.Delete(id)
+}
+
+// List 列出所有计划
+func (s *Scheduler) List() []*Schedule {
+	return s.store.List()
+}
+
+// Get 获取计划
+func (s *Scheduler) Get(id string) (*Schedule, error) {
+	return s.store.Get(id)
+}
+
+// ListOccurrences 列出计划的执行记录（最新在前）
+func (s *Scheduler) ListOccurrences(scheduleID string) ([]*Occurrence, error) {
+	if _, err := s.store.Get(scheduleID); err != nil {
+		return nil, err
+	}
+	return s.store.ListOccurrences(scheduleID), nil
+}
+
+// GetOccurrence 获取执行记录及其结果集
+func (s *Scheduler) GetOccurrence(id string) (*Occurrence, []*command.ClientCommandResult, error) {
+	return s.store.GetOccurrence(id)
+}
+
+// tick 检查到期的计划
+func (s *Scheduler) tick(now time.Time) {
+	for _, sc := range s.store.List() {
+		if !sc.Enabled || sc.NextRunAt == nil || sc.NextRunAt.After(now) {
+			continue
+		}
+		s.fire(sc, now)
+	}
+}
+
+// fire 处理一次到期的计划：判断错过与重叠，然后执行并推进下次执行时间
+func (s *Scheduler) fire(sc *Schedule, now time.Time) {
+	due := *sc.NextRunAt
+	occ := &Occurrence{
+		ID:          uuid.New().String(),
+		ScheduleID:  sc.ID,
+		ScheduledAt: due,
+		Status:      OccurrenceRunning,
+	}
+
+	run := true
+	if now.Sub(due) > sc.grace() {
+		occ.MissedRuns = sc.missedRuns(due, now)
+		if sc.MisfirePolicy == MisfireCatchUp {
+			occ.CatchUp = true
+		} else {
+			run = false
+			occ.Status = OccurrenceSkipped
+			occ.Message = fmt.Sprintf("missed %d run(s), misfire policy is skip", occ.MissedRuns)
+		}
+	}
+
+	s.mu.Lock()
+	if run && s.running[sc.ID] {
+		run = false
+		occ.Status = OccurrenceSkipped
+		occ.Message = "previous occurrence is still running"
+	}
+	if run {
+		s.running[sc.ID] = true
+	}
+	s.mu.Unlock()
+
+	// 在存储锁内只推进执行字段，避免覆盖并发的修改、启停，或恢复已删除的计划
+	advanced, err := s.store.AdvanceRun(sc.ID, due, now, run)
+	if advanced == nil {
+		if run {
+			s.mu.Lock()
+			delete(s.running, sc.ID)
+			s.mu.Unlock()
+		}
+		s.logger.Debug("Schedule changed before it fired, skipped", "schedule_id", sc.ID, "reason", err)
+		return
+	}
+	if err != nil {
+		s.logger.Warn("Failed to save schedule", "schedule_id", sc.ID, "error", err)
+	}
+	sc = advanced
+
+	if !run {
+		occ.FinishedAt = &now
+		s.saveOccurrence(occ)
+		s.logger.Warn("Scheduled run skipped", "schedule_id", sc.ID, "reason", occ.Message)
+		return
+	}
+
+	occ.StartedAt = &now
+	s.saveOccurrence(occ)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, sc.ID)
+			s.mu.Unlock()
+		}()
+		s.execute(sc, occ)
+	}()
+}
+
+// execute 执行一次计划并记录结果
+func (s *Scheduler) execute(sc *Schedule, occ *Occurrence) {
+	switch sc.Kind {
+	case KindBatch:
+		s.executeBatch(sc, occ)
+	default:
+		s.executeMulti(sc, occ)
+	}
+	finished := time.Now()
+	occ.FinishedAt = &finished
+	s.saveOccurrence(occ)
+	s.logger.Info("Scheduled run finished",
+		"schedule_id", sc.ID,
+		"occurrence_id", occ.ID,
+		"status", occ.Status,
+		"success", occ.SuccessCount,
+		"failed", occ.FailedCount)
+}
+
+// executeMulti 通过 CommandManager 多播，结果集单独保存
+func (s *Scheduler) executeMulti(sc *Schedule, occ *Occurrence) {
+	resp := s.sender.SendCommandToMultiple(sc.ClientIDs, sc.CommandType, sc.Payload, sc.timeout())
+	occ.TaskID = resp.TaskID
+	occ.Total = len(sc.ClientIDs)
+	occ.Message = resp.Message
+	for _, r := range resp.Results {
+		if r.Status == command.CommandStatusCompleted {
+			occ.SuccessCount++
+		} else {
+			occ.FailedCount++
+		}
+	}
+	if err := s.store.SaveResults(occ.ID, resp.Results); err != nil {
+		s.logger.Warn("Failed to save occurrence results", "occurrence_id", occ.ID, "error", err)
+	}
+	occ.Status = OccurrenceSuccess
+	if occ.FailedCount > 0 || occ.SuccessCount < occ.Total {
+		occ.Status = OccurrenceFailed
+	}
+}
+
+// executeBatch 创建批量任务，结果集即批量任务结果
+func (s *Scheduler) executeBatch(sc *Schedule, occ *Occurrence) {
+	if s.batch == nil {
+		occ.Status = OccurrenceFailed
+		occ.Message = "batch executor not available"
+		return
+	}
+	job, err := s.batch.ExecuteWithRollout(sc.CommandType, sc.Payload, sc.ClientIDs, sc.WaitForResult, sc.Rollout)
+	if err != nil {
+		occ.Status = OccurrenceFailed
+		occ.Message = err.Error()
+		return
+	}
+	occ.BatchJobID = job.ID
+	occ.Total = int(job.TotalCount)
+	occ.Status = OccurrenceDispatched
+}
+
+// saveOccurrence 保存执行记录，失败仅记录日志
+func (s *Scheduler) saveOccurrence(occ *Occurrence) {
+	if err := s.store.SaveOccurrence(occ); err != nil {
+		s.logger.Warn("Failed to save occurrence", "occurrence_id", occ.ID, "error", err)
+	}
+}
@@ -0,0 +1,290 @@
+package cmdschedule
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/batch"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+)
+
+// fakeSender 记录调用，failing 中的客户端返回失败
+type fakeSender struct {
+	mu      sync.Mutex
+	calls   int
+	failing map[string]bool
+	block   chan struct{}
+}
+
+func (f *fakeSender) SendCommandToMultiple(clientIDs []string, commandType string, payload json.RawMessage, timeout time.Duration) *command.MultiCommandResponse {
+	if f.block != nil {
+		<-f.block
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	resp := &command.MultiCommandResponse{TaskID: "task", Total: len(clientIDs)}
+	for _, cid := range clientIDs {
+		r := &command.ClientCommandResult{ClientID: cid, Status: command.CommandStatusCompleted, Result: json.RawMessage(`"ok"`)}
+		if f.failing[cid] {
+			r.Status = command.CommandStatusFailed
+			r.Error = "boom"
+		}
+		resp.Results = append(resp.Results, r)
+	}
+	return resp
+}
+
+func (f *fakeSender) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// fakeBatch 记录批量任务创建
+type fakeBatch struct {
+	mu   sync.Mutex
+	jobs int
+}
+
+func (f *fakeBatch) ExecuteWithRollout(cmd string, payload json.RawMessage, targets []string, wait bool, rollout *batch.RolloutPolicy) (*batch.BatchJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.jobs++
+	return &batch.BatchJob{ID: "job-1", TotalCount: int64(len(targets))}, nil
+}
+
+func newTestScheduler(t *testing.T, dir string, sender CommandSender) *Scheduler {
+	t.Helper()
+	store, err := NewStore(dir)
+	require.NoError(t, err)
+	s := NewScheduler(store, sender, monitoring.NewDefaultLogger())
+	t.Cleanup(s.Stop)
+	return s
+}
+
+// waitOccurrence 等待计划最新一次执行结束
+func waitOccurrence(t *testing.T, s *Scheduler, scheduleID string) *Occurrence {
+	t.Helper()
+	var occ *Occurrence
+	require.Eventually(t, func() bool {
+		occs, err := s.ListOccurrences(scheduleID)
+		if err != nil || len(occs) == 0 || occs[0].Status == OccurrenceRunning {
+			return false
+		}
+		occ = occs[0]
+		return true
+	}, 2*time.Second, 10*time.Millisecond)
+	return occ
+}
+
+func TestScheduleValidation(t *testing.T) {
+	s := newTestScheduler(t, "", &fakeSender{})
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	cases := []struct {
+		name string
+		sc   Schedule
+	}{
+		{"no trigger", Schedule{CommandType: "exec_shell", ClientIDs: []string{"c1"}}},
+		{"both triggers", Schedule{CommandType: "exec_shell", ClientIDs: []string{"c1"}, RunAt: &future, Cron: "@every 1m"}},
+		{"bad cron", Schedule{CommandType: "exec_shell", ClientIDs: []string{"c1"}, Cron: "* * *"}},
+		{"past run_at", Schedule{CommandType: "exec_shell", ClientIDs: []string{"c1"}, RunAt: &past}},
+		{"no clients", Schedule{CommandType: "exec_shell", Cron: "@every 1m"}},
+		{"bad misfire", Schedule{CommandType: "exec_shell", ClientIDs: []string{"c1"}, Cron: "@every 1m", MisfirePolicy: "later"}},
+		{"batch without executor", Schedule{Kind: KindBatch, CommandType: "exec_shell", Cron: "@every 1m"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.Create(&tc.sc)
+			assert.Error(t, err)
+		})
+	}
+
+	sc, err := s.Create(&Schedule{CommandType: "exec_shell", ClientIDs: []string{"c1"}, Cron: "0 */5 * * * *"})
+	require.NoError(t, err)
+	assert.True(t, sc.Enabled)
+	assert.Equal(t, MisfireSkip, sc.MisfirePolicy)
+	require.NotNil(t, sc.NextRunAt)
+	assert.True(t, sc.NextRunAt.After(time.Now()))
+}
+
+func TestRunAtExecutesOnceAndStoresResults(t *testing.T) {
+	sender := &fakeSender{failing: map[string]bool{"c2": true}}
+	s := newTestScheduler(t, t.TempDir(), sender)
+	runAt := time.Now().Add(time.Minute)
+	sc, err := s.Create(&Schedule{CommandType: "exec_shell", ClientIDs: []string{"c1", "c2"}, RunAt: &runAt})
+	require.NoError(t, err)
+
+	s.tick(runAt.Add(-time.Second))
+	assert.Equal(t, 0, sender.callCount())
+
+	s.tick(runAt.Add(time.Second))
+	occ := waitOccurrence(t, s, sc.ID)
+	assert.Equal(t, OccurrenceFailed, occ.Status)
+	assert.Equal(t, 2, occ.Total)
+	assert.Equal(t, 1, occ.SuccessCount)
+	assert.Equal(t, 1, occ.FailedCount)
+
+	_, results, err := s.GetOccurrence(occ.ID)
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	// 一次性计划执行后自动停用
+	got, err := s.Get(sc.ID)
+	require.NoError(t, err)
+	assert.False(t, got.Enabled)
+	assert.Nil(t, got.NextRunAt)
+	s.tick(runAt.Add(time.Hour))
+	assert.Equal(t, 1, sender.callCount())
+}
+
+func TestMisfirePolicies(t *testing.T) {
+	sender := &fakeSender{}
+	s := newTestScheduler(t, "", sender)
+
+	skip, err := s.Create(&Schedule{CommandType: "exec_shell", ClientIDs: []string{"c1"}, Cron: "@every 1m"})
+	require.NoError(t, err)
+	catchUp, err := s.Create(&Schedule{CommandType: "exec_shell", ClientIDs: []string{"c1"}, Cron: "@every 1m", MisfirePolicy: MisfireCatchUp})
+	require.NoError(t, err)
+
+	// 模拟停机 10 分钟后恢复
+	now := skip.NextRunAt.Add(10 * time.Minute)
+	s.tick(now)
+
+	occ := waitOccurrence(t, s, skip.ID)
+	assert.Equal(t, OccurrenceSkipped, occ.Status)
+	assert.GreaterOrEqual(t, occ.MissedRuns, 10)
+
+	occ = waitOccurrence(t, s, catchUp.ID)
+	assert.Equal(t, OccurrenceSuccess, occ.Status)
+	assert.True(t, occ.CatchUp)
+	assert.Equal(t, 1, sender.callCount(), "catch-up runs once, not once per missed run")
+
+	// 下次执行时间从恢复时刻重新计算
+	got, err := s.Get(skip.ID)
+	require.NoError(t, err)
+	assert.True(t, got.NextRunAt.After(now))
+}
+
+func TestOverlappingRunIsSkipped(t *testing.T) {
+	sender := &fakeSender{block: make(chan struct{})}
+	s := newTestScheduler(t, "", sender)
+	sc, err := s.Create(&Schedule{CommandType: "exec_shell", ClientIDs: []string{"c1"}, Cron: "@every 1s"})
+	require.NoError(t, err)
+
+	first := *sc.NextRunAt
+	s.tick(first)
+	got, err := s.Get(sc.ID)
+	require.NoError(t, err)
+	s.tick(*got.NextRunAt)
+
+	occs, err := s.ListOccurrences(sc.ID)
+	require.NoError(t, err)
+	require.Len(t, occs, 2)
+	assert.Equal(t, OccurrenceSkipped, occs[0].Status)
+	assert.Equal(t, OccurrenceRunning, occs[1].Status)
+
+	close(sender.block)
+	require.Eventually(t, func() bool {
+		occs, _ := s.ListOccurrences(sc.ID)
+		return occs[1].Status == OccurrenceSuccess
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestDisableEnableAndUpdate(t *testing.T) {
+	sender := &fakeSender{}
+	s := newTestScheduler(t, "", sender)
+	sc, err := s.Create(&Schedule{CommandType: "exec_shell", ClientIDs: []string{"c1"}, Cron: "@every 1m"})
+	require.NoError(t, err)
+
+	disabled, err := s.SetEnabled(sc.ID, false)
+	require.NoError(t, err)
+	assert.Nil(t, disabled.NextRunAt)
+	s.tick(time.Now().Add(time.Hour))
+	assert.Equal(t, 0, sender.callCount())
+
+	updated, err := s.Update(sc.ID, &Schedule{Name: "renamed", CommandType: "exec_shell", ClientIDs: []string{"c1", "c2"}, Cron: "@every 5m"})
+	require.NoError(t, err)
+	assert.False(t, updated.Enabled)
+	assert.Equal(t, "renamed", updated.Name)
+	assert.Equal(t, sc.CreatedAt, updated.CreatedAt)
+
+	enabled, err := s.SetEnabled(sc.ID, true)
+	require.NoError(t, err)
+	require.NotNil(t, enabled.NextRunAt)
+
+	require.NoError(t, s.Delete(sc.ID))
+	_, err = s.Get(sc.ID)
+	assert.ErrorIs(t, err, ErrScheduleNotFound)
+}
+
+func TestFireDoesNotOverwriteConcurrentChanges(t *testing.T) {
+	sender := &fakeSender{}
+	s := newTestScheduler(t, "", sender)
+	sc, err := s.Create(&Schedule{CommandType: "exec_shell", ClientIDs: []string{"c1"}, Cron: "@every 1m"})
+	require.NoError(t, err)
+	due := *sc.NextRunAt
+
+	// tick 取到快照后计划被停用：不执行，也不恢复启用
+	stale, err := s.Get(sc.ID)
+	require.NoError(t, err)
+	_, err = s.SetEnabled(sc.ID, false)
+	require.NoError(t, err)
+	s.fire(stale, due)
+	got, err := s.Get(sc.ID)
+	require.NoError(t, err)
+	assert.False(t, got.Enabled)
+	assert.Nil(t, got.NextRunAt)
+
+	// 快照之后被删除：不执行，也不重新写回
+	_, err = s.SetEnabled(sc.ID, true)
+	require.NoError(t, err)
+	stale, err = s.Get(sc.ID)
+	require.NoError(t, err)
+	require.NoError(t, s.Delete(sc.ID))
+	s.fire(stale, *stale.NextRunAt)
+	_, err = s.Get(sc.ID)
+	assert.ErrorIs(t, err, ErrScheduleNotFound)
+	assert.Equal(t, 0, sender.callCount())
+	assert.Empty(t, s.store.ListOccurrences(sc.ID))
+}
+
+func TestBatchScheduleLinksJob(t *testing.T) {
+	s := newTestScheduler(t, "", &fakeSender{})
+	runner := &fakeBatch{}
+	s.batch = runner
+	sc, err := s.Create(&Schedule{Kind: KindBatch, CommandType: "exec_shell", ClientIDs: []string{"c1", "c2", "c3"}, Cron: "@every 1m"})
+	require.NoError(t, err)
+
+	s.tick(*sc.NextRunAt)
+	occ := waitOccurrence(t, s, sc.ID)
+	assert.Equal(t, OccurrenceDispatched, occ.Status)
+	assert.Equal(t, "job-1", occ.BatchJobID)
+	assert.Equal(t, 3, occ.Total)
+}
+
+func TestStoreReloadMarksInterrupted(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	require.NoError(t, err)
+	sc := &Schedule{ID: "s1", CommandType: "exec_shell", ClientIDs: []string{"c1"}, Cron: "@every 1m", Enabled: true}
+	require.NoError(t, store.Save(sc))
+	require.NoError(t, store.SaveOccurrence(&Occurrence{ID: "o1", ScheduleID: "s1", Status: OccurrenceRunning}))
+
+	s := newTestScheduler(t, dir, &fakeSender{})
+	got, err := s.Get("s1")
+	require.NoError(t, err)
+	assert.True(t, got.Enabled)
+
+	s.Start()
+	occ, _, err := s.GetOccurrence("o1")
+	require.NoError(t, err)
+	assert.Equal(t, OccurrenceInterrupted, occ.Status)
+}
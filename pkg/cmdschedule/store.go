@@ -0,0 +1,265 @@
+package cmdschedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/jsonfile"
+)
+
+// 存储错误
+var (
+	ErrScheduleNotFound   = errors.New("schedule not found")
+	ErrOccurrenceNotFound = errors.New("occurrence not found")
+	ErrScheduleChanged    = errors.New("schedule changed since it became due")
+)
+
+// Store 计划与执行记录存储（JSON 文件）
+// 计划与执行记录保存在 schedules.json，multi 执行的结果集单独保存在 results/<occurrence_id>.json
+type Store struct {
+	dir         string
+	file        *jsonfile.File
+	schedules   map[string]*Schedule
+	occurrences map[string][]*Occurrence // scheduleID -> 执行记录（按时间升序）
+	results     map[string][]*command.ClientCommandResult
+}
+
+// storeFile 持久化文件格式
+type storeFile struct {
+	Schedules   []*Schedule   `json:"schedules"`
+	Occurrences []*Occurrence `json:"occurrences"`
+}
+
+// NewStore 创建存储，dir 为空时仅保存在内存中
+func NewStore(dir string) (*Store, error) {
+	var f storeFile
+	file, err := jsonfile.Open(dir, "schedules.json", &f)
+	if err != nil {
+		return nil, fmt.Errorf("open schedule store: %w", err)
+	}
+	s := &Store{
+		dir:         dir,
+		file:        file,
+		schedules:   make(map[string]*Schedule),
+		occurrences: make(map[string][]*Occurrence),
+		results:     make(map[string][]*command.ClientCommandResult),
+	}
+	if dir != "" {
+		if err := os.MkdirAll(s.resultDir(), 0755); err != nil {
+			return nil, fmt.Errorf("create schedule result dir: %w", err)
+		}
+	}
+	for _, sc := range f.Schedules {
+		s.schedules[sc.ID] = sc
+	}
+	for _, occ := range f.Occurrences {
+		s.occurrences[occ.ScheduleID] = append(s.occurrences[occ.ScheduleID], occ)
+	}
+	return s, nil
+}
+
+// List 列出所有计划（按创建时间排序）
+func (s *Store) List() []*Schedule {
+	s.file.RLock()
+	defer s.file.RUnlock()
+	list := make([]*Schedule, 0, len(s.schedules))
+	for _, sc := range s.schedules {
+		cp := *sc
+		list = append(list, &cp)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// Get 获取计划副本
+func (s *Store) Get(id string) (*Schedule, error) {
+	s.file.RLock()
+	defer s.file.RUnlock()
+	sc, ok := s.schedules[id]
+	if !ok {
+		return nil, ErrScheduleNotFound
+	}
+	cp := *sc
+	return &cp, nil
+}
+
+// Save 创建或更新计划
+func (s *Store) Save(sc *Schedule) error {
+	s.file.Lock()
+	defer s.file.Unlock()
+	cp := *sc
+	s.schedules[sc.ID] = &cp
+	return s.persist()
+}
+
+// AdvanceRun 在存储锁内推进到期计划的执行字段（next_run_at、last_run_at），不覆盖其他字段
+// 计划已删除返回 ErrScheduleNotFound；已停用或 next_run_at 不再等于 due（期间被修改）返回 ErrScheduleChanged
+// 一次性计划执行后自动停用，返回推进后的计划副本（持久化失败时仍返回副本与错误）
+func (s *Store) AdvanceRun(id string, due, now time.Time, ran bool) (*Schedule, error) {
+	s.file.Lock()
+	defer s.file.Unlock()
+	sc, ok := s.schedules[id]
+	if !ok {
+		return nil, ErrScheduleNotFound
+	}
+	if !sc.Enabled || sc.NextRunAt == nil || !sc.NextRunAt.Equal(due) {
+		return nil, ErrScheduleChanged
+	}
+	cp := *sc
+	cp.NextRunAt = cp.next(now)
+	if cp.NextRunAt == nil {
+		cp.Enabled = false
+	}
+	if ran {
+		cp.LastRunAt = &now
+	}
+	s.schedules[id] = &cp
+	out := cp
+	return &out, s.persist()
+}
+
+// Delete 删除计划及其执行记录和结果
+func (s *Store) Delete(id string) error {
+	s.file.Lock()
+	defer s.file.Unlock()
+	if _, ok := s.schedules[id]; !ok {
+		return ErrScheduleNotFound
+	}
+	for _, occ := range s.occurrences[id] {
+		s.removeResults(occ.ID)
+	}
+	delete(s.schedules, id)
+	delete(s.occurrences, id)
+	return s.persist()
+}
+
+// ListOccurrences 列出计划的执行记录（最新在前）
+func (s *Store) ListOccurrences(scheduleID string) []*Occurrence {
+	s.file.RLock()
+	defer s.file.RUnlock()
+	occs := s.occurrences[scheduleID]
+	list := make([]*Occurrence, 0, len(occs))
+	for i := len(occs) - 1; i >= 0; i-- {
+		cp := *occs[i]
+		list = append(list, &cp)
+	}
+	return list
+}
+
+// GetOccurrence 获取执行记录及其结果集
+func (s *Store) GetOccurrence(id string) (*Occurrence, []*command.ClientCommandResult, error) {
+	s.file.RLock()
+	defer s.file.RUnlock()
+	for _, occs := range s.occurrences {
+		for _, occ := range occs {
+			if occ.ID != id {
+				continue
+			}
+			cp := *occ
+			results, err := s.loadResults(id)
+			if err != nil {
+				return nil, nil, err
+			}
+			return &cp, results, nil
+		}
+	}
+	return nil, nil, ErrOccurrenceNotFound
+}
+
+// SaveOccurrence 创建或更新执行记录，每个计划只保留最近 maxOccurrences 条
+func (s *Store) SaveOccurrence(occ *Occurrence) error {
+	s.file.Lock()
+	defer s.file.Unlock()
+	cp := *occ
+	occs := s.occurrences[occ.ScheduleID]
+	replaced := false
+	for i, o := range occs {
+		if o.ID == occ.ID {
+			occs[i] = &cp
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		occs = append(occs, &cp)
+	}
+	for len(occs) > maxOccurrences {
+		s.removeResults(occs[0].ID)
+		occs = occs[1:]
+	}
+	s.occurrences[occ.ScheduleID] = occs
+	return s.persist()
+}
+
+// SaveResults 保存执行结果集
+func (s *Store) SaveResults(occurrenceID string, results []*command.ClientCommandResult) error {
+	s.file.Lock()
+	defer s.file.Unlock()
+	if s.dir == "" {
+		s.results[occurrenceID] = results
+		return nil
+	}
+	data, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	return jsonfile.WriteFile(s.resultFile(occurrenceID), data)
+}
+
+// loadResults 读取结果集，不存在时返回空（调用方持有锁）
+func (s *Store) loadResults(occurrenceID string) ([]*command.ClientCommandResult, error) {
+	if s.dir == "" {
+		return s.results[occurrenceID], nil
+	}
+	data, err := os.ReadFile(s.resultFile(occurrenceID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var results []*command.ClientCommandResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("parse results of %s: %w", occurrenceID, err)
+	}
+	return results, nil
+}
+
+// removeResults 删除结果集（调用方持有锁）
+func (s *Store) removeResults(occurrenceID string) {
+	delete(s.results, occurrenceID)
+	if s.dir != "" {
+		os.Remove(s.resultFile(occurrenceID))
+	}
+}
+
+// resultDir 结果集目录
+func (s *Store) resultDir() string {
+	return filepath.Join(s.dir, "results")
+}
+
+// resultFile 结果集文件路径
+func (s *Store) resultFile(occurrenceID string) string {
+	return filepath.Join(s.resultDir(), occurrenceID+".json")
+}
+
+// persist 写入存储文件（调用方持有锁）
+func (s *Store) persist() error {
+	f := storeFile{
+		Schedules:   make([]*Schedule, 0, len(s.schedules)),
+		Occurrences: []*Occurrence{},
+	}
+	for _, sc := range s.schedules {
+		f.Schedules = append(f.Schedules, sc)
+	}
+	for _, occs := range s.occurrences {
+		f.Occurrences = append(f.Occurrences, occs...)
+	}
+	return s.file.Save(f)
+}
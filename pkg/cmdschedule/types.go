@@ -0,0 +1,192 @@
+package cmdschedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/voilet/quic-flow/pkg/batch"
+)
+
+// Kind 计划执行方式
+type Kind string
+
+const (
+	KindMulti Kind = "multi" // 通过 CommandManager 多播（同 /api/command/multi）
+	KindBatch Kind = "batch" // 创建批量任务（同 /api/batch/execute）
+)
+
+// MisfirePolicy 错过执行时间（如服务停机）时的处理策略
+type MisfirePolicy string
+
+const (
+	MisfireSkip    MisfirePolicy = "skip"     // 跳过错过的执行，记录一条 skipped 记录（默认）
+	MisfireCatchUp MisfirePolicy = "catch_up" // 立即补执行一次，随后按计划继续
+)
+
+// OccurrenceStatus 单次执行状态
+type OccurrenceStatus string
+
+const (
+	OccurrenceRunning     OccurrenceStatus = "running"
+	OccurrenceSuccess     OccurrenceStatus = "success"
+	OccurrenceFailed      OccurrenceStatus = "failed"
+	OccurrenceDispatched  OccurrenceStatus = "dispatched" // 批量任务已创建，结果见 batch_job_id
+	OccurrenceSkipped     OccurrenceStatus = "skipped"
+	OccurrenceInterrupted OccurrenceStatus = "interrupted" // 服务重启时仍在执行
+)
+
+// 默认值
+const (
+	defaultTimeout      = 30 // 秒
+	defaultMisfireGrace = 60 // 秒
+	maxOccurrences      = 50 // 每个计划保留的执行记录数
+)
+
+// cronParser 与任务调度器一致：6 段表达式（含秒），支持 @every 等描述符
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Schedule 定时/周期命令
+// RunAt 与 Cron 二选一：RunAt 为一次性执行，Cron 为周期执行
+type Schedule struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name,omitempty"`
+	Kind        Kind            `json:"kind"`
+	ClientIDs   []string        `json:"client_ids,omitempty"` // 目标客户端（batch 为空时表示全部在线客户端）
+	CommandType string          `json:"command_type"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	Timeout     int             `json:"timeout,omitempty"` // multi 命令超时（秒），默认 30
+
+	// batch 专用
+	WaitForResult bool                 `json:"wait_for_result,omitempty"`
+	Rollout       *batch.RolloutPolicy `json:"rollout,omitempty"`
+
+	RunAt           *time.Time    `json:"run_at,omitempty"`
+	Cron            string        `json:"cron,omitempty"`
+	MisfirePolicy   MisfirePolicy `json:"misfire_policy,omitempty"`
+	MisfireGraceSec int           `json:"misfire_grace_sec,omitempty"` // 超过该时长视为错过，默认 60
+
+	Enabled   bool       `json:"enabled"`
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Occurrence 计划的单次执行
+// multi 的结果集单独存储，通过 /api/schedules/occurrences/:id 获取；batch 通过 batch_job_id 关联
+type Occurrence struct {
+	ID           string           `json:"id"`
+	ScheduleID   string           `json:"schedule_id"`
+	ScheduledAt  time.Time        `json:"scheduled_at"`
+	StartedAt    *time.Time       `json:"started_at,omitempty"`
+	FinishedAt   *time.Time       `json:"finished_at,omitempty"`
+	Status       OccurrenceStatus `json:"status"`
+	CatchUp      bool             `json:"catch_up,omitempty"`    // 是否为补执行
+	MissedRuns   int              `json:"missed_runs,omitempty"` // 错过的执行次数
+	TaskID       string           `json:"task_id,omitempty"`     // 多播任务 ID
+	BatchJobID   string           `json:"batch_job_id,omitempty"`
+	Total        int              `json:"total"`
+	SuccessCount int              `json:"success_count"`
+	FailedCount  int              `json:"failed_count"`
+	Message      string           `json:"message,omitempty"`
+}
+
+// normalize 校验计划并填充默认值
+func (s *Schedule) normalize() error {
+	switch s.Kind {
+	case "":
+		s.Kind = KindMulti
+	case KindMulti, KindBatch:
+	default:
+		return fmt.Errorf("invalid kind: %s", s.Kind)
+	}
+	if s.CommandType == "" {
+		return fmt.Errorf("command_type is required")
+	}
+	if s.Kind == KindMulti && len(s.ClientIDs) == 0 {
+		return fmt.Errorf("client_ids is required")
+	}
+	if len(s.Payload) > 0 && !json.Valid(s.Payload) {
+		return fmt.Errorf("payload must be valid JSON")
+	}
+	if s.Rollout != nil {
+		if err := s.Rollout.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if (s.RunAt == nil) == (s.Cron == "") {
+		return fmt.Errorf("exactly one of run_at or cron is required")
+	}
+	if s.Cron != "" {
+		if _, err := cronParser.Parse(s.Cron); err != nil {
+			return fmt.Errorf("invalid cron expression: %w", err)
+		}
+	}
+
+	switch s.MisfirePolicy {
+	case "":
+		s.MisfirePolicy = MisfireSkip
+	case MisfireSkip, MisfireCatchUp:
+	default:
+		return fmt.Errorf("invalid misfire_policy: %s", s.MisfirePolicy)
+	}
+	if s.MisfireGraceSec < 0 {
+		return fmt.Errorf("misfire_grace_sec must be >= 0")
+	}
+	if s.Timeout < 0 {
+		return fmt.Errorf("timeout must be >= 0")
+	}
+	return nil
+}
+
+// next 计算 after 之后的下一次执行时间，一次性计划执行后返回 nil
+func (s *Schedule) next(after time.Time) *time.Time {
+	if s.Cron == "" {
+		if s.RunAt != nil && s.RunAt.After(after) {
+			t := *s.RunAt
+			return &t
+		}
+		return nil
+	}
+	sched, err := cronParser.Parse(s.Cron)
+	if err != nil {
+		return nil
+	}
+	t := sched.Next(after)
+	return &t
+}
+
+// missedRuns 统计 [from, to] 区间内应执行的次数（上限 1000）
+func (s *Schedule) missedRuns(from, to time.Time) int {
+	if s.Cron == "" {
+		return 1
+	}
+	sched, err := cronParser.Parse(s.Cron)
+	if err != nil {
+		return 1
+	}
+	n := 1
+	for t := sched.Next(from); !t.After(to) && n < 1000; t = sched.Next(t) {
+		n++
+	}
+	return n
+}
+
+// grace 错过判定时长
+func (s *Schedule) grace() time.Duration {
+	if s.MisfireGraceSec > 0 {
+		return time.Duration(s.MisfireGraceSec) * time.Second
+	}
+	return defaultMisfireGrace * time.Second
+}
+
+// timeout 命令超时
+func (s *Schedule) timeout() time.Duration {
+	if s.Timeout > 0 {
+		return time.Duration(s.Timeout) * time.Second
+	}
+	return defaultTimeout * time.Second
+}
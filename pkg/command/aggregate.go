@@ -0,0 +1,217 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/voilet/quic-flow/pkg/jsonpath"
+)
+
+// 聚合默认值
+const (
+	defaultOutlierThreshold = 5.0 // 百分比
+	maxDiffLines            = 500 // 超过该行数不计算差异
+)
+
+// AggregateOptions 结果聚合选项
+// 先按 Field 取值，再用 Pattern 归一化，最终值相同的结果归入同一分组
+type AggregateOptions struct {
+	Field            string  `json:"field,omitempty"`             // JSON 路径（如 "stdout"、"items.0.name"），为空时使用完整结果
+	Pattern          string  `json:"pattern,omitempty"`           // 归一化正则：取第一个捕获组（无捕获组时取整个匹配），未匹配时保留原值
+	OutlierThreshold float64 `json:"outlier_threshold,omitempty"` // 占比不超过该百分比的非多数分组视为离群，默认 5
+}
+
+// ResultBucket 聚合分组
+type ResultBucket struct {
+	Key       string          `json:"key"`              // 归一化后的值（失败结果为错误信息）
+	Status    CommandStatus   `json:"status"`           // 命令状态
+	Count     int             `json:"count"`            // 客户端数量
+	Percent   float64         `json:"percent"`          // 占比（百分比）
+	ClientIDs []string        `json:"client_ids"`       // 成员客户端
+	Sample    json.RawMessage `json:"sample,omitempty"` // 原始结果样例
+	Outlier   bool            `json:"outlier"`          // 是否离群
+	Diff      []string        `json:"diff,omitempty"`   // 与多数分组的逐行差异（"-" 多数分组独有，"+" 本分组独有）
+}
+
+// ResultAggregate 聚合结果
+type ResultAggregate struct {
+	Total          int             `json:"total"`           // 结果总数
+	BucketCount    int             `json:"bucket_count"`    // 分组数量
+	Majority       string          `json:"majority"`        // 多数分组的值
+	Buckets        []*ResultBucket `json:"buckets"`         // 分组（按数量降序）
+	OutlierClients []string        `json:"outlier_clients"` // 离群分组中的客户端
+}
+
+// Validate 校验聚合选项
+func (o *AggregateOptions) Validate() error {
+	if o.Pattern != "" {
+		if _, err := regexp.Compile(o.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	if o.OutlierThreshold < 0 || o.OutlierThreshold > 100 {
+		return fmt.Errorf("outlier_threshold must be between 0 and 100")
+	}
+	return nil
+}
+
+// AggregateResults 将多个客户端的命令结果按值分组，并标记离群分组
+func AggregateResults(results []*ClientCommandResult, opts *AggregateOptions) (*ResultAggregate, error) {
+	if opts == nil {
+		opts = &AggregateOptions{}
+	}
+	var re *regexp.Regexp
+	if opts.Pattern != "" {
+		var err error
+		if re, err = regexp.Compile(opts.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	threshold := opts.OutlierThreshold
+	if threshold <= 0 {
+		threshold = defaultOutlierThreshold
+	}
+
+	agg := &ResultAggregate{
+		Buckets:        []*ResultBucket{},
+		OutlierClients: []string{},
+	}
+	byKey := make(map[string]*ResultBucket)
+	for _, r := range results {
+		if r == nil {
+			continue
+		}
+		agg.Total++
+
+		var key string
+		if r.Status == CommandStatusCompleted {
+			key = normalizeResult(r.Result, opts.Field, re)
+		} else {
+			key = r.Error
+		}
+		id := string(r.Status) + "\x00" + key
+		b, ok := byKey[id]
+		if !ok {
+			b = &ResultBucket{Key: key, Status: r.Status, Sample: r.Result}
+			byKey[id] = b
+			agg.Buckets = append(agg.Buckets, b)
+		}
+		b.Count++
+		b.ClientIDs = append(b.ClientIDs, r.ClientID)
+	}
+
+	sort.SliceStable(agg.Buckets, func(i, j int) bool {
+		if agg.Buckets[i].Count != agg.Buckets[j].Count {
+			return agg.Buckets[i].Count > agg.Buckets[j].Count
+		}
+		return agg.Buckets[i].Key < agg.Buckets[j].Key
+	})
+	agg.BucketCount = len(agg.Buckets)
+	if agg.BucketCount == 0 {
+		return agg, nil
+	}
+
+	majority := agg.Buckets[0]
+	agg.Majority = majority.Key
+	for _, b := range agg.Buckets {
+		b.Percent = float64(b.Count) / float64(agg.Total) * 100
+		if b == majority || b.Percent > threshold {
+			continue
+		}
+		b.Outlier = true
+		agg.OutlierClients = append(agg.OutlierClients, b.ClientIDs...)
+		if b.Status == CommandStatusCompleted && majority.Status == CommandStatusCompleted {
+			b.Diff = diffLines(majority.Key, b.Key)
+		}
+	}
+	return agg, nil
+}
+
+// normalizeResult 提取并归一化结果值
+func normalizeResult(raw json.RawMessage, field string, re *regexp.Regexp) string {
+	var value string
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		value = string(raw)
+	} else {
+		if field != "" {
+			// 路径不存在时按空值分组
+			v, _ = jsonpath.Lookup(v, field)
+		}
+		value = stringifyValue(v)
+	}
+
+	value = strings.TrimSpace(value)
+	if re != nil {
+		if m := re.FindStringSubmatch(value); m != nil {
+			if len(m) > 1 {
+				value = m[1]
+			} else {
+				value = m[0]
+			}
+		}
+	}
+	return value
+}
+
+// stringifyValue 字符串原样返回，其他值序列化为规范 JSON（对象按键排序）
+func stringifyValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	default:
+		data, _ := json.Marshal(val)
+		return string(data)
+	}
+}
+
+// diffLines 基于最长公共子序列计算逐行差异，仅返回不同的行
+func diffLines(a, b string) []string {
+	al, bl := strings.Split(a, "\n"), strings.Split(b, "\n")
+	if len(al) > maxDiffLines || len(bl) > maxDiffLines {
+		return nil
+	}
+
+	// lcs[i][j] 为 al[i:] 与 bl[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(al)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bl)+1)
+	}
+	for i := len(al) - 1; i >= 0; i-- {
+		for j := len(bl) - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff []string
+	i, j := 0, 0
+	for i < len(al) && j < len(bl) {
+		switch {
+		case al[i] == bl[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, "-"+al[i])
+			i++
+		default:
+			diff = append(diff, "+"+bl[j])
+			j++
+		}
+	}
+	for ; i < len(al); i++ {
+		diff = append(diff, "-"+al[i])
+	}
+	for ; j < len(bl); j++ {
+		diff = append(diff, "+"+bl[j])
+	}
+	return diff
+}
@@ -0,0 +1,77 @@
+package command
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func shellResult(clientID, stdout string) *ClientCommandResult {
+	data, _ := json.Marshal(ShellResult{Success: true, Stdout: stdout})
+	return &ClientCommandResult{ClientID: clientID, Status: CommandStatusCompleted, Result: data}
+}
+
+func TestAggregateResultsByField(t *testing.T) {
+	var results []*ClientCommandResult
+	for i := 0; i < 20; i++ {
+		results = append(results, shellResult(string(rune('a'+i)), "web\n5.15.0-91-generic\n"))
+	}
+	results = append(results,
+		shellResult("x", "web\n6.1.0-13-amd64\n"),
+		&ClientCommandResult{ClientID: "y", Status: CommandStatusTimeout, Error: "timeout"},
+	)
+
+	agg, err := AggregateResults(results, &AggregateOptions{Field: "stdout"})
+	require.NoError(t, err)
+	assert.Equal(t, 22, agg.Total)
+	require.Equal(t, 3, agg.BucketCount)
+	assert.Equal(t, "web\n5.15.0-91-generic", agg.Majority)
+	assert.Equal(t, 20, agg.Buckets[0].Count)
+	assert.False(t, agg.Buckets[0].Outlier)
+	assert.ElementsMatch(t, []string{"x", "y"}, agg.OutlierClients)
+
+	for _, b := range agg.Buckets[1:] {
+		assert.True(t, b.Outlier)
+		if b.Status == CommandStatusCompleted {
+			assert.Equal(t, []string{"-5.15.0-91-generic", "+6.1.0-13-amd64"}, b.Diff)
+		} else {
+			assert.Equal(t, "timeout", b.Key)
+			assert.Empty(t, b.Diff)
+		}
+	}
+}
+
+func TestAggregateResultsPattern(t *testing.T) {
+	results := []*ClientCommandResult{
+		shellResult("a", "nginx version: nginx/1.24.0 (build 1)"),
+		shellResult("b", "nginx version: nginx/1.24.0 (build 2)"),
+		shellResult("c", "nginx version: nginx/1.22.1"),
+	}
+	agg, err := AggregateResults(results, &AggregateOptions{Field: "stdout", Pattern: `nginx/(\S+)`, OutlierThreshold: 50})
+	require.NoError(t, err)
+	require.Equal(t, 2, agg.BucketCount)
+	assert.Equal(t, "1.24.0", agg.Majority)
+	assert.Equal(t, []string{"a", "b"}, agg.Buckets[0].ClientIDs)
+	assert.Equal(t, []string{"c"}, agg.OutlierClients)
+
+	_, err = AggregateResults(results, &AggregateOptions{Pattern: "("})
+	assert.Error(t, err)
+}
+
+func TestAggregateResultsCanonicalJSON(t *testing.T) {
+	results := []*ClientCommandResult{
+		{ClientID: "a", Status: CommandStatusCompleted, Result: json.RawMessage(`{"b":1,"a":[1,2]}`)},
+		{ClientID: "b", Status: CommandStatusCompleted, Result: json.RawMessage(`{"a":[1,2],"b":1}`)},
+		{ClientID: "c", Status: CommandStatusCompleted, Result: json.RawMessage(`{"a":[1,3],"b":1}`)},
+	}
+	agg, err := AggregateResults(results, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, agg.BucketCount)
+	assert.Equal(t, 2, agg.Buckets[0].Count)
+
+	agg, err = AggregateResults(results, &AggregateOptions{Field: "a.1"})
+	require.NoError(t, err)
+	assert.Equal(t, "2", agg.Majority)
+}
@@ -3,6 +3,8 @@ package command
 import (
 	"encoding/json"
 	"time"
+
+	"github.com/voilet/quic-flow/pkg/protocol"
 )
 
 // ============================================================================
@@ -29,16 +31,35 @@ const (
 	CmdServiceRestart = "service.restart" // 重启服务
 	CmdServiceStop    = "service.stop"    // 停止服务
 	CmdServiceStart   = "service.start"   // 启动服务
+	CmdServiceList    = "service.list"    // 服务列表
+	CmdServiceEnable  = "service.enable"  // 设置开机自启
+	CmdServiceDisable = "service.disable" // 取消开机自启
 
 	// 配置管理
 	CmdConfigGet    = "config.get"    // 获取配置
 	CmdConfigUpdate = "config.update" // 更新配置
 
+	// Agent 自升级
+	CmdAgentUpdate       = "agent.update"        // 安装已推送的新版本并重启
+	CmdAgentUpdateStatus = "agent.update_status" // 获取版本与升级状态
+
+	// 插件管理
+	CmdPluginList   = "plugin.list"   // 列出已加载的插件
+	CmdPluginRescan = "plugin.rescan" // 重新扫描插件目录
+
+	// 定时任务
+	CmdTaskExecute    = "task.execute"     // 执行任务（后台运行，进度与结果以事件上报）
+	CmdTaskCancel     = "task.cancel"      // 取消执行中的任务
+	CmdTaskConfigPush = "task.config.push" // 推送 Agent 本地调度的任务配置（TaskConfigPush）
+
 	// 网络诊断
 	CmdNetworkPing       = "network.ping"       // Ping 测试
 	CmdNetworkTrace      = "network.trace"      // 路由追踪
 	CmdNetworkInterfaces = "network.interfaces" // 获取物理网卡列表
 	CmdNetworkSpeed      = "network.speed"      // 获取网卡协商速率
+	CmdNetworkTCP        = "network.tcp"        // TCP 连接测试
+	CmdNetworkHTTP       = "network.http"       // HTTP 探测（含耗时分解）
+	CmdNetworkDNS        = "network.dns"        // DNS 解析
 
 	// 通用
 	CmdPing = "ping" // 简单存活检测
@@ -148,38 +169,209 @@ type FileWriteResult struct {
 
 // --- 服务管理 ---
 
+
 // ServiceParams 服务操作命令的参数
 type ServiceParams struct {
-	Name string `json:"name"` // 服务名称
+	Name         string `json:"name"`                    // 服务名称
+	JournalLines int    `json:"journal_lines,omitempty"` // 返回最近 N 行日志（service.status，默认 20，0 使用默认值，-1 不返回）
 }
 
 // ServiceResult 服务操作命令的结果
 type ServiceResult struct {
-	Name    string `json:"name"`    // 服务名称
-	Status  string `json:"status"`  // 状态（running/stopped/unknown）
-	Success bool   `json:"success"` // 操作是否成功
-	Message string `json:"message"` // 消息
+	Name          string   `json:"name"`                      // 服务名称
+	Status        string   `json:"status"`                    // 状态（running/stopped/unknown）
+	Success       bool     `json:"success"`                   // 操作是否成功
+	Message       string   `json:"message"`                   // 消息
+	Manager       string   `json:"manager,omitempty"`         // 服务管理器（systemd/sysv）
+	Description   string   `json:"description,omitempty"`     // 服务描述
+	LoadState     string   `json:"load_state,omitempty"`      // 加载状态（loaded/not-found/masked）
+	ActiveState   string   `json:"active_state,omitempty"`    // 活动状态（active/inactive/failed/...）
+	SubState      string   `json:"sub_state,omitempty"`       // 子状态（running/exited/dead/...）
+	UnitFileState string   `json:"unit_file_state,omitempty"` // 开机自启状态（enabled/disabled/static/...）
+	MainPID       int      `json:"main_pid,omitempty"`        // 主进程 PID
+	MemoryBytes   int64    `json:"memory_bytes,omitempty"`    // 内存占用（字节）
+	RestartCount  int      `json:"restart_count"`             // 自动重启次数
+	ActiveSince   string   `json:"active_since,omitempty"`    // 进入当前活动状态的时间
+	Journal       []string `json:"journal,omitempty"`         // 最近的日志
+}
+
+// ServiceListParams service.list 命令的参数
+type ServiceListParams struct {
+	Filter string `json:"filter,omitempty"` // 名称过滤（支持 * 通配符，不含通配符时按子串匹配）
+	State  string `json:"state,omitempty"`  // 活动状态过滤（active/inactive/failed/running）
+	All    bool   `json:"all,omitempty"`    // 是否包含未加载/非活动的单元
+}
+
+// ServiceUnit 服务单元概要信息
+type ServiceUnit struct {
+	Name        string `json:"name"`                   // 服务名称
+	Description string `json:"description,omitempty"`  // 服务描述
+	LoadState   string `json:"load_state,omitempty"`   // 加载状态
+	ActiveState string `json:"active_state,omitempty"` // 活动状态
+	SubState    string `json:"sub_state,omitempty"`    // 子状态
+	Status      string `json:"status"`                 // 状态（running/stopped/unknown）
+}
+
+// ServiceListResult service.list 命令的结果
+type ServiceListResult struct {
+	Success  bool          `json:"success"`
+	Manager  string        `json:"manager,omitempty"` // 服务管理器（systemd/sysv）
+	Services []ServiceUnit `json:"services"`
+	Count    int           `json:"count"`
+	Error    string        `json:"error,omitempty"`
 }
 
 // --- 配置管理 ---
 
 // ConfigGetParams config.get 命令的参数
 type ConfigGetParams struct {
-	Key string `json:"key"` // 配置键（空表示获取全部）
+	Key string `json:"key"` // 配置键，支持 a.b 形式（空表示获取全部）
 }
 
 // ConfigUpdateParams config.update 命令的参数
+// Patch 与 Key/Value 二选一，Patch 为 JSON Merge Patch（RFC 7386），null 表示恢复默认值
 type ConfigUpdateParams struct {
-	Key   string      `json:"key"`   // 配置键
-	Value interface{} `json:"value"` // 配置值
+	Key            string          `json:"key,omitempty"`              // 配置键
+	Value          interface{}     `json:"value,omitempty"`            // 配置值
+	Patch          json.RawMessage `json:"patch,omitempty"`            // 配置补丁
+	DryRun         bool            `json:"dry_run,omitempty"`          // 仅校验不应用
+	GracePeriodSec int             `json:"grace_period_sec,omitempty"` // 自动回滚宽限期（秒），0 使用 Agent 配置
+	NoRollback     bool            `json:"no_rollback,omitempty"`      // 禁用自动回滚
 }
 
 // ConfigResult 配置操作的结果
 type ConfigResult struct {
-	Success bool        `json:"success"`         // 是否成功
-	Key     string      `json:"key,omitempty"`   // 配置键
-	Value   interface{} `json:"value,omitempty"` // 配置值
-	Message string      `json:"message"`         // 消息
+	Success         bool            `json:"success"`                    // 是否成功
+	Key             string          `json:"key,omitempty"`              // 配置键
+	Value           interface{}     `json:"value,omitempty"`            // 配置值
+	Config          json.RawMessage `json:"config,omitempty"`           // 完整的生效配置
+	Revision        int64           `json:"revision"`                   // 配置版本
+	Changed         []string        `json:"changed,omitempty"`          // 变更的字段
+	RestartRequired []string        `json:"restart_required,omitempty"` // 需要重启才能生效的字段
+	Persisted       bool            `json:"persisted,omitempty"`        // 是否已写入配置文件
+	RollbackAt      string          `json:"rollback_at,omitempty"`      // 未重连时的自动回滚时间
+	DryRun          bool            `json:"dry_run,omitempty"`          // 是否为校验模式
+	Message         string          `json:"message"`                    // 消息
+	Error           string          `json:"error,omitempty"`            // 错误信息
+}
+
+// --- Agent 自升级 ---
+
+// AgentUpdateParams agent.update 命令的参数
+// 二进制需先通过文件传输流推送到 Agent（TaskID 为 UpdateID）
+type AgentUpdateParams struct {
+	UpdateID            string `json:"update_id"`                       // 推送时使用的传输 ID
+	Version             string `json:"version"`                         // 目标版本
+	OS                  string `json:"os"`                              // 目标系统（需与 Agent 一致）
+	Arch                string `json:"arch"`                            // 目标架构（需与 Agent 一致）
+	SHA256              string `json:"sha256"`                          // 二进制 SHA256（十六进制）
+	Signature           string `json:"signature"`                       // 对版本、平台与 SHA256 清单的 ed25519 签名（base64）
+	ReconnectTimeoutSec int    `json:"reconnect_timeout_sec,omitempty"` // 新进程未在该时间内重连则回滚，默认 60
+	AllowDowngrade      bool   `json:"allow_downgrade,omitempty"`       // 允许安装低于当前运行版本的二进制
+}
+
+// AgentUpdateResult agent.update / agent.update_status 的结果
+type AgentUpdateResult struct {
+	Success     bool   `json:"success"`                // 是否成功
+	Version     string `json:"version"`                // 当前运行版本
+	Platform    string `json:"platform"`               // 平台（os/arch）
+	State       string `json:"state,omitempty"`        // 最近一次升级状态：pending/committed/rolled_back
+	FromVersion string `json:"from_version,omitempty"` // 最近一次升级的原版本
+	ToVersion   string `json:"to_version,omitempty"`   // 最近一次升级的目标版本
+	UpdateID    string `json:"update_id,omitempty"`    // 最近一次升级 ID
+	Message     string `json:"message"`                // 消息
+	Error       string `json:"error,omitempty"`        // 错误信息
+}
+
+// --- 插件管理 ---
+
+// PluginInfo 插件信息
+type PluginInfo struct {
+	Name     string   `json:"name"`               // 插件名
+	Version  string   `json:"version,omitempty"`  // 插件版本
+	Protocol string   `json:"protocol,omitempty"` // exec/jsonrpc
+	Dir      string   `json:"dir"`                // 插件目录
+	Commands []string `json:"commands,omitempty"` // 已注册的命令类型
+	Skipped  []string `json:"skipped,omitempty"`  // 与已有处理器冲突而未注册的命令类型
+	Error    string   `json:"error,omitempty"`    // 清单无效时的错误
+}
+
+// PluginScanResult 插件目录扫描结果
+type PluginScanResult struct {
+	Loaded     []string `json:"loaded"`               // 新加载或更新的插件
+	Unloaded   []string `json:"unloaded,omitempty"`   // 已卸载的插件
+	Registered []string `json:"registered,omitempty"` // 新注册的命令类型
+	Removed    []string `json:"removed,omitempty"`    // 已注销的命令类型
+	Errors     []string `json:"errors,omitempty"`     // 无效插件的错误
+}
+
+// PluginResult plugin.list / plugin.rescan 命令的结果
+type PluginResult struct {
+	Success bool              `json:"success"`        // 是否成功
+	Dir     string            `json:"dir"`            // 插件目录
+	Plugins []PluginInfo      `json:"plugins"`        // 插件列表
+	Scan    *PluginScanResult `json:"scan,omitempty"` // 扫描结果（仅 plugin.rescan）
+	Message string            `json:"message"`        // 消息
+	Error   string            `json:"error,omitempty"`
+}
+
+// --- 定时任务 ---
+
+// 任务执行上报的事件类型（Agent -> Server）
+const (
+	EventTaskProgress = "task.progress"     // 执行进度与增量输出
+	EventTaskResult   = "task.result"       // 最终结果（离线时写入缓存，重连后补报）
+	EventTaskStats    = "report.task_stats" // 本地调度任务的每日统计（DailyStatsReport）
+)
+
+// Agent 本地调度使用的查询（Agent -> Server）
+const (
+	QueryTaskConfigPull  = "task.config.pull"  // 拉取本地调度任务配置（TaskConfigPull -> TaskConfigPullResponse）
+	QueryTaskOfflineSync = "task.offline.sync" // 同步本地执行结果（TaskOfflineSync -> OfflineSyncResponse）
+)
+
+// TaskAcceptResult task.execute 命令的结果（任务已在后台开始执行）
+type TaskAcceptResult struct {
+	Accepted    bool   `json:"accepted"`        // 是否已接受
+	ExecutionID string `json:"execution_id"`    // 执行 ID
+	Error       string `json:"error,omitempty"` // 拒绝原因
+}
+
+// TaskCancelParams task.cancel 命令的参数
+type TaskCancelParams struct {
+	ExecutionID string `json:"execution_id"` // 执行 ID
+}
+
+// TaskCancelResult task.cancel 命令的结果
+type TaskCancelResult struct {
+	Success     bool   `json:"success"`      // 是否已取消
+	ExecutionID string `json:"execution_id"` // 执行 ID
+	Message     string `json:"message"`      // 消息
+}
+
+// TaskProgressReport task.progress 事件载荷
+type TaskProgressReport struct {
+	ClientID string                 `json:"client_id"` // 客户端 ID
+	Progress *protocol.TaskProgress `json:"progress"`  // 执行进度
+}
+
+// TaskResultReport task.result 事件载荷
+type TaskResultReport struct {
+	ClientID string               `json:"client_id"` // 客户端 ID
+	Result   *protocol.TaskResult `json:"result"`    // 执行结果
+}
+
+// TaskConfigPushResult task.config.push 命令的结果
+type TaskConfigPushResult struct {
+	Success       bool  `json:"success"`        // 是否成功
+	ConfigVersion int64 `json:"config_version"` // Agent 当前的配置版本
+	TaskCount     int   `json:"task_count"`     // 已调度的任务数
+}
+
+// TaskOfflineSync task.offline.sync 查询的载荷：本地调度产生、尚未同步的执行结果
+type TaskOfflineSync struct {
+	Request *protocol.OfflineSyncRequest `json:"request"` // 同步请求
+	Results []*protocol.TaskResult       `json:"results"` // 执行结果（TaskId 为服务器任务 ID）
 }
 
 // ============================================================================
@@ -270,10 +462,16 @@ type CallbackPayload struct {
 
 // MultiCommandRequest HTTP请求结构 - 多播命令（同时下发到多个客户端）
 type MultiCommandRequest struct {
-	ClientIDs   []string        `json:"client_ids" binding:"required,min=1"` // 目标客户端列表
-	CommandType string          `json:"command_type" binding:"required"`     // 命令类型
-	Payload     json.RawMessage `json:"payload"`                             // 命令参数
-	Timeout     int             `json:"timeout,omitempty"`                   // 超时时间（秒），默认30s
+	ClientIDs   []string          `json:"client_ids" binding:"required,min=1"` // 目标客户端列表
+	CommandType string            `json:"command_type" binding:"required"`     // 命令类型
+	Payload     json.RawMessage   `json:"payload"`                             // 命令参数
+	Timeout     int               `json:"timeout,omitempty"`                   // 超时时间（秒），默认30s
+	Aggregate   *AggregateOptions `json:"aggregate,omitempty"`                 // 结果聚合选项（为空时不聚合）
+
+	// 定时执行（指定任一项时创建计划，通过 /api/schedules 管理）
+	RunAt         *time.Time `json:"run_at,omitempty"`         // 一次性执行时间
+	Cron          string     `json:"cron,omitempty"`           // 周期执行（6 段 cron 表达式，含秒）
+	MisfirePolicy string     `json:"misfire_policy,omitempty"` // 错过执行时的处理：skip（默认）/catch_up
 }
 
 // ClientCommandResult 单个客户端的命令执行结果
@@ -296,6 +494,7 @@ type MultiCommandResponse struct {
 	Results      []*ClientCommandResult `json:"results"`            // 各客户端的结果
 	Message      string                 `json:"message"`            // 摘要信息
 	Status       string                 `json:"status,omitempty"`   // 任务状态（running/completed/cancelled）
+	Aggregate    *ResultAggregate       `json:"aggregate,omitempty"` // 结果聚合（请求指定 aggregate 时返回）
 }
 
 // ============================================================================
@@ -351,6 +550,131 @@ type NetworkSpeedResult struct {
 	Count      int                `json:"count"`      // 网卡数量
 }
 
+// ============================================================================
+// 网络诊断相关结构
+// ============================================================================
+
+// NetworkPingParams network.ping 命令的参数
+type NetworkPingParams struct {
+	Target     string `json:"target"`                // 目标主机名或 IP
+	Count      int    `json:"count,omitempty"`       // 发送次数，默认 4，最大 100
+	IntervalMs int    `json:"interval_ms,omitempty"` // 发送间隔（毫秒），默认 1000，最小 200
+	TimeoutMs  int    `json:"timeout_ms,omitempty"`  // 单次等待超时（毫秒），默认 2000
+	Size       int    `json:"size,omitempty"`        // 载荷大小（字节），默认 56
+	Mode       string `json:"mode,omitempty"`        // icmp（raw socket，需要 root）/udp（非特权 ICMP socket）/空表示自动
+}
+
+// NetworkPingResult network.ping 命令的结果
+type NetworkPingResult struct {
+	Success     bool      `json:"success"`               // 是否至少收到一个回复
+	Target      string    `json:"target"`                // 目标
+	ResolvedIP  string    `json:"resolved_ip,omitempty"` // 解析后的 IP
+	Mode        string    `json:"mode,omitempty"`        // 实际使用的模式
+	Sent        int       `json:"sent"`                  // 发送数
+	Received    int       `json:"received"`              // 接收数
+	LossPercent float64   `json:"loss_percent"`          // 丢包率（%）
+	MinMs       float64   `json:"min_ms"`                // 最小 RTT（毫秒）
+	AvgMs       float64   `json:"avg_ms"`                // 平均 RTT（毫秒）
+	MaxMs       float64   `json:"max_ms"`                // 最大 RTT（毫秒）
+	StddevMs    float64   `json:"stddev_ms"`             // RTT 标准差（毫秒）
+	RTTs        []float64 `json:"rtts,omitempty"`        // 每次 RTT（毫秒，-1 表示超时）
+	Error       string    `json:"error,omitempty"`       // 错误信息
+}
+
+// NetworkTraceParams network.trace 命令的参数
+type NetworkTraceParams struct {
+	Target    string `json:"target"`               // 目标主机名或 IP
+	MaxHops   int    `json:"max_hops,omitempty"`   // 最大跳数，默认 30
+	Queries   int    `json:"queries,omitempty"`    // 每跳探测次数，默认 3
+	TimeoutMs int    `json:"timeout_ms,omitempty"` // 单次探测超时（毫秒），默认 1000
+}
+
+// NetworkTraceHop 路由追踪单跳信息
+type NetworkTraceHop struct {
+	TTL      int       `json:"ttl"`                // 跳数
+	Address  string    `json:"address,omitempty"`  // 响应地址（空表示全部超时）
+	Hostname string    `json:"hostname,omitempty"` // 反向解析的主机名
+	RTTs     []float64 `json:"rtts"`               // 每次探测 RTT（毫秒，-1 表示超时）
+	AvgMs    float64   `json:"avg_ms"`             // 平均 RTT（毫秒）
+	Loss     int       `json:"loss"`               // 超时次数
+}
+
+// NetworkTraceResult network.trace 命令的结果
+type NetworkTraceResult struct {
+	Success    bool              `json:"success"`               // 是否到达目标
+	Target     string            `json:"target"`                // 目标
+	ResolvedIP string            `json:"resolved_ip,omitempty"` // 解析后的 IP
+	Hops       []NetworkTraceHop `json:"hops"`                  // 路由跳
+	Error      string            `json:"error,omitempty"`       // 错误信息
+}
+
+// NetworkTCPParams network.tcp 命令的参数
+type NetworkTCPParams struct {
+	Host      string `json:"host"`                 // 目标主机名或 IP
+	Port      int    `json:"port"`                 // 目标端口
+	Count     int    `json:"count,omitempty"`      // 连接次数，默认 3，最大 100
+	TimeoutMs int    `json:"timeout_ms,omitempty"` // 单次连接超时（毫秒），默认 3000
+}
+
+// NetworkTCPResult network.tcp 命令的结果
+type NetworkTCPResult struct {
+	Success    bool      `json:"success"`               // 是否至少成功连接一次
+	Address    string    `json:"address"`               // 目标地址（host:port）
+	ResolvedIP string    `json:"resolved_ip,omitempty"` // 实际连接的 IP
+	Attempts   int       `json:"attempts"`              // 尝试次数
+	Successes  int       `json:"successes"`             // 成功次数
+	MinMs      float64   `json:"min_ms"`                // 最小连接耗时（毫秒）
+	AvgMs      float64   `json:"avg_ms"`                // 平均连接耗时（毫秒）
+	MaxMs      float64   `json:"max_ms"`                // 最大连接耗时（毫秒）
+	Times      []float64 `json:"times,omitempty"`       // 每次连接耗时（毫秒，-1 表示失败）
+	Error      string    `json:"error,omitempty"`       // 最后一次失败原因
+}
+
+// NetworkHTTPParams network.http 命令的参数
+type NetworkHTTPParams struct {
+	URL             string            `json:"url"`                        // 目标 URL
+	Method          string            `json:"method,omitempty"`           // 请求方法，默认 GET
+	Headers         map[string]string `json:"headers,omitempty"`          // 请求头
+	TimeoutMs       int               `json:"timeout_ms,omitempty"`       // 总超时（毫秒），默认 10000
+	InsecureTLS     bool              `json:"insecure_tls,omitempty"`     // 跳过 TLS 证书校验
+	FollowRedirects bool              `json:"follow_redirects,omitempty"` // 是否跟随重定向
+	ExpectStatus    int               `json:"expect_status,omitempty"`    // 期望状态码（0 表示 2xx/3xx 即成功）
+}
+
+// NetworkHTTPResult network.http 命令的结果（耗时均为毫秒）
+type NetworkHTTPResult struct {
+	Success    bool    `json:"success"`               // 是否成功
+	URL        string  `json:"url"`                   // 目标 URL
+	StatusCode int     `json:"status_code,omitempty"` // HTTP 状态码
+	RemoteAddr string  `json:"remote_addr,omitempty"` // 实际连接地址
+	DNSMs      float64 `json:"dns_ms"`                // DNS 解析耗时
+	ConnectMs  float64 `json:"connect_ms"`            // TCP 连接耗时
+	TLSMs      float64 `json:"tls_ms"`                // TLS 握手耗时
+	TTFBMs     float64 `json:"ttfb_ms"`               // 请求发出到首字节耗时
+	TotalMs    float64 `json:"total_ms"`              // 总耗时
+	BodyBytes  int64   `json:"body_bytes"`            // 响应体大小
+	TLSVersion string  `json:"tls_version,omitempty"` // TLS 版本
+	Error      string  `json:"error,omitempty"`       // 错误信息
+}
+
+// NetworkDNSParams network.dns 命令的参数
+type NetworkDNSParams struct {
+	Name      string `json:"name"`                 // 查询的域名（PTR 时为 IP）
+	Type      string `json:"type,omitempty"`       // 记录类型：A/AAAA/CNAME/MX/TXT/NS/PTR，默认 A
+	Server    string `json:"server,omitempty"`     // DNS 服务器（host 或 host:port，空表示系统默认）
+	TimeoutMs int    `json:"timeout_ms,omitempty"` // 超时（毫秒），默认 5000
+}
+
+// NetworkDNSResult network.dns 命令的结果
+type NetworkDNSResult struct {
+	Success    bool     `json:"success"`          // 是否成功
+	Name       string   `json:"name"`             // 查询的域名
+	Type       string   `json:"type"`             // 记录类型
+	Server     string   `json:"server,omitempty"` // 使用的 DNS 服务器
+	Records    []string `json:"records"`          // 记录值
+	DurationMs float64  `json:"duration_ms"`      // 解析耗时（毫秒）
+	Error      string   `json:"error,omitempty"`  // 错误信息
+}
 // ============================================================================
 // 硬件信息相关结构
 // ============================================================================
@@ -984,6 +1308,64 @@ type ProcessReportResult struct {
 	Error   string `json:"error,omitempty"`
 }
 
+// ProcessListParams process.list 命令的参数
+type ProcessListParams struct {
+	Filter      string `json:"filter,omitempty"`        // 按进程名/命令行过滤（子串匹配，/re/ 形式为正则）
+	User        string `json:"user,omitempty"`          // 按用户名过滤
+	Status      string `json:"status,omitempty"`        // 按状态过滤（running/sleeping/zombie/...）
+	PPID        int    `json:"ppid,omitempty"`          // 只返回指定父进程的子进程
+	SortBy      string `json:"sort_by,omitempty"`       // 排序字段：pid/name/cpu/memory/start_time/threads，默认 pid
+	Order       string `json:"order,omitempty"`         // asc/desc，默认 cpu/memory 为 desc，其余为 asc
+	Offset      int    `json:"offset,omitempty"`        // 分页偏移
+	Limit       int    `json:"limit,omitempty"`         // 分页大小，默认 100，最大 1000
+	CPUSampleMs int    `json:"cpu_sample_ms,omitempty"` // CPU 采样间隔（毫秒），0 表示使用进程生命周期平均值
+}
+
+// ProcessListItem 进程列表项
+type ProcessListItem struct {
+	PID        int     `json:"pid"`
+	PPID       int     `json:"ppid"`
+	Name       string  `json:"name"`
+	Cmdline    string  `json:"cmdline"`
+	User       string  `json:"user"`
+	Status     string  `json:"status"`
+	StartTime  string  `json:"start_time"`
+	Threads    int     `json:"threads"`
+	CPUPercent float64 `json:"cpu_percent"`
+	MemoryMB   float64 `json:"memory_mb"`
+	MemoryPct  float64 `json:"memory_pct"`
+}
+
+// ProcessListResult process.list 命令的结果
+type ProcessListResult struct {
+	Success   bool              `json:"success"`
+	Processes []ProcessListItem `json:"processes"`
+	Total     int               `json:"total"` // 过滤后的总数（分页前）
+	Offset    int               `json:"offset"`
+	Limit     int               `json:"limit"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// ProcessKillParams process.kill 命令的参数
+type ProcessKillParams struct {
+	PID         int    `json:"pid"`                    // 目标进程
+	Signal      string `json:"signal,omitempty"`       // 信号名（TERM/KILL/HUP/INT/QUIT/USR1/USR2/STOP/CONT）或编号，默认 TERM
+	Tree        bool   `json:"tree,omitempty"`         // 是否连同所有子孙进程一起终止
+	WaitSeconds int    `json:"wait_seconds,omitempty"` // 等待进程退出的超时（秒），0 表示不等待，最大 300
+	ForceAfter  bool   `json:"force_after,omitempty"`  // 等待超时后对仍存活的进程发送 KILL
+}
+
+// ProcessKillResult process.kill 命令的结果
+type ProcessKillResult struct {
+	Success   bool   `json:"success"`             // 所有目标进程均已发送信号（且在等待时均已退出）
+	Signal    string `json:"signal"`              // 实际发送的信号
+	Signaled  []int  `json:"signaled"`            // 已发送信号的 PID
+	Skipped   []int  `json:"skipped,omitempty"`   // 受策略保护而跳过的 PID
+	Remaining []int  `json:"remaining,omitempty"` // 等待超时后仍存活的 PID
+	Forced    []int  `json:"forced,omitempty"`    // 超时后被强制 KILL 的 PID
+	Message   string `json:"message,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
 // ============================================================================
 // 容器采集和上报相关结构
 // ============================================================================
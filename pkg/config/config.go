@@ -33,6 +33,15 @@ type ServerConfig struct {
 	// 数据库配置
 	Database DatabaseSettings `mapstructure:"database"`
 
+	// Agent 自升级配置
+	AgentUpdate AgentUpdateSettings `mapstructure:"agent_update"`
+
+	// 主题事件配置
+	Events EventSettings `mapstructure:"events"`
+
+	// 任务定义 Git 同步配置
+	TaskSync TaskSyncSettings `mapstructure:"task_sync"`
+
 	// 日志配置
 	Log LogSettings `mapstructure:"log"`
 }
@@ -123,6 +132,43 @@ type BatchSettings struct {
 	MaxRetries int `mapstructure:"max_retries"`
 	// 重试间隔（秒）
 	RetryInterval int `mapstructure:"retry_interval"`
+	// 启动时继续执行重启前未完成的任务（否则标记为 interrupted，可手动恢复）
+	ResumeOnStartup bool `mapstructure:"resume_on_startup"`
+}
+
+// AgentUpdateSettings Agent 自升级设置
+type AgentUpdateSettings struct {
+	// ed25519 签名私钥（base64 或文件路径），为空时登记升级包必须自带签名
+	SigningKey string `mapstructure:"signing_key"`
+}
+
+// EventSettings 主题事件设置
+type EventSettings struct {
+	// 每个主题保留的最近事件数（供晚到的订阅者回放）
+	Retention int `mapstructure:"retention"`
+}
+
+// TaskSyncSettings 任务定义 Git 同步设置
+type TaskSyncSettings struct {
+	// 仓库地址（为空时不同步）
+	RepoURL string `mapstructure:"repo_url"`
+	// 分支（为空时使用默认分支）
+	Branch string `mapstructure:"branch"`
+	// 仓库内任务定义所在目录，读取其中（含子目录）的 .yaml/.yml 文件
+	Path string `mapstructure:"path"`
+	// 同步间隔（秒）
+	Interval int `mapstructure:"interval"`
+	// 删除仓库中不存在的任务与分组
+	Prune bool `mapstructure:"prune"`
+	// 认证方式: none, ssh, token, basic
+	AuthType string `mapstructure:"auth_type"`
+	// SSH 私钥内容（auth_type=ssh）
+	SSHKey string `mapstructure:"ssh_key"`
+	// Access Token（auth_type=token）
+	Token string `mapstructure:"token"`
+	// 用户名与密码（auth_type=basic）
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
 }
 
 // LogSettings 日志设置
@@ -219,6 +265,12 @@ func DefaultConfig() *ServerConfig {
 			MaxRetries:     2,
 			RetryInterval:  1,
 		},
+		Events: EventSettings{
+			Retention: 100,
+		},
+		TaskSync: TaskSyncSettings{
+			Interval: 300,
+		},
 		Log: LogSettings{
 			Level:  "info",
 			Format: "text",
@@ -386,6 +438,13 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("batch.job_timeout", defaults.Batch.JobTimeout)
 	v.SetDefault("batch.max_retries", defaults.Batch.MaxRetries)
 	v.SetDefault("batch.retry_interval", defaults.Batch.RetryInterval)
+	v.SetDefault("batch.resume_on_startup", defaults.Batch.ResumeOnStartup)
+
+	// Events
+	v.SetDefault("events.retention", defaults.Events.Retention)
+
+	// Task sync
+	v.SetDefault("task_sync.interval", defaults.TaskSync.Interval)
 
 	// Log
 	v.SetDefault("log.level", defaults.Log.Level)
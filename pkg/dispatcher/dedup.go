@@ -0,0 +1,106 @@
+package dispatcher
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/protocol"
+)
+
+// 去重默认值
+const (
+	DefaultDedupWindow     = 25 * time.Hour // 略大于 Agent 离线缓存的默认最长保留时间
+	DefaultDedupMaxEntries = 100000
+)
+
+// dedupEntry 去重记录（按记录时间先后排列）
+type dedupEntry struct {
+	key string
+	at  time.Time
+}
+
+// Deduplicator 按（发送方, msg_id）对消息去重
+// Agent 离线缓存的消息在重连后重放，可能与断线前已经送达的消息重复；
+// 窗口期内重复的消息不再交给处理器，直接返回成功（不带结果）
+type Deduplicator struct {
+	window     time.Duration
+	maxEntries int
+
+	mu    sync.Mutex
+	seen  map[string]time.Time
+	order []dedupEntry
+	head  int
+
+	duplicates atomic.Int64
+}
+
+// NewDeduplicator 创建去重器，window 为去重窗口，maxEntries 为最多记录的消息数
+func NewDeduplicator(window time.Duration, maxEntries int) *Deduplicator {
+	if window <= 0 {
+		window = DefaultDedupWindow
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultDedupMaxEntries
+	}
+	return &Deduplicator{
+		window:     window,
+		maxEntries: maxEntries,
+		seen:       make(map[string]time.Time),
+	}
+}
+
+// Seen 判断消息是否在窗口期内出现过，未出现过时记录下来
+// msgID 为空的消息不去重
+func (d *Deduplicator) Seen(senderID, msgID string) bool {
+	if msgID == "" {
+		return false
+	}
+	key := senderID + "/" + msgID
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.evictLocked(now)
+	if _, ok := d.seen[key]; ok {
+		d.duplicates.Add(1)
+		return true
+	}
+	d.seen[key] = now
+	d.order = append(d.order, dedupEntry{key: key, at: now})
+	return false
+}
+
+// evictLocked 淘汰过期或超出容量的记录
+func (d *Deduplicator) evictLocked(now time.Time) {
+	for d.head < len(d.order) {
+		e := d.order[d.head]
+		if len(d.seen) < d.maxEntries && now.Sub(e.at) < d.window {
+			break
+		}
+		delete(d.seen, e.key)
+		d.order[d.head] = dedupEntry{}
+		d.head++
+	}
+	// 已淘汰部分过半时压缩队列
+	if d.head > 0 && d.head*2 >= len(d.order) {
+		d.order = append(d.order[:0], d.order[d.head:]...)
+		d.head = 0
+	}
+}
+
+// Duplicates 返回已拦截的重复消息数
+func (d *Deduplicator) Duplicates() int64 {
+	return d.duplicates.Load()
+}
+
+// Wrap 包装消息处理器，重复消息不交给 handler 处理
+func (d *Deduplicator) Wrap(handler MessageHandler) MessageHandler {
+	return MessageHandlerFunc(func(ctx context.Context, msg *protocol.DataMessage) (*protocol.DataMessage, error) {
+		if d.Seen(msg.SenderId, msg.MsgId) {
+			return nil, nil
+		}
+		return handler.OnMessage(ctx, msg)
+	})
+}
@@ -0,0 +1,45 @@
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/voilet/quic-flow/pkg/protocol"
+)
+
+func TestDeduplicator(t *testing.T) {
+	d := NewDeduplicator(time.Hour, 3)
+	calls := 0
+	h := d.Wrap(MessageHandlerFunc(func(ctx context.Context, msg *protocol.DataMessage) (*protocol.DataMessage, error) {
+		calls++
+		return msg, nil
+	}))
+
+	msg := &protocol.DataMessage{MsgId: "m1", SenderId: "c1"}
+	resp, _ := h.OnMessage(context.Background(), msg)
+	assert.NotNil(t, resp)
+	resp, _ = h.OnMessage(context.Background(), msg)
+	assert.Nil(t, resp)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, int64(1), d.Duplicates())
+
+	// 不同发送方的相同 msg_id 不视为重复，空 msg_id 不去重
+	assert.False(t, d.Seen("c2", "m1"))
+	assert.False(t, d.Seen("c1", ""))
+	assert.False(t, d.Seen("c1", ""))
+
+	// 超出容量时淘汰最早的记录
+	for i := 2; i <= 4; i++ {
+		assert.False(t, d.Seen("c1", fmt.Sprintf("m%d", i)))
+	}
+	assert.False(t, d.Seen("c1", "m1"))
+	assert.True(t, d.Seen("c1", "m4"))
+
+	d = NewDeduplicator(10*time.Millisecond, 0)
+	assert.False(t, d.Seen("c1", "m1"))
+	time.Sleep(20 * time.Millisecond)
+	assert.False(t, d.Seen("c1", "m1"))
+}
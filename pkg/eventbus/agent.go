@@ -0,0 +1,175 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/protocol"
+)
+
+// syncTimeout 向服务器声明订阅的超时
+const syncTimeout = 10 * time.Second
+
+// MessageSender 向服务器发送消息（*client.Client 实现）
+type MessageSender interface {
+	IsConnected() bool
+	SendMessage(ctx context.Context, msg *protocol.DataMessage, waitAck bool, timeout time.Duration) (*protocol.AckMessage, error)
+}
+
+// AgentLink Agent 侧的事件链路
+// 发布的事件经 EVENT 消息上送到服务器总线；本地订阅的模式汇总后声明给服务器，
+// 服务器投递的事件发布到本地总线，由本地订阅者消费
+type AgentLink struct {
+	bus    *Bus
+	sender MessageSender
+	logger *monitoring.Logger
+
+	mu      sync.Mutex
+	refs    map[string]int // 订阅模式 -> 本地订阅数
+	syncMu  sync.Mutex     // 串行化订阅同步
+	syncSeq int64          // 最近一次同步的版本号
+}
+
+// NewAgentLink 创建 Agent 事件链路，retention 为本地总线每个主题保留的事件数
+func NewAgentLink(sender MessageSender, retention int, logger *monitoring.Logger) *AgentLink {
+	if logger == nil {
+		logger = monitoring.NewDefaultLogger()
+	}
+	return &AgentLink{
+		bus:    NewBus(retention, logger),
+		sender: sender,
+		logger: logger,
+		refs:   make(map[string]int),
+	}
+}
+
+// Bus 返回本地总线（保存服务器投递的事件）
+func (a *AgentLink) Bus() *Bus {
+	return a.bus
+}
+
+// Publish 发布事件到服务器总线，waitAck 为 true 时等待服务器确认并返回确认结果
+func (a *AgentLink) Publish(ctx context.Context, topic string, data json.RawMessage, waitAck bool, timeout time.Duration) (string, *protocol.AckMessage, error) {
+	if err := ValidateTopic(topic); err != nil {
+		return "", nil, err
+	}
+	if len(data) > 0 && !json.Valid(data) {
+		return "", nil, fmt.Errorf("event data is not valid JSON")
+	}
+	req, _ := json.Marshal(PublishRequest{Topic: topic, Data: data})
+	msg := newEventMessage(CmdEventPublish, req)
+	ack, err := a.sender.SendMessage(ctx, msg, waitAck, timeout)
+	return msg.MsgId, ack, err
+}
+
+// Subscribe 订阅服务器投递的事件
+// 新增的模式会同步声明给服务器；订阅关闭且模式不再被引用时从服务器取消
+func (a *AgentLink) Subscribe(patterns []string, buffer int, replay bool) (*Subscription, error) {
+	sub, err := a.bus.Subscribe(patterns, buffer, replay)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	changed := false
+	for _, p := range sub.Patterns {
+		if a.refs[p] == 0 {
+			changed = true
+		}
+		a.refs[p]++
+	}
+	a.mu.Unlock()
+
+	sub.onClose = func() {
+		a.mu.Lock()
+		changed := false
+		for _, p := range sub.Patterns {
+			if a.refs[p]--; a.refs[p] <= 0 {
+				delete(a.refs, p)
+				changed = true
+			}
+		}
+		a.mu.Unlock()
+		if changed {
+			go a.syncQuietly()
+		}
+	}
+	if changed {
+		go a.syncQuietly()
+	}
+	return sub, nil
+}
+
+// Patterns 返回当前声明给服务器的订阅模式
+func (a *AgentLink) Patterns() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	patterns := make([]string, 0, len(a.refs))
+	for p := range a.refs {
+		patterns = append(patterns, p)
+	}
+	sort.Strings(patterns)
+	return patterns
+}
+
+// Sync 向服务器声明当前的全部订阅模式（连接或重连后调用）
+func (a *AgentLink) Sync(ctx context.Context) error {
+	a.syncMu.Lock()
+	defer a.syncMu.Unlock()
+	// 版本号使用纳秒时间戳并保证递增，服务器据此忽略乱序到达的旧声明（Agent 重启后仍然递增）
+	seq := time.Now().UnixNano()
+	if seq <= a.syncSeq {
+		seq = a.syncSeq + 1
+	}
+	a.syncSeq = seq
+	req, _ := json.Marshal(SubscribeRequest{Patterns: a.Patterns(), Seq: seq})
+	ctx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+	_, err := a.sender.SendMessage(ctx, newEventMessage(CmdEventSubscribe, req), false, 0)
+	return err
+}
+
+// syncQuietly 同步订阅，未连接时跳过（重连后会重新同步）
+func (a *AgentLink) syncQuietly() {
+	if !a.sender.IsConnected() {
+		return
+	}
+	if err := a.Sync(context.Background()); err != nil {
+		a.logger.Warn("Failed to sync event subscriptions", "error", err)
+	}
+}
+
+// HandleDelivery 处理服务器投递的事件（event.deliver 载荷），保留原始事件 ID
+func (a *AgentLink) HandleDelivery(payload json.RawMessage) error {
+	var ev Event
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		return fmt.Errorf("invalid event delivery: %w", err)
+	}
+	_, err := a.bus.PublishEvent(&ev)
+	return err
+}
+
+// HandleMessage 处理服务器发来的 EVENT 消息，非事件投递时返回 false
+func (a *AgentLink) HandleMessage(msg *protocol.DataMessage) (bool, error) {
+	var env envelope
+	if err := json.Unmarshal(msg.Payload, &env); err != nil || env.CommandType != CmdEventDeliver {
+		return false, nil
+	}
+	return true, a.HandleDelivery(env.Payload)
+}
+
+// newEventMessage 构造 EVENT 消息
+func newEventMessage(commandType string, payload json.RawMessage) *protocol.DataMessage {
+	data, _ := json.Marshal(envelope{CommandType: commandType, Payload: payload})
+	return &protocol.DataMessage{
+		MsgId:   uuid.New().String(),
+		Type:    protocol.MessageType_MESSAGE_TYPE_EVENT,
+		Payload: data,
+	}
+}
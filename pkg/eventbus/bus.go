@@ -0,0 +1,372 @@
+// Package eventbus 提供基于主题的事件发布/订阅
+// 主题由点分隔的段组成（如 deploy.web.health），订阅模式中 * 匹配一段、# 匹配零或多段
+package eventbus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+)
+
+// 默认值
+const (
+	DefaultRetention = 100   // 每个主题保留的最近事件数
+	DefaultMaxTopics = 10000 // 保留事件的主题数上限，超出后淘汰最久未发布的主题
+	DefaultBuffer    = 256   // 订阅通道默认缓冲
+	maxTopicLength   = 255
+)
+
+// SourceServer 服务器发布的事件来源
+const SourceServer = "server"
+
+// 错误定义
+var (
+	ErrInvalidTopic   = errors.New("invalid topic")
+	ErrInvalidPattern = errors.New("invalid topic pattern")
+)
+
+// Event 主题事件
+type Event struct {
+	ID        string          `json:"id"`
+	Seq       uint64          `json:"seq"`    // 本地递增序号，可用于增量拉取
+	Topic     string          `json:"topic"`  // 主题
+	Source    string          `json:"source"` // 发布方：客户端 ID 或 server
+	Data      json.RawMessage `json:"data,omitempty"`
+	Timestamp int64           `json:"timestamp"` // 发布时间（毫秒）
+}
+
+// TopicInfo 主题统计
+type TopicInfo struct {
+	Topic         string    `json:"topic"`
+	Retained      int       `json:"retained"`  // 当前保留的事件数
+	Published     int64     `json:"published"` // 累计发布数
+	LastPublished time.Time `json:"last_published"`
+}
+
+// topic 主题状态：最近 N 个事件（环形缓冲）
+type topic struct {
+	events    []*Event
+	next      int
+	published int64
+	last      time.Time
+}
+
+func (t *topic) add(ev *Event, retention int) {
+	if len(t.events) < retention {
+		t.events = append(t.events, ev)
+	} else {
+		t.events[t.next] = ev
+		t.next = (t.next + 1) % retention
+	}
+	t.published++
+	t.last = time.Now()
+}
+
+// ordered 按发布顺序返回保留的事件
+func (t *topic) ordered() []*Event {
+	out := make([]*Event, 0, len(t.events))
+	out = append(out, t.events[t.next:]...)
+	return append(out, t.events[:t.next]...)
+}
+
+// Subscription 订阅，事件从 C 读取
+// 消费过慢时新事件会被丢弃（不阻塞发布方），丢弃数见 Dropped
+type Subscription struct {
+	ID       uint64
+	Patterns []string
+	C        <-chan *Event
+
+	ch      chan *Event
+	bus     *Bus
+	dropped atomic.Int64
+	once    sync.Once
+	onClose func()
+}
+
+// Dropped 返回因缓冲已满而丢弃的事件数
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close 取消订阅并关闭通道
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s.ID)
+		close(s.ch)
+		s.bus.mu.Unlock()
+		if s.onClose != nil {
+			s.onClose()
+		}
+	})
+}
+
+// matches 是否匹配任一订阅模式
+func (s *Subscription) matches(topic string) bool {
+	for _, p := range s.Patterns {
+		if Match(p, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// Bus 事件总线
+type Bus struct {
+	retention int
+	maxTopics int
+	logger    *monitoring.Logger
+
+	mu     sync.RWMutex
+	seq    uint64
+	topics map[string]*topic
+	subs   map[uint64]*Subscription
+	nextID uint64
+}
+
+// NewBus 创建事件总线，retention 为每个主题保留的最近事件数（<=0 使用默认值）
+func NewBus(retention int, logger *monitoring.Logger) *Bus {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	if logger == nil {
+		logger = monitoring.NewDefaultLogger()
+	}
+	return &Bus{
+		retention: retention,
+		maxTopics: DefaultMaxTopics,
+		logger:    logger,
+		topics:    make(map[string]*topic),
+		subs:      make(map[uint64]*Subscription),
+	}
+}
+
+// ValidateTopic 校验主题：非空的点分隔段，不含通配符和空白
+func ValidateTopic(name string) error {
+	if name == "" || len(name) > maxTopicLength {
+		return fmt.Errorf("%w: %q", ErrInvalidTopic, name)
+	}
+	for _, seg := range strings.Split(name, ".") {
+		if seg == "" || seg == "*" || seg == "#" || strings.ContainsAny(seg, " \t\r\n*#") {
+			return fmt.Errorf("%w: %q", ErrInvalidTopic, name)
+		}
+	}
+	return nil
+}
+
+// ValidatePattern 校验订阅模式：* 与 # 必须占据整段
+func ValidatePattern(pattern string) error {
+	if pattern == "" || len(pattern) > maxTopicLength {
+		return fmt.Errorf("%w: %q", ErrInvalidPattern, pattern)
+	}
+	for _, seg := range strings.Split(pattern, ".") {
+		if seg == "*" || seg == "#" {
+			continue
+		}
+		if seg == "" || strings.ContainsAny(seg, " \t\r\n*#") {
+			return fmt.Errorf("%w: %q", ErrInvalidPattern, pattern)
+		}
+	}
+	return nil
+}
+
+// Match 判断主题是否匹配模式
+func Match(pattern, topic string) bool {
+	return matchSegments(strings.Split(pattern, "."), strings.Split(topic, "."))
+}
+
+func matchSegments(pattern, topic []string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case "#":
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(topic); i++ {
+				if matchSegments(pattern[1:], topic[i:]) {
+					return true
+				}
+			}
+			return false
+		case "*":
+			if len(topic) == 0 {
+				return false
+			}
+		default:
+			if len(topic) == 0 || pattern[0] != topic[0] {
+				return false
+			}
+		}
+		pattern, topic = pattern[1:], topic[1:]
+	}
+	return len(topic) == 0
+}
+
+// Publish 发布事件，返回带 ID 与序号的事件
+func (b *Bus) Publish(topicName, source string, data json.RawMessage) (*Event, error) {
+	return b.PublishEvent(&Event{Topic: topicName, Source: source, Data: data})
+}
+
+// PublishEvent 发布事件；ID 与时间戳为空时自动生成，序号总是由本总线分配
+// 用于转发其他总线的事件时保留原始 ID
+func (b *Bus) PublishEvent(ev *Event) (*Event, error) {
+	if err := ValidateTopic(ev.Topic); err != nil {
+		return nil, err
+	}
+	if len(ev.Data) > 0 && !json.Valid(ev.Data) {
+		return nil, fmt.Errorf("event data is not valid JSON")
+	}
+	e := *ev
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	if e.Timestamp == 0 {
+		e.Timestamp = time.Now().UnixMilli()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	e.Seq = b.seq
+
+	t, ok := b.topics[e.Topic]
+	if !ok {
+		if len(b.topics) >= b.maxTopics {
+			b.evictOldest()
+		}
+		t = &topic{}
+		b.topics[e.Topic] = t
+	}
+	t.add(&e, b.retention)
+
+	for _, sub := range b.subs {
+		if !sub.matches(e.Topic) {
+			continue
+		}
+		select {
+		case sub.ch <- &e:
+		default:
+			if sub.dropped.Add(1) == 1 {
+				b.logger.Warn("Event subscriber is too slow, dropping events", "subscription", sub.ID, "patterns", sub.Patterns)
+			}
+		}
+	}
+	return &e, nil
+}
+
+// evictOldest 淘汰最久未发布的主题（调用方持有锁）
+func (b *Bus) evictOldest() {
+	var oldest string
+	var last time.Time
+	for name, t := range b.topics {
+		if oldest == "" || t.last.Before(last) {
+			oldest, last = name, t.last
+		}
+	}
+	delete(b.topics, oldest)
+}
+
+// Subscribe 按模式订阅；replay 为 true 时先投递已保留的匹配事件（按发布顺序）
+func (b *Bus) Subscribe(patterns []string, buffer int, replay bool) (*Subscription, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("%w: no pattern", ErrInvalidPattern)
+	}
+	for _, p := range patterns {
+		if err := ValidatePattern(p); err != nil {
+			return nil, err
+		}
+	}
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	sub := &Subscription{ID: b.nextID, Patterns: append([]string(nil), patterns...), bus: b}
+
+	var backlog []*Event
+	if replay {
+		backlog = b.recentLocked(sub.matches, 0, 0)
+	}
+	if len(backlog) > buffer {
+		buffer = len(backlog) + DefaultBuffer
+	}
+	sub.ch = make(chan *Event, buffer)
+	sub.C = sub.ch
+	for _, ev := range backlog {
+		sub.ch <- ev
+	}
+	b.subs[sub.ID] = sub
+	return sub, nil
+}
+
+// Handle 按模式订阅并在独立 goroutine 中逐个调用 fn，直到订阅关闭
+func (b *Bus) Handle(patterns []string, fn func(*Event)) (*Subscription, error) {
+	sub, err := b.Subscribe(patterns, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for ev := range sub.C {
+			fn(ev)
+		}
+	}()
+	return sub, nil
+}
+
+// Recent 返回匹配模式、序号大于 after 的保留事件（按发布顺序，limit<=0 不限制，超出时保留最新的）
+func (b *Bus) Recent(pattern string, after uint64, limit int) ([]*Event, error) {
+	if err := ValidatePattern(pattern); err != nil {
+		return nil, err
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.recentLocked(func(t string) bool { return Match(pattern, t) }, after, limit), nil
+}
+
+func (b *Bus) recentLocked(match func(string) bool, after uint64, limit int) []*Event {
+	var out []*Event
+	for name, t := range b.topics {
+		if !match(name) {
+			continue
+		}
+		for _, ev := range t.ordered() {
+			if ev.Seq > after {
+				out = append(out, ev)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Seq < out[j].Seq })
+	if limit > 0 && len(out) > limit {
+		out = out[len(out)-limit:]
+	}
+	return out
+}
+
+// Topics 列出保留事件的主题
+func (b *Bus) Topics() []TopicInfo {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	list := make([]TopicInfo, 0, len(b.topics))
+	for name, t := range b.topics {
+		list = append(list, TopicInfo{Topic: name, Retained: len(t.events), Published: t.published, LastPublished: t.last})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Topic < list[j].Topic })
+	return list
+}
+
+// Subscribers 返回当前订阅数
+func (b *Bus) Subscribers() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs)
+}
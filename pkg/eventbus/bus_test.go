@@ -0,0 +1,222 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/protocol"
+	"github.com/voilet/quic-flow/pkg/router"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, topic string
+		want           bool
+	}{
+		{"deploy.web.health", "deploy.web.health", true},
+		{"deploy.*.health", "deploy.web.health", true},
+		{"deploy.*.health", "deploy.web.api.health", false},
+		{"deploy.#", "deploy", true},
+		{"deploy.#", "deploy.web.health", true},
+		{"deploy.#.health", "deploy.web.api.health", true},
+		{"deploy.#.health", "deploy.health", true},
+		{"#", "anything.at.all", true},
+		{"*", "a.b", false},
+		{"deploy.web", "deploy.web.health", false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, Match(c.pattern, c.topic), "%s ~ %s", c.pattern, c.topic)
+	}
+
+	assert.Error(t, ValidateTopic("deploy.*"))
+	assert.Error(t, ValidateTopic("deploy..web"))
+	assert.NoError(t, ValidatePattern("deploy.*.#"))
+	assert.Error(t, ValidatePattern("deploy.we*"))
+}
+
+func TestRetentionAndReplay(t *testing.T) {
+	bus := NewBus(3, monitoring.NewDefaultLogger())
+	for i := 1; i <= 5; i++ {
+		_, err := bus.Publish("app.a", "c1", json.RawMessage(fmt.Sprintf(`{"n":%d}`, i)))
+		require.NoError(t, err)
+	}
+	_, err := bus.Publish("app.b", SourceServer, nil)
+	require.NoError(t, err)
+
+	recent, err := bus.Recent("app.a", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, recent, 3)
+	assert.JSONEq(t, `{"n":3}`, string(recent[0].Data))
+	assert.JSONEq(t, `{"n":5}`, string(recent[2].Data))
+
+	recent, err = bus.Recent("app.*", recent[1].Seq, 0)
+	require.NoError(t, err)
+	require.Len(t, recent, 2)
+	assert.Equal(t, "app.b", recent[1].Topic)
+
+	// 晚到的订阅者先收到保留的事件，再收到新事件
+	sub, err := bus.Subscribe([]string{"app.#"}, 0, true)
+	require.NoError(t, err)
+	defer sub.Close()
+	_, err = bus.Publish("app.c", SourceServer, nil)
+	require.NoError(t, err)
+	var topics []string
+	for i := 0; i < 5; i++ {
+		topics = append(topics, (<-sub.C).Topic)
+	}
+	assert.Equal(t, []string{"app.a", "app.a", "app.a", "app.b", "app.c"}, topics)
+
+	topicsInfo := bus.Topics()
+	require.Len(t, topicsInfo, 3)
+	assert.Equal(t, int64(5), topicsInfo[0].Published)
+	assert.Equal(t, 3, topicsInfo[0].Retained)
+}
+
+func TestSlowSubscriberDropsEvents(t *testing.T) {
+	bus := NewBus(0, nil)
+	sub, err := bus.Subscribe([]string{"#"}, 1, false)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := bus.Publish("x", SourceServer, nil)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int64(2), sub.Dropped())
+	sub.Close()
+	<-sub.C // 缓冲中的事件
+	_, ok := <-sub.C
+	assert.False(t, ok)
+	assert.Equal(t, 0, bus.Subscribers())
+}
+
+// fakeSender 记录投递给各客户端的事件
+type fakeSender struct {
+	mu        sync.Mutex
+	delivered map[string][]string
+}
+
+func (s *fakeSender) SendTo(clientID string, msg *protocol.DataMessage) error {
+	var env envelope
+	if err := json.Unmarshal(msg.Payload, &env); err != nil {
+		return err
+	}
+	var ev Event
+	if err := json.Unmarshal(env.Payload, &ev); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delivered[clientID] = append(s.delivered[clientID], ev.Topic)
+	return nil
+}
+
+func (s *fakeSender) get(clientID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.delivered[clientID]
+}
+
+func TestRelay(t *testing.T) {
+	bus := NewBus(0, nil)
+	sender := &fakeSender{delivered: make(map[string][]string)}
+	relay := NewRelay(bus, sender, nil)
+	defer relay.Stop()
+
+	rt := router.NewRouter(nil)
+	relay.Register(rt)
+	exec := func(clientID, cmd, payload string) error {
+		ctx := router.WithCommandContext(context.Background(), cmd, "m", clientID)
+		_, err := rt.ExecuteWithContext(ctx, cmd, []byte(payload))
+		return err
+	}
+	require.NoError(t, exec("c1", CmdEventSubscribe, `{"patterns":["deploy.#"],"seq":2}`))
+	require.NoError(t, exec("c2", CmdEventSubscribe, `{"patterns":["deploy.*.health"]}`))
+	// 乱序到达的旧声明被忽略
+	require.NoError(t, exec("c1", CmdEventSubscribe, `{"patterns":["other"],"seq":1}`))
+	assert.Equal(t, []string{"deploy.#"}, relay.Subscriptions()["c1"])
+
+	// 载荷中的 client_id 被忽略，发送方取自连接会话
+	require.NoError(t, exec("c3", CmdEventSubscribe, `{"client_id":"c2","patterns":[]}`))
+	assert.Equal(t, []string{"deploy.*.health"}, relay.Subscriptions()["c2"])
+	assert.Error(t, exec("", CmdEventSubscribe, `{"patterns":["deploy.#"]}`))
+
+	// c1 发布的事件不回送给 c1
+	require.NoError(t, exec("c1", CmdEventPublish, `{"client_id":"c2","topic":"deploy.web.health","data":{"ok":true}}`))
+	_, err := bus.Publish("deploy.web", SourceServer, nil)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return len(sender.get("c1")) == 1 && len(sender.get("c2")) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"deploy.web"}, sender.get("c1"))
+	assert.Equal(t, []string{"deploy.web.health"}, sender.get("c2"))
+
+	assert.ErrorIs(t, exec("c1", CmdEventPublish, `{"topic":"bad.*"}`), ErrInvalidTopic)
+}
+
+// fakeServer 模拟服务器：执行 Agent 上送的事件命令
+type fakeServer struct {
+	router *router.Router
+}
+
+func (s *fakeServer) IsConnected() bool { return true }
+
+func (s *fakeServer) SendMessage(ctx context.Context, msg *protocol.DataMessage, waitAck bool, timeout time.Duration) (*protocol.AckMessage, error) {
+	var env envelope
+	if err := json.Unmarshal(msg.Payload, &env); err != nil {
+		return nil, err
+	}
+	ctx = router.WithCommandContext(ctx, env.CommandType, msg.MsgId, "agent-1")
+	if _, err := s.router.ExecuteWithContext(ctx, env.CommandType, env.Payload); err != nil {
+		return &protocol.AckMessage{MsgId: msg.MsgId, Status: protocol.AckStatus_ACK_STATUS_FAILURE, Error: err.Error()}, nil
+	}
+	return &protocol.AckMessage{MsgId: msg.MsgId, Status: protocol.AckStatus_ACK_STATUS_SUCCESS}, nil
+}
+
+func TestAgentLink(t *testing.T) {
+	serverBus := NewBus(0, nil)
+	relay := NewRelay(serverBus, &fakeSender{delivered: make(map[string][]string)}, nil)
+	defer relay.Stop()
+	rt := router.NewRouter(nil)
+	relay.Register(rt)
+
+	link := NewAgentLink(&fakeServer{router: rt}, 0, nil)
+
+	// 发布到服务器总线
+	_, _, err := link.Publish(context.Background(), "deploy.web.health", json.RawMessage(`{"ok":true}`), true, time.Second)
+	require.NoError(t, err)
+	recent, err := serverBus.Recent("#", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, recent, 1)
+	assert.Equal(t, "agent-1", recent[0].Source)
+
+	// 本地订阅汇总后声明给服务器
+	sub1, err := link.Subscribe([]string{"config.#"}, 0, false)
+	require.NoError(t, err)
+	sub2, err := link.Subscribe([]string{"config.#", "release.*"}, 0, false)
+	require.NoError(t, err)
+	require.NoError(t, link.Sync(context.Background()))
+	assert.ElementsMatch(t, []string{"config.#", "release.*"}, relay.Subscriptions()["agent-1"])
+
+	sub2.Close()
+	assert.Equal(t, []string{"config.#"}, link.Patterns())
+
+	// 服务器投递的事件进入本地总线
+	handled, err := link.HandleMessage(&protocol.DataMessage{
+		Payload: []byte(`{"command_type":"event.deliver","payload":{"id":"e1","topic":"config.updated","source":"server"}}`),
+	})
+	require.NoError(t, err)
+	assert.True(t, handled)
+	ev := <-sub1.C
+	assert.Equal(t, "e1", ev.ID)
+	assert.Equal(t, "config.updated", ev.Topic)
+
+	handled, _ = link.HandleMessage(&protocol.DataMessage{Payload: []byte(`{"command_type":"other"}`)})
+	assert.False(t, handled)
+}
@@ -0,0 +1,238 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	pkgerrors "github.com/voilet/quic-flow/pkg/errors"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/protocol"
+	"github.com/voilet/quic-flow/pkg/router"
+)
+
+// Agent 与服务器之间的事件命令（EVENT 消息中的 command_type）
+const (
+	CmdEventPublish   = "event.publish"   // Agent 发布事件到服务器总线
+	CmdEventSubscribe = "event.subscribe" // Agent 声明订阅模式（全量替换）
+	CmdEventDeliver   = "event.deliver"   // 服务器向订阅的 Agent 投递事件
+)
+
+// 转发限制
+const (
+	relayBuffer      = 4096
+	relayConcurrency = 64
+	maxAgentPatterns = 100
+)
+
+// PublishRequest event.publish 载荷（发布方取自连接会话）
+type PublishRequest struct {
+	Topic string          `json:"topic"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// SubscribeRequest event.subscribe 载荷（订阅方取自连接会话）
+type SubscribeRequest struct {
+	Patterns []string `json:"patterns"`
+	Seq      int64    `json:"seq,omitempty"` // 声明版本，小于已生效版本的声明被忽略
+}
+
+// envelope EVENT 消息载荷（与服务器/客户端的消息路由格式一致）
+type envelope struct {
+	CommandType string          `json:"command_type"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// Sender 向客户端发送消息（*server.Server 实现）
+type Sender interface {
+	SendTo(clientID string, msg *protocol.DataMessage) error
+}
+
+// Relay 服务器侧的 Agent 订阅管理：记录各 Agent 的订阅模式，
+// 将总线上匹配的事件投递给订阅的 Agent（不回送给发布方自身）
+// Agent 每次连接后重新声明全部订阅，断开期间的事件不会补投
+type Relay struct {
+	bus    *Bus
+	sender Sender
+	logger *monitoring.Logger
+
+	mu      sync.RWMutex
+	clients map[string][]string // 客户端 ID -> 订阅模式
+	seqs    map[string]int64    // 客户端 ID -> 已生效的声明版本
+
+	sub *Subscription
+	wg  sync.WaitGroup
+}
+
+// NewRelay 创建 Agent 订阅转发器并开始转发
+func NewRelay(bus *Bus, sender Sender, logger *monitoring.Logger) *Relay {
+	if logger == nil {
+		logger = monitoring.NewDefaultLogger()
+	}
+	r := &Relay{
+		bus:     bus,
+		sender:  sender,
+		logger:  logger,
+		clients: make(map[string][]string),
+		seqs:    make(map[string]int64),
+	}
+	r.sub, _ = bus.Subscribe([]string{"#"}, relayBuffer, false)
+	r.wg.Add(1)
+	go r.forward()
+	return r
+}
+
+// SetSubscriptions 替换客户端的订阅模式，模式为空时取消该客户端的全部订阅
+// seq 不为 0 时，小于等于已生效版本的声明会被忽略（消息可能乱序处理）
+func (r *Relay) SetSubscriptions(clientID string, patterns []string, seq int64) error {
+	if clientID == "" {
+		return fmt.Errorf("client_id is required")
+	}
+	if len(patterns) > maxAgentPatterns {
+		return fmt.Errorf("too many patterns: %d (max %d)", len(patterns), maxAgentPatterns)
+	}
+	for _, p := range patterns {
+		if err := ValidatePattern(p); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if seq != 0 {
+		if seq <= r.seqs[clientID] {
+			return nil
+		}
+		r.seqs[clientID] = seq
+	}
+	if len(patterns) == 0 {
+		delete(r.clients, clientID)
+		return nil
+	}
+	r.clients[clientID] = append([]string(nil), patterns...)
+	return nil
+}
+
+// RemoveClient 清除客户端订阅
+func (r *Relay) RemoveClient(clientID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.clients, clientID)
+	delete(r.seqs, clientID)
+}
+
+// Subscriptions 返回各客户端的订阅模式
+func (r *Relay) Subscriptions() map[string][]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make(map[string][]string, len(r.clients))
+	for id, patterns := range r.clients {
+		out[id] = append([]string(nil), patterns...)
+	}
+	return out
+}
+
+// Stop 停止转发
+func (r *Relay) Stop() {
+	r.sub.Close()
+	r.wg.Wait()
+}
+
+// forward 将事件投递给订阅的客户端
+func (r *Relay) forward() {
+	defer r.wg.Done()
+	sem := make(chan struct{}, relayConcurrency)
+	for ev := range r.sub.C {
+		targets := r.targets(ev)
+		if len(targets) == 0 {
+			continue
+		}
+		payload, err := json.Marshal(ev)
+		if err != nil {
+			continue
+		}
+		data, _ := json.Marshal(envelope{CommandType: CmdEventDeliver, Payload: payload})
+
+		var wg sync.WaitGroup
+		for _, clientID := range targets {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(clientID string) {
+				defer func() { <-sem; wg.Done() }()
+				msg := &protocol.DataMessage{
+					MsgId:     uuid.New().String(),
+					SenderId:  SourceServer,
+					Type:      protocol.MessageType_MESSAGE_TYPE_EVENT,
+					Payload:   data,
+					Timestamp: time.Now().UnixMilli(),
+				}
+				if err := r.sender.SendTo(clientID, msg); errors.Is(err, pkgerrors.ErrClientNotConnected) {
+					r.logger.Debug("Subscribed agent is offline, event skipped", "client_id", clientID, "topic", ev.Topic)
+				} else if err != nil {
+					r.logger.Warn("Failed to deliver event to agent", "client_id", clientID, "topic", ev.Topic, "error", err)
+				}
+			}(clientID)
+		}
+		wg.Wait()
+	}
+}
+
+// targets 返回订阅了该事件主题的客户端（排除发布方）
+func (r *Relay) targets(ev *Event) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []string
+	for clientID, patterns := range r.clients {
+		if clientID == ev.Source {
+			continue
+		}
+		for _, p := range patterns {
+			if Match(p, ev.Topic) {
+				out = append(out, clientID)
+				break
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Register 在服务器消息路由器上注册 event.publish 与 event.subscribe
+// 发送方客户端 ID 取自路由上下文（router.WithCommandContext），不信任载荷中的字段
+func (r *Relay) Register(rt *router.Router) {
+	rt.Register(CmdEventPublish, func(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+		clientID := router.GetClientID(ctx)
+		if clientID == "" {
+			return nil, fmt.Errorf("invalid event publish: sender is unknown")
+		}
+		var req PublishRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return nil, fmt.Errorf("invalid event publish: %w", err)
+		}
+		ev, err := r.bus.Publish(req.Topic, clientID, req.Data)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(map[string]interface{}{"id": ev.ID, "seq": ev.Seq})
+	})
+	rt.Register(CmdEventSubscribe, func(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+		clientID := router.GetClientID(ctx)
+		if clientID == "" {
+			return nil, fmt.Errorf("invalid event subscribe: sender is unknown")
+		}
+		var req SubscribeRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return nil, fmt.Errorf("invalid event subscribe: %w", err)
+		}
+		if err := r.SetSubscriptions(clientID, req.Patterns, req.Seq); err != nil {
+			return nil, err
+		}
+		r.logger.Info("Agent event subscriptions updated", "client_id", clientID, "patterns", req.Patterns)
+		return json.Marshal(map[string]interface{}{"patterns": req.Patterns})
+	})
+}
@@ -3,6 +3,7 @@ package filetransfer
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -58,6 +59,11 @@ func NewManager(config *Config, storage StorageBackend, db *gorm.DB) (*Manager,
 	return m, nil
 }
 
+// Storage 返回存储后端
+func (m *Manager) Storage() StorageBackend {
+	return m.storage
+}
+
 // Start 启动管理器
 func (m *Manager) Start() error {
 	return m.restorePendingTasks()
@@ -447,7 +453,7 @@ func (m *Manager) loadTaskFromDB(taskID string) (*TransferTask, error) {
 		task.CompletedAt = ft.CompletedAt
 	}
 	if ft.ErrorMessage != "" {
-		task.Error = fmt.Errorf(ft.ErrorMessage)
+		task.Error = errors.New(ft.ErrorMessage)
 	}
 
 	return task, nil
@@ -72,15 +72,15 @@ func (f *InitFrame) Encode() ([]byte, error) {
 	buf := make([]byte, 0, totalLen)
 
 	buf = append(buf, f.Type())
-	binary.BigEndian.PutUint16(buf[len(buf):len(buf)+2], uint16(len(taskIDBytes)))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(taskIDBytes)))
 	buf = append(buf, taskIDBytes...)
-	binary.BigEndian.PutUint16(buf[len(buf):len(buf)+2], uint16(len(fileNameBytes)))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(fileNameBytes)))
 	buf = append(buf, fileNameBytes...)
-	binary.BigEndian.PutUint64(buf[len(buf):len(buf)+8], uint64(f.FileSize))
-	binary.BigEndian.PutUint16(buf[len(buf):len(buf)+2], uint16(len(checksumBytes)))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(f.FileSize))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(checksumBytes)))
 	buf = append(buf, checksumBytes...)
-	binary.BigEndian.PutUint32(buf[len(buf):len(buf)+4], f.ChunkSize)
-	binary.BigEndian.PutUint16(buf[len(buf):len(buf)+2], uint16(len(optsBytes)))
+	buf = binary.BigEndian.AppendUint32(buf, f.ChunkSize)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(optsBytes)))
 	buf = append(buf, optsBytes...)
 
 	return buf, nil
@@ -193,11 +193,11 @@ func (f *DataFrame) Encode() ([]byte, error) {
 	buf := make([]byte, 0, totalLen)
 
 	buf = append(buf, f.Type())
-	binary.BigEndian.PutUint16(buf[len(buf):len(buf)+2], uint16(len(taskIDBytes)))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(taskIDBytes)))
 	buf = append(buf, taskIDBytes...)
-	binary.BigEndian.PutUint64(buf[len(buf):len(buf)+8], f.Sequence)
-	binary.BigEndian.PutUint64(buf[len(buf):len(buf)+8], uint64(f.Offset))
-	binary.BigEndian.PutUint32(buf[len(buf):len(buf)+4], uint32(dataLen))
+	buf = binary.BigEndian.AppendUint64(buf, f.Sequence)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(f.Offset))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(dataLen))
 	buf = append(buf, f.Data...)
 
 	return buf, nil
@@ -228,11 +228,11 @@ func (f *AckFrame) Encode() ([]byte, error) {
 	buf := make([]byte, 0, totalLen)
 
 	buf = append(buf, f.Type())
-	binary.BigEndian.PutUint16(buf[len(buf):len(buf)+2], uint16(len(taskIDBytes)))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(taskIDBytes)))
 	buf = append(buf, taskIDBytes...)
-	binary.BigEndian.PutUint64(buf[len(buf):len(buf)+8], f.Sequence)
-	binary.BigEndian.PutUint64(buf[len(buf):len(buf)+8], uint64(f.Offset))
-	binary.BigEndian.PutUint64(buf[len(buf):len(buf)+8], uint64(f.Received))
+	buf = binary.BigEndian.AppendUint64(buf, f.Sequence)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(f.Offset))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(f.Received))
 
 	return buf, nil
 }
@@ -264,13 +264,13 @@ func (f *ProgressFrame) Encode() ([]byte, error) {
 	buf := make([]byte, 0, totalLen)
 
 	buf = append(buf, f.Type())
-	binary.BigEndian.PutUint16(buf[len(buf):len(buf)+2], uint16(len(taskIDBytes)))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(taskIDBytes)))
 	buf = append(buf, taskIDBytes...)
 	// 进度百分比 (0-10000 表示 0.00-100.00)
-	binary.BigEndian.PutUint64(buf[len(buf):len(buf)+8], uint64(f.Progress*100))
-	binary.BigEndian.PutUint64(buf[len(buf):len(buf)+8], uint64(f.Transferred))
-	binary.BigEndian.PutUint64(buf[len(buf):len(buf)+8], uint64(f.Total))
-	binary.BigEndian.PutUint64(buf[len(buf):len(buf)+8], uint64(f.Speed))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(f.Progress*100))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(f.Transferred))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(f.Total))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(f.Speed))
 
 	return buf, nil
 }
@@ -301,11 +301,11 @@ func (f *CompleteFrame) Encode() ([]byte, error) {
 	buf := make([]byte, 0, totalLen)
 
 	buf = append(buf, f.Type())
-	binary.BigEndian.PutUint16(buf[len(buf):len(buf)+2], uint16(len(taskIDBytes)))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(taskIDBytes)))
 	buf = append(buf, taskIDBytes...)
-	binary.BigEndian.PutUint16(buf[len(buf):len(buf)+2], uint16(len(checksumBytes)))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(checksumBytes)))
 	buf = append(buf, checksumBytes...)
-	binary.BigEndian.PutUint16(buf[len(buf):len(buf)+2], uint16(len(statusBytes)))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(statusBytes)))
 	buf = append(buf, statusBytes...)
 
 	return buf, nil
@@ -336,10 +336,10 @@ func (f *ErrorFrame) Encode() ([]byte, error) {
 	buf := make([]byte, 0, totalLen)
 
 	buf = append(buf, f.Type())
-	binary.BigEndian.PutUint16(buf[len(buf):len(buf)+2], uint16(len(taskIDBytes)))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(taskIDBytes)))
 	buf = append(buf, taskIDBytes...)
-	binary.BigEndian.PutUint32(buf[len(buf):len(buf)+4], uint32(f.Code))
-	binary.BigEndian.PutUint16(buf[len(buf):len(buf)+2], uint16(len(messageBytes)))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(f.Code))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(messageBytes)))
 	buf = append(buf, messageBytes...)
 
 	return buf, nil
@@ -0,0 +1,50 @@
+package filetransfer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewFrameWriter(&buf)
+	require.NoError(t, w.WriteFrame(NewInitFrame("task-1", "agent.bin", 5, "abc123", 4096)))
+	require.NoError(t, w.WriteFrame(NewDataFrame("task-1", 7, 0, []byte("hello"))))
+	require.NoError(t, w.WriteFrame(NewCompleteFrame("task-1", "abc123", "completed")))
+	require.NoError(t, w.WriteFrame(NewErrorFrame("task-1", 3, "boom")))
+
+	r := NewFrameReader(&buf)
+
+	f, err := r.ReadFrame()
+	require.NoError(t, err)
+	init, ok := f.(*InitFrame)
+	require.True(t, ok)
+	assert.Equal(t, "task-1", init.TaskID)
+	assert.Equal(t, "agent.bin", init.FileName)
+	assert.Equal(t, int64(5), init.FileSize)
+	assert.Equal(t, "abc123", init.Checksum)
+	assert.Equal(t, uint32(4096), init.ChunkSize)
+
+	f, err = r.ReadFrame()
+	require.NoError(t, err)
+	data, ok := f.(*DataFrame)
+	require.True(t, ok)
+	assert.Equal(t, uint64(7), data.Sequence)
+	assert.Equal(t, []byte("hello"), data.Data)
+
+	f, err = r.ReadFrame()
+	require.NoError(t, err)
+	complete, ok := f.(*CompleteFrame)
+	require.True(t, ok)
+	assert.Equal(t, "completed", complete.Status)
+
+	f, err = r.ReadFrame()
+	require.NoError(t, err)
+	e, ok := f.(*ErrorFrame)
+	require.True(t, ok)
+	assert.Equal(t, 3, e.Code)
+	assert.Equal(t, "boom", e.Message)
+}
@@ -0,0 +1,43 @@
+// Package fsutil 本地文件辅助：权限检查、私有目录与密钥读取
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// CheckPermissions 拒绝组或其他用户可写、或者不属于 root 与当前用户的文件和目录，防止非特权用户替换其内容
+func CheckPermissions(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return checkInfo(path, info)
+}
+
+// EnsurePrivateDir 以 0700 创建目录；目录已存在时同样要求它不是符号链接、属于 root 或当前用户且组与其他用户不可写，
+// 防止使用他人预先创建的目录
+func EnsurePrivateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	return checkInfo(dir, info)
+}
+
+func checkInfo(path string, info os.FileInfo) error {
+	if info.Mode().Perm()&0022 != 0 {
+		return fmt.Errorf("%s is writable by group or others", path)
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && st.Uid != 0 && int(st.Uid) != os.Geteuid() {
+		return fmt.Errorf("%s is owned by uid %d", path, st.Uid)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsurePrivateDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a", "b")
+	require.NoError(t, EnsurePrivateDir(dir))
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	// 已存在但组或其他用户可写
+	open := filepath.Join(t.TempDir(), "open")
+	require.NoError(t, os.Mkdir(open, 0700))
+	require.NoError(t, os.Chmod(open, 0777))
+	assert.ErrorContains(t, EnsurePrivateDir(open), "writable")
+
+	// 指向其他目录的符号链接
+	link := filepath.Join(t.TempDir(), "link")
+	require.NoError(t, os.Symlink(t.TempDir(), link))
+	assert.ErrorContains(t, EnsurePrivateDir(link), "not a directory")
+}
+
+func TestReadSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("s3cret\n"), 0600))
+	got, err := ReadSecret(path)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", got)
+
+	got, err = ReadSecret("inline-token")
+	require.NoError(t, err)
+	assert.Equal(t, "inline-token", got)
+	got, err = ReadSecret("")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
@@ -0,0 +1,19 @@
+package fsutil
+
+import (
+	"os"
+	"strings"
+)
+
+// ReadSecret 读取密钥或令牌：以文件路径存在时读取文件内容（去掉首尾空白），否则视为值本身
+func ReadSecret(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	if data, err := os.ReadFile(s); err == nil {
+		return strings.TrimSpace(string(data)), nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	return s, nil
+}
@@ -447,6 +447,107 @@ func compareVersions(a, b string) int {
 	return 0
 }
 
+// FetchFilesRequest 读取仓库文件请求
+type FetchFilesRequest struct {
+	RepoURL    string
+	Branch     string   // 分支（空表示默认分支）
+	Path       string   // 仓库内的目录（空表示仓库根目录），包含子目录
+	Extensions []string // 只读取这些扩展名的文件，如 .yaml（空表示全部）
+}
+
+// FetchFilesResult 读取仓库文件结果
+type FetchFilesResult struct {
+	Commit string            // 读取的提交哈希
+	Files  map[string][]byte // 相对 Path 的文件路径 -> 文件内容
+}
+
+// FetchFiles 浅克隆仓库并读取指定目录下的文件
+func (c *Client) FetchFiles(ctx context.Context, req *FetchFilesRequest) (*FetchFilesResult, error) {
+	if req.RepoURL == "" {
+		return nil, fmt.Errorf("repo_url is required")
+	}
+	dir := filepath.Clean("/" + req.Path)
+
+	authURL, err := c.buildAuthURL(req.RepoURL)
+	if err != nil {
+		return nil, fmt.Errorf("build auth URL: %w", err)
+	}
+	cleanup, err := c.setupSSHEnv()
+	if err != nil {
+		return nil, fmt.Errorf("setup SSH: %w", err)
+	}
+	if cleanup != nil {
+		defer cleanup()
+	}
+
+	tmpDir, err := os.MkdirTemp("", "git-files-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	args := []string{"clone", "--depth", "1", "--single-branch"}
+	if req.Branch != "" {
+		args = append(args, "--branch", req.Branch)
+	}
+	cloneCmd := exec.CommandContext(ctx, "git", append(args, authURL, tmpDir)...)
+	cloneCmd.Env = os.Environ()
+	if err := cloneCmd.Run(); err != nil {
+		return nil, fmt.Errorf("clone: %w", err)
+	}
+
+	revCmd := exec.CommandContext(ctx, "git", "-C", tmpDir, "rev-parse", "HEAD")
+	output, err := revCmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("rev-parse: %w", err)
+	}
+
+	result := &FetchFilesResult{
+		Commit: strings.TrimSpace(string(output)),
+		Files:  make(map[string][]byte),
+	}
+	root := filepath.Join(tmpDir, dir)
+	err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !hasExtension(path, req.Extensions) {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		result.Files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", req.Path, err)
+	}
+	return result, nil
+}
+
+// hasExtension 文件扩展名是否在列表中（不区分大小写，列表为空时总是 true）
+func hasExtension(path string, extensions []string) bool {
+	if len(extensions) == 0 {
+		return true
+	}
+	ext := filepath.Ext(path)
+	for _, e := range extensions {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
+
 // ValidateRepo 验证仓库是否可访问
 func (c *Client) ValidateRepo(ctx context.Context, repoURL string) error {
 	authURL, err := c.buildAuthURL(repoURL)
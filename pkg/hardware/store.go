@@ -242,6 +242,16 @@ func (s *Store) ListDevices(offset, limit int) ([]Device, int64, error) {
 	return devices, total, err
 }
 
+// ListDevicesByClientIDs 根据客户端 ID 批量获取设备
+func (s *Store) ListDevicesByClientIDs(clientIDs []string) ([]Device, error) {
+	var devices []Device
+	if len(clientIDs) == 0 {
+		return devices, nil
+	}
+	err := s.db.Where("client_id IN ?", clientIDs).Find(&devices).Error
+	return devices, err
+}
+
 // ListDevicesByStatus 按状态列出设备
 func (s *Store) ListDevicesByStatus(status string, offset, limit int) ([]Device, int64, error) {
 	var devices []Device
@@ -0,0 +1,63 @@
+// Package jsonfile 以单个 JSON 文件持久化的存储：启动时加载、修改后原子写入，并提供保护内存数据的读写锁
+package jsonfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// File JSON 存储文件
+// 内嵌的读写锁用于保护使用方的内存数据，Save 须在持有写锁时调用
+type File struct {
+	sync.RWMutex
+	path string
+}
+
+// Open 创建目录 dir 并将 dir/name 解码到 v（文件不存在时 v 保持不变）
+// dir 为空时不读写文件，数据仅保存在内存中
+func Open(dir, name string, v interface{}) (*File, error) {
+	f := &File{}
+	if dir == "" {
+		return f, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create %s: %w", dir, err)
+	}
+	f.path = filepath.Join(dir, name)
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return f, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", f.path, err)
+	}
+	return f, nil
+}
+
+// Save 将 v 编码后原子写入文件（调用方持有写锁），仅内存存储时直接返回
+func (f *File) Save(v interface{}) error {
+	if f.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFile(f.path, data)
+}
+
+// WriteFile 先写入同目录临时文件再重命名，避免进程中断留下不完整的文件
+func WriteFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,43 @@
+package jsonfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type doc struct {
+	Items []string `json:"items"`
+}
+
+func TestFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "store")
+	var d doc
+	f, err := Open(dir, "doc.json", &d)
+	require.NoError(t, err)
+	assert.Empty(t, d.Items)
+
+	f.Lock()
+	require.NoError(t, f.Save(doc{Items: []string{"a", "b"}}))
+	f.Unlock()
+	_, err = os.Stat(filepath.Join(dir, "doc.json.tmp"))
+	assert.True(t, os.IsNotExist(err))
+
+	var loaded doc
+	_, err = Open(dir, "doc.json", &loaded)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, loaded.Items)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "doc.json"), []byte("{"), 0644))
+	_, err = Open(dir, "doc.json", &loaded)
+	assert.ErrorContains(t, err, "parse")
+}
+
+func TestFileInMemory(t *testing.T) {
+	f, err := Open("", "doc.json", &doc{})
+	require.NoError(t, err)
+	assert.NoError(t, f.Save(doc{Items: []string{"a"}}))
+}
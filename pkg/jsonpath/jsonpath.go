@@ -0,0 +1,73 @@
+// Package jsonpath 在 json.Unmarshal 解码出的通用值（map、slice）中按路径取值
+package jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Lookup 按路径取值，支持 $.a.b[0].c、a.b.0.c 与 ['key'] 形式，键不存在或下标越界时返回错误
+func Lookup(doc interface{}, path string) (interface{}, error) {
+	tokens, err := Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	v := doc
+	for _, tok := range tokens {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			next, ok := node[tok]
+			if !ok {
+				return nil, fmt.Errorf("json path %s: key %q not found", path, tok)
+			}
+			v = next
+		case []interface{}:
+			i, err := strconv.Atoi(tok)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("json path %s: index %q out of range", path, tok)
+			}
+			v = node[i]
+		default:
+			return nil, fmt.Errorf("json path %s: cannot look up %q in %s", path, tok, valueString(v))
+		}
+	}
+	return v, nil
+}
+
+// Parse 将路径拆分为键与下标
+func Parse(path string) ([]string, error) {
+	p := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	var tokens []string
+	for len(p) > 0 {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+		case '[':
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid json path %q: missing ]", path)
+			}
+			tokens = append(tokens, strings.Trim(p[1:end], `'"`))
+			p = p[end+1:]
+		default:
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			tokens = append(tokens, p[:end])
+			p = p[end:]
+		}
+	}
+	return tokens, nil
+}
+
+// valueString 错误信息中的值：字符串取原值，其他值取 JSON 编码
+func valueString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
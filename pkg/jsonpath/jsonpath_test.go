@@ -0,0 +1,28 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	var doc interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"data":{"items":[{"name":"a"},{"name":"b"}],"a.b":1}}`), &doc))
+
+	for _, path := range []string{"$.data.items[1].name", "data.items.1.name", "$['data']['items'][1]['name']"} {
+		v, err := Lookup(doc, path)
+		require.NoError(t, err, path)
+		assert.Equal(t, "b", v, path)
+	}
+	v, err := Lookup(doc, "data['a.b']")
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), v)
+
+	for _, path := range []string{"data.missing", "data.items[5]", "data.items.x", "data.items[0].name.x", "data[items"} {
+		_, err := Lookup(doc, path)
+		assert.Error(t, err, path)
+	}
+}
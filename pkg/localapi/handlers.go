@@ -0,0 +1,293 @@
+package localapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/voilet/quic-flow/pkg/router"
+)
+
+// 本地处理器租约限制
+const (
+	DefaultHandlerTTL = 60 * time.Second
+	MaxHandlerTTL     = time.Hour
+	MaxPollWait       = 60 * time.Second
+	maxPending        = 64 // 单个处理器的待领取调用上限
+)
+
+// 处理器错误
+var (
+	ErrHandlerNotFound    = errors.New("local handler not found")
+	ErrHandlerConflict    = errors.New("command type already has a handler")
+	ErrInvocationNotFound = errors.New("invocation not found")
+	ErrHandlerRemoved     = errors.New("local handler removed")
+)
+
+// HandlerInfo 本地处理器信息
+type HandlerInfo struct {
+	CommandType string    `json:"command_type"`
+	Owner       string    `json:"owner,omitempty"`
+	TTLSec      int       `json:"ttl_sec"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Pending     int       `json:"pending"`   // 等待领取的调用
+	InFlight    int       `json:"in_flight"` // 已领取、等待结果的调用
+	Invocations int64     `json:"invocations"`
+}
+
+// Invocation 投递给本地应用的一次命令调用
+type Invocation struct {
+	ID          string          `json:"id"`
+	CommandType string          `json:"command_type"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	Deadline    *time.Time      `json:"deadline,omitempty"`
+}
+
+// InvocationResult 本地应用回传的调用结果
+type InvocationResult struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// invocation 调用及其结果通道
+type invocation struct {
+	Invocation
+	ctx  context.Context // 命令执行上下文，调用方放弃后不再投递
+	done chan InvocationResult
+}
+
+// localHandler 本地应用注册的短期命令处理器
+// 应用通过长轮询领取调用并回传结果，每次轮询都会续租；租约到期后自动注销
+type localHandler struct {
+	commandType string
+	owner       string
+	ttl         time.Duration
+	expiresAt   time.Time
+	polling     int // 正在进行的长轮询数量，轮询期间不会过期
+
+	queue    chan *invocation
+	inflight map[string]*invocation
+	count    int64
+	removed  chan struct{}
+}
+
+// registry 本地处理器注册表
+type registry struct {
+	router *router.Router
+
+	mu       sync.Mutex
+	handlers map[string]*localHandler
+}
+
+func newRegistry(r *router.Router) *registry {
+	return &registry{router: r, handlers: make(map[string]*localHandler)}
+}
+
+// register 注册处理器；同一命令类型已由本地应用注册时视为续租
+func (g *registry) register(commandType, owner string, ttl time.Duration) (HandlerInfo, error) {
+	if ttl <= 0 {
+		ttl = DefaultHandlerTTL
+	}
+	if ttl > MaxHandlerTTL {
+		ttl = MaxHandlerTTL
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if h, ok := g.handlers[commandType]; ok {
+		h.ttl = ttl
+		if owner != "" {
+			h.owner = owner
+		}
+		h.expiresAt = time.Now().Add(ttl)
+		return h.info(), nil
+	}
+	if g.router.HasHandler(commandType) {
+		return HandlerInfo{}, fmt.Errorf("%w: %s", ErrHandlerConflict, commandType)
+	}
+
+	h := &localHandler{
+		commandType: commandType,
+		owner:       owner,
+		ttl:         ttl,
+		expiresAt:   time.Now().Add(ttl),
+		queue:       make(chan *invocation, maxPending),
+		inflight:    make(map[string]*invocation),
+		removed:     make(chan struct{}),
+	}
+	g.handlers[commandType] = h
+	g.router.Register(commandType, g.routeHandler(h))
+	return h.info(), nil
+}
+
+// routeHandler 返回注册到命令路由器的处理函数：投递调用并等待本地应用回传结果
+func (g *registry) routeHandler(h *localHandler) router.Handler {
+	return func(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+		inv := &invocation{
+			Invocation: Invocation{ID: uuid.New().String(), CommandType: h.commandType, Payload: payload},
+			ctx:        ctx,
+			done:       make(chan InvocationResult, 1),
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			inv.Deadline = &deadline
+		}
+
+		select {
+		case h.queue <- inv:
+		case <-h.removed:
+			return nil, ErrHandlerRemoved
+		default:
+			return nil, fmt.Errorf("local handler %s: too many pending invocations", h.commandType)
+		}
+
+		select {
+		case res := <-inv.done:
+			if res.Error != "" {
+				return nil, errors.New(res.Error)
+			}
+			if len(res.Result) == 0 {
+				return json.RawMessage("null"), nil
+			}
+			return res.Result, nil
+		case <-h.removed:
+			return nil, ErrHandlerRemoved
+		case <-ctx.Done():
+			g.mu.Lock()
+			delete(h.inflight, inv.ID)
+			g.mu.Unlock()
+			return nil, fmt.Errorf("local handler %s: %w", h.commandType, ctx.Err())
+		}
+	}
+}
+
+// poll 长轮询领取下一个调用，超时返回 nil
+func (g *registry) poll(ctx context.Context, commandType string, wait time.Duration) (*Invocation, error) {
+	g.mu.Lock()
+	h, ok := g.handlers[commandType]
+	if !ok {
+		g.mu.Unlock()
+		return nil, ErrHandlerNotFound
+	}
+	h.polling++
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		h.polling--
+		h.expiresAt = time.Now().Add(h.ttl)
+		g.mu.Unlock()
+	}()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		select {
+		case inv := <-h.queue:
+			// 调用方已超时放弃的调用直接跳过
+			if inv.ctx.Err() != nil {
+				continue
+			}
+			g.mu.Lock()
+			h.inflight[inv.ID] = inv
+			h.count++
+			g.mu.Unlock()
+			return &inv.Invocation, nil
+		case <-h.removed:
+			return nil, ErrHandlerNotFound
+		case <-timer.C:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// complete 回传调用结果
+func (g *registry) complete(commandType, id string, res InvocationResult) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	h, ok := g.handlers[commandType]
+	if !ok {
+		return ErrHandlerNotFound
+	}
+	inv, ok := h.inflight[id]
+	if !ok {
+		return ErrInvocationNotFound
+	}
+	delete(h.inflight, id)
+	h.expiresAt = time.Now().Add(h.ttl)
+	inv.done <- res
+	return nil
+}
+
+// unregister 注销处理器，未完成的调用返回 ErrHandlerRemoved
+func (g *registry) unregister(commandType string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	h, ok := g.handlers[commandType]
+	if !ok {
+		return ErrHandlerNotFound
+	}
+	g.remove(h)
+	return nil
+}
+
+// remove 注销处理器（调用方持有锁）
+func (g *registry) remove(h *localHandler) {
+	delete(g.handlers, h.commandType)
+	g.router.Unregister(h.commandType)
+	close(h.removed)
+}
+
+// expire 注销租约到期且没有进行中轮询的处理器，返回被注销的命令类型
+func (g *registry) expire(now time.Time) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var expired []string
+	for _, h := range g.handlers {
+		if h.polling == 0 && now.After(h.expiresAt) {
+			g.remove(h)
+			expired = append(expired, h.commandType)
+		}
+	}
+	return expired
+}
+
+// list 列出处理器
+func (g *registry) list() []HandlerInfo {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	list := make([]HandlerInfo, 0, len(g.handlers))
+	for _, h := range g.handlers {
+		list = append(list, h.info())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CommandType < list[j].CommandType })
+	return list
+}
+
+// closeAll 注销全部处理器
+func (g *registry) closeAll() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, h := range g.handlers {
+		g.remove(h)
+	}
+}
+
+// info 处理器信息（调用方持有锁）
+func (h *localHandler) info() HandlerInfo {
+	return HandlerInfo{
+		CommandType: h.commandType,
+		Owner:       h.owner,
+		TTLSec:      int(h.ttl / time.Second),
+		ExpiresAt:   h.expiresAt,
+		Pending:     len(h.queue),
+		InFlight:    len(h.inflight),
+		Invocations: h.count,
+	}
+}
@@ -0,0 +1,592 @@
+// Package localapi 提供 Agent 本地 API（默认监听 Unix socket），
+// 供同主机上的应用通过 Agent 已建立的链路上报事件、查询状态、注册短期命令处理器
+package localapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	pkgerrors "github.com/voilet/quic-flow/pkg/errors"
+	"github.com/voilet/quic-flow/pkg/eventbus"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/protocol"
+	"github.com/voilet/quic-flow/pkg/query"
+	"github.com/voilet/quic-flow/pkg/router"
+)
+
+// 默认值
+const (
+	// CmdReportEvent 本地应用事件上报到服务器时使用的命令类型
+	CmdReportEvent = "report.event"
+
+	socketMode        = 0660
+	defaultAckTimeout = 10 * time.Second
+	maxAckTimeout     = 60 * time.Second
+	maxBodySize       = 4 * 1024 * 1024
+	expireInterval    = time.Second
+	sseKeepalive      = 15 * time.Second
+)
+
+// Agent 本地 API 依赖的客户端能力（*client.Client 实现）
+type Agent interface {
+	GetClientID() string
+	GetState() protocol.ClientState
+	IsConnected() bool
+	GetTimeSinceLastPong() time.Duration
+	SendMessage(ctx context.Context, msg *protocol.DataMessage, waitAck bool, timeout time.Duration) (*protocol.AckMessage, error)
+	Query(ctx context.Context, name string, req interface{}) (json.RawMessage, error)
+}
+
+// StatusFunc 附加状态提供函数，结果作为 /v1/status 的一个字段返回
+type StatusFunc func() interface{}
+
+// Config 本地 API 配置
+type Config struct {
+	Address string              // 监听地址：unix:///path、/path（Unix socket）或 tcp://127.0.0.1:port（仅限回环地址）
+	Token   string              // 访问令牌（Authorization: Bearer），TCP 监听时必填；Unix socket 由文件权限控制，设置时同样校验
+	Version string              // Agent 版本
+	Router  *router.Router      // 命令路由器，本地处理器注册到此路由器
+	Events  *eventbus.AgentLink // 主题事件链路（可选），用于发布与订阅主题事件
+	Logger  *monitoring.Logger  // 日志器
+}
+
+// AppEvent 本地应用上报的事件（report.event 命令载荷）
+type AppEvent struct {
+	ClientID  string          `json:"client_id"`
+	Source    string          `json:"source,omitempty"` // 上报应用
+	Event     string          `json:"event"`            // 事件名称
+	Data      json.RawMessage `json:"data,omitempty"`
+	Timestamp int64           `json:"timestamp"`
+}
+
+// EventRequest 事件上报请求
+// 指定 topic 时发布到服务器事件总线的主题，否则以 report.event 上报（此时 event 必填）
+type EventRequest struct {
+	Event      string          `json:"event,omitempty"`
+	Topic      string          `json:"topic,omitempty"`
+	Source     string          `json:"source,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	WaitAck    bool            `json:"wait_ack"`              // 是否等待服务器确认
+	TimeoutSec int             `json:"timeout_sec,omitempty"` // 等待确认超时（默认 10 秒）
+}
+
+// QueryRequest 服务器查询请求
+type QueryRequest struct {
+	Name       string          `json:"name" binding:"required"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+	TimeoutSec int             `json:"timeout_sec,omitempty"` // 等待结果超时（默认 10 秒）
+}
+
+// RegisterHandlerRequest 注册本地处理器请求
+type RegisterHandlerRequest struct {
+	CommandType string `json:"command_type" binding:"required"`
+	Owner       string `json:"owner,omitempty"`   // 注册方（仅用于展示）
+	TTLSec      int    `json:"ttl_sec,omitempty"` // 租约时长（默认 60 秒，最长 1 小时）
+}
+
+// Server Agent 本地 API 服务器
+type Server struct {
+	cfg      Config
+	agent    Agent
+	engine   *gin.Engine
+	server   *http.Server
+	handlers *registry
+	logger   *monitoring.Logger
+	started  time.Time
+
+	network string
+	address string
+
+	mu     sync.RWMutex
+	status map[string]StatusFunc
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// ParseAddress 解析监听地址，返回网络类型与地址
+// 不带 scheme 的地址视为 Unix socket 路径；TCP 仅允许回环地址
+func ParseAddress(addr string) (network, address string, err error) {
+	switch {
+	case strings.HasPrefix(addr, "unix://"):
+		network, address = "unix", strings.TrimPrefix(addr, "unix://")
+	case strings.HasPrefix(addr, "tcp://"):
+		network, address = "tcp", strings.TrimPrefix(addr, "tcp://")
+	case strings.Contains(addr, "://"):
+		return "", "", fmt.Errorf("unsupported local api address: %s", addr)
+	default:
+		network, address = "unix", addr
+	}
+	if address == "" {
+		return "", "", fmt.Errorf("local api address is empty")
+	}
+	if network == "tcp" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid local api address: %w", err)
+		}
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return "", "", fmt.Errorf("local api must listen on a loopback address, got %s", host)
+		}
+	}
+	return network, address, nil
+}
+
+// NewServer 创建本地 API 服务器
+func NewServer(cfg Config, agent Agent) (*Server, error) {
+	if cfg.Router == nil {
+		return nil, fmt.Errorf("local api: router is required")
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = monitoring.NewDefaultLogger()
+	}
+	network, address, err := ParseAddress(cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+	// 回环端口对本机所有用户开放，必须使用令牌
+	if network == "tcp" && cfg.Token == "" {
+		return nil, fmt.Errorf("local api: a token is required when listening on tcp")
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	s := &Server{
+		cfg:      cfg,
+		agent:    agent,
+		engine:   gin.New(),
+		handlers: newRegistry(cfg.Router),
+		logger:   cfg.Logger,
+		started:  time.Now(),
+		network:  network,
+		address:  address,
+		status:   make(map[string]StatusFunc),
+		stopCh:   make(chan struct{}),
+	}
+	s.engine.Use(gin.Recovery())
+	s.engine.Use(func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBodySize)
+		c.Next()
+	})
+	if cfg.Token != "" {
+		s.engine.Use(s.authenticate)
+	}
+
+	v1 := s.engine.Group("/v1")
+	{
+		v1.GET("/status", s.handleStatus)
+		v1.POST("/events", s.handlePublishEvent)
+		v1.GET("/events/stream", s.handleEventStream)
+		v1.POST("/query", s.handleQuery)
+
+		v1.GET("/handlers", s.handleListHandlers)
+		v1.POST("/handlers", s.handleRegisterHandler)
+		v1.DELETE("/handlers/:type", s.handleUnregisterHandler)
+		v1.GET("/handlers/:type/next", s.handlePoll)
+		v1.POST("/handlers/:type/invocations/:id", s.handleComplete)
+	}
+	s.server = &http.Server{Handler: s.engine, ReadHeaderTimeout: 10 * time.Second}
+	return s, nil
+}
+
+// authenticate 校验 Authorization: Bearer 令牌
+func (s *Server) authenticate(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.Token)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"success": false, "error": "invalid or missing token"})
+		return
+	}
+	c.Next()
+}
+
+// Handler 返回 HTTP 处理器
+func (s *Server) Handler() http.Handler {
+	return s.engine
+}
+
+// Address 返回监听地址
+func (s *Server) Address() string {
+	return s.network + "://" + s.address
+}
+
+// AddStatus 添加附加状态字段
+func (s *Server) AddStatus(name string, fn StatusFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status[name] = fn
+}
+
+// Start 开始监听
+// Unix socket 文件权限为 0660（在受限的 umask 下创建，不存在其他用户可连接的时间窗口），启动前会清理上次遗留的 socket 文件
+func (s *Server) Start() error {
+	if s.network == "unix" {
+		if fi, err := os.Lstat(s.address); err == nil {
+			if fi.Mode()&os.ModeSocket == 0 {
+				return fmt.Errorf("local api: %s exists and is not a socket", s.address)
+			}
+			os.Remove(s.address)
+		}
+	}
+	ln, err := s.listen()
+	if err != nil {
+		return fmt.Errorf("local api: listen: %w", err)
+	}
+	if s.network == "unix" {
+		if err := os.Chmod(s.address, socketMode); err != nil {
+			ln.Close()
+			return fmt.Errorf("local api: chmod socket: %w", err)
+		}
+	} else {
+		s.address = ln.Addr().String()
+	}
+
+	s.wg.Add(2)
+	go func() {
+		defer s.wg.Done()
+		if err := s.server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("Local API server error", "error", err)
+		}
+	}()
+	go s.expireLoop()
+
+	s.logger.Info("Local API listening", "address", s.Address())
+	return nil
+}
+
+// listen 监听地址，Unix socket 创建期间将 umask 收紧到 socketMode 以外的位
+func (s *Server) listen() (net.Listener, error) {
+	if s.network != "unix" {
+		return net.Listen(s.network, s.address)
+	}
+	old := syscall.Umask(^socketMode & 0777)
+	defer syscall.Umask(old)
+	return net.Listen(s.network, s.address)
+}
+
+// Stop 停止服务器并注销全部本地处理器
+func (s *Server) Stop(ctx context.Context) error {
+	close(s.stopCh)
+	s.handlers.closeAll()
+	err := s.server.Shutdown(ctx)
+	s.wg.Wait()
+	return err
+}
+
+// expireLoop 定期注销租约到期的本地处理器
+func (s *Server) expireLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(expireInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			for _, cmd := range s.handlers.expire(now) {
+				s.logger.Info("Local handler lease expired", "command_type", cmd)
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// handleStatus 查询 Agent 状态与连接状态
+func (s *Server) handleStatus(c *gin.Context) {
+	status := gin.H{
+		"client_id":       s.agent.GetClientID(),
+		"version":         s.cfg.Version,
+		"state":           s.agent.GetState().String(),
+		"connected":       s.agent.IsConnected(),
+		"last_pong_ago_s": int64(s.agent.GetTimeSinceLastPong() / time.Second),
+		"uptime_s":        int64(time.Since(s.started) / time.Second),
+		"commands":        s.cfg.Router.ListCommands(),
+		"local_handlers":  s.handlers.list(),
+	}
+	if s.cfg.Events != nil {
+		status["event_patterns"] = s.cfg.Events.Patterns()
+	}
+	s.mu.RLock()
+	for name, fn := range s.status {
+		status[name] = fn()
+	}
+	s.mu.RUnlock()
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": status})
+}
+
+// handlePublishEvent 将本地应用的事件作为 EVENT 消息上报到服务器
+func (s *Server) handlePublishEvent(c *gin.Context) {
+	var req EventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		localAPIError(c, err)
+		return
+	}
+	if len(req.Data) > 0 && !json.Valid(req.Data) {
+		localAPIError(c, fmt.Errorf("data is not valid JSON"))
+		return
+	}
+
+	timeout := defaultAckTimeout
+	if req.TimeoutSec > 0 {
+		timeout = time.Duration(req.TimeoutSec) * time.Second
+	}
+	if timeout > maxAckTimeout {
+		timeout = maxAckTimeout
+	}
+
+	if req.Topic != "" {
+		if s.cfg.Events == nil {
+			localAPIError(c, fmt.Errorf("topic events are not enabled"))
+			return
+		}
+		msgID, ack, err := s.cfg.Events.Publish(c.Request.Context(), req.Topic, req.Data, req.WaitAck, timeout)
+		if err != nil {
+			localAPIError(c, err)
+			return
+		}
+		writeAck(c, msgID, ack)
+		return
+	}
+	if req.Event == "" {
+		localAPIError(c, fmt.Errorf("event or topic is required"))
+		return
+	}
+
+	event, _ := json.Marshal(AppEvent{
+		ClientID:  s.agent.GetClientID(),
+		Source:    req.Source,
+		Event:     req.Event,
+		Data:      req.Data,
+		Timestamp: time.Now().UnixMilli(),
+	})
+	payload, _ := json.Marshal(struct {
+		CommandType string          `json:"command_type"`
+		Payload     json.RawMessage `json:"payload"`
+	}{CommandType: CmdReportEvent, Payload: event})
+
+	msg := &protocol.DataMessage{
+		MsgId:   uuid.New().String(),
+		Type:    protocol.MessageType_MESSAGE_TYPE_EVENT,
+		Payload: payload,
+	}
+	ack, err := s.agent.SendMessage(c.Request.Context(), msg, req.WaitAck, timeout)
+	if err != nil {
+		localAPIError(c, err)
+		return
+	}
+	writeAck(c, msg.MsgId, ack)
+}
+
+// writeAck 返回上报结果，ack 不为空时附带服务器确认结果
+func writeAck(c *gin.Context, msgID string, ack *protocol.AckMessage) {
+	data := gin.H{"msg_id": msgID}
+	if ack != nil {
+		if ack.Status != protocol.AckStatus_ACK_STATUS_SUCCESS {
+			c.JSON(http.StatusBadGateway, gin.H{"success": false, "data": data, "error": ack.Error})
+			return
+		}
+		data["acked"] = true
+		if json.Valid(ack.Result) {
+			data["result"] = json.RawMessage(ack.Result)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": data})
+}
+
+// handleEventStream 以 SSE 推送服务器投递的主题事件
+// 参数：pattern（可重复或逗号分隔），replay=true 时先推送本地保留的匹配事件；
+// 订阅期间模式会声明给服务器，所有订阅者断开后取消
+func (s *Server) handleEventStream(c *gin.Context) {
+	if s.cfg.Events == nil {
+		localAPIError(c, fmt.Errorf("topic events are not enabled"))
+		return
+	}
+	var patterns []string
+	for _, v := range c.QueryArray("pattern") {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				patterns = append(patterns, p)
+			}
+		}
+	}
+	sub, err := s.cfg.Events.Subscribe(patterns, 0, c.Query("replay") == "true")
+	if err != nil {
+		localAPIError(c, err)
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		localAPIError(c, fmt.Errorf("streaming not supported"))
+		return
+	}
+	c.Status(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(sseKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case ev, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(ev)
+			fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", ev.ID, ev.Topic, data)
+			flusher.Flush()
+		case <-keepalive.C:
+			fmt.Fprint(c.Writer, ": keepalive\n\n")
+			flusher.Flush()
+		case <-c.Request.Context().Done():
+			return
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// handleQuery 经 Agent 链路向服务器发起查询，返回查询结果
+func (s *Server) handleQuery(c *gin.Context) {
+	var req QueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		localAPIError(c, err)
+		return
+	}
+	if len(req.Payload) > 0 && !json.Valid(req.Payload) {
+		localAPIError(c, fmt.Errorf("payload is not valid JSON"))
+		return
+	}
+
+	timeout := defaultAckTimeout
+	if req.TimeoutSec > 0 {
+		timeout = time.Duration(req.TimeoutSec) * time.Second
+	}
+	if timeout > maxAckTimeout {
+		timeout = maxAckTimeout
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	var payload interface{}
+	if len(req.Payload) > 0 {
+		payload = req.Payload
+	}
+	result, err := s.agent.Query(ctx, req.Name, payload)
+	if err != nil {
+		localAPIError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": result})
+}
+
+// handleListHandlers 列出本地处理器
+func (s *Server) handleListHandlers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": s.handlers.list()})
+}
+
+// handleRegisterHandler 注册本地处理器（已注册时续租）
+func (s *Server) handleRegisterHandler(c *gin.Context) {
+	var req RegisterHandlerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		localAPIError(c, err)
+		return
+	}
+	info, err := s.handlers.register(req.CommandType, req.Owner, time.Duration(req.TTLSec)*time.Second)
+	if err != nil {
+		localAPIError(c, err)
+		return
+	}
+	s.logger.Info("Local handler registered", "command_type", info.CommandType, "owner", info.Owner, "ttl_sec", info.TTLSec)
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": info})
+}
+
+// handleUnregisterHandler 注销本地处理器
+func (s *Server) handleUnregisterHandler(c *gin.Context) {
+	cmd := c.Param("type")
+	if err := s.handlers.unregister(cmd); err != nil {
+		localAPIError(c, err)
+		return
+	}
+	s.logger.Info("Local handler unregistered", "command_type", cmd)
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// handlePoll 长轮询领取下一个调用，wait 秒内无调用时返回 204
+func (s *Server) handlePoll(c *gin.Context) {
+	wait := 30 * time.Second
+	if v := c.Query("wait"); v != "" {
+		sec, err := strconv.Atoi(v)
+		if err != nil || sec < 0 {
+			localAPIError(c, fmt.Errorf("invalid wait: %s", v))
+			return
+		}
+		wait = time.Duration(sec) * time.Second
+	}
+	if wait > MaxPollWait {
+		wait = MaxPollWait
+	}
+
+	inv, err := s.handlers.poll(c.Request.Context(), c.Param("type"), wait)
+	if err != nil {
+		localAPIError(c, err)
+		return
+	}
+	if inv == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": inv})
+}
+
+// handleComplete 回传调用结果
+func (s *Server) handleComplete(c *gin.Context) {
+	var res InvocationResult
+	if err := c.ShouldBindJSON(&res); err != nil {
+		localAPIError(c, err)
+		return
+	}
+	if len(res.Result) > 0 && !json.Valid(res.Result) {
+		localAPIError(c, fmt.Errorf("result is not valid JSON"))
+		return
+	}
+	if err := s.handlers.complete(c.Param("type"), c.Param("id"), res); err != nil {
+		localAPIError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// localAPIError 返回错误响应
+func localAPIError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, ErrHandlerNotFound), errors.Is(err, ErrInvocationNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrHandlerConflict):
+		status = http.StatusConflict
+	case errors.Is(err, pkgerrors.ErrClientNotConnected):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, query.ErrQueryFailed):
+		status = http.StatusBadGateway
+	case errors.Is(err, pkgerrors.ErrHeartbeatTimeout), errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusGatewayTimeout
+	}
+	c.JSON(status, gin.H{
+		"success": false,
+		"error":   err.Error(),
+	})
+}
@@ -0,0 +1,337 @@
+package localapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pkgerrors "github.com/voilet/quic-flow/pkg/errors"
+	"github.com/voilet/quic-flow/pkg/eventbus"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/protocol"
+	"github.com/voilet/quic-flow/pkg/query"
+	"github.com/voilet/quic-flow/pkg/router"
+)
+
+// fakeAgent 记录上报的消息
+type fakeAgent struct {
+	mu        sync.Mutex
+	connected bool
+	sent      []*protocol.DataMessage
+}
+
+func (a *fakeAgent) GetClientID() string                 { return "agent-1" }
+func (a *fakeAgent) GetTimeSinceLastPong() time.Duration { return time.Second }
+
+func (a *fakeAgent) GetState() protocol.ClientState {
+	return protocol.ClientState_CLIENT_STATE_CONNECTED
+}
+
+func (a *fakeAgent) IsConnected() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.connected
+}
+
+func (a *fakeAgent) setConnected(connected bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.connected = connected
+}
+
+func (a *fakeAgent) messages() []*protocol.DataMessage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.sent
+}
+
+func (a *fakeAgent) SendMessage(ctx context.Context, msg *protocol.DataMessage, waitAck bool, timeout time.Duration) (*protocol.AckMessage, error) {
+	if !a.IsConnected() {
+		return nil, pkgerrors.ErrClientNotConnected
+	}
+	a.mu.Lock()
+	a.sent = append(a.sent, msg)
+	a.mu.Unlock()
+	if !waitAck {
+		return nil, nil
+	}
+	return &protocol.AckMessage{MsgId: msg.MsgId, Status: protocol.AckStatus_ACK_STATUS_SUCCESS, Result: []byte(`{"ok":true}`)}, nil
+}
+
+func (a *fakeAgent) Query(ctx context.Context, name string, req interface{}) (json.RawMessage, error) {
+	if !a.IsConnected() {
+		return nil, pkgerrors.ErrClientNotConnected
+	}
+	if name != "config.get" {
+		return nil, fmt.Errorf("%w: %s: unknown query", query.ErrQueryFailed, name)
+	}
+	data, _ := json.Marshal(req)
+	return json.RawMessage(`{"request":` + string(data) + `}`), nil
+}
+
+func newTestServer(t *testing.T) (*Server, *router.Router, *fakeAgent) {
+	logger := monitoring.NewDefaultLogger()
+	r := router.NewRouter(logger)
+	r.Register("builtin", func(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+		return nil, nil
+	})
+	agent := &fakeAgent{connected: true}
+	s, err := NewServer(Config{
+		Address: filepath.Join(t.TempDir(), "agent.sock"),
+		Version: "1.0.0",
+		Router:  r,
+		Events:  eventbus.NewAgentLink(agent, 0, logger),
+		Logger:  logger,
+	}, agent)
+	require.NoError(t, err)
+	return s, r, agent
+}
+
+// do 发送请求并解析响应
+func do(t *testing.T, s *Server, method, path string, body interface{}) (int, map[string]interface{}) {
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, httptest.NewRequest(method, path, &buf))
+	var resp map[string]interface{}
+	if w.Body.Len() > 0 {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w.Code, resp
+}
+
+func TestParseAddress(t *testing.T) {
+	network, addr, err := ParseAddress("/run/quic-client.sock")
+	require.NoError(t, err)
+	assert.Equal(t, "unix", network)
+	assert.Equal(t, "/run/quic-client.sock", addr)
+
+	network, _, err = ParseAddress("tcp://127.0.0.1:9901")
+	require.NoError(t, err)
+	assert.Equal(t, "tcp", network)
+
+	_, _, err = ParseAddress("tcp://0.0.0.0:9901")
+	assert.ErrorContains(t, err, "loopback")
+	_, _, err = ParseAddress("http://127.0.0.1:9901")
+	assert.Error(t, err)
+}
+
+func TestTokenAuth(t *testing.T) {
+	r := router.NewRouter(nil)
+	_, err := NewServer(Config{Address: "tcp://127.0.0.1:0", Router: r}, &fakeAgent{})
+	assert.ErrorContains(t, err, "token")
+
+	s, err := NewServer(Config{Address: "tcp://127.0.0.1:0", Token: "secret", Router: r}, &fakeAgent{connected: true})
+	require.NoError(t, err)
+	code, _ := do(t, s, http.MethodGet, "/v1/status", nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	s.Handler().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestPublishEvent(t *testing.T) {
+	s, _, agent := newTestServer(t)
+
+	code, resp := do(t, s, http.MethodPost, "/v1/events", map[string]interface{}{
+		"event": "deploy.health", "source": "web", "data": map[string]string{"status": "ok"}, "wait_ack": true,
+	})
+	require.Equal(t, http.StatusOK, code)
+	data := resp["data"].(map[string]interface{})
+	assert.Equal(t, true, data["acked"])
+
+	sent := agent.messages()
+	require.Len(t, sent, 1)
+	assert.Equal(t, protocol.MessageType_MESSAGE_TYPE_EVENT, sent[0].Type)
+	var envelope struct {
+		CommandType string   `json:"command_type"`
+		Payload     AppEvent `json:"payload"`
+	}
+	require.NoError(t, json.Unmarshal(sent[0].Payload, &envelope))
+	assert.Equal(t, CmdReportEvent, envelope.CommandType)
+	assert.Equal(t, "agent-1", envelope.Payload.ClientID)
+	assert.Equal(t, "deploy.health", envelope.Payload.Event)
+	assert.JSONEq(t, `{"status":"ok"}`, string(envelope.Payload.Data))
+
+	agent.setConnected(false)
+	code, _ = do(t, s, http.MethodPost, "/v1/events", map[string]interface{}{"event": "x"})
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	code, _ = do(t, s, http.MethodPost, "/v1/events", map[string]interface{}{"source": "web"})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestPublishTopicEvent(t *testing.T) {
+	s, _, agent := newTestServer(t)
+
+	code, resp := do(t, s, http.MethodPost, "/v1/events", map[string]interface{}{
+		"topic": "deploy.web.health", "data": map[string]bool{"ok": true}, "wait_ack": true,
+	})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, resp["data"].(map[string]interface{})["acked"])
+
+	sent := agent.messages()
+	require.Len(t, sent, 1)
+	var envelope struct {
+		CommandType string                  `json:"command_type"`
+		Payload     eventbus.PublishRequest `json:"payload"`
+	}
+	require.NoError(t, json.Unmarshal(sent[0].Payload, &envelope))
+	assert.Equal(t, eventbus.CmdEventPublish, envelope.CommandType)
+	assert.Equal(t, "deploy.web.health", envelope.Payload.Topic)
+
+	code, _ = do(t, s, http.MethodPost, "/v1/events", map[string]interface{}{"topic": "deploy.*"})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestEventStream(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/v1/events/stream?pattern=config.%23")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"config.#"}, s.cfg.Events.Patterns())
+
+	require.NoError(t, s.cfg.Events.HandleDelivery(json.RawMessage(`{"id":"e1","topic":"other"}`)))
+	require.NoError(t, s.cfg.Events.HandleDelivery(json.RawMessage(`{"id":"e2","topic":"config.updated","data":{"v":2}}`)))
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 3 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	assert.Equal(t, "id: e2", lines[0])
+	assert.Equal(t, "event: config.updated", lines[1])
+	assert.Contains(t, lines[2], `"data":{"v":2}`)
+}
+
+func TestQuery(t *testing.T) {
+	s, _, agent := newTestServer(t)
+
+	code, resp := do(t, s, http.MethodPost, "/v1/query", map[string]interface{}{
+		"name": "config.get", "payload": map[string]string{"key": "db"},
+	})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]interface{}{"request": map[string]interface{}{"key": "db"}}, resp["data"])
+
+	code, _ = do(t, s, http.MethodPost, "/v1/query", map[string]interface{}{"name": "secret.get"})
+	assert.Equal(t, http.StatusBadGateway, code)
+
+	agent.setConnected(false)
+	code, _ = do(t, s, http.MethodPost, "/v1/query", map[string]interface{}{"name": "config.get"})
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
+
+func TestStatus(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	s.AddStatus("extra", func() interface{} { return 42 })
+
+	code, resp := do(t, s, http.MethodGet, "/v1/status", nil)
+	require.Equal(t, http.StatusOK, code)
+	data := resp["data"].(map[string]interface{})
+	assert.Equal(t, "agent-1", data["client_id"])
+	assert.Equal(t, true, data["connected"])
+	assert.Equal(t, float64(42), data["extra"])
+}
+
+func TestLocalHandler(t *testing.T) {
+	s, r, _ := newTestServer(t)
+
+	code, _ := do(t, s, http.MethodPost, "/v1/handlers", map[string]interface{}{"command_type": "builtin"})
+	assert.Equal(t, http.StatusConflict, code)
+
+	code, _ = do(t, s, http.MethodPost, "/v1/handlers", map[string]interface{}{"command_type": "app.health", "owner": "web"})
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, r.HasHandler("app.health"))
+
+	// 应用侧：长轮询领取调用并回传结果
+	go func() {
+		code, resp := do(t, s, http.MethodGet, "/v1/handlers/app.health/next?wait=5", nil)
+		if code != http.StatusOK {
+			return
+		}
+		inv := resp["data"].(map[string]interface{})
+		do(t, s, http.MethodPost, "/v1/handlers/app.health/invocations/"+inv["id"].(string), map[string]interface{}{
+			"result": map[string]interface{}{"healthy": true, "payload": inv["payload"]},
+		})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := r.ExecuteWithContext(ctx, "app.health", []byte(`{"deep":true}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"healthy":true,"payload":{"deep":true}}`, string(out))
+
+	// 应用回传错误
+	go func() {
+		_, resp := do(t, s, http.MethodGet, "/v1/handlers/app.health/next?wait=5", nil)
+		inv := resp["data"].(map[string]interface{})
+		do(t, s, http.MethodPost, "/v1/handlers/app.health/invocations/"+inv["id"].(string), map[string]interface{}{"error": "unhealthy"})
+	}()
+	_, err = r.ExecuteWithContext(ctx, "app.health", nil)
+	assert.EqualError(t, err, "unhealthy")
+
+	// 无调用时轮询返回 204
+	code, _ = do(t, s, http.MethodGet, "/v1/handlers/app.health/next?wait=0", nil)
+	assert.Equal(t, http.StatusNoContent, code)
+
+	code, _ = do(t, s, http.MethodDelete, "/v1/handlers/app.health", nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.False(t, r.HasHandler("app.health"))
+	code, _ = do(t, s, http.MethodGet, "/v1/handlers/app.health/next?wait=0", nil)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestHandlerLeaseExpires(t *testing.T) {
+	s, r, _ := newTestServer(t)
+	_, err := s.handlers.register("app.short", "", time.Second)
+	require.NoError(t, err)
+
+	assert.Empty(t, s.handlers.expire(time.Now()))
+	assert.Equal(t, []string{"app.short"}, s.handlers.expire(time.Now().Add(2*time.Second)))
+	assert.False(t, r.HasHandler("app.short"))
+}
+
+func TestServeUnixSocket(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	require.NoError(t, s.Start())
+	defer s.Stop(context.Background())
+	info, err := os.Stat(s.address)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(socketMode), info.Mode().Perm())
+
+	httpClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", s.address)
+		},
+	}}
+	resp, err := httpClient.Get("http://agent/v1/status")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -9,7 +9,7 @@ import (
 // Logger 封装结构化日志功能
 type Logger struct {
 	logger *slog.Logger
-	level  slog.Level
+	level  *slog.LevelVar // 与子 Logger 共享，支持运行时调整
 }
 
 // LogLevel 定义日志级别类型
@@ -40,8 +40,11 @@ func NewLogger(level LogLevel, format string) *Logger {
 		slogLevel = slog.LevelInfo
 	}
 
+	levelVar := new(slog.LevelVar)
+	levelVar.Set(slogLevel)
+
 	opts := &slog.HandlerOptions{
-		Level:     slogLevel,
+		Level:     levelVar,
 		AddSource: true, // 添加源文件和行号
 	}
 
@@ -54,7 +57,7 @@ func NewLogger(level LogLevel, format string) *Logger {
 
 	return &Logger{
 		logger: slog.New(handler),
-		level:  slogLevel,
+		level:  levelVar,
 	}
 }
 
@@ -119,7 +122,7 @@ func (l *Logger) WithGroup(name string) *Logger {
 	}
 }
 
-// SetLevel 设置日志级别
+// SetLevel 设置日志级别（对已创建的子 Logger 同样生效）
 func (l *Logger) SetLevel(level LogLevel) {
 	var slogLevel slog.Level
 	switch level {
@@ -134,7 +137,20 @@ func (l *Logger) SetLevel(level LogLevel) {
 	default:
 		slogLevel = slog.LevelInfo
 	}
-	l.level = slogLevel
+	l.level.Set(slogLevel)
+}
+
+// Level 返回当前日志级别
+func (l *Logger) Level() LogLevel {
+	switch l.level.Level() {
+	case slog.LevelDebug:
+		return LogLevelDebug
+	case slog.LevelWarn:
+		return LogLevelWarn
+	case slog.LevelError:
+		return LogLevelError
+	}
+	return LogLevelInfo
 }
 
 // Enabled 检查指定级别的日志是否会被记录
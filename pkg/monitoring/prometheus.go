@@ -3,7 +3,10 @@ package monitoring
 import (
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/voilet/quic-flow/pkg/protocol"
 )
@@ -11,24 +14,56 @@ import (
 // PrometheusHandler HTTP handler for Prometheus metrics export (T048)
 // 生成 Prometheus 文本格式的指标输出
 type PrometheusHandler struct {
-	metrics *Metrics
-	prefix  string // 指标名称前缀（默认 "quic_backbone_"）
+	snapshot func() *protocol.MetricsSnapshot
+	prefix   string // 指标名称前缀（默认 "quic_backbone_"）
+
+	collectorsMu sync.RWMutex
+	collectors   []Collector // 外部模块注册的指标
+}
+
+// MetricSample 带标签的指标样本
+type MetricSample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// MetricFamily 同名指标的所有样本
+type MetricFamily struct {
+	Name    string // 不含前缀
+	Help    string
+	Type    string // gauge、counter
+	Samples []MetricSample
 }
 
+// Collector 外部模块的指标采集函数，每次抓取时调用
+type Collector func() []*MetricFamily
+
 // NewPrometheusHandler 创建新的 Prometheus Handler
 func NewPrometheusHandler(metrics *Metrics, prefix string) *PrometheusHandler {
+	return NewSnapshotPrometheusHandler(metrics.GetSnapshot, prefix)
+}
+
+// NewSnapshotPrometheusHandler 创建从指标快照函数（如 Server.GetMetrics）导出的 Prometheus Handler
+func NewSnapshotPrometheusHandler(snapshot func() *protocol.MetricsSnapshot, prefix string) *PrometheusHandler {
 	if prefix == "" {
 		prefix = "quic_backbone_"
 	}
 	return &PrometheusHandler{
-		metrics: metrics,
-		prefix:  prefix,
+		snapshot: snapshot,
+		prefix:   prefix,
 	}
 }
 
+// AddCollector 注册外部模块的指标，可在服务运行中注册
+func (h *PrometheusHandler) AddCollector(c Collector) {
+	h.collectorsMu.Lock()
+	defer h.collectorsMu.Unlock()
+	h.collectors = append(h.collectors, c)
+}
+
 // ServeHTTP 实现 http.Handler 接口
 func (h *PrometheusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	snapshot := h.metrics.GetSnapshot()
+	snapshot := h.snapshot()
 
 	// 设置 Content-Type
 	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
@@ -84,9 +119,53 @@ func (h *PrometheusHandler) formatMetrics(snapshot *protocol.MetricsSnapshot) st
 	// 系统指标
 	h.writeGauge(&sb, "uptime_seconds", "System uptime in seconds", snapshot.UptimeSeconds)
 
+	// 外部模块的指标
+	h.collectorsMu.RLock()
+	collectors := h.collectors
+	h.collectorsMu.RUnlock()
+	for _, collect := range collectors {
+		for _, family := range collect() {
+			h.writeFamily(&sb, family)
+		}
+	}
+
 	return sb.String()
 }
 
+// writeFamily 写入带标签的指标，标签按名称排序；没有样本时不输出
+func (h *PrometheusHandler) writeFamily(sb *strings.Builder, family *MetricFamily) {
+	if len(family.Samples) == 0 {
+		return
+	}
+	fullName := h.prefix + family.Name
+	sb.WriteString(fmt.Sprintf("# HELP %s %s\n", fullName, family.Help))
+	sb.WriteString(fmt.Sprintf("# TYPE %s %s\n", fullName, family.Type))
+
+	for _, sample := range family.Samples {
+		sb.WriteString(fullName)
+		if len(sample.Labels) > 0 {
+			names := make([]string, 0, len(sample.Labels))
+			for name := range sample.Labels {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			sb.WriteString("{")
+			for i, name := range names {
+				if i > 0 {
+					sb.WriteString(",")
+				}
+				sb.WriteString(fmt.Sprintf("%s=\"%s\"", name, labelEscaper.Replace(sample.Labels[name])))
+			}
+			sb.WriteString("}")
+		}
+		sb.WriteString(" " + strconv.FormatFloat(sample.Value, 'g', -1, 64) + "\n")
+	}
+}
+
+// labelEscaper 转义标签值中的反斜杠、双引号与换行
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
 // writeGauge 写入 Gauge 类型指标
 func (h *PrometheusHandler) writeGauge(sb *strings.Builder, name, help string, value int64) {
 	fullName := h.prefix + name
@@ -0,0 +1,123 @@
+package netdiag
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/command"
+)
+
+// DNS 查询默认值
+const dnsDefaultTimeout = 5 * time.Second
+
+// DNSLookup 解析指定类型的 DNS 记录
+func DNSLookup(ctx context.Context, params command.NetworkDNSParams) *command.NetworkDNSResult {
+	recordType := strings.ToUpper(params.Type)
+	if recordType == "" {
+		recordType = "A"
+	}
+	result := &command.NetworkDNSResult{
+		Name:    params.Name,
+		Type:    recordType,
+		Server:  params.Server,
+		Records: []string{},
+	}
+
+	if params.Name == "" {
+		result.Error = "name is required"
+		return result
+	}
+	timeout := time.Duration(params.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = dnsDefaultTimeout
+	}
+
+	resolver := net.DefaultResolver
+	if params.Server != "" {
+		server := params.Server
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		result.Server = server
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				d := net.Dialer{Timeout: timeout}
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	records, err := lookupRecords(lookupCtx, resolver, recordType, params.Name)
+	result.DurationMs = msFloat(time.Since(start))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Records = records
+	result.Success = true
+	return result
+}
+
+// lookupRecords 按记录类型查询
+func lookupRecords(ctx context.Context, r *net.Resolver, recordType, name string) ([]string, error) {
+	var records []string
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := r.LookupIP(ctx, network, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			records = append(records, ip.String())
+		}
+	case "CNAME":
+		cname, err := r.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, cname)
+	case "MX":
+		mxs, err := r.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range mxs {
+			records = append(records, fmt.Sprintf("%d %s", mx.Pref, mx.Host))
+		}
+	case "TXT":
+		txts, err := r.LookupTXT(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, txts...)
+	case "NS":
+		nss, err := r.LookupNS(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range nss {
+			records = append(records, ns.Host)
+		}
+	case "PTR":
+		names, err := r.LookupAddr(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, names...)
+	default:
+		return nil, fmt.Errorf("unsupported record type: %s", recordType)
+	}
+	return records, nil
+}
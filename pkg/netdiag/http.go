@@ -0,0 +1,134 @@
+package netdiag
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/command"
+)
+
+// HTTP 探测默认值
+const (
+	httpDefaultTimeout = 10 * time.Second
+	httpMaxTimeout     = 60 * time.Second
+	httpMaxBodyRead    = 10 * 1024 * 1024 // 最多读取 10MB 响应体
+)
+
+// HTTPProbe 发起一次 HTTP 请求并返回各阶段耗时
+func HTTPProbe(ctx context.Context, params command.NetworkHTTPParams) *command.NetworkHTTPResult {
+	result := &command.NetworkHTTPResult{URL: params.URL}
+
+	if !strings.HasPrefix(params.URL, "http://") && !strings.HasPrefix(params.URL, "https://") {
+		result.Error = "url must start with http:// or https://"
+		return result
+	}
+	method := strings.ToUpper(params.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	timeout := time.Duration(params.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = httpDefaultTimeout
+	}
+	if timeout > httpMaxTimeout {
+		timeout = httpMaxTimeout
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var dnsStart, dnsDone, connStart, connDone, tlsStart, tlsDone, wrote, firstByte time.Time
+	trace := &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:           func(httptrace.DNSDoneInfo) { dnsDone = time.Now() },
+		ConnectStart:      func(string, string) { connStart = time.Now() },
+		ConnectDone:       func(string, string, error) { connDone = time.Now() },
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { tlsDone = time.Now() },
+		GotConn: func(info httptrace.GotConnInfo) {
+			result.RemoteAddr = info.Conn.RemoteAddr().String()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { wrote = time.Now() },
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(reqCtx, trace), method, params.URL, nil)
+	if err != nil {
+		result.Error = fmt.Sprintf("build request: %v", err)
+		return result
+	}
+	for k, v := range params.Headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			DisableKeepAlives: true,
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: params.InsecureTLS},
+		},
+	}
+	if !params.FollowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.TotalMs = msFloat(time.Since(start))
+		result.Error = err.Error()
+		fillHTTPTimings(result, dnsStart, dnsDone, connStart, connDone, tlsStart, tlsDone, wrote, firstByte)
+		return result
+	}
+	defer resp.Body.Close()
+
+	n, _ := io.Copy(io.Discard, io.LimitReader(resp.Body, httpMaxBodyRead))
+	result.TotalMs = msFloat(time.Since(start))
+	result.BodyBytes = n
+	result.StatusCode = resp.StatusCode
+	if resp.TLS != nil {
+		result.TLSVersion = tls.VersionName(resp.TLS.Version)
+	}
+	fillHTTPTimings(result, dnsStart, dnsDone, connStart, connDone, tlsStart, tlsDone, wrote, firstByte)
+
+	if params.ExpectStatus > 0 {
+		result.Success = resp.StatusCode == params.ExpectStatus
+		if !result.Success {
+			result.Error = fmt.Sprintf("unexpected status %d, want %d", resp.StatusCode, params.ExpectStatus)
+		}
+	} else {
+		result.Success = resp.StatusCode < 400
+		if !result.Success {
+			result.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+		}
+	}
+	return result
+}
+
+// fillHTTPTimings 根据 httptrace 时间点计算各阶段耗时
+func fillHTTPTimings(result *command.NetworkHTTPResult, dnsStart, dnsDone, connStart, connDone, tlsStart, tlsDone, wrote, firstByte time.Time) {
+	if !dnsStart.IsZero() && !dnsDone.IsZero() {
+		result.DNSMs = msFloat(dnsDone.Sub(dnsStart))
+	}
+	if !connStart.IsZero() && !connDone.IsZero() {
+		result.ConnectMs = msFloat(connDone.Sub(connStart))
+	}
+	if !tlsStart.IsZero() && !tlsDone.IsZero() {
+		result.TLSMs = msFloat(tlsDone.Sub(tlsStart))
+	}
+	if !wrote.IsZero() && !firstByte.IsZero() {
+		result.TTFBMs = msFloat(firstByte.Sub(wrote))
+	}
+}
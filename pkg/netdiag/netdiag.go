@@ -0,0 +1,81 @@
+// Package netdiag 提供 Agent 端原生网络诊断（Ping/Traceroute/TCP/HTTP/DNS）
+package netdiag
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"time"
+)
+
+// resolveIP 解析目标地址，优先返回 IPv4
+func resolveIP(ctx context.Context, target string) (net.IP, error) {
+	if target == "" {
+		return nil, fmt.Errorf("target is required")
+	}
+	if ip := net.ParseIP(target); ip != nil {
+		return ip, nil
+	}
+
+	resolveCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(resolveCtx, target)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", target, err)
+	}
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			return addr.IP, nil
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("resolve %s: no address found", target)
+	}
+	return addrs[0].IP, nil
+}
+
+// rttStats 计算 RTT 统计值（忽略小于 0 的超时项）
+func rttStats(rtts []float64) (min, avg, max, stddev float64) {
+	var valid []float64
+	for _, v := range rtts {
+		if v >= 0 {
+			valid = append(valid, v)
+		}
+	}
+	if len(valid) == 0 {
+		return 0, 0, 0, 0
+	}
+
+	min, max = valid[0], valid[0]
+	var sum float64
+	for _, v := range valid {
+		sum += v
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	avg = sum / float64(len(valid))
+
+	var variance float64
+	for _, v := range valid {
+		variance += (v - avg) * (v - avg)
+	}
+	stddev = math.Sqrt(variance / float64(len(valid)))
+
+	return round2(min), round2(avg), round2(max), round2(stddev)
+}
+
+// msFloat 将耗时转换为毫秒（保留 3 位小数）
+func msFloat(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*1000) / 1000
+}
+
+// round2 保留 2 位小数
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
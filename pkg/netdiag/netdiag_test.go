@@ -0,0 +1,75 @@
+package netdiag
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/voilet/quic-flow/pkg/command"
+)
+
+func TestRTTStats(t *testing.T) {
+	min, avg, max, stddev := rttStats([]float64{1, -1, 3})
+	if min != 1 || avg != 2 || max != 3 || stddev != 1 {
+		t.Errorf("rttStats = %v %v %v %v, want 1 2 3 1", min, avg, max, stddev)
+	}
+
+	min, avg, max, _ = rttStats([]float64{-1, -1})
+	if min != 0 || avg != 0 || max != 0 {
+		t.Errorf("rttStats(all timeout) = %v %v %v, want zeros", min, avg, max)
+	}
+}
+
+func TestMatchQuotedEcho(t *testing.T) {
+	// 20 字节 IPv4 头 + 8 字节 ICMP Echo 头
+	data := make([]byte, 28)
+	data[0] = 0x45
+	binary.BigEndian.PutUint16(data[24:26], 1234)
+	binary.BigEndian.PutUint16(data[26:28], 7)
+
+	if !matchQuotedEcho(data, false, 1234, 7) {
+		t.Error("expected quoted echo to match")
+	}
+	if matchQuotedEcho(data, false, 1234, 8) {
+		t.Error("expected seq mismatch")
+	}
+	if matchQuotedEcho(data[:20], false, 1234, 7) {
+		t.Error("expected short data to be rejected")
+	}
+}
+
+func TestTCPConnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	result := TCPConnect(context.Background(), command.NetworkTCPParams{Host: "127.0.0.1", Port: port, Count: 2})
+	if !result.Success || result.Successes != 2 || result.Attempts != 2 {
+		t.Errorf("TCPConnect = %+v, want 2/2 successes", result)
+	}
+
+	result = TCPConnect(context.Background(), command.NetworkTCPParams{Host: "127.0.0.1", Port: 0})
+	if result.Success || result.Error == "" {
+		t.Errorf("TCPConnect(port 0) = %+v, want error", result)
+	}
+}
+
+func TestDNSLookup_UnsupportedType(t *testing.T) {
+	result := DNSLookup(context.Background(), command.NetworkDNSParams{Name: "localhost", Type: "SRV"})
+	if result.Success || result.Error == "" {
+		t.Errorf("DNSLookup(SRV) = %+v, want error", result)
+	}
+}
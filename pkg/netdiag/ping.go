@@ -0,0 +1,250 @@
+package netdiag
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"github.com/voilet/quic-flow/pkg/command"
+)
+
+// Ping 相关默认值
+const (
+	pingDefaultCount    = 4
+	pingMaxCount        = 100
+	pingDefaultInterval = time.Second
+	pingMinInterval     = 200 * time.Millisecond
+	pingDefaultTimeout  = 2 * time.Second
+	pingDefaultSize     = 56
+	pingMaxSize         = 8192
+)
+
+// Ping 模式
+const (
+	PingModeICMP = "icmp" // raw ICMP socket，需要 root 或 CAP_NET_RAW
+	PingModeUDP  = "udp"  // 非特权 ICMP datagram socket（Linux 需 net.ipv4.ping_group_range 允许）
+)
+
+// icmpConn 封装 ICMP 连接及其地址族相关参数
+type icmpConn struct {
+	conn     *icmp.PacketConn
+	mode     string
+	ipv6     bool
+	proto    int
+	echoType icmp.Type
+	replyTyp icmp.Type
+	dst      net.Addr
+}
+
+// Ping 向目标发送 ICMP Echo 并统计 RTT
+func Ping(ctx context.Context, params command.NetworkPingParams) *command.NetworkPingResult {
+	result := &command.NetworkPingResult{Target: params.Target}
+
+	count := params.Count
+	if count <= 0 {
+		count = pingDefaultCount
+	}
+	if count > pingMaxCount {
+		count = pingMaxCount
+	}
+	interval := time.Duration(params.IntervalMs) * time.Millisecond
+	if interval <= 0 {
+		interval = pingDefaultInterval
+	}
+	if interval < pingMinInterval {
+		interval = pingMinInterval
+	}
+	timeout := time.Duration(params.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = pingDefaultTimeout
+	}
+	size := params.Size
+	if size <= 0 {
+		size = pingDefaultSize
+	}
+	if size > pingMaxSize {
+		size = pingMaxSize
+	}
+
+	ip, err := resolveIP(ctx, params.Target)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.ResolvedIP = ip.String()
+
+	c, err := openICMP(ip, params.Mode)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer c.conn.Close()
+	result.Mode = c.mode
+
+	id := os.Getpid() & 0xffff
+	payload := make([]byte, size)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+
+	for seq := 0; seq < count; seq++ {
+		if ctx.Err() != nil {
+			break
+		}
+		start := time.Now()
+		rtt, err := c.echo(id, seq, payload, timeout)
+		result.Sent++
+		if err != nil {
+			result.RTTs = append(result.RTTs, -1)
+		} else {
+			result.Received++
+			result.RTTs = append(result.RTTs, msFloat(rtt))
+		}
+
+		if seq < count-1 {
+			if wait := interval - time.Since(start); wait > 0 {
+				select {
+				case <-ctx.Done():
+				case <-time.After(wait):
+				}
+			}
+		}
+	}
+
+	if result.Sent > 0 {
+		result.LossPercent = round2(float64(result.Sent-result.Received) / float64(result.Sent) * 100)
+	}
+	result.MinMs, result.AvgMs, result.MaxMs, result.StddevMs = rttStats(result.RTTs)
+	result.Success = result.Received > 0
+	if !result.Success && result.Error == "" {
+		result.Error = "100% packet loss"
+	}
+	return result
+}
+
+// openICMP 打开 ICMP 连接
+// mode 为空时先尝试非特权 socket，失败后回退到 raw socket
+func openICMP(ip net.IP, mode string) (*icmpConn, error) {
+	modes := []string{PingModeUDP, PingModeICMP}
+	switch mode {
+	case "":
+	case PingModeUDP, PingModeICMP:
+		modes = []string{mode}
+	default:
+		return nil, fmt.Errorf("unsupported ping mode: %s", mode)
+	}
+
+	var lastErr error
+	for _, m := range modes {
+		c, err := listenICMP(ip, m)
+		if err == nil {
+			return c, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("open icmp socket: %w", lastErr)
+}
+
+// listenICMP 按模式和地址族创建 ICMP 连接
+func listenICMP(ip net.IP, mode string) (*icmpConn, error) {
+	c := &icmpConn{mode: mode, ipv6: ip.To4() == nil}
+
+	var network, laddr string
+	if c.ipv6 {
+		c.proto = 58
+		c.echoType, c.replyTyp = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+		laddr = "::"
+		network = "ip6:ipv6-icmp"
+		if mode == PingModeUDP {
+			network = "udp6"
+		}
+	} else {
+		c.proto = 1
+		c.echoType, c.replyTyp = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+		laddr = "0.0.0.0"
+		network = "ip4:icmp"
+		if mode == PingModeUDP {
+			network = "udp4"
+		}
+	}
+
+	conn, err := icmp.ListenPacket(network, laddr)
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+
+	if mode == PingModeUDP {
+		c.dst = &net.UDPAddr{IP: ip}
+	} else {
+		c.dst = &net.IPAddr{IP: ip}
+	}
+	return c, nil
+}
+
+// echo 发送一个 Echo 请求并等待对应的回复
+func (c *icmpConn) echo(id, seq int, payload []byte, timeout time.Duration) (time.Duration, error) {
+	msg := icmp.Message{
+		Type: c.echoType,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: payload},
+	}
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	if _, err := c.conn.WriteTo(b, c.dst); err != nil {
+		return 0, err
+	}
+
+	deadline := start.Add(timeout)
+	if err := c.conn.SetReadDeadline(deadline); err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, 1500+len(payload))
+	for {
+		n, peer, err := c.conn.ReadFrom(buf)
+		if err != nil {
+			return 0, err
+		}
+		rtt := time.Since(start)
+
+		reply, err := icmp.ParseMessage(c.proto, buf[:n])
+		if err != nil || reply.Type != c.replyTyp {
+			continue
+		}
+		echo, ok := reply.Body.(*icmp.Echo)
+		if !ok || echo.Seq != seq {
+			continue
+		}
+		// 非特权 socket 的 ID 由内核改写，且只会收到本 socket 的回复
+		if c.mode == PingModeICMP && (echo.ID != id || !sameHost(peer, c.dst)) {
+			continue
+		}
+		return rtt, nil
+	}
+}
+
+// sameHost 比较两个地址的 IP 是否相同
+func sameHost(a, b net.Addr) bool {
+	return addrIP(a).Equal(addrIP(b))
+}
+
+// addrIP 提取地址中的 IP
+func addrIP(a net.Addr) net.IP {
+	switch v := a.(type) {
+	case *net.IPAddr:
+		return v.IP
+	case *net.UDPAddr:
+		return v.IP
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package netdiag
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/command"
+)
+
+// TCP 连接测试默认值
+const (
+	tcpDefaultCount   = 3
+	tcpMaxCount       = 100
+	tcpDefaultTimeout = 3 * time.Second
+	tcpProbeInterval  = 200 * time.Millisecond
+)
+
+// TCPConnect 多次建立 TCP 连接并统计耗时（不含 DNS 解析）
+func TCPConnect(ctx context.Context, params command.NetworkTCPParams) *command.NetworkTCPResult {
+	result := &command.NetworkTCPResult{
+		Address: net.JoinHostPort(params.Host, strconv.Itoa(params.Port)),
+	}
+
+	if params.Port <= 0 || params.Port > 65535 {
+		result.Error = fmt.Sprintf("invalid port: %d", params.Port)
+		return result
+	}
+	count := params.Count
+	if count <= 0 {
+		count = tcpDefaultCount
+	}
+	if count > tcpMaxCount {
+		count = tcpMaxCount
+	}
+	timeout := time.Duration(params.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = tcpDefaultTimeout
+	}
+
+	ip, err := resolveIP(ctx, params.Host)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.ResolvedIP = ip.String()
+	addr := net.JoinHostPort(ip.String(), strconv.Itoa(params.Port))
+
+	dialer := &net.Dialer{Timeout: timeout}
+	for i := 0; i < count; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(tcpProbeInterval):
+			}
+		}
+
+		result.Attempts++
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			result.Times = append(result.Times, -1)
+			result.Error = err.Error()
+			continue
+		}
+		result.Times = append(result.Times, msFloat(time.Since(start)))
+		result.Successes++
+		conn.Close()
+	}
+
+	result.MinMs, result.AvgMs, result.MaxMs, _ = rttStats(result.Times)
+	result.Success = result.Successes > 0
+	if result.Success && result.Successes == result.Attempts {
+		result.Error = ""
+	}
+	return result
+}
@@ -0,0 +1,205 @@
+package netdiag
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"github.com/voilet/quic-flow/pkg/command"
+)
+
+// Traceroute 相关默认值
+const (
+	traceDefaultMaxHops = 30
+	traceMaxHops        = 64
+	traceDefaultQueries = 3
+	traceMaxQueries     = 10
+	traceDefaultTimeout = time.Second
+	traceLookupTimeout  = 500 * time.Millisecond
+)
+
+// Trace 基于 ICMP Echo + 递增 TTL 的路由追踪
+// 需要 raw socket 权限（root 或 CAP_NET_RAW）才能接收 Time Exceeded 报文
+func Trace(ctx context.Context, params command.NetworkTraceParams) *command.NetworkTraceResult {
+	result := &command.NetworkTraceResult{Target: params.Target}
+
+	maxHops := params.MaxHops
+	if maxHops <= 0 {
+		maxHops = traceDefaultMaxHops
+	}
+	if maxHops > traceMaxHops {
+		maxHops = traceMaxHops
+	}
+	queries := params.Queries
+	if queries <= 0 {
+		queries = traceDefaultQueries
+	}
+	if queries > traceMaxQueries {
+		queries = traceMaxQueries
+	}
+	timeout := time.Duration(params.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = traceDefaultTimeout
+	}
+
+	ip, err := resolveIP(ctx, params.Target)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.ResolvedIP = ip.String()
+
+	c, err := listenICMP(ip, PingModeICMP)
+	if err != nil {
+		result.Error = fmt.Sprintf("traceroute requires raw socket privileges: %v", err)
+		return result
+	}
+	defer c.conn.Close()
+
+	id := os.Getpid() & 0xffff
+	seq := 0
+	for ttl := 1; ttl <= maxHops; ttl++ {
+		if ctx.Err() != nil {
+			result.Error = ctx.Err().Error()
+			break
+		}
+		if err := c.setTTL(ttl); err != nil {
+			result.Error = fmt.Sprintf("set ttl: %v", err)
+			break
+		}
+
+		hop := command.NetworkTraceHop{TTL: ttl}
+		reached := false
+		for q := 0; q < queries; q++ {
+			seq++
+			peer, done, rtt, err := c.probe(id, seq, timeout)
+			if err != nil {
+				hop.RTTs = append(hop.RTTs, -1)
+				hop.Loss++
+				continue
+			}
+			hop.RTTs = append(hop.RTTs, msFloat(rtt))
+			if hop.Address == "" {
+				hop.Address = peer
+			}
+			reached = reached || done
+		}
+
+		_, hop.AvgMs, _, _ = rttStats(hop.RTTs)
+		if hop.Address != "" {
+			hop.Hostname = reverseLookup(ctx, hop.Address)
+		}
+		result.Hops = append(result.Hops, hop)
+
+		if reached {
+			result.Success = true
+			break
+		}
+	}
+
+	if !result.Success && result.Error == "" {
+		result.Error = fmt.Sprintf("destination not reached within %d hops", maxHops)
+	}
+	return result
+}
+
+// setTTL 设置出站报文的 TTL / Hop Limit
+func (c *icmpConn) setTTL(ttl int) error {
+	if c.ipv6 {
+		return c.conn.IPv6PacketConn().SetHopLimit(ttl)
+	}
+	return c.conn.IPv4PacketConn().SetTTL(ttl)
+}
+
+// probe 发送一个探测包，返回响应方地址以及是否已到达目标
+func (c *icmpConn) probe(id, seq int, timeout time.Duration) (string, bool, time.Duration, error) {
+	msg := icmp.Message{
+		Type: c.echoType,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("quic-flow-trace")},
+	}
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return "", false, 0, err
+	}
+
+	start := time.Now()
+	if _, err := c.conn.WriteTo(b, c.dst); err != nil {
+		return "", false, 0, err
+	}
+	if err := c.conn.SetReadDeadline(start.Add(timeout)); err != nil {
+		return "", false, 0, err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := c.conn.ReadFrom(buf)
+		if err != nil {
+			return "", false, 0, err
+		}
+		rtt := time.Since(start)
+
+		reply, err := icmp.ParseMessage(c.proto, buf[:n])
+		if err != nil {
+			continue
+		}
+
+		switch reply.Type {
+		case c.replyTyp:
+			echo, ok := reply.Body.(*icmp.Echo)
+			if ok && echo.ID == id && echo.Seq == seq && sameHost(peer, c.dst) {
+				return addrIP(peer).String(), true, rtt, nil
+			}
+		case ipv4.ICMPTypeTimeExceeded, ipv6.ICMPTypeTimeExceeded:
+			body, ok := reply.Body.(*icmp.TimeExceeded)
+			if ok && matchQuotedEcho(body.Data, c.ipv6, id, seq) {
+				return addrIP(peer).String(), false, rtt, nil
+			}
+		case ipv4.ICMPTypeDestinationUnreachable, ipv6.ICMPTypeDestinationUnreachable:
+			body, ok := reply.Body.(*icmp.DstUnreach)
+			if ok && matchQuotedEcho(body.Data, c.ipv6, id, seq) {
+				// 目标不可达同样视为追踪结束
+				return addrIP(peer).String(), true, rtt, nil
+			}
+		}
+	}
+}
+
+// matchQuotedEcho 检查 ICMP 差错报文中引用的原始报文是否为本次探测
+// data 为原始 IP 头 + 至少 8 字节的 ICMP 头
+func matchQuotedEcho(data []byte, isIPv6 bool, id, seq int) bool {
+	var hdrLen int
+	if isIPv6 {
+		hdrLen = ipv6.HeaderLen
+	} else {
+		if len(data) < 1 {
+			return false
+		}
+		hdrLen = int(data[0]&0x0f) * 4
+	}
+	if len(data) < hdrLen+8 {
+		return false
+	}
+	echo := data[hdrLen:]
+	return int(binary.BigEndian.Uint16(echo[4:6])) == id &&
+		int(binary.BigEndian.Uint16(echo[6:8])) == seq
+}
+
+// reverseLookup 反向解析主机名（失败时返回空）
+func reverseLookup(ctx context.Context, addr string) string {
+	lookupCtx, cancel := context.WithTimeout(ctx, traceLookupTimeout)
+	defer cancel()
+
+	names, err := net.DefaultResolver.LookupAddr(lookupCtx, addr)
+	if err != nil || len(names) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(names[0], ".")
+}
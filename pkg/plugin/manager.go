@@ -0,0 +1,255 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/fsutil"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/router"
+)
+
+// TaskConfig 插件执行器配置（任务 ExecutorType 为 PLUGIN 时的 executor_config）
+type TaskConfig struct {
+	Command string          `json:"command"`          // 插件命令类型
+	Params  json.RawMessage `json:"params,omitempty"` // 命令载荷
+}
+
+// loaded 已加载的插件
+type loaded struct {
+	manifest *Manifest
+	invoker  invoker
+	info     command.PluginInfo
+}
+
+// Manager 插件管理器
+// 扫描插件目录中的 <name>/plugin.json，将声明的命令类型注册到路由器，
+// 重新扫描时注册新增命令、注销已删除插件的命令，清单变化的插件会被重新加载
+type Manager struct {
+	dir    string
+	router *router.Router
+	logger *monitoring.Logger
+
+	mu       sync.Mutex
+	plugins  map[string]*loaded            // 插件目录名 -> 插件
+	commands map[string]string             // 命令类型 -> 插件目录名
+	invalid  map[string]command.PluginInfo // 清单无效的插件目录
+}
+
+// NewManager 创建插件管理器
+func NewManager(dir string, r *router.Router, logger *monitoring.Logger) *Manager {
+	if logger == nil {
+		logger = monitoring.NewDefaultLogger()
+	}
+	return &Manager{
+		dir:      dir,
+		router:   r,
+		logger:   logger,
+		plugins:  make(map[string]*loaded),
+		commands: make(map[string]string),
+		invalid:  make(map[string]command.PluginInfo),
+	}
+}
+
+// Dir 返回插件目录
+func (m *Manager) Dir() string {
+	return m.dir
+}
+
+// Scan 扫描插件目录并同步路由注册，目录不存在时卸载全部插件
+// 插件目录对组或其他用户可写、或者不属于 root 与当前用户时拒绝扫描
+func (m *Manager) Scan() (*command.PluginScanResult, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read plugins dir: %w", err)
+	}
+	if err == nil {
+		if err := fsutil.CheckPermissions(m.dir); err != nil {
+			return nil, fmt.Errorf("plugins dir: %w", err)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := &command.PluginScanResult{Loaded: []string{}}
+	found := make(map[string]*Manifest)
+	m.invalid = make(map[string]command.PluginInfo)
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(m.dir, e.Name())
+		if _, err := os.Stat(filepath.Join(dir, ManifestFile)); os.IsNotExist(err) {
+			continue
+		}
+		manifest, err := LoadManifest(dir)
+		if err != nil {
+			m.invalid[e.Name()] = command.PluginInfo{Name: e.Name(), Dir: dir, Error: err.Error()}
+			result.Errors = append(result.Errors, err.Error())
+			m.logger.Warn("Invalid plugin", "dir", dir, "error", err)
+			continue
+		}
+		found[e.Name()] = manifest
+	}
+
+	// 卸载已删除或已变化的插件
+	for key, p := range m.plugins {
+		if manifest, ok := found[key]; ok && reflect.DeepEqual(manifest, p.manifest) {
+			continue
+		}
+		m.unload(key, result)
+		if _, ok := found[key]; !ok {
+			result.Unloaded = append(result.Unloaded, p.manifest.Name)
+		}
+	}
+
+	// 加载新增或变化的插件（按目录名排序，冲突时先加载者优先）
+	keys := make([]string, 0, len(found))
+	for key := range found {
+		if _, ok := m.plugins[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		m.load(key, found[key], result)
+	}
+
+	sort.Strings(result.Registered)
+	sort.Strings(result.Removed)
+	m.logger.Info("Plugins scanned", "dir", m.dir, "plugins", len(m.plugins),
+		"loaded", len(result.Loaded), "unloaded", len(result.Unloaded), "errors", len(result.Errors))
+	return result, nil
+}
+
+// load 注册插件命令（调用方持有锁）
+func (m *Manager) load(key string, manifest *Manifest, result *command.PluginScanResult) {
+	p := &loaded{
+		manifest: manifest,
+		info: command.PluginInfo{
+			Name:     manifest.Name,
+			Version:  manifest.Version,
+			Protocol: string(manifest.Protocol),
+			Dir:      filepath.Dir(manifest.Executable),
+		},
+	}
+	switch manifest.Protocol {
+	case ProtocolJSONRPC:
+		p.invoker = &rpcInvoker{manifest: manifest, logger: m.logger}
+	default:
+		p.invoker = &execInvoker{manifest: manifest}
+	}
+
+	for _, cmd := range manifest.Commands {
+		if _, owned := m.commands[cmd]; owned || m.router.HasHandler(cmd) {
+			p.info.Skipped = append(p.info.Skipped, cmd)
+			m.logger.Warn("Plugin command conflicts with an existing handler", "plugin", manifest.Name, "command_type", cmd)
+			continue
+		}
+		m.router.Register(cmd, m.handler(p, cmd))
+		m.commands[cmd] = key
+		p.info.Commands = append(p.info.Commands, cmd)
+		result.Registered = append(result.Registered, cmd)
+	}
+	m.plugins[key] = p
+	result.Loaded = append(result.Loaded, manifest.Name)
+	m.logger.Info("Plugin loaded", "plugin", manifest.Name, "version", manifest.Version, "commands", p.info.Commands)
+}
+
+// unload 注销插件命令并停止进程（调用方持有锁）
+func (m *Manager) unload(key string, result *command.PluginScanResult) {
+	p := m.plugins[key]
+	for _, cmd := range p.info.Commands {
+		m.router.Unregister(cmd)
+		delete(m.commands, cmd)
+		result.Removed = append(result.Removed, cmd)
+	}
+	delete(m.plugins, key)
+	go p.invoker.stop()
+	m.logger.Info("Plugin unloaded", "plugin", p.manifest.Name)
+}
+
+// handler 返回插件命令的路由处理函数
+func (m *Manager) handler(p *loaded, cmd string) router.Handler {
+	timeout := time.Duration(p.manifest.TimeoutSec) * time.Second
+	return func(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return p.invoker.call(ctx, cmd, payload)
+	}
+}
+
+// List 列出插件（含无效插件）
+func (m *Manager) List() []command.PluginInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]command.PluginInfo, 0, len(m.plugins)+len(m.invalid))
+	for _, p := range m.plugins {
+		list = append(list, p.info)
+	}
+	for _, info := range m.invalid {
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Dir < list[j].Dir })
+	return list
+}
+
+// Execute 执行插件命令
+func (m *Manager) Execute(ctx context.Context, cmdType string, payload json.RawMessage) (json.RawMessage, error) {
+	m.mu.Lock()
+	key, ok := m.commands[cmdType]
+	var p *loaded
+	if ok {
+		p = m.plugins[key]
+	}
+	m.mu.Unlock()
+	if p == nil {
+		return nil, fmt.Errorf("no plugin provides command: %s", cmdType)
+	}
+	return m.handler(p, cmdType)(ctx, payload)
+}
+
+// ExecuteTask 按任务执行器配置（TaskConfig JSON）执行插件命令
+func (m *Manager) ExecuteTask(ctx context.Context, executorConfig string) (json.RawMessage, error) {
+	var cfg TaskConfig
+	if err := json.Unmarshal([]byte(executorConfig), &cfg); err != nil {
+		return nil, fmt.Errorf("invalid plugin executor config: %w", err)
+	}
+	if cfg.Command == "" {
+		return nil, fmt.Errorf("plugin executor config: command is required")
+	}
+	return m.Execute(ctx, cfg.Command, cfg.Params)
+}
+
+// Stop 停止所有常驻插件进程并注销命令
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	plugins := m.plugins
+	for _, p := range plugins {
+		for _, cmd := range p.info.Commands {
+			m.router.Unregister(cmd)
+		}
+	}
+	m.plugins = make(map[string]*loaded)
+	m.commands = make(map[string]string)
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, p := range plugins {
+		wg.Add(1)
+		go func(p *loaded) {
+			defer wg.Done()
+			p.invoker.stop()
+		}(p)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,201 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/router"
+)
+
+// echoScript exec 协议：输出命令类型与载荷
+const echoScript = `#!/bin/sh
+payload=$(cat)
+if [ "$1" = "demo.fail" ]; then
+  echo "something broke" >&2
+  exit 3
+fi
+printf '{"command":"%s","payload":%s}' "$1" "$payload"
+`
+
+// rpcScript jsonrpc 协议：逐行读取请求，回复 method
+const rpcScript = `#!/bin/sh
+while read -r line; do
+  id=$(echo "$line" | sed -n 's/.*"id":\([0-9]*\).*/\1/p')
+  method=$(echo "$line" | sed -n 's/.*"method":"\([^"]*\)".*/\1/p')
+  if [ "$method" = "rpc.fail" ]; then
+    printf '{"jsonrpc":"2.0","id":%s,"error":{"code":-32000,"message":"failed"}}\n' "$id"
+  else
+    printf '{"jsonrpc":"2.0","id":%s,"result":{"method":"%s","pid":%s}}\n' "$id" "$method" "$$"
+  fi
+done
+`
+
+// writePlugin 在插件目录下创建插件
+func writePlugin(t *testing.T, root, name, script string, manifest map[string]interface{}) {
+	dir := filepath.Join(root, name)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "run.sh"), []byte(script), 0755))
+	manifest["name"] = name
+	manifest["executable"] = "run.sh"
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestFile), data, 0644))
+}
+
+func newTestManager(t *testing.T) (*Manager, *router.Router, string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	root := t.TempDir()
+	logger := monitoring.NewDefaultLogger()
+	r := router.NewRouter(logger)
+	r.Register("builtin", func(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+		return json.RawMessage(`"builtin"`), nil
+	})
+	m := NewManager(root, r, logger)
+	t.Cleanup(m.Stop)
+	return m, r, root
+}
+
+func TestExecPlugin(t *testing.T) {
+	m, r, root := newTestManager(t)
+	writePlugin(t, root, "demo", echoScript, map[string]interface{}{
+		"commands": []string{"demo.echo", "demo.fail", "builtin"},
+	})
+
+	scan, err := m.Scan()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"demo"}, scan.Loaded)
+	assert.Equal(t, []string{"demo.echo", "demo.fail"}, scan.Registered)
+
+	out, err := r.Execute("demo.echo", []byte(`{"x":1}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"command":"demo.echo","payload":{"x":1}}`, string(out))
+
+	_, err = r.Execute("demo.fail", []byte(`{}`))
+	assert.ErrorContains(t, err, "something broke")
+
+	// 与内置命令冲突时不覆盖
+	out, err = r.Execute("builtin", nil)
+	require.NoError(t, err)
+	assert.Equal(t, `"builtin"`, string(out))
+	list := m.List()
+	require.Len(t, list, 1)
+	assert.Equal(t, []string{"builtin"}, list[0].Skipped)
+}
+
+func TestRPCPlugin(t *testing.T) {
+	m, r, root := newTestManager(t)
+	writePlugin(t, root, "rpc", rpcScript, map[string]interface{}{
+		"protocol": "jsonrpc",
+		"commands": []string{"rpc.hello", "rpc.fail"},
+	})
+	_, err := m.Scan()
+	require.NoError(t, err)
+
+	var first, second struct {
+		Method string `json:"method"`
+		PID    int    `json:"pid"`
+	}
+	out, err := r.Execute("rpc.hello", []byte(`{"a":"b"}`))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(out, &first))
+	assert.Equal(t, "rpc.hello", first.Method)
+
+	// 常驻进程被复用
+	out, err = r.Execute("rpc.hello", nil)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(out, &second))
+	assert.Equal(t, first.PID, second.PID)
+
+	_, err = r.Execute("rpc.fail", nil)
+	assert.ErrorContains(t, err, "failed")
+}
+
+func TestRescan(t *testing.T) {
+	m, r, root := newTestManager(t)
+	writePlugin(t, root, "a", echoScript, map[string]interface{}{"commands": []string{"a.run"}})
+	writePlugin(t, root, "bad", echoScript, map[string]interface{}{"commands": []string{"Bad Command"}})
+	scan, err := m.Scan()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, scan.Loaded)
+	assert.Len(t, scan.Errors, 1)
+	assert.True(t, r.HasHandler("a.run"))
+
+	// 未变化的插件不会重新加载
+	scan, err = m.Scan()
+	require.NoError(t, err)
+	assert.Empty(t, scan.Loaded)
+
+	// 修改清单、新增插件
+	writePlugin(t, root, "a", echoScript, map[string]interface{}{"commands": []string{"a.run2"}})
+	writePlugin(t, root, "b", echoScript, map[string]interface{}{"commands": []string{"b.run"}})
+	scan, err = m.Scan()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b"}, scan.Loaded)
+	assert.Equal(t, []string{"a.run"}, scan.Removed)
+	assert.False(t, r.HasHandler("a.run"))
+	assert.True(t, r.HasHandler("a.run2"))
+
+	// 删除插件
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "b")))
+	scan, err = m.Scan()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, scan.Unloaded)
+	assert.False(t, r.HasHandler("b.run"))
+}
+
+func TestManifestValidation(t *testing.T) {
+	root := t.TempDir()
+	writePlugin(t, root, "escape", echoScript, map[string]interface{}{"commands": []string{"x.run"}})
+	dir := filepath.Join(root, "escape")
+
+	data := []byte(`{"name":"escape","executable":"../other/run.sh","commands":["x.run"]}`)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestFile), data, 0644))
+	_, err := LoadManifest(dir)
+	assert.ErrorContains(t, err, "escapes")
+
+	data = []byte(`{"name":"escape","executable":"run.sh","commands":["x.run"]}`)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestFile), data, 0644))
+	require.NoError(t, os.Chmod(filepath.Join(dir, "run.sh"), 0777))
+	_, err = LoadManifest(dir)
+	assert.ErrorContains(t, err, "writable")
+
+	// 插件子目录与插件目录本身同样不能对组或其他用户可写
+	require.NoError(t, os.Chmod(filepath.Join(dir, "run.sh"), 0755))
+	require.NoError(t, os.Chmod(dir, 0777))
+	_, err = LoadManifest(dir)
+	assert.ErrorContains(t, err, "writable")
+
+	require.NoError(t, os.Chmod(dir, 0755))
+	require.NoError(t, os.Chmod(root, 0777))
+	_, err = NewManager(root, router.NewRouter(nil), nil).Scan()
+	assert.ErrorContains(t, err, "writable")
+}
+
+func TestExecuteTask(t *testing.T) {
+	m, _, root := newTestManager(t)
+	writePlugin(t, root, "demo", echoScript, map[string]interface{}{
+		"commands":    []string{"demo.echo"},
+		"timeout_sec": 5,
+	})
+	_, err := m.Scan()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, err := m.ExecuteTask(ctx, `{"command":"demo.echo","params":{"n":2}}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"command":"demo.echo","payload":{"n":2}}`, string(out))
+
+	_, err = m.ExecuteTask(ctx, `{"command":"missing"}`)
+	assert.ErrorContains(t, err, "no plugin")
+}
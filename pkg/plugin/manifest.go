@@ -0,0 +1,148 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/fsutil"
+)
+
+// ManifestFile 插件清单文件名（位于 <plugins_dir>/<name>/ 下）
+const ManifestFile = "plugin.json"
+
+// Protocol 插件通信协议
+type Protocol string
+
+const (
+	// ProtocolExec 每次调用启动一次可执行文件：命令类型作为第一个参数，
+	// stdin 为命令载荷（JSON），stdout 为结果（JSON），非零退出码表示失败
+	ProtocolExec Protocol = "exec"
+	// ProtocolJSONRPC 常驻进程，通过 stdin/stdout 逐行交换 JSON-RPC 2.0 消息，
+	// method 为命令类型，params 为命令载荷
+	ProtocolJSONRPC Protocol = "jsonrpc"
+)
+
+// 默认值与限制
+const (
+	defaultTimeoutSec = 60
+	maxTimeoutSec     = 3600
+)
+
+var (
+	pluginNameRe  = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+	commandTypeRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,127}$`)
+)
+
+// Manifest 插件清单
+type Manifest struct {
+	Name       string            `json:"name"`
+	Version    string            `json:"version,omitempty"`
+	Executable string            `json:"executable"`            // 相对插件目录的路径
+	Protocol   Protocol          `json:"protocol,omitempty"`    // exec（默认）或 jsonrpc
+	Commands   []string          `json:"commands"`              // 注册的命令类型
+	Args       []string          `json:"args,omitempty"`        // 附加参数（exec 协议位于命令类型之后）
+	Env        map[string]string `json:"env,omitempty"`         // 附加环境变量
+	TimeoutSec int               `json:"timeout_sec,omitempty"` // 单次调用超时，默认 60
+
+	modTime time.Time // 可执行文件修改时间，用于重新扫描时发现替换
+}
+
+// LoadManifest 读取并校验插件目录中的清单
+func LoadManifest(dir string) (*Manifest, error) {
+	path := filepath.Join(dir, ManifestFile)
+	if err := checkTree(dir, path); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := m.validate(dir); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// validate 校验清单并规范化默认值
+func (m *Manifest) validate(dir string) error {
+	if !pluginNameRe.MatchString(m.Name) {
+		return fmt.Errorf("invalid plugin name: %q", m.Name)
+	}
+	switch m.Protocol {
+	case "":
+		m.Protocol = ProtocolExec
+	case ProtocolExec, ProtocolJSONRPC:
+	default:
+		return fmt.Errorf("unsupported protocol: %q", m.Protocol)
+	}
+	if len(m.Commands) == 0 {
+		return fmt.Errorf("no commands declared")
+	}
+	seen := make(map[string]bool)
+	for _, cmd := range m.Commands {
+		if !commandTypeRe.MatchString(cmd) {
+			return fmt.Errorf("invalid command type: %q", cmd)
+		}
+		if seen[cmd] {
+			return fmt.Errorf("duplicate command type: %s", cmd)
+		}
+		seen[cmd] = true
+	}
+	if m.TimeoutSec == 0 {
+		m.TimeoutSec = defaultTimeoutSec
+	}
+	if m.TimeoutSec < 0 || m.TimeoutSec > maxTimeoutSec {
+		return fmt.Errorf("timeout_sec must be between 1 and %d", maxTimeoutSec)
+	}
+
+	// 可执行文件必须位于插件目录内
+	if m.Executable == "" || filepath.IsAbs(m.Executable) {
+		return fmt.Errorf("executable must be a relative path inside the plugin directory")
+	}
+	exe := filepath.Join(dir, m.Executable)
+	if rel, err := filepath.Rel(dir, exe); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("executable escapes the plugin directory: %s", m.Executable)
+	}
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() || info.Mode().Perm()&0111 == 0 {
+		return fmt.Errorf("%s is not executable", m.Executable)
+	}
+	if err := checkTree(dir, exe); err != nil {
+		return err
+	}
+	m.Executable = exe
+	m.modTime = info.ModTime()
+	return nil
+}
+
+// checkTree 检查 path 及其位于 root 内的各级父目录（含 root）
+func checkTree(root, path string) error {
+	for {
+		if err := fsutil.CheckPermissions(path); err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return nil
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,294 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/monitoring"
+)
+
+// 输出限制
+const (
+	maxOutputSize = 16 * 1024 * 1024 // 单次调用结果上限
+	maxStderrSize = 4096             // 错误信息中保留的 stderr 长度
+	stopTimeout   = 2 * time.Second  // 常驻进程退出等待时间
+)
+
+// ErrPluginStopped 插件已停止（被卸载或重新扫描替换）
+var ErrPluginStopped = errors.New("plugin stopped")
+
+// invoker 插件调用方式
+type invoker interface {
+	call(ctx context.Context, command string, payload json.RawMessage) (json.RawMessage, error)
+	stop()
+}
+
+// environ 构造插件进程的环境变量
+func environ(m *Manifest) []string {
+	env := os.Environ()
+	env = append(env, "QUIC_PLUGIN_NAME="+m.Name, "QUIC_PLUGIN_DIR="+filepath.Dir(m.Executable))
+	for k, v := range m.Env {
+		env = append(env, k+"="+v)
+	}
+	return env
+}
+
+// limitedBuffer 超出上限后丢弃数据并记录溢出
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room < len(p) {
+		b.overflow = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// execInvoker exec 协议：每次调用启动一个进程
+type execInvoker struct {
+	manifest *Manifest
+}
+
+func (r *execInvoker) call(ctx context.Context, command string, payload json.RawMessage) (json.RawMessage, error) {
+	args := append([]string{command}, r.manifest.Args...)
+	cmd := exec.CommandContext(ctx, r.manifest.Executable, args...)
+	cmd.Dir = filepath.Dir(r.manifest.Executable)
+	cmd.Env = append(environ(r.manifest), "QUIC_PLUGIN_COMMAND="+command)
+	cmd.Stdin = bytes.NewReader(payload)
+	stdout := &limitedBuffer{limit: maxOutputSize}
+	stderr := &limitedBuffer{limit: maxStderrSize}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("plugin %s: %w", r.manifest.Name, ctx.Err())
+		}
+		if msg := strings.TrimSpace(stderr.buf.String()); msg != "" {
+			return nil, fmt.Errorf("plugin %s: %v: %s", r.manifest.Name, err, msg)
+		}
+		return nil, fmt.Errorf("plugin %s: %w", r.manifest.Name, err)
+	}
+	if stdout.overflow {
+		return nil, fmt.Errorf("plugin %s: output exceeds %d bytes", r.manifest.Name, maxOutputSize)
+	}
+	out := bytes.TrimSpace(stdout.buf.Bytes())
+	if len(out) == 0 {
+		return json.RawMessage("null"), nil
+	}
+	if !json.Valid(out) {
+		return nil, fmt.Errorf("plugin %s: output is not valid JSON", r.manifest.Name)
+	}
+	return json.RawMessage(out), nil
+}
+
+func (r *execInvoker) stop() {}
+
+// rpcRequest JSON-RPC 2.0 请求
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      uint64          `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse JSON-RPC 2.0 响应
+type rpcResponse struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError JSON-RPC 2.0 错误
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// rpcInvoker jsonrpc 协议：首次调用时启动常驻进程，进程退出后下次调用重新启动
+type rpcInvoker struct {
+	manifest *Manifest
+	logger   *monitoring.Logger
+
+	mu      sync.Mutex
+	proc    *rpcProcess
+	nextID  uint64
+	stopped bool
+}
+
+// rpcProcess 一个常驻插件进程
+type rpcProcess struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+	done    chan struct{} // 进程退出后关闭
+
+	mu      sync.Mutex
+	pending map[uint64]chan *rpcResponse
+	err     error // 进程退出原因
+}
+
+func (r *rpcInvoker) call(ctx context.Context, command string, payload json.RawMessage) (json.RawMessage, error) {
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		return nil, ErrPluginStopped
+	}
+	p, err := r.process()
+	if err != nil {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("plugin %s: start: %w", r.manifest.Name, err)
+	}
+	r.nextID++
+	id := r.nextID
+	r.mu.Unlock()
+
+	ch := make(chan *rpcResponse, 1)
+	p.mu.Lock()
+	if p.err != nil {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("plugin %s: %w", r.manifest.Name, p.err)
+	}
+	p.pending[id] = ch
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.pending, id)
+		p.mu.Unlock()
+	}()
+
+	if len(payload) == 0 {
+		payload = nil
+	}
+	line, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: id, Method: command, Params: payload})
+	if err != nil {
+		return nil, err
+	}
+	p.writeMu.Lock()
+	_, err = p.stdin.Write(append(line, '\n'))
+	p.writeMu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: write request: %w", r.manifest.Name, err)
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return nil, fmt.Errorf("plugin %s: %s (code %d)", r.manifest.Name, resp.Error.Message, resp.Error.Code)
+		}
+		if len(resp.Result) == 0 {
+			return json.RawMessage("null"), nil
+		}
+		return resp.Result, nil
+	case <-p.done:
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return nil, fmt.Errorf("plugin %s: %w", r.manifest.Name, p.err)
+	case <-ctx.Done():
+		return nil, fmt.Errorf("plugin %s: %w", r.manifest.Name, ctx.Err())
+	}
+}
+
+// process 返回运行中的进程，必要时启动（调用方持有锁）
+func (r *rpcInvoker) process() (*rpcProcess, error) {
+	if r.proc != nil {
+		select {
+		case <-r.proc.done:
+		default:
+			return r.proc, nil
+		}
+	}
+
+	cmd := exec.Command(r.manifest.Executable, r.manifest.Args...)
+	cmd.Dir = filepath.Dir(r.manifest.Executable)
+	cmd.Env = environ(r.manifest)
+	stderr := &limitedBuffer{limit: maxStderrSize}
+	cmd.Stderr = stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p := &rpcProcess{
+		cmd:     cmd,
+		stdin:   stdin,
+		done:    make(chan struct{}),
+		pending: make(map[uint64]chan *rpcResponse),
+	}
+	r.proc = p
+	r.logger.Info("Plugin process started", "plugin", r.manifest.Name, "pid", cmd.Process.Pid)
+
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), maxOutputSize)
+		for scanner.Scan() {
+			var resp rpcResponse
+			if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+				r.logger.Warn("Invalid plugin response", "plugin", r.manifest.Name, "error", err)
+				continue
+			}
+			p.mu.Lock()
+			ch, ok := p.pending[resp.ID]
+			p.mu.Unlock()
+			if ok {
+				ch <- &resp
+			}
+		}
+		waitErr := cmd.Wait()
+		p.mu.Lock()
+		switch {
+		case waitErr != nil && stderr.buf.Len() > 0:
+			p.err = fmt.Errorf("process exited: %v: %s", waitErr, strings.TrimSpace(stderr.buf.String()))
+		case waitErr != nil:
+			p.err = fmt.Errorf("process exited: %w", waitErr)
+		default:
+			p.err = errors.New("process exited")
+		}
+		p.mu.Unlock()
+		close(p.done)
+		r.logger.Info("Plugin process exited", "plugin", r.manifest.Name, "error", waitErr)
+	}()
+	return p, nil
+}
+
+// stop 关闭 stdin 通知进程退出，超时后强制结束
+func (r *rpcInvoker) stop() {
+	r.mu.Lock()
+	r.stopped = true
+	p := r.proc
+	r.mu.Unlock()
+	if p == nil {
+		return
+	}
+	p.stdin.Close()
+	select {
+	case <-p.done:
+	case <-time.After(stopTimeout):
+		p.cmd.Process.Kill()
+		<-p.done
+	}
+}
@@ -21,10 +21,13 @@ type MatchRule struct {
 // Info 进程信息
 type Info struct {
 	PID        int       `json:"pid"`
+	PPID       int       `json:"ppid,omitempty"`
 	Name       string    `json:"name"`
 	Cmdline    string    `json:"cmdline"`
+	User       string    `json:"user,omitempty"`
 	StartTime  time.Time `json:"start_time"`
 	Status     string    `json:"status"`
+	Threads    int       `json:"threads,omitempty"`
 	CPUPercent float64   `json:"cpu_percent"`
 	MemoryMB   float64   `json:"memory_mb"`
 	MemoryPct  float64   `json:"memory_pct"`
@@ -0,0 +1,256 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// 终止进程相关默认值
+const (
+	killMaxWait      = 300 * time.Second
+	killPollInterval = 100 * time.Millisecond
+)
+
+// signalNames 支持的信号
+var signalNames = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"TERM": syscall.SIGTERM,
+	"STOP": syscall.SIGSTOP,
+	"CONT": syscall.SIGCONT,
+}
+
+// KillPolicy 进程终止策略
+// PID 1、内核线程以及 Agent 自身始终受保护，无需配置
+type KillPolicy struct {
+	ProtectedPIDs  []int    // 额外受保护的 PID
+	ProtectedNames []string // 受保护的进程名（/proc/[pid]/comm）
+}
+
+// KillOptions 终止进程选项
+type KillOptions struct {
+	Signal     syscall.Signal // 信号
+	Tree       bool           // 是否连同子孙进程
+	Wait       time.Duration  // 等待退出的超时，0 表示不等待
+	ForceAfter bool           // 等待超时后发送 KILL
+}
+
+// KillResult 终止进程结果
+type KillResult struct {
+	Signaled  []int // 已发送信号的 PID
+	Skipped   []int // 受保护而跳过的 PID
+	Remaining []int // 等待超时后仍存活的 PID
+	Forced    []int // 被强制 KILL 的 PID
+}
+
+// DefaultKillPolicy 返回默认策略（仅包含内置保护）
+func DefaultKillPolicy() *KillPolicy {
+	return &KillPolicy{}
+}
+
+// ParseSignal 解析信号名（TERM/SIGTERM/15），空字符串表示 TERM
+func ParseSignal(name string) (syscall.Signal, error) {
+	if name == "" {
+		return syscall.SIGTERM, nil
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		for _, sig := range signalNames {
+			if int(sig) == n {
+				return sig, nil
+			}
+		}
+		return 0, fmt.Errorf("unsupported signal: %s", name)
+	}
+	upper := strings.TrimPrefix(strings.ToUpper(name), "SIG")
+	if sig, ok := signalNames[upper]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unsupported signal: %s", name)
+}
+
+// SignalName 返回信号的短名称
+func SignalName(sig syscall.Signal) string {
+	for name, s := range signalNames {
+		if s == sig {
+			return name
+		}
+	}
+	return strconv.Itoa(int(sig))
+}
+
+// Check 检查 PID 是否允许被终止
+func (p *KillPolicy) Check(pid int) error {
+	if pid <= 1 {
+		return fmt.Errorf("pid %d is protected", pid)
+	}
+	self := os.Getpid()
+	if pid == self {
+		return fmt.Errorf("pid %d is the agent itself", pid)
+	}
+	// kthreadd 及其子进程为内核线程
+	if pid == 2 {
+		return fmt.Errorf("pid %d is a kernel thread", pid)
+	}
+	if st, err := readProcStat(pid); err == nil && st.ppid == 2 {
+		return fmt.Errorf("pid %d is a kernel thread", pid)
+	}
+	if p == nil {
+		return nil
+	}
+	for _, protected := range p.ProtectedPIDs {
+		if pid == protected {
+			return fmt.Errorf("pid %d is protected by policy", pid)
+		}
+	}
+	if len(p.ProtectedNames) > 0 {
+		data, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
+		if err == nil {
+			name := strings.TrimSpace(string(data))
+			for _, protected := range p.ProtectedNames {
+				if name == protected {
+					return fmt.Errorf("process %s (pid %d) is protected by policy", name, pid)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Kill 按策略向进程（及其子孙进程）发送信号
+func (c *Collector) Kill(pid int, opts KillOptions, policy *KillPolicy) (*KillResult, error) {
+	if err := policy.Check(pid); err != nil {
+		return nil, err
+	}
+	if !processAlive(pid) {
+		return nil, fmt.Errorf("process %d not found", pid)
+	}
+	// 终止 Agent 的祖先进程树会连带终止 Agent 自身
+	if opts.Tree && isAncestorOf(pid, os.Getpid()) {
+		return nil, fmt.Errorf("pid %d is an ancestor of the agent", pid)
+	}
+
+	targets := []int{pid}
+	if opts.Tree {
+		// 子孙进程优先（深度优先后序），避免父进程退出后子进程被重新托管
+		targets = append(c.descendants(pid), pid)
+	}
+
+	result := &KillResult{}
+	for _, target := range targets {
+		if target != pid {
+			if err := policy.Check(target); err != nil {
+				result.Skipped = append(result.Skipped, target)
+				continue
+			}
+		}
+		if err := syscall.Kill(target, opts.Signal); err != nil {
+			if err == syscall.ESRCH {
+				continue
+			}
+			if target == pid {
+				return result, fmt.Errorf("kill %d: %w", target, err)
+			}
+			result.Skipped = append(result.Skipped, target)
+			continue
+		}
+		result.Signaled = append(result.Signaled, target)
+	}
+
+	if opts.Wait <= 0 || len(result.Signaled) == 0 {
+		return result, nil
+	}
+	if opts.Wait > killMaxWait {
+		opts.Wait = killMaxWait
+	}
+
+	result.Remaining = waitGone(result.Signaled, opts.Wait)
+	if len(result.Remaining) > 0 && opts.ForceAfter && opts.Signal != syscall.SIGKILL {
+		for _, target := range result.Remaining {
+			if err := syscall.Kill(target, syscall.SIGKILL); err == nil {
+				result.Forced = append(result.Forced, target)
+			}
+		}
+		result.Remaining = waitGone(result.Remaining, 5*time.Second)
+	}
+	return result, nil
+}
+
+// descendants 返回 pid 的所有子孙进程（后序：深层在前）
+func (c *Collector) descendants(pid int) []int {
+	pids, err := c.getAllPids()
+	if err != nil {
+		return nil
+	}
+
+	children := make(map[int][]int)
+	for _, p := range pids {
+		if st, err := readProcStat(p); err == nil {
+			children[st.ppid] = append(children[st.ppid], p)
+		}
+	}
+
+	var result []int
+	var walk func(int)
+	walk = func(p int) {
+		kids := children[p]
+		sort.Ints(kids)
+		for _, kid := range kids {
+			walk(kid)
+			result = append(result, kid)
+		}
+	}
+	walk(pid)
+	return result
+}
+
+// isAncestorOf 判断 ancestor 是否为 pid 的祖先进程
+func isAncestorOf(ancestor, pid int) bool {
+	for i := 0; i < 64 && pid > 1; i++ {
+		st, err := readProcStat(pid)
+		if err != nil {
+			return false
+		}
+		if st.ppid == ancestor {
+			return true
+		}
+		pid = st.ppid
+	}
+	return false
+}
+
+// waitGone 等待进程退出，返回超时后仍存活的 PID
+func waitGone(pids []int, timeout time.Duration) []int {
+	deadline := time.Now().Add(timeout)
+	remaining := pids
+	for {
+		var alive []int
+		for _, pid := range remaining {
+			if processAlive(pid) {
+				alive = append(alive, pid)
+			}
+		}
+		remaining = alive
+		if len(remaining) == 0 || time.Now().After(deadline) {
+			return remaining
+		}
+		time.Sleep(killPollInterval)
+	}
+}
+
+// processAlive 检查进程是否仍存活（僵尸进程视为已退出）
+func processAlive(pid int) bool {
+	st, err := readProcStat(pid)
+	if err != nil {
+		return false
+	}
+	return st.state != "Z" && st.state != "X" && st.state != "x"
+}
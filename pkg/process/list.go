@@ -0,0 +1,302 @@
+package process
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/user"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks /proc 中时间字段的单位（USER_HZ，Linux 上基本固定为 100）
+const clockTicks = 100
+
+// 列表默认值
+const (
+	listDefaultLimit    = 100
+	listMaxLimit        = 1000
+	listMaxCPUSampleDur = 5 * time.Second
+)
+
+// ListOptions 进程列表选项
+type ListOptions struct {
+	Filter         string        // 进程名/命令行过滤（子串，/re/ 形式为正则）
+	User           string        // 用户名过滤
+	Status         string        // 状态过滤
+	PPID           int           // 父进程过滤
+	SortBy         string        // pid/name/cpu/memory/start_time/threads
+	Order          string        // asc/desc
+	Offset         int           // 分页偏移
+	Limit          int           // 分页大小
+	CPUSampleDelay time.Duration // CPU 采样间隔，0 表示生命周期平均值
+}
+
+// procStat /proc/[pid]/stat 中用到的字段
+type procStat struct {
+	state      string
+	ppid       int
+	utime      uint64
+	stime      uint64
+	threads    int
+	startTicks uint64
+}
+
+// List 列出所有进程，支持过滤、排序和分页
+// 返回当前页的进程和过滤后的总数
+func (c *Collector) List(opts ListOptions) ([]Info, int, error) {
+	var filterRe *regexp.Regexp
+	if len(opts.Filter) > 2 && strings.HasPrefix(opts.Filter, "/") && strings.HasSuffix(opts.Filter, "/") {
+		re, err := regexp.Compile(opts.Filter[1 : len(opts.Filter)-1])
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid filter regexp: %w", err)
+		}
+		filterRe = re
+	}
+
+	pids, err := c.getAllPids()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// CPU 采样：第一次读取
+	var before map[int]uint64
+	if opts.CPUSampleDelay > 0 {
+		if opts.CPUSampleDelay > listMaxCPUSampleDur {
+			opts.CPUSampleDelay = listMaxCPUSampleDur
+		}
+		before = make(map[int]uint64, len(pids))
+		for _, pid := range pids {
+			if st, err := readProcStat(pid); err == nil {
+				before[pid] = st.utime + st.stime
+			}
+		}
+		time.Sleep(opts.CPUSampleDelay)
+	}
+
+	bootTime := c.getBootTime()
+	uptime := readUptime()
+	users := make(map[string]string)
+
+	var result []Info
+	for _, pid := range pids {
+		info, st, err := c.getListInfo(pid, bootTime, users)
+		if err != nil {
+			continue
+		}
+
+		// 过滤
+		if opts.PPID > 0 && info.PPID != opts.PPID {
+			continue
+		}
+		if opts.Status != "" && info.Status != opts.Status {
+			continue
+		}
+		if opts.User != "" && info.User != opts.User {
+			continue
+		}
+		if opts.Filter != "" {
+			if filterRe != nil {
+				if !filterRe.MatchString(info.Name) && !filterRe.MatchString(info.Cmdline) {
+					continue
+				}
+			} else if !strings.Contains(info.Name, opts.Filter) && !strings.Contains(info.Cmdline, opts.Filter) {
+				continue
+			}
+		}
+
+		// CPU 使用率
+		total := st.utime + st.stime
+		if before != nil {
+			if prev, ok := before[pid]; ok && total >= prev {
+				info.CPUPercent = float64(total-prev) / clockTicks / opts.CPUSampleDelay.Seconds() * 100
+			}
+		} else if elapsed := uptime - float64(st.startTicks)/clockTicks; elapsed > 0 {
+			info.CPUPercent = float64(total) / clockTicks / elapsed * 100
+		}
+		info.CPUPercent = math.Round(info.CPUPercent*100) / 100
+
+		result = append(result, info)
+	}
+
+	sortInfos(result, opts.SortBy, opts.Order)
+
+	total := len(result)
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = listDefaultLimit
+	}
+	if limit > listMaxLimit {
+		limit = listMaxLimit
+	}
+	offset := opts.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= total {
+		return []Info{}, total, nil
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return result[offset:end], total, nil
+}
+
+// getListInfo 读取进程列表所需的信息
+func (c *Collector) getListInfo(pid int, bootTime time.Time, users map[string]string) (Info, *procStat, error) {
+	st, err := readProcStat(pid)
+	if err != nil {
+		return Info{}, nil, err
+	}
+
+	info := Info{
+		PID:       pid,
+		PPID:      st.ppid,
+		Status:    stateName(st.state),
+		Threads:   st.threads,
+		StartTime: bootTime.Add(time.Duration(st.startTicks) * time.Second / clockTicks),
+	}
+	if info.Name, err = c.getProcessName(pid); err != nil {
+		return Info{}, nil, err
+	}
+	info.Cmdline, _ = c.getProcessCmdline(pid)
+	info.MemoryMB, info.MemoryPct, _ = c.getProcessMemory(pid)
+	info.User = processUser(pid, users)
+
+	return info, st, nil
+}
+
+// readProcStat 解析 /proc/[pid]/stat
+// comm 字段可能包含空格和括号，因此从最后一个 ')' 之后开始按空格切分
+func readProcStat(pid int) (*procStat, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return nil, err
+	}
+	return parseProcStat(string(data))
+}
+
+// parseProcStat 解析 stat 文件内容
+func parseProcStat(data string) (*procStat, error) {
+	idx := strings.LastIndex(data, ")")
+	if idx < 0 {
+		return nil, fmt.Errorf("invalid stat format")
+	}
+	// fields[0] 对应第 3 个字段 state
+	fields := strings.Fields(data[idx+1:])
+	if len(fields) < 20 {
+		return nil, fmt.Errorf("invalid stat format")
+	}
+
+	st := &procStat{state: fields[0]}
+	st.ppid, _ = strconv.Atoi(fields[1])
+	st.utime, _ = strconv.ParseUint(fields[11], 10, 64)
+	st.stime, _ = strconv.ParseUint(fields[12], 10, 64)
+	st.threads, _ = strconv.Atoi(fields[17])
+	st.startTicks, _ = strconv.ParseUint(fields[19], 10, 64)
+	return st, nil
+}
+
+// stateName 将 stat 状态字符转换为可读名称
+func stateName(state string) string {
+	switch state {
+	case "S":
+		return "sleeping"
+	case "R":
+		return "running"
+	case "Z":
+		return "zombie"
+	case "D":
+		return "disk_sleep"
+	case "T", "t":
+		return "stopped"
+	case "I":
+		return "idle"
+	case "X", "x":
+		return "dead"
+	}
+	return "running"
+}
+
+// processUser 获取进程的用户名（按 UID 缓存）
+func processUser(pid int, cache map[string]string) string {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return ""
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "Uid:") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return ""
+		}
+		uid := fields[1]
+		if name, ok := cache[uid]; ok {
+			return name
+		}
+		name := uid
+		if u, err := user.LookupId(uid); err == nil {
+			name = u.Username
+		}
+		cache[uid] = name
+		return name
+	}
+	return ""
+}
+
+// readUptime 读取系统运行时间（秒）
+func readUptime() float64 {
+	data, err := os.ReadFile("/proc/uptime")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0
+	}
+	uptime, _ := strconv.ParseFloat(fields[0], 64)
+	return uptime
+}
+
+// sortInfos 按指定字段排序
+func sortInfos(infos []Info, sortBy, order string) {
+	var less func(a, b Info) bool
+	desc := false
+	switch sortBy {
+	case "name":
+		less = func(a, b Info) bool { return a.Name < b.Name }
+	case "cpu":
+		less = func(a, b Info) bool { return a.CPUPercent < b.CPUPercent }
+		desc = true
+	case "memory":
+		less = func(a, b Info) bool { return a.MemoryMB < b.MemoryMB }
+		desc = true
+	case "start_time":
+		less = func(a, b Info) bool { return a.StartTime.Before(b.StartTime) }
+	case "threads":
+		less = func(a, b Info) bool { return a.Threads < b.Threads }
+		desc = true
+	default:
+		less = func(a, b Info) bool { return a.PID < b.PID }
+	}
+	switch order {
+	case "asc":
+		desc = false
+	case "desc":
+		desc = true
+	}
+
+	sort.SliceStable(infos, func(i, j int) bool {
+		if desc {
+			return less(infos[j], infos[i])
+		}
+		return less(infos[i], infos[j])
+	})
+}
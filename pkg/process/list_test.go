@@ -0,0 +1,95 @@
+package process
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseProcStat(t *testing.T) {
+	// comm 中包含空格和括号
+	data := "1234 (my (weird) proc) S 1 1234 1234 0 -1 4194560 100 0 0 0 250 50 0 0 20 0 4 0 9876 0 0"
+	st, err := parseProcStat(data)
+	if err != nil {
+		t.Fatalf("parseProcStat: %v", err)
+	}
+	if st.state != "S" || st.ppid != 1 {
+		t.Errorf("state/ppid = %s/%d, want S/1", st.state, st.ppid)
+	}
+	if st.utime != 250 || st.stime != 50 {
+		t.Errorf("utime/stime = %d/%d, want 250/50", st.utime, st.stime)
+	}
+	if st.threads != 4 || st.startTicks != 9876 {
+		t.Errorf("threads/start = %d/%d, want 4/9876", st.threads, st.startTicks)
+	}
+
+	if _, err := parseProcStat("garbage"); err == nil {
+		t.Error("expected error for invalid stat")
+	}
+}
+
+func TestSortInfos(t *testing.T) {
+	now := time.Now()
+	infos := []Info{
+		{PID: 3, Name: "b", CPUPercent: 1, StartTime: now},
+		{PID: 1, Name: "c", CPUPercent: 5, StartTime: now.Add(-time.Hour)},
+		{PID: 2, Name: "a", CPUPercent: 3, StartTime: now.Add(time.Hour)},
+	}
+
+	sortInfos(infos, "", "")
+	if infos[0].PID != 1 || infos[2].PID != 3 {
+		t.Errorf("default sort by pid failed: %+v", infos)
+	}
+
+	sortInfos(infos, "cpu", "")
+	if infos[0].PID != 1 || infos[2].PID != 3 {
+		t.Errorf("cpu sort should default to desc: %+v", infos)
+	}
+
+	sortInfos(infos, "name", "desc")
+	if infos[0].Name != "c" || infos[2].Name != "a" {
+		t.Errorf("name desc sort failed: %+v", infos)
+	}
+}
+
+func TestKillPolicyCheck(t *testing.T) {
+	policy := &KillPolicy{ProtectedPIDs: []int{424242}}
+
+	for _, pid := range []int{0, 1, 2, os.Getpid(), 424242} {
+		if err := policy.Check(pid); err == nil {
+			t.Errorf("pid %d should be protected", pid)
+		}
+	}
+
+	// 未配置策略时仍保留内置保护
+	var nilPolicy *KillPolicy
+	if err := nilPolicy.Check(1); err == nil {
+		t.Error("pid 1 should be protected with nil policy")
+	}
+	if err := nilPolicy.Check(424242); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParseSignal(t *testing.T) {
+	cases := map[string]syscall.Signal{
+		"":        syscall.SIGTERM,
+		"TERM":    syscall.SIGTERM,
+		"sigkill": syscall.SIGKILL,
+		"9":       syscall.SIGKILL,
+		"HUP":     syscall.SIGHUP,
+	}
+	for name, want := range cases {
+		got, err := ParseSignal(name)
+		if err != nil || got != want {
+			t.Errorf("ParseSignal(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseSignal("BOGUS"); err == nil {
+		t.Error("expected error for unknown signal")
+	}
+	if SignalName(syscall.SIGTERM) != "TERM" {
+		t.Errorf("SignalName(SIGTERM) = %s", SignalName(syscall.SIGTERM))
+	}
+}
@@ -0,0 +1,42 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/monitoring"
+)
+
+// LoggingMiddleware 日志中间件，记录查询来源、耗时与错误
+func LoggingMiddleware(logger *monitoring.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) (json.RawMessage, error) {
+			start := time.Now()
+			result, err := next(ctx, req)
+			if err != nil {
+				logger.Warn("Query failed", "query", req.Name, "client_id", req.ClientID, "duration", time.Since(start), "error", err)
+			} else {
+				logger.Debug("Query completed", "query", req.Name, "client_id", req.ClientID, "duration", time.Since(start), "size", len(result))
+			}
+			return result, err
+		}
+	}
+}
+
+// RecoveryMiddleware panic 恢复中间件
+func RecoveryMiddleware(logger *monitoring.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) (result json.RawMessage, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Error("Query handler panic recovered", "query", req.Name, "panic", r, "stack", string(debug.Stack()))
+					result, err = nil, fmt.Errorf("internal error: %v", r)
+				}
+			}()
+			return next(ctx, req)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Package query 提供 Agent 主动发起的请求/响应查询（QUERY 消息）
+// 服务器按查询名称注册处理器，处理结果通过 Ack 的 Result 返回给 Agent，
+// 用于 Agent 按需拉取配置、凭据、发布清单等
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/protocol"
+)
+
+var (
+	// ErrUnknownQuery 表示查询名称未注册
+	ErrUnknownQuery = errors.New("unknown query")
+
+	// ErrQueryFailed 表示服务器处理查询失败
+	ErrQueryFailed = errors.New("query failed")
+)
+
+// Request 查询请求（QUERY 消息载荷）
+type Request struct {
+	Name     string          `json:"name"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+	ClientID string          `json:"-"` // 发起查询的客户端 ID（由服务器根据连接会话填写）
+}
+
+// Handler 查询处理函数，返回的数据作为 Ack 结果返回给 Agent
+type Handler func(ctx context.Context, req *Request) (json.RawMessage, error)
+
+// Middleware 中间件函数
+type Middleware func(Handler) Handler
+
+// Router 服务器侧查询路由器（与命令路由器 router.Router 对应）
+type Router struct {
+	handlers    map[string]Handler // 查询名称 -> Handler
+	middlewares []Middleware
+	mu          sync.RWMutex
+	logger      *monitoring.Logger
+}
+
+// NewRouter 创建查询路由器
+func NewRouter(logger *monitoring.Logger) *Router {
+	if logger == nil {
+		logger = monitoring.NewLogger(monitoring.LogLevelInfo, "text")
+	}
+	return &Router{
+		handlers: make(map[string]Handler),
+		logger:   logger,
+	}
+}
+
+// Register 注册查询处理器
+func (r *Router) Register(name string, handler Handler) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[name] = handler
+	r.logger.Debug("Query handler registered", "query", name)
+	return r
+}
+
+// Use 添加全局中间件，按添加顺序执行
+func (r *Router) Use(middleware Middleware) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middlewares = append(r.middlewares, middleware)
+	return r
+}
+
+// Unregister 注销查询处理器
+func (r *Router) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.handlers, name)
+}
+
+// HasHandler 检查是否已注册某个查询
+func (r *Router) HasHandler(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.handlers[name]
+	return ok
+}
+
+// ListQueries 列出已注册的查询名称
+func (r *Router) ListQueries() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.handlers))
+	for name := range r.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Execute 执行查询
+func (r *Router) Execute(ctx context.Context, req *Request) (json.RawMessage, error) {
+	r.mu.RLock()
+	handler, ok := r.handlers[req.Name]
+	middlewares := r.middlewares
+	r.mu.RUnlock()
+
+	if !ok {
+		r.logger.Warn("Unknown query", "query", req.Name, "client_id", req.ClientID)
+		return nil, fmt.Errorf("%w: %s", ErrUnknownQuery, req.Name)
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler(ctx, req)
+}
+
+// HandleMessage 处理 Agent 发来的 QUERY 消息（可直接注册为 Dispatcher 的消息处理器）
+// 查询结果作为 RESPONSE 消息的载荷返回，由传输层放入 Ack 结果
+func (r *Router) HandleMessage(ctx context.Context, msg *protocol.DataMessage) (*protocol.DataMessage, error) {
+	var req Request
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	if req.Name == "" {
+		return nil, fmt.Errorf("invalid query: name is required")
+	}
+	req.ClientID = msg.SenderId
+
+	result, err := r.Execute(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.DataMessage{
+		MsgId:     msg.MsgId,
+		SenderId:  "server",
+		Type:      protocol.MessageType_MESSAGE_TYPE_RESPONSE,
+		Payload:   result,
+		Timestamp: time.Now().UnixMilli(),
+	}, nil
+}
+
+// Typed 将强类型处理函数包装为 Handler：请求载荷解码为 Req，返回值编码为 JSON
+func Typed[Req, Resp any](fn func(ctx context.Context, clientID string, req Req) (Resp, error)) Handler {
+	return func(ctx context.Context, r *Request) (json.RawMessage, error) {
+		var req Req
+		if len(r.Payload) > 0 && string(r.Payload) != "null" {
+			if err := json.Unmarshal(r.Payload, &req); err != nil {
+				return nil, fmt.Errorf("invalid %s request: %w", r.Name, err)
+			}
+		}
+		resp, err := fn(ctx, r.ClientID, req)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(resp)
+	}
+}
+
+// NewMessage 构造 QUERY 消息，req 为请求参数（可为 nil）
+func NewMessage(name string, req interface{}) (*protocol.DataMessage, error) {
+	var payload json.RawMessage
+	if req != nil {
+		data, err := json.Marshal(req)
+		if err != nil {
+			return nil, fmt.Errorf("marshal query request: %w", err)
+		}
+		payload = data
+	}
+	data, err := json.Marshal(Request{Name: name, Payload: payload})
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.DataMessage{
+		MsgId:   uuid.New().String(),
+		Type:    protocol.MessageType_MESSAGE_TYPE_QUERY,
+		Payload: data,
+	}, nil
+}
@@ -0,0 +1,71 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/protocol"
+)
+
+type configReq struct {
+	Key string `json:"key"`
+}
+
+type configResp struct {
+	ClientID string `json:"client_id"`
+	Value    string `json:"value"`
+}
+
+func TestHandleMessage(t *testing.T) {
+	r := NewRouter(nil)
+	r.Use(RecoveryMiddleware(r.logger))
+	r.Register("config.get", Typed(func(ctx context.Context, clientID string, req configReq) (*configResp, error) {
+		if req.Key == "" {
+			return nil, errors.New("key is required")
+		}
+		return &configResp{ClientID: clientID, Value: "value-of-" + req.Key}, nil
+	}))
+	r.Register("panic", func(ctx context.Context, req *Request) (json.RawMessage, error) {
+		panic("boom")
+	})
+	assert.Equal(t, []string{"config.get", "panic"}, r.ListQueries())
+
+	msg, err := NewMessage("config.get", configReq{Key: "db"})
+	require.NoError(t, err)
+	assert.Equal(t, protocol.MessageType_MESSAGE_TYPE_QUERY, msg.Type)
+	msg.SenderId = "agent-1"
+
+	resp, err := r.HandleMessage(context.Background(), msg)
+	require.NoError(t, err)
+	assert.Equal(t, msg.MsgId, resp.MsgId)
+	assert.Equal(t, protocol.MessageType_MESSAGE_TYPE_RESPONSE, resp.Type)
+	assert.JSONEq(t, `{"client_id":"agent-1","value":"value-of-db"}`, string(resp.Payload))
+
+	// 处理器错误、未知查询、panic 均以错误返回（由传输层写入失败 Ack）
+	msg, _ = NewMessage("config.get", nil)
+	_, err = r.HandleMessage(context.Background(), msg)
+	assert.EqualError(t, err, "key is required")
+
+	msg, _ = NewMessage("secret.get", nil)
+	_, err = r.HandleMessage(context.Background(), msg)
+	assert.ErrorIs(t, err, ErrUnknownQuery)
+
+	msg, _ = NewMessage("panic", nil)
+	_, err = r.HandleMessage(context.Background(), msg)
+	assert.ErrorContains(t, err, "internal error")
+
+	_, err = r.HandleMessage(context.Background(), &protocol.DataMessage{Payload: []byte(`{"payload":{}}`)})
+	assert.Error(t, err)
+}
+
+func TestTypedInvalidPayload(t *testing.T) {
+	h := Typed(func(ctx context.Context, clientID string, req configReq) (configResp, error) {
+		return configResp{}, nil
+	})
+	_, err := h(context.Background(), &Request{Name: "config.get", Payload: json.RawMessage(`{"key":1}`)})
+	assert.ErrorContains(t, err, "invalid config.get request")
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/voilet/quic-flow/pkg/calendar"
 	"github.com/voilet/quic-flow/pkg/git"
 	"github.com/voilet/quic-flow/pkg/release/callback"
 	"github.com/voilet/quic-flow/pkg/release/credential"
@@ -28,6 +30,7 @@ type ReleaseAPI struct {
 	engine        *engine.Engine
 	callbackMgr   *callback.Manager
 	credentialMgr *credential.Manager
+	calendar      *calendar.Store // 禁止窗口（可选）
 }
 
 // NewReleaseAPI 创建发布系统 API
@@ -76,6 +79,13 @@ func (api *ReleaseAPI) SetRemoteExecutor(cmdSender executor.CommandSender) {
 func (api *ReleaseAPI) SetDB(db *gorm.DB) {
 	api.db = db
 	api.engine = engine.NewEngine(db)
+	api.engine.SetCalendar(api.calendar)
+}
+
+// SetCalendar 设置禁止窗口，开始发布前检查
+func (api *ReleaseAPI) SetCalendar(blackouts *calendar.Store) {
+	api.calendar = blackouts
+	api.engine.SetCalendar(blackouts)
 }
 
 // checkDB 检查数据库是否配置
@@ -156,6 +166,7 @@ func (api *ReleaseAPI) RegisterRoutes(r *gin.RouterGroup) {
 		release.GET("/deploys", api.ListReleases)
 		release.GET("/deploys/:id", api.GetRelease)
 		release.POST("/deploys/:id/start", api.StartRelease)
+		release.GET("/deploys/:id/blocks", api.GetReleaseBlocks)
 		release.POST("/deploys/:id/cancel", api.CancelRelease)
 		release.POST("/deploys/:id/rollback", api.RollbackRelease)
 		release.POST("/deploys/:id/promote", api.PromoteRelease)
@@ -821,16 +832,59 @@ func (api *ReleaseAPI) GetRelease(c *gin.Context) {
 }
 
 // StartRelease 开始发布
+// 处于发布窗口之外或禁止窗口之内时返回 409，需在请求体中给出 override_reason 才能强制开始
 func (api *ReleaseAPI) StartRelease(c *gin.Context) {
 	id := c.Param("id")
-	if err := api.engine.StartRelease(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+	var req struct {
+		OverrideReason string `json:"override_reason"`
+	}
+	c.ShouldBindJSON(&req)
+
+	if err := api.startRelease(c.Request.Context(), id, req.OverrideReason); err != nil {
+		releaseStartError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
+// GetReleaseBlocks 获取当前阻止该发布开始的发布窗口与禁止窗口
+func (api *ReleaseAPI) GetReleaseBlocks(c *gin.Context) {
+	if !api.checkDB(c) {
+		return
+	}
+	var release models.Release
+	if err := api.db.First(&release, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": "release not found"})
+		return
+	}
+
+	blocks, err := api.engine.ReleaseBlocks(c.Request.Context(), &release, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "blocked": len(blocks) > 0, "blocks": blocks})
+}
+
+// startRelease 开始发布，给出 overrideReason 时忽略发布窗口与禁止窗口
+func (api *ReleaseAPI) startRelease(ctx context.Context, releaseID, overrideReason string) error {
+	if overrideReason != "" {
+		return api.engine.ForceStartRelease(ctx, releaseID, overrideReason, "admin") // TODO: 从认证获取
+	}
+	return api.engine.StartRelease(ctx, releaseID)
+}
+
+// releaseStartError 开始发布失败的响应，被窗口阻止时返回 409
+func releaseStartError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, engine.ErrReleaseBlocked) {
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{"success": false, "error": err.Error()})
+}
+
 // CancelRelease 取消发布
 func (api *ReleaseAPI) CancelRelease(c *gin.Context) {
 	id := c.Param("id")
@@ -847,7 +901,8 @@ func (api *ReleaseAPI) RollbackRelease(c *gin.Context) {
 	id := c.Param("id")
 
 	var req struct {
-		TargetVersion string `json:"target_version"`
+		TargetVersion  string `json:"target_version"`
+		OverrideReason string `json:"override_reason"` // 强制在发布窗口之外或禁止窗口之内回滚的原因
 	}
 	c.ShouldBindJSON(&req)
 
@@ -876,8 +931,8 @@ func (api *ReleaseAPI) RollbackRelease(c *gin.Context) {
 	}
 
 	// 自动开始回滚
-	if err := api.engine.StartRelease(c.Request.Context(), rollback.ID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+	if err := api.startRelease(c.Request.Context(), rollback.ID, req.OverrideReason); err != nil {
+		releaseStartError(c, err)
 		return
 	}
 
@@ -914,7 +969,7 @@ func (api *ReleaseAPI) InstallService(c *gin.Context) {
 
 	// 自动开始
 	if err := api.engine.StartRelease(c.Request.Context(), release.ID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		releaseStartError(c, err)
 		return
 	}
 
@@ -939,7 +994,7 @@ func (api *ReleaseAPI) UpdateService(c *gin.Context) {
 	}
 
 	if err := api.engine.StartRelease(c.Request.Context(), release.ID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		releaseStartError(c, err)
 		return
 	}
 
@@ -964,7 +1019,7 @@ func (api *ReleaseAPI) UninstallService(c *gin.Context) {
 	}
 
 	if err := api.engine.StartRelease(c.Request.Context(), release.ID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		releaseStartError(c, err)
 		return
 	}
 
@@ -1004,7 +1059,7 @@ func (api *ReleaseAPI) ApproveRelease(c *gin.Context) {
 
 	// 自动开始发布
 	if err := api.engine.StartRelease(c.Request.Context(), approval.ReleaseID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		releaseStartError(c, err)
 		return
 	}
 
@@ -3,9 +3,11 @@ package engine
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/voilet/quic-flow/pkg/calendar"
 	"github.com/voilet/quic-flow/pkg/release/executor"
 	"github.com/voilet/quic-flow/pkg/release/models"
 	"github.com/voilet/quic-flow/pkg/release/variable"
@@ -19,6 +21,7 @@ type Engine struct {
 	varManager   *variable.Manager
 	scriptExec   *executor.ScriptExecutor
 	remoteExec   *executor.RemoteExecutor
+	calendar     *calendar.Store // 禁止窗口（可选）
 
 	// 运行中的发布
 	runningReleases sync.Map
@@ -192,8 +195,21 @@ func (e *Engine) CreateRelease(ctx context.Context, req *CreateReleaseRequest) (
 	return release, nil
 }
 
-// StartRelease 开始发布
+// StartRelease 开始发布，处于环境的发布窗口之外或禁止窗口之内时返回 ErrReleaseBlocked
 func (e *Engine) StartRelease(ctx context.Context, releaseID string) error {
+	return e.startRelease(ctx, releaseID, nil)
+}
+
+// ForceStartRelease 开始发布，忽略发布窗口与禁止窗口；必须给出原因，被忽略的窗口记录在发布中
+func (e *Engine) ForceStartRelease(ctx context.Context, releaseID, reason, by string) error {
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("override reason is required")
+	}
+	return e.startRelease(ctx, releaseID, &models.BlackoutOverride{Reason: reason, By: by})
+}
+
+// startRelease 开始发布，override 为空时遵守发布窗口与禁止窗口
+func (e *Engine) startRelease(ctx context.Context, releaseID string, override *models.BlackoutOverride) error {
 	var release models.Release
 	if err := e.db.WithContext(ctx).First(&release, "id = ?", releaseID).Error; err != nil {
 		return fmt.Errorf("release not found: %w", err)
@@ -215,8 +231,22 @@ func (e *Engine) StartRelease(ctx context.Context, releaseID string) error {
 		}
 	}
 
-	// 更新状态为运行中
+	// 检查发布窗口与禁止窗口
 	now := time.Now()
+	blocks, err := e.ReleaseBlocks(ctx, &release, now)
+	if err != nil {
+		return err
+	}
+	if len(blocks) > 0 {
+		if override == nil {
+			return fmt.Errorf("%w: %s", ErrReleaseBlocked, strings.Join(blocks, "; "))
+		}
+		override.Windows = blocks
+		override.At = now
+		release.BlackoutOverride = override
+	}
+
+	// 更新状态为运行中
 	release.Status = models.ReleaseStatusRunning
 	release.StartedAt = &now
 	if err := e.db.WithContext(ctx).Save(&release).Error; err != nil {
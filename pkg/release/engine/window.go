@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/calendar"
+	"github.com/voilet/quic-flow/pkg/release/models"
+)
+
+// ErrReleaseBlocked 发布处于环境的发布窗口之外或禁止窗口之内
+var ErrReleaseBlocked = errors.New("release blocked")
+
+// SetCalendar 设置禁止窗口，开始发布前检查全局与该环境的窗口
+func (e *Engine) SetCalendar(blackouts *calendar.Store) {
+	e.calendar = blackouts
+}
+
+// ReleaseBlocks 返回阻止该发布在 at 开始的原因：环境的发布窗口与生效的禁止窗口
+func (e *Engine) ReleaseBlocks(ctx context.Context, release *models.Release, at time.Time) ([]string, error) {
+	var env models.Environment
+	if err := e.db.WithContext(ctx).First(&env, "id = ?", release.EnvironmentID).Error; err != nil {
+		return nil, fmt.Errorf("environment not found: %w", err)
+	}
+
+	var blocks []string
+	if !env.ReleaseWindow.Allows(at) {
+		blocks = append(blocks, fmt.Sprintf("outside release window of environment %s", env.Name))
+	}
+	if e.calendar != nil {
+		for _, w := range e.calendar.Active(calendar.KindReleases, calendar.Target{EnvironmentID: env.ID}, at) {
+			blocks = append(blocks, fmt.Sprintf("blackout window %s", w.Name))
+		}
+	}
+	return blocks, nil
+}
@@ -291,6 +291,48 @@ func (r *ReleaseWindow) Scan(value interface{}) error {
 	return json.Unmarshal(bytes, r)
 }
 
+// Allows 时间 t 是否在发布窗口内，未启用时总是允许
+// allowed_days 为空时每天都允许；start_time、end_time 为空时全天允许，end_time 不晚于 start_time 时窗口结束于次日
+func (r *ReleaseWindow) Allows(t time.Time) bool {
+	if r == nil || !r.Enabled {
+		return true
+	}
+	if r.Timezone != "" {
+		if loc, err := time.LoadLocation(r.Timezone); err == nil {
+			t = t.In(loc)
+		}
+	}
+	dayAllowed := func(day time.Time) bool {
+		if len(r.AllowedDays) == 0 {
+			return true
+		}
+		weekday := int(day.Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+		for _, d := range r.AllowedDays {
+			if d == weekday {
+				return true
+			}
+		}
+		return false
+	}
+	clock := func(s string, def int) int {
+		v, err := time.Parse("15:04", s)
+		if err != nil {
+			return def
+		}
+		return v.Hour()*60 + v.Minute()
+	}
+
+	start, end := clock(r.StartTime, 0), clock(r.EndTime, 24*60)
+	minute := t.Hour()*60 + t.Minute()
+	if start < end {
+		return dayAllowed(t) && minute >= start && minute < end
+	}
+	return (dayAllowed(t) && minute >= start) || (dayAllowed(t.AddDate(0, 0, -1)) && minute < end)
+}
+
 // Target 部署目标
 type Target struct {
 	ID            string       `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
@@ -440,6 +482,9 @@ type Release struct {
 	// 定时发布
 	ScheduledAt *time.Time `gorm:"index" json:"scheduled_at,omitempty"`
 
+	// 在发布窗口外或禁止窗口内强制开始的记录
+	BlackoutOverride *BlackoutOverride `gorm:"type:jsonb" json:"blackout_override,omitempty"`
+
 	// 执行结果
 	Results TargetResults `gorm:"type:jsonb" json:"results,omitempty"`
 
@@ -487,6 +532,29 @@ func (r *ReleaseStrategy) Scan(value interface{}) error {
 	return json.Unmarshal(bytes, r)
 }
 
+// BlackoutOverride 强制开始发布的原因与当时阻止发布的窗口
+type BlackoutOverride struct {
+	Reason  string    `json:"reason"`
+	By      string    `json:"by"`
+	Windows []string  `json:"windows"`
+	At      time.Time `json:"at"`
+}
+
+func (b BlackoutOverride) Value() (driver.Value, error) {
+	return json.Marshal(b)
+}
+
+func (b *BlackoutOverride) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, b)
+}
+
 // RollbackConfig 回滚配置
 type RollbackConfig struct {
 	Granularity   RollbackGranularity `json:"granularity"`
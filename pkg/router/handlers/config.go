@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/agentconfig"
+	"github.com/voilet/quic-flow/pkg/command"
+)
+
+// agentConfig 配置管理器（通过 Config.AgentConfig 设置）
+var agentConfig *agentconfig.Manager
+
+// ConfigGet 获取 Agent 生效配置
+// 命令类型: config.get
+// 用法: r.Register(command.CmdConfigGet, handlers.ConfigGet)
+func ConfigGet(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	var params command.ConfigGetParams
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &params); err != nil {
+			return nil, fmt.Errorf("invalid params: %w", err)
+		}
+	}
+	if agentConfig == nil {
+		return nil, fmt.Errorf("agent config is not managed")
+	}
+
+	cfg := agentConfig.Get()
+	result := command.ConfigResult{
+		Success:  true,
+		Key:      params.Key,
+		Revision: agentConfig.Revision(),
+		Message:  "ok",
+	}
+	if params.Key != "" {
+		value, ok := agentconfig.Lookup(cfg, params.Key)
+		if !ok {
+			result.Success = false
+			result.Message = "key not found"
+			result.Error = fmt.Sprintf("unknown config key: %s", params.Key)
+			return json.Marshal(result)
+		}
+		result.Value = value
+		return json.Marshal(result)
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	result.Config = data
+	return json.Marshal(result)
+}
+
+// ConfigUpdate 校验并应用配置补丁，持久化后热加载
+// 宽限期内 Agent 未能重连时自动回滚到上一份可用配置
+// 命令类型: config.update
+// 用法: r.Register(command.CmdConfigUpdate, handlers.ConfigUpdate)
+func ConfigUpdate(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	var params command.ConfigUpdateParams
+	if err := json.Unmarshal(payload, &params); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+	if agentConfig == nil {
+		return nil, fmt.Errorf("agent config is not managed")
+	}
+
+	patch := params.Patch
+	if len(patch) == 0 {
+		var err error
+		if patch, err = agentconfig.KeyPatch(params.Key, params.Value); err != nil {
+			return json.Marshal(command.ConfigResult{
+				Success: false,
+				Message: "invalid params",
+				Error:   err.Error(),
+			})
+		}
+	}
+
+	res, err := agentConfig.Update(patch, agentconfig.UpdateOptions{
+		DryRun:      params.DryRun,
+		GracePeriod: time.Duration(params.GracePeriodSec) * time.Second,
+		NoRollback:  params.NoRollback,
+	})
+	if err != nil {
+		return json.Marshal(command.ConfigResult{
+			Success:  false,
+			Key:      params.Key,
+			Revision: agentConfig.Revision(),
+			DryRun:   params.DryRun,
+			Message:  "config rejected",
+			Error:    err.Error(),
+		})
+	}
+
+	data, err := json.Marshal(res.Config)
+	if err != nil {
+		return nil, err
+	}
+	result := command.ConfigResult{
+		Success:         true,
+		Key:             params.Key,
+		Config:          data,
+		Revision:        res.Revision,
+		Changed:         res.Changed,
+		RestartRequired: res.RestartRequired,
+		Persisted:       res.Persisted,
+		DryRun:          params.DryRun,
+	}
+	if !res.RollbackAt.IsZero() {
+		result.RollbackAt = res.RollbackAt.Format(time.RFC3339)
+	}
+	switch {
+	case params.DryRun:
+		result.Message = "config valid"
+	case len(res.Changed) == 0:
+		result.Message = "no changes"
+	case len(res.RestartRequired) > 0:
+		result.Message = "config updated, restart required for some fields"
+	default:
+		result.Message = "config updated"
+	}
+	return json.Marshal(result)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/netdiag"
+)
+
+// NetworkPing ICMP Ping 测试
+// 命令类型: network.ping
+// 用法: r.Register(command.CmdNetworkPing, handlers.NetworkPing)
+func NetworkPing(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	var params command.NetworkPingParams
+	if err := json.Unmarshal(payload, &params); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+	return json.Marshal(netdiag.Ping(ctx, params))
+}
+
+// NetworkTrace 路由追踪
+// 命令类型: network.trace
+func NetworkTrace(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	var params command.NetworkTraceParams
+	if err := json.Unmarshal(payload, &params); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+	return json.Marshal(netdiag.Trace(ctx, params))
+}
+
+// NetworkTCP TCP 连接测试
+// 命令类型: network.tcp
+func NetworkTCP(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	var params command.NetworkTCPParams
+	if err := json.Unmarshal(payload, &params); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+	return json.Marshal(netdiag.TCPConnect(ctx, params))
+}
+
+// NetworkHTTP HTTP 探测
+// 命令类型: network.http
+func NetworkHTTP(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	var params command.NetworkHTTPParams
+	if err := json.Unmarshal(payload, &params); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+	return json.Marshal(netdiag.HTTPProbe(ctx, params))
+}
+
+// NetworkDNS DNS 解析
+// 命令类型: network.dns
+func NetworkDNS(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	var params command.NetworkDNSParams
+	if err := json.Unmarshal(payload, &params); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+	return json.Marshal(netdiag.DNSLookup(ctx, params))
+}
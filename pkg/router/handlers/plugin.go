@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/plugin"
+)
+
+// pluginManager 插件管理器（通过 Config.Plugins 设置）
+var pluginManager *plugin.Manager
+
+// PluginList 列出插件目录中的插件及其注册的命令
+// 命令类型: plugin.list
+// 用法: r.Register(command.CmdPluginList, handlers.PluginList)
+func PluginList(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	if pluginManager == nil {
+		return nil, fmt.Errorf("plugins are not enabled")
+	}
+	return json.Marshal(command.PluginResult{
+		Success: true,
+		Dir:     pluginManager.Dir(),
+		Plugins: pluginManager.List(),
+		Message: "ok",
+	})
+}
+
+// PluginRescan 重新扫描插件目录，注册新增命令并注销已删除插件的命令
+// 命令类型: plugin.rescan
+// 用法: r.Register(command.CmdPluginRescan, handlers.PluginRescan)
+func PluginRescan(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	if pluginManager == nil {
+		return nil, fmt.Errorf("plugins are not enabled")
+	}
+	scan, err := pluginManager.Scan()
+	if err != nil {
+		return json.Marshal(command.PluginResult{
+			Success: false,
+			Dir:     pluginManager.Dir(),
+			Plugins: pluginManager.List(),
+			Message: "rescan failed",
+			Error:   err.Error(),
+		})
+	}
+	return json.Marshal(command.PluginResult{
+		Success: true,
+		Dir:     pluginManager.Dir(),
+		Plugins: pluginManager.List(),
+		Scan:    scan,
+		Message: fmt.Sprintf("%d plugin(s) loaded, %d unloaded", len(scan.Loaded), len(scan.Unloaded)),
+	})
+}
@@ -76,3 +76,119 @@ func ProcessReport(ctx context.Context, payload json.RawMessage) (json.RawMessag
 		Success: true,
 	})
 }
+
+// processKillPolicy process.kill 使用的终止策略（可通过 Config.ProcessKillPolicy 覆盖）
+var processKillPolicy = process.DefaultKillPolicy()
+
+// ProcessList 列出进程（支持过滤、排序、分页）
+// 命令类型: process.list
+// 用法: r.Register(command.CmdProcessList, handlers.ProcessList)
+func ProcessList(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	var params command.ProcessListParams
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &params); err != nil {
+			return nil, fmt.Errorf("invalid params: %w", err)
+		}
+	}
+
+	collector := process.NewCollector(nil)
+	infos, total, err := collector.List(process.ListOptions{
+		Filter:         params.Filter,
+		User:           params.User,
+		Status:         params.Status,
+		PPID:           params.PPID,
+		SortBy:         params.SortBy,
+		Order:          params.Order,
+		Offset:         params.Offset,
+		Limit:          params.Limit,
+		CPUSampleDelay: time.Duration(params.CPUSampleMs) * time.Millisecond,
+	})
+	if err != nil {
+		return json.Marshal(command.ProcessListResult{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	processes := make([]command.ProcessListItem, 0, len(infos))
+	for _, info := range infos {
+		processes = append(processes, command.ProcessListItem{
+			PID:        info.PID,
+			PPID:       info.PPID,
+			Name:       info.Name,
+			Cmdline:    info.Cmdline,
+			User:       info.User,
+			Status:     info.Status,
+			StartTime:  info.StartTime.Format(time.RFC3339),
+			Threads:    info.Threads,
+			CPUPercent: info.CPUPercent,
+			MemoryMB:   info.MemoryMB,
+			MemoryPct:  info.MemoryPct,
+		})
+	}
+
+	return json.Marshal(command.ProcessListResult{
+		Success:   true,
+		Processes: processes,
+		Total:     total,
+		Offset:    params.Offset,
+		Limit:     len(processes),
+	})
+}
+
+// ProcessKill 向进程发送信号（受终止策略保护，PID 1 和 Agent 自身不可终止）
+// 命令类型: process.kill
+// 用法: r.Register(command.CmdProcessKill, handlers.ProcessKill)
+func ProcessKill(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	var params command.ProcessKillParams
+	if err := json.Unmarshal(payload, &params); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+
+	sig, err := process.ParseSignal(params.Signal)
+	if err != nil {
+		return json.Marshal(command.ProcessKillResult{
+			Success: false,
+			Signal:  params.Signal,
+			Error:   err.Error(),
+		})
+	}
+
+	collector := process.NewCollector(nil)
+	result, err := collector.Kill(params.PID, process.KillOptions{
+		Signal:     sig,
+		Tree:       params.Tree,
+		Wait:       time.Duration(params.WaitSeconds) * time.Second,
+		ForceAfter: params.ForceAfter,
+	}, processKillPolicy)
+	if err != nil {
+		resp := command.ProcessKillResult{
+			Success: false,
+			Signal:  process.SignalName(sig),
+			Error:   err.Error(),
+		}
+		if result != nil {
+			resp.Signaled = result.Signaled
+			resp.Skipped = result.Skipped
+		}
+		return json.Marshal(resp)
+	}
+
+	resp := command.ProcessKillResult{
+		Success:   len(result.Signaled) > 0 && len(result.Remaining) == 0,
+		Signal:    process.SignalName(sig),
+		Signaled:  result.Signaled,
+		Skipped:   result.Skipped,
+		Remaining: result.Remaining,
+		Forced:    result.Forced,
+	}
+	switch {
+	case len(result.Remaining) > 0:
+		resp.Message = fmt.Sprintf("%d process(es) still alive after %ds", len(result.Remaining), params.WaitSeconds)
+	case params.WaitSeconds > 0:
+		resp.Message = fmt.Sprintf("%d process(es) exited", len(result.Signaled))
+	default:
+		resp.Message = fmt.Sprintf("signal %s sent to %d process(es)", resp.Signal, len(result.Signaled))
+	}
+	return json.Marshal(resp)
+}
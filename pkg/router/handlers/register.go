@@ -1,15 +1,27 @@
 package handlers
 
 import (
+	"github.com/voilet/quic-flow/pkg/agentconfig"
+	"github.com/voilet/quic-flow/pkg/agentupdate"
 	"github.com/voilet/quic-flow/pkg/command"
 	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/plugin"
+	"github.com/voilet/quic-flow/pkg/process"
 	"github.com/voilet/quic-flow/pkg/router"
+	"github.com/voilet/quic-flow/pkg/task/executor"
+	"github.com/voilet/quic-flow/pkg/task/local"
 )
 
 // Config 处理器配置
 type Config struct {
-	Version string                 // 客户端版本号
-	Logger  *monitoring.Logger     // 可选，用于 Server 端处理器
+	Version           string               // 客户端版本号
+	Logger            *monitoring.Logger   // 可选，用于 Server 端处理器
+	ProcessKillPolicy *process.KillPolicy  // 可选，process.kill 的额外保护策略
+	AgentConfig       *agentconfig.Manager // 可选，设置后注册 config.get/config.update
+	Updater           *agentupdate.Updater // 可选，设置后注册 agent.update/agent.update_status
+	Plugins           *plugin.Manager      // 可选，设置后注册 plugin.list/plugin.rescan
+	Tasks             *executor.Runner     // 可选，设置后注册 task.execute/task.cancel
+	LocalTasks        *local.Scheduler     // 可选，设置后注册 task.config.push
 }
 
 // RegisterBuiltinHandlers 注册所有内置处理器
@@ -27,6 +39,25 @@ func RegisterBuiltinHandlers(r *router.Router, cfg *Config) {
 	// 初始化状态（用于 GetStatus）
 	InitStatus(cfg.Version)
 
+	if cfg.ProcessKillPolicy != nil {
+		processKillPolicy = cfg.ProcessKillPolicy
+	}
+	if cfg.AgentConfig != nil {
+		agentConfig = cfg.AgentConfig
+	}
+	if cfg.Updater != nil {
+		agentUpdater = cfg.Updater
+	}
+	if cfg.Plugins != nil {
+		pluginManager = cfg.Plugins
+	}
+	if cfg.Tasks != nil {
+		taskRunner = cfg.Tasks
+	}
+	if cfg.LocalTasks != nil {
+		localTasks = cfg.LocalTasks
+	}
+
 	// 注册内置处理器（简洁的函数式风格）
 	r.Register(command.CmdExecShell, ExecShell)
 	r.Register(command.CmdGetStatus, GetStatus)
@@ -35,6 +66,13 @@ func RegisterBuiltinHandlers(r *router.Router, cfg *Config) {
 	r.Register(command.CmdNetworkInterfaces, GetNetworkInterfaces)
 	r.Register(command.CmdNetworkSpeed, GetNetworkSpeed)
 
+	// 网络诊断处理器
+	r.Register(command.CmdNetworkPing, NetworkPing)
+	r.Register(command.CmdNetworkTrace, NetworkTrace)
+	r.Register(command.CmdNetworkTCP, NetworkTCP)
+	r.Register(command.CmdNetworkHTTP, NetworkHTTP)
+	r.Register(command.CmdNetworkDNS, NetworkDNS)
+
 	// 硬件信息处理器
 	r.Register(command.CmdHardwareInfo, GetHardwareInfo)
 
@@ -45,6 +83,35 @@ func RegisterBuiltinHandlers(r *router.Router, cfg *Config) {
 	// 进程采集处理器
 	r.Register(command.CmdProcessCollect, ProcessCollect)
 	r.Register(command.CmdProcessReport, ProcessReport)
+	r.Register(command.CmdProcessList, ProcessList)
+	r.Register(command.CmdProcessKill, ProcessKill)
+
+	// 配置管理处理器（需要配置管理器）
+	if cfg.AgentConfig != nil {
+		r.Register(command.CmdConfigGet, ConfigGet)
+		r.Register(command.CmdConfigUpdate, ConfigUpdate)
+	}
+
+	// Agent 自升级处理器（需要升级器）
+	if cfg.Updater != nil {
+		r.Register(command.CmdAgentUpdate, AgentUpdate)
+		r.Register(command.CmdAgentUpdateStatus, AgentUpdateStatus)
+	}
+
+	// 插件管理处理器（需要插件管理器，插件命令在扫描时注册）
+	if cfg.Plugins != nil {
+		r.Register(command.CmdPluginList, PluginList)
+		r.Register(command.CmdPluginRescan, PluginRescan)
+	}
+
+	// 定时任务处理器（需要任务执行器）
+	if cfg.Tasks != nil {
+		r.Register(command.CmdTaskExecute, TaskExecute)
+		r.Register(command.CmdTaskCancel, TaskCancel)
+	}
+	if cfg.LocalTasks != nil {
+		r.Register(command.CmdTaskConfigPush, TaskConfigPush)
+	}
 
 	// 容器采集处理器
 	r.Register(command.CmdContainerCollect, ContainerCollect)
@@ -56,6 +123,15 @@ func RegisterBuiltinHandlers(r *router.Router, cfg *Config) {
 	// K8s Pod 采集处理器
 	r.Register(command.CmdK8sCollect, K8sCollect)
 	r.Register(command.CmdK8sReport, K8sReport)
+
+	// 服务管理处理器（systemd，SysV 回退）
+	r.Register(command.CmdServiceStatus, ServiceStatus)
+	r.Register(command.CmdServiceStart, ServiceStart)
+	r.Register(command.CmdServiceStop, ServiceStop)
+	r.Register(command.CmdServiceRestart, ServiceRestart)
+	r.Register(command.CmdServiceList, ServiceList)
+	r.Register(command.CmdServiceEnable, ServiceEnable)
+	r.Register(command.CmdServiceDisable, ServiceDisable)
 }
 
 // ============================================================================
@@ -72,12 +148,32 @@ const (
 	CmdEcho              = command.CmdEcho
 	CmdNetworkInterfaces = command.CmdNetworkInterfaces
 	CmdNetworkSpeed      = command.CmdNetworkSpeed
+	CmdNetworkPing       = command.CmdNetworkPing
+	CmdNetworkTrace      = command.CmdNetworkTrace
+	CmdNetworkTCP        = command.CmdNetworkTCP
+	CmdNetworkHTTP       = command.CmdNetworkHTTP
+	CmdNetworkDNS        = command.CmdNetworkDNS
 	CmdHardwareInfo      = command.CmdHardwareInfo
 	CmdDiskBenchmark     = command.CmdDiskBenchmark
 	CmdDiskIOPS          = command.CmdDiskIOPS
 	// 进程采集
 	CmdProcessCollect = command.CmdProcessCollect
 	CmdProcessReport  = command.CmdProcessReport
+	CmdProcessList    = command.CmdProcessList
+	CmdProcessKill    = command.CmdProcessKill
+	// 配置管理
+	CmdConfigGet    = command.CmdConfigGet
+	CmdConfigUpdate = command.CmdConfigUpdate
+	// Agent 自升级
+	CmdAgentUpdate       = command.CmdAgentUpdate
+	CmdAgentUpdateStatus = command.CmdAgentUpdateStatus
+	// 插件管理
+	CmdPluginList   = command.CmdPluginList
+	CmdPluginRescan = command.CmdPluginRescan
+	// 定时任务
+	CmdTaskExecute    = command.CmdTaskExecute
+	CmdTaskCancel     = command.CmdTaskCancel
+	CmdTaskConfigPush = command.CmdTaskConfigPush
 	// 容器采集
 	CmdContainerCollect = command.CmdContainerCollect
 	CmdContainerReport  = command.CmdContainerReport
@@ -87,4 +183,12 @@ const (
 	// K8s Pod 采集
 	CmdK8sCollect = command.CmdK8sCollect
 	CmdK8sReport  = command.CmdK8sReport
+	// 服务管理
+	CmdServiceStatus  = command.CmdServiceStatus
+	CmdServiceStart   = command.CmdServiceStart
+	CmdServiceStop    = command.CmdServiceStop
+	CmdServiceRestart = command.CmdServiceRestart
+	CmdServiceList    = command.CmdServiceList
+	CmdServiceEnable  = command.CmdServiceEnable
+	CmdServiceDisable = command.CmdServiceDisable
 )
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/service"
+)
+
+// ServiceStatus 查询服务状态
+// 命令类型: service.status
+// 用法: r.Register(command.CmdServiceStatus, handlers.ServiceStatus)
+func ServiceStatus(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	var params command.ServiceParams
+	if err := json.Unmarshal(payload, &params); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+
+	mgr := service.NewManager()
+	st, err := mgr.Status(ctx, params.Name, params.JournalLines)
+	if err != nil {
+		result := command.ServiceResult{
+			Name:    params.Name,
+			Status:  service.StatusUnknown,
+			Success: false,
+			Message: err.Error(),
+			Manager: mgr.Kind(),
+		}
+		if st != nil {
+			result.LoadState = st.LoadState
+		}
+		return json.Marshal(result)
+	}
+
+	return json.Marshal(toServiceResult(mgr.Kind(), st, "ok"))
+}
+
+// ServiceStart 启动服务
+// 命令类型: service.start
+func ServiceStart(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	return serviceAction(ctx, payload, "start", (*service.Manager).Start)
+}
+
+// ServiceStop 停止服务
+// 命令类型: service.stop
+func ServiceStop(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	return serviceAction(ctx, payload, "stop", (*service.Manager).Stop)
+}
+
+// ServiceRestart 重启服务
+// 命令类型: service.restart
+func ServiceRestart(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	return serviceAction(ctx, payload, "restart", (*service.Manager).Restart)
+}
+
+// ServiceEnable 设置服务开机自启
+// 命令类型: service.enable
+func ServiceEnable(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	return serviceAction(ctx, payload, "enable", (*service.Manager).Enable)
+}
+
+// ServiceDisable 取消服务开机自启
+// 命令类型: service.disable
+func ServiceDisable(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	return serviceAction(ctx, payload, "disable", (*service.Manager).Disable)
+}
+
+// ServiceList 列出服务
+// 命令类型: service.list
+func ServiceList(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	var params command.ServiceListParams
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &params); err != nil {
+			return nil, fmt.Errorf("invalid params: %w", err)
+		}
+	}
+
+	mgr := service.NewManager()
+	units, err := mgr.List(ctx, service.ListOptions{
+		Filter: params.Filter,
+		State:  params.State,
+		All:    params.All,
+	})
+	if err != nil {
+		return json.Marshal(command.ServiceListResult{
+			Success: false,
+			Manager: mgr.Kind(),
+			Error:   err.Error(),
+		})
+	}
+
+	services := make([]command.ServiceUnit, 0, len(units))
+	for _, u := range units {
+		services = append(services, command.ServiceUnit{
+			Name:        u.Name,
+			Description: u.Description,
+			LoadState:   u.LoadState,
+			ActiveState: u.ActiveState,
+			SubState:    u.SubState,
+			Status:      u.Status,
+		})
+	}
+
+	return json.Marshal(command.ServiceListResult{
+		Success:  true,
+		Manager:  mgr.Kind(),
+		Services: services,
+		Count:    len(services),
+	})
+}
+
+// serviceAction 执行服务操作并返回操作后的状态
+func serviceAction(ctx context.Context, payload json.RawMessage, action string,
+	fn func(*service.Manager, context.Context, string) error) (json.RawMessage, error) {
+	var params command.ServiceParams
+	if err := json.Unmarshal(payload, &params); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+
+	mgr := service.NewManager()
+	if err := fn(mgr, ctx, params.Name); err != nil {
+		return json.Marshal(command.ServiceResult{
+			Name:    params.Name,
+			Status:  service.StatusUnknown,
+			Success: false,
+			Message: fmt.Sprintf("%s failed: %v", action, err),
+			Manager: mgr.Kind(),
+		})
+	}
+
+	// 操作成功后回读状态，不附带日志
+	st, err := mgr.Status(ctx, params.Name, -1)
+	if err != nil {
+		return json.Marshal(command.ServiceResult{
+			Name:    params.Name,
+			Status:  service.StatusUnknown,
+			Success: true,
+			Message: fmt.Sprintf("%s succeeded, but status query failed: %v", action, err),
+			Manager: mgr.Kind(),
+		})
+	}
+
+	return json.Marshal(toServiceResult(mgr.Kind(), st, action+" succeeded"))
+}
+
+// toServiceResult 转换为命令结果格式
+func toServiceResult(manager string, st *service.Status, message string) command.ServiceResult {
+	return command.ServiceResult{
+		Name:          st.Name,
+		Status:        st.Status,
+		Success:       true,
+		Message:       message,
+		Manager:       manager,
+		Description:   st.Description,
+		LoadState:     st.LoadState,
+		ActiveState:   st.ActiveState,
+		SubState:      st.SubState,
+		UnitFileState: st.UnitFileState,
+		MainPID:       st.MainPID,
+		MemoryBytes:   st.MemoryBytes,
+		RestartCount:  st.RestartCount,
+		ActiveSince:   st.ActiveSince,
+		Journal:       st.Journal,
+	}
+}
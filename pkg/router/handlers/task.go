@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/protocol"
+	"github.com/voilet/quic-flow/pkg/task/executor"
+	"github.com/voilet/quic-flow/pkg/task/local"
+)
+
+var (
+	// taskRunner 定时任务执行器（通过 Config.Tasks 设置）
+	taskRunner *executor.Runner
+
+	// localTasks 本地任务调度器（通过 Config.LocalTasks 设置）
+	localTasks *local.Scheduler
+)
+
+// TaskExecute 在后台执行服务器下发的任务，进度与结果以 task.progress/task.result 事件上报
+// 命令类型: task.execute
+// 用法: r.Register(command.CmdTaskExecute, handlers.TaskExecute)
+func TaskExecute(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	if taskRunner == nil {
+		return nil, fmt.Errorf("task execution is not enabled")
+	}
+	var task protocol.TaskExecution
+	if err := json.Unmarshal(payload, &task); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+	result := command.TaskAcceptResult{Accepted: true, ExecutionID: task.ExecutionId}
+	if err := taskRunner.Start(&task); err != nil {
+		result.Accepted = false
+		result.Error = err.Error()
+	}
+	return json.Marshal(result)
+}
+
+// TaskCancel 取消执行中的任务
+// 命令类型: task.cancel
+// 用法: r.Register(command.CmdTaskCancel, handlers.TaskCancel)
+func TaskCancel(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	if taskRunner == nil {
+		return nil, fmt.Errorf("task execution is not enabled")
+	}
+	var params command.TaskCancelParams
+	if err := json.Unmarshal(payload, &params); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+	if params.ExecutionID == "" {
+		return nil, fmt.Errorf("execution_id is required")
+	}
+	result := command.TaskCancelResult{ExecutionID: params.ExecutionID, Message: "cancelling"}
+	if taskRunner.Cancel(params.ExecutionID) {
+		result.Success = true
+	} else {
+		result.Message = "execution not running"
+	}
+	return json.Marshal(result)
+}
+
+// TaskConfigPush 应用服务器推送的本地调度任务配置（版本不高于当前版本时忽略）
+// 命令类型: task.config.push
+// 用法: r.Register(command.CmdTaskConfigPush, handlers.TaskConfigPush)
+func TaskConfigPush(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	if localTasks == nil {
+		return nil, fmt.Errorf("local task scheduling is not enabled")
+	}
+	var push protocol.TaskConfigPush
+	if err := json.Unmarshal(payload, &push); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+	if err := localTasks.Apply(&push); err != nil {
+		return nil, err
+	}
+	status := localTasks.Status()
+	return json.Marshal(command.TaskConfigPushResult{
+		Success:       true,
+		ConfigVersion: status.ConfigVersion,
+		TaskCount:     status.Tasks,
+	})
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/voilet/quic-flow/pkg/agentupdate"
+	"github.com/voilet/quic-flow/pkg/command"
+)
+
+// agentUpdater 自升级器（通过 Config.Updater 设置）
+var agentUpdater *agentupdate.Updater
+
+// AgentUpdate 安装服务器已推送的二进制，校验签名后替换并重启
+// 新版本未能在超时内重连时自动回滚
+// 命令类型: agent.update
+// 用法: r.Register(command.CmdAgentUpdate, handlers.AgentUpdate)
+func AgentUpdate(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	var params command.AgentUpdateParams
+	if err := json.Unmarshal(payload, &params); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+	if agentUpdater == nil {
+		return nil, fmt.Errorf("agent updates are not enabled")
+	}
+
+	result, err := agentUpdater.Apply(&params)
+	if err != nil {
+		return json.Marshal(command.AgentUpdateResult{
+			Success:  false,
+			UpdateID: params.UpdateID,
+			Message:  "update rejected",
+			Error:    err.Error(),
+		})
+	}
+	return json.Marshal(result)
+}
+
+// AgentUpdateStatus 查询当前版本与最近一次升级状态
+// 命令类型: agent.update_status
+// 用法: r.Register(command.CmdAgentUpdateStatus, handlers.AgentUpdateStatus)
+func AgentUpdateStatus(ctx context.Context, payload json.RawMessage) (json.RawMessage, error) {
+	if agentUpdater == nil {
+		return nil, fmt.Errorf("agent updates are not enabled")
+	}
+	return json.Marshal(agentUpdater.Status())
+}
@@ -268,6 +268,14 @@ func WithCallbackContext(ctx context.Context, needCallback bool, callbackID stri
 	return ctx
 }
 
+// GetClientID 从context中获取发送方客户端 ID（由服务器根据连接会话填写）
+func GetClientID(ctx context.Context) string {
+	if v, ok := ctx.Value(ContextKeyClientID).(string); ok {
+		return v
+	}
+	return ""
+}
+
 // GetCallbackInfo 从context中获取回调信息
 func GetCallbackInfo(ctx context.Context) (needCallback bool, callbackID string) {
 	if v := ctx.Value(ContextKeyNeedCallback); v != nil {
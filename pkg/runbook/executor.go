@@ -0,0 +1,402 @@
+package runbook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+)
+
+// 执行默认值
+const (
+	defaultStepTimeout = 30 * time.Second
+	maxRunHistory      = 200
+)
+
+// CommandSender 命令下发接口（由 command.CommandManager 实现）
+// 通过 CommandManager 下发的每条命令都会进入命令历史
+type CommandSender interface {
+	SendCommandToMultiple(clientIDs []string, commandType string, payload json.RawMessage, timeout time.Duration) *command.MultiCommandResponse
+}
+
+// Executor Runbook 执行器
+type Executor struct {
+	store  *Store
+	sender CommandSender
+	logger *monitoring.Logger
+
+	mu      sync.RWMutex
+	runs    map[string]*Run
+	cancels map[string]context.CancelFunc
+}
+
+// NewExecutor 创建执行器
+func NewExecutor(store *Store, sender CommandSender, logger *monitoring.Logger) *Executor {
+	return &Executor{
+		store:   store,
+		sender:  sender,
+		logger:  logger,
+		runs:    make(map[string]*Run),
+		cancels: make(map[string]context.CancelFunc),
+	}
+}
+
+// RunTemplate 同步执行单个模板
+func (e *Executor) RunTemplate(templateID string, params map[string]string, clientIDs []string) (*Run, error) {
+	t, err := e.store.GetTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+	rb := &Runbook{
+		Name:  t.Name,
+		Steps: []Step{{Name: t.Name, TemplateID: t.ID, OnFailure: FailurePolicyContinue}},
+	}
+	run, err := e.prepare(rb, params, clientIDs)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e.track(run, cancel)
+	e.execute(ctx, run, rb)
+	return e.snapshot(run.ID), nil
+}
+
+// Start 异步执行 Runbook，返回执行记录
+func (e *Executor) Start(runbookID string, params map[string]string, clientIDs []string) (*Run, error) {
+	rb, err := e.store.GetRunbook(runbookID)
+	if err != nil {
+		return nil, err
+	}
+	run, err := e.prepare(rb, params, clientIDs)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e.track(run, cancel)
+	go e.execute(ctx, run, rb)
+	return e.snapshot(run.ID), nil
+}
+
+// Cancel 取消执行（当前步骤完成后生效）
+func (e *Executor) Cancel(runID string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	run, ok := e.runs[runID]
+	if !ok {
+		return fmt.Errorf("run not found: %s", runID)
+	}
+	cancel, ok := e.cancels[runID]
+	if !ok {
+		return fmt.Errorf("run %s is already %s", runID, run.Status)
+	}
+	cancel()
+	return nil
+}
+
+// GetRun 获取执行记录
+func (e *Executor) GetRun(runID string) (*Run, bool) {
+	run := e.snapshot(runID)
+	return run, run != nil
+}
+
+// ListRuns 列出执行记录（最新在前）
+func (e *Executor) ListRuns(runbookID string) []*Run {
+	e.mu.RLock()
+	ids := make([]string, 0, len(e.runs))
+	for id, run := range e.runs {
+		if runbookID == "" || run.RunbookID == runbookID {
+			ids = append(ids, id)
+		}
+	}
+	e.mu.RUnlock()
+
+	list := make([]*Run, 0, len(ids))
+	for _, id := range ids {
+		if run := e.snapshot(id); run != nil {
+			list = append(list, run)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list
+}
+
+// prepare 校验参数并创建执行记录
+func (e *Executor) prepare(rb *Runbook, params map[string]string, clientIDs []string) (*Run, error) {
+	if len(clientIDs) == 0 {
+		return nil, fmt.Errorf("no target clients")
+	}
+
+	// 预先渲染所有步骤，参数错误在执行前返回
+	run := &Run{
+		ID:          uuid.New().String(),
+		RunbookID:   rb.ID,
+		RunbookName: rb.Name,
+		ClientIDs:   clientIDs,
+		Params:      params,
+		Status:      RunStatusPending,
+		CreatedAt:   time.Now(),
+	}
+	for i := range rb.Steps {
+		step := &rb.Steps[i]
+		t, err := e.store.GetTemplate(step.TemplateID)
+		if err != nil {
+			return nil, fmt.Errorf("step %s: %w", step.Name, err)
+		}
+		values, err := stepValues(step, params)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := t.Render(values); err != nil {
+			return nil, fmt.Errorf("step %s: %w", step.Name, err)
+		}
+		run.Steps = append(run.Steps, &StepRun{
+			Name:        step.Name,
+			TemplateID:  t.ID,
+			CommandType: t.CommandType,
+			Status:      RunStatusPending,
+		})
+	}
+	return run, nil
+}
+
+// track 登记执行记录，超出上限时淘汰最早的已结束记录
+func (e *Executor) track(run *Run, cancel context.CancelFunc) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.runs[run.ID] = run
+	e.cancels[run.ID] = cancel
+
+	if len(e.runs) <= maxRunHistory {
+		return
+	}
+	var oldest *Run
+	for _, r := range e.runs {
+		if _, active := e.cancels[r.ID]; active {
+			continue
+		}
+		if oldest == nil || r.CreatedAt.Before(oldest.CreatedAt) {
+			oldest = r
+		}
+	}
+	if oldest != nil {
+		delete(e.runs, oldest.ID)
+	}
+}
+
+// execute 按顺序执行步骤
+func (e *Executor) execute(ctx context.Context, run *Run, rb *Runbook) {
+	e.update(run, func() { run.Status = RunStatusRunning })
+	e.logger.Info("Runbook run started", "run_id", run.ID, "runbook", run.RunbookName, "client_count", len(run.ClientIDs))
+
+	active := run.ClientIDs
+	status := RunStatusSuccess
+	runErr := ""
+
+	for i := range rb.Steps {
+		step := &rb.Steps[i]
+		sr := run.Steps[i]
+
+		if ctx.Err() != nil {
+			status, runErr = RunStatusCancelled, "cancelled"
+			e.skipRemaining(run, i)
+			break
+		}
+		if len(active) == 0 {
+			status, runErr = RunStatusFailed, "no clients left to run"
+			e.skipRemaining(run, i)
+			break
+		}
+
+		succeeded, failed := e.runStep(ctx, run, step, sr, active)
+
+		if len(failed) == 0 {
+			continue
+		}
+		switch step.OnFailure {
+		case FailurePolicyContinue:
+			status = RunStatusFailed
+		case FailurePolicyExclude:
+			status = RunStatusFailed
+			active = succeeded
+		default:
+			status, runErr = RunStatusFailed, fmt.Sprintf("step %s failed on %d client(s)", step.Name, len(failed))
+			e.skipRemaining(run, i+1)
+		}
+		if runErr != "" {
+			break
+		}
+	}
+
+	e.update(run, func() {
+		now := time.Now()
+		run.Status = status
+		run.Error = runErr
+		run.FinishedAt = &now
+	})
+
+	e.mu.Lock()
+	if cancel, ok := e.cancels[run.ID]; ok {
+		cancel()
+		delete(e.cancels, run.ID)
+	}
+	e.mu.Unlock()
+
+	e.logger.Info("Runbook run finished", "run_id", run.ID, "runbook", run.RunbookName, "status", status)
+}
+
+// runStep 执行单个步骤（含失败客户端重试），返回成功与失败的客户端
+func (e *Executor) runStep(ctx context.Context, run *Run, step *Step, sr *StepRun, clientIDs []string) ([]string, []string) {
+	t, err := e.store.GetTemplate(step.TemplateID)
+	var payload json.RawMessage
+	if err == nil {
+		var values map[string]string
+		if values, err = stepValues(step, run.Params); err == nil {
+			payload, err = t.Render(values)
+		}
+	}
+	now := time.Now()
+	if err != nil {
+		e.update(run, func() {
+			sr.Status = RunStatusFailed
+			sr.Error = err.Error()
+			sr.ClientIDs = clientIDs
+			sr.FailedCount = len(clientIDs)
+			sr.StartedAt, sr.FinishedAt = &now, &now
+		})
+		return nil, clientIDs
+	}
+
+	timeout := defaultStepTimeout
+	if t.Timeout > 0 {
+		timeout = time.Duration(t.Timeout) * time.Second
+	}
+	if step.Timeout > 0 {
+		timeout = time.Duration(step.Timeout) * time.Second
+	}
+
+	e.update(run, func() {
+		sr.Status = RunStatusRunning
+		sr.ClientIDs = clientIDs
+		sr.StartedAt = &now
+	})
+
+	results := make(map[string]*command.ClientCommandResult, len(clientIDs))
+	pending := clientIDs
+	for attempt := 0; attempt <= step.Retries && len(pending) > 0; attempt++ {
+		if attempt > 0 && ctx.Err() != nil {
+			break
+		}
+		resp := e.sender.SendCommandToMultiple(pending, t.CommandType, payload, timeout)
+
+		var retry []string
+		byClient := make(map[string]*command.ClientCommandResult)
+		if resp != nil {
+			for _, r := range resp.Results {
+				if r != nil {
+					byClient[r.ClientID] = r
+				}
+			}
+		}
+		for _, cid := range pending {
+			r, ok := byClient[cid]
+			if !ok {
+				r = &command.ClientCommandResult{ClientID: cid, Status: command.CommandStatusFailed, Error: "no result"}
+			}
+			results[cid] = r
+			if !ResultSucceeded(r) {
+				retry = append(retry, cid)
+			}
+		}
+		pending = retry
+
+		e.update(run, func() {
+			sr.Attempts = attempt + 1
+			if resp != nil && resp.TaskID != "" {
+				sr.TaskIDs = append(sr.TaskIDs, resp.TaskID)
+			}
+		})
+	}
+
+	var succeeded, failed []string
+	ordered := make([]*command.ClientCommandResult, 0, len(clientIDs))
+	for _, cid := range clientIDs {
+		r := results[cid]
+		ordered = append(ordered, r)
+		if ResultSucceeded(r) {
+			succeeded = append(succeeded, cid)
+		} else {
+			failed = append(failed, cid)
+		}
+	}
+
+	e.update(run, func() {
+		end := time.Now()
+		sr.Results = ordered
+		sr.SuccessCount = len(succeeded)
+		sr.FailedCount = len(failed)
+		sr.FinishedAt = &end
+		sr.Status = RunStatusSuccess
+		if len(failed) > 0 {
+			sr.Status = RunStatusFailed
+		}
+	})
+	return succeeded, failed
+}
+
+// skipRemaining 将 from 之后的步骤标记为跳过
+func (e *Executor) skipRemaining(run *Run, from int) {
+	e.update(run, func() {
+		for _, sr := range run.Steps[from:] {
+			if sr.Status == RunStatusPending {
+				sr.Status = RunStatusSkipped
+			}
+		}
+	})
+}
+
+// update 在锁内修改执行记录
+func (e *Executor) update(run *Run, fn func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fn()
+}
+
+// snapshot 返回执行记录的副本
+func (e *Executor) snapshot(runID string) *Run {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	run, ok := e.runs[runID]
+	if !ok {
+		return nil
+	}
+	cp := *run
+	cp.Steps = make([]*StepRun, len(run.Steps))
+	for i, sr := range run.Steps {
+		s := *sr
+		s.TaskIDs = append([]string(nil), sr.TaskIDs...)
+		s.Results = append([]*command.ClientCommandResult(nil), sr.Results...)
+		cp.Steps[i] = &s
+	}
+	return &cp
+}
+
+// ResultSucceeded 判断客户端结果是否成功
+// 命令状态为 completed 且结果中的 success 字段（如存在）不为 false
+func ResultSucceeded(r *command.ClientCommandResult) bool {
+	if r == nil || r.Status != command.CommandStatusCompleted {
+		return false
+	}
+	var body struct {
+		Success *bool `json:"success"`
+	}
+	if len(r.Result) > 0 && json.Unmarshal(r.Result, &body) == nil && body.Success != nil {
+		return *body.Success
+	}
+	return true
+}
@@ -0,0 +1,178 @@
+package runbook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/voilet/quic-flow/pkg/release/variable"
+)
+
+// paramNameRe 参数名格式（与 variable.Manager 支持的 ${VAR} 一致）
+var paramNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// exactVarRe 整个字符串仅为一个 ${VAR}
+var exactVarRe = regexp.MustCompile(`^\$\{([A-Za-z_][A-Za-z0-9_]*)\}$`)
+
+// resolver 变量替换（仅使用 Resolve，不需要数据库）
+var resolver = variable.NewManager(nil)
+
+// Validate 校验模板定义
+func (t *Template) Validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("template name is required")
+	}
+	if t.CommandType == "" {
+		return fmt.Errorf("command_type is required")
+	}
+	if len(t.Payload) == 0 {
+		t.Payload = json.RawMessage("{}")
+	}
+	if !json.Valid(t.Payload) {
+		return fmt.Errorf("payload must be valid JSON")
+	}
+	if t.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+
+	seen := make(map[string]bool)
+	for i := range t.Params {
+		p := &t.Params[i]
+		if !paramNameRe.MatchString(p.Name) {
+			return fmt.Errorf("invalid param name: %q", p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("duplicate param: %s", p.Name)
+		}
+		seen[p.Name] = true
+		if p.Type == "" {
+			p.Type = ParamTypeString
+		}
+		if p.Type == ParamTypeEnum && len(p.Options) == 0 {
+			return fmt.Errorf("param %s: enum requires options", p.Name)
+		}
+		if p.Default != "" {
+			if _, err := p.convert(p.Default); err != nil {
+				return fmt.Errorf("param %s: invalid default: %w", p.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// convert 按类型校验并转换参数值
+func (p *Param) convert(value string) (interface{}, error) {
+	switch p.Type {
+	case ParamTypeString, "":
+		return value, nil
+	case ParamTypeInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", value)
+		}
+		return n, nil
+	case ParamTypeBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", value)
+		}
+		return b, nil
+	case ParamTypeEnum:
+		for _, opt := range p.Options {
+			if value == opt {
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not one of %v", value, p.Options)
+	}
+	return nil, fmt.Errorf("unsupported param type: %s", p.Type)
+}
+
+// Render 使用参数值渲染模板 payload
+// 字符串中的 ${VAR} 由 variable.Manager 替换；若字符串整体为 ${VAR} 且参数为 int/bool，替换为对应 JSON 类型
+func (t *Template) Render(values map[string]string) (json.RawMessage, error) {
+	vars := make(map[string]string)
+	typed := make(map[string]interface{})
+	for _, p := range t.Params {
+		value, ok := values[p.Name]
+		if !ok || value == "" {
+			value = p.Default
+		}
+		if value == "" {
+			if p.Required {
+				return nil, fmt.Errorf("param %s is required", p.Name)
+			}
+			vars[p.Name] = ""
+			continue
+		}
+		v, err := p.convert(value)
+		if err != nil {
+			return nil, fmt.Errorf("param %s: %w", p.Name, err)
+		}
+		vars[p.Name] = value
+		typed[p.Name] = v
+	}
+
+	var payload interface{}
+	if err := json.Unmarshal(t.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("invalid template payload: %w", err)
+	}
+
+	varCtx := &variable.Context{Custom: vars}
+	rendered, err := renderValue(payload, varCtx, typed)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(rendered)
+}
+
+// renderValue 递归替换 JSON 中的字符串
+func renderValue(v interface{}, varCtx *variable.Context, typed map[string]interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		if m := exactVarRe.FindStringSubmatch(val); m != nil {
+			if tv, ok := typed[m[1]]; ok {
+				return tv, nil
+			}
+		}
+		return resolver.Resolve(context.Background(), val, varCtx)
+	case map[string]interface{}:
+		for k, item := range val {
+			r, err := renderValue(item, varCtx, typed)
+			if err != nil {
+				return nil, err
+			}
+			val[k] = r
+		}
+		return val, nil
+	case []interface{}:
+		for i, item := range val {
+			r, err := renderValue(item, varCtx, typed)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = r
+		}
+		return val, nil
+	}
+	return v, nil
+}
+
+// stepValues 合并运行参数与步骤参数，步骤参数中的 ${VAR} 引用运行参数
+func stepValues(step *Step, runParams map[string]string) (map[string]string, error) {
+	values := make(map[string]string, len(runParams)+len(step.Params))
+	for k, v := range runParams {
+		values[k] = v
+	}
+	varCtx := &variable.Context{Custom: runParams}
+	for k, v := range step.Params {
+		resolved, err := resolver.Resolve(context.Background(), v, varCtx)
+		if err != nil {
+			return nil, fmt.Errorf("step %s param %s: %w", step.Name, k, err)
+		}
+		values[k] = resolved
+	}
+	return values, nil
+}
@@ -0,0 +1,197 @@
+package runbook
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+)
+
+// fakeSender 按命令类型返回失败的客户端
+type fakeSender struct {
+	mu       sync.Mutex
+	calls    []string
+	payloads []json.RawMessage
+	failOn   map[string]map[string]int // commandType -> clientID -> 剩余失败次数
+}
+
+func (f *fakeSender) SendCommandToMultiple(clientIDs []string, commandType string, payload json.RawMessage, timeout time.Duration) *command.MultiCommandResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, commandType)
+	f.payloads = append(f.payloads, payload)
+
+	resp := &command.MultiCommandResponse{TaskID: "task-" + commandType}
+	for _, cid := range clientIDs {
+		ok, _ := json.Marshal(command.ShellResult{Success: true})
+		r := &command.ClientCommandResult{ClientID: cid, Status: command.CommandStatusCompleted, Result: ok}
+		if n := f.failOn[commandType][cid]; n > 0 {
+			f.failOn[commandType][cid] = n - 1
+			failed, _ := json.Marshal(command.ShellResult{Success: false, ExitCode: 1})
+			r.Result = failed
+		}
+		resp.Results = append(resp.Results, r)
+	}
+	return resp
+}
+
+func TestTemplateRender(t *testing.T) {
+	tpl := &Template{
+		Name:        "restart",
+		CommandType: command.CmdExecShell,
+		Payload:     json.RawMessage(`{"command":"systemctl restart ${SERVICE}","timeout":"${TIMEOUT}","args":["${MODE}"]}`),
+		Params: []Param{
+			{Name: "SERVICE", Required: true},
+			{Name: "TIMEOUT", Type: ParamTypeInt, Default: "30"},
+			{Name: "MODE", Type: ParamTypeEnum, Options: []string{"soft", "hard"}, Default: "soft"},
+		},
+	}
+	require.NoError(t, tpl.Validate())
+
+	payload, err := tpl.Render(map[string]string{"SERVICE": `nginx"; rm -rf /`})
+	require.NoError(t, err)
+	var out map[string]interface{}
+	require.NoError(t, json.Unmarshal(payload, &out))
+	assert.Equal(t, `systemctl restart nginx"; rm -rf /`, out["command"])
+	assert.Equal(t, float64(30), out["timeout"])
+	assert.Equal(t, []interface{}{"soft"}, out["args"])
+
+	_, err = tpl.Render(nil)
+	assert.Error(t, err, "missing required param")
+	_, err = tpl.Render(map[string]string{"SERVICE": "x", "TIMEOUT": "abc"})
+	assert.Error(t, err)
+	_, err = tpl.Render(map[string]string{"SERVICE": "x", "MODE": "other"})
+	assert.Error(t, err)
+}
+
+func TestTemplateValidate(t *testing.T) {
+	assert.Error(t, (&Template{Name: "a"}).Validate())
+	assert.Error(t, (&Template{Name: "a", CommandType: "x", Payload: json.RawMessage(`{`)}).Validate())
+	assert.Error(t, (&Template{Name: "a", CommandType: "x", Params: []Param{{Name: "1X"}}}).Validate())
+	assert.Error(t, (&Template{Name: "a", CommandType: "x", Params: []Param{{Name: "E", Type: ParamTypeEnum}}}).Validate())
+	assert.Error(t, (&Template{Name: "a", CommandType: "x", Params: []Param{{Name: "N", Type: ParamTypeInt, Default: "x"}}}).Validate())
+}
+
+func TestStorePersistence(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewStore(dir)
+	require.NoError(t, err)
+
+	tpl := &Template{Name: "uptime", CommandType: command.CmdExecShell, Payload: json.RawMessage(`{"command":"uptime"}`)}
+	require.NoError(t, s.SaveTemplate(tpl))
+	rb := &Runbook{Name: "check", Steps: []Step{{TemplateID: tpl.ID}}}
+	require.NoError(t, s.SaveRunbook(rb))
+	assert.Equal(t, FailurePolicyAbort, rb.Steps[0].OnFailure)
+
+	assert.ErrorIs(t, s.DeleteTemplate(tpl.ID), ErrTemplateInUse)
+	assert.ErrorIs(t, s.SaveRunbook(&Runbook{Name: "bad", Steps: []Step{{TemplateID: "missing"}}}), ErrTemplateNotFound)
+
+	reloaded, err := NewStore(dir)
+	require.NoError(t, err)
+	assert.Len(t, reloaded.ListTemplates(), 1)
+	got, err := reloaded.GetRunbook(rb.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "check", got.Name)
+}
+
+func newTestExecutor(t *testing.T, sender *fakeSender) (*Store, *Executor) {
+	s, err := NewStore("")
+	require.NoError(t, err)
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, s.SaveTemplate(&Template{Name: name, CommandType: "cmd." + name}))
+	}
+	return s, NewExecutor(s, sender, monitoring.NewDefaultLogger())
+}
+
+func templateID(s *Store, name string) string {
+	for _, t := range s.ListTemplates() {
+		if t.Name == name {
+			return t.ID
+		}
+	}
+	return ""
+}
+
+func waitRun(t *testing.T, e *Executor, id string) *Run {
+	var run *Run
+	require.Eventually(t, func() bool {
+		run, _ = e.GetRun(id)
+		return run.FinishedAt != nil
+	}, 2*time.Second, 5*time.Millisecond)
+	return run
+}
+
+func TestExecutorAbortPolicy(t *testing.T) {
+	sender := &fakeSender{failOn: map[string]map[string]int{"cmd.b": {"c2": 1}}}
+	s, e := newTestExecutor(t, sender)
+
+	rb := &Runbook{Name: "abort", Steps: []Step{
+		{TemplateID: templateID(s, "a")},
+		{TemplateID: templateID(s, "b")},
+		{TemplateID: templateID(s, "c")},
+	}}
+	require.NoError(t, s.SaveRunbook(rb))
+
+	run, err := e.Start(rb.ID, nil, []string{"c1", "c2"})
+	require.NoError(t, err)
+	run = waitRun(t, e, run.ID)
+
+	assert.Equal(t, RunStatusFailed, run.Status)
+	assert.Equal(t, RunStatusSuccess, run.Steps[0].Status)
+	assert.Equal(t, RunStatusFailed, run.Steps[1].Status)
+	assert.Equal(t, 1, run.Steps[1].FailedCount)
+	assert.Equal(t, RunStatusSkipped, run.Steps[2].Status)
+	assert.Equal(t, []string{"cmd.a", "cmd.b"}, sender.calls)
+}
+
+func TestExecutorExcludeAndRetry(t *testing.T) {
+	sender := &fakeSender{failOn: map[string]map[string]int{
+		"cmd.a": {"c1": 1},  // 重试后成功
+		"cmd.b": {"c2": 10}, // 始终失败
+	}}
+	s, e := newTestExecutor(t, sender)
+
+	rb := &Runbook{Name: "exclude", Steps: []Step{
+		{TemplateID: templateID(s, "a"), Retries: 1},
+		{TemplateID: templateID(s, "b"), OnFailure: FailurePolicyExclude},
+		{TemplateID: templateID(s, "c")},
+	}}
+	require.NoError(t, s.SaveRunbook(rb))
+
+	run, err := e.Start(rb.ID, nil, []string{"c1", "c2"})
+	require.NoError(t, err)
+	run = waitRun(t, e, run.ID)
+
+	assert.Equal(t, RunStatusFailed, run.Status)
+	assert.Equal(t, 2, run.Steps[0].Attempts)
+	assert.Equal(t, RunStatusSuccess, run.Steps[0].Status)
+	assert.Equal(t, []string{"c1"}, run.Steps[2].ClientIDs)
+	assert.Equal(t, RunStatusSuccess, run.Steps[2].Status)
+}
+
+func TestExecutorRunTemplateParams(t *testing.T) {
+	sender := &fakeSender{}
+	s, e := newTestExecutor(t, sender)
+
+	tpl := &Template{
+		Name:        "echo",
+		CommandType: command.CmdExecShell,
+		Payload:     json.RawMessage(`{"command":"echo ${MSG}"}`),
+		Params:      []Param{{Name: "MSG", Required: true}},
+	}
+	require.NoError(t, s.SaveTemplate(tpl))
+
+	_, err := e.RunTemplate(tpl.ID, nil, []string{"c1"})
+	assert.Error(t, err)
+
+	run, err := e.RunTemplate(tpl.ID, map[string]string{"MSG": "hi"}, []string{"c1"})
+	require.NoError(t, err)
+	assert.Equal(t, RunStatusSuccess, run.Status)
+	assert.JSONEq(t, `{"command":"echo hi"}`, string(sender.payloads[0]))
+	assert.Len(t, e.ListRuns(""), 1)
+}
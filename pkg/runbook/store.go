@@ -0,0 +1,211 @@
+package runbook
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/voilet/quic-flow/pkg/jsonfile"
+)
+
+// 存储错误
+var (
+	ErrTemplateNotFound = errors.New("template not found")
+	ErrRunbookNotFound  = errors.New("runbook not found")
+	ErrTemplateInUse    = errors.New("template is used by runbook")
+)
+
+// Store 模板与 Runbook 存储（JSON 文件）
+type Store struct {
+	file      *jsonfile.File
+	templates map[string]*Template
+	runbooks  map[string]*Runbook
+}
+
+// storeFile 持久化文件格式
+type storeFile struct {
+	Templates []*Template `json:"templates"`
+	Runbooks  []*Runbook  `json:"runbooks"`
+}
+
+// NewStore 创建存储，dir 为空时仅保存在内存中
+func NewStore(dir string) (*Store, error) {
+	var f storeFile
+	file, err := jsonfile.Open(dir, "runbooks.json", &f)
+	if err != nil {
+		return nil, fmt.Errorf("open runbook store: %w", err)
+	}
+	s := &Store{
+		file:      file,
+		templates: make(map[string]*Template),
+		runbooks:  make(map[string]*Runbook),
+	}
+	for _, t := range f.Templates {
+		s.templates[t.ID] = t
+	}
+	for _, rb := range f.Runbooks {
+		s.runbooks[rb.ID] = rb
+	}
+	return s, nil
+}
+
+// ListTemplates 列出所有模板（按名称排序）
+func (s *Store) ListTemplates() []*Template {
+	s.file.RLock()
+	defer s.file.RUnlock()
+	list := make([]*Template, 0, len(s.templates))
+	for _, t := range s.templates {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// GetTemplate 获取模板
+func (s *Store) GetTemplate(id string) (*Template, error) {
+	s.file.RLock()
+	defer s.file.RUnlock()
+	t, ok := s.templates[id]
+	if !ok {
+		return nil, ErrTemplateNotFound
+	}
+	return t, nil
+}
+
+// SaveTemplate 创建或更新模板（ID 为空时创建）
+func (s *Store) SaveTemplate(t *Template) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+
+	s.file.Lock()
+	defer s.file.Unlock()
+
+	now := time.Now()
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+		t.CreatedAt = now
+	} else if old, ok := s.templates[t.ID]; ok {
+		t.CreatedAt = old.CreatedAt
+	} else {
+		return ErrTemplateNotFound
+	}
+	t.UpdatedAt = now
+	s.templates[t.ID] = t
+	return s.persist()
+}
+
+// DeleteTemplate 删除模板（被 Runbook 引用时拒绝）
+func (s *Store) DeleteTemplate(id string) error {
+	s.file.Lock()
+	defer s.file.Unlock()
+	if _, ok := s.templates[id]; !ok {
+		return ErrTemplateNotFound
+	}
+	for _, rb := range s.runbooks {
+		for _, step := range rb.Steps {
+			if step.TemplateID == id {
+				return fmt.Errorf("%w: %s", ErrTemplateInUse, rb.Name)
+			}
+		}
+	}
+	delete(s.templates, id)
+	return s.persist()
+}
+
+// ListRunbooks 列出所有 Runbook（按名称排序）
+func (s *Store) ListRunbooks() []*Runbook {
+	s.file.RLock()
+	defer s.file.RUnlock()
+	list := make([]*Runbook, 0, len(s.runbooks))
+	for _, rb := range s.runbooks {
+		list = append(list, rb)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// GetRunbook 获取 Runbook
+func (s *Store) GetRunbook(id string) (*Runbook, error) {
+	s.file.RLock()
+	defer s.file.RUnlock()
+	rb, ok := s.runbooks[id]
+	if !ok {
+		return nil, ErrRunbookNotFound
+	}
+	return rb, nil
+}
+
+// SaveRunbook 创建或更新 Runbook（ID 为空时创建），步骤引用的模板必须存在
+func (s *Store) SaveRunbook(rb *Runbook) error {
+	if rb.Name == "" {
+		return fmt.Errorf("runbook name is required")
+	}
+	if len(rb.Steps) == 0 {
+		return fmt.Errorf("runbook requires at least one step")
+	}
+
+	s.file.Lock()
+	defer s.file.Unlock()
+
+	for i := range rb.Steps {
+		step := &rb.Steps[i]
+		if _, ok := s.templates[step.TemplateID]; !ok {
+			return fmt.Errorf("step %d: %w: %s", i+1, ErrTemplateNotFound, step.TemplateID)
+		}
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("step-%d", i+1)
+		}
+		switch step.OnFailure {
+		case "":
+			step.OnFailure = FailurePolicyAbort
+		case FailurePolicyAbort, FailurePolicyContinue, FailurePolicyExclude:
+		default:
+			return fmt.Errorf("step %s: invalid on_failure: %s", step.Name, step.OnFailure)
+		}
+		if step.Retries < 0 || step.Retries > 5 {
+			return fmt.Errorf("step %s: retries must be between 0 and 5", step.Name)
+		}
+	}
+
+	now := time.Now()
+	if rb.ID == "" {
+		rb.ID = uuid.New().String()
+		rb.CreatedAt = now
+	} else if old, ok := s.runbooks[rb.ID]; ok {
+		rb.CreatedAt = old.CreatedAt
+	} else {
+		return ErrRunbookNotFound
+	}
+	rb.UpdatedAt = now
+	s.runbooks[rb.ID] = rb
+	return s.persist()
+}
+
+// DeleteRunbook 删除 Runbook
+func (s *Store) DeleteRunbook(id string) error {
+	s.file.Lock()
+	defer s.file.Unlock()
+	if _, ok := s.runbooks[id]; !ok {
+		return ErrRunbookNotFound
+	}
+	delete(s.runbooks, id)
+	return s.persist()
+}
+
+// persist 写入存储文件（调用方持有锁）
+func (s *Store) persist() error {
+	f := storeFile{
+		Templates: make([]*Template, 0, len(s.templates)),
+		Runbooks:  make([]*Runbook, 0, len(s.runbooks)),
+	}
+	for _, t := range s.templates {
+		f.Templates = append(f.Templates, t)
+	}
+	for _, rb := range s.runbooks {
+		f.Runbooks = append(f.Runbooks, rb)
+	}
+	return s.file.Save(f)
+}
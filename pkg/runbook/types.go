@@ -0,0 +1,113 @@
+package runbook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/command"
+)
+
+// ParamType 模板参数类型
+type ParamType string
+
+const (
+	ParamTypeString ParamType = "string"
+	ParamTypeInt    ParamType = "int"
+	ParamTypeBool   ParamType = "bool"
+	ParamTypeEnum   ParamType = "enum"
+)
+
+// FailurePolicy 步骤失败策略
+type FailurePolicy string
+
+const (
+	FailurePolicyAbort    FailurePolicy = "abort"    // 任一客户端失败即终止 Runbook（默认）
+	FailurePolicyContinue FailurePolicy = "continue" // 忽略失败，所有客户端继续执行后续步骤
+	FailurePolicyExclude  FailurePolicy = "exclude"  // 失败的客户端不再执行后续步骤
+)
+
+// RunStatus 执行状态
+type RunStatus string
+
+const (
+	RunStatusPending   RunStatus = "pending"
+	RunStatusRunning   RunStatus = "running"
+	RunStatusSuccess   RunStatus = "success"
+	RunStatusFailed    RunStatus = "failed"
+	RunStatusCancelled RunStatus = "cancelled"
+	RunStatusSkipped   RunStatus = "skipped"
+)
+
+// Param 模板参数定义
+type Param struct {
+	Name        string    `json:"name"`                  // 变量名（payload 中以 ${NAME} 引用）
+	Type        ParamType `json:"type"`                  // 参数类型，默认 string
+	Description string    `json:"description,omitempty"` // 说明
+	Required    bool      `json:"required,omitempty"`    // 是否必填（无默认值时）
+	Default     string    `json:"default,omitempty"`     // 默认值
+	Options     []string  `json:"options,omitempty"`     // 可选值（enum）
+}
+
+// Template 命令模板
+type Template struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	CommandType string          `json:"command_type"`      // 命令类型，如 exec_shell
+	Payload     json.RawMessage `json:"payload"`           // 参数化的命令参数，支持 ${VAR}
+	Params      []Param         `json:"params,omitempty"`  // 参数定义
+	Timeout     int             `json:"timeout,omitempty"` // 命令超时（秒），默认 30
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// Step Runbook 步骤
+type Step struct {
+	Name       string            `json:"name"`
+	TemplateID string            `json:"template_id"`
+	Params     map[string]string `json:"params,omitempty"`     // 步骤级参数（可引用 Runbook 运行参数 ${VAR}）
+	OnFailure  FailurePolicy     `json:"on_failure,omitempty"` // 失败策略，默认 abort
+	Retries    int               `json:"retries,omitempty"`    // 失败客户端的重试次数
+	Timeout    int               `json:"timeout,omitempty"`    // 覆盖模板超时（秒）
+}
+
+// Runbook 按顺序执行的模板序列
+type Runbook struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Steps       []Step    `json:"steps"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// StepRun 单个步骤的执行记录
+type StepRun struct {
+	Name         string                         `json:"name"`
+	TemplateID   string                         `json:"template_id"`
+	CommandType  string                         `json:"command_type"`
+	Status       RunStatus                      `json:"status"`
+	Attempts     int                            `json:"attempts"`
+	TaskIDs      []string                       `json:"task_ids,omitempty"` // 多播任务 ID（每次尝试一个）
+	ClientIDs    []string                       `json:"client_ids"`         // 本步骤的目标客户端
+	SuccessCount int                            `json:"success_count"`
+	FailedCount  int                            `json:"failed_count"`
+	Results      []*command.ClientCommandResult `json:"results,omitempty"` // 各客户端最后一次的结果
+	Error        string                         `json:"error,omitempty"`
+	StartedAt    *time.Time                     `json:"started_at,omitempty"`
+	FinishedAt   *time.Time                     `json:"finished_at,omitempty"`
+}
+
+// Run Runbook（或单个模板）的一次执行
+type Run struct {
+	ID          string            `json:"id"`
+	RunbookID   string            `json:"runbook_id,omitempty"`
+	RunbookName string            `json:"runbook_name"`
+	ClientIDs   []string          `json:"client_ids"`
+	Params      map[string]string `json:"params,omitempty"`
+	Status      RunStatus         `json:"status"`
+	Steps       []*StepRun        `json:"steps"`
+	Error       string            `json:"error,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	FinishedAt  *time.Time        `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 服务管理器类型
+const (
+	ManagerSystemd = "systemd"
+	ManagerSysV    = "sysv"
+)
+
+// 统一状态（与 command.ServiceResult.Status 对齐）
+const (
+	StatusRunning = "running"
+	StatusStopped = "stopped"
+	StatusUnknown = "unknown"
+)
+
+// 默认值
+const (
+	DefaultJournalLines = 20
+	MaxJournalLines     = 500
+	commandTimeout      = 60 * time.Second
+	maxOutputSize       = 64 * 1024
+)
+
+// serviceNamePattern 合法的服务名（防止参数注入，如 "--root=/"）
+var serviceNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9@._:\\-]*$`)
+
+// Status 服务状态详情
+type Status struct {
+	Name          string
+	Description   string
+	LoadState     string
+	ActiveState   string
+	SubState      string
+	UnitFileState string
+	MainPID       int
+	MemoryBytes   int64
+	RestartCount  int
+	ActiveSince   string
+	Status        string // running/stopped/unknown
+	Journal       []string
+}
+
+// Unit 服务列表项
+type Unit struct {
+	Name        string
+	Description string
+	LoadState   string
+	ActiveState string
+	SubState    string
+	Status      string
+}
+
+// ListOptions 服务列表过滤条件
+type ListOptions struct {
+	Filter string // 名称过滤（支持 * 通配符，不含通配符时按子串匹配）
+	State  string // 活动状态过滤
+	All    bool   // 是否包含非活动单元
+}
+
+// Manager 服务管理器
+// 优先使用 systemd（systemctl/journalctl），不可用时回退到 SysV init 脚本
+type Manager struct {
+	kind string
+}
+
+// NewManager 创建服务管理器，自动探测 init 系统
+func NewManager() *Manager {
+	return &Manager{kind: detectManager()}
+}
+
+// Kind 返回当前使用的服务管理器类型
+func (m *Manager) Kind() string {
+	return m.kind
+}
+
+// ValidateName 校验服务名
+func ValidateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("service name is required")
+	}
+	if len(name) > 256 || !serviceNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid service name: %q", name)
+	}
+	return nil
+}
+
+// Status 获取服务状态
+// journalLines: 返回最近日志行数（<0 表示不返回）
+func (m *Manager) Status(ctx context.Context, name string, journalLines int) (*Status, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	if m.kind == ManagerSystemd {
+		return systemdStatus(ctx, name, journalLines)
+	}
+	return sysvStatus(ctx, name)
+}
+
+// Start 启动服务
+func (m *Manager) Start(ctx context.Context, name string) error {
+	return m.control(ctx, name, "start")
+}
+
+// Stop 停止服务
+func (m *Manager) Stop(ctx context.Context, name string) error {
+	return m.control(ctx, name, "stop")
+}
+
+// Restart 重启服务
+func (m *Manager) Restart(ctx context.Context, name string) error {
+	return m.control(ctx, name, "restart")
+}
+
+// Enable 设置开机自启
+func (m *Manager) Enable(ctx context.Context, name string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if m.kind == ManagerSystemd {
+		_, err := run(ctx, "systemctl", "enable", name)
+		return err
+	}
+	return sysvSetEnabled(ctx, name, true)
+}
+
+// Disable 取消开机自启
+func (m *Manager) Disable(ctx context.Context, name string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if m.kind == ManagerSystemd {
+		_, err := run(ctx, "systemctl", "disable", name)
+		return err
+	}
+	return sysvSetEnabled(ctx, name, false)
+}
+
+// List 列出服务
+func (m *Manager) List(ctx context.Context, opts ListOptions) ([]Unit, error) {
+	var units []Unit
+	var err error
+	if m.kind == ManagerSystemd {
+		units, err = systemdList(ctx, opts.All)
+	} else {
+		units, err = sysvList(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Unit, 0, len(units))
+	for _, u := range units {
+		if !matchFilter(u.Name, opts.Filter) {
+			continue
+		}
+		if opts.State != "" && opts.State != u.ActiveState && opts.State != u.SubState && opts.State != u.Status {
+			continue
+		}
+		result = append(result, u)
+	}
+	return result, nil
+}
+
+// control 执行 start/stop/restart
+func (m *Manager) control(ctx context.Context, name, action string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if m.kind == ManagerSystemd {
+		_, err := run(ctx, "systemctl", action, name)
+		return err
+	}
+	return sysvControl(ctx, name, action)
+}
+
+// detectManager 探测 init 系统
+func detectManager() string {
+	// 与 sd_booted() 相同的判断方式
+	if fi, err := os.Stat("/run/systemd/system"); err == nil && fi.IsDir() {
+		if _, err := exec.LookPath("systemctl"); err == nil {
+			return ManagerSystemd
+		}
+	}
+	return ManagerSysV
+}
+
+// matchFilter 名称过滤
+func matchFilter(name, filter string) bool {
+	if filter == "" {
+		return true
+	}
+	if strings.ContainsAny(filter, "*?[") {
+		if ok, _ := path.Match(filter, name); ok {
+			return true
+		}
+		// 允许省略 .service 后缀
+		ok, _ := path.Match(filter, strings.TrimSuffix(name, ".service"))
+		return ok
+	}
+	return strings.Contains(name, filter)
+}
+
+// run 执行外部命令，失败时返回包含输出的错误
+func run(ctx context.Context, name string, args ...string) (string, error) {
+	execCtx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(execCtx, name, args...)
+	cmd.Env = append(os.Environ(), "LANG=C", "LC_ALL=C", "SYSTEMD_PAGER=", "SYSTEMD_COLORS=0")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	out := stdout.String()
+	if len(out) > maxOutputSize {
+		out = out[:maxOutputSize]
+	}
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(out)
+		}
+		if execCtx.Err() == context.DeadlineExceeded {
+			return out, fmt.Errorf("%s %s: timeout after %s", name, strings.Join(args, " "), commandTimeout)
+		}
+		if msg != "" {
+			return out, fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, msg)
+		}
+		return out, fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	return out, nil
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// systemdProperties systemctl show 查询的属性
+var systemdProperties = []string{
+	"Id",
+	"Description",
+	"LoadState",
+	"ActiveState",
+	"SubState",
+	"UnitFileState",
+	"MainPID",
+	"MemoryCurrent",
+	"NRestarts",
+	"ActiveEnterTimestamp",
+}
+
+// systemdStatus 通过 systemctl show 获取服务状态
+func systemdStatus(ctx context.Context, name string, journalLines int) (*Status, error) {
+	out, err := run(ctx, "systemctl", "show", name, "--no-pager",
+		"--property="+strings.Join(systemdProperties, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	st := parseShowOutput(out)
+	if st.Name == "" {
+		st.Name = name
+	}
+	if st.LoadState == "not-found" {
+		return st, fmt.Errorf("service %s not found", name)
+	}
+
+	if journalLines == 0 {
+		journalLines = DefaultJournalLines
+	}
+	if journalLines > MaxJournalLines {
+		journalLines = MaxJournalLines
+	}
+	if journalLines > 0 {
+		// journal 读取失败（如无权限）不影响状态结果
+		st.Journal, _ = systemdJournal(ctx, st.Name, journalLines)
+	}
+	return st, nil
+}
+
+// systemdJournal 读取服务最近的日志
+func systemdJournal(ctx context.Context, name string, lines int) ([]string, error) {
+	out, err := run(ctx, "journalctl", "-u", name, "-n", strconv.Itoa(lines),
+		"--no-pager", "--no-hostname", "-o", "short-iso")
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(out), nil
+}
+
+// systemdList 通过 systemctl list-units 列出服务
+func systemdList(ctx context.Context, all bool) ([]Unit, error) {
+	args := []string{"list-units", "--type=service", "--no-legend", "--no-pager", "--plain"}
+	if all {
+		args = append(args, "--all")
+	}
+	out, err := run(ctx, "systemctl", args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseListUnits(out), nil
+}
+
+// parseShowOutput 解析 systemctl show 的 KEY=VALUE 输出
+func parseShowOutput(out string) *Status {
+	st := &Status{}
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Id":
+			st.Name = value
+		case "Description":
+			st.Description = value
+		case "LoadState":
+			st.LoadState = value
+		case "ActiveState":
+			st.ActiveState = value
+		case "SubState":
+			st.SubState = value
+		case "UnitFileState":
+			st.UnitFileState = value
+		case "MainPID":
+			st.MainPID, _ = strconv.Atoi(value)
+		case "MemoryCurrent":
+			// 未启用内存统计时为 "[not set]" 或 2^64-1
+			if v, err := strconv.ParseUint(value, 10, 64); err == nil && v < 1<<63 {
+				st.MemoryBytes = int64(v)
+			}
+		case "NRestarts":
+			st.RestartCount, _ = strconv.Atoi(value)
+		case "ActiveEnterTimestamp":
+			st.ActiveSince = value
+		}
+	}
+	st.Status = systemdToStatus(st.ActiveState, st.SubState)
+	return st
+}
+
+// parseListUnits 解析 systemctl list-units --plain --no-legend 的输出
+// 格式: UNIT LOAD ACTIVE SUB DESCRIPTION...
+func parseListUnits(out string) []Unit {
+	var units []Unit
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		// 部分版本即使 --plain 仍会输出 "●" 标记失败的单元
+		line := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "●"))
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		u := Unit{
+			Name:        fields[0],
+			LoadState:   fields[1],
+			ActiveState: fields[2],
+			SubState:    fields[3],
+		}
+		if len(fields) > 4 {
+			u.Description = strings.Join(fields[4:], " ")
+		}
+		u.Status = systemdToStatus(u.ActiveState, u.SubState)
+		units = append(units, u)
+	}
+	return units
+}
+
+// systemdToStatus 将 systemd 状态映射为统一状态
+func systemdToStatus(activeState, subState string) string {
+	switch activeState {
+	case "active", "reloading", "activating", "deactivating":
+		return StatusRunning
+	case "inactive", "failed":
+		return StatusStopped
+	}
+	if subState == "running" {
+		return StatusRunning
+	}
+	return StatusUnknown
+}
+
+// splitLines 按行切分并去掉空行
+func splitLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
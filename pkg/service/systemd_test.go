@@ -0,0 +1,115 @@
+package service
+
+import (
+	"testing"
+)
+
+func TestParseShowOutput(t *testing.T) {
+	out := `Id=nginx.service
+Description=A high performance web server
+LoadState=loaded
+ActiveState=active
+SubState=running
+UnitFileState=enabled
+MainPID=1234
+MemoryCurrent=10485760
+NRestarts=2
+ActiveEnterTimestamp=Mon 2024-01-01 10:00:00 UTC
+`
+	st := parseShowOutput(out)
+
+	if st.Name != "nginx.service" {
+		t.Errorf("Name = %v, want nginx.service", st.Name)
+	}
+	if st.Status != StatusRunning {
+		t.Errorf("Status = %v, want %v", st.Status, StatusRunning)
+	}
+	if st.MainPID != 1234 {
+		t.Errorf("MainPID = %v, want 1234", st.MainPID)
+	}
+	if st.MemoryBytes != 10485760 {
+		t.Errorf("MemoryBytes = %v, want 10485760", st.MemoryBytes)
+	}
+	if st.RestartCount != 2 {
+		t.Errorf("RestartCount = %v, want 2", st.RestartCount)
+	}
+	if st.UnitFileState != "enabled" {
+		t.Errorf("UnitFileState = %v, want enabled", st.UnitFileState)
+	}
+}
+
+func TestParseShowOutput_MemoryNotSet(t *testing.T) {
+	tests := []string{"[not set]", "18446744073709551615", ""}
+	for _, v := range tests {
+		st := parseShowOutput("ActiveState=inactive\nSubState=dead\nMemoryCurrent=" + v + "\n")
+		if st.MemoryBytes != 0 {
+			t.Errorf("MemoryCurrent=%q: MemoryBytes = %v, want 0", v, st.MemoryBytes)
+		}
+		if st.Status != StatusStopped {
+			t.Errorf("Status = %v, want %v", st.Status, StatusStopped)
+		}
+	}
+}
+
+func TestParseListUnits(t *testing.T) {
+	out := `cron.service        loaded active   running Regular background program processing daemon
+● nginx.service     loaded failed   failed  A high performance web server
+ssh.service         loaded inactive dead    OpenBSD Secure Shell server
+`
+	units := parseListUnits(out)
+	if len(units) != 3 {
+		t.Fatalf("len(units) = %v, want 3", len(units))
+	}
+
+	if units[0].Name != "cron.service" || units[0].Status != StatusRunning {
+		t.Errorf("units[0] = %+v", units[0])
+	}
+	if units[0].Description != "Regular background program processing daemon" {
+		t.Errorf("units[0].Description = %q", units[0].Description)
+	}
+	if units[1].Name != "nginx.service" || units[1].ActiveState != "failed" || units[1].Status != StatusStopped {
+		t.Errorf("units[1] = %+v", units[1])
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"nginx", false},
+		{"nginx.service", false},
+		{"getty@tty1.service", false},
+		{"", true},
+		{"--root=/", true},
+		{"nginx; rm -rf /", true},
+		{"../etc/passwd", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateName(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateName(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMatchFilter(t *testing.T) {
+	tests := []struct {
+		name, filter string
+		want         bool
+	}{
+		{"nginx.service", "", true},
+		{"nginx.service", "ngin", true},
+		{"nginx.service", "nginx*", true},
+		{"nginx.service", "ngin?", true},
+		{"sshd.service", "nginx*", false},
+		{"sshd.service", "http", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchFilter(tt.name, tt.filter); got != tt.want {
+			t.Errorf("matchFilter(%q, %q) = %v, want %v", tt.name, tt.filter, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// sysvInitDir SysV init 脚本目录
+const sysvInitDir = "/etc/init.d"
+
+// sysvStatus 通过 init 脚本的 status 动作获取服务状态
+// 按 LSB 约定：退出码 0 表示运行中，1-3 表示已停止，4 表示未知
+func sysvStatus(ctx context.Context, name string) (*Status, error) {
+	script, err := sysvScript(name)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &Status{
+		Name:      name,
+		LoadState: "loaded",
+	}
+
+	// SysV 没有 journal，以 status 动作的输出代替
+	out, err := run(ctx, script, "status")
+	st.Journal = splitLines(out)
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		st.ActiveState = "active"
+		st.SubState = "running"
+		st.Status = StatusRunning
+	case errors.As(err, &exitErr) && exitErr.ExitCode() >= 1 && exitErr.ExitCode() <= 3:
+		st.ActiveState = "inactive"
+		st.SubState = "dead"
+		st.Status = StatusStopped
+	default:
+		st.ActiveState = "unknown"
+		st.Status = StatusUnknown
+	}
+
+	if sysvEnabled(name) {
+		st.UnitFileState = "enabled"
+	} else {
+		st.UnitFileState = "disabled"
+	}
+	return st, nil
+}
+
+// sysvControl 执行 start/stop/restart
+func sysvControl(ctx context.Context, name, action string) error {
+	script, err := sysvScript(name)
+	if err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("service"); err == nil {
+		_, err = run(ctx, "service", name, action)
+		return err
+	}
+	_, err = run(ctx, script, action)
+	return err
+}
+
+// sysvSetEnabled 通过 update-rc.d（Debian 系）或 chkconfig（RHEL 系）设置开机自启
+func sysvSetEnabled(ctx context.Context, name string, enabled bool) error {
+	if _, err := sysvScript(name); err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("update-rc.d"); err == nil {
+		action := "disable"
+		if enabled {
+			action = "enable"
+		}
+		// 未安装过链接时 enable 会失败，先补装默认链接
+		if enabled && !sysvHasLinks(name) {
+			if _, err := run(ctx, "update-rc.d", name, "defaults"); err != nil {
+				return err
+			}
+		}
+		_, err = run(ctx, "update-rc.d", name, action)
+		return err
+	}
+	if _, err := exec.LookPath("chkconfig"); err == nil {
+		action := "off"
+		if enabled {
+			action = "on"
+		}
+		_, err = run(ctx, "chkconfig", name, action)
+		return err
+	}
+	return fmt.Errorf("neither update-rc.d nor chkconfig is available")
+}
+
+// sysvList 列出 /etc/init.d 下的服务
+func sysvList(ctx context.Context) ([]Unit, error) {
+	entries, err := os.ReadDir(sysvInitDir)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", sysvInitDir, err)
+	}
+
+	var units []Unit
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || name == "README" || name == "functions" || name == "skeleton" {
+			continue
+		}
+		if ValidateName(name) != nil {
+			continue
+		}
+		st, err := sysvStatus(ctx, name)
+		if err != nil {
+			continue
+		}
+		units = append(units, Unit{
+			Name:        st.Name,
+			LoadState:   st.LoadState,
+			ActiveState: st.ActiveState,
+			SubState:    st.SubState,
+			Status:      st.Status,
+		})
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i].Name < units[j].Name })
+	return units, nil
+}
+
+// sysvScript 返回服务对应的 init 脚本路径
+func sysvScript(name string) (string, error) {
+	script := filepath.Join(sysvInitDir, name)
+	fi, err := os.Stat(script)
+	if err != nil || fi.IsDir() || fi.Mode()&0111 == 0 {
+		return "", fmt.Errorf("service %s not found", name)
+	}
+	return script, nil
+}
+
+// sysvEnabled 检查当前默认运行级别下是否存在启动链接
+func sysvEnabled(name string) bool {
+	for _, level := range []string{"2", "3", "5"} {
+		matches, _ := filepath.Glob(filepath.Join("/etc", "rc"+level+".d", "S[0-9][0-9]"+name))
+		if len(matches) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// sysvHasLinks 检查是否存在任何运行级别的链接
+func sysvHasLinks(name string) bool {
+	matches, _ := filepath.Glob(filepath.Join("/etc", "rc?.d", "[SK][0-9][0-9]"+name))
+	return len(matches) > 0
+}
@@ -0,0 +1,211 @@
+package spool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/protocol"
+)
+
+// 重放参数
+const (
+	replayAckTimeout = 10 * time.Second
+	retryInterval    = 5 * time.Second
+)
+
+// Sender 向服务器发送消息（*client.Client 实现）
+type Sender interface {
+	IsConnected() bool
+	SendMessage(ctx context.Context, msg *protocol.DataMessage, waitAck bool, timeout time.Duration) (*protocol.AckMessage, error)
+}
+
+// SpoolableFunc 判断消息是否可以离线缓存
+type SpoolableFunc func(msg *protocol.DataMessage) bool
+
+// DefaultSpoolable 默认的可缓存消息：EVENT 消息中的上报（report.*）、命令回调、主题事件发布与任务结果
+// 订阅声明、任务进度等与连接状态相关或时效性强的消息不缓存
+func DefaultSpoolable(msg *protocol.DataMessage) bool {
+	if msg.Type != protocol.MessageType_MESSAGE_TYPE_EVENT {
+		return false
+	}
+	var env struct {
+		CommandType string `json:"command_type"`
+	}
+	if err := json.Unmarshal(msg.Payload, &env); err != nil {
+		return false
+	}
+	return strings.HasPrefix(env.CommandType, "report.") ||
+		env.CommandType == "command.callback" ||
+		env.CommandType == "event.publish" ||
+		env.CommandType == "task.result"
+}
+
+// OutboxStats 离线缓存状态
+type OutboxStats struct {
+	Stats
+	Replayed int64 `json:"replayed"` // 累计重放成功的消息数
+}
+
+// Outbox 带离线缓存的消息发送器
+// 不等待确认的可缓存消息在链路断开或发送失败时写入队列；队列非空时新消息也进入队尾以保持顺序。
+// 重连后按顺序重放（等待服务器确认后删除），服务器按 msg_id 去重
+type Outbox struct {
+	sender    Sender
+	spool     *Spool
+	spoolable SpoolableFunc
+	logger    *monitoring.Logger
+
+	flushMu  sync.Mutex
+	replayed atomic.Int64
+
+	trigger chan struct{}
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewOutbox 创建发送器，spoolable 为 nil 时使用 DefaultSpoolable
+func NewOutbox(sender Sender, spool *Spool, spoolable SpoolableFunc, logger *monitoring.Logger) *Outbox {
+	if spoolable == nil {
+		spoolable = DefaultSpoolable
+	}
+	if logger == nil {
+		logger = monitoring.NewDefaultLogger()
+	}
+	return &Outbox{
+		sender:    sender,
+		spool:     spool,
+		spoolable: spoolable,
+		logger:    logger,
+		trigger:   make(chan struct{}, 1),
+		stopCh:    make(chan struct{}),
+	}
+}
+
+// SendMessage 发送消息（签名与 Client.SendMessage 一致，可直接替换）
+// 需要等待确认或不可缓存的消息直接发送；可缓存的消息发送失败时写入队列并返回成功
+func (o *Outbox) SendMessage(ctx context.Context, msg *protocol.DataMessage, waitAck bool, timeout time.Duration) (*protocol.AckMessage, error) {
+	if waitAck || !o.spoolable(msg) {
+		return o.sender.SendMessage(ctx, msg, waitAck, timeout)
+	}
+	return nil, o.Send(ctx, msg)
+}
+
+// Send 发送可缓存的消息（不等待确认），链路不可用时写入队列
+func (o *Outbox) Send(ctx context.Context, msg *protocol.DataMessage) error {
+	// 重放时依赖 msg_id 去重，入队前必须确定
+	if msg.MsgId == "" {
+		msg.MsgId = uuid.New().String()
+	}
+	if msg.Timestamp == 0 {
+		msg.Timestamp = time.Now().UnixMilli()
+	}
+
+	if o.spool.Depth() == 0 && o.sender.IsConnected() {
+		_, err := o.sender.SendMessage(ctx, msg, false, 0)
+		if err == nil {
+			return nil
+		}
+		o.logger.Debug("Send failed, spooling message", "msg_id", msg.MsgId, "error", err)
+	}
+
+	if err := o.spool.Enqueue(msg); err != nil {
+		return err
+	}
+	if o.sender.IsConnected() {
+		o.Trigger()
+	}
+	return nil
+}
+
+// Trigger 触发一次后台重放（连接或重连后调用）
+func (o *Outbox) Trigger() {
+	select {
+	case o.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Flush 按顺序重放队列中的消息，遇到发送失败时停止并返回错误
+// 收到服务器确认（无论处理成功与否）即从队列删除；同一时间只有一个重放在进行
+func (o *Outbox) Flush(ctx context.Context) (int, error) {
+	if !o.flushMu.TryLock() {
+		return 0, nil
+	}
+	defer o.flushMu.Unlock()
+
+	n := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		seq, msg := o.spool.Peek()
+		if msg == nil {
+			return n, nil
+		}
+		ack, err := o.sender.SendMessage(ctx, msg, true, replayAckTimeout)
+		if err != nil {
+			return n, err
+		}
+		if ack != nil && ack.Status != protocol.AckStatus_ACK_STATUS_SUCCESS {
+			o.logger.Warn("Spooled message rejected by server", "msg_id", msg.MsgId, "error", ack.Error)
+		}
+		o.spool.Remove(seq)
+		o.replayed.Add(1)
+		n++
+	}
+}
+
+// Start 启动后台重放：收到触发或定期检查时，若已连接且队列非空则重放
+func (o *Outbox) Start() {
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+		ticker := time.NewTicker(retryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-o.trigger:
+			case <-ticker.C:
+			case <-o.stopCh:
+				return
+			}
+			if !o.sender.IsConnected() || o.spool.Depth() == 0 {
+				continue
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				select {
+				case <-o.stopCh:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+			n, err := o.Flush(ctx)
+			cancel()
+			if n > 0 {
+				o.logger.Info("Spooled messages replayed", "count", n, "remaining", o.spool.Depth())
+			}
+			if err != nil && !errors.Is(err, context.Canceled) {
+				o.logger.Warn("Spool replay interrupted", "error", err, "remaining", o.spool.Depth())
+			}
+		}
+	}()
+}
+
+// Stop 停止后台重放，未发送的消息保留在磁盘上，下次启动后继续重放
+func (o *Outbox) Stop() {
+	close(o.stopCh)
+	o.wg.Wait()
+}
+
+// Stats 返回离线缓存状态
+func (o *Outbox) Stats() OutboxStats {
+	return OutboxStats{Stats: o.spool.Stats(), Replayed: o.replayed.Load()}
+}
@@ -0,0 +1,330 @@
+// Package spool 提供 Agent 离线缓存：链路断开时，可缓存的出站消息（事件、上报）
+// 写入有界的磁盘队列，重连后按顺序重放，服务器按 msg_id 去重
+package spool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/protocol"
+)
+
+// DropPolicy 队列满时的丢弃策略
+type DropPolicy string
+
+const (
+	DropOldest DropPolicy = "oldest" // 丢弃最早的消息，为新消息腾出空间
+	DropNewest DropPolicy = "newest" // 拒绝新消息
+)
+
+// 默认值
+const (
+	DefaultMaxBytes    = 64 * 1024 * 1024
+	DefaultMaxMessages = 10000
+	DefaultMaxAge      = 24 * time.Hour
+
+	fileSuffix = ".msg"
+)
+
+var (
+	// ErrSpoolFull 表示队列已满且丢弃策略为 newest
+	ErrSpoolFull = errors.New("spool is full")
+
+	// ErrMessageTooLarge 表示单条消息超过队列容量
+	ErrMessageTooLarge = errors.New("message exceeds spool capacity")
+)
+
+// Config 离线缓存配置
+type Config struct {
+	Dir         string        // 缓存目录
+	MaxBytes    int64         // 最大占用字节数（默认 64MB）
+	MaxMessages int           // 最大消息数（默认 10000）
+	MaxAge      time.Duration // 消息最长保留时间，超时丢弃（默认 24h）
+	DropPolicy  DropPolicy    // 队列满时的丢弃策略（默认 oldest）
+}
+
+// Stats 队列状态
+type Stats struct {
+	Depth        int    `json:"depth"`          // 当前缓存的消息数
+	Bytes        int64  `json:"bytes"`          // 当前占用字节数
+	OldestAgeSec int64  `json:"oldest_age_sec"` // 最早消息的缓存时长
+	Dropped      int64  `json:"dropped"`        // 因队列满丢弃的消息数
+	Expired      int64  `json:"expired"`        // 因超时丢弃的消息数
+	MaxBytes     int64  `json:"max_bytes"`
+	MaxMessages  int    `json:"max_messages"`
+	DropPolicy   string `json:"drop_policy"`
+}
+
+// record 磁盘上的消息记录
+type record struct {
+	MsgID     string `json:"msg_id"`
+	Type      int32  `json:"type"`
+	Payload   []byte `json:"payload"`
+	Timestamp int64  `json:"timestamp"` // 消息产生时间（毫秒）
+}
+
+// entry 队列中的消息索引
+type entry struct {
+	seq       uint64
+	size      int64
+	spooledAt time.Time
+}
+
+// Spool 有界磁盘队列，每条消息一个文件，文件名为递增序号
+type Spool struct {
+	cfg    Config
+	logger *monitoring.Logger
+
+	mu      sync.Mutex
+	entries []entry
+	bytes   int64
+	nextSeq uint64
+	dropped int64
+	expired int64
+}
+
+// Open 打开（或创建）缓存目录并加载已有消息
+func Open(cfg Config, logger *monitoring.Logger) (*Spool, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("spool: dir is required")
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultMaxBytes
+	}
+	if cfg.MaxMessages <= 0 {
+		cfg.MaxMessages = DefaultMaxMessages
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = DefaultMaxAge
+	}
+	switch cfg.DropPolicy {
+	case "":
+		cfg.DropPolicy = DropOldest
+	case DropOldest, DropNewest:
+	default:
+		return nil, fmt.Errorf("spool: invalid drop policy %q (oldest|newest)", cfg.DropPolicy)
+	}
+	if logger == nil {
+		logger = monitoring.NewDefaultLogger()
+	}
+	if err := os.MkdirAll(cfg.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("spool: create dir: %w", err)
+	}
+
+	s := &Spool{cfg: cfg, logger: logger, nextSeq: 1}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if len(s.entries) > 0 {
+		logger.Info("Spool loaded", "dir", cfg.Dir, "depth", len(s.entries), "bytes", s.bytes)
+	}
+	return s, nil
+}
+
+// load 扫描缓存目录，清理写入中断留下的临时文件
+func (s *Spool) load() error {
+	files, err := os.ReadDir(s.cfg.Dir)
+	if err != nil {
+		return fmt.Errorf("spool: read dir: %w", err)
+	}
+	for _, f := range files {
+		name := f.Name()
+		if strings.HasSuffix(name, ".tmp") {
+			os.Remove(filepath.Join(s.cfg.Dir, name))
+			continue
+		}
+		if f.IsDir() || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, fileSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		s.entries = append(s.entries, entry{seq: seq, size: info.Size(), spooledAt: info.ModTime()})
+		s.bytes += info.Size()
+	}
+	sort.Slice(s.entries, func(i, j int) bool { return s.entries[i].seq < s.entries[j].seq })
+	if n := len(s.entries); n > 0 {
+		s.nextSeq = s.entries[n-1].seq + 1
+	}
+	return nil
+}
+
+// path 返回消息文件路径
+func (s *Spool) path(seq uint64) string {
+	return filepath.Join(s.cfg.Dir, fmt.Sprintf("%020d%s", seq, fileSuffix))
+}
+
+// Enqueue 将消息追加到队尾
+// 超出容量时按丢弃策略处理：oldest 丢弃最早的消息，newest 返回 ErrSpoolFull
+func (s *Spool) Enqueue(msg *protocol.DataMessage) error {
+	data, err := json.Marshal(record{
+		MsgID:     msg.MsgId,
+		Type:      int32(msg.Type),
+		Payload:   msg.Payload,
+		Timestamp: msg.Timestamp,
+	})
+	if err != nil {
+		return fmt.Errorf("spool: encode message: %w", err)
+	}
+	size := int64(len(data))
+	if size > s.cfg.MaxBytes {
+		return ErrMessageTooLarge
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.expireLocked(now)
+	for len(s.entries) >= s.cfg.MaxMessages || s.bytes+size > s.cfg.MaxBytes {
+		s.dropped++
+		if s.cfg.DropPolicy == DropNewest {
+			return ErrSpoolFull
+		}
+		s.removeLocked(0)
+	}
+
+	seq := s.nextSeq
+	tmp := s.path(seq) + ".tmp"
+	if err := writeFile(tmp, data); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("spool: write message: %w", err)
+	}
+	if err := os.Rename(tmp, s.path(seq)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("spool: write message: %w", err)
+	}
+	s.nextSeq++
+	s.entries = append(s.entries, entry{seq: seq, size: size, spooledAt: now})
+	s.bytes += size
+	return nil
+}
+
+// writeFile 写入并落盘
+func writeFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Peek 返回队首消息及其序号，队列为空时返回 nil
+// 超时或无法解析的消息直接丢弃
+func (s *Spool) Peek() (uint64, *protocol.DataMessage) {
+	seqs, msgs := s.PeekBatch(1)
+	if len(msgs) == 0 {
+		return 0, nil
+	}
+	return seqs[0], msgs[0]
+}
+
+// PeekBatch 按入队顺序返回队首最多 n 条消息及其序号（批量同步后逐条 Remove）
+// 超时或无法解析的消息直接丢弃
+func (s *Spool) PeekBatch(n int) ([]uint64, []*protocol.DataMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireLocked(time.Now())
+
+	var (
+		seqs []uint64
+		msgs []*protocol.DataMessage
+	)
+	for i := 0; i < len(s.entries) && len(msgs) < n; {
+		e := s.entries[i]
+		data, err := os.ReadFile(s.path(e.seq))
+		var rec record
+		if err == nil {
+			err = json.Unmarshal(data, &rec)
+		}
+		if err != nil {
+			s.logger.Warn("Dropping unreadable spooled message", "seq", e.seq, "error", err)
+			s.removeLocked(i)
+			continue
+		}
+		seqs = append(seqs, e.seq)
+		msgs = append(msgs, &protocol.DataMessage{
+			MsgId:     rec.MsgID,
+			Type:      protocol.MessageType(rec.Type),
+			Payload:   rec.Payload,
+			Timestamp: rec.Timestamp,
+		})
+		i++
+	}
+	return seqs, msgs
+}
+
+// Remove 移除指定序号的消息（重放成功后调用），消息已被丢弃时忽略
+func (s *Spool) Remove(seq uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := sort.Search(len(s.entries), func(i int) bool { return s.entries[i].seq >= seq })
+	if i < len(s.entries) && s.entries[i].seq == seq {
+		s.removeLocked(i)
+	}
+}
+
+// removeLocked 删除第 i 条消息
+func (s *Spool) removeLocked(i int) {
+	e := s.entries[i]
+	if err := os.Remove(s.path(e.seq)); err != nil && !os.IsNotExist(err) {
+		s.logger.Warn("Failed to remove spooled message", "seq", e.seq, "error", err)
+	}
+	s.bytes -= e.size
+	s.entries = append(s.entries[:i], s.entries[i+1:]...)
+}
+
+// expireLocked 丢弃超过最长保留时间的消息（按入队顺序，只需检查队首）
+func (s *Spool) expireLocked(now time.Time) {
+	for len(s.entries) > 0 && now.Sub(s.entries[0].spooledAt) > s.cfg.MaxAge {
+		s.expired++
+		s.removeLocked(0)
+	}
+}
+
+// Depth 返回当前缓存的消息数
+func (s *Spool) Depth() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Stats 返回队列状态
+func (s *Spool) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := Stats{
+		Depth:       len(s.entries),
+		Bytes:       s.bytes,
+		Dropped:     s.dropped,
+		Expired:     s.expired,
+		MaxBytes:    s.cfg.MaxBytes,
+		MaxMessages: s.cfg.MaxMessages,
+		DropPolicy:  string(s.cfg.DropPolicy),
+	}
+	if len(s.entries) > 0 {
+		st.OldestAgeSec = int64(time.Since(s.entries[0].spooledAt) / time.Second)
+	}
+	return st
+}
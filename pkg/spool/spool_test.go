@@ -0,0 +1,207 @@
+package spool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/protocol"
+)
+
+func reportMsg(n int) *protocol.DataMessage {
+	return &protocol.DataMessage{
+		MsgId:   fmt.Sprintf("m%d", n),
+		Type:    protocol.MessageType_MESSAGE_TYPE_EVENT,
+		Payload: []byte(fmt.Sprintf(`{"command_type":"report.status","payload":{"n":%d}}`, n)),
+	}
+}
+
+func TestSpoolPersistsInOrder(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(Config{Dir: dir}, nil)
+	require.NoError(t, err)
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, s.Enqueue(reportMsg(i)))
+	}
+
+	// 重新打开后保留顺序
+	s, err = Open(Config{Dir: dir}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, s.Depth())
+	var ids []string
+	for {
+		seq, msg := s.Peek()
+		if msg == nil {
+			break
+		}
+		ids = append(ids, msg.MsgId)
+		s.Remove(seq)
+	}
+	assert.Equal(t, []string{"m1", "m2", "m3"}, ids)
+	assert.Equal(t, int64(0), s.Stats().Bytes)
+
+	require.NoError(t, s.Enqueue(reportMsg(4)))
+	seq, _ := s.Peek()
+	assert.Equal(t, uint64(4), seq)
+
+	// 批量读取不移除消息
+	require.NoError(t, s.Enqueue(reportMsg(5)))
+	require.NoError(t, s.Enqueue(reportMsg(6)))
+	seqs, msgs := s.PeekBatch(2)
+	assert.Equal(t, []uint64{4, 5}, seqs)
+	assert.Equal(t, "m5", msgs[1].MsgId)
+	seqs, _ = s.PeekBatch(10)
+	assert.Len(t, seqs, 3)
+}
+
+func TestSpoolLimits(t *testing.T) {
+	s, err := Open(Config{Dir: t.TempDir(), MaxMessages: 2}, nil)
+	require.NoError(t, err)
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, s.Enqueue(reportMsg(i)))
+	}
+	_, msg := s.Peek()
+	assert.Equal(t, "m2", msg.MsgId)
+	assert.Equal(t, int64(1), s.Stats().Dropped)
+
+	s, err = Open(Config{Dir: t.TempDir(), MaxMessages: 2, DropPolicy: DropNewest}, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Enqueue(reportMsg(1)))
+	require.NoError(t, s.Enqueue(reportMsg(2)))
+	assert.ErrorIs(t, s.Enqueue(reportMsg(3)), ErrSpoolFull)
+	_, msg = s.Peek()
+	assert.Equal(t, "m1", msg.MsgId)
+
+	s, err = Open(Config{Dir: t.TempDir(), MaxBytes: 10}, nil)
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.Enqueue(reportMsg(1)), ErrMessageTooLarge)
+
+	s, err = Open(Config{Dir: t.TempDir(), MaxAge: 10 * time.Millisecond}, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Enqueue(reportMsg(1)))
+	time.Sleep(20 * time.Millisecond)
+	_, msg = s.Peek()
+	assert.Nil(t, msg)
+	assert.Equal(t, int64(1), s.Stats().Expired)
+
+	_, err = Open(Config{Dir: t.TempDir(), DropPolicy: "random"}, nil)
+	assert.Error(t, err)
+}
+
+// fakeSender 模拟可断开的链路
+type fakeSender struct {
+	mu        sync.Mutex
+	connected bool
+	sent      []string
+	acked     []bool
+}
+
+func (f *fakeSender) IsConnected() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.connected
+}
+
+func (f *fakeSender) setConnected(v bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.connected = v
+}
+
+func (f *fakeSender) SendMessage(ctx context.Context, msg *protocol.DataMessage, waitAck bool, timeout time.Duration) (*protocol.AckMessage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.connected {
+		return nil, errors.New("not connected")
+	}
+	f.sent = append(f.sent, msg.MsgId)
+	f.acked = append(f.acked, waitAck)
+	if !waitAck {
+		return nil, nil
+	}
+	return &protocol.AckMessage{MsgId: msg.MsgId, Status: protocol.AckStatus_ACK_STATUS_SUCCESS}, nil
+}
+
+func (f *fakeSender) snapshot() ([]string, []bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.sent...), append([]bool(nil), f.acked...)
+}
+
+func TestOutboxSpoolsAndReplays(t *testing.T) {
+	s, err := Open(Config{Dir: t.TempDir()}, nil)
+	require.NoError(t, err)
+	sender := &fakeSender{}
+	o := NewOutbox(sender, s, nil, nil)
+
+	// 离线时可缓存的消息入队，不可缓存的消息直接返回错误
+	for i := 1; i <= 3; i++ {
+		_, err := o.SendMessage(context.Background(), reportMsg(i), false, 0)
+		require.NoError(t, err)
+	}
+	_, err = o.SendMessage(context.Background(), reportMsg(4), true, time.Second)
+	assert.Error(t, err)
+	_, err = o.SendMessage(context.Background(), &protocol.DataMessage{
+		Type: protocol.MessageType_MESSAGE_TYPE_EVENT, Payload: []byte(`{"command_type":"event.subscribe"}`),
+	}, false, 0)
+	assert.Error(t, err)
+	assert.Equal(t, 3, o.Stats().Depth)
+
+	// 重连后按顺序重放，并等待确认
+	sender.setConnected(true)
+	n, err := o.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	sent, acked := sender.snapshot()
+	assert.Equal(t, []string{"m1", "m2", "m3"}, sent)
+	assert.Equal(t, []bool{true, true, true}, acked)
+	assert.Equal(t, 0, o.Stats().Depth)
+	assert.Equal(t, int64(3), o.Stats().Replayed)
+
+	// 在线且队列为空时直接发送
+	_, err = o.SendMessage(context.Background(), reportMsg(5), false, 0)
+	require.NoError(t, err)
+	sent, acked = sender.snapshot()
+	assert.Equal(t, "m5", sent[3])
+	assert.False(t, acked[3])
+}
+
+func TestOutboxBackgroundReplay(t *testing.T) {
+	s, err := Open(Config{Dir: t.TempDir()}, nil)
+	require.NoError(t, err)
+	sender := &fakeSender{}
+	o := NewOutbox(sender, s, nil, nil)
+	o.Start()
+	defer o.Stop()
+
+	require.NoError(t, o.Send(context.Background(), reportMsg(1)))
+	sender.setConnected(true)
+	o.Trigger()
+	require.Eventually(t, func() bool { return o.Stats().Depth == 0 }, 2*time.Second, 10*time.Millisecond)
+	sent, _ := sender.snapshot()
+	assert.Equal(t, []string{"m1"}, sent)
+}
+
+func TestDefaultSpoolable(t *testing.T) {
+	assert.True(t, DefaultSpoolable(reportMsg(1)))
+	assert.True(t, DefaultSpoolable(&protocol.DataMessage{
+		Type: protocol.MessageType_MESSAGE_TYPE_EVENT, Payload: []byte(`{"command_type":"command.callback"}`),
+	}))
+	assert.True(t, DefaultSpoolable(&protocol.DataMessage{
+		Type: protocol.MessageType_MESSAGE_TYPE_EVENT, Payload: []byte(`{"command_type":"task.result"}`),
+	}))
+	assert.False(t, DefaultSpoolable(&protocol.DataMessage{
+		Type: protocol.MessageType_MESSAGE_TYPE_EVENT, Payload: []byte(`{"command_type":"task.progress"}`),
+	}))
+	assert.False(t, DefaultSpoolable(&protocol.DataMessage{
+		Type: protocol.MessageType_MESSAGE_TYPE_QUERY, Payload: []byte(`{"command_type":"report.status"}`),
+	}))
+	assert.False(t, DefaultSpoolable(&protocol.DataMessage{
+		Type: protocol.MessageType_MESSAGE_TYPE_EVENT, Payload: []byte(`not json`),
+	}))
+}
@@ -0,0 +1,107 @@
+// Package executor 提供 Agent 端的定时任务执行：按服务器下发的 TaskExecution
+// 调用 Shell/HTTP/插件执行器，处理超时与重试，并以事件上报进度和结果
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// shellWaitDelay 超时或取消后等待子进程输出管道关闭的最长时间
+const shellWaitDelay = 5 * time.Second
+
+// Executor 任务执行器
+// Execute 按执行器配置（ExecutorConfig JSON）执行一次任务，输出写入 out 并返回退出码；
+// 执行失败（包括退出码非 0）时返回错误
+type Executor interface {
+	Execute(ctx context.Context, config string, out io.Writer) (int, error)
+}
+
+// ShellConfig Shell 执行器配置
+type ShellConfig struct {
+	Command string            `json:"command"`           // 要执行的命令
+	Shell   string            `json:"shell,omitempty"`   // 解释器（默认 sh）
+	WorkDir string            `json:"workdir,omitempty"` // 工作目录
+	Env     map[string]string `json:"env,omitempty"`     // 额外的环境变量
+}
+
+// ShellExecutor 通过 `<shell> -c <command>` 执行命令，stdout 与 stderr 合并输出
+type ShellExecutor struct{}
+
+// Execute 执行 Shell 命令
+func (ShellExecutor) Execute(ctx context.Context, config string, out io.Writer) (int, error) {
+	var cfg ShellConfig
+	if err := json.Unmarshal([]byte(config), &cfg); err != nil {
+		return -1, fmt.Errorf("invalid shell executor config: %w", err)
+	}
+	if strings.TrimSpace(cfg.Command) == "" {
+		return -1, fmt.Errorf("shell executor config: command is required")
+	}
+	shell := cfg.Shell
+	if shell == "" {
+		shell = "sh"
+	}
+
+	cmd := exec.CommandContext(ctx, shell, "-c", cfg.Command)
+	cmd.Dir = cfg.WorkDir
+	if len(cfg.Env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range cfg.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	// 独立进程组：超时或取消时终止命令启动的所有子进程
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = shellWaitDelay
+
+	err := cmd.Run()
+	if err == nil {
+		return 0, nil
+	}
+	if ctx.Err() != nil {
+		return -1, ctx.Err()
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), fmt.Errorf("exit status %d", exitErr.ExitCode())
+	}
+	return -1, err
+}
+
+// PluginRunner 按执行器配置执行插件命令（*plugin.Manager 实现）
+type PluginRunner interface {
+	ExecuteTask(ctx context.Context, executorConfig string) (json.RawMessage, error)
+}
+
+// PluginExecutor 通过插件执行任务，插件返回的结果作为输出
+type PluginExecutor struct {
+	Plugins PluginRunner
+}
+
+// Execute 执行插件命令
+func (e PluginExecutor) Execute(ctx context.Context, config string, out io.Writer) (int, error) {
+	result, err := e.Plugins.ExecuteTask(ctx, config)
+	if len(result) > 0 {
+		out.Write(result)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+		return 1, err
+	}
+	return 0, nil
+}
@@ -0,0 +1,369 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/jsonpath"
+)
+
+// HTTP 执行器的退出码（请求未完成时为 -1）
+const (
+	HTTPExitOK              = 0 // 状态码与断言均通过
+	HTTPExitUnexpectedCode  = 1 // 状态码不在期望范围内
+	HTTPExitAssertionFailed = 2 // 响应体断言失败
+)
+
+// maxResponseBody 读取用于断言的响应体上限，超出部分丢弃
+const maxResponseBody = 1 << 20
+
+// HTTPConfig HTTP 执行器配置
+type HTTPConfig struct {
+	URL            string          `json:"url"`                       // 请求地址
+	Method         string          `json:"method,omitempty"`          // 请求方法（默认 GET）
+	Headers        json.RawMessage `json:"headers,omitempty"`         // 请求头：对象，或 Web 表单提交的 JSON 字符串
+	Body           string          `json:"body,omitempty"`            // 请求体，text/template 模板（字段见 HTTPTemplateData）
+	ExpectedStatus json.RawMessage `json:"expected_status,omitempty"` // 期望状态码：[200,204] 或 "200,204,3xx"，默认 2xx
+	Assert         *HTTPAssertion  `json:"assert,omitempty"`          // 响应体断言
+	TLS            *HTTPTLSConfig  `json:"tls,omitempty"`             // TLS 选项
+	Timeout        int             `json:"timeout,omitempty"`         // 请求超时（秒），为 0 时只受任务超时限制
+}
+
+// HTTPAssertion 响应体断言
+// 设置 json_path 时按路径从 JSON 响应体取值，否则以整个响应体作为值；
+// 值须等于 equals（按字符串比较）并匹配 regex，二者为空时不检查
+type HTTPAssertion struct {
+	JSONPath string `json:"json_path,omitempty"` // 取值路径，如 $.data.items[0].status 或 data.items.0.status
+	Equals   string `json:"equals,omitempty"`    // 期望值
+	Regex    string `json:"regex,omitempty"`     // 正则
+}
+
+// HTTPTLSConfig TLS 选项
+type HTTPTLSConfig struct {
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"` // 跳过证书校验
+	CAFile             string `json:"ca_file,omitempty"`              // 额外信任的 CA 证书（PEM）
+	CertFile           string `json:"cert_file,omitempty"`            // 客户端证书（PEM，双向 TLS）
+	KeyFile            string `json:"key_file,omitempty"`             // 客户端私钥（PEM）
+	ServerName         string `json:"server_name,omitempty"`          // 覆盖 SNI 与证书校验的主机名
+}
+
+// HTTPTemplateData 请求体模板可用的字段
+// 不提供读取环境变量等函数：任务由服务器下发，模板不能读出 Agent 本机的密钥
+type HTTPTemplateData struct {
+	ClientID    string // 客户端 ID
+	Hostname    string // 主机名
+	TaskID      string // 任务 ID
+	TaskName    string // 任务名称
+	ExecutionID string // 执行 ID
+	Timestamp   int64  // 当前时间（毫秒）
+	Time        string // 当前时间（RFC3339）
+}
+
+// parseHeaders 解析请求头，兼容对象与 JSON 字符串两种形式
+func parseHeaders(raw json.RawMessage) (map[string]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(s) == "" {
+			return nil, nil
+		}
+		raw = json.RawMessage(s)
+	}
+	var headers map[string]string
+	if err := json.Unmarshal(raw, &headers); err != nil {
+		return nil, err
+	}
+	return headers, nil
+}
+
+// parseExpectedStatus 解析期望状态码，返回 3 位模式（数字或 x），默认 2xx
+func parseExpectedStatus(raw json.RawMessage) ([]string, error) {
+	var items []string
+	if len(raw) > 0 && string(raw) != "null" {
+		switch raw[0] {
+		case '[':
+			var list []interface{}
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return nil, err
+			}
+			for _, v := range list {
+				items = append(items, fmt.Sprint(v))
+			}
+		case '"':
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return nil, err
+			}
+			items = strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+		default:
+			items = []string{string(raw)}
+		}
+	}
+	if len(items) == 0 {
+		return []string{"2xx"}, nil
+	}
+
+	patterns := make([]string, 0, len(items))
+	for _, item := range items {
+		p := strings.ToLower(strings.TrimSpace(item))
+		if len(p) != 3 || strings.Trim(p, "0123456789x") != "" {
+			return nil, fmt.Errorf("invalid status %q", item)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// statusMatches 状态码是否匹配任一模式
+func statusMatches(code int, patterns []string) bool {
+	s := strconv.Itoa(code)
+	for _, p := range patterns {
+		matched := len(s) == 3
+		for i := 0; matched && i < 3; i++ {
+			matched = p[i] == 'x' || p[i] == s[i]
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// HTTPExecutor 发送 HTTP 请求，状态码符合期望且断言通过视为成功
+// 输出为请求行、状态行与响应体，失败原因附在末尾
+type HTTPExecutor struct {
+	Client *http.Client // 为空时使用 http.DefaultClient；配置了 TLS 选项时使用独立的 Transport
+}
+
+// Execute 执行 HTTP 请求
+func (e HTTPExecutor) Execute(ctx context.Context, config string, out io.Writer) (int, error) {
+	var cfg HTTPConfig
+	if err := json.Unmarshal([]byte(config), &cfg); err != nil {
+		return -1, fmt.Errorf("invalid http executor config: %w", err)
+	}
+	if cfg.URL == "" {
+		return -1, fmt.Errorf("http executor config: url is required")
+	}
+	headers, err := parseHeaders(cfg.Headers)
+	if err != nil {
+		return -1, fmt.Errorf("http executor config: invalid headers: %w", err)
+	}
+	expected, err := parseExpectedStatus(cfg.ExpectedStatus)
+	if err != nil {
+		return -1, fmt.Errorf("http executor config: invalid expected_status: %w", err)
+	}
+	var pattern *regexp.Regexp
+	if cfg.Assert != nil && cfg.Assert.Regex != "" {
+		if pattern, err = regexp.Compile(cfg.Assert.Regex); err != nil {
+			return -1, fmt.Errorf("http executor config: invalid assert regex: %w", err)
+		}
+	}
+	body, err := renderBody(ctx, cfg.Body)
+	if err != nil {
+		return -1, fmt.Errorf("http executor config: invalid body template: %w", err)
+	}
+	client, err := e.client(cfg.TLS)
+	if err != nil {
+		return -1, fmt.Errorf("http executor config: %w", err)
+	}
+	method := strings.ToUpper(cfg.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	reqCtx := ctx
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		reqCtx, cancel = context.WithTimeout(ctx, time.Duration(cfg.Timeout)*time.Second)
+		defer cancel()
+	}
+	var reqBody io.Reader
+	if body != "" {
+		reqBody = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(reqCtx, method, cfg.URL, reqBody)
+	if err != nil {
+		return -1, fmt.Errorf("http executor: %w", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	start := time.Now()
+	fmt.Fprintf(out, "%s %s\n", method, cfg.URL)
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+		if errors.Is(reqCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("request timeout after %ds", cfg.Timeout)
+		}
+		fmt.Fprintf(out, "\n%v\n", err)
+		return -1, err
+	}
+	defer resp.Body.Close()
+
+	fmt.Fprintf(out, "%s %s (%dms)\n\n", resp.Proto, resp.Status, time.Since(start).Milliseconds())
+	var buf bytes.Buffer
+	_, err = io.Copy(io.MultiWriter(out, &buf), io.LimitReader(resp.Body, maxResponseBody))
+	if err != nil {
+		if ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+		if errors.Is(reqCtx.Err(), context.DeadlineExceeded) {
+			return -1, fmt.Errorf("request timeout after %ds", cfg.Timeout)
+		}
+		return -1, fmt.Errorf("read response: %w", err)
+	}
+
+	if !statusMatches(resp.StatusCode, expected) {
+		err := fmt.Errorf("unexpected status: %s (expected %s)", resp.Status, strings.Join(expected, ","))
+		fmt.Fprintf(out, "\n\n%v\n", err)
+		return HTTPExitUnexpectedCode, err
+	}
+	if cfg.Assert != nil {
+		if err := checkAssertion(cfg.Assert, pattern, buf.Bytes()); err != nil {
+			err = fmt.Errorf("assertion failed: %w", err)
+			fmt.Fprintf(out, "\n\n%v\n", err)
+			return HTTPExitAssertionFailed, err
+		}
+	}
+	return HTTPExitOK, nil
+}
+
+// client 返回发送请求使用的客户端，配置了 TLS 选项时基于默认 Transport 创建
+func (e HTTPExecutor) client(opts *HTTPTLSConfig) (*http.Client, error) {
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if opts == nil {
+		return client, nil
+	}
+
+	tlsCfg := &tls.Config{
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+		ServerName:         opts.ServerName,
+	}
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ca_file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_file: no certificates found")
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+	// 每次执行使用独立连接，避免复用不同 TLS 选项的连接
+	transport.DisableKeepAlives = true
+	return &http.Client{
+		Transport:     transport,
+		CheckRedirect: client.CheckRedirect,
+		Jar:           client.Jar,
+	}, nil
+}
+
+// renderBody 渲染请求体模板
+func renderBody(ctx context.Context, body string) (string, error) {
+	if !strings.Contains(body, "{{") {
+		return body, nil
+	}
+	tmpl, err := template.New("body").Option("missingkey=error").Parse(body)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	data := HTTPTemplateData{Timestamp: now.UnixMilli(), Time: now.Format(time.RFC3339)}
+	data.Hostname, _ = os.Hostname()
+	if info, ok := executionFrom(ctx); ok {
+		data.ClientID = info.clientID
+		data.TaskID = info.task.TaskId
+		data.TaskName = info.task.TaskName
+		data.ExecutionID = info.task.ExecutionId
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// checkAssertion 检查响应体断言
+func checkAssertion(a *HTTPAssertion, pattern *regexp.Regexp, body []byte) error {
+	value := string(body)
+	if a.JSONPath != "" {
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		var doc interface{}
+		if err := dec.Decode(&doc); err != nil {
+			return fmt.Errorf("response is not valid JSON: %v", err)
+		}
+		v, err := jsonpath.Lookup(doc, a.JSONPath)
+		if err != nil {
+			return err
+		}
+		value = jsonValueString(v)
+	}
+	if a.Equals != "" && value != a.Equals {
+		return fmt.Errorf("%s = %q, expected %q", assertionSubject(a), value, a.Equals)
+	}
+	if pattern != nil && !pattern.MatchString(value) {
+		return fmt.Errorf("%s does not match %q", assertionSubject(a), a.Regex)
+	}
+	return nil
+}
+
+// assertionSubject 断言失败信息中的取值描述
+func assertionSubject(a *HTTPAssertion) string {
+	if a.JSONPath != "" {
+		return a.JSONPath
+	}
+	return "response body"
+}
+
+// jsonValueString 值的字符串形式：字符串取原值，其他值取 JSON 编码
+func jsonValueString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,159 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/protocol"
+)
+
+func TestHTTPExecutorExpectedStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		expected string
+		code     int
+	}{
+		{``, HTTPExitOK},
+		{`"2xx"`, HTTPExitOK},
+		{`"200, 202"`, HTTPExitOK},
+		{`[200,202]`, HTTPExitOK},
+		{`[200]`, HTTPExitUnexpectedCode},
+		{`"3xx"`, HTTPExitUnexpectedCode},
+	}
+	for _, c := range cases {
+		cfg := `{"url":"` + srv.URL + `"`
+		if c.expected != "" {
+			cfg += `,"expected_status":` + c.expected
+		}
+		var out bytes.Buffer
+		code, err := HTTPExecutor{}.Execute(context.Background(), cfg+`}`, &out)
+		assert.Equal(t, c.code, code, c.expected)
+		assert.Equal(t, c.code != HTTPExitOK, err != nil, c.expected)
+	}
+
+	var out bytes.Buffer
+	code, err := HTTPExecutor{}.Execute(context.Background(), `{"url":"`+srv.URL+`","expected_status":"2x"}`, &out)
+	assert.Error(t, err)
+	assert.Equal(t, -1, code)
+}
+
+func TestHTTPExecutorAssertion(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ok","data":{"items":[{"id":1,"ready":true},{"id":12345678901234}]}}`))
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		assert string
+		code   int
+	}{
+		{`{"json_path":"$.status","equals":"ok"}`, HTTPExitOK},
+		{`{"json_path":"data.items.0.ready","equals":"true"}`, HTTPExitOK},
+		{`{"json_path":"$.data.items[1].id","equals":"12345678901234"}`, HTTPExitOK},
+		{`{"json_path":"$.data.items[0]","regex":"\"ready\":true"}`, HTTPExitOK},
+		{`{"regex":"^\\{\"status\":\"ok\""}`, HTTPExitOK},
+		{`{"json_path":"$.status","equals":"down"}`, HTTPExitAssertionFailed},
+		{`{"json_path":"$.data.items[5].id"}`, HTTPExitAssertionFailed},
+		{`{"json_path":"$.missing"}`, HTTPExitAssertionFailed},
+		{`{"regex":"error"}`, HTTPExitAssertionFailed},
+	}
+	for _, c := range cases {
+		var out bytes.Buffer
+		code, err := HTTPExecutor{}.Execute(context.Background(), `{"url":"`+srv.URL+`","assert":`+c.assert+`}`, &out)
+		assert.Equal(t, c.code, code, c.assert)
+		if c.code == HTTPExitAssertionFailed {
+			assert.Error(t, err, c.assert)
+			assert.Contains(t, out.String(), "assertion failed", c.assert)
+		} else {
+			assert.NoError(t, err, c.assert)
+		}
+	}
+
+	var out bytes.Buffer
+	code, err := HTTPExecutor{}.Execute(context.Background(), `{"url":"`+srv.URL+`","assert":{"regex":"("}}`, &out)
+	assert.Error(t, err)
+	assert.Equal(t, -1, code)
+}
+
+func TestHTTPExecutorBodyTemplate(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer srv.Close()
+
+	ctx := withExecution(context.Background(), "client-1", &protocol.TaskExecution{TaskId: "7", TaskName: "ping", ExecutionId: "exec-1"})
+	var out bytes.Buffer
+	code, err := HTTPExecutor{}.Execute(ctx, `{"url":"`+srv.URL+`","method":"POST",`+
+		`"body":"{{.ClientID}}/{{.TaskID}}/{{.TaskName}}/{{.ExecutionID}}"}`, &out)
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "client-1/7/ping/exec-1", body)
+
+	_, err = HTTPExecutor{}.Execute(ctx, `{"url":"`+srv.URL+`","body":"{{.Unknown}}"}`, &out)
+	assert.Error(t, err)
+	// 模板不能读取 Agent 的环境变量
+	t.Setenv("HTTP_TEST_SECRET", "s3cret")
+	_, err = HTTPExecutor{}.Execute(ctx, `{"url":"`+srv.URL+`","body":"{{env \"HTTP_TEST_SECRET\"}}"}`, &out)
+	assert.ErrorContains(t, err, "function \"env\" not defined")
+}
+
+func TestHTTPExecutorTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secure"))
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	// 自签名证书默认校验失败
+	_, err := HTTPExecutor{}.Execute(context.Background(), `{"url":"`+srv.URL+`"}`, &out)
+	assert.Error(t, err)
+
+	code, err := HTTPExecutor{}.Execute(context.Background(), `{"url":"`+srv.URL+`","tls":{"insecure_skip_verify":true}}`, &out)
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o600))
+	out.Reset()
+	code, err = HTTPExecutor{}.Execute(context.Background(), `{"url":"`+srv.URL+`","tls":{"ca_file":"`+caFile+`","server_name":"example.com"}}`, &out)
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out.String(), "secure")
+
+	_, err = HTTPExecutor{}.Execute(context.Background(), `{"url":"`+srv.URL+`","tls":{"ca_file":"missing.pem"}}`, &out)
+	assert.Error(t, err)
+}
+
+func TestHTTPExecutorTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	start := time.Now()
+	code, err := HTTPExecutor{}.Execute(context.Background(), `{"url":"`+srv.URL+`","timeout":1}`, &out)
+	assert.Error(t, err)
+	assert.Equal(t, -1, code)
+	assert.Contains(t, err.Error(), "timeout")
+	assert.Less(t, time.Since(start), 4*time.Second)
+}
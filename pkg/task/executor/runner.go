@@ -0,0 +1,377 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/protocol"
+)
+
+// 执行参数
+const (
+	DefaultMaxOutputSize = 64 * 1024 // 单次执行保留的最大输出
+
+	progressInterval = time.Second      // 增量输出上报间隔
+	reportTimeout    = 10 * time.Second // 上报发送超时
+)
+
+var (
+	// ErrUnsupportedExecutor 表示没有对应类型的执行器
+	ErrUnsupportedExecutor = errors.New("unsupported executor type")
+
+	// ErrAlreadyRunning 表示同一执行 ID 的任务正在执行
+	ErrAlreadyRunning = errors.New("execution already running")
+
+	errCancelled = errors.New("execution cancelled")
+	errStopped   = errors.New("agent stopping")
+)
+
+// Runner Agent 端任务执行器：后台执行服务器下发的任务，
+// 运行中定期上报 task.progress（增量输出），结束后上报 task.result
+type Runner struct {
+	sender        command.ClientAPI
+	clientID      string
+	maxOutputSize int
+	logger        *monitoring.Logger
+
+	mu        sync.Mutex
+	executors map[protocol.ExecutorType]Executor
+	running   map[string]context.CancelCauseFunc
+	wg        sync.WaitGroup
+}
+
+// NewRunner 创建执行器，默认支持 Shell 与 HTTP，插件执行器通过 SetExecutor 注册
+func NewRunner(sender command.ClientAPI, clientID string, logger *monitoring.Logger) *Runner {
+	if logger == nil {
+		logger = monitoring.NewDefaultLogger()
+	}
+	return &Runner{
+		sender:        sender,
+		clientID:      clientID,
+		maxOutputSize: DefaultMaxOutputSize,
+		logger:        logger,
+		executors: map[protocol.ExecutorType]Executor{
+			protocol.ExecutorType_EXECUTOR_TYPE_SHELL: ShellExecutor{},
+			protocol.ExecutorType_EXECUTOR_TYPE_HTTP:  HTTPExecutor{},
+		},
+		running: make(map[string]context.CancelCauseFunc),
+	}
+}
+
+// SetExecutor 注册（或替换）指定类型的执行器
+func (r *Runner) SetExecutor(t protocol.ExecutorType, e Executor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.executors[t] = e
+}
+
+// ResultFunc 接收执行结果
+type ResultFunc func(result *protocol.TaskResult)
+
+// Start 在后台开始执行服务器下发的任务，立即返回
+func (r *Runner) Start(task *protocol.TaskExecution) error {
+	return r.start(task, true, r.reportResult)
+}
+
+// StartLocal 在后台执行 Agent 本地调度的任务：不上报进度，结果交给 done 处理
+// （由调用方缓存并同步到服务器）
+func (r *Runner) StartLocal(task *protocol.TaskExecution, done ResultFunc) error {
+	return r.start(task, false, done)
+}
+
+func (r *Runner) start(task *protocol.TaskExecution, progress bool, done ResultFunc) error {
+	if task == nil || task.ExecutionId == "" {
+		return fmt.Errorf("execution_id is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.executors[task.ExecutorType]
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrUnsupportedExecutor, task.ExecutorType)
+	}
+	if _, ok := r.running[task.ExecutionId]; ok {
+		return fmt.Errorf("%w: %s", ErrAlreadyRunning, task.ExecutionId)
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	r.running[task.ExecutionId] = cancel
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer func() {
+			r.mu.Lock()
+			delete(r.running, task.ExecutionId)
+			r.mu.Unlock()
+			cancel(nil)
+		}()
+		done(r.run(ctx, task, e, progress))
+	}()
+	return nil
+}
+
+// Cancel 取消执行中的任务，任务不存在时返回 false
+func (r *Runner) Cancel(executionID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cancel, ok := r.running[executionID]
+	if ok {
+		cancel(errCancelled)
+	}
+	return ok
+}
+
+// Running 返回执行中的任务 ID
+func (r *Runner) Running() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]string, 0, len(r.running))
+	for id := range r.running {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Stop 取消所有执行中的任务并等待其上报结果
+func (r *Runner) Stop() {
+	r.mu.Lock()
+	for _, cancel := range r.running {
+		cancel(errStopped)
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+}
+
+// run 执行任务：失败或超时按 retry_count/retry_interval 重试，取消时不再重试
+func (r *Runner) run(ctx context.Context, task *protocol.TaskExecution, e Executor, progress bool) *protocol.TaskResult {
+	start := time.Now()
+	out := newOutputBuffer(r.maxOutputSize)
+
+	var (
+		status   protocol.ExecutionStatus
+		exitCode int
+		err      error
+		attempt  int
+	)
+	for attempt = 0; ; attempt++ {
+		if attempt > 0 {
+			fmt.Fprintf(out, "\n--- retry %d/%d ---\n", attempt, task.RetryCount)
+		}
+		status, exitCode, err = r.attempt(ctx, task, e, out, progress)
+		if status == protocol.ExecutionStatus_EXECUTION_STATUS_SUCCESS ||
+			status == protocol.ExecutionStatus_EXECUTION_STATUS_CANCELLED ||
+			attempt >= int(task.RetryCount) {
+			break
+		}
+		r.logger.Info("Task attempt failed, retrying",
+			"execution_id", task.ExecutionId, "attempt", attempt+1, "error", err)
+		select {
+		case <-time.After(time.Duration(task.RetryInterval) * time.Second):
+			continue
+		case <-ctx.Done():
+			status, err = protocol.ExecutionStatus_EXECUTION_STATUS_CANCELLED, context.Cause(ctx)
+		}
+		break
+	}
+
+	result := &protocol.TaskResult{
+		ExecutionId: task.ExecutionId,
+		TaskId:      task.TaskId,
+		Status:      status,
+		ExitCode:    int32(exitCode),
+		Output:      out.String(),
+		DurationMs:  time.Since(start).Milliseconds(),
+		RetryCount:  int32(attempt),
+		Timestamp:   time.Now().UnixMilli(),
+	}
+	if err != nil {
+		result.ErrorMsg = err.Error()
+	}
+	r.logger.Info("Task finished",
+		"execution_id", task.ExecutionId,
+		"task_id", task.TaskId,
+		"status", int32(status),
+		"exit_code", exitCode,
+		"retries", attempt,
+		"duration_ms", result.DurationMs)
+	return result
+}
+
+// reportResult 以 task.result 事件上报执行结果
+func (r *Runner) reportResult(result *protocol.TaskResult) {
+	if err := r.report(command.EventTaskResult, command.TaskResultReport{ClientID: r.clientID, Result: result}); err != nil {
+		r.logger.Warn("Failed to report task result", "execution_id", result.ExecutionId, "error", err)
+	}
+}
+
+// attempt 执行一次任务，progress 为 true 时运行期间定期上报增量输出
+func (r *Runner) attempt(ctx context.Context, task *protocol.TaskExecution, e Executor, out *outputBuffer, progress bool) (protocol.ExecutionStatus, int, error) {
+	ctx = withExecution(ctx, r.clientID, task)
+	execCtx, cancel := ctx, context.CancelFunc(func() {})
+	if task.Timeout > 0 {
+		execCtx, cancel = context.WithTimeout(ctx, time.Duration(task.Timeout)*time.Second)
+	}
+	defer cancel()
+	if !progress {
+		exitCode, err := e.Execute(execCtx, task.ExecutorConfig, out)
+		return classify(ctx, execCtx, task, exitCode, err)
+	}
+
+	r.sendProgress(task, out)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if out.HasPending() {
+					r.sendProgress(task, out)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	exitCode, err := e.Execute(execCtx, task.ExecutorConfig, out)
+	close(done)
+	wg.Wait()
+	return classify(ctx, execCtx, task, exitCode, err)
+}
+
+// executionInfo 当前执行的信息，供执行器读取（如 HTTP 请求体模板）
+type executionInfo struct {
+	clientID string
+	task     *protocol.TaskExecution
+}
+
+type executionKey struct{}
+
+// withExecution 将执行信息放入上下文
+func withExecution(ctx context.Context, clientID string, task *protocol.TaskExecution) context.Context {
+	return context.WithValue(ctx, executionKey{}, executionInfo{clientID: clientID, task: task})
+}
+
+// executionFrom 从上下文读取执行信息
+func executionFrom(ctx context.Context) (executionInfo, bool) {
+	info, ok := ctx.Value(executionKey{}).(executionInfo)
+	return info, ok
+}
+
+// classify 根据执行结果与上下文状态确定执行状态
+func classify(ctx, execCtx context.Context, task *protocol.TaskExecution, exitCode int, err error) (protocol.ExecutionStatus, int, error) {
+	switch {
+	case err == nil:
+		return protocol.ExecutionStatus_EXECUTION_STATUS_SUCCESS, exitCode, nil
+	case ctx.Err() != nil:
+		return protocol.ExecutionStatus_EXECUTION_STATUS_CANCELLED, exitCode, context.Cause(ctx)
+	case errors.Is(execCtx.Err(), context.DeadlineExceeded):
+		return protocol.ExecutionStatus_EXECUTION_STATUS_TIMEOUT, exitCode, fmt.Errorf("timeout after %ds", task.Timeout)
+	default:
+		return protocol.ExecutionStatus_EXECUTION_STATUS_FAILED, exitCode, err
+	}
+}
+
+// sendProgress 上报运行状态与自上次上报以来的新输出
+func (r *Runner) sendProgress(task *protocol.TaskExecution, out *outputBuffer) {
+	progress := &protocol.TaskProgress{
+		ExecutionId: task.ExecutionId,
+		TaskId:      task.TaskId,
+		Status:      protocol.ExecutionStatus_EXECUTION_STATUS_RUNNING,
+		Output:      out.Pending(),
+		Timestamp:   time.Now().UnixMilli(),
+	}
+	if err := r.report(command.EventTaskProgress, command.TaskProgressReport{ClientID: r.clientID, Progress: progress}); err != nil {
+		r.logger.Debug("Failed to report task progress", "execution_id", task.ExecutionId, "error", err)
+	}
+}
+
+// report 以 EVENT 消息上报
+func (r *Runner) report(commandType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(command.CommandPayload{CommandType: commandType, Payload: data})
+	if err != nil {
+		return err
+	}
+	msg := &protocol.DataMessage{
+		MsgId:     uuid.New().String(),
+		SenderId:  r.clientID,
+		Type:      protocol.MessageType_MESSAGE_TYPE_EVENT,
+		Payload:   body,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+	defer cancel()
+	_, err = r.sender.SendMessage(ctx, msg, false, 0)
+	return err
+}
+
+// outputBuffer 并发安全、有上限的输出缓冲，记录已上报的位置以便增量推送
+type outputBuffer struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+	reported  int
+}
+
+func newOutputBuffer(max int) *outputBuffer {
+	return &outputBuffer{max: max}
+}
+
+// Write 写入输出，超出上限的部分丢弃
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if room := b.max - b.buf.Len(); len(p) > room {
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// HasPending 是否有未上报的输出
+func (b *outputBuffer) HasPending() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Len() > b.reported
+}
+
+// Pending 返回未上报的输出并标记为已上报
+func (b *outputBuffer) Pending() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := string(b.buf.Bytes()[b.reported:])
+	b.reported = b.buf.Len()
+	return s
+}
+
+// String 返回全部输出，被截断时附加提示
+func (b *outputBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.truncated {
+		return b.buf.String() + "\n... (output truncated)"
+	}
+	return b.buf.String()
+}
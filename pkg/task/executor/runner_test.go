@@ -0,0 +1,165 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/protocol"
+)
+
+// fakeSender 记录上报的进度与结果
+type fakeSender struct {
+	mu       sync.Mutex
+	progress []*protocol.TaskProgress
+	results  []*protocol.TaskResult
+}
+
+func (f *fakeSender) SendMessage(ctx context.Context, msg *protocol.DataMessage, waitAck bool, timeout time.Duration) (*protocol.AckMessage, error) {
+	var env command.CommandPayload
+	if err := json.Unmarshal(msg.Payload, &env); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch env.CommandType {
+	case command.EventTaskProgress:
+		var r command.TaskProgressReport
+		json.Unmarshal(env.Payload, &r)
+		f.progress = append(f.progress, r.Progress)
+	case command.EventTaskResult:
+		var r command.TaskResultReport
+		json.Unmarshal(env.Payload, &r)
+		f.results = append(f.results, r.Result)
+	}
+	return nil, nil
+}
+
+func (f *fakeSender) result() *protocol.TaskResult {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.results) == 0 {
+		return nil
+	}
+	return f.results[0]
+}
+
+func runTask(t *testing.T, r *Runner, sender *fakeSender, task *protocol.TaskExecution) *protocol.TaskResult {
+	require.NoError(t, r.Start(task))
+	require.Eventually(t, func() bool { return sender.result() != nil }, 10*time.Second, 10*time.Millisecond)
+	return sender.result()
+}
+
+func shellTask(id, cmd string) *protocol.TaskExecution {
+	cfg, _ := json.Marshal(ShellConfig{Command: cmd})
+	return &protocol.TaskExecution{
+		ExecutionId:    id,
+		TaskId:         "1",
+		ExecutorType:   protocol.ExecutorType_EXECUTOR_TYPE_SHELL,
+		ExecutorConfig: string(cfg),
+	}
+}
+
+func TestRunnerShellSuccess(t *testing.T) {
+	sender := &fakeSender{}
+	r := NewRunner(sender, "client-1", nil)
+
+	result := runTask(t, r, sender, shellTask("e1", "echo hello; echo oops >&2"))
+	assert.Equal(t, protocol.ExecutionStatus_EXECUTION_STATUS_SUCCESS, result.Status)
+	assert.Equal(t, int32(0), result.ExitCode)
+	assert.Equal(t, "hello\noops\n", result.Output)
+	assert.Equal(t, "e1", result.ExecutionId)
+	assert.NotEmpty(t, sender.progress)
+	assert.Empty(t, r.Running())
+}
+
+func TestRunnerRetriesAndTimeout(t *testing.T) {
+	sender := &fakeSender{}
+	r := NewRunner(sender, "client-1", nil)
+	task := shellTask("e2", "echo try; exit 3")
+	task.RetryCount = 2
+
+	result := runTask(t, r, sender, task)
+	assert.Equal(t, protocol.ExecutionStatus_EXECUTION_STATUS_FAILED, result.Status)
+	assert.Equal(t, int32(3), result.ExitCode)
+	assert.Equal(t, int32(2), result.RetryCount)
+	assert.Equal(t, 3, strings.Count(result.Output, "try\n"))
+	assert.Contains(t, result.Output, "--- retry 2/2 ---")
+	assert.Equal(t, "exit status 3", result.ErrorMsg)
+
+	sender = &fakeSender{}
+	r = NewRunner(sender, "client-1", nil)
+	task = shellTask("e3", "sleep 5")
+	task.Timeout = 1
+	result = runTask(t, r, sender, task)
+	assert.Equal(t, protocol.ExecutionStatus_EXECUTION_STATUS_TIMEOUT, result.Status)
+	assert.Less(t, result.DurationMs, int64(4000))
+}
+
+func TestRunnerCancel(t *testing.T) {
+	sender := &fakeSender{}
+	r := NewRunner(sender, "client-1", nil)
+	task := shellTask("e4", "sleep 5")
+	task.RetryCount = 3
+	require.NoError(t, r.Start(task))
+	assert.ErrorIs(t, r.Start(task), ErrAlreadyRunning)
+	assert.Equal(t, []string{"e4"}, r.Running())
+
+	assert.True(t, r.Cancel("e4"))
+	assert.False(t, r.Cancel("missing"))
+	require.Eventually(t, func() bool { return sender.result() != nil }, 10*time.Second, 10*time.Millisecond)
+	result := sender.result()
+	assert.Equal(t, protocol.ExecutionStatus_EXECUTION_STATUS_CANCELLED, result.Status)
+	assert.Equal(t, int32(0), result.RetryCount)
+
+	err := r.Start(&protocol.TaskExecution{ExecutionId: "e5", ExecutorType: protocol.ExecutorType_EXECUTOR_TYPE_PLUGIN})
+	assert.ErrorIs(t, err, ErrUnsupportedExecutor)
+}
+
+func TestHTTPExecutor(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(r.Method + " ok"))
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	// Web 表单提交的 headers 为 JSON 字符串
+	code, err := HTTPExecutor{}.Execute(context.Background(),
+		`{"url":"`+srv.URL+`","method":"post","headers":"{\"X-Token\":\"abc\"}","body":"x"}`, &out)
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out.String(), "200 OK")
+	assert.Contains(t, out.String(), "POST ok")
+
+	out.Reset()
+	code, err = HTTPExecutor{}.Execute(context.Background(), `{"url":"`+srv.URL+`","headers":{"X-Token":"bad"}}`, &out)
+	assert.Error(t, err)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out.String(), "401")
+
+	_, err = HTTPExecutor{}.Execute(context.Background(), `{}`, &out)
+	assert.Error(t, err)
+}
+
+func TestOutputBuffer(t *testing.T) {
+	b := newOutputBuffer(8)
+	b.Write([]byte("abc"))
+	assert.Equal(t, "abc", b.Pending())
+	assert.False(t, b.HasPending())
+	b.Write([]byte("defghij"))
+	assert.Equal(t, "defgh", b.Pending())
+	assert.Equal(t, "abcdefgh\n... (output truncated)", b.String())
+}
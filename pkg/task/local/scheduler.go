@@ -0,0 +1,372 @@
+// Package local 提供 Agent 端的本地任务调度：按服务器推送（或连接时拉取）的
+// TaskConfig 在本地按 Cron 执行任务，断网期间照常运行；执行结果缓存在本地磁盘，
+// 在线时通过 task.offline.sync 查询同步到服务器，并按日汇总统计以 report.task_stats 上报
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/protocol"
+	"github.com/voilet/quic-flow/pkg/spool"
+	"github.com/voilet/quic-flow/pkg/task/executor"
+)
+
+// 本地状态文件与参数
+const (
+	configFile = "config.json" // 最近一次收到的任务配置
+	statsFile  = "stats.json"  // 每日统计
+	resultsDir = "results"     // 待同步的执行结果
+
+	resultsMaxAge = 7 * 24 * time.Hour // 执行结果最长保留时间
+	pullTimeout   = 30 * time.Second   // 拉取配置超时
+)
+
+// cronParser 与服务器调度器一致，支持秒级表达式
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Agent Agent 与服务器的链路（cmd/client 中的 spooledClient 实现）
+type Agent interface {
+	GetClientID() string
+	IsConnected() bool
+	SendMessage(ctx context.Context, msg *protocol.DataMessage, waitAck bool, timeout time.Duration) (*protocol.AckMessage, error)
+	Query(ctx context.Context, name string, req interface{}) (json.RawMessage, error)
+}
+
+// Status 本地调度状态
+type Status struct {
+	ConfigVersion  int64     `json:"config_version"`            // 当前配置版本
+	Tasks          int       `json:"tasks"`                     // 已调度的任务数
+	Running        int       `json:"running"`                   // 执行中的任务数
+	PendingResults int       `json:"pending_results"`           // 待同步的执行结果
+	LastSync       time.Time `json:"last_sync,omitempty"`       // 最近一次同步成功的时间
+	LastSyncError  string    `json:"last_sync_error,omitempty"` // 最近一次同步失败的原因
+}
+
+// savedConfig config.json 的内容
+type savedConfig struct {
+	Version int64                  `json:"version"`
+	Tasks   []*protocol.TaskConfig `json:"tasks"`
+}
+
+// Scheduler Agent 本地任务调度器
+type Scheduler struct {
+	dir     string
+	agent   Agent
+	runner  *executor.Runner
+	results *spool.Spool
+	logger  *monitoring.Logger
+
+	mu      sync.Mutex
+	cron    *cron.Cron
+	version int64
+	tasks   map[string]*protocol.TaskConfig // 任务 ID -> 配置
+	entries map[string]cron.EntryID
+	active  map[string]int // 任务 ID -> 执行中的数量
+
+	statsMu  sync.Mutex
+	stats    map[string]map[string]*protocol.TaskStats // 日期 -> 任务 ID -> 统计
+	dirty    map[string]bool                           // 有变化、待上报的日期
+	lastSync time.Time
+	syncErr  string
+
+	syncMu  sync.Mutex // 串行化同步
+	trigger chan struct{}
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewScheduler 创建本地调度器，dir 保存配置、统计与待同步的结果
+// 启动时加载上次的配置，离线时也能按原配置执行
+func NewScheduler(dir string, agent Agent, runner *executor.Runner, logger *monitoring.Logger) (*Scheduler, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("local scheduler: dir is required")
+	}
+	if logger == nil {
+		logger = monitoring.NewDefaultLogger()
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("local scheduler: create dir: %w", err)
+	}
+	results, err := spool.Open(spool.Config{Dir: filepath.Join(dir, resultsDir), MaxAge: resultsMaxAge}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("local scheduler: %w", err)
+	}
+
+	s := &Scheduler{
+		dir:     dir,
+		agent:   agent,
+		runner:  runner,
+		results: results,
+		logger:  logger,
+		cron:    cron.New(cron.WithParser(cronParser)),
+		tasks:   make(map[string]*protocol.TaskConfig),
+		entries: make(map[string]cron.EntryID),
+		active:  make(map[string]int),
+		stats:   make(map[string]map[string]*protocol.TaskStats),
+		dirty:   make(map[string]bool),
+		trigger: make(chan struct{}, 1),
+		stopCh:  make(chan struct{}),
+	}
+	if err := s.loadConfig(); err != nil {
+		return nil, err
+	}
+	if err := s.loadStats(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Start 开始调度并启动后台同步
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	s.rescheduleLocked()
+	s.mu.Unlock()
+	s.cron.Start()
+
+	s.wg.Add(1)
+	go s.syncLoop()
+	s.logger.Info("Local task scheduler started", "dir", s.dir, "version", s.version, "tasks", len(s.tasks))
+}
+
+// Stop 停止调度与后台同步（执行中的任务由 Runner.Stop 取消，结果仍写入本地缓存）
+func (s *Scheduler) Stop() {
+	<-s.cron.Stop().Done()
+	close(s.stopCh)
+	s.wg.Wait()
+}
+
+// Apply 应用服务器推送的配置，版本不高于当前版本时忽略
+func (s *Scheduler) Apply(push *protocol.TaskConfigPush) error {
+	if push == nil {
+		return fmt.Errorf("config push is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if push.ConfigVersion <= s.version {
+		s.logger.Debug("Ignoring stale task config", "version", push.ConfigVersion, "current", s.version)
+		return nil
+	}
+
+	tasks := make(map[string]*protocol.TaskConfig, len(s.tasks))
+	if push.Action != protocol.ConfigAction_CONFIG_ACTION_REPLACE {
+		for id, t := range s.tasks {
+			tasks[id] = t
+		}
+	}
+	switch push.Action {
+	case protocol.ConfigAction_CONFIG_ACTION_REPLACE, protocol.ConfigAction_CONFIG_ACTION_CREATE,
+		protocol.ConfigAction_CONFIG_ACTION_UPDATE:
+		for _, t := range push.Tasks {
+			if t != nil && t.TaskId != "" {
+				tasks[t.TaskId] = t
+			}
+		}
+	case protocol.ConfigAction_CONFIG_ACTION_DELETE:
+		for _, t := range push.Tasks {
+			if t != nil {
+				delete(tasks, t.TaskId)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported config action: %d", push.Action)
+	}
+	return s.setLocked(push.ConfigVersion, tasks)
+}
+
+// Pull 向服务器拉取配置（连接或重连后调用），版本不一致时以服务器配置为准
+func (s *Scheduler) Pull(ctx context.Context) error {
+	s.mu.Lock()
+	current := s.version
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, pullTimeout)
+	defer cancel()
+	raw, err := s.agent.Query(ctx, command.QueryTaskConfigPull, &protocol.TaskConfigPull{
+		ClientId:       s.agent.GetClientID(),
+		CurrentVersion: current,
+	})
+	if err != nil {
+		return fmt.Errorf("pull task config: %w", err)
+	}
+	var resp protocol.TaskConfigPullResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return fmt.Errorf("invalid task config response: %w", err)
+	}
+	if !resp.HasUpdate {
+		return nil
+	}
+
+	// 服务器重启或回滚后版本可能变小，拉取的结果总是覆盖本地配置
+	tasks := make(map[string]*protocol.TaskConfig, len(resp.Tasks))
+	for _, t := range resp.Tasks {
+		if t != nil && t.TaskId != "" {
+			tasks[t.TaskId] = t
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setLocked(resp.ConfigVersion, tasks)
+}
+
+// setLocked 替换配置、持久化并重新调度
+func (s *Scheduler) setLocked(version int64, tasks map[string]*protocol.TaskConfig) error {
+	list := make([]*protocol.TaskConfig, 0, len(tasks))
+	for _, t := range tasks {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].TaskId < list[j].TaskId })
+	if err := writeJSON(filepath.Join(s.dir, configFile), savedConfig{Version: version, Tasks: list}); err != nil {
+		return fmt.Errorf("save task config: %w", err)
+	}
+
+	s.version = version
+	s.tasks = tasks
+	s.rescheduleLocked()
+	s.logger.Info("Local task config applied", "version", version, "tasks", len(tasks))
+	return nil
+}
+
+// rescheduleLocked 按当前配置重建 Cron 条目
+func (s *Scheduler) rescheduleLocked() {
+	for id, entry := range s.entries {
+		s.cron.Remove(entry)
+		delete(s.entries, id)
+	}
+	for id, t := range s.tasks {
+		if !t.Enabled {
+			continue
+		}
+		schedule, err := cronParser.Parse(t.CronExpr)
+		if err != nil {
+			s.logger.Warn("Invalid cron expression for local task", "task_id", id, "cron", t.CronExpr, "error", err)
+			continue
+		}
+		task := t
+		s.entries[id] = s.cron.Schedule(schedule, cron.FuncJob(func() { s.run(task) }))
+	}
+}
+
+// run 执行一次任务，执行中的数量达到 concurrency 时跳过本次调度
+func (s *Scheduler) run(t *protocol.TaskConfig) {
+	limit := int(t.Concurrency)
+	if limit <= 0 {
+		limit = 1
+	}
+	s.mu.Lock()
+	if s.active[t.TaskId] >= limit {
+		s.mu.Unlock()
+		s.logger.Warn("Local task still running, skipping", "task_id", t.TaskId, "task_name", t.TaskName)
+		return
+	}
+	s.active[t.TaskId]++
+	s.mu.Unlock()
+
+	execution := &protocol.TaskExecution{
+		ExecutionId:    "local-" + uuid.New().String(),
+		TaskId:         t.TaskId,
+		TaskName:       t.TaskName,
+		ExecutorType:   t.ExecutorType,
+		ExecutorConfig: t.ExecutorConfig,
+		Timeout:        t.Timeout,
+		RetryCount:     t.RetryCount,
+		RetryInterval:  t.RetryInterval,
+		ExecutionType:  protocol.ExecutionType_EXECUTION_TYPE_SCHEDULED,
+		Timestamp:      time.Now().UnixMilli(),
+	}
+	done := func(result *protocol.TaskResult) { s.finish(t, result) }
+	if err := s.runner.StartLocal(execution, done); err != nil {
+		// 无法执行（如执行器不支持）也记录为失败，服务器可见
+		done(&protocol.TaskResult{
+			ExecutionId: execution.ExecutionId,
+			TaskId:      t.TaskId,
+			Status:      protocol.ExecutionStatus_EXECUTION_STATUS_FAILED,
+			ExitCode:    -1,
+			ErrorMsg:    err.Error(),
+			Timestamp:   time.Now().UnixMilli(),
+		})
+	}
+}
+
+// finish 记录执行结果并触发同步
+func (s *Scheduler) finish(t *protocol.TaskConfig, result *protocol.TaskResult) {
+	s.mu.Lock()
+	s.active[t.TaskId]--
+	if s.active[t.TaskId] <= 0 {
+		delete(s.active, t.TaskId)
+	}
+	s.mu.Unlock()
+
+	if err := s.saveResult(result); err != nil {
+		s.logger.Warn("Failed to cache local task result", "execution_id", result.ExecutionId, "error", err)
+	}
+	s.recordStats(t.TaskName, result)
+	s.Trigger()
+}
+
+// Status 返回本地调度状态
+func (s *Scheduler) Status() Status {
+	s.mu.Lock()
+	st := Status{ConfigVersion: s.version, Tasks: len(s.entries)}
+	for _, n := range s.active {
+		st.Running += n
+	}
+	s.mu.Unlock()
+
+	st.PendingResults = s.results.Depth()
+	s.statsMu.Lock()
+	st.LastSync, st.LastSyncError = s.lastSync, s.syncErr
+	s.statsMu.Unlock()
+	return st
+}
+
+// loadConfig 加载上次保存的配置
+func (s *Scheduler) loadConfig() error {
+	var saved savedConfig
+	if err := readJSON(filepath.Join(s.dir, configFile), &saved); err != nil {
+		return fmt.Errorf("local scheduler: load config: %w", err)
+	}
+	s.version = saved.Version
+	for _, t := range saved.Tasks {
+		if t != nil && t.TaskId != "" {
+			s.tasks[t.TaskId] = t
+		}
+	}
+	return nil
+}
+
+// readJSON 读取 JSON 文件，文件不存在时保持零值
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeJSON 原子写入 JSON 文件
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
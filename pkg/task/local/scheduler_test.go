@@ -0,0 +1,167 @@
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/protocol"
+	"github.com/voilet/quic-flow/pkg/task/executor"
+)
+
+// fakeAgent 模拟服务器：应答配置拉取与结果同步，记录上报的统计
+type fakeAgent struct {
+	mu        sync.Mutex
+	connected bool
+	pull      *protocol.TaskConfigPullResponse
+	synced    []*protocol.TaskResult
+	stats     []*protocol.DailyStatsReport
+}
+
+func (f *fakeAgent) GetClientID() string { return "client-1" }
+
+func (f *fakeAgent) IsConnected() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.connected
+}
+
+func (f *fakeAgent) SendMessage(ctx context.Context, msg *protocol.DataMessage, waitAck bool, timeout time.Duration) (*protocol.AckMessage, error) {
+	var env command.CommandPayload
+	if err := json.Unmarshal(msg.Payload, &env); err != nil {
+		return nil, err
+	}
+	if env.CommandType == command.EventTaskStats {
+		var report protocol.DailyStatsReport
+		if err := json.Unmarshal(env.Payload, &report); err != nil {
+			return nil, err
+		}
+		f.mu.Lock()
+		f.stats = append(f.stats, &report)
+		f.mu.Unlock()
+	}
+	return nil, nil
+}
+
+func (f *fakeAgent) Query(ctx context.Context, name string, req interface{}) (json.RawMessage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch name {
+	case command.QueryTaskConfigPull:
+		return json.Marshal(f.pull)
+	case command.QueryTaskOfflineSync:
+		sync := req.(*command.TaskOfflineSync)
+		f.synced = append(f.synced, sync.Results...)
+		return json.Marshal(&protocol.OfflineSyncResponse{Success: true, SyncedCount: int64(len(sync.Results))})
+	}
+	return nil, assert.AnError
+}
+
+func shellConfig(id, cmd string) *protocol.TaskConfig {
+	cfg, _ := json.Marshal(executor.ShellConfig{Command: cmd})
+	return &protocol.TaskConfig{
+		TaskId:         id,
+		TaskName:       "task-" + id,
+		ExecutorType:   protocol.ExecutorType_EXECUTOR_TYPE_SHELL,
+		ExecutorConfig: string(cfg),
+		CronExpr:       "0 0 3 * * *",
+		Concurrency:    1,
+		Enabled:        true,
+	}
+}
+
+func newTestScheduler(t *testing.T, dir string, agent *fakeAgent) *Scheduler {
+	s, err := NewScheduler(dir, agent, executor.NewRunner(agent, agent.GetClientID(), nil), nil)
+	require.NoError(t, err)
+	return s
+}
+
+func TestSchedulerApplyAndPull(t *testing.T) {
+	dir := t.TempDir()
+	agent := &fakeAgent{connected: true}
+	s := newTestScheduler(t, dir, agent)
+
+	require.NoError(t, s.Apply(&protocol.TaskConfigPush{ConfigVersion: 10, Action: protocol.ConfigAction_CONFIG_ACTION_REPLACE,
+		Tasks: []*protocol.TaskConfig{shellConfig("1", "true"), shellConfig("2", "true")}}))
+	require.NoError(t, s.Apply(&protocol.TaskConfigPush{ConfigVersion: 11, Action: protocol.ConfigAction_CONFIG_ACTION_DELETE,
+		Tasks: []*protocol.TaskConfig{{TaskId: "2"}}}))
+	// 过期的推送被忽略
+	require.NoError(t, s.Apply(&protocol.TaskConfigPush{ConfigVersion: 5, Action: protocol.ConfigAction_CONFIG_ACTION_REPLACE}))
+	assert.Equal(t, Status{ConfigVersion: 11, Tasks: 1}, s.Status())
+
+	// 重启后从磁盘恢复配置
+	s = newTestScheduler(t, dir, agent)
+	s.Start()
+	defer s.Stop()
+	assert.Equal(t, int64(11), s.Status().ConfigVersion)
+	assert.Equal(t, 1, s.Status().Tasks)
+
+	// 拉取的配置总是覆盖本地（服务器重启后版本可能变小）
+	agent.pull = &protocol.TaskConfigPullResponse{ConfigVersion: 3, HasUpdate: true,
+		Tasks: []*protocol.TaskConfig{shellConfig("7", "true"), shellConfig("8", "true")}}
+	require.NoError(t, s.Pull(context.Background()))
+	assert.Equal(t, int64(3), s.Status().ConfigVersion)
+	assert.Equal(t, 2, s.Status().Tasks)
+
+	agent.pull = &protocol.TaskConfigPullResponse{ConfigVersion: 3}
+	require.NoError(t, s.Pull(context.Background()))
+	assert.Equal(t, 2, s.Status().Tasks)
+}
+
+func TestSchedulerOfflineResultsAndStats(t *testing.T) {
+	agent := &fakeAgent{}
+	s := newTestScheduler(t, t.TempDir(), agent)
+
+	// 离线执行：结果缓存在本地
+	s.run(shellConfig("1", "echo hi"))
+	s.run(shellConfig("2", "exit 2"))
+	require.Eventually(t, func() bool { return s.Status().PendingResults == 2 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, s.Sync(context.Background()))
+	assert.Empty(t, agent.synced)
+
+	// 重连后同步结果与每日统计
+	agent.connected = true
+	require.NoError(t, s.Sync(context.Background()))
+	assert.Equal(t, 0, s.Status().PendingResults)
+	require.Len(t, agent.synced, 2)
+	byTask := map[string]*protocol.TaskResult{}
+	for _, r := range agent.synced {
+		byTask[r.TaskId] = r
+	}
+	assert.Equal(t, protocol.ExecutionStatus_EXECUTION_STATUS_SUCCESS, byTask["1"].Status)
+	assert.Equal(t, "hi\n", byTask["1"].Output)
+	assert.Equal(t, int32(2), byTask["2"].ExitCode)
+
+	require.Len(t, agent.stats, 1)
+	report := agent.stats[0]
+	assert.Equal(t, time.Now().Format(dateLayout), report.Date)
+	require.Len(t, report.TaskStats, 2)
+	assert.Equal(t, int32(1), report.TaskStats[0].SuccessCount)
+	assert.Equal(t, int32(1), report.TaskStats[1].FailureCount)
+	assert.Equal(t, int32(1), report.TaskStats[0].HourlyDistribution[time.Now().Format("15")])
+
+	// 统计已上报，无变化时不重复上报
+	require.NoError(t, s.Sync(context.Background()))
+	assert.Len(t, agent.stats, 1)
+}
+
+func TestSchedulerConcurrencyLimit(t *testing.T) {
+	agent := &fakeAgent{}
+	s := newTestScheduler(t, t.TempDir(), agent)
+
+	task := shellConfig("1", "sleep 1")
+	s.run(task)
+	s.run(task) // 上一次仍在执行，跳过
+	assert.Equal(t, 1, s.Status().Running)
+	require.Eventually(t, func() bool { return s.Status().PendingResults == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 0, s.Status().Running)
+
+	// 不支持的执行器记录为失败
+	s.run(&protocol.TaskConfig{TaskId: "9", ExecutorType: protocol.ExecutorType_EXECUTOR_TYPE_PLUGIN})
+	assert.Equal(t, 2, s.Status().PendingResults)
+}
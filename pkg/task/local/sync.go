@@ -0,0 +1,311 @@
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/protocol"
+)
+
+// 同步参数
+const (
+	syncInterval  = 30 * time.Second // 后台同步间隔
+	syncTimeout   = 30 * time.Second // 单次同步超时
+	syncBatchSize = 100              // 每次查询同步的结果数
+	statsDays     = 7                // 本地保留的统计天数
+
+	dateLayout = "2006-01-02"
+)
+
+// savedStats stats.json 的内容
+type savedStats struct {
+	Days  map[string]map[string]*protocol.TaskStats `json:"days"`
+	Dirty []string                                  `json:"dirty"`
+}
+
+// Trigger 请求尽快同步（不阻塞）
+func (s *Scheduler) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// syncLoop 定期及按需同步执行结果与统计
+func (s *Scheduler) syncLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+		case <-s.trigger:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+		err := s.Sync(ctx)
+		cancel()
+		if err != nil {
+			s.logger.Warn("Failed to sync local task results", "error", err)
+		}
+	}
+}
+
+// Sync 在线时将缓存的执行结果分批同步到服务器，再上报有变化的每日统计
+// 服务器确认后才删除本地结果，重传的结果由服务器去重
+func (s *Scheduler) Sync(ctx context.Context) error {
+	if !s.agent.IsConnected() {
+		return nil
+	}
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	err := s.syncResults(ctx)
+	if err == nil {
+		err = s.reportStats(ctx)
+	}
+
+	s.statsMu.Lock()
+	if err != nil {
+		s.syncErr = err.Error()
+	} else {
+		s.syncErr = ""
+	}
+	s.statsMu.Unlock()
+	return err
+}
+
+// syncResults 同步缓存的执行结果
+func (s *Scheduler) syncResults(ctx context.Context) error {
+	for {
+		seqs, msgs := s.results.PeekBatch(syncBatchSize)
+		if len(msgs) == 0 {
+			return nil
+		}
+		results := make([]*protocol.TaskResult, 0, len(msgs))
+		for _, msg := range msgs {
+			var r protocol.TaskResult
+			if err := json.Unmarshal(msg.Payload, &r); err != nil {
+				s.logger.Warn("Dropping unreadable local task result", "msg_id", msg.MsgId, "error", err)
+				continue
+			}
+			results = append(results, &r)
+		}
+
+		s.statsMu.Lock()
+		lastSync := s.lastSync
+		s.statsMu.Unlock()
+		var lastSyncMs int64
+		if !lastSync.IsZero() {
+			lastSyncMs = lastSync.UnixMilli()
+		}
+		raw, err := s.agent.Query(ctx, command.QueryTaskOfflineSync, &command.TaskOfflineSync{
+			Request: &protocol.OfflineSyncRequest{
+				ClientId:     s.agent.GetClientID(),
+				LastSyncTime: lastSyncMs,
+				RecordCount:  int32(len(results)),
+			},
+			Results: results,
+		})
+		if err != nil {
+			return fmt.Errorf("sync task results: %w", err)
+		}
+		var resp protocol.OfflineSyncResponse
+		if err := json.Unmarshal(raw, &resp); err != nil {
+			return fmt.Errorf("invalid sync response: %w", err)
+		}
+		if !resp.Success {
+			return fmt.Errorf("sync task results rejected: %s", resp.Message)
+		}
+
+		for _, seq := range seqs {
+			s.results.Remove(seq)
+		}
+		s.statsMu.Lock()
+		s.lastSync = time.Now()
+		s.statsMu.Unlock()
+		s.logger.Info("Local task results synced", "count", len(results), "synced", resp.SyncedCount)
+	}
+}
+
+// reportStats 以 report.task_stats 事件上报有变化的每日统计
+func (s *Scheduler) reportStats(ctx context.Context) error {
+	s.statsMu.Lock()
+	var reports []*protocol.DailyStatsReport
+	for date := range s.dirty {
+		report := &protocol.DailyStatsReport{
+			ClientId:   s.agent.GetClientID(),
+			Date:       date,
+			ReportTime: time.Now().UnixMilli(),
+		}
+		for _, st := range s.stats[date] {
+			report.TaskStats = append(report.TaskStats, st)
+		}
+		sort.Slice(report.TaskStats, func(i, j int) bool { return report.TaskStats[i].TaskId < report.TaskStats[j].TaskId })
+		reports = append(reports, report)
+	}
+	s.statsMu.Unlock()
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Date < reports[j].Date })
+
+	for _, report := range reports {
+		data, err := json.Marshal(report)
+		if err != nil {
+			return err
+		}
+		body, err := json.Marshal(command.CommandPayload{CommandType: command.EventTaskStats, Payload: data})
+		if err != nil {
+			return err
+		}
+		msg := &protocol.DataMessage{
+			MsgId:     uuid.New().String(),
+			SenderId:  s.agent.GetClientID(),
+			Type:      protocol.MessageType_MESSAGE_TYPE_EVENT,
+			Payload:   body,
+			Timestamp: time.Now().UnixMilli(),
+		}
+		if _, err := s.agent.SendMessage(ctx, msg, false, 0); err != nil {
+			return fmt.Errorf("report task stats: %w", err)
+		}
+
+		// 发送期间有新的执行时保留待上报标记
+		s.statsMu.Lock()
+		if sameStats(report.TaskStats, s.stats[report.Date]) {
+			delete(s.dirty, report.Date)
+		}
+		s.statsMu.Unlock()
+	}
+	if len(reports) > 0 {
+		return s.saveStats()
+	}
+	return nil
+}
+
+// sameStats 上报的统计是否仍是最新
+func sameStats(reported []*protocol.TaskStats, current map[string]*protocol.TaskStats) bool {
+	if len(reported) != len(current) {
+		return false
+	}
+	for _, st := range reported {
+		cur, ok := current[st.TaskId]
+		if !ok || cur != st {
+			return false
+		}
+	}
+	return true
+}
+
+// saveResult 将执行结果写入本地缓存
+func (s *Scheduler) saveResult(result *protocol.TaskResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return s.results.Enqueue(&protocol.DataMessage{
+		MsgId:     result.ExecutionId,
+		Type:      protocol.MessageType_MESSAGE_TYPE_EVENT,
+		Payload:   data,
+		Timestamp: result.Timestamp,
+	})
+}
+
+// recordStats 将执行结果计入当日统计
+// 统计对象在每次变化时替换（而非原地修改），上报时据此判断是否有新的执行
+func (s *Scheduler) recordStats(taskName string, result *protocol.TaskResult) {
+	end := time.UnixMilli(result.Timestamp)
+	date := end.Format(dateLayout)
+
+	s.statsMu.Lock()
+	day := s.stats[date]
+	if day == nil {
+		day = make(map[string]*protocol.TaskStats)
+		s.stats[date] = day
+	}
+	st := &protocol.TaskStats{TaskId: result.TaskId, HourlyDistribution: make(map[string]int32)}
+	if old := day[result.TaskId]; old != nil {
+		// 逐字段复制：protobuf 消息含内部状态，不能整体赋值
+		st.SuccessCount = old.SuccessCount
+		st.FailureCount = old.FailureCount
+		st.TimeoutCount = old.TimeoutCount
+		st.CancelledCount = old.CancelledCount
+		st.TotalDurationMs = old.TotalDurationMs
+		st.MaxDurationMs = old.MaxDurationMs
+		st.MinDurationMs = old.MinDurationMs
+		for h, n := range old.HourlyDistribution {
+			st.HourlyDistribution[h] = n
+		}
+	}
+	st.TaskName = taskName
+
+	switch result.Status {
+	case protocol.ExecutionStatus_EXECUTION_STATUS_SUCCESS:
+		st.SuccessCount++
+	case protocol.ExecutionStatus_EXECUTION_STATUS_TIMEOUT:
+		st.TimeoutCount++
+	case protocol.ExecutionStatus_EXECUTION_STATUS_CANCELLED:
+		st.CancelledCount++
+	default:
+		st.FailureCount++
+	}
+	duration := int32(result.DurationMs)
+	first := st.SuccessCount+st.FailureCount+st.TimeoutCount+st.CancelledCount == 1
+	st.TotalDurationMs += result.DurationMs
+	if first || duration > st.MaxDurationMs {
+		st.MaxDurationMs = duration
+	}
+	if first || duration < st.MinDurationMs {
+		st.MinDurationMs = duration
+	}
+	st.HourlyDistribution[fmt.Sprintf("%02d", end.Hour())]++
+	day[result.TaskId] = st
+	s.dirty[date] = true
+
+	// 只保留最近 statsDays 天
+	cutoff := time.Now().AddDate(0, 0, -(statsDays - 1)).Format(dateLayout)
+	for d := range s.stats {
+		if d < cutoff {
+			delete(s.stats, d)
+			delete(s.dirty, d)
+		}
+	}
+	s.statsMu.Unlock()
+
+	if err := s.saveStats(); err != nil {
+		s.logger.Warn("Failed to save local task stats", "error", err)
+	}
+}
+
+// loadStats 加载本地统计
+func (s *Scheduler) loadStats() error {
+	var saved savedStats
+	if err := readJSON(filepath.Join(s.dir, statsFile), &saved); err != nil {
+		return fmt.Errorf("local scheduler: load stats: %w", err)
+	}
+	for date, day := range saved.Days {
+		s.stats[date] = day
+	}
+	for _, date := range saved.Dirty {
+		if s.stats[date] != nil {
+			s.dirty[date] = true
+		}
+	}
+	return nil
+}
+
+// saveStats 持久化本地统计
+func (s *Scheduler) saveStats() error {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	saved := savedStats{Days: s.stats}
+	for date := range s.dirty {
+		saved.Dirty = append(saved.Dirty, date)
+	}
+	sort.Strings(saved.Dirty)
+	return writeJSON(filepath.Join(s.dir, statsFile), saved)
+}
@@ -10,7 +10,7 @@ import (
 type Client struct {
 	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
 	ClientID     string    `gorm:"size:64;uniqueIndex;not null;comment:客户端ID" json:"client_id"`
-	GroupID      *int64    `gorm:"index:idx_group_id;comment:所属分组ID" json:"group_id,omitempty"`
+	GroupID      *int64    `gorm:"index:idx_group_id;comment:所属分组ID(已废弃,分组成员见 tb_client_group_relation)" json:"group_id,omitempty"`
 	Hostname     string    `gorm:"size:128;comment:主机名" json:"hostname"`
 	IP           string    `gorm:"size:64;comment:IP地址" json:"ip"`
 	TaskVersion  int64     `gorm:"not null;default:0;comment:任务配置版本" json:"task_version"`
@@ -27,3 +27,16 @@ type Client struct {
 func (Client) TableName() string {
 	return "tb_client"
 }
+
+// ClientGroupRelation 客户端与静态分组的成员关系（一个客户端可属于多个分组）
+type ClientGroupRelation struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	ClientID  string    `gorm:"size:64;not null;uniqueIndex:idx_client_group;comment:客户端ID(tb_client.client_id)" json:"client_id"`
+	GroupID   int64     `gorm:"not null;uniqueIndex:idx_client_group;index:idx_client_group_group;comment:分组ID" json:"group_id"`
+	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (ClientGroupRelation) TableName() string {
+	return "tb_client_group_relation"
+}
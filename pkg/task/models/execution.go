@@ -24,6 +24,7 @@ const (
 	ExecutionStatusFailed    ExecutionStatus = 4 // 失败
 	ExecutionStatusTimeout   ExecutionStatus = 5 // 超时
 	ExecutionStatusCancelled ExecutionStatus = 6 // 已取消
+	ExecutionStatusQueued    ExecutionStatus = 7 // 排队中（等待同一客户端上的上一次执行结束）
 )
 
 // Execution 任务执行记录表
@@ -34,14 +35,18 @@ type Execution struct {
 	ClientID      string         `gorm:"size:64;not null;index:idx_client_id;comment:客户端ID" json:"client_id"`
 	GroupID       *int64         `gorm:"index:idx_group_id;comment:分组ID" json:"group_id,omitempty"`
 	ExecutionType ExecutionType  `gorm:"not null;default:1;comment:执行类型:1=定时,2=手动" json:"execution_type"`
-	Status        ExecutionStatus `gorm:"not null;index:idx_status;comment:状态:1=Pending,2=Running,3=Success,4=Failed,5=Timeout,6=Cancelled" json:"status"`
+	Status        ExecutionStatus `gorm:"not null;index:idx_status;comment:状态:1=Pending,2=Running,3=Success,4=Failed,5=Timeout,6=Cancelled,7=Queued" json:"status"`
 	StartTime     *time.Time     `gorm:"index:idx_start_time;comment:开始时间" json:"start_time,omitempty"`
 	EndTime       *time.Time     `gorm:"comment:结束时间" json:"end_time,omitempty"`
 	Duration      int            `gorm:"comment:执行耗时(毫秒)" json:"duration"`
 	ExitCode      int            `gorm:"comment:退出码" json:"exit_code"`
 	Output        string         `gorm:"type:text;comment:执行输出" json:"output"`
+	OutputPath    string         `gorm:"size:512;comment:完整输出在文件存储中的路径" json:"output_path,omitempty"`
+	OutputSize    int            `gorm:"not null;default:0;comment:完整输出大小(字节)" json:"output_size"`
 	ErrorMsg      string         `gorm:"type:text;comment:错误信息" json:"error_msg"`
 	RetryCount    int            `gorm:"not null;default:0;comment:重试次数" json:"retry_count"`
+	WorkflowRunID *int64         `gorm:"index:idx_workflow_run;comment:工作流运行ID" json:"workflow_run_id,omitempty"`
+	WorkflowNode  string         `gorm:"size:64;comment:工作流节点" json:"workflow_node,omitempty"`
 	CreatedAt     time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP;index" json:"created_at"`
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
 
@@ -54,6 +59,16 @@ func (Execution) TableName() string {
 	return "tb_execution"
 }
 
+// ExecutionLogChunk 执行日志的增量片段（实时日志推送）
+// Offset 为片段在完整输出中的字节偏移；Finished 表示执行已结束，之后不再有新的片段
+type ExecutionLogChunk struct {
+	ExecutionID int64           `json:"execution_id"`
+	Offset      int             `json:"offset"`
+	Output      string          `json:"output"`
+	Finished    bool            `json:"finished"`
+	Status      ExecutionStatus `json:"status"`
+}
+
 // IsFinished 检查执行是否已完成
 func (e *Execution) IsFinished() bool {
 	return e.Status == ExecutionStatusSuccess ||
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
 	"time"
 
 	"gorm.io/gorm"
@@ -12,6 +16,7 @@ type TaskGroup struct {
 	Name        string    `gorm:"size:64;uniqueIndex;not null;comment:分组名称" json:"name"`
 	Description string    `gorm:"size:256;comment:分组描述" json:"description"`
 	Tags        string    `gorm:"size:256;comment:标签(逗号分隔)" json:"tags"`
+	Selector    *GroupSelector `gorm:"type:text;comment:动态分组选择器(JSON,为空时为静态分组)" json:"selector,omitempty"`
 	CreatedAt   time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt   time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
@@ -25,6 +30,63 @@ func (TaskGroup) TableName() string {
 	return "tb_task_group"
 }
 
+// IsDynamic 是否为动态分组：成员在下发时按选择器从在线客户端中筛选
+func (g *TaskGroup) IsDynamic() bool {
+	return g.Selector != nil
+}
+
+// GroupSelector 动态分组选择器，所有条件同时满足的在线客户端属于该分组，空选择器匹配所有在线客户端
+type GroupSelector struct {
+	Labels          map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`                     // Agent 配置中的标签（全部匹配）
+	OS              string            `json:"os,omitempty" yaml:"os,omitempty"`                             // 操作系统，如 linux（不区分大小写）
+	Arch            string            `json:"arch,omitempty" yaml:"arch,omitempty"`                         // 架构，如 x86_64（不区分大小写）
+	MinCPU          int               `json:"min_cpu,omitempty" yaml:"min_cpu,omitempty"`                   // 最少 CPU 线程数
+	MinMemoryGB     float64           `json:"min_memory_gb,omitempty" yaml:"min_memory_gb,omitempty"`       // 最少内存（GB）
+	Hostname        string            `json:"hostname,omitempty" yaml:"hostname,omitempty"`                 // 主机名正则
+	ContainerPrefix string            `json:"container_prefix,omitempty" yaml:"container_prefix,omitempty"` // 存在名称以该前缀开头的运行中容器
+}
+
+// Validate 校验选择器
+func (s *GroupSelector) Validate() error {
+	if s.MinCPU < 0 || s.MinMemoryGB < 0 {
+		return fmt.Errorf("min_cpu and min_memory_gb must not be negative")
+	}
+	if s.Hostname != "" {
+		if _, err := regexp.Compile(s.Hostname); err != nil {
+			return fmt.Errorf("invalid hostname pattern: %w", err)
+		}
+	}
+	return nil
+}
+
+// NeedsHardware 是否包含硬件条件（需要客户端上报过硬件信息）
+func (s *GroupSelector) NeedsHardware() bool {
+	return s.OS != "" || s.Arch != "" || s.MinCPU > 0 || s.MinMemoryGB > 0 || s.Hostname != ""
+}
+
+// Value 实现 driver.Valuer 接口
+func (s GroupSelector) Value() (driver.Value, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现 sql.Scanner 接口
+func (s *GroupSelector) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return fmt.Errorf("unsupported selector type %T", value)
+	}
+}
+
 // TaskGroupRelation 任务分组关联表
 type TaskGroupRelation struct {
 	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
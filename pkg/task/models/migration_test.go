@@ -24,16 +24,49 @@ func TestMigrate(t *testing.T) {
 	assert.True(t, db.Migrator().HasTable("tb_task_group_relation")) // many2many 关联表
 	assert.True(t, db.Migrator().HasTable(&Execution{}))
 	assert.True(t, db.Migrator().HasTable(&Client{}))
+	assert.True(t, db.Migrator().HasTable(&ClientGroupRelation{}))
+	assert.True(t, db.Migrator().HasTable(&TaskDailyStats{}))
+	assert.True(t, db.Migrator().HasTable(&Workflow{}))
+	assert.True(t, db.Migrator().HasTable(&WorkflowNode{}))
+	assert.True(t, db.Migrator().HasTable(&WorkflowEdge{}))
+	assert.True(t, db.Migrator().HasTable(&WorkflowRun{}))
+	assert.True(t, db.Migrator().HasTable(&WorkflowRunNode{}))
 }
 
 func TestAllModels(t *testing.T) {
 	// 验证所有模型都已注册
 	assert.NotEmpty(t, AllModels)
-	assert.Len(t, AllModels, 4) // TaskGroupRelation 由 many2many 自动管理
+	assert.Len(t, AllModels, 11) // TaskGroupRelation 由 many2many 自动管理
 
 	// 验证模型类型
 	assert.Contains(t, AllModels, &Task{})
 	assert.Contains(t, AllModels, &TaskGroup{})
 	assert.Contains(t, AllModels, &Execution{})
 	assert.Contains(t, AllModels, &Client{})
+	assert.Contains(t, AllModels, &ClientGroupRelation{})
+	assert.Contains(t, AllModels, &TaskDailyStats{})
+	assert.Contains(t, AllModels, &Workflow{})
+	assert.Contains(t, AllModels, &WorkflowRun{})
+}
+
+func TestMigrateClientGroups(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	assert.NoError(t, Migrate(db))
+
+	// 旧版本通过 tb_client.group_id 记录的分组归属迁移到成员关系表，重复迁移不产生重复记录
+	groupID := int64(7)
+	assert.NoError(t, db.Create(&Client{ClientID: "web-1", GroupID: &groupID}).Error)
+	assert.NoError(t, db.Create(&Client{ClientID: "web-2"}).Error)
+	assert.NoError(t, Migrate(db))
+	assert.NoError(t, Migrate(db))
+
+	var relations []ClientGroupRelation
+	assert.NoError(t, db.Find(&relations).Error)
+	if assert.Len(t, relations, 1) {
+		assert.Equal(t, "web-1", relations[0].ClientID)
+		assert.Equal(t, groupID, relations[0].GroupID)
+	}
 }
@@ -14,6 +14,13 @@ var AllModels = []interface{}{
 	&TaskGroup{},
 	&Execution{},
 	&Client{},
+	&ClientGroupRelation{},
+	&TaskDailyStats{},
+	&Workflow{},
+	&WorkflowNode{},
+	&WorkflowEdge{},
+	&WorkflowRun{},
+	&WorkflowRunNode{},
 }
 
 // Migrate 执行数据库迁移
@@ -69,6 +76,10 @@ func Migrate(db *gorm.DB) error {
 		}
 	}
 
+	if err := migrateClientGroups(db); err != nil {
+		return fmt.Errorf("failed to migrate client groups: %w", err)
+	}
+
 	// 创建额外索引（仅对 PostgreSQL 和 MySQL）
 	// 注意：GORM AutoMigrate 会自动创建单列索引，这里只创建复合索引
 	// 对于 SQLite，跳过索引创建（测试环境）
@@ -79,6 +90,14 @@ func Migrate(db *gorm.DB) error {
 	return nil
 }
 
+// migrateClientGroups 将旧版 tb_client.group_id 的分组归属写入成员关系表（已存在的关系跳过）
+func migrateClientGroups(db *gorm.DB) error {
+	return db.Exec(`INSERT INTO tb_client_group_relation (client_id, group_id, created_at)
+		SELECT c.client_id, c.group_id, CURRENT_TIMESTAMP FROM tb_client c
+		WHERE c.group_id IS NOT NULL AND c.deleted_at IS NULL AND NOT EXISTS (
+			SELECT 1 FROM tb_client_group_relation r WHERE r.client_id = c.client_id AND r.group_id = c.group_id)`).Error
+}
+
 // detectDBType 检测数据库类型
 func detectDBType(db *gorm.DB) string {
 	dialectorName := db.Dialector.Name()
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// TaskDailyStats Agent 本地调度任务的每日统计（由 Agent 上报 DailyStatsReport 汇总）
+// 同一客户端、日期、任务只保留一条，重复上报时覆盖
+type TaskDailyStats struct {
+	ID                 int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	ClientID           string    `gorm:"size:64;not null;uniqueIndex:idx_daily_stats_key;comment:客户端ID" json:"client_id"`
+	Date               string    `gorm:"size:10;not null;uniqueIndex:idx_daily_stats_key;index;comment:统计日期(YYYY-MM-DD)" json:"date"`
+	TaskID             int64     `gorm:"not null;uniqueIndex:idx_daily_stats_key;index;comment:任务ID" json:"task_id"`
+	TaskName           string    `gorm:"size:128;comment:任务名称(冗余)" json:"task_name"`
+	SuccessCount       int       `gorm:"not null;default:0;comment:成功次数" json:"success_count"`
+	FailureCount       int       `gorm:"not null;default:0;comment:失败次数" json:"failure_count"`
+	TimeoutCount       int       `gorm:"not null;default:0;comment:超时次数" json:"timeout_count"`
+	CancelledCount     int       `gorm:"not null;default:0;comment:取消次数" json:"cancelled_count"`
+	TotalDuration      int64     `gorm:"not null;default:0;comment:总耗时(毫秒)" json:"total_duration"`
+	MaxDuration        int       `gorm:"not null;default:0;comment:最大耗时(毫秒)" json:"max_duration"`
+	MinDuration        int       `gorm:"not null;default:0;comment:最小耗时(毫秒)" json:"min_duration"`
+	HourlyDistribution string    `gorm:"type:text;comment:小时分布(JSON)" json:"hourly_distribution"`
+	ReportTime         time.Time `gorm:"comment:上报时间" json:"report_time"`
+	CreatedAt          time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt          time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (TaskDailyStats) TableName() string {
+	return "tb_task_daily_stats"
+}
+
+// TotalCount 执行总次数
+func (s *TaskDailyStats) TotalCount() int {
+	return s.SuccessCount + s.FailureCount + s.TimeoutCount + s.CancelledCount
+}
@@ -23,6 +23,24 @@ const (
 	TaskStatusEnabled  TaskStatus = 1 // 启用
 )
 
+// OverlapPolicy 重叠策略：同一任务在同一客户端上的执行数已达并发上限时如何处理新的执行
+type OverlapPolicy string
+
+const (
+	OverlapSkip  OverlapPolicy = "skip"  // 跳过本次执行
+	OverlapQueue OverlapPolicy = "queue" // 排队，等上一次执行结束后下发
+	OverlapKill  OverlapPolicy = "kill"  // 取消正在执行的上一次执行，立即下发
+)
+
+// MisfirePolicy 错过策略：服务器停机期间错过的定时触发在启动后如何补偿
+type MisfirePolicy string
+
+const (
+	MisfireSkip     MisfirePolicy = "skip"      // 不补偿
+	MisfireFireOnce MisfirePolicy = "fire_once" // 补偿一次
+	MisfireFireAll  MisfirePolicy = "fire_all"  // 每次错过的触发都补偿（有上限）
+)
+
 // Task 定时任务表
 type Task struct {
 	ID             int64       `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -35,7 +53,15 @@ type Task struct {
 	RetryCount     int         `gorm:"not null;default:0;comment:重试次数" json:"retry_count"`
 	RetryInterval  int         `gorm:"not null;default:60;comment:重试间隔(秒)" json:"retry_interval"`
 	Concurrency    int         `gorm:"not null;default:1;comment:最大并发数" json:"concurrency"`
+	OverlapPolicy  OverlapPolicy `gorm:"size:16;not null;default:skip;comment:重叠策略:skip,queue,kill" json:"overlap_policy"`
+	MisfirePolicy  MisfirePolicy `gorm:"size:16;not null;default:skip;comment:错过策略:skip,fire_once,fire_all" json:"misfire_policy"`
+	Jitter         int         `gorm:"not null;default:0;comment:随机延迟上限(秒)" json:"jitter"`
+	Spread         int         `gorm:"not null;default:0;comment:分散窗口(秒)" json:"spread"`
+	LastFireTime   *time.Time  `gorm:"comment:最近一次定时触发时间" json:"last_fire_time,omitempty"`
+	RetainRuns     int         `gorm:"not null;default:0;comment:保留最近的执行记录数(0=不限)" json:"retain_runs"`
+	RetainDays     int         `gorm:"not null;default:0;comment:执行记录保留天数(0=不限)" json:"retain_days"`
 	Status         TaskStatus  `gorm:"not null;default:1;index;comment:状态:0=禁用,1=启用" json:"status"`
+	LocalSchedule  bool        `gorm:"not null;default:false;comment:是否由Agent本地调度" json:"local_schedule"`
 	CreatedBy      string      `gorm:"size:64;comment:创建人" json:"created_by"`
 	CreatedAt      time.Time   `gorm:"not null;default:CURRENT_TIMESTAMP;index" json:"created_at"`
 	UpdatedAt      time.Time   `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
@@ -50,6 +76,35 @@ func (Task) TableName() string {
 	return "tb_task"
 }
 
+// GetOverlapPolicy 获取重叠策略，未设置时为 skip
+func (t *Task) GetOverlapPolicy() OverlapPolicy {
+	if t.OverlapPolicy == "" {
+		return OverlapSkip
+	}
+	return t.OverlapPolicy
+}
+
+// GetMisfirePolicy 获取错过策略，未设置时为 skip
+func (t *Task) GetMisfirePolicy() MisfirePolicy {
+	if t.MisfirePolicy == "" {
+		return MisfireSkip
+	}
+	return t.MisfirePolicy
+}
+
+// GetConcurrency 获取单个客户端上的最大并发数，未设置时为 1
+func (t *Task) GetConcurrency() int {
+	if t.Concurrency <= 0 {
+		return 1
+	}
+	return t.Concurrency
+}
+
+// HasRetention 是否设置了执行记录保留策略
+func (t *Task) HasRetention() bool {
+	return t.RetainRuns > 0 || t.RetainDays > 0
+}
+
 // IsEnabled 检查任务是否启用
 func (t *Task) IsEnabled() bool {
 	return t.Status == TaskStatusEnabled
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EdgeCondition 工作流边的触发条件
+type EdgeCondition string
+
+const (
+	EdgeOnSuccess EdgeCondition = "on_success" // 上游成功
+	EdgeOnFailure EdgeCondition = "on_failure" // 上游失败（含超时、取消）
+	EdgeAlways    EdgeCondition = "always"     // 上游结束即触发（含跳过）
+)
+
+// EdgeScope 工作流边的客户端范围
+type EdgeScope string
+
+const (
+	// EdgeScopeSameClient 按客户端串联：上游在某客户端上的执行满足条件后，下游在同一客户端上执行
+	EdgeScopeSameClient EdgeScope = "same_client"
+	// EdgeScopeAny 按节点汇总：上游在所有客户端上结束后按整体结果判断，下游在其任务分组的在线客户端上执行
+	EdgeScopeAny EdgeScope = "any"
+)
+
+// Workflow 任务工作流表：由任务节点与条件边组成的 DAG
+type Workflow struct {
+	ID          int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string         `gorm:"size:128;uniqueIndex;not null;comment:工作流名称" json:"name"`
+	Description string         `gorm:"size:512;comment:工作流描述" json:"description"`
+	CronExpr    string         `gorm:"size:64;comment:Cron表达式(为空时只能手动触发)" json:"cron_expr"`
+	Status      TaskStatus     `gorm:"not null;default:1;index;comment:状态:0=禁用,1=启用" json:"status"`
+	CreatedBy   string         `gorm:"size:64;comment:创建人" json:"created_by"`
+	CreatedAt   time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP;index" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// 关联关系
+	Nodes []WorkflowNode `gorm:"foreignKey:WorkflowID" json:"nodes"`
+	Edges []WorkflowEdge `gorm:"foreignKey:WorkflowID" json:"edges"`
+}
+
+// TableName 指定表名
+func (Workflow) TableName() string {
+	return "tb_workflow"
+}
+
+// IsEnabled 检查工作流是否启用
+func (w *Workflow) IsEnabled() bool {
+	return w.Status == TaskStatusEnabled
+}
+
+// WorkflowNode 工作流节点表：节点名在工作流内唯一，每个节点执行一个任务
+type WorkflowNode struct {
+	ID         int64  `gorm:"primaryKey;autoIncrement" json:"-"`
+	WorkflowID int64  `gorm:"not null;index;comment:工作流ID" json:"-"`
+	Name       string `gorm:"size:64;not null;comment:节点名称" json:"name"`
+	TaskID     int64  `gorm:"not null;comment:任务ID" json:"task_id"`
+}
+
+// TableName 指定表名
+func (WorkflowNode) TableName() string {
+	return "tb_workflow_node"
+}
+
+// WorkflowEdge 工作流边表：From 节点结束后按条件触发 To 节点
+type WorkflowEdge struct {
+	ID         int64         `gorm:"primaryKey;autoIncrement" json:"-"`
+	WorkflowID int64         `gorm:"not null;index;comment:工作流ID" json:"-"`
+	From       string        `gorm:"column:from_node;size:64;not null;comment:上游节点" json:"from"`
+	To         string        `gorm:"column:to_node;size:64;not null;comment:下游节点" json:"to"`
+	Condition  EdgeCondition `gorm:"size:16;not null;default:on_success;comment:触发条件" json:"condition"`
+	Scope      EdgeScope     `gorm:"size:16;not null;default:same_client;comment:客户端范围" json:"scope"`
+}
+
+// TableName 指定表名
+func (WorkflowEdge) TableName() string {
+	return "tb_workflow_edge"
+}
+
+// WorkflowRunStatus 工作流运行状态
+type WorkflowRunStatus int
+
+const (
+	WorkflowRunStatusRunning WorkflowRunStatus = 1 // 运行中
+	WorkflowRunStatusSuccess WorkflowRunStatus = 2 // 成功
+	WorkflowRunStatusFailed  WorkflowRunStatus = 3 // 失败
+)
+
+// WorkflowNodeStatus 工作流运行中节点的状态
+type WorkflowNodeStatus int
+
+const (
+	WorkflowNodeStatusWaiting WorkflowNodeStatus = 1 // 等待上游
+	WorkflowNodeStatusRunning WorkflowNodeStatus = 2 // 执行中
+	WorkflowNodeStatusSuccess WorkflowNodeStatus = 3 // 全部执行成功
+	WorkflowNodeStatusFailed  WorkflowNodeStatus = 4 // 有执行失败
+	WorkflowNodeStatusSkipped WorkflowNodeStatus = 5 // 条件不满足或没有目标客户端
+)
+
+// IsFinished 节点是否已结束
+func (s WorkflowNodeStatus) IsFinished() bool {
+	return s == WorkflowNodeStatusSuccess || s == WorkflowNodeStatusFailed || s == WorkflowNodeStatusSkipped
+}
+
+// WorkflowDefinition 工作流定义快照（运行按启动时的定义推进，不受之后的修改影响）
+type WorkflowDefinition struct {
+	Nodes []WorkflowNode `json:"nodes"`
+	Edges []WorkflowEdge `json:"edges"`
+}
+
+// WorkflowRun 工作流运行记录表，各节点的执行记录通过 Execution.WorkflowRunID 关联
+type WorkflowRun struct {
+	ID           int64             `gorm:"primaryKey;autoIncrement" json:"id"`
+	WorkflowID   int64             `gorm:"not null;index;comment:工作流ID" json:"workflow_id"`
+	WorkflowName string            `gorm:"size:128;comment:工作流名称(冗余)" json:"workflow_name"`
+	TriggerType  ExecutionType     `gorm:"not null;default:1;comment:触发类型:1=定时,2=手动" json:"trigger_type"`
+	Status       WorkflowRunStatus `gorm:"not null;index;comment:状态:1=Running,2=Success,3=Failed" json:"status"`
+	Definition   string            `gorm:"type:text;comment:定义快照(JSON)" json:"-"`
+	StartTime    time.Time         `gorm:"not null;comment:开始时间" json:"start_time"`
+	EndTime      *time.Time        `gorm:"comment:结束时间" json:"end_time,omitempty"`
+	CreatedAt    time.Time         `gorm:"not null;default:CURRENT_TIMESTAMP;index" json:"created_at"`
+
+	// 关联关系
+	Nodes []WorkflowRunNode `gorm:"foreignKey:RunID" json:"nodes,omitempty"`
+}
+
+// TableName 指定表名
+func (WorkflowRun) TableName() string {
+	return "tb_workflow_run"
+}
+
+// IsFinished 运行是否已结束
+func (r *WorkflowRun) IsFinished() bool {
+	return r.Status == WorkflowRunStatusSuccess || r.Status == WorkflowRunStatusFailed
+}
+
+// WorkflowRunNode 工作流运行中各节点的状态
+type WorkflowRunNode struct {
+	ID        int64              `gorm:"primaryKey;autoIncrement" json:"-"`
+	RunID     int64              `gorm:"not null;index;comment:运行ID" json:"-"`
+	Node      string             `gorm:"size:64;not null;comment:节点名称" json:"node"`
+	TaskID    int64              `gorm:"not null;comment:任务ID" json:"task_id"`
+	TaskName  string             `gorm:"size:128;comment:任务名称(冗余)" json:"task_name"`
+	Status    WorkflowNodeStatus `gorm:"not null;comment:状态:1=Waiting,2=Running,3=Success,4=Failed,5=Skipped" json:"status"`
+	Message   string             `gorm:"size:512;comment:说明(如跳过原因)" json:"message,omitempty"`
+	StartTime *time.Time         `gorm:"comment:开始时间" json:"start_time,omitempty"`
+	EndTime   *time.Time         `gorm:"comment:结束时间" json:"end_time,omitempty"`
+}
+
+// TableName 指定表名
+func (WorkflowRunNode) TableName() string {
+	return "tb_workflow_run_node"
+}
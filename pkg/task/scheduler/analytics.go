@@ -0,0 +1,504 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/task/models"
+	"github.com/voilet/quic-flow/pkg/task/store"
+)
+
+const (
+	// analyticsSchedule 任务健康报告的刷新周期（每 5 分钟）
+	analyticsSchedule = "0 */5 * * * *"
+	// healthWindow 客户端异常与耗时突增的统计范围
+	healthWindow = 7 * 24 * time.Hour
+	// maxTrendDays 趋势最多统计的天数
+	maxTrendDays = 30
+
+	// outlierMinRuns 客户端参与异常判断所需的最少执行次数
+	outlierMinRuns = 3
+	// failingRate 成功率低于该值的客户端视为持续失败
+	failingRate = 0.5
+	// failingStreak 最近连续失败达到该次数的客户端视为持续失败
+	failingStreak = 3
+	// slowRatio 耗时中位数达到其他客户端中位数的该倍数视为明显偏慢
+	slowRatio = 2.0
+
+	// anomalyRecentRuns 判断耗时突增时取最近的成功执行数
+	anomalyRecentRuns = 3
+	// anomalyMinBaseline 滚动基线所需的最少成功执行数
+	anomalyMinBaseline = 10
+	// anomalyRatio 最近耗时中位数达到基线中位数的该倍数视为突增
+	anomalyRatio = 2.0
+
+	// minDurationDelta 偏慢与突增要求的最小耗时差（毫秒），避免短任务的抖动被标记
+	minDurationDelta = 1000
+)
+
+// analyticsWindows 成功率统计的时间窗口（由短到长）
+var analyticsWindows = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", maxTrendDays * 24 * time.Hour},
+}
+
+// RunStats 一组已结束执行的统计：成功率不计已取消的执行，耗时只统计成功的执行
+type RunStats struct {
+	Total       int     `json:"total"`
+	Success     int     `json:"success"`
+	Failed      int     `json:"failed"` // 失败与超时
+	Cancelled   int     `json:"cancelled"`
+	SuccessRate float64 `json:"success_rate"` // 0-1，没有成功或失败的执行时为 0
+	P50Duration int     `json:"p50_duration"` // 耗时中位数（毫秒）
+	P95Duration int     `json:"p95_duration"` // 耗时 P95（毫秒）
+	AvgDuration int     `json:"avg_duration"` // 平均耗时（毫秒）
+	MaxDuration int     `json:"max_duration"` // 最大耗时（毫秒）
+}
+
+// WindowStats 最近一段时间的执行统计
+type WindowStats struct {
+	Window string `json:"window"` // 24h、7d、30d
+	RunStats
+}
+
+// TrendPoint 按天统计的趋势
+type TrendPoint struct {
+	Date string `json:"date"` // 2006-01-02
+	RunStats
+}
+
+// ClientHealth 任务在单个客户端上最近 7 天的执行情况
+type ClientHealth struct {
+	ClientID string `json:"client_id"`
+	RunStats
+	ConsecutiveFailures int     `json:"consecutive_failures"` // 最近连续失败（含超时）次数
+	SlowRatio           float64 `json:"slow_ratio"`           // 耗时中位数与其他客户端中位数之比（无可比较的客户端时为 0）
+	Failing             bool    `json:"failing"`              // 持续失败：成功率过低或连续失败
+	Slow                bool    `json:"slow"`                 // 明显慢于其他客户端
+}
+
+// DurationAnomaly 任务最近几次成功执行的耗时与之前的滚动基线（最近 7 天）的比较
+type DurationAnomaly struct {
+	BaselineRuns int     `json:"baseline_runs"` // 基线的成功执行数
+	BaselineP50  int     `json:"baseline_p50"`  // 基线耗时中位数（毫秒）
+	RecentP50    int     `json:"recent_p50"`    // 最近执行的耗时中位数（毫秒）
+	Ratio        float64 `json:"ratio"`         // RecentP50 / BaselineP50（基线为 0 时为 0）
+	Anomalous    bool    `json:"anomalous"`     // 耗时突增
+}
+
+// TaskAnalytics 任务的执行分析
+type TaskAnalytics struct {
+	TaskID   int64            `json:"task_id"`
+	TaskName string           `json:"task_name"`
+	Windows  []*WindowStats   `json:"windows"`
+	Trend    []*TrendPoint    `json:"trend,omitempty"`
+	Clients  []*ClientHealth  `json:"clients"`
+	Anomaly  *DurationAnomaly `json:"anomaly,omitempty"` // 成功执行不足以建立基线时为空
+}
+
+// ClientTaskHealth 客户端上单个任务的执行情况（与同任务其他客户端比较）
+type ClientTaskHealth struct {
+	TaskID   int64  `json:"task_id"`
+	TaskName string `json:"task_name"`
+	*ClientHealth
+}
+
+// ClientAnalytics 客户端的执行分析
+type ClientAnalytics struct {
+	ClientID string              `json:"client_id"`
+	Windows  []*WindowStats      `json:"windows"`
+	Trend    []*TrendPoint       `json:"trend"`
+	Tasks    []*ClientTaskHealth `json:"tasks"` // 最近 7 天执行过的任务
+}
+
+// HealthReport 所有任务最近 7 天的健康报告（定期刷新，供 Prometheus 导出）
+type HealthReport struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	Tasks       []*TaskAnalytics `json:"tasks"` // 最近 7 天有执行的任务，不含趋势
+}
+
+// TaskAnalytics 统计任务在各时间窗口的成功率与耗时、最近 days 天的每日趋势、
+// 各客户端的异常标记与耗时突增
+func (m *TaskManager) TaskAnalytics(ctx context.Context, taskID int64, days int) (*TaskAnalytics, error) {
+	task, err := m.taskStore.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("task not found: %w", err)
+	}
+
+	now := time.Now()
+	longest := analyticsWindows[len(analyticsWindows)-1].duration
+	executions, err := m.executionStore.ListHistory(ctx, &store.ExecutionHistoryParams{
+		TaskID: taskID,
+		Since:  now.Add(-longest),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list execution history: %w", err)
+	}
+
+	result := analyzeTask(executions, now, longest)
+	result.TaskID = task.ID
+	result.TaskName = task.Name
+	result.Trend = dailyTrend(executions, now, days)
+	return result, nil
+}
+
+// ClientAnalytics 统计客户端在各时间窗口的成功率与耗时、最近 days 天的每日趋势，
+// 以及最近 7 天执行过的各任务在该客户端上的异常标记
+func (m *TaskManager) ClientAnalytics(ctx context.Context, clientID string, days int) (*ClientAnalytics, error) {
+	now := time.Now()
+	longest := analyticsWindows[len(analyticsWindows)-1].duration
+	executions, err := m.executionStore.ListHistory(ctx, &store.ExecutionHistoryParams{
+		ClientID: clientID,
+		Since:    now.Add(-longest),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list execution history: %w", err)
+	}
+
+	result := &ClientAnalytics{
+		ClientID: clientID,
+		Windows:  windowStats(executions, now, longest),
+		Trend:    dailyTrend(executions, now, days),
+		Tasks:    []*ClientTaskHealth{},
+	}
+
+	// 异常标记需要与同任务的其他客户端比较
+	for _, taskExecutions := range groupByTask(sinceTime(executions, now.Add(-healthWindow))) {
+		taskID := taskExecutions[0].TaskID
+		peers, err := m.executionStore.ListHistory(ctx, &store.ExecutionHistoryParams{
+			TaskID: taskID,
+			Since:  now.Add(-healthWindow),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list execution history of task %d: %w", taskID, err)
+		}
+		for _, health := range clientHealth(peers) {
+			if health.ClientID == clientID {
+				result.Tasks = append(result.Tasks, &ClientTaskHealth{
+					TaskID:       taskID,
+					TaskName:     taskExecutions[len(taskExecutions)-1].TaskName,
+					ClientHealth: health,
+				})
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// RefreshHealth 重新生成所有任务最近 7 天的健康报告
+func (m *TaskManager) RefreshHealth(ctx context.Context) (*HealthReport, error) {
+	now := time.Now()
+	executions, err := m.executionStore.ListHistory(ctx, &store.ExecutionHistoryParams{
+		Since: now.Add(-healthWindow),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list execution history: %w", err)
+	}
+
+	report := &HealthReport{GeneratedAt: now, Tasks: []*TaskAnalytics{}}
+	for _, taskExecutions := range groupByTask(executions) {
+		result := analyzeTask(taskExecutions, now, healthWindow)
+		result.TaskID = taskExecutions[0].TaskID
+		result.TaskName = taskExecutions[len(taskExecutions)-1].TaskName
+		report.Tasks = append(report.Tasks, result)
+	}
+
+	m.healthMu.Lock()
+	m.health = report
+	m.healthMu.Unlock()
+	return report, nil
+}
+
+// HealthReport 获取最近一次生成的健康报告，尚未生成过时返回 nil
+func (m *TaskManager) HealthReport() *HealthReport {
+	m.healthMu.Lock()
+	defer m.healthMu.Unlock()
+	return m.health
+}
+
+// MetricsCollector 返回导出任务健康报告的 Prometheus 指标采集函数，
+// 首次抓取时若尚未生成报告则立即生成
+func (m *TaskManager) MetricsCollector() monitoring.Collector {
+	return func() []*monitoring.MetricFamily {
+		report := m.HealthReport()
+		if report == nil {
+			var err error
+			if report, err = m.RefreshHealth(context.Background()); err != nil {
+				m.logger.Error("Failed to refresh task health", "error", err)
+				return nil
+			}
+		}
+		return healthMetrics(report)
+	}
+}
+
+// healthMetrics 将健康报告转换为 Prometheus 指标
+func healthMetrics(report *HealthReport) []*monitoring.MetricFamily {
+	gauge := func(name, help string) *monitoring.MetricFamily {
+		return &monitoring.MetricFamily{Name: name, Help: help, Type: "gauge"}
+	}
+	runs := gauge("task_runs", "Finished task executions in the window")
+	successRate := gauge("task_success_rate", "Task success rate in the window, cancelled executions excluded")
+	p50 := gauge("task_duration_p50_milliseconds", "P50 duration of successful task executions in the window")
+	p95 := gauge("task_duration_p95_milliseconds", "P95 duration of successful task executions in the window")
+	anomaly := gauge("task_duration_anomaly", "1 if recent task durations jumped compared to the rolling baseline")
+	failing := gauge("task_client_failing", "1 if the task consistently fails on the client (last 7 days)")
+	slow := gauge("task_client_slow", "1 if the task is much slower on the client than on its peers (last 7 days)")
+	generated := gauge("task_health_generated_timestamp_seconds", "Time the task health report was generated")
+	generated.Samples = []monitoring.MetricSample{{Value: float64(report.GeneratedAt.Unix())}}
+
+	flag := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	for _, task := range report.Tasks {
+		taskLabels := func(extra ...string) map[string]string {
+			labels := map[string]string{"task_id": fmt.Sprint(task.TaskID), "task": task.TaskName}
+			for i := 0; i+1 < len(extra); i += 2 {
+				labels[extra[i]] = extra[i+1]
+			}
+			return labels
+		}
+		for _, w := range task.Windows {
+			labels := taskLabels("window", w.Window)
+			runs.Samples = append(runs.Samples, monitoring.MetricSample{Labels: labels, Value: float64(w.Total)})
+			if w.Success+w.Failed > 0 {
+				successRate.Samples = append(successRate.Samples, monitoring.MetricSample{Labels: labels, Value: w.SuccessRate})
+			}
+			if w.Success > 0 {
+				p50.Samples = append(p50.Samples, monitoring.MetricSample{Labels: labels, Value: float64(w.P50Duration)})
+				p95.Samples = append(p95.Samples, monitoring.MetricSample{Labels: labels, Value: float64(w.P95Duration)})
+			}
+		}
+		if task.Anomaly != nil {
+			anomaly.Samples = append(anomaly.Samples, monitoring.MetricSample{Labels: taskLabels(), Value: flag(task.Anomaly.Anomalous)})
+		}
+		for _, client := range task.Clients {
+			labels := taskLabels("client_id", client.ClientID)
+			failing.Samples = append(failing.Samples, monitoring.MetricSample{Labels: labels, Value: flag(client.Failing)})
+			slow.Samples = append(slow.Samples, monitoring.MetricSample{Labels: labels, Value: flag(client.Slow)})
+		}
+	}
+	return []*monitoring.MetricFamily{runs, successRate, p50, p95, anomaly, failing, slow, generated}
+}
+
+// analyzeTask 统计单个任务的执行记录（按 ID 升序）：span 以内的时间窗口、客户端异常与耗时突增
+func analyzeTask(executions []*models.Execution, now time.Time, span time.Duration) *TaskAnalytics {
+	recent := sinceTime(executions, now.Add(-healthWindow))
+	return &TaskAnalytics{
+		Windows: windowStats(executions, now, span),
+		Clients: clientHealth(recent),
+		Anomaly: durationAnomaly(recent),
+	}
+}
+
+// windowStats 统计不超过 span 的各时间窗口
+func windowStats(executions []*models.Execution, now time.Time, span time.Duration) []*WindowStats {
+	result := []*WindowStats{}
+	for _, w := range analyticsWindows {
+		if w.duration > span {
+			break
+		}
+		result = append(result, &WindowStats{
+			Window:   w.name,
+			RunStats: runStats(sinceTime(executions, now.Add(-w.duration))),
+		})
+	}
+	return result
+}
+
+// dailyTrend 按天统计最近 days 天（含今天，最多 30 天），没有执行的日期统计为 0
+func dailyTrend(executions []*models.Execution, now time.Time, days int) []*TrendPoint {
+	if days <= 0 {
+		days = 7
+	}
+	if days > maxTrendDays {
+		days = maxTrendDays
+	}
+
+	byDate := make(map[string][]*models.Execution)
+	for _, execution := range executions {
+		date := execution.CreatedAt.In(now.Location()).Format("2006-01-02")
+		byDate[date] = append(byDate[date], execution)
+	}
+
+	trend := make([]*TrendPoint, 0, days)
+	for i := days - 1; i >= 0; i-- {
+		date := now.AddDate(0, 0, -i).Format("2006-01-02")
+		trend = append(trend, &TrendPoint{Date: date, RunStats: runStats(byDate[date])})
+	}
+	return trend
+}
+
+// clientHealth 按客户端统计并标记持续失败与明显偏慢的客户端，按客户端 ID 排序
+func clientHealth(executions []*models.Execution) []*ClientHealth {
+	byClient := make(map[string][]*models.Execution)
+	for _, execution := range executions {
+		byClient[execution.ClientID] = append(byClient[execution.ClientID], execution)
+	}
+
+	result := make([]*ClientHealth, 0, len(byClient))
+	for clientID, list := range byClient {
+		health := &ClientHealth{ClientID: clientID, RunStats: runStats(list)}
+		for i := len(list) - 1; i >= 0; i-- {
+			status := list[i].Status
+			if status == models.ExecutionStatusCancelled {
+				continue
+			}
+			if status == models.ExecutionStatusSuccess {
+				break
+			}
+			health.ConsecutiveFailures++
+		}
+		health.Failing = health.ConsecutiveFailures >= failingStreak ||
+			(health.Success+health.Failed >= outlierMinRuns && health.SuccessRate < failingRate)
+		result = append(result, health)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ClientID < result[j].ClientID })
+
+	// 与其他至少两个客户端的耗时中位数比较
+	var comparable []*ClientHealth
+	for _, health := range result {
+		if health.Success >= outlierMinRuns {
+			comparable = append(comparable, health)
+		}
+	}
+	if len(comparable) < 3 {
+		return result
+	}
+	for _, health := range comparable {
+		peers := make([]int, 0, len(comparable)-1)
+		for _, peer := range comparable {
+			if peer != health {
+				peers = append(peers, peer.P50Duration)
+			}
+		}
+		sort.Ints(peers)
+		median := percentile(peers, 0.5)
+		if median > 0 {
+			health.SlowRatio = round2(float64(health.P50Duration) / float64(median))
+		}
+		health.Slow = health.P50Duration-median >= minDurationDelta &&
+			(median == 0 || health.SlowRatio >= slowRatio)
+	}
+	return result
+}
+
+// durationAnomaly 比较最近几次成功执行的耗时与之前的成功执行，基线不足时返回 nil
+func durationAnomaly(executions []*models.Execution) *DurationAnomaly {
+	var durations []int
+	for _, execution := range executions {
+		if execution.Status == models.ExecutionStatusSuccess {
+			durations = append(durations, execution.Duration)
+		}
+	}
+	if len(durations) < anomalyRecentRuns+anomalyMinBaseline {
+		return nil
+	}
+
+	split := len(durations) - anomalyRecentRuns
+	baseline := append([]int(nil), durations[:split]...)
+	recent := append([]int(nil), durations[split:]...)
+	sort.Ints(baseline)
+	sort.Ints(recent)
+
+	anomaly := &DurationAnomaly{
+		BaselineRuns: len(baseline),
+		BaselineP50:  percentile(baseline, 0.5),
+		RecentP50:    percentile(recent, 0.5),
+	}
+	if anomaly.BaselineP50 > 0 {
+		anomaly.Ratio = round2(float64(anomaly.RecentP50) / float64(anomaly.BaselineP50))
+	}
+	anomaly.Anomalous = anomaly.RecentP50-anomaly.BaselineP50 >= minDurationDelta &&
+		(anomaly.BaselineP50 == 0 || anomaly.Ratio >= anomalyRatio)
+	return anomaly
+}
+
+// runStats 统计一组已结束的执行
+func runStats(executions []*models.Execution) RunStats {
+	stats := RunStats{Total: len(executions)}
+	var durations []int
+	var total int64
+	for _, execution := range executions {
+		switch execution.Status {
+		case models.ExecutionStatusSuccess:
+			stats.Success++
+			durations = append(durations, execution.Duration)
+			total += int64(execution.Duration)
+		case models.ExecutionStatusFailed, models.ExecutionStatusTimeout:
+			stats.Failed++
+		case models.ExecutionStatusCancelled:
+			stats.Cancelled++
+		}
+	}
+	if finished := stats.Success + stats.Failed; finished > 0 {
+		stats.SuccessRate = round2(float64(stats.Success) / float64(finished))
+	}
+	if len(durations) > 0 {
+		sort.Ints(durations)
+		stats.P50Duration = percentile(durations, 0.5)
+		stats.P95Duration = percentile(durations, 0.95)
+		stats.AvgDuration = int(total / int64(len(durations)))
+		stats.MaxDuration = durations[len(durations)-1]
+	}
+	return stats
+}
+
+// sinceTime 返回创建时间不早于 since 的执行记录，保持原顺序
+func sinceTime(executions []*models.Execution, since time.Time) []*models.Execution {
+	result := make([]*models.Execution, 0, len(executions))
+	for _, execution := range executions {
+		if !execution.CreatedAt.Before(since) {
+			result = append(result, execution)
+		}
+	}
+	return result
+}
+
+// groupByTask 按任务分组，按任务 ID 排序，组内保持原顺序
+func groupByTask(executions []*models.Execution) [][]*models.Execution {
+	byTask := make(map[int64][]*models.Execution)
+	var taskIDs []int64
+	for _, execution := range executions {
+		if _, ok := byTask[execution.TaskID]; !ok {
+			taskIDs = append(taskIDs, execution.TaskID)
+		}
+		byTask[execution.TaskID] = append(byTask[execution.TaskID], execution)
+	}
+	sort.Slice(taskIDs, func(i, j int) bool { return taskIDs[i] < taskIDs[j] })
+
+	groups := make([][]*models.Execution, 0, len(taskIDs))
+	for _, taskID := range taskIDs {
+		groups = append(groups, byTask[taskID])
+	}
+	return groups
+}
+
+// percentile 计算已排序数据的分位数（最近秩法），空数据返回 0
+func percentile(sorted []int, p float64) int {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// round2 保留两位小数
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,154 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/protocol"
+	"github.com/voilet/quic-flow/pkg/task/models"
+)
+
+// newAnalyticsEnv 创建一个任务及其执行历史：
+// c1-c3 耗时 1s、c4 耗时 5s（各 5 次成功），c5 连续失败 3 次，c2 取消 1 次，
+// 最后 c1 的 3 次成功耗时 9s（耗时突增），另有 20 天前的 1 次失败
+func newAnalyticsEnv(t *testing.T) (*TaskManager, *models.Task) {
+	env := newTestManager(t)
+	ctx := context.Background()
+	m, taskStore, executions := env.m, env.tasks, env.executions
+
+	task := &models.Task{Name: "backup", ExecutorType: models.ExecutorTypeShell, ExecutorConfig: `{"command":"true"}`, CronExpr: "@hourly"}
+	require.NoError(t, taskStore.Create(ctx, task))
+	add := func(clientID string, status models.ExecutionStatus, duration int, age time.Duration) {
+		require.NoError(t, executions.Create(ctx, &models.Execution{TaskID: task.ID, TaskName: task.Name, ClientID: clientID,
+			Status: status, Duration: duration, CreatedAt: time.Now().Add(-age)}))
+	}
+
+	add("c1", models.ExecutionStatusFailed, 0, 20*24*time.Hour)
+	for i := 0; i < 5; i++ {
+		for _, clientID := range []string{"c1", "c2", "c3"} {
+			add(clientID, models.ExecutionStatusSuccess, 1000, 48*time.Hour)
+		}
+		add("c4", models.ExecutionStatusSuccess, 5000, 48*time.Hour)
+	}
+	for i := 0; i < 3; i++ {
+		add("c5", models.ExecutionStatusFailed, 200, 48*time.Hour)
+	}
+	add("c2", models.ExecutionStatusCancelled, 0, 48*time.Hour)
+	for i := 0; i < 3; i++ {
+		add("c1", models.ExecutionStatusSuccess, 9000, time.Hour)
+	}
+	// 没有执行的任务不出现在健康报告中
+	require.NoError(t, taskStore.Create(ctx, &models.Task{Name: "idle", ExecutorType: models.ExecutorTypeShell, ExecutorConfig: `{"command":"true"}`, CronExpr: "@hourly"}))
+	return m, task
+}
+
+func TestTaskManager_TaskAnalytics(t *testing.T) {
+	m, task := newAnalyticsEnv(t)
+
+	result, err := m.TaskAnalytics(context.Background(), task.ID, 7)
+	require.NoError(t, err)
+	assert.Equal(t, "backup", result.TaskName)
+
+	require.Len(t, result.Windows, 3)
+	day, week, month := result.Windows[0], result.Windows[1], result.Windows[2]
+	assert.Equal(t, "24h", day.Window)
+	assert.Equal(t, RunStats{Total: 3, Success: 3, SuccessRate: 1, P50Duration: 9000, P95Duration: 9000, AvgDuration: 9000, MaxDuration: 9000}, day.RunStats)
+	assert.Equal(t, 27, week.Total)
+	assert.Equal(t, 23, week.Success)
+	assert.Equal(t, 3, week.Failed)
+	assert.Equal(t, 1, week.Cancelled)
+	assert.Equal(t, 0.88, week.SuccessRate)
+	assert.Equal(t, 1000, week.P50Duration)
+	assert.Equal(t, 9000, week.P95Duration)
+	assert.Equal(t, 28, month.Total)
+	assert.Equal(t, 0.85, month.SuccessRate)
+
+	require.Len(t, result.Trend, 7)
+	assert.Equal(t, time.Now().Format("2006-01-02"), result.Trend[6].Date)
+	total := 0
+	for _, point := range result.Trend {
+		total += point.Total
+	}
+	assert.Equal(t, 27, total)
+
+	clients := make(map[string]*ClientHealth)
+	for _, client := range result.Clients {
+		clients[client.ClientID] = client
+	}
+	require.Len(t, clients, 5)
+	assert.True(t, clients["c4"].Slow)
+	assert.Equal(t, 5.0, clients["c4"].SlowRatio)
+	assert.False(t, clients["c1"].Slow, "c1 median is still 1s")
+	assert.True(t, clients["c5"].Failing)
+	assert.Equal(t, 3, clients["c5"].ConsecutiveFailures)
+	assert.False(t, clients["c5"].Slow, "too few successful runs to compare")
+	for _, clientID := range []string{"c1", "c2", "c3", "c4"} {
+		assert.False(t, clients[clientID].Failing, clientID)
+	}
+
+	require.NotNil(t, result.Anomaly)
+	assert.Equal(t, DurationAnomaly{BaselineRuns: 20, BaselineP50: 1000, RecentP50: 9000, Ratio: 9, Anomalous: true}, *result.Anomaly)
+
+	_, err = m.TaskAnalytics(context.Background(), 999, 7)
+	assert.Error(t, err)
+}
+
+func TestTaskManager_ClientAnalytics(t *testing.T) {
+	m, task := newAnalyticsEnv(t)
+
+	result, err := m.ClientAnalytics(context.Background(), "c4", 3)
+	require.NoError(t, err)
+	assert.Len(t, result.Trend, 3)
+	require.Len(t, result.Windows, 3)
+	assert.Equal(t, 5, result.Windows[1].Total)
+	assert.Equal(t, 5000, result.Windows[1].P50Duration)
+	require.Len(t, result.Tasks, 1)
+	assert.Equal(t, task.ID, result.Tasks[0].TaskID)
+	assert.Equal(t, "backup", result.Tasks[0].TaskName)
+	assert.True(t, result.Tasks[0].Slow)
+
+	result, err = m.ClientAnalytics(context.Background(), "unknown", 0)
+	require.NoError(t, err)
+	assert.Len(t, result.Trend, 7)
+	assert.Zero(t, result.Windows[2].Total)
+	assert.Empty(t, result.Tasks)
+}
+
+func TestTaskManager_HealthMetrics(t *testing.T) {
+	m, task := newAnalyticsEnv(t)
+	assert.Nil(t, m.HealthReport())
+
+	handler := monitoring.NewSnapshotPrometheusHandler(func() *protocol.MetricsSnapshot { return &protocol.MetricsSnapshot{} }, "")
+	handler.AddCollector(m.MetricsCollector())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	// 首次抓取时生成报告，只统计最近 7 天
+	report := m.HealthReport()
+	require.NotNil(t, report)
+	require.Len(t, report.Tasks, 1)
+	assert.Len(t, report.Tasks[0].Windows, 2)
+	assert.Empty(t, report.Tasks[0].Trend)
+
+	labels := fmt.Sprintf(`task="backup",task_id="%d"`, task.ID)
+	for _, line := range []string{
+		"# TYPE quic_backbone_task_success_rate gauge",
+		`quic_backbone_task_runs{` + labels + `,window="24h"} 3`,
+		`quic_backbone_task_success_rate{` + labels + `,window="7d"} 0.88`,
+		`quic_backbone_task_duration_p95_milliseconds{` + labels + `,window="7d"} 9000`,
+		`quic_backbone_task_duration_anomaly{` + labels + `} 1`,
+		`quic_backbone_task_client_failing{client_id="c5",` + labels + `} 1`,
+		`quic_backbone_task_client_slow{client_id="c4",` + labels + `} 1`,
+		`quic_backbone_task_client_slow{client_id="c1",` + labels + `} 0`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+	assert.NotContains(t, body, `task="idle"`)
+}
@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/calendar"
+	"github.com/voilet/quic-flow/pkg/task/models"
+)
+
+// SetCalendar 设置禁止窗口，定时触发落在窗口内时跳过（需在启动调度器前调用）
+// 只影响定时触发、停机补偿与分散窗口内的延迟下发，手动触发与 Agent 本地调度的任务不受限制
+func (s *CronScheduler) SetCalendar(blackouts *calendar.Store) {
+	s.calendar = blackouts
+	s.taskDispatcher.suppressed = s.suppressed
+}
+
+// taskBlackouts 返回任务当前所处的禁止窗口：全局窗口，或作用于任务任一关联分组的窗口
+func (s *CronScheduler) taskBlackouts(ctx context.Context, task *models.Task, at time.Time) []*calendar.Window {
+	if s.calendar == nil {
+		return nil
+	}
+	return s.calendar.Active(calendar.KindTasks, s.groupTarget(ctx, task.ID), at)
+}
+
+// workflowBlackouts 返回工作流当前所处的禁止窗口：全局窗口，或作用于任一节点任务关联分组的窗口
+func (s *CronScheduler) workflowBlackouts(ctx context.Context, workflow *models.Workflow, at time.Time) []*calendar.Window {
+	if s.calendar == nil {
+		return nil
+	}
+	taskIDs := make([]int64, 0, len(workflow.Nodes))
+	for _, node := range workflow.Nodes {
+		taskIDs = append(taskIDs, node.TaskID)
+	}
+	return s.calendar.Active(calendar.KindTasks, s.groupTarget(ctx, taskIDs...), at)
+}
+
+// groupTarget 任务关联分组组成的窗口作用对象（分组在检查时读取，绑定变更后立即生效）
+func (s *CronScheduler) groupTarget(ctx context.Context, taskIDs ...int64) calendar.Target {
+	seen := make(map[int64]bool)
+	target := calendar.Target{GroupIDs: []string{}}
+	for _, taskID := range taskIDs {
+		groupIDs, err := s.taskDispatcher.taskStore.GetGroupIDs(ctx, taskID)
+		if err != nil {
+			s.logger.Warn("Failed to get task groups for blackout check", "task_id", taskID, "error", err)
+		}
+		for _, id := range groupIDs {
+			if !seen[id] {
+				seen[id] = true
+				target.GroupIDs = append(target.GroupIDs, strconv.FormatInt(id, 10))
+			}
+		}
+	}
+	return target
+}
+
+// suppressed 任务处于禁止窗口时记录日志并返回 true
+func (s *CronScheduler) suppressed(ctx context.Context, task *models.Task) bool {
+	windows := s.taskBlackouts(ctx, task, time.Now())
+	if len(windows) == 0 {
+		return false
+	}
+	s.logger.Info("Task fire suppressed by blackout window",
+		"task_id", task.ID,
+		"task_name", task.Name,
+		"windows", calendar.Names(windows))
+	return true
+}
@@ -0,0 +1,103 @@
+package scheduler
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/calendar"
+	"github.com/voilet/quic-flow/pkg/task/models"
+)
+
+func TestCronScheduler_Blackout(t *testing.T) {
+	env := newTestManager(t, withClients("client-1"))
+	ctx := context.Background()
+	cron, tasks, executions := env.cron, env.tasks, env.executions
+	blackouts, err := calendar.NewStore("")
+	require.NoError(t, err)
+	cron.SetCalendar(blackouts)
+
+	newTask := func(name string, group *models.TaskGroup) *models.Task {
+		last := time.Now().Add(-time.Hour - time.Minute)
+		task := &models.Task{Name: name, ExecutorType: models.ExecutorTypeShell, ExecutorConfig: `{"command":"true"}`,
+			CronExpr: "0 0 * * * *", MisfirePolicy: models.MisfireFireOnce, LastFireTime: &last}
+		require.NoError(t, tasks.Create(ctx, task))
+		require.NoError(t, tasks.BindGroup(ctx, task.ID, group.ID))
+		return task
+	}
+	web := env.group(t, "web", "client-1")
+	dbGroup := env.group(t, "db", "client-1")
+	webTask, dbTask := newTask("rotate-logs", web), newTask("vacuum", dbGroup)
+
+	start, end := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+	create := func(w *calendar.Window) *calendar.Window {
+		w.Type = calendar.WindowOnce
+		w.StartAt, w.EndAt = &start, &end
+		created, err := blackouts.Create(w)
+		require.NoError(t, err)
+		return created
+	}
+	create(&calendar.Window{Name: "db maintenance", Scope: calendar.ScopeGroup, ScopeID: strconv.FormatInt(dbGroup.ID, 10)})
+	create(&calendar.Window{Name: "release freeze", AppliesTo: []calendar.Kind{calendar.KindReleases}})
+	runs := func(task *models.Task) int {
+		list, err := executions.GetByTaskID(ctx, task.ID, 0)
+		require.NoError(t, err)
+		return len(list)
+	}
+
+	// 分组窗口只跳过关联了该分组的任务，只限制发布的全局窗口不影响任务
+	cron.createJob(webTask)()
+	cron.createJob(dbTask)()
+	require.Eventually(t, func() bool { return runs(webTask) == 1 }, 2*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		task, err := tasks.GetByID(ctx, dbTask.ID)
+		return err == nil && task.LastFireTime != nil && time.Since(*task.LastFireTime) < time.Minute
+	}, 2*time.Second, 10*time.Millisecond, "suppressed fire is still recorded")
+	assert.Zero(t, runs(dbTask))
+
+	// 工作流受作用于任一节点任务关联分组的窗口限制
+	flow := &models.Workflow{Name: "nightly", Nodes: []models.WorkflowNode{{Name: "vacuum", TaskID: dbTask.ID}}}
+	assert.Equal(t, []string{"db maintenance"}, calendar.Names(cron.workflowBlackouts(ctx, flow, time.Now())))
+	flow.Nodes[0].TaskID = webTask.ID
+	assert.Empty(t, cron.workflowBlackouts(ctx, flow, time.Now()))
+
+	// 全局窗口跳过所有任务的停机补偿
+	create(&calendar.Window{Name: "holiday", AppliesTo: []calendar.Kind{calendar.KindTasks}})
+	assert.Len(t, cron.taskBlackouts(ctx, webTask, time.Now()), 1)
+	assert.Equal(t, []string{"db maintenance", "holiday"}, calendar.Names(cron.taskBlackouts(ctx, dbTask, time.Now())))
+	assert.Empty(t, cron.taskBlackouts(ctx, dbTask, end))
+	catchUp := newTask("backup", web)
+	assert.Equal(t, 1, cron.CatchUp(catchUp, time.Now()))
+	time.Sleep(100 * time.Millisecond)
+	assert.Zero(t, runs(catchUp))
+}
+
+func TestTaskDispatcher_DelayedDispatchBlackout(t *testing.T) {
+	env := newTestManager(t, withClients("client-1"))
+	ctx := context.Background()
+	blackouts, err := calendar.NewStore("")
+	require.NoError(t, err)
+	env.cron.SetCalendar(blackouts)
+
+	group := env.group(t, "web", "client-1")
+	task := &models.Task{Name: "rotate-logs", ExecutorType: models.ExecutorTypeShell, ExecutorConfig: `{"command":"true"}`,
+		CronExpr: "@hourly", Jitter: 1}
+	require.NoError(t, env.tasks.Create(ctx, task))
+	require.NoError(t, env.tasks.BindGroup(ctx, task.ID, group.ID))
+
+	// 随机延迟期间处于禁止窗口：下发前重新检查，不再下发
+	start, end := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+	window, err := blackouts.Create(&calendar.Window{Name: "web maintenance", Type: calendar.WindowOnce, StartAt: &start, EndAt: &end,
+		Scope: calendar.ScopeGroup, ScopeID: strconv.FormatInt(group.ID, 10)})
+	require.NoError(t, err)
+	require.NoError(t, env.d.Dispatch(ctx, task, models.ExecutionTypeScheduled))
+	time.Sleep(1200 * time.Millisecond)
+	assert.Zero(t, env.sentCount())
+
+	require.NoError(t, blackouts.Delete(window.ID))
+	require.NoError(t, env.d.Dispatch(ctx, task, models.ExecutionTypeScheduled))
+	require.Eventually(t, func() bool { return env.sentCount() == 1 }, 2*time.Second, 10*time.Millisecond)
+}
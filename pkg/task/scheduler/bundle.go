@@ -0,0 +1,620 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/voilet/quic-flow/pkg/task/models"
+	"github.com/voilet/quic-flow/pkg/task/store"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// 任务字段的默认值（与 models.Task 的数据库默认值一致），任务定义中省略时使用
+	defaultTaskTimeout       = 300
+	defaultTaskRetryInterval = 60
+)
+
+// ErrInvalidBundle 任务定义无效（名称重复、字段无效或引用了不存在的分组）
+var ErrInvalidBundle = errors.New("invalid task bundle")
+
+// executorNames 执行器类型在任务定义中的名称
+var executorNames = map[models.ExecutorType]string{
+	models.ExecutorTypeShell:  "shell",
+	models.ExecutorTypeHTTP:   "http",
+	models.ExecutorTypePlugin: "plugin",
+}
+
+// Bundle 声明式任务定义（YAML），任务与分组均以名称标识
+type Bundle struct {
+	Groups []*BundleGroup `yaml:"groups,omitempty"`
+	Tasks  []*BundleTask  `yaml:"tasks,omitempty"`
+}
+
+// BundleGroup 分组定义，selector 为空时为静态分组
+type BundleGroup struct {
+	Name        string                `yaml:"name"`
+	Description string                `yaml:"description,omitempty"`
+	Tags        string                `yaml:"tags,omitempty"`
+	Selector    *models.GroupSelector `yaml:"selector,omitempty"`
+}
+
+// BundleTask 任务定义，省略的字段使用创建任务时的默认值
+type BundleTask struct {
+	Name          string                 `yaml:"name"`
+	Description   string                 `yaml:"description,omitempty"`
+	Executor      string                 `yaml:"executor"`                 // shell、http、plugin
+	Config        map[string]interface{} `yaml:"config,omitempty"`         // 执行器配置
+	Cron          string                 `yaml:"cron"`                     // Cron 表达式
+	Timeout       *int                   `yaml:"timeout,omitempty"`        // 超时（秒），默认 300
+	RetryCount    int                    `yaml:"retry_count,omitempty"`    // 重试次数
+	RetryInterval *int                   `yaml:"retry_interval,omitempty"` // 重试间隔（秒），默认 60
+	Concurrency   int                    `yaml:"concurrency,omitempty"`    // 单个客户端上的最大并发数，默认 1
+	OverlapPolicy models.OverlapPolicy   `yaml:"overlap_policy,omitempty"` // 默认 skip
+	MisfirePolicy models.MisfirePolicy   `yaml:"misfire_policy,omitempty"` // 默认 skip
+	Jitter        int                    `yaml:"jitter,omitempty"`
+	Spread        int                    `yaml:"spread,omitempty"`
+	RetainRuns    int                    `yaml:"retain_runs,omitempty"`
+	RetainDays    int                    `yaml:"retain_days,omitempty"`
+	LocalSchedule bool                   `yaml:"local_schedule,omitempty"`
+	Enabled       *bool                  `yaml:"enabled,omitempty"` // 默认 true
+	Groups        []string               `yaml:"groups,omitempty"`  // 分组名称
+}
+
+// ApplyOptions 应用任务定义的选项
+type ApplyOptions struct {
+	DryRun bool // 只计算变更，不修改
+	Prune  bool // 删除任务定义中不存在的任务与分组
+
+	// AllowEmpty Prune 时允许应用空定义（删除全部任务与分组）。
+	// 默认拒绝，避免来源目录为空或读取异常时误删所有任务
+	AllowEmpty bool
+}
+
+// BundleChange 应用任务定义产生的一项变更
+type BundleChange struct {
+	Kind   string   `json:"kind"`             // group、task
+	Name   string   `json:"name"`             // 名称
+	Action string   `json:"action"`           // create、update、delete
+	Fields []string `json:"fields,omitempty"` // update 时变化的字段
+}
+
+// ApplyResult 应用任务定义的结果
+type ApplyResult struct {
+	DryRun    bool            `json:"dry_run"`
+	Revision  string          `json:"revision,omitempty"` // 任务定义来源的版本（Git 同步时为提交哈希）
+	Changes   []*BundleChange `json:"changes"`
+	Unchanged int             `json:"unchanged"`        // 无变化的任务与分组数
+	Errors    []string        `json:"errors,omitempty"` // 执行失败的变更
+}
+
+// ParseBundle 解析 YAML 任务定义，多个文档（--- 分隔）合并为一份
+func ParseBundle(data []byte) (*Bundle, error) {
+	bundle := &Bundle{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	for {
+		var doc Bundle
+		if err := decoder.Decode(&doc); err != nil {
+			if err == io.EOF {
+				return bundle, nil
+			}
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		bundle.Merge(&doc)
+	}
+}
+
+// Merge 合并另一份任务定义，重名在应用时报错
+func (b *Bundle) Merge(other *Bundle) {
+	b.Groups = append(b.Groups, other.Groups...)
+	b.Tasks = append(b.Tasks, other.Tasks...)
+}
+
+// Marshal 输出 YAML
+func (b *Bundle) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(b); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SetGroupStore 设置分组存储，导出与应用任务定义、解析静态分组成员需要
+func (m *TaskManager) SetGroupStore(groupStore store.GroupStore) {
+	m.groupStore = groupStore
+	m.dispatcher.SetGroupStore(groupStore)
+}
+
+// ExportBundle 导出所有任务与分组，按名称排序，省略默认值
+func (m *TaskManager) ExportBundle(ctx context.Context) (*Bundle, error) {
+	if m.groupStore == nil {
+		return nil, fmt.Errorf("group store not configured")
+	}
+	groups, err := m.groupStore.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+	tasks, err := m.taskStore.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	bundle := &Bundle{Groups: []*BundleGroup{}, Tasks: []*BundleTask{}}
+	for _, group := range groups {
+		bundle.Groups = append(bundle.Groups, groupSpec(group))
+	}
+	for _, task := range tasks {
+		spec := taskSpec(task)
+		spec.compact()
+		bundle.Tasks = append(bundle.Tasks, spec)
+	}
+	sort.Slice(bundle.Groups, func(i, j int) bool { return bundle.Groups[i].Name < bundle.Groups[j].Name })
+	sort.Slice(bundle.Tasks, func(i, j int) bool { return bundle.Tasks[i].Name < bundle.Tasks[j].Name })
+	return bundle, nil
+}
+
+// ApplyBundle 按名称将任务定义应用到现有的任务与分组：不存在的创建，有变化的更新，
+// Prune 时删除定义中不存在的任务与分组（空定义须显式 AllowEmpty）。重复应用同一份定义不产生变更
+func (m *TaskManager) ApplyBundle(ctx context.Context, bundle *Bundle, opts ApplyOptions) (*ApplyResult, error) {
+	if m.groupStore == nil {
+		return nil, fmt.Errorf("group store not configured")
+	}
+	if err := m.validateBundle(bundle); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	if opts.Prune && !opts.AllowEmpty && len(bundle.Tasks) == 0 && len(bundle.Groups) == 0 {
+		return nil, fmt.Errorf("%w: refusing to prune with an empty bundle", ErrInvalidBundle)
+	}
+
+	m.bundleMu.Lock()
+	defer m.bundleMu.Unlock()
+
+	groupList, err := m.groupStore.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+	taskList, err := m.taskStore.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	groups := make(map[string]*models.TaskGroup, len(groupList))
+	for _, group := range groupList {
+		groups[group.Name] = group
+	}
+	tasks := make(map[string]*models.Task, len(taskList))
+	for _, task := range taskList {
+		tasks[task.Name] = task
+	}
+
+	// 任务引用的分组必须在定义中，或者（不删除多余分组时）已经存在
+	defined := make(map[string]bool, len(bundle.Groups))
+	for _, group := range bundle.Groups {
+		defined[group.Name] = true
+	}
+	for _, task := range bundle.Tasks {
+		for _, name := range task.Groups {
+			if !defined[name] && (opts.Prune || groups[name] == nil) {
+				return nil, fmt.Errorf("%w: task %q: unknown group %q", ErrInvalidBundle, task.Name, name)
+			}
+		}
+	}
+
+	result := &ApplyResult{DryRun: opts.DryRun, Changes: []*BundleChange{}}
+	record := func(change *BundleChange, err error) {
+		result.Changes = append(result.Changes, change)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s %s %q: %v", change.Action, change.Kind, change.Name, err))
+		}
+	}
+
+	for _, desired := range bundle.Groups {
+		current := groups[desired.Name]
+		if current == nil {
+			change := &BundleChange{Kind: "group", Name: desired.Name, Action: "create"}
+			if opts.DryRun {
+				record(change, nil)
+				continue
+			}
+			group := &models.TaskGroup{Name: desired.Name, Description: desired.Description, Tags: desired.Tags, Selector: desired.Selector}
+			err := m.groupStore.Create(ctx, group)
+			if err == nil {
+				groups[group.Name] = group
+			}
+			record(change, err)
+			continue
+		}
+
+		fields := diffGroup(groupSpec(current), desired)
+		if len(fields) == 0 {
+			result.Unchanged++
+			continue
+		}
+		change := &BundleChange{Kind: "group", Name: desired.Name, Action: "update", Fields: fields}
+		if opts.DryRun {
+			record(change, nil)
+			continue
+		}
+		current.Description = desired.Description
+		current.Tags = desired.Tags
+		current.Selector = desired.Selector
+		record(change, m.groupStore.Update(ctx, current))
+	}
+
+	for _, desired := range bundle.Tasks {
+		desired.normalize()
+		current := tasks[desired.Name]
+		if current == nil {
+			change := &BundleChange{Kind: "task", Name: desired.Name, Action: "create"}
+			if opts.DryRun {
+				record(change, nil)
+				continue
+			}
+			record(change, m.createBundleTask(ctx, desired, groups))
+			continue
+		}
+
+		fields := diffTask(taskSpec(current), desired)
+		if len(fields) == 0 {
+			result.Unchanged++
+			continue
+		}
+		change := &BundleChange{Kind: "task", Name: desired.Name, Action: "update", Fields: fields}
+		if opts.DryRun {
+			record(change, nil)
+			continue
+		}
+		groupIDs, err := bundleGroupIDs(desired, groups)
+		if err == nil {
+			err = m.UpdateTask(ctx, desired.updateRequest(current.ID, groupIDs))
+		}
+		record(change, err)
+	}
+
+	if opts.Prune {
+		keepTasks := make(map[string]bool, len(bundle.Tasks))
+		for _, task := range bundle.Tasks {
+			keepTasks[task.Name] = true
+		}
+		for _, task := range taskList {
+			if keepTasks[task.Name] {
+				continue
+			}
+			change := &BundleChange{Kind: "task", Name: task.Name, Action: "delete"}
+			if opts.DryRun {
+				record(change, nil)
+				continue
+			}
+			record(change, m.DeleteTask(ctx, task.ID))
+		}
+		for _, group := range groupList {
+			if defined[group.Name] {
+				continue
+			}
+			change := &BundleChange{Kind: "group", Name: group.Name, Action: "delete"}
+			if opts.DryRun {
+				record(change, nil)
+				continue
+			}
+			record(change, m.groupStore.Delete(ctx, group.ID))
+		}
+	}
+
+	m.logger.Info("Task bundle applied",
+		"dry_run", opts.DryRun,
+		"prune", opts.Prune,
+		"changes", len(result.Changes),
+		"unchanged", result.Unchanged,
+		"errors", len(result.Errors))
+	return result, nil
+}
+
+// createBundleTask 按定义创建任务；创建时不能直接写入的值（如禁用状态、为 0 的超时）随后更新
+func (m *TaskManager) createBundleTask(ctx context.Context, desired *BundleTask, groups map[string]*models.TaskGroup) error {
+	groupIDs, err := bundleGroupIDs(desired, groups)
+	if err != nil {
+		return err
+	}
+	update := desired.updateRequest(0, groupIDs)
+	task, err := m.CreateTask(ctx, &CreateTaskRequest{
+		Name:           desired.Name,
+		Description:    desired.Description,
+		ExecutorType:   *update.ExecutorType,
+		ExecutorConfig: *update.ExecutorConfig,
+		CronExpr:       desired.Cron,
+		Timeout:        *desired.Timeout,
+		RetryCount:     desired.RetryCount,
+		RetryInterval:  *desired.RetryInterval,
+		Concurrency:    desired.Concurrency,
+		OverlapPolicy:  desired.OverlapPolicy,
+		MisfirePolicy:  desired.MisfirePolicy,
+		Jitter:         desired.Jitter,
+		Spread:         desired.Spread,
+		RetainRuns:     desired.RetainRuns,
+		RetainDays:     desired.RetainDays,
+		LocalSchedule:  desired.LocalSchedule,
+		CreatedBy:      "bundle",
+		GroupIDs:       groupIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	created, err := m.taskStore.GetByID(ctx, task.ID)
+	if err != nil {
+		return err
+	}
+	if len(diffTask(taskSpec(created), desired)) == 0 {
+		return nil
+	}
+	update.TaskID = task.ID
+	return m.UpdateTask(ctx, update)
+}
+
+// validateBundle 校验任务定义：名称非空且不重复、执行器与 Cron 表达式有效、数值不为负
+func (m *TaskManager) validateBundle(bundle *Bundle) error {
+	names := make(map[string]bool)
+	for _, group := range bundle.Groups {
+		if group.Name == "" {
+			return fmt.Errorf("group name is required")
+		}
+		if names[group.Name] {
+			return fmt.Errorf("duplicate group %q", group.Name)
+		}
+		names[group.Name] = true
+		if group.Selector != nil {
+			if err := group.Selector.Validate(); err != nil {
+				return fmt.Errorf("group %q: %w", group.Name, err)
+			}
+		}
+	}
+
+	names = make(map[string]bool)
+	for _, task := range bundle.Tasks {
+		if task.Name == "" {
+			return fmt.Errorf("task name is required")
+		}
+		if names[task.Name] {
+			return fmt.Errorf("duplicate task %q", task.Name)
+		}
+		names[task.Name] = true
+		if _, ok := executorType(task.Executor); !ok {
+			return fmt.Errorf("task %q: unknown executor %q", task.Name, task.Executor)
+		}
+		if err := m.cron.validateCronExpr(task.Cron); err != nil {
+			return fmt.Errorf("task %q: invalid cron expression: %w", task.Name, err)
+		}
+		if err := validateSchedulePolicy(task.OverlapPolicy, task.MisfirePolicy, task.Jitter, task.Spread); err != nil {
+			return fmt.Errorf("task %q: %w", task.Name, err)
+		}
+		if (task.Timeout != nil && *task.Timeout < 0) || (task.RetryInterval != nil && *task.RetryInterval < 0) ||
+			task.RetryCount < 0 || task.Concurrency < 0 || task.RetainRuns < 0 || task.RetainDays < 0 {
+			return fmt.Errorf("task %q: numeric fields must not be negative", task.Name)
+		}
+	}
+	return nil
+}
+
+// normalize 填充省略字段的默认值，分组按名称排序去重
+func (t *BundleTask) normalize() {
+	if t.Timeout == nil {
+		timeout := defaultTaskTimeout
+		t.Timeout = &timeout
+	}
+	if t.RetryInterval == nil {
+		interval := defaultTaskRetryInterval
+		t.RetryInterval = &interval
+	}
+	if t.Concurrency <= 0 {
+		t.Concurrency = 1
+	}
+	if t.OverlapPolicy == "" {
+		t.OverlapPolicy = models.OverlapSkip
+	}
+	if t.MisfirePolicy == "" {
+		t.MisfirePolicy = models.MisfireSkip
+	}
+	if t.Enabled == nil {
+		enabled := true
+		t.Enabled = &enabled
+	}
+	sort.Strings(t.Groups)
+	groups := t.Groups[:0]
+	for i, name := range t.Groups {
+		if i == 0 || name != t.Groups[i-1] {
+			groups = append(groups, name)
+		}
+	}
+	t.Groups = groups
+}
+
+// compact 省略等于默认值的字段（导出用）
+func (t *BundleTask) compact() {
+	if t.Timeout != nil && *t.Timeout == defaultTaskTimeout {
+		t.Timeout = nil
+	}
+	if t.RetryInterval != nil && *t.RetryInterval == defaultTaskRetryInterval {
+		t.RetryInterval = nil
+	}
+	if t.Concurrency == 1 {
+		t.Concurrency = 0
+	}
+	if t.OverlapPolicy == models.OverlapSkip {
+		t.OverlapPolicy = ""
+	}
+	if t.MisfirePolicy == models.MisfireSkip {
+		t.MisfirePolicy = ""
+	}
+	if t.Enabled != nil && *t.Enabled {
+		t.Enabled = nil
+	}
+}
+
+// updateRequest 生成写入全部字段的更新请求
+func (t *BundleTask) updateRequest(taskID int64, groupIDs []int64) *UpdateTaskRequest {
+	executor, _ := executorType(t.Executor)
+	config := configJSON(t.Config)
+	status := models.TaskStatusDisabled
+	if *t.Enabled {
+		status = models.TaskStatusEnabled
+	}
+	if groupIDs == nil {
+		groupIDs = []int64{}
+	}
+	return &UpdateTaskRequest{
+		TaskID:         taskID,
+		Description:    &t.Description,
+		ExecutorType:   &executor,
+		ExecutorConfig: &config,
+		CronExpr:       &t.Cron,
+		Timeout:        t.Timeout,
+		RetryCount:     &t.RetryCount,
+		RetryInterval:  t.RetryInterval,
+		Concurrency:    &t.Concurrency,
+		OverlapPolicy:  &t.OverlapPolicy,
+		MisfirePolicy:  &t.MisfirePolicy,
+		Jitter:         &t.Jitter,
+		Spread:         &t.Spread,
+		RetainRuns:     &t.RetainRuns,
+		RetainDays:     &t.RetainDays,
+		Status:         &status,
+		LocalSchedule:  &t.LocalSchedule,
+		GroupIDs:       groupIDs,
+	}
+}
+
+// taskSpec 将任务转换为（填充默认值后的）任务定义
+func taskSpec(task *models.Task) *BundleTask {
+	timeout, interval := task.Timeout, task.RetryInterval
+	enabled := task.IsEnabled()
+	spec := &BundleTask{
+		Name:          task.Name,
+		Description:   task.Description,
+		Executor:      executorNames[task.ExecutorType],
+		Cron:          task.CronExpr,
+		Timeout:       &timeout,
+		RetryCount:    task.RetryCount,
+		RetryInterval: &interval,
+		Concurrency:   task.GetConcurrency(),
+		OverlapPolicy: task.GetOverlapPolicy(),
+		MisfirePolicy: task.GetMisfirePolicy(),
+		Jitter:        task.Jitter,
+		Spread:        task.Spread,
+		RetainRuns:    task.RetainRuns,
+		RetainDays:    task.RetainDays,
+		LocalSchedule: task.LocalSchedule,
+		Enabled:       &enabled,
+	}
+	if spec.Executor == "" {
+		spec.Executor = fmt.Sprint(int(task.ExecutorType))
+	}
+	if task.ExecutorConfig != "" {
+		_ = json.Unmarshal([]byte(task.ExecutorConfig), &spec.Config)
+	}
+	for _, group := range task.Groups {
+		spec.Groups = append(spec.Groups, group.Name)
+	}
+	sort.Strings(spec.Groups)
+	return spec
+}
+
+// groupSpec 将分组转换为分组定义
+func groupSpec(group *models.TaskGroup) *BundleGroup {
+	return &BundleGroup{
+		Name:        group.Name,
+		Description: group.Description,
+		Tags:        group.Tags,
+		Selector:    group.Selector,
+	}
+}
+
+// diffTask 比较任务定义（均已填充默认值），返回变化的字段
+func diffTask(current, desired *BundleTask) []string {
+	var fields []string
+	check := func(name string, changed bool) {
+		if changed {
+			fields = append(fields, name)
+		}
+	}
+	check("description", current.Description != desired.Description)
+	check("executor", current.Executor != desired.Executor)
+	check("config", configJSON(current.Config) != configJSON(desired.Config))
+	check("cron", current.Cron != desired.Cron)
+	check("timeout", *current.Timeout != *desired.Timeout)
+	check("retry_count", current.RetryCount != desired.RetryCount)
+	check("retry_interval", *current.RetryInterval != *desired.RetryInterval)
+	check("concurrency", current.Concurrency != desired.Concurrency)
+	check("overlap_policy", current.OverlapPolicy != desired.OverlapPolicy)
+	check("misfire_policy", current.MisfirePolicy != desired.MisfirePolicy)
+	check("jitter", current.Jitter != desired.Jitter)
+	check("spread", current.Spread != desired.Spread)
+	check("retain_runs", current.RetainRuns != desired.RetainRuns)
+	check("retain_days", current.RetainDays != desired.RetainDays)
+	check("local_schedule", current.LocalSchedule != desired.LocalSchedule)
+	check("enabled", *current.Enabled != *desired.Enabled)
+	check("groups", len(current.Groups)+len(desired.Groups) > 0 && !reflect.DeepEqual(current.Groups, desired.Groups))
+	return fields
+}
+
+// diffGroup 比较分组定义，返回变化的字段
+func diffGroup(current, desired *BundleGroup) []string {
+	var fields []string
+	if current.Description != desired.Description {
+		fields = append(fields, "description")
+	}
+	if current.Tags != desired.Tags {
+		fields = append(fields, "tags")
+	}
+	currentSelector, _ := json.Marshal(current.Selector)
+	desiredSelector, _ := json.Marshal(desired.Selector)
+	if !bytes.Equal(currentSelector, desiredSelector) {
+		fields = append(fields, "selector")
+	}
+	return fields
+}
+
+// bundleGroupIDs 将任务定义中的分组名称转换为分组ID
+func bundleGroupIDs(task *BundleTask, groups map[string]*models.TaskGroup) ([]int64, error) {
+	ids := make([]int64, 0, len(task.Groups))
+	for _, name := range task.Groups {
+		group := groups[name]
+		if group == nil {
+			return nil, fmt.Errorf("group %q not found", name)
+		}
+		ids = append(ids, group.ID)
+	}
+	return ids, nil
+}
+
+// executorType 解析执行器名称
+func executorType(name string) (models.ExecutorType, bool) {
+	for executor, executorName := range executorNames {
+		if executorName == name {
+			return executor, true
+		}
+	}
+	return 0, false
+}
+
+// configJSON 将执行器配置转换为 JSON（键有序），空配置为 {}
+func configJSON(config map[string]interface{}) string {
+	if len(config) == 0 {
+		return "{}"
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
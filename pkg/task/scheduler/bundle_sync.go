@@ -0,0 +1,91 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// BundleSource 任务定义的同步来源（如 Git 仓库）
+type BundleSource interface {
+	// Load 读取任务定义，revision 为来源的版本（如提交哈希）
+	Load(ctx context.Context) (bundle *Bundle, revision string, err error)
+}
+
+// BundleSyncStatus 任务定义同步状态
+type BundleSyncStatus struct {
+	Enabled  bool         `json:"enabled"`
+	Prune    bool         `json:"prune"`
+	Interval string       `json:"interval,omitempty"`  // 同步间隔
+	LastSync *time.Time   `json:"last_sync,omitempty"` // 最近一次同步时间
+	Revision string       `json:"revision,omitempty"`  // 最近一次成功应用的版本
+	Result   *ApplyResult `json:"result,omitempty"`    // 最近一次成功同步的结果
+	Error    string       `json:"error,omitempty"`     // 最近一次同步失败的原因
+}
+
+// EnableBundleSync 按 interval 定期从 source 读取任务定义并应用，prune 时删除来源中不存在的任务与分组。
+// 每次同步都完整应用，在界面上对任务的修改会在下次同步时被覆盖
+func (m *TaskManager) EnableBundleSync(source BundleSource, interval time.Duration, prune bool) error {
+	if interval <= 0 {
+		return fmt.Errorf("sync interval must be positive")
+	}
+	m.syncMu.Lock()
+	m.bundleSource = source
+	m.bundlePrune = prune
+	m.syncStatus.Enabled = true
+	m.syncStatus.Prune = prune
+	m.syncStatus.Interval = interval.String()
+	m.syncMu.Unlock()
+
+	return m.cron.AddFunc("task-bundle-sync", "@every "+interval.String(), func(ctx context.Context) {
+		if _, err := m.SyncBundle(ctx, false); err != nil {
+			m.logger.Error("Failed to sync task bundle", "error", err)
+		}
+	})
+}
+
+// SyncBundle 立即从同步来源读取任务定义并应用，dryRun 时只计算变更且不更新同步状态
+func (m *TaskManager) SyncBundle(ctx context.Context, dryRun bool) (*ApplyResult, error) {
+	m.syncMu.Lock()
+	source, prune := m.bundleSource, m.bundlePrune
+	m.syncMu.Unlock()
+	if source == nil {
+		return nil, fmt.Errorf("task bundle sync not configured")
+	}
+
+	bundle, revision, err := source.Load(ctx)
+	var result *ApplyResult
+	if err == nil {
+		result, err = m.ApplyBundle(ctx, bundle, ApplyOptions{DryRun: dryRun, Prune: prune})
+	}
+	if dryRun {
+		if result != nil {
+			result.Revision = revision
+		}
+		return result, err
+	}
+
+	now := time.Now()
+	m.syncMu.Lock()
+	defer m.syncMu.Unlock()
+	m.syncStatus.LastSync = &now
+	if err != nil {
+		m.syncStatus.Error = err.Error()
+		return nil, err
+	}
+	result.Revision = revision
+	m.syncStatus.Revision = revision
+	m.syncStatus.Result = result
+	m.syncStatus.Error = ""
+	if len(result.Changes) > 0 {
+		m.logger.Info("Task bundle synced", "revision", revision, "changes", len(result.Changes), "errors", len(result.Errors))
+	}
+	return result, nil
+}
+
+// BundleSyncStatus 获取任务定义同步状态
+func (m *TaskManager) BundleSyncStatus() BundleSyncStatus {
+	m.syncMu.Lock()
+	defer m.syncMu.Unlock()
+	return m.syncStatus
+}
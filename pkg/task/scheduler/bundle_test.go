@@ -0,0 +1,247 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/task/models"
+)
+
+const testBundle = `
+groups:
+  - name: web
+    description: web servers
+  - name: prod
+    selector:
+      labels:
+        env: prod
+tasks:
+  - name: cleanup
+    executor: shell
+    config:
+      command: find /tmp -mtime +7 -delete
+    cron: "0 0 3 * * *"
+    groups: [web, prod]
+---
+tasks:
+  - name: probe
+    executor: http
+    config:
+      url: http://127.0.0.1:8080/health
+      expect_status: [200]
+    cron: "@every 1m"
+    timeout: 0
+    overlap_policy: queue
+    enabled: false
+`
+
+func applyBundle(t *testing.T, m *TaskManager, data string, opts ApplyOptions) *ApplyResult {
+	bundle, err := ParseBundle([]byte(data))
+	require.NoError(t, err)
+	result, err := m.ApplyBundle(context.Background(), bundle, opts)
+	require.NoError(t, err)
+	require.Empty(t, result.Errors)
+	return result
+}
+
+func TestTaskManager_ApplyBundle(t *testing.T) {
+	env := newTestManager(t)
+	m, taskStore, groupStore := env.m, env.tasks, env.groups
+	ctx := context.Background()
+
+	// 试运行不修改
+	result := applyBundle(t, m, testBundle, ApplyOptions{DryRun: true})
+	assert.True(t, result.DryRun)
+	assert.Len(t, result.Changes, 4)
+	tasks, err := taskStore.ListAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+
+	result = applyBundle(t, m, testBundle, ApplyOptions{})
+	assert.Equal(t, []*BundleChange{
+		{Kind: "group", Name: "web", Action: "create"},
+		{Kind: "group", Name: "prod", Action: "create"},
+		{Kind: "task", Name: "cleanup", Action: "create"},
+		{Kind: "task", Name: "probe", Action: "create"},
+	}, result.Changes)
+
+	tasks, err = taskStore.ListAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	cleanup, probe := tasks[0], tasks[1]
+	assert.Equal(t, models.ExecutorTypeShell, cleanup.ExecutorType)
+	assert.JSONEq(t, `{"command":"find /tmp -mtime +7 -delete"}`, cleanup.ExecutorConfig)
+	assert.Equal(t, 300, cleanup.Timeout)
+	assert.True(t, cleanup.IsEnabled())
+	assert.Len(t, cleanup.Groups, 2)
+	groups, err := groupStore.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	for _, group := range groups {
+		assert.Equal(t, group.Name == "prod", group.IsDynamic(), group.Name)
+	}
+	assert.Equal(t, models.ExecutorTypeHTTP, probe.ExecutorType)
+	assert.Zero(t, probe.Timeout, "explicit zero timeout")
+	assert.Equal(t, models.OverlapQueue, probe.OverlapPolicy)
+	assert.False(t, probe.IsEnabled())
+
+	// 重复应用不产生变更
+	result = applyBundle(t, m, testBundle, ApplyOptions{})
+	assert.Empty(t, result.Changes)
+	assert.Equal(t, 4, result.Unchanged)
+
+	// 修改后试运行列出变化的字段
+	changed := `
+groups:
+  - name: web
+tasks:
+  - name: cleanup
+    executor: shell
+    config: {command: "find /tmp -mtime +3 -delete"}
+    cron: "0 0 4 * * *"
+    groups: [web]
+`
+	result = applyBundle(t, m, changed, ApplyOptions{DryRun: true, Prune: true})
+	assert.Equal(t, []*BundleChange{
+		{Kind: "group", Name: "web", Action: "update", Fields: []string{"description"}},
+		{Kind: "task", Name: "cleanup", Action: "update", Fields: []string{"config", "cron", "groups"}},
+		{Kind: "task", Name: "probe", Action: "delete"},
+		{Kind: "group", Name: "prod", Action: "delete"},
+	}, result.Changes)
+
+	applyBundle(t, m, changed, ApplyOptions{Prune: true})
+	tasks, err = taskStore.ListAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "0 0 4 * * *", tasks[0].CronExpr)
+	require.Len(t, tasks[0].Groups, 1)
+	assert.Equal(t, "web", tasks[0].Groups[0].Name)
+	groups, err = groupStore.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, groups, 1)
+}
+
+func TestTaskManager_ApplyBundleInvalid(t *testing.T) {
+	m := newTestManager(t).m
+
+	for name, data := range map[string]string{
+		"unknown field":    "tasks:\n  - name: a\n    executor: shell\n    cron: '@hourly'\n    retries: 3\n",
+		"unknown executor": "tasks:\n  - name: a\n    executor: ssh\n    cron: '@hourly'\n",
+		"invalid cron":     "tasks:\n  - name: a\n    executor: shell\n    cron: 'every day'\n",
+		"duplicate task":   "tasks:\n  - {name: a, executor: shell, cron: '@hourly'}\n---\ntasks:\n  - {name: a, executor: shell, cron: '@daily'}\n",
+		"unknown group":    "tasks:\n  - {name: a, executor: shell, cron: '@hourly', groups: [db]}\n",
+	} {
+		bundle, err := ParseBundle([]byte(data))
+		if err == nil {
+			_, err = m.ApplyBundle(context.Background(), bundle, ApplyOptions{DryRun: true})
+		}
+		assert.True(t, errors.Is(err, ErrInvalidBundle), name)
+	}
+}
+
+func TestTaskManager_ApplyBundleRefusesEmptyPrune(t *testing.T) {
+	env := newTestManager(t)
+	m, taskStore := env.m, env.tasks
+	ctx := context.Background()
+	applyBundle(t, m, testBundle, ApplyOptions{})
+
+	empty, err := ParseBundle(nil)
+	require.NoError(t, err)
+	_, err = m.ApplyBundle(ctx, empty, ApplyOptions{Prune: true})
+	assert.True(t, errors.Is(err, ErrInvalidBundle))
+	tasks, err := taskStore.ListAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, tasks, 2)
+
+	// Git 来源为空时同步失败，不删除任务
+	require.NoError(t, m.EnableBundleSync(&fakeBundleSource{revision: "empty"}, time.Minute, true))
+	_, err = m.SyncBundle(ctx, false)
+	assert.Error(t, err)
+	tasks, err = taskStore.ListAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, tasks, 2)
+
+	result, err := m.ApplyBundle(ctx, empty, ApplyOptions{Prune: true, AllowEmpty: true})
+	require.NoError(t, err)
+	assert.Len(t, result.Changes, 4)
+}
+
+func TestTaskManager_ExportBundle(t *testing.T) {
+	m := newTestManager(t).m
+	ctx := context.Background()
+	applyBundle(t, m, testBundle, ApplyOptions{})
+
+	bundle, err := m.ExportBundle(ctx)
+	require.NoError(t, err)
+	require.Len(t, bundle.Tasks, 2)
+	assert.Equal(t, []string{"prod", "web"}, bundle.Tasks[0].Groups)
+	assert.Nil(t, bundle.Tasks[0].Timeout, "default timeout is omitted")
+	require.NotNil(t, bundle.Tasks[1].Timeout)
+	assert.Zero(t, *bundle.Tasks[1].Timeout)
+
+	// 导出的定义应用到新环境后与原环境一致，再次应用不产生变更
+	data, err := bundle.Marshal()
+	require.NoError(t, err)
+	other := newTestManager(t).m
+	applyBundle(t, other, string(data), ApplyOptions{})
+	result := applyBundle(t, other, testBundle, ApplyOptions{Prune: true})
+	assert.Empty(t, result.Changes)
+	result = applyBundle(t, m, string(data), ApplyOptions{Prune: true})
+	assert.Empty(t, result.Changes)
+}
+
+// fakeBundleSource 返回固定任务定义的同步来源
+type fakeBundleSource struct {
+	data     string
+	revision string
+	err      error
+}
+
+func (s *fakeBundleSource) Load(ctx context.Context) (*Bundle, string, error) {
+	if s.err != nil {
+		return nil, "", s.err
+	}
+	bundle, err := ParseBundle([]byte(s.data))
+	return bundle, s.revision, err
+}
+
+func TestTaskManager_SyncBundle(t *testing.T) {
+	env := newTestManager(t)
+	m, taskStore := env.m, env.tasks
+	ctx := context.Background()
+
+	_, err := m.SyncBundle(ctx, false)
+	assert.Error(t, err, "sync not configured")
+
+	source := &fakeBundleSource{data: testBundle, revision: "abc123"}
+	require.NoError(t, m.EnableBundleSync(source, time.Minute, true))
+
+	result, err := m.SyncBundle(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", result.Revision)
+	assert.Nil(t, m.BundleSyncStatus().LastSync, "dry run does not update status")
+
+	result, err = m.SyncBundle(ctx, false)
+	require.NoError(t, err)
+	assert.Len(t, result.Changes, 4)
+	status := m.BundleSyncStatus()
+	assert.True(t, status.Enabled)
+	assert.Equal(t, "1m0s", status.Interval)
+	assert.Equal(t, "abc123", status.Revision)
+	require.NotNil(t, status.LastSync)
+
+	source.err = errors.New("clone failed")
+	_, err = m.SyncBundle(ctx, false)
+	assert.Error(t, err)
+	status = m.BundleSyncStatus()
+	assert.Equal(t, "clone failed", status.Error)
+	assert.Equal(t, "abc123", status.Revision, "last applied revision is kept")
+
+	tasks, err := taskStore.ListAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, tasks, 2)
+}
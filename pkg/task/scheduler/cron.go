@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/robfig/cron/v3"
+	"github.com/voilet/quic-flow/pkg/calendar"
 	"github.com/voilet/quic-flow/pkg/task/models"
 	"github.com/voilet/quic-flow/pkg/monitoring"
 )
@@ -17,11 +18,21 @@ type CronScheduler struct {
 	logger         *monitoring.Logger
 	taskDispatcher *TaskDispatcher
 	jobRegistry    map[cron.EntryID]int64 // EntryID -> TaskID
+	workflowJobs   map[int64]cron.EntryID // WorkflowID -> EntryID
 	registryMu     sync.RWMutex
 	ctx            context.Context
 	cancel         context.CancelFunc
+	misfireDelay   time.Duration   // 启动后延迟补偿错过的触发，给 Agent 留出重连的时间
+	calendar       *calendar.Store // 禁止窗口（可选，见 blackout.go）
 }
 
+const (
+	// defaultMisfireDelay 启动后补偿错过的触发前的等待时间
+	defaultMisfireDelay = 30 * time.Second
+	// maxMisfireRuns fire_all 策略最多补偿的次数
+	maxMisfireRuns = 10
+)
+
 // NewCronScheduler 创建调度器
 func NewCronScheduler(logger *monitoring.Logger, dispatcher *TaskDispatcher) *CronScheduler {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -36,8 +47,10 @@ func NewCronScheduler(logger *monitoring.Logger, dispatcher *TaskDispatcher) *Cr
 		logger:         logger,
 		taskDispatcher: dispatcher,
 		jobRegistry:    make(map[cron.EntryID]int64),
+		workflowJobs:   make(map[int64]cron.EntryID),
 		ctx:            ctx,
 		cancel:         cancel,
+		misfireDelay:   defaultMisfireDelay,
 	}
 }
 
@@ -71,6 +84,11 @@ func (s *CronScheduler) AddTask(task *models.Task) (cron.EntryID, error) {
 		return 0, fmt.Errorf("invalid cron expression: %w", err)
 	}
 
+	// 本地调度的任务由 Agent 按下发的配置执行，服务器不调度
+	if task.LocalSchedule {
+		return 0, nil
+	}
+
 	// 创建 Job
 	job := s.createJob(task)
 
@@ -148,8 +166,18 @@ func (s *CronScheduler) createJob(task *models.Task) func() {
 				}
 			}()
 
+			// 记录触发时间，服务器重启后据此判断错过的触发
+			if err := s.taskDispatcher.taskStore.SetLastFireTime(s.ctx, task.ID, time.Now()); err != nil {
+				s.logger.Warn("Failed to record task fire time", "task_id", task.ID, "error", err)
+			}
+
+			// 禁止窗口内跳过本次触发；触发时间照常记录，重启后不作为错过的触发补偿
+			if s.suppressed(s.ctx, task) {
+				return
+			}
+
 			// 执行任务分发
-			if err := s.taskDispatcher.Dispatch(s.ctx, task); err != nil {
+			if err := s.taskDispatcher.Dispatch(s.ctx, task, models.ExecutionTypeScheduled); err != nil {
 				s.logger.Error("Failed to dispatch task",
 					"task_id", task.ID,
 					"error", err)
@@ -158,6 +186,76 @@ func (s *CronScheduler) createJob(task *models.Task) func() {
 	}
 }
 
+// CatchUp 按任务的错过策略补偿服务器停机期间错过的定时触发（最近触发时间到 now 之间），返回补偿的次数
+// 补偿在 misfireDelay 后执行；无论是否补偿，最近触发时间都更新为 now，避免再次重启时重复计算
+func (s *CronScheduler) CatchUp(task *models.Task, now time.Time) int {
+	if task.LastFireTime == nil || task.LocalSchedule {
+		return 0
+	}
+	missed, err := missedFireTimes(task.CronExpr, *task.LastFireTime, now, maxMisfireRuns)
+	if err != nil {
+		s.logger.Warn("Failed to compute missed fire times", "task_id", task.ID, "error", err)
+		return 0
+	}
+	if len(missed) == 0 {
+		return 0
+	}
+	if err := s.taskDispatcher.taskStore.SetLastFireTime(s.ctx, task.ID, now); err != nil {
+		s.logger.Warn("Failed to record task fire time", "task_id", task.ID, "error", err)
+	}
+
+	runs := 0
+	switch task.GetMisfirePolicy() {
+	case models.MisfireFireOnce:
+		runs = 1
+	case models.MisfireFireAll:
+		runs = len(missed)
+	}
+	s.logger.Info("Task misfired during downtime",
+		"task_id", task.ID,
+		"task_name", task.Name,
+		"missed_since", missed[0],
+		"policy", string(task.GetMisfirePolicy()),
+		"runs", runs)
+	if runs == 0 {
+		return 0
+	}
+
+	go func() {
+		timer := time.NewTimer(s.misfireDelay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-s.ctx.Done():
+			return
+		}
+		if s.suppressed(s.ctx, task) {
+			return
+		}
+		// 依次补偿，每次都按任务的重叠策略判定
+		for i := 0; i < runs; i++ {
+			if err := s.taskDispatcher.Dispatch(s.ctx, task, models.ExecutionTypeScheduled); err != nil {
+				s.logger.Error("Failed to dispatch misfired task", "task_id", task.ID, "error", err)
+			}
+		}
+	}()
+	return runs
+}
+
+// missedFireTimes 计算 (last, now] 区间内按 Cron 表达式应触发的时间，最多返回 limit 个（最早的）
+func missedFireTimes(expr string, last, now time.Time, limit int) ([]time.Time, error) {
+	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+	schedule, err := parser.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	var missed []time.Time
+	for t := schedule.Next(last); !t.IsZero() && !t.After(now) && len(missed) < limit; t = schedule.Next(t) {
+		missed = append(missed, t)
+	}
+	return missed, nil
+}
+
 // validateCronExpr 验证 Cron 表达式
 func (s *CronScheduler) validateCronExpr(expr string) error {
 	// 使用 cron 解析器验证（支持秒级表达式）
@@ -183,3 +281,96 @@ func (s *CronScheduler) GetNextRunTime(taskID int64) (time.Time, error) {
 
 	return time.Time{}, fmt.Errorf("task %d not found in registry", taskID)
 }
+
+// AddWorkflow 按工作流的 Cron 表达式注册定时运行，run 在独立 goroutine 中执行
+// 已注册的同一工作流先移除；没有 Cron 表达式的工作流只能手动触发，不注册
+func (s *CronScheduler) AddWorkflow(workflow *models.Workflow, run func(ctx context.Context)) error {
+	s.RemoveWorkflow(workflow.ID)
+	if !workflow.IsEnabled() || workflow.CronExpr == "" {
+		return nil
+	}
+	if err := s.validateCronExpr(workflow.CronExpr); err != nil {
+		return fmt.Errorf("invalid cron expression: %w", err)
+	}
+
+	workflowID := workflow.ID
+	entryID, err := s.cron.AddFunc(workflow.CronExpr, func() {
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					s.logger.Error("Workflow panic", "workflow_id", workflowID, "panic", r)
+				}
+			}()
+			// 与任务相同，受全局窗口与作用于任一节点任务关联分组的窗口限制
+			if windows := s.workflowBlackouts(s.ctx, workflow, time.Now()); len(windows) > 0 {
+				s.logger.Info("Workflow run suppressed by blackout window",
+					"workflow_id", workflowID,
+					"windows", calendar.Names(windows))
+				return
+			}
+			run(s.ctx)
+		}()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add cron job: %w", err)
+	}
+
+	s.registryMu.Lock()
+	s.workflowJobs[workflowID] = entryID
+	s.registryMu.Unlock()
+
+	s.logger.Info("Workflow added to scheduler",
+		"workflow_id", workflowID,
+		"workflow_name", workflow.Name,
+		"cron_expr", workflow.CronExpr)
+	return nil
+}
+
+// AddFunc 注册系统定时作业（如执行记录清理），fn 在独立 goroutine 中执行
+func (s *CronScheduler) AddFunc(name, spec string, fn func(ctx context.Context)) error {
+	_, err := s.cron.AddFunc(spec, func() {
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					s.logger.Error("Scheduled job panic", "job", name, "panic", r)
+				}
+			}()
+			fn(s.ctx)
+		}()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add cron job %s: %w", name, err)
+	}
+	s.logger.Info("Scheduled job added", "job", name, "spec", spec)
+	return nil
+}
+
+// RemoveWorkflow 移除工作流的定时运行，未注册时返回 false
+func (s *CronScheduler) RemoveWorkflow(workflowID int64) bool {
+	s.registryMu.Lock()
+	entryID, ok := s.workflowJobs[workflowID]
+	delete(s.workflowJobs, workflowID)
+	s.registryMu.Unlock()
+
+	if ok {
+		s.cron.Remove(entryID)
+		s.logger.Info("Workflow removed from scheduler", "workflow_id", workflowID)
+	}
+	return ok
+}
+
+// GetWorkflowNextRunTime 获取工作流下次运行时间
+func (s *CronScheduler) GetWorkflowNextRunTime(workflowID int64) (time.Time, error) {
+	s.registryMu.RLock()
+	entryID, ok := s.workflowJobs[workflowID]
+	s.registryMu.RUnlock()
+	if !ok {
+		return time.Time{}, fmt.Errorf("workflow %d not found in registry", workflowID)
+	}
+
+	entry := s.cron.Entry(entryID)
+	if entry.ID == 0 {
+		return time.Time{}, fmt.Errorf("entry not found")
+	}
+	return entry.Next, nil
+}
@@ -2,119 +2,244 @@ package scheduler
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
-	"github.com/voilet/quic-flow/pkg/dispatcher"
+	"github.com/google/uuid"
+	"github.com/voilet/quic-flow/pkg/callback"
+	"github.com/voilet/quic-flow/pkg/command"
 	"github.com/voilet/quic-flow/pkg/protocol"
 	"github.com/voilet/quic-flow/pkg/session"
 	"github.com/voilet/quic-flow/pkg/task/models"
 	"github.com/voilet/quic-flow/pkg/task/store"
 	"github.com/voilet/quic-flow/pkg/monitoring"
-	"google.golang.org/protobuf/proto"
 )
 
+// dispatchAckTimeout 等待客户端接受任务的超时时间
+const dispatchAckTimeout = 30 * time.Second
+
+// TaskSender 向客户端发送消息并等待确认（*server.Server 实现）
+type TaskSender interface {
+	SendToWithPromise(clientID string, msg *protocol.DataMessage, timeout time.Duration) (*callback.Promise, error)
+}
+
 // TaskDispatcher 任务分发器
+// 为每个目标客户端创建执行记录并下发 task.execute 命令，
+// 客户端上报的 task.progress/task.result 事件更新执行记录（见 result.go）
 type TaskDispatcher struct {
-	dispatcher *dispatcher.Dispatcher
-	sessionMgr *session.SessionManager
-	taskStore  store.TaskStore
-	logger     *monitoring.Logger
+	sender         TaskSender
+	sessionMgr     *session.SessionManager
+	taskStore      store.TaskStore
+	executionStore store.ExecutionStore
+	statsStore     store.DailyStatsStore // 本地调度任务的每日统计（可选）
+	logger         *monitoring.Logger
+
+	execMu       sync.Mutex                   // 串行化执行记录的读-改-写
+	hook         func(exec *models.Execution) // 执行记录更新通知（如 WebSocket 推送）
+	workflowHook func(exec *models.Execution) // 工作流节点执行结束通知（推进工作流运行）
+	listClients  func() []string              // 在线客户端列表
+	slots        sync.Map                     // slotKey -> *sync.Mutex，串行化同一任务、同一客户端的并发判定
+
+	logHook        func(chunk *models.ExecutionLogChunk) // 执行日志增量通知（实时日志）
+	logStorage     LogStorage                            // 大输出的文件存储（可选，见 logs.go）
+	logOffloadSize int                                   // 输出转存阈值
+
+	resolver   TargetResolver   // 动态分组的信息来源（可选，见 targeting.go）
+	groupStore store.GroupStore // 静态分组的成员关系（未设置时静态分组无法下发）
+
+	suppressed func(ctx context.Context, task *models.Task) bool // 延迟下发前重新检查禁止窗口（由 CronScheduler.SetCalendar 设置）
 }
 
 // NewTaskDispatcher 创建任务分发器
 func NewTaskDispatcher(
-	disp *dispatcher.Dispatcher,
+	sender TaskSender,
 	sessionMgr *session.SessionManager,
 	taskStore store.TaskStore,
+	executionStore store.ExecutionStore,
 	logger *monitoring.Logger,
 ) *TaskDispatcher {
-	return &TaskDispatcher{
-		dispatcher: disp,
-		sessionMgr: sessionMgr,
-		taskStore:  taskStore,
-		logger:     logger,
+	d := &TaskDispatcher{
+		sender:         sender,
+		sessionMgr:     sessionMgr,
+		taskStore:      taskStore,
+		executionStore: executionStore,
+		logger:         logger,
+		listClients:    func() []string { return nil },
 	}
+	if sessionMgr != nil {
+		d.listClients = sessionMgr.ListClientIDs
+	}
+	return d
+}
+
+// SetExecutionHook 设置执行记录更新通知，需在分发任务前调用
+func (d *TaskDispatcher) SetExecutionHook(hook func(exec *models.Execution)) {
+	d.hook = hook
 }
 
-// Dispatch 分发任务到目标客户端
-func (d *TaskDispatcher) Dispatch(ctx context.Context, task *models.Task) error {
+// Dispatch 分发任务到目标客户端，execType 区分定时执行与手动触发
+func (d *TaskDispatcher) Dispatch(ctx context.Context, task *models.Task, execType models.ExecutionType) error {
 	if task == nil {
 		return fmt.Errorf("task is nil")
 	}
 
+	targetClients, err := d.targetClients(ctx, task)
+	if err != nil {
+		return err
+	}
+	if len(targetClients) == 0 {
+		return nil
+	}
+
+	// 分发到每个客户端，定时执行按任务的分散窗口与随机延迟错开下发时间
+	var lastErr error
+	for _, clientID := range targetClients {
+		if delay := dispatchDelay(task, clientID, execType); delay > 0 {
+			d.dispatchLater(ctx, task.ID, clientID, execType, delay)
+			continue
+		}
+		if err := d.dispatchToClient(ctx, clientID, task, execType); err != nil {
+			d.logger.Error("Failed to dispatch task to client",
+				"task_id", task.ID,
+				"client_id", clientID,
+				"error", err)
+			lastErr = err
+			// 继续分发到其他客户端，不因单个失败而中断
+		}
+	}
+
+	return lastErr
+}
+
+// targetClients 获取任务关联分组下的在线客户端（去重），动态分组在此时按选择器筛选
+// 没有关联分组或没有在线客户端时记录警告并返回空列表
+func (d *TaskDispatcher) targetClients(ctx context.Context, task *models.Task) ([]string, error) {
 	// 获取任务关联的分组
-	groupIDs, err := d.taskStore.GetGroupIDs(ctx, task.ID)
+	groups, err := d.taskStore.GetGroups(ctx, task.ID)
 	if err != nil {
-		return fmt.Errorf("failed to get task groups: %w", err)
+		return nil, fmt.Errorf("failed to get task groups: %w", err)
 	}
 
 	// 如果没有关联分组，记录警告但不返回错误
-	if len(groupIDs) == 0 {
+	if len(groups) == 0 {
 		d.logger.Warn("Task has no associated groups", "task_id", task.ID, "task_name", task.Name)
-		return nil
+		return nil, nil
 	}
 
 	// 获取分组下的所有在线客户端
 	var targetClients []string
-	for _, groupID := range groupIDs {
-		clients, err := d.getOnlineClientsByGroup(ctx, groupID)
+	seen := make(map[string]bool)
+	for _, group := range groups {
+		clients, err := d.ResolveGroup(ctx, group)
 		if err != nil {
-			d.logger.Warn("Failed to get clients for group", "group_id", groupID, "error", err)
+			d.logger.Warn("Failed to get clients for group", "group_id", group.ID, "error", err)
 			continue
 		}
-		targetClients = append(targetClients, clients...)
+		for _, clientID := range clients {
+			if !seen[clientID] {
+				seen[clientID] = true
+				targetClients = append(targetClients, clientID)
+			}
+		}
 	}
 
 	if len(targetClients) == 0 {
 		d.logger.Warn("No online clients found for task", "task_id", task.ID, "task_name", task.Name)
+	}
+	return targetClients, nil
+}
+
+// dispatchToClient 分发任务到单个客户端
+func (d *TaskDispatcher) dispatchToClient(ctx context.Context, clientID string, task *models.Task, execType models.ExecutionType) error {
+	return d.dispatchExecution(ctx, task, &models.Execution{
+		ClientID:      clientID,
+		ExecutionType: execType,
+	})
+}
+
+// dispatchExecution 按执行记录模板（客户端、执行类型、所属工作流）分发任务
+// 先按并发上限与重叠策略决定本次执行是下发、排队还是跳过（见 overlap.go），
+// 再创建执行记录（其 ID 即执行 ID）并下发 task.execute；跳过时不创建执行记录
+func (d *TaskDispatcher) dispatchExecution(ctx context.Context, task *models.Task, execution *models.Execution) error {
+	clientID := execution.ClientID
+	execution.TaskID = task.ID
+	execution.TaskName = task.Name
+	execution.CreatedAt = time.Now()
+
+	unlock := d.lockSlot(task.ID, clientID)
+	adm, err := d.admit(ctx, task, clientID)
+	if err != nil {
+		unlock()
+		return err
+	}
+	if adm.skip {
+		unlock()
+		d.logger.Info("Task execution skipped",
+			"task_id", task.ID,
+			"task_name", task.Name,
+			"client_id", clientID,
+			"reason", adm.reason)
 		return nil
 	}
+	execution.Status = adm.status
+	if err := d.executionStore.Create(ctx, execution); err != nil {
+		unlock()
+		return fmt.Errorf("failed to create execution record: %w", err)
+	}
+	unlock()
+	d.notify(execution)
 
-	// 分发到每个客户端
-	var lastErr error
-	for _, clientID := range targetClients {
-		if err := d.dispatchToClient(ctx, clientID, task); err != nil {
-			d.logger.Error("Failed to dispatch task to client",
-				"task_id", task.ID,
-				"client_id", clientID,
-				"error", err)
-			lastErr = err
-			// 继续分发到其他客户端，不因单个失败而中断
+	for _, previous := range adm.cancel {
+		d.cancelExecution(previous)
+	}
+	if execution.Status == models.ExecutionStatusQueued {
+		d.logger.Info("Task execution queued",
+			"task_id", task.ID,
+			"client_id", clientID,
+			"execution_id", execution.ID)
+		if adm.promote {
+			go d.startQueued(task.ID, clientID)
 		}
+		return nil
 	}
-
-	return lastErr
+	return d.sendExecution(task, execution)
 }
 
-// dispatchToClient 分发任务到单个客户端
-func (d *TaskDispatcher) dispatchToClient(ctx context.Context, clientID string, task *models.Task) error {
-	// 生成执行ID（使用时间戳+任务ID）
-	executionID := fmt.Sprintf("%d-%d", task.ID, time.Now().UnixMilli())
+// sendExecution 向客户端下发 task.execute，客户端是否接受在后台等待确认
+func (d *TaskDispatcher) sendExecution(task *models.Task, execution *models.Execution) error {
+	clientID := execution.ClientID
+	executionID := strconv.FormatInt(execution.ID, 10)
 
 	// 构造任务执行消息
 	execMsg := &protocol.TaskExecution{
 		ExecutionId:    executionID,
-		TaskId:         fmt.Sprintf("%d", task.ID),
+		TaskId:         strconv.FormatInt(task.ID, 10),
 		TaskName:       task.Name,
 		ExecutorType:   protocol.ExecutorType(task.ExecutorType),
 		ExecutorConfig: task.ExecutorConfig,
 		Timeout:        int32(task.Timeout),
 		RetryCount:     int32(task.RetryCount),
 		RetryInterval:  int32(task.RetryInterval),
-		ExecutionType:  protocol.ExecutionType_EXECUTION_TYPE_SCHEDULED,
+		ExecutionType:  protocol.ExecutionType(execution.ExecutionType),
 		Timestamp:      time.Now().UnixMilli(),
 	}
-
-	// 序列化消息
-	payload, err := proto.Marshal(execMsg)
+	data, err := json.Marshal(execMsg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal task execution: %w", err)
 	}
+	payload, err := json.Marshal(command.CommandPayload{
+		CommandType: command.CmdTaskExecute,
+		Payload:     data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal command payload: %w", err)
+	}
 
-	// 构造数据消息
 	msg := &protocol.DataMessage{
-		MsgId:      executionID,
+		MsgId:      uuid.New().String(),
 		SenderId:   "server",
 		ReceiverId: clientID,
 		Type:       protocol.MessageType_MESSAGE_TYPE_COMMAND,
@@ -123,10 +248,12 @@ func (d *TaskDispatcher) dispatchToClient(ctx context.Context, clientID string,
 		Timestamp:  time.Now().UnixMilli(),
 	}
 
-	// 通过 dispatcher 发送消息
-	if err := d.dispatcher.Dispatch(ctx, msg, nil); err != nil {
+	promise, err := d.sender.SendToWithPromise(clientID, msg, dispatchAckTimeout)
+	if err != nil {
+		d.failExecution(execution.ID, fmt.Sprintf("dispatch failed: %v", err))
 		return fmt.Errorf("failed to send message: %w", err)
 	}
+	go d.waitAccepted(execution.ID, clientID, promise)
 
 	d.logger.Info("Task dispatched to client",
 		"task_id", task.ID,
@@ -137,19 +264,46 @@ func (d *TaskDispatcher) dispatchToClient(ctx context.Context, clientID string,
 	return nil
 }
 
-// getOnlineClientsByGroup 获取分组下的在线客户端ID列表
+// waitAccepted 等待客户端确认接受任务，未接受时将执行记录标记为失败
+func (d *TaskDispatcher) waitAccepted(executionID int64, clientID string, promise *callback.Promise) {
+	resp := <-promise.RespChan
+	var reason string
+	switch {
+	case resp.Error != nil:
+		reason = resp.Error.Error()
+	case resp.AckMessage == nil:
+		reason = "no ack from client"
+	case resp.AckMessage.Status != protocol.AckStatus_ACK_STATUS_SUCCESS:
+		reason = resp.AckMessage.Error
+	default:
+		var accept command.TaskAcceptResult
+		if err := json.Unmarshal(resp.AckMessage.Result, &accept); err != nil {
+			reason = fmt.Sprintf("invalid accept result: %v", err)
+		} else if !accept.Accepted {
+			reason = accept.Error
+		}
+	}
+	if reason == "" {
+		return
+	}
+	d.logger.Warn("Task rejected by client", "execution_id", executionID, "client_id", clientID, "reason", reason)
+	d.failExecution(executionID, "dispatch failed: "+reason)
+}
+
+// getOnlineClientsByGroup 获取静态分组下的在线客户端ID列表（成员见 tb_client_group_relation），
+// 保持在线客户端列表的顺序。未设置分组存储时返回错误，不会退化为所有在线客户端
 func (d *TaskDispatcher) getOnlineClientsByGroup(ctx context.Context, groupID int64) ([]string, error) {
-	// 获取所有在线客户端
-	allOnlineClients := d.sessionMgr.ListClientIDs()
-
-	// 注意：这里简化处理，实际应该查询数据库中的客户端分组关系
-	// 由于 session manager 中没有存储分组信息，我们需要通过其他方式获取
-	// 这里暂时返回所有在线客户端，实际实现中应该：
-	// 1. 查询数据库获取分组下的所有客户端ID（包括离线）
-	// 2. 过滤出在线客户端
-
-	// TODO: 实现从数据库查询客户端分组关系的逻辑
-	// 目前先返回所有在线客户端作为占位实现
-	// 在实际生产环境中，应该查询 models.Client 表，根据 group_id 过滤
-	return allOnlineClients, nil
+	if d.groupStore == nil {
+		return nil, fmt.Errorf("static group %d: group store not configured", groupID)
+	}
+	members, err := d.groupStore.ListClientIDs(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members of group %d: %w", groupID, err)
+	}
+	return keepClients(d.listClients(), members), nil
+}
+
+// SetGroupStore 设置分组存储，静态分组的成员从中查询
+func (d *TaskDispatcher) SetGroupStore(groupStore store.GroupStore) {
+	d.groupStore = groupStore
 }
@@ -0,0 +1,155 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/callback"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/protocol"
+	"github.com/voilet/quic-flow/pkg/task/models"
+	"github.com/voilet/quic-flow/pkg/task/store"
+)
+
+// fakeSender 记录下发的任务与取消，并按 accept 结果确认
+type fakeSender struct {
+	mu        sync.Mutex
+	accept    command.TaskAcceptResult
+	sent      []*protocol.TaskExecution
+	cancelled []string
+}
+
+func (f *fakeSender) SendToWithPromise(clientID string, msg *protocol.DataMessage, timeout time.Duration) (*callback.Promise, error) {
+	var cmd command.CommandPayload
+	if err := json.Unmarshal(msg.Payload, &cmd); err != nil {
+		return nil, err
+	}
+	if cmd.CommandType == command.CmdTaskCancel {
+		var params command.TaskCancelParams
+		if err := json.Unmarshal(cmd.Payload, &params); err != nil {
+			return nil, err
+		}
+		f.mu.Lock()
+		f.cancelled = append(f.cancelled, params.ExecutionID)
+		f.mu.Unlock()
+		p := callback.NewPromise(msg.MsgId, timeout, nil)
+		p.Complete(&protocol.AckMessage{MsgId: msg.MsgId, Status: protocol.AckStatus_ACK_STATUS_SUCCESS})
+		return p, nil
+	}
+	var exec protocol.TaskExecution
+	if err := json.Unmarshal(cmd.Payload, &exec); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	f.sent = append(f.sent, &exec)
+	accept := f.accept
+	f.mu.Unlock()
+
+	accept.ExecutionID = exec.ExecutionId
+	result, _ := json.Marshal(accept)
+	p := callback.NewPromise(msg.MsgId, timeout, nil)
+	p.Complete(&protocol.AckMessage{MsgId: msg.MsgId, Status: protocol.AckStatus_ACK_STATUS_SUCCESS, Result: result})
+	return p, nil
+}
+
+func newTestDispatcher(t *testing.T, sender TaskSender) (*TaskDispatcher, store.ExecutionStore) {
+	db := openTestDB(t)
+	executions := store.NewExecutionStore(db)
+	logger := monitoring.NewLogger(monitoring.LogLevelError, "text")
+	return NewTaskDispatcher(sender, nil, store.NewTaskStore(db), executions, logger), executions
+}
+
+func TestTaskDispatcher_ExecutionLifecycle(t *testing.T) {
+	sender := &fakeSender{accept: command.TaskAcceptResult{Accepted: true}}
+	d, executions := newTestDispatcher(t, sender)
+	var updates []models.ExecutionStatus
+	d.SetExecutionHook(func(exec *models.Execution) { updates = append(updates, exec.Status) })
+
+	task := &models.Task{ID: 7, Name: "backup", ExecutorType: models.ExecutorTypeShell,
+		ExecutorConfig: `{"command":"echo hi"}`, Timeout: 30, RetryCount: 2, RetryInterval: 5}
+	require.NoError(t, d.dispatchToClient(context.Background(), "client-1", task, models.ExecutionTypeManual))
+
+	require.Len(t, sender.sent, 1)
+	sent := sender.sent[0]
+	assert.Equal(t, "7", sent.TaskId)
+	assert.Equal(t, protocol.ExecutorType_EXECUTOR_TYPE_SHELL, sent.ExecutorType)
+	assert.Equal(t, protocol.ExecutionType_EXECUTION_TYPE_MANUAL, sent.ExecutionType)
+	assert.Equal(t, int32(2), sent.RetryCount)
+
+	list, err := executions.GetByClientID(context.Background(), "client-1", 0)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, models.ExecutionStatusPending, list[0].Status)
+	assert.Equal(t, sent.ExecutionId, strconv.FormatInt(list[0].ID, 10))
+
+	// 进度：标记执行中并追加输出
+	for _, chunk := range []string{"line1\n", "line2\n"} {
+		require.NoError(t, d.HandleProgress(context.Background(), &command.TaskProgressReport{
+			ClientID: "client-1",
+			Progress: &protocol.TaskProgress{ExecutionId: sent.ExecutionId, Status: protocol.ExecutionStatus_EXECUTION_STATUS_RUNNING, Output: chunk},
+		}))
+	}
+	exec, err := executions.GetByID(context.Background(), list[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ExecutionStatusRunning, exec.Status)
+	assert.Equal(t, "line1\nline2\n", exec.Output)
+	assert.NotNil(t, exec.StartTime)
+
+	// 其他客户端不能上报该执行
+	assert.Error(t, d.HandleProgress(context.Background(), &command.TaskProgressReport{
+		ClientID: "client-2",
+		Progress: &protocol.TaskProgress{ExecutionId: sent.ExecutionId},
+	}))
+
+	// 结果：写入最终状态
+	require.NoError(t, d.HandleResult(context.Background(), &command.TaskResultReport{
+		ClientID: "client-1",
+		Result: &protocol.TaskResult{ExecutionId: sent.ExecutionId, Status: protocol.ExecutionStatus_EXECUTION_STATUS_FAILED,
+			ExitCode: 3, Output: "line1\nline2\nboom\n", ErrorMsg: "exit status 3", DurationMs: 1500, RetryCount: 2,
+			Timestamp: time.Now().UnixMilli()},
+	}))
+	exec, err = executions.GetByID(context.Background(), list[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ExecutionStatusFailed, exec.Status)
+	assert.Equal(t, 3, exec.ExitCode)
+	assert.Equal(t, "exit status 3", exec.ErrorMsg)
+	assert.Equal(t, 1500, exec.Duration)
+	assert.Equal(t, 2, exec.RetryCount)
+	assert.NotNil(t, exec.EndTime)
+
+	// 结束后的进度被忽略
+	require.NoError(t, d.HandleProgress(context.Background(), &command.TaskProgressReport{
+		ClientID: "client-1",
+		Progress: &protocol.TaskProgress{ExecutionId: sent.ExecutionId, Output: "late"},
+	}))
+	exec, _ = executions.GetByID(context.Background(), list[0].ID)
+	assert.Equal(t, models.ExecutionStatusFailed, exec.Status)
+
+	assert.Equal(t, []models.ExecutionStatus{
+		models.ExecutionStatusPending, models.ExecutionStatusRunning,
+		models.ExecutionStatusRunning, models.ExecutionStatusFailed,
+	}, updates)
+}
+
+func TestTaskDispatcher_RejectedByClient(t *testing.T) {
+	sender := &fakeSender{accept: command.TaskAcceptResult{Accepted: false, Error: "unsupported executor type: 3"}}
+	d, executions := newTestDispatcher(t, sender)
+
+	task := &models.Task{ID: 1, Name: "plugin-task", ExecutorType: models.ExecutorTypePlugin}
+	require.NoError(t, d.dispatchToClient(context.Background(), "client-1", task, models.ExecutionTypeScheduled))
+
+	require.Eventually(t, func() bool {
+		list, _ := executions.GetByClientID(context.Background(), "client-1", 0)
+		return len(list) == 1 && list[0].Status == models.ExecutionStatusFailed
+	}, 2*time.Second, 10*time.Millisecond)
+	list, _ := executions.GetByClientID(context.Background(), "client-1", 0)
+	assert.Contains(t, list[0].ErrorMsg, "unsupported executor type")
+	assert.Equal(t, models.ExecutionTypeScheduled, list[0].ExecutionType)
+}
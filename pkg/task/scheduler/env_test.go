@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/task/models"
+	"github.com/voilet/quic-flow/pkg/task/store"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// testEnv 使用临时 SQLite 数据库的任务管理器及其依赖
+type testEnv struct {
+	db         *gorm.DB
+	logger     *monitoring.Logger
+	sender     *fakeSender
+	d          *TaskDispatcher
+	cron       *CronScheduler
+	m          *TaskManager
+	tasks      store.TaskStore
+	executions store.ExecutionStore
+	groups     store.GroupStore
+}
+
+// testOption 测试环境选项
+type testOption func(env *testEnv)
+
+// withClients 设置在线客户端列表
+func withClients(clients ...string) testOption {
+	return func(env *testEnv) {
+		env.d.listClients = func() []string { return clients }
+	}
+}
+
+// newTestManager 创建测试环境：发送器接受所有下发，已设置分组存储，调度器在测试结束时停止
+func newTestManager(t *testing.T, opts ...testOption) *testEnv {
+	db := openTestDB(t)
+	env := &testEnv{
+		db:         db,
+		logger:     monitoring.NewLogger(monitoring.LogLevelError, "text"),
+		sender:     &fakeSender{accept: command.TaskAcceptResult{Accepted: true}},
+		tasks:      store.NewTaskStore(db),
+		executions: store.NewExecutionStore(db),
+		groups:     store.NewGroupStore(db),
+	}
+	env.d = NewTaskDispatcher(env.sender, nil, env.tasks, env.executions, env.logger)
+	env.cron = NewCronScheduler(env.logger, env.d)
+	env.cron.misfireDelay = 0
+	env.m = NewTaskManager(env.cron, env.d, env.tasks, env.executions, env.logger)
+	env.m.SetGroupStore(env.groups)
+	t.Cleanup(env.cron.Stop)
+	for _, opt := range opts {
+		opt(env)
+	}
+	return env
+}
+
+// group 创建静态分组并加入客户端
+func (env *testEnv) group(t *testing.T, name string, clientIDs ...string) *models.TaskGroup {
+	group := &models.TaskGroup{Name: name}
+	require.NoError(t, env.groups.Create(context.Background(), group))
+	if len(clientIDs) > 0 {
+		joinGroup(t, env.db, group.ID, clientIDs...)
+	}
+	return group
+}
+
+func openTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "task.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.Migrate(db))
+	return db
+}
+
+// joinGroup 登记客户端（不存在时）并加入静态分组
+func joinGroup(t *testing.T, db *gorm.DB, groupID int64, clientIDs ...string) {
+	for _, clientID := range clientIDs {
+		require.NoError(t, db.Where(models.Client{ClientID: clientID}).FirstOrCreate(&models.Client{}).Error)
+	}
+	require.NoError(t, store.NewGroupStore(db).AddClients(context.Background(), groupID, clientIDs))
+}
@@ -0,0 +1,277 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/protocol"
+	"github.com/voilet/quic-flow/pkg/task/models"
+	"github.com/voilet/quic-flow/pkg/task/store"
+)
+
+// Agent 本地调度（local_schedule 任务）：
+// 服务器不调度这类任务，而是把配置以 task.config.push 推送给 Agent（Agent 连接时也会
+// 通过 task.config.pull 查询拉取），Agent 按 Cron 在本地执行，结果缓存在本地，
+// 在线时通过 task.offline.sync 查询写入执行记录，每日统计以 report.task_stats 事件上报
+
+// LocalTaskConfigs 返回 Agent 本地调度的任务配置（启用且已关联分组的 local_schedule 任务）
+func (m *TaskManager) LocalTaskConfigs(ctx context.Context) ([]*protocol.TaskConfig, error) {
+	tasks, err := m.taskStore.ListEnabled(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list enabled tasks: %w", err)
+	}
+
+	configs := make([]*protocol.TaskConfig, 0)
+	for _, task := range tasks {
+		// 与服务器分发一致：没有关联分组的任务不下发
+		if !task.LocalSchedule || len(task.Groups) == 0 {
+			continue
+		}
+		configs = append(configs, &protocol.TaskConfig{
+			TaskId:         strconv.FormatInt(task.ID, 10),
+			TaskName:       task.Name,
+			ExecutorType:   protocol.ExecutorType(task.ExecutorType),
+			ExecutorConfig: task.ExecutorConfig,
+			CronExpr:       task.CronExpr,
+			Timeout:        int32(task.Timeout),
+			RetryCount:     int32(task.RetryCount),
+			RetryInterval:  int32(task.RetryInterval),
+			Concurrency:    int32(task.Concurrency),
+			Enabled:        true,
+		})
+	}
+	return configs, nil
+}
+
+// PullConfig 处理 Agent 的 task.config.pull 查询，版本不一致时返回完整配置
+func (m *TaskManager) PullConfig(ctx context.Context, clientID string, req *protocol.TaskConfigPull) (*protocol.TaskConfigPullResponse, error) {
+	version := m.GetConfigVersion()
+	resp := &protocol.TaskConfigPullResponse{
+		ConfigVersion: version,
+		HasUpdate:     req == nil || req.CurrentVersion != version,
+		Timestamp:     time.Now().UnixMilli(),
+	}
+	if !resp.HasUpdate {
+		return resp, nil
+	}
+
+	configs, err := m.LocalTaskConfigs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp.Tasks = configs
+	m.logger.Info("Local task config pulled", "client_id", clientID, "version", version, "task_count", len(configs))
+	return resp, nil
+}
+
+// configChanged 任务变更后更新配置版本，并向在线 Agent 推送最新的本地调度配置
+func (m *TaskManager) configChanged(ctx context.Context) {
+	version := m.bumpConfigVersion()
+	configs, err := m.LocalTaskConfigs(ctx)
+	if err != nil {
+		m.logger.Warn("Failed to load local task configs", "error", err)
+		return
+	}
+	m.dispatcher.PushConfig(&protocol.TaskConfigPush{
+		ConfigVersion: version,
+		Action:        protocol.ConfigAction_CONFIG_ACTION_REPLACE,
+		Tasks:         configs,
+		Timestamp:     time.Now().UnixMilli(),
+	})
+}
+
+// bumpConfigVersion 递增配置版本，且不小于当前毫秒时间戳（服务器重启后版本仍然递增）
+func (m *TaskManager) bumpConfigVersion() int64 {
+	for {
+		old := atomic.LoadInt64(&m.configVersion)
+		next := old + 1
+		if now := time.Now().UnixMilli(); now > next {
+			next = now
+		}
+		if atomic.CompareAndSwapInt64(&m.configVersion, old, next) {
+			return next
+		}
+	}
+}
+
+// PushConfig 向所有在线客户端推送本地调度配置，发送结果在后台记录
+func (d *TaskDispatcher) PushConfig(push *protocol.TaskConfigPush) {
+	if d.sessionMgr == nil {
+		return
+	}
+	data, err := json.Marshal(push)
+	if err != nil {
+		d.logger.Warn("Failed to marshal task config push", "error", err)
+		return
+	}
+	payload, err := json.Marshal(command.CommandPayload{
+		CommandType: command.CmdTaskConfigPush,
+		Payload:     data,
+	})
+	if err != nil {
+		d.logger.Warn("Failed to marshal command payload", "error", err)
+		return
+	}
+
+	for _, clientID := range d.sessionMgr.ListClientIDs() {
+		msg := &protocol.DataMessage{
+			MsgId:      uuid.New().String(),
+			SenderId:   "server",
+			ReceiverId: clientID,
+			Type:       protocol.MessageType_MESSAGE_TYPE_COMMAND,
+			Payload:    payload,
+			WaitAck:    true,
+			Timestamp:  time.Now().UnixMilli(),
+		}
+		promise, err := d.sender.SendToWithPromise(clientID, msg, dispatchAckTimeout)
+		if err != nil {
+			d.logger.Warn("Failed to push task config", "client_id", clientID, "error", err)
+			continue
+		}
+		go func(clientID string) {
+			resp := <-promise.RespChan
+			switch {
+			case resp.Error != nil:
+				d.logger.Warn("Task config push failed", "client_id", clientID, "error", resp.Error)
+			case resp.AckMessage != nil && resp.AckMessage.Status != protocol.AckStatus_ACK_STATUS_SUCCESS:
+				// 旧版本 Agent 不支持本地调度
+				d.logger.Debug("Task config push rejected", "client_id", clientID, "error", resp.AckMessage.Error)
+			}
+		}(clientID)
+	}
+	d.logger.Info("Local task config pushed", "version", push.ConfigVersion, "task_count", len(push.Tasks))
+}
+
+// HandleOfflineSync 处理 Agent 的 task.offline.sync 查询：将本地执行结果写入执行记录
+// 同一客户端、任务、开始时间的记录已存在时视为已同步（Agent 重传），不重复写入
+func (d *TaskDispatcher) HandleOfflineSync(ctx context.Context, clientID string, sync *command.TaskOfflineSync) (*protocol.OfflineSyncResponse, error) {
+	if sync == nil {
+		return nil, fmt.Errorf("sync payload is required")
+	}
+
+	taskNames := make(map[int64]string)
+	var synced, created int64
+	for _, r := range sync.Results {
+		if r == nil {
+			continue
+		}
+		taskID, err := strconv.ParseInt(r.TaskId, 10, 64)
+		if err != nil {
+			// 无法识别的记录直接确认，避免 Agent 反复重传
+			d.logger.Warn("Invalid task id in offline result", "client_id", clientID, "task_id", r.TaskId)
+			synced++
+			continue
+		}
+
+		end := timeFromMillis(r.Timestamp)
+		start := end.Add(-time.Duration(r.DurationMs) * time.Millisecond)
+		exists, err := d.executionStore.Exists(ctx, clientID, taskID, start)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check execution: %w", err)
+		}
+		if exists {
+			synced++
+			continue
+		}
+
+		name, ok := taskNames[taskID]
+		if !ok {
+			// 已删除的任务仍保留执行记录，仅缺少名称
+			if task, err := d.taskStore.GetByID(ctx, taskID); err == nil {
+				name = task.Name
+			}
+			taskNames[taskID] = name
+		}
+
+		execution := &models.Execution{
+			TaskID:        taskID,
+			TaskName:      name,
+			ClientID:      clientID,
+			ExecutionType: models.ExecutionTypeScheduled,
+			Status:        models.ExecutionStatus(r.Status),
+			StartTime:     &start,
+			EndTime:       &end,
+			Duration:      int(r.DurationMs),
+			ExitCode:      int(r.ExitCode),
+			Output:        r.Output,
+			ErrorMsg:      r.ErrorMsg,
+			RetryCount:    int(r.RetryCount),
+			CreatedAt:     start,
+		}
+		if err := d.executionStore.Create(ctx, execution); err != nil {
+			return nil, fmt.Errorf("failed to create execution record: %w", err)
+		}
+		d.notify(execution)
+		synced++
+		created++
+	}
+
+	d.logger.Info("Offline task results synced",
+		"client_id", clientID,
+		"received", len(sync.Results),
+		"created", created)
+	return &protocol.OfflineSyncResponse{
+		Success:     true,
+		Message:     fmt.Sprintf("%d results synced, %d new", synced, created),
+		SyncedCount: synced,
+		ServerTime:  time.Now().UnixMilli(),
+	}, nil
+}
+
+// SetDailyStatsStore 设置每日统计存储（report.task_stats 写入）
+func (d *TaskDispatcher) SetDailyStatsStore(s store.DailyStatsStore) {
+	d.statsStore = s
+}
+
+// HandleDailyStats 处理 Agent 上报的 report.task_stats：按客户端、日期、任务覆盖写入
+func (d *TaskDispatcher) HandleDailyStats(ctx context.Context, report *protocol.DailyStatsReport) error {
+	if report == nil || report.ClientId == "" || report.Date == "" {
+		return fmt.Errorf("client_id and date are required")
+	}
+	if d.statsStore == nil {
+		return fmt.Errorf("daily stats store not configured")
+	}
+
+	reportTime := timeFromMillis(report.ReportTime)
+	stats := make([]*models.TaskDailyStats, 0, len(report.TaskStats))
+	for _, s := range report.TaskStats {
+		if s == nil {
+			continue
+		}
+		taskID, err := strconv.ParseInt(s.TaskId, 10, 64)
+		if err != nil {
+			d.logger.Warn("Invalid task id in daily stats", "client_id", report.ClientId, "task_id", s.TaskId)
+			continue
+		}
+		hourly, err := json.Marshal(s.HourlyDistribution)
+		if err != nil {
+			return fmt.Errorf("invalid hourly distribution: %w", err)
+		}
+		stats = append(stats, &models.TaskDailyStats{
+			ClientID:           report.ClientId,
+			Date:               report.Date,
+			TaskID:             taskID,
+			TaskName:           s.TaskName,
+			SuccessCount:       int(s.SuccessCount),
+			FailureCount:       int(s.FailureCount),
+			TimeoutCount:       int(s.TimeoutCount),
+			CancelledCount:     int(s.CancelledCount),
+			TotalDuration:      s.TotalDurationMs,
+			MaxDuration:        int(s.MaxDurationMs),
+			MinDuration:        int(s.MinDurationMs),
+			HourlyDistribution: string(hourly),
+			ReportTime:         reportTime,
+		})
+	}
+	if err := d.statsStore.Upsert(ctx, stats); err != nil {
+		return fmt.Errorf("failed to save daily stats: %w", err)
+	}
+	d.logger.Debug("Daily task stats saved", "client_id", report.ClientId, "date", report.Date, "tasks", len(stats))
+	return nil
+}
@@ -0,0 +1,109 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/protocol"
+	"github.com/voilet/quic-flow/pkg/task/models"
+	"github.com/voilet/quic-flow/pkg/task/store"
+)
+
+func TestTaskManager_LocalTaskConfig(t *testing.T) {
+	env := newTestManager(t)
+	ctx := context.Background()
+	m := env.m
+	group := env.group(t, "web")
+
+	local, err := m.CreateTask(ctx, &CreateTaskRequest{Name: "cleanup", ExecutorType: models.ExecutorTypeShell,
+		ExecutorConfig: `{"command":"echo hi"}`, CronExpr: "0 */5 * * * *", Timeout: 30, Concurrency: 1,
+		LocalSchedule: true, GroupIDs: []int64{group.ID}})
+	require.NoError(t, err)
+	_, err = m.CreateTask(ctx, &CreateTaskRequest{Name: "server-side", ExecutorType: models.ExecutorTypeShell,
+		ExecutorConfig: `{"command":"echo hi"}`, CronExpr: "0 */5 * * * *", GroupIDs: []int64{group.ID}})
+	require.NoError(t, err)
+
+	// 本地调度的任务不进入服务器调度器
+	_, err = m.GetNextRunTime(local.ID)
+	assert.Error(t, err)
+
+	resp, err := m.PullConfig(ctx, "client-1", &protocol.TaskConfigPull{ClientId: "client-1"})
+	require.NoError(t, err)
+	assert.True(t, resp.HasUpdate)
+	require.Len(t, resp.Tasks, 1)
+	assert.Equal(t, "cleanup", resp.Tasks[0].TaskName)
+	assert.Equal(t, "0 */5 * * * *", resp.Tasks[0].CronExpr)
+	assert.Equal(t, int32(30), resp.Tasks[0].Timeout)
+
+	// 版本一致时不返回配置
+	same, err := m.PullConfig(ctx, "client-1", &protocol.TaskConfigPull{CurrentVersion: resp.ConfigVersion})
+	require.NoError(t, err)
+	assert.False(t, same.HasUpdate)
+	assert.Empty(t, same.Tasks)
+
+	// 改回服务器调度后版本递增，本地配置为空
+	off := false
+	require.NoError(t, m.UpdateTask(ctx, &UpdateTaskRequest{TaskID: local.ID, LocalSchedule: &off}))
+	assert.Greater(t, m.GetConfigVersion(), resp.ConfigVersion)
+	configs, err := m.LocalTaskConfigs(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, configs)
+}
+
+func TestTaskDispatcher_OfflineSyncAndDailyStats(t *testing.T) {
+	env := newTestManager(t)
+	ctx := context.Background()
+	d, taskStore, executions := env.d, env.tasks, env.executions
+	statsStore := store.NewDailyStatsStore(env.db)
+	d.SetDailyStatsStore(statsStore)
+
+	task := &models.Task{Name: "cleanup", ExecutorType: models.ExecutorTypeShell, ExecutorConfig: "{}", CronExpr: "@every 1m"}
+	require.NoError(t, taskStore.Create(ctx, task))
+	taskID := "1"
+
+	now := time.Now().UnixMilli()
+	sync := &command.TaskOfflineSync{
+		Request: &protocol.OfflineSyncRequest{ClientId: "client-1", RecordCount: 2},
+		Results: []*protocol.TaskResult{
+			{ExecutionId: "local-1", TaskId: taskID, Status: protocol.ExecutionStatus_EXECUTION_STATUS_SUCCESS, Output: "ok", DurationMs: 1200, Timestamp: now - 60000},
+			{ExecutionId: "local-2", TaskId: taskID, Status: protocol.ExecutionStatus_EXECUTION_STATUS_FAILED, ExitCode: 2, ErrorMsg: "exit status 2", DurationMs: 300, Timestamp: now},
+		},
+	}
+	resp, err := d.HandleOfflineSync(ctx, "client-1", sync)
+	require.NoError(t, err)
+	assert.True(t, resp.Success)
+	assert.Equal(t, int64(2), resp.SyncedCount)
+
+	// 重传不重复写入
+	resp, err = d.HandleOfflineSync(ctx, "client-1", sync)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), resp.SyncedCount)
+
+	list, err := executions.GetByClientID(ctx, "client-1", 0)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	for _, exec := range list {
+		assert.Equal(t, "cleanup", exec.TaskName)
+		assert.Equal(t, models.ExecutionTypeScheduled, exec.ExecutionType)
+		assert.True(t, exec.IsFinished())
+	}
+
+	report := &protocol.DailyStatsReport{ClientId: "client-1", Date: "2026-10-18", ReportTime: now,
+		TaskStats: []*protocol.TaskStats{{TaskId: taskID, TaskName: "cleanup", SuccessCount: 1, TotalDurationMs: 1200,
+			MaxDurationMs: 1200, MinDurationMs: 1200, HourlyDistribution: map[string]int32{"09": 1}}}}
+	require.NoError(t, d.HandleDailyStats(ctx, report))
+	report.TaskStats[0].SuccessCount = 5
+	report.TaskStats[0].FailureCount = 1
+	require.NoError(t, d.HandleDailyStats(ctx, report))
+
+	stats, err := statsStore.List(ctx, &store.DailyStatsListParams{ClientID: "client-1"})
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 5, stats[0].SuccessCount)
+	assert.Equal(t, 6, stats[0].TotalCount())
+	assert.JSONEq(t, `{"09":1}`, stats[0].HourlyDistribution)
+}
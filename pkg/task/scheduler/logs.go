@@ -0,0 +1,161 @@
+package scheduler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/filetransfer"
+	"github.com/voilet/quic-flow/pkg/task/models"
+)
+
+const (
+	// DefaultLogOffloadSize 输出超过该大小时，执行结束后完整输出转存到文件存储
+	DefaultLogOffloadSize = 8 * 1024
+	// logPreviewSize 转存后数据库中保留的输出尾部大小
+	logPreviewSize = 2 * 1024
+	// logStoragePrefix 执行日志在文件存储中的路径前缀
+	logStoragePrefix = "task-logs"
+)
+
+// LogStorage 执行日志的文件存储（由 filetransfer.StorageBackend 实现）
+type LogStorage interface {
+	Store(ctx context.Context, path string, reader io.Reader, metadata filetransfer.FileMeta) error
+	Retrieve(ctx context.Context, path string) (io.ReadCloser, filetransfer.FileMeta, error)
+	Delete(ctx context.Context, path string) error
+}
+
+// SetLogStorage 设置执行日志的文件存储，输出超过 offloadSize（<=0 时为 DefaultLogOffloadSize）的执行
+// 结束后完整输出以 gzip 转存，数据库只保留尾部预览
+func (d *TaskDispatcher) SetLogStorage(storage LogStorage, offloadSize int) {
+	if offloadSize <= 0 {
+		offloadSize = DefaultLogOffloadSize
+	}
+	d.logStorage = storage
+	d.logOffloadSize = offloadSize
+}
+
+// SetLogHook 设置执行日志增量通知（如 WebSocket 实时日志），需在分发任务前调用
+func (d *TaskDispatcher) SetLogHook(hook func(chunk *models.ExecutionLogChunk)) {
+	d.logHook = hook
+}
+
+// emitLog 通知执行日志增量
+func (d *TaskDispatcher) emitLog(chunk *models.ExecutionLogChunk) {
+	if d.logHook != nil {
+		d.logHook(chunk)
+	}
+}
+
+// offloadOutput 将超过阈值的完整输出转存到文件存储，数据库保留尾部预览；
+// 转存失败时输出仍保存在数据库中
+func (d *TaskDispatcher) offloadOutput(ctx context.Context, execution *models.Execution) {
+	execution.OutputSize = len(execution.Output)
+	// 已转存过的执行（重复上报结果）总是转存，以替换之前的输出
+	previous := execution.OutputPath
+	if d.logStorage == nil || (len(execution.Output) <= d.logOffloadSize && previous == "") {
+		return
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%d-%d.log.gz", execution.ID, now.UnixNano())
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Name = name
+	zw.ModTime = now
+	if _, err := io.WriteString(zw, execution.Output); err != nil {
+		d.logger.Warn("Failed to compress execution output", "execution_id", execution.ID, "error", err)
+		return
+	}
+	if err := zw.Close(); err != nil {
+		d.logger.Warn("Failed to compress execution output", "execution_id", execution.ID, "error", err)
+		return
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	path := logStoragePrefix + "/" + name
+	err := d.logStorage.Store(ctx, path, bytes.NewReader(buf.Bytes()), filetransfer.FileMeta{
+		Name:        name,
+		Path:        path,
+		Size:        int64(buf.Len()),
+		ModTime:     now,
+		ContentType: "application/gzip",
+		Checksum:    hex.EncodeToString(sum[:]),
+		UserID:      "anonymous",
+		Tags:        []string{"task-log"},
+		Description: fmt.Sprintf("task %d execution %d output", execution.TaskID, execution.ID),
+	})
+	if err != nil {
+		d.logger.Warn("Failed to store execution output, keeping it in database",
+			"execution_id", execution.ID,
+			"size", len(execution.Output),
+			"error", err)
+		return
+	}
+
+	execution.OutputPath = path
+	execution.Output = outputTail(execution.Output, logPreviewSize)
+	if previous != "" {
+		d.deleteLog(ctx, previous)
+	}
+}
+
+// ExecutionOutput 获取执行记录及其完整输出（已转存的输出从文件存储读取）
+func (d *TaskDispatcher) ExecutionOutput(ctx context.Context, executionID int64) (*models.Execution, string, error) {
+	execution, err := d.executionStore.GetByID(ctx, executionID)
+	if err != nil {
+		return nil, "", fmt.Errorf("execution %d not found: %w", executionID, err)
+	}
+	if execution.OutputPath == "" {
+		return execution, execution.Output, nil
+	}
+	if d.logStorage == nil {
+		return execution, execution.Output, fmt.Errorf("log storage not configured, only output tail available")
+	}
+
+	rc, _, err := d.logStorage.Retrieve(ctx, execution.OutputPath)
+	if err != nil {
+		return execution, execution.Output, fmt.Errorf("failed to retrieve output: %w", err)
+	}
+	defer rc.Close()
+	zr, err := gzip.NewReader(rc)
+	if err != nil {
+		return execution, execution.Output, fmt.Errorf("failed to read output: %w", err)
+	}
+	defer zr.Close()
+	var out strings.Builder
+	if _, err := io.Copy(&out, zr); err != nil {
+		return execution, execution.Output, fmt.Errorf("failed to read output: %w", err)
+	}
+	return execution, out.String(), nil
+}
+
+// deleteLog 删除文件存储中的执行日志，返回是否已删除
+func (d *TaskDispatcher) deleteLog(ctx context.Context, path string) bool {
+	if d.logStorage == nil {
+		return false
+	}
+	if err := d.logStorage.Delete(ctx, path); err != nil {
+		d.logger.Warn("Failed to delete execution output", "path", path, "error", err)
+		return false
+	}
+	return true
+}
+
+// outputTail 截取输出尾部，从完整的行开始
+func outputTail(output string, size int) string {
+	if len(output) <= size {
+		return output
+	}
+	tail := output[len(output)-size:]
+	if i := strings.IndexByte(tail, '\n'); i >= 0 && i < len(tail)-1 {
+		tail = tail[i+1:]
+	}
+	return tail
+}
@@ -0,0 +1,238 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/filetransfer"
+	"github.com/voilet/quic-flow/pkg/protocol"
+	"github.com/voilet/quic-flow/pkg/task/models"
+)
+
+// memLogStorage 内存日志存储
+type memLogStorage struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func newMemLogStorage() *memLogStorage {
+	return &memLogStorage{files: make(map[string][]byte)}
+}
+
+func (s *memLogStorage) Store(ctx context.Context, path string, reader io.Reader, metadata filetransfer.FileMeta) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[path] = data
+	return nil
+}
+
+func (s *memLogStorage) Retrieve(ctx context.Context, path string) (io.ReadCloser, filetransfer.FileMeta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.files[path]
+	if !ok {
+		return nil, filetransfer.FileMeta{}, fmt.Errorf("file not found: %s", path)
+	}
+	return io.NopCloser(bytes.NewReader(data)), filetransfer.FileMeta{Path: path, Size: int64(len(data))}, nil
+}
+
+func (s *memLogStorage) Delete(ctx context.Context, path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.files[path]; !ok {
+		return fmt.Errorf("file not found: %s", path)
+	}
+	delete(s.files, path)
+	return nil
+}
+
+func (s *memLogStorage) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.files)
+}
+
+func TestTaskDispatcher_LogChunks(t *testing.T) {
+	sender := &fakeSender{accept: command.TaskAcceptResult{Accepted: true}}
+	d, executions := newTestDispatcher(t, sender)
+	var chunks []models.ExecutionLogChunk
+	d.SetLogHook(func(chunk *models.ExecutionLogChunk) { chunks = append(chunks, *chunk) })
+
+	task := &models.Task{ID: 7, Name: "build", ExecutorType: models.ExecutorTypeShell, ExecutorConfig: `{"command":"make"}`}
+	require.NoError(t, d.dispatchToClient(context.Background(), "client-1", task, models.ExecutionTypeManual))
+	executionID := sender.sent[0].ExecutionId
+
+	for _, out := range []string{"line1\n", "", "line2\n"} {
+		require.NoError(t, d.HandleProgress(context.Background(), &command.TaskProgressReport{
+			ClientID: "client-1",
+			Progress: &protocol.TaskProgress{ExecutionId: executionID, Status: protocol.ExecutionStatus_EXECUTION_STATUS_RUNNING, Output: out},
+		}))
+	}
+	// 最终输出中尚未推送的部分随结果补发
+	require.NoError(t, d.HandleResult(context.Background(), &command.TaskResultReport{
+		ClientID: "client-1",
+		Result: &protocol.TaskResult{ExecutionId: executionID, Status: protocol.ExecutionStatus_EXECUTION_STATUS_SUCCESS,
+			Output: "line1\nline2\ndone\n", DurationMs: 10, Timestamp: time.Now().UnixMilli()},
+	}))
+
+	id, _ := strconv.ParseInt(executionID, 10, 64)
+	assert.Equal(t, []models.ExecutionLogChunk{
+		{ExecutionID: id, Offset: 0, Output: "line1\n", Status: models.ExecutionStatusRunning},
+		{ExecutionID: id, Offset: 6, Output: "line2\n", Status: models.ExecutionStatusRunning},
+		{ExecutionID: id, Offset: 12, Output: "done\n", Status: models.ExecutionStatusSuccess},
+		{ExecutionID: id, Offset: 17, Finished: true, Status: models.ExecutionStatusSuccess},
+	}, chunks)
+
+	exec, err := executions.GetByID(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, 17, exec.OutputSize)
+	assert.Empty(t, exec.OutputPath)
+}
+
+func TestTaskDispatcher_OffloadOutput(t *testing.T) {
+	sender := &fakeSender{accept: command.TaskAcceptResult{Accepted: true}}
+	d, executions := newTestDispatcher(t, sender)
+	storage := newMemLogStorage()
+	d.SetLogStorage(storage, 1024)
+	ctx := context.Background()
+
+	task := &models.Task{ID: 7, Name: "dump", ExecutorType: models.ExecutorTypeShell, ExecutorConfig: `{"command":"dump"}`}
+	report := func(executionID, output string) {
+		require.NoError(t, d.HandleResult(ctx, &command.TaskResultReport{
+			ClientID: "client-1",
+			Result: &protocol.TaskResult{ExecutionId: executionID, Status: protocol.ExecutionStatus_EXECUTION_STATUS_SUCCESS,
+				Output: output, DurationMs: 10, Timestamp: time.Now().UnixMilli()},
+		}))
+	}
+
+	// 较小的输出保存在数据库中
+	require.NoError(t, d.dispatchToClient(ctx, "client-1", task, models.ExecutionTypeManual))
+	report(sender.sent[0].ExecutionId, "small\n")
+	small, _ := strconv.ParseInt(sender.sent[0].ExecutionId, 10, 64)
+	exec, output, err := d.ExecutionOutput(ctx, small)
+	require.NoError(t, err)
+	assert.Empty(t, exec.OutputPath)
+	assert.Equal(t, "small\n", output)
+	assert.Equal(t, 0, storage.count())
+
+	// 较大的输出转存，数据库保留尾部
+	require.NoError(t, d.dispatchToClient(ctx, "client-1", task, models.ExecutionTypeManual))
+	var full strings.Builder
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&full, "line %d\n", i)
+	}
+	report(sender.sent[1].ExecutionId, full.String())
+	large, _ := strconv.ParseInt(sender.sent[1].ExecutionId, 10, 64)
+	stored, err := executions.GetByID(ctx, large)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(stored.OutputPath, logStoragePrefix+"/"))
+	assert.Equal(t, full.Len(), stored.OutputSize)
+	assert.LessOrEqual(t, len(stored.Output), logPreviewSize)
+	assert.True(t, strings.HasPrefix(stored.Output, "line "), "preview starts at a full line")
+	assert.True(t, strings.HasSuffix(full.String(), stored.Output))
+
+	_, output, err = d.ExecutionOutput(ctx, large)
+	require.NoError(t, err)
+	assert.Equal(t, full.String(), output)
+
+	// 重复上报结果时替换之前转存的输出
+	report(sender.sent[1].ExecutionId, "retried\n")
+	_, output, err = d.ExecutionOutput(ctx, large)
+	require.NoError(t, err)
+	assert.Equal(t, "retried\n", output)
+	assert.Equal(t, 1, storage.count())
+
+	// 未配置存储时只能读取尾部
+	d.logStorage = nil
+	_, output, err = d.ExecutionOutput(ctx, large)
+	assert.Error(t, err)
+	assert.Equal(t, "retried\n", output)
+}
+
+func TestTaskManager_PurgeExecutions(t *testing.T) {
+	env := newTestManager(t)
+	ctx := context.Background()
+	d, m, taskStore, executions := env.d, env.m, env.tasks, env.executions
+	storage := newMemLogStorage()
+	d.SetLogStorage(storage, 0)
+
+	newTask := func(name string, runs, days int) *models.Task {
+		task := &models.Task{Name: name, ExecutorType: models.ExecutorTypeShell, ExecutorConfig: `{"command":"true"}`,
+			CronExpr: "@hourly", RetainRuns: runs, RetainDays: days}
+		require.NoError(t, taskStore.Create(ctx, task))
+		return task
+	}
+	addExecutions := func(task *models.Task, n int, age time.Duration, status models.ExecutionStatus) {
+		for i := 0; i < n; i++ {
+			exec := &models.Execution{TaskID: task.ID, ClientID: "client-1", Status: status, CreatedAt: time.Now().Add(-age)}
+			require.NoError(t, executions.Create(ctx, exec))
+			if status == models.ExecutionStatusSuccess && i == 0 {
+				// 第一条输出转存到文件存储
+				exec.Output = strings.Repeat("x", DefaultLogOffloadSize+1)
+				d.offloadOutput(ctx, exec)
+				require.NoError(t, executions.Update(ctx, exec))
+			}
+		}
+	}
+
+	byRuns := newTask("runs", 3, 0)
+	addExecutions(byRuns, 5, time.Hour, models.ExecutionStatusSuccess)
+	addExecutions(byRuns, 1, 0, models.ExecutionStatusRunning)
+	byDays := newTask("days", 0, 7)
+	addExecutions(byDays, 2, 10*24*time.Hour, models.ExecutionStatusSuccess)
+	addExecutions(byDays, 2, time.Hour, models.ExecutionStatusFailed)
+	unlimited := newTask("unlimited", 0, 0)
+	addExecutions(unlimited, 4, 30*24*time.Hour, models.ExecutionStatusSuccess)
+	assert.Equal(t, 3, storage.count())
+
+	// dry run 只统计
+	report, err := m.PurgeExecutions(ctx, true)
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, int64(4), report.Executions)
+	assert.Equal(t, 2, report.LogFiles)
+	assert.Nil(t, m.LastPurgeReport())
+	assert.Equal(t, 3, storage.count())
+
+	report, err = m.PurgeExecutions(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), report.Executions)
+	assert.Equal(t, 2, report.LogFiles)
+	assert.Equal(t, int64(2*(DefaultLogOffloadSize+1)), report.LogBytes)
+	require.Len(t, report.Tasks, 2)
+	assert.Equal(t, byRuns.ID, report.Tasks[0].TaskID)
+	assert.Equal(t, int64(2), report.Tasks[0].Executions)
+	assert.Equal(t, byDays.ID, report.Tasks[1].TaskID)
+	assert.Same(t, report, m.LastPurgeReport())
+	assert.Equal(t, 1, storage.count())
+
+	count := func(task *models.Task) int {
+		list, err := executions.GetByTaskID(ctx, task.ID, 0)
+		require.NoError(t, err)
+		return len(list)
+	}
+	// 最近 3 条已结束的记录与执行中的记录保留
+	assert.Equal(t, 4, count(byRuns))
+	assert.Equal(t, 2, count(byDays))
+	assert.Equal(t, 4, count(unlimited))
+
+	// 再次清理没有可删除的记录
+	report, err = m.PurgeExecutions(ctx, false)
+	require.NoError(t, err)
+	assert.Zero(t, report.Executions)
+	assert.Empty(t, report.Tasks)
+}
@@ -3,6 +3,7 @@ package scheduler
 import (
 	"context"
 	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -17,8 +18,21 @@ type TaskManager struct {
 	dispatcher    *TaskDispatcher
 	taskStore     store.TaskStore
 	executionStore store.ExecutionStore
+	groupStore    store.GroupStore // 导出与应用任务定义（可选，见 bundle.go）
 	configVersion int64
 	logger        *monitoring.Logger
+
+	purgeMu   sync.Mutex   // 串行化执行记录清理
+	lastPurge *PurgeReport // 最近一次清理报告（见 retention.go）
+
+	healthMu sync.Mutex
+	health   *HealthReport // 最近一次健康报告（见 analytics.go）
+
+	bundleMu     sync.Mutex   // 串行化任务定义的应用
+	bundleSource BundleSource // 任务定义同步来源（见 bundle_sync.go）
+	bundlePrune  bool         // 同步时删除来源中不存在的任务与分组
+	syncMu       sync.Mutex
+	syncStatus   BundleSyncStatus
 }
 
 // NewTaskManager 创建任务管理器
@@ -34,7 +48,9 @@ func NewTaskManager(
 		dispatcher:     dispatcher,
 		taskStore:      taskStore,
 		executionStore: executionStore,
-		logger:         logger,
+		// 以启动时间初始化版本号，服务器重启后 Agent 拉取配置时能发现变化
+		configVersion: time.Now().UnixMilli(),
+		logger:        logger,
 	}
 }
 
@@ -46,7 +62,8 @@ func (m *TaskManager) Initialize(ctx context.Context) error {
 		return fmt.Errorf("failed to list enabled tasks: %w", err)
 	}
 
-	// 添加到调度器
+	// 添加到调度器，并按错过策略补偿停机期间错过的触发
+	now := time.Now()
 	for _, task := range tasks {
 		if _, err := m.cron.AddTask(task); err != nil {
 			m.logger.Warn("Failed to add task to scheduler",
@@ -54,7 +71,27 @@ func (m *TaskManager) Initialize(ctx context.Context) error {
 				"task_name", task.Name,
 				"error", err)
 			// 继续处理其他任务
+			continue
+		}
+		m.cron.CatchUp(task, now)
+	}
+
+	// 按任务的保留策略定期清理执行记录
+	if err := m.cron.AddFunc("execution-retention", retentionSchedule, func(ctx context.Context) {
+		if _, err := m.PurgeExecutions(ctx, false); err != nil {
+			m.logger.Error("Failed to purge executions", "error", err)
 		}
+	}); err != nil {
+		m.logger.Warn("Failed to schedule execution retention", "error", err)
+	}
+
+	// 定期刷新任务健康报告（Prometheus 导出）
+	if err := m.cron.AddFunc("task-health", analyticsSchedule, func(ctx context.Context) {
+		if _, err := m.RefreshHealth(ctx); err != nil {
+			m.logger.Error("Failed to refresh task health", "error", err)
+		}
+	}); err != nil {
+		m.logger.Warn("Failed to schedule task health refresh", "error", err)
 	}
 
 	m.logger.Info("Task manager initialized", "task_count", len(tasks))
@@ -63,22 +100,37 @@ func (m *TaskManager) Initialize(ctx context.Context) error {
 
 // CreateTaskRequest 创建任务请求
 type CreateTaskRequest struct {
-	Name           string
-	Description    string
-	ExecutorType   models.ExecutorType
-	ExecutorConfig string
-	CronExpr       string
-	Timeout        int
-	RetryCount     int
-	RetryInterval  int
-	Concurrency    int
-	CreatedBy      string
-	GroupIDs       []int64 // 关联的分组ID列表
+	Name           string              `json:"name"`
+	Description    string              `json:"description"`
+	ExecutorType   models.ExecutorType `json:"executor_type"`
+	ExecutorConfig string              `json:"executor_config"`
+	CronExpr       string              `json:"cron_expr"`
+	Timeout        int                 `json:"timeout"`
+	RetryCount     int                 `json:"retry_count"`
+	RetryInterval  int                 `json:"retry_interval"`
+	Concurrency    int                 `json:"concurrency"`    // 单个客户端上的最大并发数
+	OverlapPolicy  models.OverlapPolicy `json:"overlap_policy"` // 达到并发上限时：skip（默认）、queue、kill
+	MisfirePolicy  models.MisfirePolicy `json:"misfire_policy"` // 停机错过的触发：skip（默认）、fire_once、fire_all
+	Jitter         int                 `json:"jitter"`         // 随机延迟上限（秒）
+	Spread         int                 `json:"spread"`         // 分散窗口（秒），客户端在窗口内按固定偏移错开执行
+	RetainRuns     int                 `json:"retain_runs"`    // 保留最近的执行记录数（0=不限）
+	RetainDays     int                 `json:"retain_days"`    // 执行记录保留天数（0=不限）
+	LocalSchedule  bool                `json:"local_schedule"` // 由 Agent 本地调度
+	CreatedBy      string              `json:"created_by"`
+	GroupIDs       []int64             `json:"group_ids"` // 关联的分组ID列表
 }
 
 // CreateTask 创建任务
 func (m *TaskManager) CreateTask(ctx context.Context, req *CreateTaskRequest) (*models.Task, error) {
-	// 创建任务模型
+	if err := validateSchedulePolicy(req.OverlapPolicy, req.MisfirePolicy, req.Jitter, req.Spread); err != nil {
+		return nil, err
+	}
+	if req.RetainRuns < 0 || req.RetainDays < 0 {
+		return nil, fmt.Errorf("retain_runs and retain_days must not be negative")
+	}
+
+	// 创建任务模型，从创建时开始计算错过的触发
+	now := time.Now()
 	task := &models.Task{
 		Name:           req.Name,
 		Description:    req.Description,
@@ -89,6 +141,14 @@ func (m *TaskManager) CreateTask(ctx context.Context, req *CreateTaskRequest) (*
 		RetryCount:     req.RetryCount,
 		RetryInterval:  req.RetryInterval,
 		Concurrency:    req.Concurrency,
+		OverlapPolicy:  req.OverlapPolicy,
+		MisfirePolicy:  req.MisfirePolicy,
+		Jitter:         req.Jitter,
+		Spread:         req.Spread,
+		RetainRuns:     req.RetainRuns,
+		RetainDays:     req.RetainDays,
+		LastFireTime:   &now,
+		LocalSchedule:  req.LocalSchedule,
 		Status:         models.TaskStatusEnabled,
 		CreatedBy:      req.CreatedBy,
 	}
@@ -118,8 +178,8 @@ func (m *TaskManager) CreateTask(ctx context.Context, req *CreateTaskRequest) (*
 		}
 	}
 
-	// 更新配置版本
-	atomic.AddInt64(&m.configVersion, 1)
+	// 更新配置版本并推送 Agent 本地调度配置
+	m.configChanged(ctx)
 
 	m.logger.Info("Task created",
 		"task_id", task.ID,
@@ -130,18 +190,25 @@ func (m *TaskManager) CreateTask(ctx context.Context, req *CreateTaskRequest) (*
 
 // UpdateTaskRequest 更新任务请求
 type UpdateTaskRequest struct {
-	TaskID         int64
-	Name           *string
-	Description    *string
-	ExecutorType   *models.ExecutorType
-	ExecutorConfig *string
-	CronExpr       *string
-	Timeout        *int
-	RetryCount     *int
-	RetryInterval  *int
-	Concurrency    *int
-	Status         *models.TaskStatus
-	GroupIDs       []int64 // 如果提供，将替换所有分组关联
+	TaskID         int64                `json:"-"`
+	Name           *string              `json:"name"`
+	Description    *string              `json:"description"`
+	ExecutorType   *models.ExecutorType `json:"executor_type"`
+	ExecutorConfig *string              `json:"executor_config"`
+	CronExpr       *string              `json:"cron_expr"`
+	Timeout        *int                 `json:"timeout"`
+	RetryCount     *int                 `json:"retry_count"`
+	RetryInterval  *int                 `json:"retry_interval"`
+	Concurrency    *int                 `json:"concurrency"`
+	OverlapPolicy  *models.OverlapPolicy `json:"overlap_policy"`
+	MisfirePolicy  *models.MisfirePolicy `json:"misfire_policy"`
+	Jitter         *int                 `json:"jitter"`
+	Spread         *int                 `json:"spread"`
+	RetainRuns     *int                 `json:"retain_runs"`
+	RetainDays     *int                 `json:"retain_days"`
+	Status         *models.TaskStatus   `json:"status"`
+	LocalSchedule  *bool                `json:"local_schedule"`
+	GroupIDs       []int64              `json:"group_ids"` // 如果提供，将替换所有分组关联
 }
 
 // UpdateTask 更新任务
@@ -180,9 +247,39 @@ func (m *TaskManager) UpdateTask(ctx context.Context, req *UpdateTaskRequest) er
 	if req.Concurrency != nil {
 		task.Concurrency = *req.Concurrency
 	}
+	if req.OverlapPolicy != nil {
+		task.OverlapPolicy = *req.OverlapPolicy
+	}
+	if req.MisfirePolicy != nil {
+		task.MisfirePolicy = *req.MisfirePolicy
+	}
+	if req.Jitter != nil {
+		task.Jitter = *req.Jitter
+	}
+	if req.Spread != nil {
+		task.Spread = *req.Spread
+	}
+	if req.RetainRuns != nil {
+		task.RetainRuns = *req.RetainRuns
+	}
+	if req.RetainDays != nil {
+		task.RetainDays = *req.RetainDays
+	}
+	// 重新启用的任务从启用时开始计算错过的触发，禁用期间不补偿
+	reenabled := req.Status != nil && *req.Status == models.TaskStatusEnabled && !task.IsEnabled()
 	if req.Status != nil {
 		task.Status = *req.Status
 	}
+	if req.LocalSchedule != nil {
+		task.LocalSchedule = *req.LocalSchedule
+	}
+
+	if err := validateSchedulePolicy(task.OverlapPolicy, task.MisfirePolicy, task.Jitter, task.Spread); err != nil {
+		return err
+	}
+	if task.RetainRuns < 0 || task.RetainDays < 0 {
+		return fmt.Errorf("retain_runs and retain_days must not be negative")
+	}
 
 	// 更新分组关联
 	if req.GroupIDs != nil {
@@ -235,6 +332,11 @@ func (m *TaskManager) UpdateTask(ctx context.Context, req *UpdateTaskRequest) er
 	if err := m.taskStore.Update(ctx, task); err != nil {
 		return fmt.Errorf("failed to update task: %w", err)
 	}
+	if reenabled {
+		if err := m.taskStore.SetLastFireTime(ctx, task.ID, time.Now()); err != nil {
+			m.logger.Warn("Failed to reset task fire time", "task_id", task.ID, "error", err)
+		}
+	}
 
 	// 更新调度器中的任务
 	if err := m.cron.UpdateTask(task); err != nil {
@@ -243,8 +345,8 @@ func (m *TaskManager) UpdateTask(ctx context.Context, req *UpdateTaskRequest) er
 			"error", err)
 	}
 
-	// 更新配置版本
-	atomic.AddInt64(&m.configVersion, 1)
+	// 更新配置版本并推送 Agent 本地调度配置
+	m.configChanged(ctx)
 
 	m.logger.Info("Task updated", "task_id", task.ID)
 	return nil
@@ -282,8 +384,8 @@ func (m *TaskManager) DeleteTask(ctx context.Context, taskID int64) error {
 		return fmt.Errorf("failed to delete task: %w", err)
 	}
 
-	// 更新配置版本
-	atomic.AddInt64(&m.configVersion, 1)
+	// 更新配置版本并推送 Agent 本地调度配置
+	m.configChanged(ctx)
 
 	m.logger.Info("Task deleted", "task_id", taskID)
 	return nil
@@ -297,25 +399,8 @@ func (m *TaskManager) TriggerTask(ctx context.Context, taskID int64) error {
 		return fmt.Errorf("task not found: %w", err)
 	}
 
-	// 创建执行记录
-	execution := &models.Execution{
-		TaskID:        task.ID,
-		TaskName:      task.Name,
-		ExecutionType: models.ExecutionTypeManual,
-		Status:        models.ExecutionStatusPending,
-		CreatedAt:     time.Now(),
-	}
-
-	// 保存执行记录（这里不指定 ClientID，因为会分发到多个客户端）
-	// 实际执行时，每个客户端会创建自己的执行记录
-	if err := m.executionStore.Create(ctx, execution); err != nil {
-		m.logger.Warn("Failed to create execution record",
-			"task_id", taskID,
-			"error", err)
-	}
-
-	// 分发任务
-	if err := m.dispatcher.Dispatch(ctx, task); err != nil {
+	// 分发任务（每个目标客户端创建一条执行记录）
+	if err := m.dispatcher.Dispatch(ctx, task, models.ExecutionTypeManual); err != nil {
 		return fmt.Errorf("failed to dispatch task: %w", err)
 	}
 
@@ -323,12 +408,40 @@ func (m *TaskManager) TriggerTask(ctx context.Context, taskID int64) error {
 	return nil
 }
 
+// validateSchedulePolicy 校验重叠策略、错过策略与错开下发的参数，策略为空时使用默认值
+func validateSchedulePolicy(overlap models.OverlapPolicy, misfire models.MisfirePolicy, jitter, spread int) error {
+	switch overlap {
+	case "", models.OverlapSkip, models.OverlapQueue, models.OverlapKill:
+	default:
+		return fmt.Errorf("invalid overlap policy %q", overlap)
+	}
+	switch misfire {
+	case "", models.MisfireSkip, models.MisfireFireOnce, models.MisfireFireAll:
+	default:
+		return fmt.Errorf("invalid misfire policy %q", misfire)
+	}
+	if jitter < 0 || spread < 0 {
+		return fmt.Errorf("jitter and spread must not be negative")
+	}
+	return nil
+}
+
 // GetNextRunTime 获取任务下次执行时间
 func (m *TaskManager) GetNextRunTime(taskID int64) (time.Time, error) {
 	return m.cron.GetNextRunTime(taskID)
 }
 
-// GetConfigVersion 获取配置版本号
+// ResolveGroup 获取分组当前的在线成员（动态分组按选择器筛选，静态分组按成员关系），用于预览分组
+func (m *TaskManager) ResolveGroup(ctx context.Context, group *models.TaskGroup) ([]string, error) {
+	return m.dispatcher.ResolveGroup(ctx, group)
+}
+
+// ExecutionOutput 获取执行记录及其完整输出（已转存的输出从文件存储读取）
+func (m *TaskManager) ExecutionOutput(ctx context.Context, executionID int64) (*models.Execution, string, error) {
+	return m.dispatcher.ExecutionOutput(ctx, executionID)
+}
+
+// GetConfigVersion 获取配置版本号（Agent 本地调度配置的版本）
 func (m *TaskManager) GetConfigVersion() int64 {
 	return atomic.LoadInt64(&m.configVersion)
 }
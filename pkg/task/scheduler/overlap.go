@@ -0,0 +1,261 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/protocol"
+	"github.com/voilet/quic-flow/pkg/task/models"
+)
+
+const (
+	// maxQueuedExecutions 同一任务在同一客户端上最多排队的执行数，超出时跳过
+	maxQueuedExecutions = 10
+	// staleExecutionGrace 判定执行失联的宽限时间：超过任务最长运行时间加宽限仍未上报结果的执行
+	// 不再占用并发槽位，并标记为超时（客户端之后补报的结果仍会覆盖）
+	staleExecutionGrace = 5 * time.Minute
+)
+
+// admission 并发判定结果
+type admission struct {
+	status  models.ExecutionStatus // 新执行记录的状态：Pending 立即下发，Queued 排队
+	skip    bool                   // 跳过本次执行
+	reason  string                 // 跳过原因
+	cancel  []*models.Execution    // 需要取消的上一次执行（重叠策略 kill）
+	promote bool                   // 有空闲槽位但已有排队记录：新记录排到队尾后立即出队
+}
+
+// slotKey 并发槽位的键：任务 + 客户端
+type slotKey struct {
+	taskID   int64
+	clientID string
+}
+
+// lockSlot 锁定任务在客户端上的并发判定，返回解锁函数
+func (d *TaskDispatcher) lockSlot(taskID int64, clientID string) func() {
+	v, _ := d.slots.LoadOrStore(slotKey{taskID, clientID}, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// admit 按任务的并发上限与重叠策略判定新的执行，调用方需持有槽位锁
+func (d *TaskDispatcher) admit(ctx context.Context, task *models.Task, clientID string) (*admission, error) {
+	running, queued, err := d.activeExecutions(ctx, task, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := task.GetConcurrency()
+	if len(running) < limit {
+		if len(queued) > 0 {
+			return &admission{status: models.ExecutionStatusQueued, promote: true}, nil
+		}
+		return &admission{status: models.ExecutionStatusPending}, nil
+	}
+
+	switch task.GetOverlapPolicy() {
+	case models.OverlapQueue:
+		if len(queued) >= maxQueuedExecutions {
+			return &admission{skip: true, reason: fmt.Sprintf("queue is full (%d executions)", len(queued))}, nil
+		}
+		return &admission{status: models.ExecutionStatusQueued}, nil
+	case models.OverlapKill:
+		return &admission{status: models.ExecutionStatusPending, cancel: running[:len(running)-limit+1]}, nil
+	default:
+		return &admission{skip: true, reason: fmt.Sprintf("%d previous execution(s) still running", len(running))}, nil
+	}
+}
+
+// activeExecutions 获取任务在客户端上未结束的执行，分为占用槽位的（待执行、执行中）与排队中的
+// 失联的执行标记为超时，不计入
+func (d *TaskDispatcher) activeExecutions(ctx context.Context, task *models.Task, clientID string) (running, queued []*models.Execution, err error) {
+	executions, err := d.executionStore.ListActive(ctx, task.ID, clientID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list active executions: %w", err)
+	}
+
+	deadline := executionDeadline(task)
+	for _, execution := range executions {
+		if execution.Status == models.ExecutionStatusQueued {
+			queued = append(queued, execution)
+			continue
+		}
+		since := execution.CreatedAt
+		if execution.StartTime != nil {
+			since = *execution.StartTime
+		}
+		if deadline > 0 && time.Since(since) > deadline {
+			d.logger.Warn("Execution lost, marking as timeout",
+				"execution_id", execution.ID,
+				"task_id", task.ID,
+				"client_id", clientID)
+			d.finishExecution(execution.ID, models.ExecutionStatusTimeout, "no result reported before deadline")
+			continue
+		}
+		running = append(running, execution)
+	}
+	return running, queued, nil
+}
+
+// executionDeadline 任务一次执行（含重试）的最长时间加宽限，任务未设置超时时返回 0（不判定失联）
+func executionDeadline(task *models.Task) time.Duration {
+	if task.Timeout <= 0 {
+		return 0
+	}
+	attempt := time.Duration(task.Timeout+task.RetryInterval) * time.Second
+	return time.Duration(task.RetryCount+1)*attempt + dispatchAckTimeout + staleExecutionGrace
+}
+
+// startQueued 在有空闲槽位时按排队顺序下发任务在客户端上排队的执行
+func (d *TaskDispatcher) startQueued(taskID int64, clientID string) {
+	ctx := context.Background()
+	task, err := d.taskStore.GetByID(ctx, taskID)
+	if err != nil {
+		d.logger.Debug("Task not found, skipping queued executions", "task_id", taskID, "error", err)
+		return
+	}
+
+	for {
+		unlock := d.lockSlot(taskID, clientID)
+		running, queued, err := d.activeExecutions(ctx, task, clientID)
+		if err != nil {
+			unlock()
+			d.logger.Warn("Failed to load queued executions", "task_id", taskID, "client_id", clientID, "error", err)
+			return
+		}
+		if len(queued) == 0 || len(running) >= task.GetConcurrency() {
+			unlock()
+			return
+		}
+
+		// 出队时刷新创建时间，失联判定从下发时开始计算
+		next := queued[0]
+		next.Task = nil
+		next.Status = models.ExecutionStatusPending
+		next.CreatedAt = time.Now()
+		d.execMu.Lock()
+		err = d.executionStore.Update(ctx, next)
+		d.execMu.Unlock()
+		unlock()
+		if err != nil {
+			d.logger.Warn("Failed to dequeue execution", "execution_id", next.ID, "error", err)
+			return
+		}
+		d.notify(next)
+
+		// 下发失败时执行记录已标记为失败，继续处理下一条
+		if err := d.sendExecution(task, next); err != nil {
+			d.logger.Warn("Failed to dispatch queued execution",
+				"execution_id", next.ID,
+				"client_id", clientID,
+				"error", err)
+		}
+	}
+}
+
+// cancelExecution 向客户端下发 task.cancel 取消执行（重叠策略 kill），
+// 最终状态以客户端上报的 task.result 为准
+func (d *TaskDispatcher) cancelExecution(execution *models.Execution) {
+	executionID := strconv.FormatInt(execution.ID, 10)
+	params, err := json.Marshal(command.TaskCancelParams{ExecutionID: executionID})
+	if err != nil {
+		d.logger.Warn("Failed to marshal task cancel", "execution_id", executionID, "error", err)
+		return
+	}
+	payload, err := json.Marshal(command.CommandPayload{
+		CommandType: command.CmdTaskCancel,
+		Payload:     params,
+	})
+	if err != nil {
+		d.logger.Warn("Failed to marshal command payload", "execution_id", executionID, "error", err)
+		return
+	}
+
+	msg := &protocol.DataMessage{
+		MsgId:      uuid.New().String(),
+		SenderId:   "server",
+		ReceiverId: execution.ClientID,
+		Type:       protocol.MessageType_MESSAGE_TYPE_COMMAND,
+		Payload:    payload,
+		WaitAck:    true,
+		Timestamp:  time.Now().UnixMilli(),
+	}
+	promise, err := d.sender.SendToWithPromise(execution.ClientID, msg, dispatchAckTimeout)
+	if err != nil {
+		d.logger.Warn("Failed to cancel previous execution",
+			"execution_id", executionID,
+			"client_id", execution.ClientID,
+			"error", err)
+		return
+	}
+	d.logger.Info("Previous execution cancelled by overlap policy",
+		"execution_id", executionID,
+		"task_id", execution.TaskID,
+		"client_id", execution.ClientID)
+
+	go func() {
+		resp := <-promise.RespChan
+		if resp.Error != nil {
+			d.logger.Warn("Task cancel not acknowledged",
+				"execution_id", executionID,
+				"client_id", execution.ClientID,
+				"error", resp.Error)
+		}
+	}()
+}
+
+// dispatchDelay 定时执行下发到客户端前的延迟：分散窗口内按任务与客户端哈希得到的固定偏移，
+// 加上随机延迟。手动触发不延迟
+func dispatchDelay(task *models.Task, clientID string, execType models.ExecutionType) time.Duration {
+	if execType != models.ExecutionTypeScheduled {
+		return 0
+	}
+	var delay time.Duration
+	if task.Spread > 0 {
+		h := fnv.New32a()
+		fmt.Fprintf(h, "%d/%s", task.ID, clientID)
+		window := time.Duration(task.Spread) * time.Second
+		delay += time.Duration(h.Sum32()) * time.Millisecond % window
+	}
+	if task.Jitter > 0 {
+		delay += rand.N(time.Duration(task.Jitter) * time.Second)
+	}
+	return delay
+}
+
+// dispatchLater 延迟下发到客户端；下发前重新加载任务，期间被禁用、删除或进入禁止窗口的任务不再下发
+func (d *TaskDispatcher) dispatchLater(ctx context.Context, taskID int64, clientID string, execType models.ExecutionType, delay time.Duration) {
+	go func() {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		}
+
+		task, err := d.taskStore.GetByID(ctx, taskID)
+		if err != nil || !task.IsEnabled() {
+			d.logger.Debug("Task removed or disabled before delayed dispatch", "task_id", taskID, "client_id", clientID)
+			return
+		}
+		if d.suppressed != nil && d.suppressed(ctx, task) {
+			return
+		}
+		if err := d.dispatchToClient(ctx, clientID, task, execType); err != nil {
+			d.logger.Error("Failed to dispatch task to client",
+				"task_id", taskID,
+				"client_id", clientID,
+				"error", err)
+		}
+	}()
+}
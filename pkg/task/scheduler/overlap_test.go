@@ -0,0 +1,207 @@
+package scheduler
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/protocol"
+	"github.com/voilet/quic-flow/pkg/task/models"
+)
+
+func (env *testEnv) createTask(t *testing.T, task *models.Task) *models.Task {
+	task.ExecutorType = models.ExecutorTypeShell
+	task.ExecutorConfig = `{"command":"sleep 60"}`
+	task.CronExpr = "@hourly"
+	require.NoError(t, env.tasks.Create(context.Background(), task))
+	return task
+}
+
+// statuses 客户端上的执行记录状态，按 ID 顺序（出队会刷新创建时间）
+func (env *testEnv) statuses(t *testing.T, clientID string) []models.ExecutionStatus {
+	list, err := env.executions.GetByClientID(context.Background(), clientID, 0)
+	require.NoError(t, err)
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	statuses := make([]models.ExecutionStatus, len(list))
+	for i, exec := range list {
+		statuses[i] = exec.Status
+	}
+	return statuses
+}
+
+func (env *testEnv) sentCount() int {
+	env.sender.mu.Lock()
+	defer env.sender.mu.Unlock()
+	return len(env.sender.sent)
+}
+
+func (env *testEnv) finish(t *testing.T, clientID string, executionID string) {
+	require.NoError(t, env.d.HandleResult(context.Background(), &command.TaskResultReport{
+		ClientID: clientID,
+		Result: &protocol.TaskResult{ExecutionId: executionID, Status: protocol.ExecutionStatus_EXECUTION_STATUS_SUCCESS,
+			DurationMs: 10, Timestamp: time.Now().UnixMilli()},
+	}))
+}
+
+func TestTaskDispatcher_OverlapSkip(t *testing.T) {
+	env := newTestManager(t)
+	ctx := context.Background()
+	task := env.createTask(t, &models.Task{Name: "skip"})
+	assert.Equal(t, models.OverlapSkip, task.GetOverlapPolicy())
+
+	require.NoError(t, env.d.dispatchToClient(ctx, "client-1", task, models.ExecutionTypeScheduled))
+	require.NoError(t, env.d.dispatchToClient(ctx, "client-1", task, models.ExecutionTypeScheduled))
+	// 其他客户端不受影响
+	require.NoError(t, env.d.dispatchToClient(ctx, "client-2", task, models.ExecutionTypeScheduled))
+	assert.Equal(t, []models.ExecutionStatus{models.ExecutionStatusPending}, env.statuses(t, "client-1"))
+	assert.Equal(t, 2, env.sentCount())
+
+	// 上一次结束后可以再次执行
+	env.finish(t, "client-1", env.sender.sent[0].ExecutionId)
+	require.NoError(t, env.d.dispatchToClient(ctx, "client-1", task, models.ExecutionTypeScheduled))
+	assert.Equal(t, []models.ExecutionStatus{models.ExecutionStatusSuccess, models.ExecutionStatusPending}, env.statuses(t, "client-1"))
+}
+
+func TestTaskDispatcher_OverlapConcurrency(t *testing.T) {
+	env := newTestManager(t)
+	ctx := context.Background()
+	task := env.createTask(t, &models.Task{Name: "parallel", Concurrency: 2})
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, env.d.dispatchToClient(ctx, "client-1", task, models.ExecutionTypeScheduled))
+	}
+	assert.Equal(t, []models.ExecutionStatus{models.ExecutionStatusPending, models.ExecutionStatusPending}, env.statuses(t, "client-1"))
+}
+
+func TestTaskDispatcher_OverlapQueue(t *testing.T) {
+	env := newTestManager(t)
+	ctx := context.Background()
+	task := env.createTask(t, &models.Task{Name: "queue", OverlapPolicy: models.OverlapQueue})
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, env.d.dispatchToClient(ctx, "client-1", task, models.ExecutionTypeScheduled))
+	}
+	assert.Equal(t, []models.ExecutionStatus{
+		models.ExecutionStatusPending, models.ExecutionStatusQueued, models.ExecutionStatusQueued,
+	}, env.statuses(t, "client-1"))
+	assert.Equal(t, 1, env.sentCount())
+
+	// 上一次结束后按排队顺序下发下一次
+	env.finish(t, "client-1", env.sender.sent[0].ExecutionId)
+	require.Eventually(t, func() bool { return env.sentCount() == 2 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []models.ExecutionStatus{
+		models.ExecutionStatusSuccess, models.ExecutionStatusPending, models.ExecutionStatusQueued,
+	}, env.statuses(t, "client-1"))
+
+	// 队列已满时跳过
+	for i := 0; i < maxQueuedExecutions; i++ {
+		require.NoError(t, env.d.dispatchToClient(ctx, "client-1", task, models.ExecutionTypeScheduled))
+	}
+	running, queued, err := env.d.activeExecutions(ctx, task, "client-1")
+	require.NoError(t, err)
+	assert.Len(t, running, 1)
+	assert.Len(t, queued, maxQueuedExecutions)
+}
+
+func TestTaskDispatcher_OverlapKill(t *testing.T) {
+	env := newTestManager(t)
+	ctx := context.Background()
+	task := env.createTask(t, &models.Task{Name: "kill", OverlapPolicy: models.OverlapKill})
+
+	require.NoError(t, env.d.dispatchToClient(ctx, "client-1", task, models.ExecutionTypeScheduled))
+	require.NoError(t, env.d.dispatchToClient(ctx, "client-1", task, models.ExecutionTypeScheduled))
+	assert.Equal(t, 2, env.sentCount())
+	assert.Equal(t, []string{env.sender.sent[0].ExecutionId}, env.sender.cancelled)
+}
+
+func TestTaskDispatcher_LostExecution(t *testing.T) {
+	env := newTestManager(t)
+	ctx := context.Background()
+	task := env.createTask(t, &models.Task{Name: "lost", Timeout: 10})
+
+	// 超过最长运行时间仍未上报结果的执行不再占用槽位
+	lost := &models.Execution{TaskID: task.ID, ClientID: "client-1", Status: models.ExecutionStatusRunning,
+		CreatedAt: time.Now().Add(-executionDeadline(task) - time.Minute)}
+	require.NoError(t, env.executions.Create(ctx, lost))
+
+	require.NoError(t, env.d.dispatchToClient(ctx, "client-1", task, models.ExecutionTypeScheduled))
+	assert.Equal(t, []models.ExecutionStatus{models.ExecutionStatusTimeout, models.ExecutionStatusPending}, env.statuses(t, "client-1"))
+}
+
+func TestDispatchDelay(t *testing.T) {
+	task := &models.Task{ID: 3, Spread: 60}
+	first := dispatchDelay(task, "client-1", models.ExecutionTypeScheduled)
+	assert.Equal(t, first, dispatchDelay(task, "client-1", models.ExecutionTypeScheduled), "spread offset is stable")
+	assert.Less(t, first, time.Minute)
+	assert.Zero(t, dispatchDelay(task, "client-1", models.ExecutionTypeManual))
+
+	// 客户端在窗口内分散
+	offsets := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		offsets[dispatchDelay(task, "client-"+strconv.Itoa(i), models.ExecutionTypeScheduled)] = true
+	}
+	assert.Greater(t, len(offsets), 90)
+
+	task = &models.Task{ID: 3, Jitter: 5}
+	for i := 0; i < 100; i++ {
+		delay := dispatchDelay(task, "client-1", models.ExecutionTypeScheduled)
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+		assert.Less(t, delay, 5*time.Second)
+	}
+}
+
+func TestMissedFireTimes(t *testing.T) {
+	last := time.Date(2026, 1, 1, 10, 0, 0, 0, time.Local)
+	now := last.Add(5*time.Hour + 30*time.Minute)
+
+	missed, err := missedFireTimes("0 0 * * * *", last, now, maxMisfireRuns)
+	require.NoError(t, err)
+	require.Len(t, missed, 5)
+	assert.Equal(t, last.Add(time.Hour), missed[0])
+	assert.Equal(t, last.Add(5*time.Hour), missed[4])
+
+	missed, err = missedFireTimes("0 0 * * * *", last, now, 2)
+	require.NoError(t, err)
+	assert.Len(t, missed, 2)
+
+	missed, err = missedFireTimes("0 0 0 * * *", last, now, maxMisfireRuns)
+	require.NoError(t, err)
+	assert.Empty(t, missed)
+}
+
+func TestCronScheduler_CatchUp(t *testing.T) {
+	env := newTestManager(t, withClients("client-1"))
+	ctx := context.Background()
+	cron, tasks := env.cron, env.tasks
+	group := env.group(t, "web", "client-1")
+	newTask := func(name string, policy models.MisfirePolicy) *models.Task {
+		last := time.Now().Add(-3*time.Hour - time.Minute)
+		task := &models.Task{Name: name, ExecutorType: models.ExecutorTypeShell, ExecutorConfig: `{"command":"true"}`,
+			CronExpr: "0 0 * * * *", MisfirePolicy: policy, OverlapPolicy: models.OverlapQueue, LastFireTime: &last}
+		require.NoError(t, tasks.Create(ctx, task))
+		require.NoError(t, tasks.BindGroup(ctx, task.ID, group.ID))
+		return task
+	}
+
+	now := time.Now()
+	assert.Equal(t, 0, cron.CatchUp(newTask("skip", models.MisfireSkip), now))
+	assert.Equal(t, 1, cron.CatchUp(newTask("once", models.MisfireFireOnce), now))
+	all := newTask("all", models.MisfireFireAll)
+	assert.Equal(t, 3, cron.CatchUp(all, now))
+
+	// fire_all 的补偿按重叠策略排队：一次下发，两次排队
+	require.Eventually(t, func() bool {
+		list, _ := env.executions.GetByTaskID(ctx, all.ID, 0)
+		return len(list) == 3
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// 最近触发时间已更新，再次启动不会重复补偿
+	reloaded, err := tasks.GetByID(ctx, all.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, cron.CatchUp(reloaded, time.Now()))
+}
@@ -0,0 +1,207 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/task/models"
+)
+
+// HandleProgress 处理客户端上报的 task.progress：标记为执行中并追加增量输出
+// 已结束的执行忽略迟到的进度
+func (d *TaskDispatcher) HandleProgress(ctx context.Context, report *command.TaskProgressReport) error {
+	if report == nil || report.Progress == nil {
+		return fmt.Errorf("progress is required")
+	}
+	p := report.Progress
+
+	d.execMu.Lock()
+	defer d.execMu.Unlock()
+	execution, err := d.getExecution(ctx, p.ExecutionId, report.ClientID)
+	if err != nil {
+		return err
+	}
+	if isFinished(execution.Status) {
+		return nil
+	}
+
+	execution.Status = models.ExecutionStatusRunning
+	if execution.StartTime == nil {
+		start := timeFromMillis(p.Timestamp)
+		execution.StartTime = &start
+	}
+	offset := len(execution.Output)
+	execution.Output += p.Output
+	if err := d.executionStore.Update(ctx, execution); err != nil {
+		return fmt.Errorf("failed to update execution: %w", err)
+	}
+	d.notify(execution)
+	if p.Output != "" {
+		d.emitLog(&models.ExecutionLogChunk{
+			ExecutionID: execution.ID,
+			Offset:      offset,
+			Output:      p.Output,
+			Status:      execution.Status,
+		})
+	}
+	return nil
+}
+
+// HandleResult 处理客户端上报的 task.result，以客户端结果为准覆盖执行记录
+// （包括分发确认超时后已被标记为失败、但实际执行完成的记录）
+func (d *TaskDispatcher) HandleResult(ctx context.Context, report *command.TaskResultReport) error {
+	if report == nil || report.Result == nil {
+		return fmt.Errorf("result is required")
+	}
+	r := report.Result
+
+	d.execMu.Lock()
+	defer d.execMu.Unlock()
+	execution, err := d.getExecution(ctx, r.ExecutionId, report.ClientID)
+	if err != nil {
+		return err
+	}
+
+	// 实时日志补发最终输出中尚未推送的部分
+	var tail *models.ExecutionLogChunk
+	if !isFinished(execution.Status) && execution.OutputPath == "" &&
+		len(r.Output) > len(execution.Output) && strings.HasPrefix(r.Output, execution.Output) {
+		tail = &models.ExecutionLogChunk{
+			ExecutionID: execution.ID,
+			Offset:      len(execution.Output),
+			Output:      r.Output[len(execution.Output):],
+		}
+	}
+
+	end := timeFromMillis(r.Timestamp)
+	if execution.StartTime == nil {
+		start := end.Add(-time.Duration(r.DurationMs) * time.Millisecond)
+		execution.StartTime = &start
+	}
+	execution.EndTime = &end
+	execution.Status = models.ExecutionStatus(r.Status)
+	execution.ExitCode = int(r.ExitCode)
+	execution.Output = r.Output
+	execution.ErrorMsg = r.ErrorMsg
+	execution.Duration = int(r.DurationMs)
+	execution.RetryCount = int(r.RetryCount)
+	d.offloadOutput(ctx, execution)
+	if err := d.executionStore.Update(ctx, execution); err != nil {
+		return fmt.Errorf("failed to update execution: %w", err)
+	}
+	if tail != nil {
+		tail.Status = execution.Status
+		d.emitLog(tail)
+	}
+
+	d.logger.Info("Task execution finished",
+		"execution_id", execution.ID,
+		"task_id", execution.TaskID,
+		"client_id", execution.ClientID,
+		"status", int(execution.Status),
+		"exit_code", execution.ExitCode,
+		"duration_ms", execution.Duration)
+	d.notify(execution)
+	return nil
+}
+
+// failExecution 将尚未结束的执行记录标记为失败（下发或确认失败）
+func (d *TaskDispatcher) failExecution(executionID int64, errMsg string) {
+	d.finishExecution(executionID, models.ExecutionStatusFailed, errMsg)
+}
+
+// finishExecution 以指定状态结束尚未结束的执行记录
+func (d *TaskDispatcher) finishExecution(executionID int64, status models.ExecutionStatus, errMsg string) {
+	d.execMu.Lock()
+	defer d.execMu.Unlock()
+
+	ctx := context.Background()
+	execution, err := d.executionStore.GetByID(ctx, executionID)
+	if err != nil {
+		d.logger.Warn("Failed to load execution", "execution_id", executionID, "error", err)
+		return
+	}
+	if isFinished(execution.Status) {
+		return
+	}
+	now := time.Now()
+	execution.Task = nil
+	execution.Status = status
+	execution.ErrorMsg = errMsg
+	execution.EndTime = &now
+	if err := d.executionStore.Update(ctx, execution); err != nil {
+		d.logger.Warn("Failed to update execution", "execution_id", executionID, "error", err)
+		return
+	}
+	d.notify(execution)
+}
+
+// getExecution 按执行 ID 加载执行记录，并校验上报的客户端
+func (d *TaskDispatcher) getExecution(ctx context.Context, executionID, clientID string) (*models.Execution, error) {
+	id, err := strconv.ParseInt(executionID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid execution id %q", executionID)
+	}
+	execution, err := d.executionStore.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("execution %d not found: %w", id, err)
+	}
+	if clientID != "" && execution.ClientID != clientID {
+		return nil, fmt.Errorf("execution %d does not belong to client %s", id, clientID)
+	}
+	// 避免更新时连带写入预加载的任务
+	execution.Task = nil
+	return execution, nil
+}
+
+// notify 通知执行记录更新
+func (d *TaskDispatcher) notify(execution *models.Execution) {
+	if d.hook != nil {
+		d.hook(execution)
+	}
+	if execution.IsFinished() {
+		d.emitLog(&models.ExecutionLogChunk{
+			ExecutionID: execution.ID,
+			Offset:      outputSize(execution),
+			Finished:    true,
+			Status:      execution.Status,
+		})
+	}
+	if d.workflowHook != nil && execution.WorkflowRunID != nil && execution.IsFinished() {
+		d.workflowHook(execution)
+	}
+	// 执行结束后释放的并发槽位交给排队中的执行
+	if execution.IsFinished() && d.taskStore != nil {
+		go d.startQueued(execution.TaskID, execution.ClientID)
+	}
+}
+
+// outputSize 执行的完整输出大小
+func outputSize(execution *models.Execution) int {
+	if execution.OutputPath != "" {
+		return execution.OutputSize
+	}
+	return len(execution.Output)
+}
+
+// isFinished 执行是否已结束
+func isFinished(status models.ExecutionStatus) bool {
+	switch status {
+	case models.ExecutionStatusSuccess, models.ExecutionStatusFailed,
+		models.ExecutionStatusTimeout, models.ExecutionStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// timeFromMillis 将毫秒时间戳转换为时间，为 0 时取当前时间
+func timeFromMillis(ms int64) time.Time {
+	if ms <= 0 {
+		return time.Now()
+	}
+	return time.UnixMilli(ms)
+}
@@ -0,0 +1,155 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/task/store"
+)
+
+const (
+	// retentionSchedule 执行记录清理的 Cron 表达式（每小时第 15 分钟）
+	retentionSchedule = "0 15 * * * *"
+	// purgeBatchSize 每批清理的执行记录数
+	purgeBatchSize = 500
+)
+
+// PurgeReport 执行记录清理报告
+type PurgeReport struct {
+	DryRun     bool               `json:"dry_run"`          // 仅统计，不删除
+	StartTime  time.Time          `json:"start_time"`       // 开始时间
+	EndTime    time.Time          `json:"end_time"`         // 结束时间
+	Executions int64              `json:"executions"`       // 删除的执行记录数
+	LogFiles   int                `json:"log_files"`        // 删除的日志文件数
+	LogBytes   int64              `json:"log_bytes"`        // 删除的日志原始大小（字节）
+	Tasks      []*TaskPurgeResult `json:"tasks"`            // 各任务的清理结果（只包含有删除的任务）
+	Errors     []string           `json:"errors,omitempty"` // 清理失败的任务
+}
+
+// TaskPurgeResult 单个任务的清理结果
+type TaskPurgeResult struct {
+	TaskID     int64  `json:"task_id"`
+	TaskName   string `json:"task_name"`
+	RetainRuns int    `json:"retain_runs"`
+	RetainDays int    `json:"retain_days"`
+	Executions int64  `json:"executions"` // 删除的执行记录数
+	FirstID    int64  `json:"first_id"`   // 删除的最小执行 ID
+	LastID     int64  `json:"last_id"`    // 删除的最大执行 ID
+	LogFiles   int    `json:"log_files"`  // 删除的日志文件数
+	LogBytes   int64  `json:"log_bytes"`  // 删除的日志原始大小（字节）
+}
+
+// PurgeExecutions 按任务的保留策略（保留最近 N 条、保留 N 天）清理已结束的执行记录及其转存的日志，
+// dryRun 时只统计将被清理的记录。未设置保留策略的任务不清理
+func (m *TaskManager) PurgeExecutions(ctx context.Context, dryRun bool) (*PurgeReport, error) {
+	m.purgeMu.Lock()
+	defer m.purgeMu.Unlock()
+
+	tasks, err := m.taskStore.ListWithRetention(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks with retention: %w", err)
+	}
+
+	report := &PurgeReport{DryRun: dryRun, StartTime: time.Now(), Tasks: []*TaskPurgeResult{}}
+	for _, task := range tasks {
+		params := &store.ExecutionPurgeParams{TaskID: task.ID, KeepRuns: task.RetainRuns}
+		if task.RetainDays > 0 {
+			before := report.StartTime.AddDate(0, 0, -task.RetainDays)
+			params.Before = &before
+		}
+		result := &TaskPurgeResult{
+			TaskID:     task.ID,
+			TaskName:   task.Name,
+			RetainRuns: task.RetainRuns,
+			RetainDays: task.RetainDays,
+		}
+		if err := m.purgeTask(ctx, params, result, dryRun); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("task %d: %v", task.ID, err))
+		}
+		if result.Executions == 0 {
+			continue
+		}
+		report.Tasks = append(report.Tasks, result)
+		report.Executions += result.Executions
+		report.LogFiles += result.LogFiles
+		report.LogBytes += result.LogBytes
+	}
+	report.EndTime = time.Now()
+
+	if !dryRun {
+		m.lastPurge = report
+	}
+	m.logger.Info("Executions purged",
+		"dry_run", dryRun,
+		"tasks", len(report.Tasks),
+		"executions", report.Executions,
+		"log_files", report.LogFiles,
+		"log_bytes", report.LogBytes,
+		"errors", len(report.Errors))
+	for _, result := range report.Tasks {
+		m.logger.Info("Task executions purged",
+			"dry_run", dryRun,
+			"task_id", result.TaskID,
+			"task_name", result.TaskName,
+			"executions", result.Executions,
+			"first_id", result.FirstID,
+			"last_id", result.LastID,
+			"log_files", result.LogFiles)
+	}
+	return report, nil
+}
+
+// purgeTask 分批清理单个任务的执行记录，先删除转存的日志再删除记录
+func (m *TaskManager) purgeTask(ctx context.Context, params *store.ExecutionPurgeParams, result *TaskPurgeResult, dryRun bool) error {
+	if !dryRun {
+		params.Limit = purgeBatchSize
+	}
+	for {
+		executions, err := m.executionStore.ListPurgeable(ctx, params)
+		if err != nil {
+			return err
+		}
+		if len(executions) == 0 {
+			return nil
+		}
+
+		ids := make([]int64, 0, len(executions))
+		for _, execution := range executions {
+			ids = append(ids, execution.ID)
+			if result.FirstID == 0 || execution.ID < result.FirstID {
+				result.FirstID = execution.ID
+			}
+			if execution.ID > result.LastID {
+				result.LastID = execution.ID
+			}
+			if execution.OutputPath == "" {
+				continue
+			}
+			if dryRun || m.dispatcher.deleteLog(ctx, execution.OutputPath) {
+				result.LogFiles++
+				result.LogBytes += int64(execution.OutputSize)
+			}
+		}
+
+		if dryRun {
+			result.Executions += int64(len(ids))
+			return nil
+		}
+		deleted, err := m.executionStore.DeleteByIDs(ctx, ids)
+		result.Executions += deleted
+		if err != nil {
+			return err
+		}
+		if len(executions) < purgeBatchSize {
+			return nil
+		}
+	}
+}
+
+// LastPurgeReport 获取最近一次（非 dry run）清理报告，尚未清理过时返回 nil
+func (m *TaskManager) LastPurgeReport() *PurgeReport {
+	m.purgeMu.Lock()
+	defer m.purgeMu.Unlock()
+	return m.lastPurge
+}
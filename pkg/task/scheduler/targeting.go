@@ -0,0 +1,117 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/voilet/quic-flow/pkg/task/models"
+)
+
+// HostFacts 客户端的硬件信息（来自硬件信息上报）
+type HostFacts struct {
+	Hostname string
+	OS       string
+	Arch     string
+	CPUs     int     // CPU 线程数
+	MemoryGB float64 // 内存（GB）
+}
+
+// TargetResolver 动态分组筛选客户端所需的信息（由服务器注入）
+type TargetResolver interface {
+	// HostFacts 获取客户端的硬件信息，未上报过硬件信息的客户端不在结果中
+	HostFacts(ctx context.Context, clientIDs []string) (map[string]*HostFacts, error)
+	// MatchLabels 向客户端查询 Agent 标签，返回标签全部匹配的客户端
+	MatchLabels(ctx context.Context, clientIDs []string, labels map[string]string) []string
+	// MatchContainers 向客户端查询运行中的容器，返回存在名称以 prefix 开头的容器的客户端
+	MatchContainers(ctx context.Context, clientIDs []string, prefix string) []string
+}
+
+// SetTargetResolver 设置动态分组的信息来源，未设置时只支持空选择器
+func (d *TaskDispatcher) SetTargetResolver(resolver TargetResolver) {
+	d.resolver = resolver
+}
+
+// ResolveGroup 获取分组下的在线客户端ID列表：动态分组按选择器筛选，
+// 静态分组取成员关系表中的在线成员（未设置分组存储时返回错误）
+func (d *TaskDispatcher) ResolveGroup(ctx context.Context, group *models.TaskGroup) ([]string, error) {
+	if group.IsDynamic() {
+		return d.ResolveSelector(ctx, group.Selector)
+	}
+	return d.getOnlineClientsByGroup(ctx, group.ID)
+}
+
+// ResolveSelector 按选择器从在线客户端中筛选：先按硬件信息（数据库），再向剩余客户端查询标签与容器，
+// 结果保持在线客户端列表的顺序
+func (d *TaskDispatcher) ResolveSelector(ctx context.Context, selector *models.GroupSelector) ([]string, error) {
+	if err := selector.Validate(); err != nil {
+		return nil, err
+	}
+	clients := d.listClients()
+	if len(clients) == 0 {
+		return nil, nil
+	}
+	needsAgent := len(selector.Labels) > 0 || selector.ContainerPrefix != ""
+	if d.resolver == nil && (selector.NeedsHardware() || needsAgent) {
+		return nil, fmt.Errorf("target resolver not configured")
+	}
+
+	if selector.NeedsHardware() {
+		facts, err := d.resolver.HostFacts(ctx, clients)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load hardware facts: %w", err)
+		}
+		var hostname *regexp.Regexp
+		if selector.Hostname != "" {
+			hostname = regexp.MustCompile(selector.Hostname)
+		}
+		clients = filterClients(clients, func(clientID string) bool {
+			return matchHost(selector, hostname, facts[clientID])
+		})
+	}
+	if len(selector.Labels) > 0 && len(clients) > 0 {
+		clients = keepClients(clients, d.resolver.MatchLabels(ctx, clients, selector.Labels))
+	}
+	if selector.ContainerPrefix != "" && len(clients) > 0 {
+		clients = keepClients(clients, d.resolver.MatchContainers(ctx, clients, selector.ContainerPrefix))
+	}
+	return clients, nil
+}
+
+// matchHost 判断硬件信息是否满足选择器的硬件条件，没有硬件信息时不匹配
+func matchHost(selector *models.GroupSelector, hostname *regexp.Regexp, facts *HostFacts) bool {
+	if facts == nil {
+		return false
+	}
+	if selector.OS != "" && !strings.EqualFold(selector.OS, facts.OS) {
+		return false
+	}
+	if selector.Arch != "" && !strings.EqualFold(selector.Arch, facts.Arch) {
+		return false
+	}
+	if facts.CPUs < selector.MinCPU || facts.MemoryGB < selector.MinMemoryGB {
+		return false
+	}
+	return hostname == nil || hostname.MatchString(facts.Hostname)
+}
+
+// filterClients 保留满足条件的客户端
+func filterClients(clients []string, keep func(clientID string) bool) []string {
+	result := make([]string, 0, len(clients))
+	for _, clientID := range clients {
+		if keep(clientID) {
+			result = append(result, clientID)
+		}
+	}
+	return result
+}
+
+// keepClients 保留在 matched 中的客户端，保持 clients 的顺序
+func keepClients(clients, matched []string) []string {
+	set := make(map[string]bool, len(matched))
+	for _, clientID := range matched {
+		set[clientID] = true
+	}
+	return filterClients(clients, func(clientID string) bool { return set[clientID] })
+}
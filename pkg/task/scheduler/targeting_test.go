@@ -0,0 +1,226 @@
+package scheduler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/task/models"
+)
+
+// fakeResolver 固定的客户端硬件信息、标签与容器
+type fakeResolver struct {
+	facts      map[string]*HostFacts
+	labels     map[string]map[string]string
+	containers map[string][]string
+	queried    []string // 查询过标签或容器的客户端
+}
+
+func (r *fakeResolver) HostFacts(ctx context.Context, clientIDs []string) (map[string]*HostFacts, error) {
+	return r.facts, nil
+}
+
+func (r *fakeResolver) MatchLabels(ctx context.Context, clientIDs []string, labels map[string]string) []string {
+	r.queried = append(r.queried, clientIDs...)
+	var matched []string
+	for _, clientID := range clientIDs {
+		ok := true
+		for k, v := range labels {
+			if r.labels[clientID][k] != v {
+				ok = false
+			}
+		}
+		if ok {
+			matched = append(matched, clientID)
+		}
+	}
+	return matched
+}
+
+func (r *fakeResolver) MatchContainers(ctx context.Context, clientIDs []string, prefix string) []string {
+	r.queried = append(r.queried, clientIDs...)
+	var matched []string
+	for _, clientID := range clientIDs {
+		for _, name := range r.containers[clientID] {
+			if strings.HasPrefix(name, prefix) {
+				matched = append(matched, clientID)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+func newFakeResolver() *fakeResolver {
+	return &fakeResolver{
+		facts: map[string]*HostFacts{
+			"web-1": {Hostname: "web-1.prod", OS: "linux", Arch: "x86_64", CPUs: 16, MemoryGB: 64},
+			"web-2": {Hostname: "web-2.prod", OS: "linux", Arch: "aarch64", CPUs: 4, MemoryGB: 8},
+			"db-1":  {Hostname: "db-1.prod", OS: "linux", Arch: "x86_64", CPUs: 32, MemoryGB: 256},
+			"win-1": {Hostname: "win-1", OS: "windows", Arch: "x86_64", CPUs: 8, MemoryGB: 32},
+		},
+		labels: map[string]map[string]string{
+			"web-1": {"env": "prod", "role": "web"},
+			"web-2": {"env": "staging", "role": "web"},
+			"db-1":  {"env": "prod", "role": "db"},
+		},
+		containers: map[string][]string{
+			"web-1": {"nginx-1"},
+			"db-1":  {"mysql", "nginx-exporter"},
+		},
+	}
+}
+
+func TestTaskDispatcher_ResolveSelector(t *testing.T) {
+	d, _ := newTestDispatcher(t, &fakeSender{})
+	d.listClients = func() []string { return []string{"web-1", "web-2", "db-1", "win-1", "new-1"} }
+	ctx := context.Background()
+
+	// 未设置信息来源时只支持空选择器
+	clients, err := d.ResolveSelector(ctx, &models.GroupSelector{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web-1", "web-2", "db-1", "win-1", "new-1"}, clients)
+	_, err = d.ResolveSelector(ctx, &models.GroupSelector{OS: "linux"})
+	assert.Error(t, err)
+
+	resolver := newFakeResolver()
+	d.SetTargetResolver(resolver)
+	cases := []struct {
+		name     string
+		selector models.GroupSelector
+		want     []string
+	}{
+		{"os", models.GroupSelector{OS: "Linux"}, []string{"web-1", "web-2", "db-1"}},
+		{"arch", models.GroupSelector{Arch: "x86_64"}, []string{"web-1", "db-1", "win-1"}},
+		{"cpu and memory", models.GroupSelector{MinCPU: 8, MinMemoryGB: 64}, []string{"web-1", "db-1"}},
+		{"hostname", models.GroupSelector{Hostname: `^web-\d+\.prod$`}, []string{"web-1", "web-2"}},
+		{"labels", models.GroupSelector{Labels: map[string]string{"env": "prod"}}, []string{"web-1", "db-1"}},
+		{"container", models.GroupSelector{ContainerPrefix: "nginx"}, []string{"web-1", "db-1"}},
+		{"combined", models.GroupSelector{OS: "linux", Labels: map[string]string{"role": "web"}, ContainerPrefix: "nginx"}, []string{"web-1"}},
+		{"no match", models.GroupSelector{MinCPU: 64}, []string{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			clients, err := d.ResolveSelector(ctx, &tc.selector)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, clients)
+		})
+	}
+
+	// 先按硬件信息筛选，只向剩余客户端查询标签
+	resolver.queried = nil
+	_, err = d.ResolveSelector(ctx, &models.GroupSelector{Arch: "aarch64", Labels: map[string]string{"role": "web"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web-2"}, resolver.queried)
+
+	_, err = d.ResolveSelector(ctx, &models.GroupSelector{Hostname: "("})
+	assert.Error(t, err)
+}
+
+func TestTaskDispatcher_DynamicGroupDispatch(t *testing.T) {
+	env := newTestManager(t, withClients("web-1", "web-2", "db-1"))
+	ctx := context.Background()
+	d, sender, tasks, groups := env.d, env.sender, env.tasks, env.groups
+	d.SetTargetResolver(newFakeResolver())
+
+	dynamic := &models.TaskGroup{Name: "prod-x86", Selector: &models.GroupSelector{Arch: "x86_64", Labels: map[string]string{"env": "prod"}}}
+	require.NoError(t, groups.Create(ctx, dynamic))
+	static := &models.TaskGroup{Name: "static"}
+	require.NoError(t, groups.Create(ctx, static))
+
+	// 选择器随分组保存
+	loaded, err := groups.GetByID(ctx, dynamic.ID)
+	require.NoError(t, err)
+	require.True(t, loaded.IsDynamic())
+	assert.Equal(t, dynamic.Selector, loaded.Selector)
+	loaded, err = groups.GetByID(ctx, static.ID)
+	require.NoError(t, err)
+	assert.False(t, loaded.IsDynamic())
+
+	task := &models.Task{Name: "deploy", ExecutorType: models.ExecutorTypeShell, ExecutorConfig: `{"command":"true"}`, CronExpr: "@hourly"}
+	require.NoError(t, tasks.Create(ctx, task))
+	require.NoError(t, tasks.BindGroup(ctx, task.ID, dynamic.ID))
+
+	clients, err := d.targetClients(ctx, task)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web-1", "db-1"}, clients)
+
+	require.NoError(t, d.Dispatch(ctx, task, models.ExecutionTypeManual))
+	assert.Len(t, sender.sent, 2)
+
+	// 成员在下发时求值：选择器变更后立即生效
+	loaded, err = groups.GetByID(ctx, dynamic.ID)
+	require.NoError(t, err)
+	loaded.Selector.MinCPU = 20
+	require.NoError(t, groups.Update(ctx, loaded))
+	clients, err = d.targetClients(ctx, task)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"db-1"}, clients)
+
+	// 清空选择器改为静态分组
+	loaded.Selector = nil
+	require.NoError(t, groups.Update(ctx, loaded))
+	loaded, err = groups.GetByID(ctx, dynamic.ID)
+	require.NoError(t, err)
+	assert.False(t, loaded.IsDynamic())
+}
+
+func TestTaskDispatcher_StaticGroupDispatch(t *testing.T) {
+	env := newTestManager(t, withClients("web-1", "web-2", "db-1"))
+	ctx := context.Background()
+	db, d, sender, tasks, groups := env.db, env.d, env.sender, env.tasks, env.groups
+	d.SetGroupStore(nil)
+
+	for _, clientID := range []string{"web-1", "web-2", "db-1", "offline-1"} {
+		require.NoError(t, db.Create(&models.Client{ClientID: clientID}).Error)
+	}
+	static := &models.TaskGroup{Name: "web"}
+	require.NoError(t, groups.Create(ctx, static))
+	require.NoError(t, groups.AddClients(ctx, static.ID, []string{"web-1", "offline-1", "unknown"}))
+	task := &models.Task{Name: "deploy", ExecutorType: models.ExecutorTypeShell, ExecutorConfig: `{"command":"true"}`, CronExpr: "@hourly"}
+	require.NoError(t, tasks.Create(ctx, task))
+	require.NoError(t, tasks.BindGroup(ctx, task.ID, static.ID))
+
+	// 未设置分组存储时不下发，而不是下发到所有在线客户端
+	_, err := d.ResolveGroup(ctx, static)
+	assert.Error(t, err)
+	require.NoError(t, d.Dispatch(ctx, task, models.ExecutionTypeManual))
+	assert.Empty(t, sender.sent)
+
+	// 只下发到在线的成员
+	d.SetGroupStore(groups)
+	require.NoError(t, d.Dispatch(ctx, task, models.ExecutionTypeManual))
+	require.Len(t, sender.sent, 1)
+	var targets []string
+	require.NoError(t, db.Model(&models.Execution{}).Pluck("client_id", &targets).Error)
+	assert.Equal(t, []string{"web-1"}, targets)
+
+	// 客户端可属于多个分组，移除后不再是成员
+	other := &models.TaskGroup{Name: "all"}
+	require.NoError(t, groups.Create(ctx, other))
+	require.NoError(t, groups.AddClients(ctx, other.ID, []string{"web-1", "web-2"}))
+	require.NoError(t, groups.RemoveClient(ctx, static.ID, "web-1"))
+	members, err := groups.ListClientIDs(ctx, static.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"offline-1"}, members)
+	members, err = groups.ListClientIDs(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web-1", "web-2"}, members)
+}
+
+func TestTaskManager_ResolveStaticGroup(t *testing.T) {
+	env := newTestManager(t, withClients("web-1", "web-2", "db-1", "db-2"))
+	ctx := context.Background()
+
+	// 预览只包含在线的成员，顺序与在线客户端列表一致
+	static := env.group(t, "db", "db-2", "db-1", "db-3")
+	clients, err := env.m.ResolveGroup(ctx, static)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"db-1", "db-2"}, clients)
+
+	clients, err = env.m.ResolveGroup(ctx, env.group(t, "empty"))
+	require.NoError(t, err)
+	assert.Empty(t, clients)
+}
@@ -0,0 +1,314 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/monitoring"
+	"github.com/voilet/quic-flow/pkg/task/models"
+	"github.com/voilet/quic-flow/pkg/task/store"
+)
+
+// WorkflowManager 工作流管理器
+// 工作流是任务节点组成的 DAG：根节点（没有入边）在其任务分组的在线客户端上执行，
+// 下游节点按入边的条件（on_success/on_failure/always）与客户端范围（same_client/any）触发。
+// 每次运行生成一条 WorkflowRun 记录，节点的执行记录通过 workflow_run_id 关联（推进逻辑见 workflow_run.go）
+type WorkflowManager struct {
+	cron           *CronScheduler
+	dispatcher     *TaskDispatcher
+	taskStore      store.TaskStore
+	workflowStore  store.WorkflowStore
+	executionStore store.ExecutionStore
+	logger         *monitoring.Logger
+
+	advanceMu sync.Mutex                    // 串行化运行的推进
+	hook      func(run *models.WorkflowRun) // 运行状态更新通知（如 WebSocket 推送）
+}
+
+// NewWorkflowManager 创建工作流管理器，并接收分发器的节点执行结束通知
+func NewWorkflowManager(
+	cron *CronScheduler,
+	dispatcher *TaskDispatcher,
+	taskStore store.TaskStore,
+	workflowStore store.WorkflowStore,
+	executionStore store.ExecutionStore,
+	logger *monitoring.Logger,
+) *WorkflowManager {
+	m := &WorkflowManager{
+		cron:           cron,
+		dispatcher:     dispatcher,
+		taskStore:      taskStore,
+		workflowStore:  workflowStore,
+		executionStore: executionStore,
+		logger:         logger,
+	}
+	dispatcher.workflowHook = m.onExecutionFinished
+	return m
+}
+
+// SetRunHook 设置运行状态更新通知，需在触发运行前调用
+func (m *WorkflowManager) SetRunHook(hook func(run *models.WorkflowRun)) {
+	m.hook = hook
+}
+
+// Initialize 注册启用工作流的定时运行，并继续推进服务器重启前未结束的运行
+func (m *WorkflowManager) Initialize(ctx context.Context) error {
+	workflows, err := m.workflowStore.ListEnabled(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list enabled workflows: %w", err)
+	}
+	for _, workflow := range workflows {
+		if err := m.cron.AddWorkflow(workflow, m.scheduledRun(workflow.ID)); err != nil {
+			m.logger.Warn("Failed to add workflow to scheduler",
+				"workflow_id", workflow.ID,
+				"workflow_name", workflow.Name,
+				"error", err)
+		}
+	}
+
+	runs, err := m.workflowStore.ListRunning(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list running workflow runs: %w", err)
+	}
+	for _, run := range runs {
+		if err := m.Advance(ctx, run.ID); err != nil {
+			m.logger.Warn("Failed to resume workflow run", "run_id", run.ID, "error", err)
+		}
+	}
+
+	m.logger.Info("Workflow manager initialized", "workflow_count", len(workflows), "running_runs", len(runs))
+	return nil
+}
+
+// CreateWorkflowRequest 创建工作流请求
+type CreateWorkflowRequest struct {
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	CronExpr    string                `json:"cron_expr"` // 为空时只能手动触发
+	Nodes       []models.WorkflowNode `json:"nodes"`
+	Edges       []models.WorkflowEdge `json:"edges"`
+	CreatedBy   string                `json:"created_by"`
+}
+
+// CreateWorkflow 创建工作流
+func (m *WorkflowManager) CreateWorkflow(ctx context.Context, req *CreateWorkflowRequest) (*models.Workflow, error) {
+	workflow := &models.Workflow{
+		Name:        req.Name,
+		Description: req.Description,
+		CronExpr:    req.CronExpr,
+		Status:      models.TaskStatusEnabled,
+		CreatedBy:   req.CreatedBy,
+		Nodes:       req.Nodes,
+		Edges:       req.Edges,
+	}
+	if err := m.validate(ctx, workflow); err != nil {
+		return nil, err
+	}
+
+	if err := m.workflowStore.Create(ctx, workflow); err != nil {
+		return nil, fmt.Errorf("failed to create workflow: %w", err)
+	}
+	if err := m.cron.AddWorkflow(workflow, m.scheduledRun(workflow.ID)); err != nil {
+		m.logger.Warn("Failed to add workflow to scheduler", "workflow_id", workflow.ID, "error", err)
+	}
+
+	m.logger.Info("Workflow created",
+		"workflow_id", workflow.ID,
+		"workflow_name", workflow.Name,
+		"nodes", len(workflow.Nodes),
+		"edges", len(workflow.Edges))
+	return workflow, nil
+}
+
+// UpdateWorkflowRequest 更新工作流请求
+type UpdateWorkflowRequest struct {
+	WorkflowID  int64                 `json:"-"`
+	Name        *string               `json:"name"`
+	Description *string               `json:"description"`
+	CronExpr    *string               `json:"cron_expr"`
+	Status      *models.TaskStatus    `json:"status"`
+	Nodes       []models.WorkflowNode `json:"nodes"` // 如果提供，将与 edges 一起替换整个 DAG
+	Edges       []models.WorkflowEdge `json:"edges"`
+}
+
+// UpdateWorkflow 更新工作流，运行中的记录仍按启动时的定义推进
+func (m *WorkflowManager) UpdateWorkflow(ctx context.Context, req *UpdateWorkflowRequest) (*models.Workflow, error) {
+	workflow, err := m.workflowStore.GetByID(ctx, req.WorkflowID)
+	if err != nil {
+		return nil, fmt.Errorf("workflow not found: %w", err)
+	}
+
+	if req.Name != nil {
+		workflow.Name = *req.Name
+	}
+	if req.Description != nil {
+		workflow.Description = *req.Description
+	}
+	if req.CronExpr != nil {
+		workflow.CronExpr = *req.CronExpr
+	}
+	if req.Status != nil {
+		workflow.Status = *req.Status
+	}
+	if req.Nodes != nil {
+		workflow.Nodes = req.Nodes
+		workflow.Edges = req.Edges
+	}
+	if err := m.validate(ctx, workflow); err != nil {
+		return nil, err
+	}
+
+	if err := m.workflowStore.Update(ctx, workflow); err != nil {
+		return nil, fmt.Errorf("failed to update workflow: %w", err)
+	}
+	if err := m.cron.AddWorkflow(workflow, m.scheduledRun(workflow.ID)); err != nil {
+		m.logger.Warn("Failed to update workflow in scheduler", "workflow_id", workflow.ID, "error", err)
+	}
+
+	m.logger.Info("Workflow updated", "workflow_id", workflow.ID)
+	return workflow, nil
+}
+
+// EnableWorkflow 启用工作流
+func (m *WorkflowManager) EnableWorkflow(ctx context.Context, workflowID int64) error {
+	status := models.TaskStatusEnabled
+	_, err := m.UpdateWorkflow(ctx, &UpdateWorkflowRequest{WorkflowID: workflowID, Status: &status})
+	return err
+}
+
+// DisableWorkflow 禁用工作流（停止定时运行，不影响运行中的记录）
+func (m *WorkflowManager) DisableWorkflow(ctx context.Context, workflowID int64) error {
+	status := models.TaskStatusDisabled
+	_, err := m.UpdateWorkflow(ctx, &UpdateWorkflowRequest{WorkflowID: workflowID, Status: &status})
+	return err
+}
+
+// DeleteWorkflow 删除工作流（软删除，保留运行记录）
+func (m *WorkflowManager) DeleteWorkflow(ctx context.Context, workflowID int64) error {
+	m.cron.RemoveWorkflow(workflowID)
+	if err := m.workflowStore.Delete(ctx, workflowID); err != nil {
+		return fmt.Errorf("failed to delete workflow: %w", err)
+	}
+	m.logger.Info("Workflow deleted", "workflow_id", workflowID)
+	return nil
+}
+
+// TriggerWorkflow 手动触发工作流运行
+func (m *WorkflowManager) TriggerWorkflow(ctx context.Context, workflowID int64) (*models.WorkflowRun, error) {
+	return m.StartRun(ctx, workflowID, models.ExecutionTypeManual)
+}
+
+// GetNextRunTime 获取工作流下次定时运行时间
+func (m *WorkflowManager) GetNextRunTime(workflowID int64) (time.Time, error) {
+	return m.cron.GetWorkflowNextRunTime(workflowID)
+}
+
+// scheduledRun 返回工作流的定时运行函数
+func (m *WorkflowManager) scheduledRun(workflowID int64) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		if _, err := m.StartRun(ctx, workflowID, models.ExecutionTypeScheduled); err != nil {
+			m.logger.Error("Failed to start scheduled workflow run", "workflow_id", workflowID, "error", err)
+		}
+	}
+}
+
+// validate 校验工作流：节点名唯一且任务存在，边引用已有节点，条件与范围合法，且不存在环
+// 边的条件默认 on_success，范围默认 same_client
+func (m *WorkflowManager) validate(ctx context.Context, workflow *models.Workflow) error {
+	if workflow.Name == "" {
+		return fmt.Errorf("workflow name is required")
+	}
+	if workflow.CronExpr != "" {
+		if err := m.cron.validateCronExpr(workflow.CronExpr); err != nil {
+			return fmt.Errorf("invalid cron expression: %w", err)
+		}
+	}
+	if len(workflow.Nodes) == 0 {
+		return fmt.Errorf("workflow must have at least one node")
+	}
+
+	for _, node := range workflow.Nodes {
+		if _, err := m.taskStore.GetByID(ctx, node.TaskID); err != nil {
+			return fmt.Errorf("node %q: task %d not found", node.Name, node.TaskID)
+		}
+	}
+	for i := range workflow.Edges {
+		edge := &workflow.Edges[i]
+		if edge.Condition == "" {
+			edge.Condition = models.EdgeOnSuccess
+		}
+		if edge.Scope == "" {
+			edge.Scope = models.EdgeScopeSameClient
+		}
+	}
+
+	_, err := topoOrder(models.WorkflowDefinition{Nodes: workflow.Nodes, Edges: workflow.Edges})
+	return err
+}
+
+// topoOrder 校验 DAG 的结构并返回拓扑序（同层按定义顺序）
+func topoOrder(def models.WorkflowDefinition) ([]string, error) {
+	indegree := make(map[string]int, len(def.Nodes))
+	for _, node := range def.Nodes {
+		if node.Name == "" {
+			return nil, fmt.Errorf("node name is required")
+		}
+		if _, ok := indegree[node.Name]; ok {
+			return nil, fmt.Errorf("duplicate node %q", node.Name)
+		}
+		indegree[node.Name] = 0
+	}
+
+	next := make(map[string][]string)
+	seen := make(map[[2]string]bool)
+	for _, edge := range def.Edges {
+		if _, ok := indegree[edge.From]; !ok {
+			return nil, fmt.Errorf("edge %s -> %s: unknown node %q", edge.From, edge.To, edge.From)
+		}
+		if _, ok := indegree[edge.To]; !ok {
+			return nil, fmt.Errorf("edge %s -> %s: unknown node %q", edge.From, edge.To, edge.To)
+		}
+		if edge.From == edge.To {
+			return nil, fmt.Errorf("edge %s -> %s: self loop", edge.From, edge.To)
+		}
+		key := [2]string{edge.From, edge.To}
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate edge %s -> %s", edge.From, edge.To)
+		}
+		seen[key] = true
+		switch edge.Condition {
+		case models.EdgeOnSuccess, models.EdgeOnFailure, models.EdgeAlways:
+		default:
+			return nil, fmt.Errorf("edge %s -> %s: invalid condition %q", edge.From, edge.To, edge.Condition)
+		}
+		switch edge.Scope {
+		case models.EdgeScopeSameClient, models.EdgeScopeAny:
+		default:
+			return nil, fmt.Errorf("edge %s -> %s: invalid scope %q", edge.From, edge.To, edge.Scope)
+		}
+		next[edge.From] = append(next[edge.From], edge.To)
+		indegree[edge.To]++
+	}
+
+	order := make([]string, 0, len(def.Nodes))
+	for len(order) < len(def.Nodes) {
+		progressed := false
+		for _, node := range def.Nodes {
+			if indegree[node.Name] != 0 {
+				continue
+			}
+			indegree[node.Name] = -1
+			order = append(order, node.Name)
+			for _, to := range next[node.Name] {
+				indegree[to]--
+			}
+			progressed = true
+		}
+		if !progressed {
+			return nil, fmt.Errorf("workflow contains a cycle")
+		}
+	}
+	return order, nil
+}
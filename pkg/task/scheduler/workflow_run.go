@@ -0,0 +1,552 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/task/models"
+)
+
+// 工作流运行的推进：
+// 每当节点的执行结束（见 TaskDispatcher.notify），按拓扑序重新评估运行中的每个节点：
+//   - any 入边：上游节点结束后按节点整体结果判断（全部成功为成功，有失败为失败）
+//   - same_client 入边：上游节点在某客户端上的执行结束且满足条件后，立即在该客户端上执行下游节点，
+//     各客户端互不等待；上游节点结束时仍没有任何客户端满足条件则下游跳过
+//   - 没有 same_client 入边的节点在其任务分组的在线客户端上执行
+// 多条入边须全部满足；节点失败且没有 on_failure 出边时，运行记为失败
+
+// runState 一次推进中运行的内存视图
+type runState struct {
+	run      *models.WorkflowRun
+	order    []string
+	nodes    map[string]*models.WorkflowRunNode
+	incoming map[string][]models.WorkflowEdge
+	outgoing map[string][]models.WorkflowEdge
+	execs    map[string]map[string]*models.Execution // 节点 -> 客户端 -> 执行记录
+	changed  bool
+}
+
+// loadRunState 加载运行记录、定义快照与执行记录
+func (m *WorkflowManager) loadRunState(ctx context.Context, runID int64) (*runState, models.WorkflowDefinition, error) {
+	var def models.WorkflowDefinition
+	run, err := m.workflowStore.GetRun(ctx, runID)
+	if err != nil {
+		return nil, def, fmt.Errorf("workflow run %d not found: %w", runID, err)
+	}
+	if err := json.Unmarshal([]byte(run.Definition), &def); err != nil {
+		return nil, def, fmt.Errorf("invalid workflow definition of run %d: %w", runID, err)
+	}
+	order, err := topoOrder(def)
+	if err != nil {
+		return nil, def, fmt.Errorf("invalid workflow definition of run %d: %w", runID, err)
+	}
+	executions, err := m.executionStore.ListByWorkflowRun(ctx, runID)
+	if err != nil {
+		return nil, def, fmt.Errorf("failed to list executions of run %d: %w", runID, err)
+	}
+
+	st := &runState{
+		run:      run,
+		order:    order,
+		nodes:    make(map[string]*models.WorkflowRunNode, len(run.Nodes)),
+		incoming: make(map[string][]models.WorkflowEdge),
+		outgoing: make(map[string][]models.WorkflowEdge),
+		execs:    make(map[string]map[string]*models.Execution),
+	}
+	for i := range run.Nodes {
+		st.nodes[run.Nodes[i].Node] = &run.Nodes[i]
+	}
+	for _, edge := range def.Edges {
+		st.incoming[edge.To] = append(st.incoming[edge.To], edge)
+		st.outgoing[edge.From] = append(st.outgoing[edge.From], edge)
+	}
+	for _, exec := range executions {
+		st.addExecution(exec)
+	}
+	return st, def, nil
+}
+
+// addExecution 记录节点在客户端上的执行
+func (st *runState) addExecution(exec *models.Execution) {
+	byClient := st.execs[exec.WorkflowNode]
+	if byClient == nil {
+		byClient = make(map[string]*models.Execution)
+		st.execs[exec.WorkflowNode] = byClient
+	}
+	byClient[exec.ClientID] = exec
+}
+
+// StartRun 按工作流当前定义创建运行记录并启动根节点
+func (m *WorkflowManager) StartRun(ctx context.Context, workflowID int64, trigger models.ExecutionType) (*models.WorkflowRun, error) {
+	workflow, err := m.workflowStore.GetByID(ctx, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("workflow not found: %w", err)
+	}
+	def := models.WorkflowDefinition{Nodes: workflow.Nodes, Edges: workflow.Edges}
+	if _, err := topoOrder(def); err != nil {
+		return nil, fmt.Errorf("invalid workflow: %w", err)
+	}
+	data, err := json.Marshal(def)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal workflow definition: %w", err)
+	}
+
+	run := &models.WorkflowRun{
+		WorkflowID:   workflow.ID,
+		WorkflowName: workflow.Name,
+		TriggerType:  trigger,
+		Status:       models.WorkflowRunStatusRunning,
+		Definition:   string(data),
+		StartTime:    time.Now(),
+	}
+	for _, node := range workflow.Nodes {
+		runNode := models.WorkflowRunNode{Node: node.Name, TaskID: node.TaskID, Status: models.WorkflowNodeStatusWaiting}
+		if task, err := m.taskStore.GetByID(ctx, node.TaskID); err == nil {
+			runNode.TaskName = task.Name
+		}
+		run.Nodes = append(run.Nodes, runNode)
+	}
+	if err := m.workflowStore.CreateRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("failed to create workflow run: %w", err)
+	}
+	m.logger.Info("Workflow run started",
+		"workflow_id", workflow.ID,
+		"workflow_name", workflow.Name,
+		"run_id", run.ID,
+		"trigger", int(trigger))
+
+	if err := m.Advance(ctx, run.ID); err != nil {
+		return nil, err
+	}
+	return m.workflowStore.GetRun(ctx, run.ID)
+}
+
+// onExecutionFinished 节点执行结束后在后台推进所属运行
+func (m *WorkflowManager) onExecutionFinished(exec *models.Execution) {
+	runID := *exec.WorkflowRunID
+	go func() {
+		if err := m.Advance(context.Background(), runID); err != nil {
+			m.logger.Warn("Failed to advance workflow run", "run_id", runID, "error", err)
+		}
+	}()
+}
+
+// Advance 推进运行：下发满足条件的节点，结束已完成的节点，全部节点结束后结束运行
+func (m *WorkflowManager) Advance(ctx context.Context, runID int64) error {
+	m.advanceMu.Lock()
+	defer m.advanceMu.Unlock()
+
+	st, _, err := m.loadRunState(ctx, runID)
+	if err != nil {
+		return err
+	}
+	if st.run.IsFinished() {
+		return nil
+	}
+
+	for _, name := range st.order {
+		if node := st.nodes[name]; node != nil && !node.Status.IsFinished() {
+			m.advanceNode(ctx, st, node)
+		}
+	}
+
+	finished, failed := true, false
+	for _, node := range st.nodes {
+		if !node.Status.IsFinished() {
+			finished = false
+		}
+		if node.Status == models.WorkflowNodeStatusFailed && !handlesFailure(st.outgoing[node.Node]) {
+			failed = true
+		}
+	}
+	if finished {
+		now := time.Now()
+		st.run.EndTime = &now
+		st.run.Status = models.WorkflowRunStatusSuccess
+		if failed {
+			st.run.Status = models.WorkflowRunStatusFailed
+		}
+		if err := m.workflowStore.UpdateRun(ctx, st.run); err != nil {
+			return fmt.Errorf("failed to update workflow run: %w", err)
+		}
+		st.changed = true
+		m.logger.Info("Workflow run finished",
+			"run_id", st.run.ID,
+			"workflow_name", st.run.WorkflowName,
+			"status", int(st.run.Status))
+	}
+
+	if st.changed && m.hook != nil {
+		m.hook(st.run)
+	}
+	return nil
+}
+
+// advanceNode 评估单个未结束的节点
+func (m *WorkflowManager) advanceNode(ctx context.Context, st *runState, node *models.WorkflowRunNode) {
+	// any 入边：上游整体结果不满足条件时跳过，上游未结束时等待
+	var sameClient []models.WorkflowEdge
+	waiting := false
+	for _, edge := range st.incoming[node.Node] {
+		if edge.Scope != models.EdgeScopeAny {
+			sameClient = append(sameClient, edge)
+			continue
+		}
+		up := st.nodes[edge.From]
+		if !up.Status.IsFinished() {
+			waiting = true
+			continue
+		}
+		if !conditionHolds(edge.Condition, nodeOutcome(up.Status)) {
+			m.finishNode(ctx, st, node, models.WorkflowNodeStatusSkipped,
+				fmt.Sprintf("%s: %s not met", edge.From, edge.Condition))
+			return
+		}
+	}
+	if waiting {
+		return
+	}
+
+	task, err := m.taskStore.GetByID(ctx, node.TaskID)
+	if err != nil {
+		m.finishNode(ctx, st, node, models.WorkflowNodeStatusFailed, fmt.Sprintf("task %d not found", node.TaskID))
+		return
+	}
+
+	// 没有 same_client 入边：在任务分组的在线客户端上执行一次
+	if len(sameClient) == 0 {
+		if node.Status == models.WorkflowNodeStatusWaiting {
+			clients, err := m.dispatcher.targetClients(ctx, task)
+			if err != nil {
+				m.finishNode(ctx, st, node, models.WorkflowNodeStatusFailed, err.Error())
+				return
+			}
+			if len(clients) == 0 {
+				m.finishNode(ctx, st, node, models.WorkflowNodeStatusSkipped, "no online clients")
+				return
+			}
+			for _, clientID := range clients {
+				m.dispatchNode(ctx, st, node, task, clientID)
+			}
+		}
+		m.resolveNode(ctx, st, node)
+		return
+	}
+
+	// same_client 入边：逐个客户端判断上游在该客户端上的执行
+	upstreamDone := true
+	for _, edge := range sameClient {
+		if !st.nodes[edge.From].Status.IsFinished() {
+			upstreamDone = false
+		}
+	}
+	for _, clientID := range sortedClients(st.execs[sameClient[0].From]) {
+		if st.execs[node.Node][clientID] != nil {
+			continue
+		}
+		ready := true
+		for _, edge := range sameClient {
+			up := st.execs[edge.From][clientID]
+			if up == nil || !up.IsFinished() || !conditionHolds(edge.Condition, executionOutcome(up.Status)) {
+				ready = false
+				break
+			}
+		}
+		if ready {
+			m.dispatchNode(ctx, st, node, task, clientID)
+		}
+	}
+	if !upstreamDone {
+		return
+	}
+	if len(st.execs[node.Node]) == 0 {
+		m.finishNode(ctx, st, node, models.WorkflowNodeStatusSkipped, "condition not met on any client")
+		return
+	}
+	m.resolveNode(ctx, st, node)
+}
+
+// dispatchNode 在客户端上执行节点的任务，执行记录关联到运行
+func (m *WorkflowManager) dispatchNode(ctx context.Context, st *runState, node *models.WorkflowRunNode, task *models.Task, clientID string) {
+	runID := st.run.ID
+	exec := &models.Execution{
+		ClientID:      clientID,
+		ExecutionType: st.run.TriggerType,
+		WorkflowRunID: &runID,
+		WorkflowNode:  node.Node,
+	}
+	// 下发失败时执行记录已标记为失败，结束通知会再次推进运行
+	if err := m.dispatcher.dispatchExecution(ctx, task, exec); err != nil {
+		m.logger.Warn("Failed to dispatch workflow node",
+			"run_id", runID,
+			"node", node.Node,
+			"client_id", clientID,
+			"error", err)
+	}
+	if exec.ID != 0 {
+		st.addExecution(exec)
+	}
+
+	if node.Status == models.WorkflowNodeStatusWaiting {
+		now := time.Now()
+		node.Status = models.WorkflowNodeStatusRunning
+		node.StartTime = &now
+		m.saveNode(ctx, st, node)
+	}
+}
+
+// resolveNode 节点的执行全部结束后，按执行结果结束节点
+func (m *WorkflowManager) resolveNode(ctx context.Context, st *runState, node *models.WorkflowRunNode) {
+	executions := st.execs[node.Node]
+	if len(executions) == 0 {
+		m.finishNode(ctx, st, node, models.WorkflowNodeStatusFailed, "dispatch failed")
+		return
+	}
+	failed := 0
+	for _, exec := range executions {
+		if !exec.IsFinished() {
+			return
+		}
+		if exec.Status != models.ExecutionStatusSuccess {
+			failed++
+		}
+	}
+	if failed > 0 {
+		m.finishNode(ctx, st, node, models.WorkflowNodeStatusFailed,
+			fmt.Sprintf("%d/%d executions failed", failed, len(executions)))
+		return
+	}
+	m.finishNode(ctx, st, node, models.WorkflowNodeStatusSuccess, "")
+}
+
+// finishNode 结束节点
+func (m *WorkflowManager) finishNode(ctx context.Context, st *runState, node *models.WorkflowRunNode, status models.WorkflowNodeStatus, message string) {
+	now := time.Now()
+	node.Status = status
+	node.Message = message
+	node.EndTime = &now
+	m.saveNode(ctx, st, node)
+	m.logger.Info("Workflow node finished",
+		"run_id", st.run.ID,
+		"node", node.Node,
+		"status", int(status),
+		"message", message)
+}
+
+// saveNode 保存节点状态
+func (m *WorkflowManager) saveNode(ctx context.Context, st *runState, node *models.WorkflowRunNode) {
+	if err := m.workflowStore.UpdateRunNode(ctx, node); err != nil {
+		m.logger.Warn("Failed to update workflow run node", "run_id", st.run.ID, "node", node.Node, "error", err)
+	}
+	st.changed = true
+}
+
+// outcome 上游结果：成功、失败或跳过
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	outcomeSkipped
+)
+
+// nodeOutcome 节点的整体结果
+func nodeOutcome(status models.WorkflowNodeStatus) outcome {
+	switch status {
+	case models.WorkflowNodeStatusSuccess:
+		return outcomeSuccess
+	case models.WorkflowNodeStatusSkipped:
+		return outcomeSkipped
+	}
+	return outcomeFailure
+}
+
+// executionOutcome 单次执行的结果（超时、取消视为失败）
+func executionOutcome(status models.ExecutionStatus) outcome {
+	if status == models.ExecutionStatusSuccess {
+		return outcomeSuccess
+	}
+	return outcomeFailure
+}
+
+// conditionHolds 上游结果是否满足边的条件
+func conditionHolds(cond models.EdgeCondition, o outcome) bool {
+	switch cond {
+	case models.EdgeOnSuccess:
+		return o == outcomeSuccess
+	case models.EdgeOnFailure:
+		return o == outcomeFailure
+	case models.EdgeAlways:
+		return true
+	}
+	return false
+}
+
+// handlesFailure 节点是否有处理失败的 on_failure 出边
+func handlesFailure(edges []models.WorkflowEdge) bool {
+	for _, edge := range edges {
+		if edge.Condition == models.EdgeOnFailure {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedClients 执行记录的客户端 ID（排序）
+func sortedClients(execs map[string]*models.Execution) []string {
+	clients := make([]string, 0, len(execs))
+	for clientID := range execs {
+		clients = append(clients, clientID)
+	}
+	sort.Strings(clients)
+	return clients
+}
+
+// WorkflowRunGraph 运行的可视化状态：节点（含各客户端的执行）、边的走向与 Mermaid 流程图
+type WorkflowRunGraph struct {
+	Run     *models.WorkflowRun `json:"run"`
+	Nodes   []WorkflowGraphNode `json:"nodes"`
+	Edges   []WorkflowGraphEdge `json:"edges"`
+	Mermaid string              `json:"mermaid"`
+}
+
+// WorkflowGraphNode 运行中的节点
+type WorkflowGraphNode struct {
+	models.WorkflowRunNode
+	Executions []WorkflowNodeExecution `json:"executions"`
+}
+
+// WorkflowNodeExecution 节点在单个客户端上的执行（输出见执行记录详情）
+type WorkflowNodeExecution struct {
+	ExecutionID int64                  `json:"execution_id"`
+	ClientID    string                 `json:"client_id"`
+	Status      models.ExecutionStatus `json:"status"`
+	ExitCode    int                    `json:"exit_code"`
+	Duration    int                    `json:"duration"`
+	ErrorMsg    string                 `json:"error_msg,omitempty"`
+}
+
+// 边的走向
+const (
+	EdgeStatePending  = "pending"   // 上游未结束
+	EdgeStateTaken    = "taken"     // 已触发下游
+	EdgeStateNotTaken = "not_taken" // 条件不满足
+)
+
+// WorkflowGraphEdge 运行中的边
+type WorkflowGraphEdge struct {
+	models.WorkflowEdge
+	State string `json:"state"`
+}
+
+// RunGraph 返回运行的可视化状态
+func (m *WorkflowManager) RunGraph(ctx context.Context, runID int64) (*WorkflowRunGraph, error) {
+	st, def, err := m.loadRunState(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &WorkflowRunGraph{Run: st.run}
+	for _, runNode := range st.run.Nodes {
+		node := WorkflowGraphNode{WorkflowRunNode: runNode, Executions: []WorkflowNodeExecution{}}
+		for _, clientID := range sortedClients(st.execs[runNode.Node]) {
+			exec := st.execs[runNode.Node][clientID]
+			node.Executions = append(node.Executions, WorkflowNodeExecution{
+				ExecutionID: exec.ID,
+				ClientID:    exec.ClientID,
+				Status:      exec.Status,
+				ExitCode:    exec.ExitCode,
+				Duration:    exec.Duration,
+				ErrorMsg:    exec.ErrorMsg,
+			})
+		}
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	for _, edge := range def.Edges {
+		graph.Edges = append(graph.Edges, WorkflowGraphEdge{WorkflowEdge: edge, State: st.edgeState(edge)})
+	}
+	graph.Mermaid = graph.mermaid()
+	return graph, nil
+}
+
+// edgeState 计算边的走向
+func (st *runState) edgeState(edge models.WorkflowEdge) string {
+	up, down := st.nodes[edge.From], st.nodes[edge.To]
+	if edge.Scope == models.EdgeScopeAny {
+		if !up.Status.IsFinished() {
+			return EdgeStatePending
+		}
+		if conditionHolds(edge.Condition, nodeOutcome(up.Status)) && down.Status != models.WorkflowNodeStatusSkipped {
+			return EdgeStateTaken
+		}
+		return EdgeStateNotTaken
+	}
+	for clientID := range st.execs[edge.To] {
+		if exec := st.execs[edge.From][clientID]; exec != nil && exec.IsFinished() &&
+			conditionHolds(edge.Condition, executionOutcome(exec.Status)) {
+			return EdgeStateTaken
+		}
+	}
+	if !up.Status.IsFinished() {
+		return EdgeStatePending
+	}
+	return EdgeStateNotTaken
+}
+
+// nodeStatusNames 节点状态在流程图中的名称（同时作为样式类名）
+var nodeStatusNames = map[models.WorkflowNodeStatus]string{
+	models.WorkflowNodeStatusWaiting: "waiting",
+	models.WorkflowNodeStatusRunning: "running",
+	models.WorkflowNodeStatusSuccess: "success",
+	models.WorkflowNodeStatusFailed:  "failed",
+	models.WorkflowNodeStatusSkipped: "skipped",
+}
+
+// mermaid 生成 Mermaid 流程图：节点按状态着色，未触发的边为虚线
+func (g *WorkflowRunGraph) mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	ids := make(map[string]string, len(g.Nodes))
+	for i, node := range g.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[node.Node] = id
+		label := node.Node
+		if node.TaskName != "" && node.TaskName != node.Node {
+			label += "<br/>" + node.TaskName
+		}
+		status := nodeStatusNames[node.Status]
+		if n := len(node.Executions); n > 0 {
+			ok := 0
+			for _, exec := range node.Executions {
+				if exec.Status == models.ExecutionStatusSuccess {
+					ok++
+				}
+			}
+			label += fmt.Sprintf("<br/>%s %d/%d", status, ok, n)
+		} else {
+			label += "<br/>" + status
+		}
+		fmt.Fprintf(&b, "  %s[\"%s\"]:::%s\n", id, strings.ReplaceAll(label, `"`, "#quot;"), status)
+	}
+	for _, edge := range g.Edges {
+		label := string(edge.Condition)
+		if edge.Scope == models.EdgeScopeAny {
+			label += " (any)"
+		}
+		arrow := "-->"
+		if edge.State == EdgeStateNotTaken {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "  %s %s|%s| %s\n", ids[edge.From], arrow, label, ids[edge.To])
+	}
+	b.WriteString("  classDef waiting fill:#f4f4f5,stroke:#909399\n")
+	b.WriteString("  classDef running fill:#ecf5ff,stroke:#409eff\n")
+	b.WriteString("  classDef success fill:#f0f9eb,stroke:#67c23a\n")
+	b.WriteString("  classDef failed fill:#fef0f0,stroke:#f56c6c\n")
+	b.WriteString("  classDef skipped fill:#fdf6ec,stroke:#e6a23c,stroke-dasharray:4\n")
+	return b.String()
+}
@@ -0,0 +1,273 @@
+package scheduler
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/voilet/quic-flow/pkg/command"
+	"github.com/voilet/quic-flow/pkg/protocol"
+	"github.com/voilet/quic-flow/pkg/task/models"
+	"github.com/voilet/quic-flow/pkg/task/store"
+)
+
+type workflowEnv struct {
+	m          *WorkflowManager
+	d          *TaskDispatcher
+	executions store.ExecutionStore
+	workflows  store.WorkflowStore
+	tasks      map[string]int64
+}
+
+func newWorkflowEnv(t *testing.T, clients ...string) *workflowEnv {
+	base := newTestManager(t, withClients(clients...))
+	ctx := context.Background()
+	workflows := store.NewWorkflowStore(base.db)
+	m := NewWorkflowManager(base.cron, base.d, base.tasks, workflows, base.executions, base.logger)
+
+	group := base.group(t, "db", clients...)
+	env := &workflowEnv{m: m, d: base.d, executions: base.executions, workflows: workflows, tasks: map[string]int64{}}
+	for _, name := range []string{"backup", "verify", "prune", "alert"} {
+		task := &models.Task{Name: name, ExecutorType: models.ExecutorTypeShell, ExecutorConfig: `{"command":"true"}`, CronExpr: "@daily"}
+		require.NoError(t, base.tasks.Create(ctx, task))
+		require.NoError(t, base.tasks.BindGroup(ctx, task.ID, group.ID))
+		env.tasks[name] = task.ID
+	}
+	return env
+}
+
+// execution 等待节点在客户端上的执行记录
+func (env *workflowEnv) execution(t *testing.T, runID int64, node, clientID string) *models.Execution {
+	var found *models.Execution
+	require.Eventually(t, func() bool {
+		list, err := env.executions.ListByWorkflowRun(context.Background(), runID)
+		require.NoError(t, err)
+		for _, exec := range list {
+			if exec.WorkflowNode == node && exec.ClientID == clientID {
+				found = exec
+				return true
+			}
+		}
+		return false
+	}, 2*time.Second, 10*time.Millisecond, "%s on %s", node, clientID)
+	return found
+}
+
+// finish 模拟客户端上报执行结果
+func (env *workflowEnv) finish(t *testing.T, exec *models.Execution, status protocol.ExecutionStatus) {
+	require.NoError(t, env.d.HandleResult(context.Background(), &command.TaskResultReport{
+		ClientID: exec.ClientID,
+		Result: &protocol.TaskResult{ExecutionId: strconv.FormatInt(exec.ID, 10), Status: status,
+			DurationMs: 10, Timestamp: time.Now().UnixMilli()},
+	}))
+}
+
+// nodeStatus 等待运行中节点达到指定状态
+func (env *workflowEnv) nodeStatus(t *testing.T, runID int64, node string, status models.WorkflowNodeStatus) {
+	require.Eventually(t, func() bool {
+		run, err := env.workflows.GetRun(context.Background(), runID)
+		require.NoError(t, err)
+		for _, n := range run.Nodes {
+			if n.Node == node {
+				return n.Status == status
+			}
+		}
+		return false
+	}, 2*time.Second, 10*time.Millisecond, "node %s", node)
+}
+
+func (env *workflowEnv) countExecutions(t *testing.T, runID int64, node string) int {
+	list, err := env.executions.ListByWorkflowRun(context.Background(), runID)
+	require.NoError(t, err)
+	n := 0
+	for _, exec := range list {
+		if exec.WorkflowNode == node {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWorkflowManager_Run(t *testing.T) {
+	env := newWorkflowEnv(t, "c1", "c2")
+	ctx := context.Background()
+
+	// backup 成功的客户端上各自 verify；verify 整体成功后 prune；backup 有失败时 alert
+	wf, err := env.m.CreateWorkflow(ctx, &CreateWorkflowRequest{
+		Name: "nightly-backup",
+		Nodes: []models.WorkflowNode{
+			{Name: "backup", TaskID: env.tasks["backup"]},
+			{Name: "verify", TaskID: env.tasks["verify"]},
+			{Name: "prune", TaskID: env.tasks["prune"]},
+			{Name: "alert", TaskID: env.tasks["alert"]},
+		},
+		Edges: []models.WorkflowEdge{
+			{From: "backup", To: "verify"},
+			{From: "verify", To: "prune", Scope: models.EdgeScopeAny},
+			{From: "backup", To: "alert", Condition: models.EdgeOnFailure, Scope: models.EdgeScopeAny},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.EdgeOnSuccess, wf.Edges[0].Condition)
+	assert.Equal(t, models.EdgeScopeSameClient, wf.Edges[0].Scope)
+
+	run, err := env.m.TriggerWorkflow(ctx, wf.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.WorkflowRunStatusRunning, run.Status)
+	assert.Equal(t, models.ExecutionTypeManual, run.TriggerType)
+	require.Len(t, run.Nodes, 4)
+	assert.Equal(t, models.WorkflowNodeStatusRunning, run.Nodes[0].Status)
+	assert.Equal(t, 2, env.countExecutions(t, run.ID, "backup"))
+
+	// c1 上 backup 成功后立即在 c1 上 verify，不等待 c2
+	env.finish(t, env.execution(t, run.ID, "backup", "c1"), protocol.ExecutionStatus_EXECUTION_STATUS_SUCCESS)
+	verify := env.execution(t, run.ID, "verify", "c1")
+	assert.Equal(t, env.tasks["verify"], verify.TaskID)
+
+	// c2 上 backup 失败：c2 不执行 verify，backup 节点失败触发 alert
+	env.finish(t, env.execution(t, run.ID, "backup", "c2"), protocol.ExecutionStatus_EXECUTION_STATUS_FAILED)
+	env.nodeStatus(t, run.ID, "backup", models.WorkflowNodeStatusFailed)
+	alert1 := env.execution(t, run.ID, "alert", "c1")
+	alert2 := env.execution(t, run.ID, "alert", "c2")
+
+	env.finish(t, verify, protocol.ExecutionStatus_EXECUTION_STATUS_SUCCESS)
+	env.nodeStatus(t, run.ID, "verify", models.WorkflowNodeStatusSuccess)
+	assert.Equal(t, 1, env.countExecutions(t, run.ID, "verify"))
+	prune1 := env.execution(t, run.ID, "prune", "c1")
+	prune2 := env.execution(t, run.ID, "prune", "c2")
+
+	for _, exec := range []*models.Execution{alert1, alert2, prune1, prune2} {
+		env.finish(t, exec, protocol.ExecutionStatus_EXECUTION_STATUS_SUCCESS)
+	}
+	// backup 的失败由 on_failure 边处理，运行成功
+	require.Eventually(t, func() bool {
+		run, _ = env.workflows.GetRun(ctx, run.ID)
+		return run.IsFinished()
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, models.WorkflowRunStatusSuccess, run.Status)
+	assert.NotNil(t, run.EndTime)
+
+	graph, err := env.m.RunGraph(ctx, run.ID)
+	require.NoError(t, err)
+	require.Len(t, graph.Nodes, 4)
+	assert.Len(t, graph.Nodes[0].Executions, 2)
+	states := map[string]string{}
+	for _, edge := range graph.Edges {
+		states[edge.From+"->"+edge.To] = edge.State
+	}
+	assert.Equal(t, map[string]string{
+		"backup->verify": EdgeStateTaken,
+		"verify->prune":  EdgeStateTaken,
+		"backup->alert":  EdgeStateTaken,
+	}, states)
+	assert.Contains(t, graph.Mermaid, "flowchart LR")
+	assert.Contains(t, graph.Mermaid, `n0["backup<br/>failed 1/2"]:::failed`)
+	assert.Contains(t, graph.Mermaid, "n0 -->|on_success| n1")
+}
+
+func TestWorkflowManager_SkipAndFail(t *testing.T) {
+	env := newWorkflowEnv(t, "c1")
+	ctx := context.Background()
+
+	wf, err := env.m.CreateWorkflow(ctx, &CreateWorkflowRequest{
+		Name: "chain",
+		Nodes: []models.WorkflowNode{
+			{Name: "backup", TaskID: env.tasks["backup"]},
+			{Name: "verify", TaskID: env.tasks["verify"]},
+			{Name: "prune", TaskID: env.tasks["prune"]},
+			{Name: "cleanup", TaskID: env.tasks["alert"]},
+		},
+		Edges: []models.WorkflowEdge{
+			{From: "backup", To: "verify"},
+			{From: "verify", To: "prune"},
+			{From: "backup", To: "cleanup", Condition: models.EdgeAlways},
+		},
+	})
+	require.NoError(t, err)
+
+	run, err := env.m.TriggerWorkflow(ctx, wf.ID)
+	require.NoError(t, err)
+	env.finish(t, env.execution(t, run.ID, "backup", "c1"), protocol.ExecutionStatus_EXECUTION_STATUS_TIMEOUT)
+
+	// 成功链路被跳过，always 边照常执行
+	env.nodeStatus(t, run.ID, "verify", models.WorkflowNodeStatusSkipped)
+	env.nodeStatus(t, run.ID, "prune", models.WorkflowNodeStatusSkipped)
+	env.finish(t, env.execution(t, run.ID, "cleanup", "c1"), protocol.ExecutionStatus_EXECUTION_STATUS_SUCCESS)
+
+	// backup 失败且没有 on_failure 边，运行失败
+	require.Eventually(t, func() bool {
+		run, _ = env.workflows.GetRun(ctx, run.ID)
+		return run.IsFinished()
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, models.WorkflowRunStatusFailed, run.Status)
+
+	graph, err := env.m.RunGraph(ctx, run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, EdgeStateNotTaken, graph.Edges[0].State)
+	assert.Contains(t, graph.Mermaid, "n0 -.->|on_success| n1")
+}
+
+func TestWorkflowManager_NoClients(t *testing.T) {
+	env := newWorkflowEnv(t)
+	ctx := context.Background()
+
+	wf, err := env.m.CreateWorkflow(ctx, &CreateWorkflowRequest{
+		Name:  "single",
+		Nodes: []models.WorkflowNode{{Name: "backup", TaskID: env.tasks["backup"]}},
+	})
+	require.NoError(t, err)
+	run, err := env.m.TriggerWorkflow(ctx, wf.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.WorkflowRunStatusSuccess, run.Status)
+	assert.Equal(t, models.WorkflowNodeStatusSkipped, run.Nodes[0].Status)
+	assert.Equal(t, "no online clients", run.Nodes[0].Message)
+}
+
+func TestWorkflowManager_Validate(t *testing.T) {
+	env := newWorkflowEnv(t)
+	ctx := context.Background()
+	nodes := []models.WorkflowNode{{Name: "a", TaskID: env.tasks["backup"]}, {Name: "b", TaskID: env.tasks["verify"]}}
+
+	cases := map[string]*CreateWorkflowRequest{
+		"no nodes":       {Name: "x"},
+		"unknown task":   {Name: "x", Nodes: []models.WorkflowNode{{Name: "a", TaskID: 999}}},
+		"duplicate node": {Name: "x", Nodes: []models.WorkflowNode{nodes[0], nodes[0]}},
+		"unknown node":   {Name: "x", Nodes: nodes, Edges: []models.WorkflowEdge{{From: "a", To: "c"}}},
+		"self loop":      {Name: "x", Nodes: nodes, Edges: []models.WorkflowEdge{{From: "a", To: "a"}}},
+		"cycle":          {Name: "x", Nodes: nodes, Edges: []models.WorkflowEdge{{From: "a", To: "b"}, {From: "b", To: "a"}}},
+		"bad condition":  {Name: "x", Nodes: nodes, Edges: []models.WorkflowEdge{{From: "a", To: "b", Condition: "maybe"}}},
+		"bad scope":      {Name: "x", Nodes: nodes, Edges: []models.WorkflowEdge{{From: "a", To: "b", Scope: "all"}}},
+		"bad cron":       {Name: "x", Nodes: nodes, CronExpr: "every day"},
+	}
+	for name, req := range cases {
+		_, err := env.m.CreateWorkflow(ctx, req)
+		assert.Error(t, err, name)
+	}
+
+	// 定时运行随启用状态注册与移除
+	env.m.cron.Start()
+	defer env.m.cron.Stop()
+	wf, err := env.m.CreateWorkflow(ctx, &CreateWorkflowRequest{Name: "nightly", CronExpr: "0 0 2 * * *", Nodes: nodes,
+		Edges: []models.WorkflowEdge{{From: "a", To: "b"}}})
+	require.NoError(t, err)
+	next, err := env.m.GetNextRunTime(wf.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, next.Hour())
+
+	require.NoError(t, env.m.DisableWorkflow(ctx, wf.ID))
+	_, err = env.m.GetNextRunTime(wf.ID)
+	assert.Error(t, err)
+
+	// 更新 DAG 整体替换节点与边
+	updated, err := env.m.UpdateWorkflow(ctx, &UpdateWorkflowRequest{WorkflowID: wf.ID, Nodes: nodes[:1]})
+	require.NoError(t, err)
+	assert.Len(t, updated.Nodes, 1)
+	loaded, err := env.workflows.GetByID(ctx, wf.ID)
+	require.NoError(t, err)
+	assert.Len(t, loaded.Nodes, 1)
+	assert.Empty(t, loaded.Edges)
+	assert.False(t, loaded.IsEnabled())
+}
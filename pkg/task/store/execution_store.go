@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/voilet/quic-flow/pkg/task/models"
 	"gorm.io/gorm"
@@ -15,6 +16,12 @@ type ExecutionStore interface {
 	List(ctx context.Context, params *ExecutionListParams) ([]*models.Execution, int64, error)
 	GetByTaskID(ctx context.Context, taskID int64, limit int) ([]*models.Execution, error)
 	GetByClientID(ctx context.Context, clientID string, limit int) ([]*models.Execution, error)
+	Exists(ctx context.Context, clientID string, taskID int64, startTime time.Time) (bool, error)
+	ListByWorkflowRun(ctx context.Context, runID int64) ([]*models.Execution, error)
+	ListActive(ctx context.Context, taskID int64, clientID string) ([]*models.Execution, error)
+	ListPurgeable(ctx context.Context, params *ExecutionPurgeParams) ([]*models.Execution, error)
+	DeleteByIDs(ctx context.Context, executionIDs []int64) (int64, error)
+	ListHistory(ctx context.Context, params *ExecutionHistoryParams) ([]*models.Execution, error)
 }
 
 // ExecutionListParams 执行记录列表查询参数
@@ -27,6 +34,22 @@ type ExecutionListParams struct {
 	Keyword  string // 关键词搜索
 }
 
+// ExecutionPurgeParams 按保留策略查询可清理的执行记录
+// 只包含已结束的执行：超出最近 KeepRuns 条的，或创建时间早于 Before 的
+type ExecutionPurgeParams struct {
+	TaskID   int64      // 任务ID
+	KeepRuns int        // 保留最近的执行记录数（0=不限）
+	Before   *time.Time // 早于该时间的执行记录可清理（nil=不限）
+	Limit    int        // 最多返回数量
+}
+
+// ExecutionHistoryParams 执行历史查询参数（执行分析），只包含已结束的执行
+type ExecutionHistoryParams struct {
+	TaskID   int64     // 任务ID（0=不限）
+	ClientID string    // 客户端ID（空=不限）
+	Since    time.Time // 创建时间不早于该时间
+}
+
 // executionStoreImpl 执行记录存储实现
 type executionStoreImpl struct {
 	db *gorm.DB
@@ -136,3 +159,112 @@ func (s *executionStoreImpl) GetByClientID(ctx context.Context, clientID string,
 	err := query.Find(&executions).Error
 	return executions, err
 }
+
+// Exists 检查客户端是否已有同一任务、同一开始时间的执行记录（离线补报去重）
+func (s *executionStoreImpl) Exists(ctx context.Context, clientID string, taskID int64, startTime time.Time) (bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.Execution{}).
+		Where("client_id = ? AND task_id = ? AND start_time = ?", clientID, taskID, startTime).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListByWorkflowRun 获取工作流运行的所有执行记录，按创建顺序排列
+func (s *executionStoreImpl) ListByWorkflowRun(ctx context.Context, runID int64) ([]*models.Execution, error) {
+	var executions []*models.Execution
+	err := s.db.WithContext(ctx).
+		Where("workflow_run_id = ?", runID).
+		Order("id ASC").
+		Find(&executions).Error
+	return executions, err
+}
+
+// ListActive 获取任务在客户端上未结束的执行记录（待执行、执行中、排队中），按创建顺序排列
+func (s *executionStoreImpl) ListActive(ctx context.Context, taskID int64, clientID string) ([]*models.Execution, error) {
+	var executions []*models.Execution
+	err := s.db.WithContext(ctx).
+		Where("task_id = ? AND client_id = ? AND status IN ?", taskID, clientID, []int{
+			int(models.ExecutionStatusPending),
+			int(models.ExecutionStatusRunning),
+			int(models.ExecutionStatusQueued),
+		}).
+		Order("id ASC").
+		Find(&executions).Error
+	return executions, err
+}
+
+// finishedStatuses 已结束的执行状态
+var finishedStatuses = []int{
+	int(models.ExecutionStatusSuccess),
+	int(models.ExecutionStatusFailed),
+	int(models.ExecutionStatusTimeout),
+	int(models.ExecutionStatusCancelled),
+}
+
+// ListPurgeable 按保留策略查询可清理的执行记录（仅包含 ID、任务与输出存储字段），按 ID 升序排列
+func (s *executionStoreImpl) ListPurgeable(ctx context.Context, params *ExecutionPurgeParams) ([]*models.Execution, error) {
+	finished := func() *gorm.DB {
+		return s.db.WithContext(ctx).Model(&models.Execution{}).
+			Where("task_id = ? AND status IN ?", params.TaskID, finishedStatuses)
+	}
+
+	// 第 KeepRuns 新的执行记录的 ID，更早的记录超出保留数量
+	var cutoffID int64
+	if params.KeepRuns > 0 {
+		var ids []int64
+		if err := finished().Order("id DESC").Offset(params.KeepRuns-1).Limit(1).Pluck("id", &ids).Error; err != nil {
+			return nil, err
+		}
+		if len(ids) > 0 {
+			cutoffID = ids[0]
+		}
+	}
+
+	query := finished()
+	switch {
+	case cutoffID > 0 && params.Before != nil:
+		query = query.Where("(id < ? OR created_at < ?)", cutoffID, *params.Before)
+	case cutoffID > 0:
+		query = query.Where("id < ?", cutoffID)
+	case params.Before != nil:
+		query = query.Where("created_at < ?", *params.Before)
+	default:
+		return nil, nil
+	}
+	if params.Limit > 0 {
+		query = query.Limit(params.Limit)
+	}
+
+	var executions []*models.Execution
+	err := query.Select("id", "task_id", "client_id", "output_path", "output_size", "created_at").
+		Order("id ASC").
+		Find(&executions).Error
+	return executions, err
+}
+
+// DeleteByIDs 物理删除执行记录，返回删除的数量
+func (s *executionStoreImpl) DeleteByIDs(ctx context.Context, executionIDs []int64) (int64, error) {
+	if len(executionIDs) == 0 {
+		return 0, nil
+	}
+	result := s.db.WithContext(ctx).Unscoped().Where("id IN ?", executionIDs).Delete(&models.Execution{})
+	return result.RowsAffected, result.Error
+}
+
+// ListHistory 查询已结束的执行记录（仅包含统计所需的字段），按 ID 升序排列
+func (s *executionStoreImpl) ListHistory(ctx context.Context, params *ExecutionHistoryParams) ([]*models.Execution, error) {
+	query := s.db.WithContext(ctx).Model(&models.Execution{}).
+		Where("status IN ? AND created_at >= ?", finishedStatuses, params.Since)
+	if params.TaskID > 0 {
+		query = query.Where("task_id = ?", params.TaskID)
+	}
+	if params.ClientID != "" {
+		query = query.Where("client_id = ?", params.ClientID)
+	}
+
+	var executions []*models.Execution
+	err := query.Select("id", "task_id", "task_name", "client_id", "status", "duration", "created_at").
+		Order("id ASC").
+		Find(&executions).Error
+	return executions, err
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/voilet/quic-flow/pkg/task/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // GroupStore 分组存储接口
@@ -15,6 +16,7 @@ type GroupStore interface {
 	GetByID(ctx context.Context, groupID int64) (*models.TaskGroup, error)
 	List(ctx context.Context) ([]*models.TaskGroup, error)
 	GetClients(ctx context.Context, groupID int64) ([]*models.Client, error)
+	ListClientIDs(ctx context.Context, groupID int64) ([]string, error)
 	AddClients(ctx context.Context, groupID int64, clientIDs []string) error
 	RemoveClient(ctx context.Context, groupID int64, clientID string) error
 }
@@ -34,14 +36,21 @@ func (s *groupStoreImpl) Create(ctx context.Context, group *models.TaskGroup) er
 	return s.db.WithContext(ctx).Create(group).Error
 }
 
-// Update 更新分组
+// Update 更新分组（选择器为空时改为静态分组）
 func (s *groupStoreImpl) Update(ctx context.Context, group *models.TaskGroup) error {
-	return s.db.WithContext(ctx).Model(group).Updates(group).Error
+	return s.db.WithContext(ctx).Model(group).
+		Select("name", "description", "tags", "selector", "updated_at").
+		Updates(group).Error
 }
 
-// Delete 删除分组（软删除）
+// Delete 删除分组（软删除）及其客户端成员关系
 func (s *groupStoreImpl) Delete(ctx context.Context, groupID int64) error {
-	return s.db.WithContext(ctx).Delete(&models.TaskGroup{}, groupID).Error
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", groupID).Delete(&models.ClientGroupRelation{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.TaskGroup{}, groupID).Error
+	})
 }
 
 // GetByID 根据ID获取分组
@@ -67,28 +76,52 @@ func (s *groupStoreImpl) List(ctx context.Context) ([]*models.TaskGroup, error)
 func (s *groupStoreImpl) GetClients(ctx context.Context, groupID int64) ([]*models.Client, error) {
 	var clients []*models.Client
 	err := s.db.WithContext(ctx).
-		Where("group_id = ?", groupID).
+		Joins("JOIN tb_client_group_relation r ON r.client_id = tb_client.client_id").
+		Where("r.group_id = ?", groupID).
+		Order("tb_client.client_id").
 		Find(&clients).Error
 	return clients, err
 }
 
-// AddClients 添加客户端到分组
+// ListClientIDs 获取分组下的客户端ID列表（包括离线客户端）
+func (s *groupStoreImpl) ListClientIDs(ctx context.Context, groupID int64) ([]string, error) {
+	var clientIDs []string
+	err := s.db.WithContext(ctx).
+		Model(&models.Client{}).
+		Joins("JOIN tb_client_group_relation r ON r.client_id = tb_client.client_id").
+		Where("r.group_id = ?", groupID).
+		Order("tb_client.client_id").
+		Pluck("tb_client.client_id", &clientIDs).Error
+	return clientIDs, err
+}
+
+// AddClients 添加客户端到分组（只添加 tb_client 中存在的客户端，已是成员的跳过）
 func (s *groupStoreImpl) AddClients(ctx context.Context, groupID int64, clientIDs []string) error {
 	if len(clientIDs) == 0 {
 		return nil
 	}
-	// 批量更新客户端的 group_id
-	return s.db.WithContext(ctx).
+	var existing []string
+	if err := s.db.WithContext(ctx).
 		Model(&models.Client{}).
 		Where("client_id IN ?", clientIDs).
-		Update("group_id", groupID).Error
+		Pluck("client_id", &existing).Error; err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+	relations := make([]*models.ClientGroupRelation, 0, len(existing))
+	for _, clientID := range existing {
+		relations = append(relations, &models.ClientGroupRelation{ClientID: clientID, GroupID: groupID})
+	}
+	return s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&relations).Error
 }
 
 // RemoveClient 从分组移除客户端
 func (s *groupStoreImpl) RemoveClient(ctx context.Context, groupID int64, clientID string) error {
-	// 将客户端的 group_id 设置为 NULL
 	return s.db.WithContext(ctx).
-		Model(&models.Client{}).
 		Where("client_id = ? AND group_id = ?", clientID, groupID).
-		Update("group_id", nil).Error
+		Delete(&models.ClientGroupRelation{}).Error
 }
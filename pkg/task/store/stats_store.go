@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+
+	"github.com/voilet/quic-flow/pkg/task/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DailyStatsStore 每日统计存储接口
+type DailyStatsStore interface {
+	Upsert(ctx context.Context, stats []*models.TaskDailyStats) error
+	List(ctx context.Context, params *DailyStatsListParams) ([]*models.TaskDailyStats, error)
+}
+
+// DailyStatsListParams 每日统计查询参数
+type DailyStatsListParams struct {
+	TaskID   *int64 // 任务ID筛选
+	ClientID string // 客户端ID筛选
+	FromDate string // 起始日期（含，YYYY-MM-DD）
+	ToDate   string // 结束日期（含，YYYY-MM-DD）
+}
+
+// dailyStatsStoreImpl 每日统计存储实现
+type dailyStatsStoreImpl struct {
+	db *gorm.DB
+}
+
+// NewDailyStatsStore 创建每日统计存储
+func NewDailyStatsStore(db *gorm.DB) DailyStatsStore {
+	return &dailyStatsStoreImpl{db: db}
+}
+
+// Upsert 写入统计，同一客户端、日期、任务已存在时覆盖
+func (s *dailyStatsStoreImpl) Upsert(ctx context.Context, stats []*models.TaskDailyStats) error {
+	if len(stats) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "client_id"}, {Name: "date"}, {Name: "task_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"task_name", "success_count", "failure_count", "timeout_count", "cancelled_count",
+			"total_duration", "max_duration", "min_duration", "hourly_distribution",
+			"report_time", "updated_at",
+		}),
+	}).Create(stats).Error
+}
+
+// List 查询统计，按日期排序
+func (s *dailyStatsStoreImpl) List(ctx context.Context, params *DailyStatsListParams) ([]*models.TaskDailyStats, error) {
+	query := s.db.WithContext(ctx).Model(&models.TaskDailyStats{})
+	if params != nil {
+		if params.TaskID != nil {
+			query = query.Where("task_id = ?", *params.TaskID)
+		}
+		if params.ClientID != "" {
+			query = query.Where("client_id = ?", params.ClientID)
+		}
+		if params.FromDate != "" {
+			query = query.Where("date >= ?", params.FromDate)
+		}
+		if params.ToDate != "" {
+			query = query.Where("date <= ?", params.ToDate)
+		}
+	}
+
+	var stats []*models.TaskDailyStats
+	err := query.Order("date ASC, task_id ASC").Find(&stats).Error
+	return stats, err
+}
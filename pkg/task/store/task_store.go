@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/voilet/quic-flow/pkg/task/models"
 	"gorm.io/gorm"
@@ -16,9 +17,13 @@ type TaskStore interface {
 	GetByID(ctx context.Context, taskID int64) (*models.Task, error)
 	List(ctx context.Context, params *ListParams) ([]*models.Task, int64, error)
 	ListEnabled(ctx context.Context) ([]*models.Task, error)
+	ListAll(ctx context.Context) ([]*models.Task, error)
 	BindGroup(ctx context.Context, taskID int64, groupID int64) error
 	UnbindGroup(ctx context.Context, taskID int64, groupID int64) error
 	GetGroupIDs(ctx context.Context, taskID int64) ([]int64, error)
+	GetGroups(ctx context.Context, taskID int64) ([]*models.TaskGroup, error)
+	SetLastFireTime(ctx context.Context, taskID int64, fireTime time.Time) error
+	ListWithRetention(ctx context.Context) ([]*models.Task, error)
 }
 
 // ListParams 列表查询参数
@@ -45,8 +50,12 @@ func (s *taskStoreImpl) Create(ctx context.Context, task *models.Task) error {
 }
 
 // Update 更新任务
+// 写入全部字段（包括禁用状态等零值），分组关联由 BindGroup/UnbindGroup 维护，
+// 最近触发时间由 SetLastFireTime 维护
 func (s *taskStoreImpl) Update(ctx context.Context, task *models.Task) error {
-	return s.db.WithContext(ctx).Model(task).Updates(task).Error
+	return s.db.WithContext(ctx).Model(task).
+		Select("*").Omit("Groups", "CreatedAt", "DeletedAt", "LastFireTime").
+		Updates(task).Error
 }
 
 // Delete 删除任务（软删除）
@@ -114,6 +123,16 @@ func (s *taskStoreImpl) ListEnabled(ctx context.Context) ([]*models.Task, error)
 	return tasks, err
 }
 
+// ListAll 获取所有任务（含分组），按 ID 排序
+func (s *taskStoreImpl) ListAll(ctx context.Context) ([]*models.Task, error) {
+	var tasks []*models.Task
+	err := s.db.WithContext(ctx).
+		Preload("Groups").
+		Order("id ASC").
+		Find(&tasks).Error
+	return tasks, err
+}
+
 // BindGroup 绑定任务到分组
 func (s *taskStoreImpl) BindGroup(ctx context.Context, taskID int64, groupID int64) error {
 	var task models.Task
@@ -158,3 +177,34 @@ func (s *taskStoreImpl) GetGroupIDs(ctx context.Context, taskID int64) ([]int64,
 
 	return groupIDs, nil
 }
+
+// GetGroups 获取任务关联的分组（含动态分组选择器）
+func (s *taskStoreImpl) GetGroups(ctx context.Context, taskID int64) ([]*models.TaskGroup, error) {
+	var task models.Task
+	if err := s.db.WithContext(ctx).Preload("Groups").First(&task, taskID).Error; err != nil {
+		return nil, err
+	}
+
+	groups := make([]*models.TaskGroup, len(task.Groups))
+	for i := range task.Groups {
+		groups[i] = &task.Groups[i]
+	}
+	return groups, nil
+}
+
+// SetLastFireTime 记录任务最近一次定时触发时间（服务器重启后据此判断错过的触发）
+func (s *taskStoreImpl) SetLastFireTime(ctx context.Context, taskID int64, fireTime time.Time) error {
+	return s.db.WithContext(ctx).Model(&models.Task{}).
+		Where("id = ?", taskID).
+		UpdateColumn("last_fire_time", fireTime).Error
+}
+
+// ListWithRetention 获取设置了执行记录保留策略的任务（包括禁用的任务）
+func (s *taskStoreImpl) ListWithRetention(ctx context.Context) ([]*models.Task, error) {
+	var tasks []*models.Task
+	err := s.db.WithContext(ctx).
+		Where("retain_runs > 0 OR retain_days > 0").
+		Order("id ASC").
+		Find(&tasks).Error
+	return tasks, err
+}
@@ -0,0 +1,213 @@
+package store
+
+import (
+	"context"
+
+	"github.com/voilet/quic-flow/pkg/task/models"
+	"gorm.io/gorm"
+)
+
+// WorkflowStore 工作流及其运行记录存储接口
+type WorkflowStore interface {
+	Create(ctx context.Context, workflow *models.Workflow) error
+	Update(ctx context.Context, workflow *models.Workflow) error
+	Delete(ctx context.Context, workflowID int64) error
+	GetByID(ctx context.Context, workflowID int64) (*models.Workflow, error)
+	List(ctx context.Context, params *ListParams) ([]*models.Workflow, int64, error)
+	ListEnabled(ctx context.Context) ([]*models.Workflow, error)
+
+	CreateRun(ctx context.Context, run *models.WorkflowRun) error
+	UpdateRun(ctx context.Context, run *models.WorkflowRun) error
+	UpdateRunNode(ctx context.Context, node *models.WorkflowRunNode) error
+	GetRun(ctx context.Context, runID int64) (*models.WorkflowRun, error)
+	ListRuns(ctx context.Context, params *WorkflowRunListParams) ([]*models.WorkflowRun, int64, error)
+	ListRunning(ctx context.Context) ([]*models.WorkflowRun, error)
+}
+
+// WorkflowRunListParams 工作流运行记录列表查询参数
+type WorkflowRunListParams struct {
+	Page       int    // 页码
+	PageSize   int    // 每页数量
+	WorkflowID *int64 // 工作流ID筛选
+	Status     *int   // 状态筛选
+}
+
+// workflowStoreImpl 工作流存储实现
+type workflowStoreImpl struct {
+	db *gorm.DB
+}
+
+// NewWorkflowStore 创建工作流存储
+func NewWorkflowStore(db *gorm.DB) WorkflowStore {
+	return &workflowStoreImpl{db: db}
+}
+
+// Create 创建工作流（连同节点与边）
+func (s *workflowStoreImpl) Create(ctx context.Context, workflow *models.Workflow) error {
+	return s.db.WithContext(ctx).Create(workflow).Error
+}
+
+// Update 更新工作流，节点与边整体替换
+func (s *workflowStoreImpl) Update(ctx context.Context, workflow *models.Workflow) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(workflow).
+			Select("*").Omit("Nodes", "Edges", "CreatedAt", "DeletedAt").
+			Updates(workflow).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workflow_id = ?", workflow.ID).Delete(&models.WorkflowNode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workflow_id = ?", workflow.ID).Delete(&models.WorkflowEdge{}).Error; err != nil {
+			return err
+		}
+		for i := range workflow.Nodes {
+			workflow.Nodes[i].ID = 0
+			workflow.Nodes[i].WorkflowID = workflow.ID
+		}
+		for i := range workflow.Edges {
+			workflow.Edges[i].ID = 0
+			workflow.Edges[i].WorkflowID = workflow.ID
+		}
+		if len(workflow.Nodes) > 0 {
+			if err := tx.Create(&workflow.Nodes).Error; err != nil {
+				return err
+			}
+		}
+		if len(workflow.Edges) > 0 {
+			if err := tx.Create(&workflow.Edges).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Delete 删除工作流（软删除，保留节点、边与运行记录）
+func (s *workflowStoreImpl) Delete(ctx context.Context, workflowID int64) error {
+	return s.db.WithContext(ctx).Delete(&models.Workflow{}, workflowID).Error
+}
+
+// GetByID 根据ID获取工作流
+func (s *workflowStoreImpl) GetByID(ctx context.Context, workflowID int64) (*models.Workflow, error) {
+	var workflow models.Workflow
+	err := s.db.WithContext(ctx).Preload("Nodes").Preload("Edges").First(&workflow, workflowID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// List 列表查询工作流
+func (s *workflowStoreImpl) List(ctx context.Context, params *ListParams) ([]*models.Workflow, int64, error) {
+	if params == nil {
+		params = &ListParams{
+			Page:     1,
+			PageSize: 20,
+		}
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.Workflow{})
+	if params.Status != nil {
+		query = query.Where("status = ?", *params.Status)
+	}
+	if params.Keyword != "" {
+		keyword := "%" + params.Keyword + "%"
+		query = query.Where("name LIKE ? OR description LIKE ?", keyword, keyword)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var workflows []*models.Workflow
+	offset := (params.Page - 1) * params.PageSize
+	err := query.Preload("Nodes").Preload("Edges").
+		Offset(offset).
+		Limit(params.PageSize).
+		Order("created_at DESC").
+		Find(&workflows).Error
+
+	return workflows, total, err
+}
+
+// ListEnabled 获取所有启用的工作流
+func (s *workflowStoreImpl) ListEnabled(ctx context.Context) ([]*models.Workflow, error) {
+	var workflows []*models.Workflow
+	err := s.db.WithContext(ctx).
+		Where("status = ?", int(models.TaskStatusEnabled)).
+		Preload("Nodes").Preload("Edges").
+		Find(&workflows).Error
+	return workflows, err
+}
+
+// CreateRun 创建运行记录（连同节点状态）
+func (s *workflowStoreImpl) CreateRun(ctx context.Context, run *models.WorkflowRun) error {
+	return s.db.WithContext(ctx).Create(run).Error
+}
+
+// UpdateRun 更新运行记录（不含节点状态）
+func (s *workflowStoreImpl) UpdateRun(ctx context.Context, run *models.WorkflowRun) error {
+	return s.db.WithContext(ctx).Model(run).
+		Select("*").Omit("Nodes", "CreatedAt").
+		Updates(run).Error
+}
+
+// UpdateRunNode 更新运行中节点的状态
+func (s *workflowStoreImpl) UpdateRunNode(ctx context.Context, node *models.WorkflowRunNode) error {
+	return s.db.WithContext(ctx).Model(node).Select("*").Updates(node).Error
+}
+
+// GetRun 根据ID获取运行记录（含节点状态）
+func (s *workflowStoreImpl) GetRun(ctx context.Context, runID int64) (*models.WorkflowRun, error) {
+	var run models.WorkflowRun
+	err := s.db.WithContext(ctx).
+		Preload("Nodes", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		First(&run, runID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ListRuns 列表查询运行记录（不含节点状态）
+func (s *workflowStoreImpl) ListRuns(ctx context.Context, params *WorkflowRunListParams) ([]*models.WorkflowRun, int64, error) {
+	if params == nil {
+		params = &WorkflowRunListParams{
+			Page:     1,
+			PageSize: 20,
+		}
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.WorkflowRun{})
+	if params.WorkflowID != nil {
+		query = query.Where("workflow_id = ?", *params.WorkflowID)
+	}
+	if params.Status != nil {
+		query = query.Where("status = ?", *params.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var runs []*models.WorkflowRun
+	offset := (params.Page - 1) * params.PageSize
+	err := query.Offset(offset).
+		Limit(params.PageSize).
+		Order("id DESC").
+		Find(&runs).Error
+
+	return runs, total, err
+}
+
+// ListRunning 获取所有运行中的记录（服务器重启后继续推进）
+func (s *workflowStoreImpl) ListRunning(ctx context.Context) ([]*models.WorkflowRun, error) {
+	var runs []*models.WorkflowRun
+	err := s.db.WithContext(ctx).
+		Where("status = ?", int(models.WorkflowRunStatusRunning)).
+		Find(&runs).Error
+	return runs, err
+}
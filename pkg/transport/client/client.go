@@ -53,11 +53,15 @@ type Client struct {
 	reconnectAttempts atomic.Int32 // 重连尝试次数
 
 	// 心跳
-	lastPongTime atomic.Value // time.Time - 最后收到 Pong 的时间
+	lastPongTime      atomic.Value // time.Time - 最后收到 Pong 的时间
+	heartbeatInterval atomic.Int64 // 当前心跳间隔（纳秒），支持运行时调整
 
 	// SSH 处理器
 	sshHandler SSHStreamHandler
 	sshMu      sync.RWMutex
+
+	// 文件传输流处理器
+	fileTransferHandler FileTransferHandler
 }
 
 // SSHStreamHandler SSH 流处理函数类型
@@ -71,6 +75,17 @@ func (c *Client) SetSSHHandler(handler SSHStreamHandler) {
 	c.logger.Info("SSH handler set")
 }
 
+// FileTransferHandler 文件传输流处理函数类型（流头部已读取）
+type FileTransferHandler func(stream *quic.Stream) error
+
+// SetFileTransferHandler 设置文件传输流处理器
+func (c *Client) SetFileTransferHandler(handler FileTransferHandler) {
+	c.sshMu.Lock()
+	defer c.sshMu.Unlock()
+	c.fileTransferHandler = handler
+	c.logger.Info("File transfer handler set")
+}
+
 // NewClient 创建新的客户端实例 (T027)
 func NewClient(config *ClientConfig) (*Client, error) {
 	if config == nil {
@@ -110,6 +125,7 @@ func NewClient(config *ClientConfig) (*Client, error) {
 	// 初始化状态
 	c.state.Store(protocol.ClientState_CLIENT_STATE_IDLE)
 	c.lastPongTime.Store(time.Now())
+	c.heartbeatInterval.Store(int64(config.HeartbeatInterval))
 
 	return c, nil
 }
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"fmt"
 	"time"
 
 	pkgerrors "github.com/voilet/quic-flow/pkg/errors"
@@ -13,10 +14,11 @@ import (
 func (c *Client) heartbeatLoop() {
 	defer c.wg.Done()
 
-	ticker := time.NewTicker(c.config.HeartbeatInterval)
+	interval := c.HeartbeatInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	c.logger.Debug("Heartbeat loop started", "interval", c.config.HeartbeatInterval)
+	c.logger.Debug("Heartbeat loop started", "interval", interval)
 
 	for {
 		select {
@@ -25,6 +27,13 @@ func (c *Client) heartbeatLoop() {
 			return
 
 		case <-ticker.C:
+			// 心跳间隔被调整时重置 ticker
+			if d := c.HeartbeatInterval(); d != interval {
+				interval = d
+				ticker.Reset(interval)
+				c.logger.Info("Heartbeat interval changed", "interval", interval)
+			}
+
 			if !c.IsConnected() {
 				c.logger.Debug("Not connected, skipping heartbeat")
 				continue
@@ -42,6 +51,20 @@ func (c *Client) heartbeatLoop() {
 	}
 }
 
+// HeartbeatInterval 返回当前心跳间隔
+func (c *Client) HeartbeatInterval() time.Duration {
+	return time.Duration(c.heartbeatInterval.Load())
+}
+
+// SetHeartbeatInterval 运行时调整心跳间隔，在下一次心跳时生效
+func (c *Client) SetHeartbeatInterval(d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("%w: HeartbeatInterval must be positive", pkgerrors.ErrInvalidConfig)
+	}
+	c.heartbeatInterval.Store(int64(d))
+	return nil
+}
+
 // sendHeartbeat 发送心跳 Ping 并等待 Pong
 func (c *Client) sendHeartbeat() error {
 	// 打开新的流
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/voilet/quic-flow/pkg/protocol"
+	"github.com/voilet/quic-flow/pkg/query"
+)
+
+// Query 向服务器发起查询并等待结果
+// name: 查询名称（服务器 query.Router 中注册的名称）
+// req: 请求参数（编码为 JSON，可为 nil）
+// 超时取 ctx 的截止时间，未设置时为 30s；服务器处理失败时返回的错误包装 query.ErrQueryFailed
+func (c *Client) Query(ctx context.Context, name string, req interface{}) (json.RawMessage, error) {
+	msg, err := query.NewMessage(name, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		if timeout = time.Until(deadline); timeout <= 0 {
+			return nil, context.DeadlineExceeded
+		}
+	}
+
+	ack, err := c.SendMessage(ctx, msg, true, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if ack.Status != protocol.AckStatus_ACK_STATUS_SUCCESS {
+		return nil, fmt.Errorf("%w: %s: %s", query.ErrQueryFailed, name, ack.Error)
+	}
+	return ack.Result, nil
+}
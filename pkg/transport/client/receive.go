@@ -85,6 +85,24 @@ func (c *Client) handleStream(stream *quic.Stream) {
 		return
 	}
 
+	// 文件传输流（如 Agent 升级包），转发给文件传输处理器
+	if header != nil && header.Type == quicssh.StreamTypeFileTransfer {
+		c.sshMu.RLock()
+		handler := c.fileTransferHandler
+		c.sshMu.RUnlock()
+		if handler == nil {
+			c.logger.Error("收到文件传输流但没有设置处理器")
+			stream.CancelRead(0)
+			stream.Close()
+			return
+		}
+		if err := handler(stream); err != nil {
+			c.logger.Error("文件传输流处理失败", "error", err)
+		}
+		stream.Close()
+		return
+	}
+
 	// 不是 SSH 流，使用包装的 reader 继续处理普通消息
 	// peekedBytes 包含已读取的字节，需要先处理这些字节
 	wrappedStream := &prefixedReader{
@@ -400,6 +400,9 @@ func (s *Server) handleData(clientID string, stream *quic.Stream, frame *protoco
 		return
 	}
 
+	// 发送方以连接会话为准，处理器据此识别请求来源
+	dataMsg.SenderId = clientID
+
 	// 触发事件
 	if s.hooks != nil {
 		s.hooks.SafeOnMessageReceived(dataMsg.MsgId, clientID)
@@ -413,23 +416,34 @@ func (s *Server) handleData(clientID string, stream *quic.Stream, frame *protoco
 		// 异步分发
 		if err := s.dispatcher.Dispatch(s.ctx, dataMsg, responseCh); err != nil {
 			s.logger.Error("Failed to dispatch message", "client_id", clientID, "msg_id", dataMsg.MsgId, "error", err)
+			if dataMsg.WaitAck {
+				s.sendAck(clientID, stream, dataMsg.MsgId, protocol.AckStatus_ACK_STATUS_FAILURE, nil, err.Error())
+			}
 			return
 		}
 
-		// 等待响应（如果需要）
+		// 等待处理结果（如果需要确认），结果通过 Ack 返回给客户端
 		if dataMsg.WaitAck {
 			select {
 			case resp := <-responseCh:
+				if resp.Error != nil {
+					s.sendAck(clientID, stream, dataMsg.MsgId, protocol.AckStatus_ACK_STATUS_FAILURE, nil, resp.Error.Error())
+					return
+				}
+				var result []byte
 				if resp.Response != nil {
-					// 发送响应回客户端
-					s.sendResponse(clientID, stream, resp.Response)
+					result = resp.Response.Payload
 				}
+				s.sendAck(clientID, stream, dataMsg.MsgId, protocol.AckStatus_ACK_STATUS_SUCCESS, result, "")
 			case <-s.ctx.Done():
 				return
 			}
 		}
 	} else {
 		s.logger.Warn("No dispatcher set, message not processed", "client_id", clientID, "msg_id", dataMsg.MsgId)
+		if dataMsg.WaitAck {
+			s.sendAck(clientID, stream, dataMsg.MsgId, protocol.AckStatus_ACK_STATUS_FAILURE, nil, "no dispatcher configured")
+		}
 	}
 }
 
@@ -518,26 +532,26 @@ func (s *Server) GetSessions() *session.SessionManager {
 	return s.sessions
 }
 
-// sendResponse 发送响应消息到客户端
-func (s *Server) sendResponse(clientID string, stream *quic.Stream, msg *protocol.DataMessage) {
-	if msg == nil {
-		return
-	}
-
-	// 编码响应消息
-	responseFrame, err := codec.EncodeDataMessage(msg)
+// sendAck 在客户端发起的流上回复 Ack（携带处理结果或错误）
+func (s *Server) sendAck(clientID string, stream *quic.Stream, msgID string, status protocol.AckStatus, result []byte, errorMsg string) {
+	ackFrame, err := codec.EncodeAckMessage(&protocol.AckMessage{
+		MsgId:  msgID,
+		Status: status,
+		Result: result,
+		Error:  errorMsg,
+	}, time.Now().UnixMilli())
 	if err != nil {
-		s.logger.Error("Failed to encode response", "client_id", clientID, "error", err)
+		s.logger.Error("Failed to encode ack", "client_id", clientID, "msg_id", msgID, "error", err)
+		s.metrics.RecordEncodingError()
 		return
 	}
 
-	// 发送响应
-	if err := s.codec.WriteFrame(stream, responseFrame); err != nil {
-		s.logger.Error("Failed to send response", "client_id", clientID, "error", err)
+	if err := s.codec.WriteFrame(stream, ackFrame); err != nil {
+		s.logger.Error("Failed to send ack", "client_id", clientID, "msg_id", msgID, "error", err)
 		return
 	}
 
-	s.logger.Debug("Response sent", "client_id", clientID, "msg_id", msg.MsgId)
+	s.logger.Debug("Ack sent", "client_id", clientID, "msg_id", msgID, "status", status)
 }
 
 // SendTo 发送消息到指定客户端（单播）(T038)
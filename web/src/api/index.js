@@ -505,8 +505,14 @@ export const api = {
     return request.post('/release/deploys', data)
   },
 
-  startRelease(id) {
-    return request.post(`/release/deploys/${id}/start`)
+  // overrideReason 非空时忽略发布窗口与禁止窗口强制开始
+  startRelease(id, overrideReason = '') {
+    return request.post(`/release/deploys/${id}/start`, overrideReason ? { override_reason: overrideReason } : {})
+  },
+
+  // 当前阻止发布开始的发布窗口与禁止窗口
+  getReleaseBlocks(id) {
+    return request.get(`/release/deploys/${id}/blocks`)
   },
 
   cancelRelease(id) {
@@ -521,6 +527,35 @@ export const api = {
     return request.post(`/release/deploys/${id}/promote`)
   },
 
+  // 禁止窗口
+  getBlackoutWindows() {
+    return request.get('/calendar/windows')
+  },
+
+  createBlackoutWindow(data) {
+    return request.post('/calendar/windows', data)
+  },
+
+  updateBlackoutWindow(id, data) {
+    return request.put(`/calendar/windows/${id}`, data)
+  },
+
+  deleteBlackoutWindow(id) {
+    return request.delete(`/calendar/windows/${id}`)
+  },
+
+  setBlackoutWindowEnabled(id, enabled) {
+    return request.post(`/calendar/windows/${id}/${enabled ? 'enable' : 'disable'}`)
+  },
+
+  getBlackoutOccurrences(params = {}) {
+    return request.get('/calendar/occurrences', { params })
+  },
+
+  getActiveBlackouts(params = {}) {
+    return request.get('/calendar/active', { params })
+  },
+
   // 快捷操作
   installService(data) {
     return request.post('/release/install', data)
@@ -45,6 +45,29 @@ export const taskApi = {
   // 获取下次执行时间
   getNextRunTime(id) {
     return request.get(`/tasks/${id}/next-run`)
+  },
+
+  // 导出所有任务与分组（YAML 文本）
+  exportTasks() {
+    return request.get('/tasks/export', { responseType: 'text' })
+  },
+
+  // 应用 YAML 任务定义，dryRun 时只返回变更
+  applyTasks(yaml, { dryRun = false, prune = false } = {}) {
+    return request.post('/tasks/apply', yaml, {
+      params: { dry_run: dryRun, prune },
+      headers: { 'Content-Type': 'application/x-yaml' }
+    })
+  },
+
+  // 获取 Git 任务定义同步状态
+  getTaskSyncStatus() {
+    return request.get('/tasks/sync')
+  },
+
+  // 立即从 Git 仓库同步任务定义
+  syncTasks(dryRun = false) {
+    return request.post('/tasks/sync', null, { params: { dry_run: dryRun } })
   }
 }
 
@@ -68,6 +91,31 @@ export const executionApi = {
   // 获取执行统计
   getExecutionStats(params) {
     return request.get('/executions/stats', { params })
+  },
+
+  // 按保留策略清理执行记录（dryRun 时只统计）
+  purgeExecutions(dryRun = false) {
+    return request.post('/executions/purge', null, { params: { dry_run: dryRun } })
+  },
+
+  // 获取最近一次清理报告
+  getPurgeReport() {
+    return request.get('/executions/purge')
+  },
+
+  // 获取任务的执行分析（成功率、耗时趋势、客户端异常与耗时突增）
+  getTaskAnalytics(taskId, days = 7) {
+    return request.get(`/executions/analytics/tasks/${taskId}`, { params: { days } })
+  },
+
+  // 获取客户端的执行分析
+  getClientAnalytics(clientId, days = 7) {
+    return request.get(`/executions/analytics/clients/${clientId}`, { params: { days } })
+  },
+
+  // 获取所有任务的健康报告
+  getHealthReport(refresh = false) {
+    return request.get('/executions/analytics/health', { params: { refresh } })
   }
 }
 
@@ -111,5 +159,15 @@ export const groupApi = {
   // 从分组移除客户端
   removeGroupClient(id, clientId) {
     return request.delete(`/groups/${id}/clients/${clientId}`)
+  },
+
+  // 预览分组当前的在线成员（动态分组按选择器筛选）
+  previewGroup(id) {
+    return request.get(`/groups/${id}/preview`)
+  },
+
+  // 预览选择器匹配的在线客户端
+  previewSelector(selector) {
+    return request.post('/groups/preview', selector)
   }
 }